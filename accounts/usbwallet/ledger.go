@@ -154,6 +154,14 @@ func (w *ledgerDriver) Derive(path accounts.DerivationPath) (common.Address, err
 	return w.ledgerDerive(path)
 }
 
+// DerivePublicKey implements usbwallet.publicKeyDriver, sending a derivation
+// request to the Ledger and returning the uncompressed public key located on
+// that derivation path.
+func (w *ledgerDriver) DerivePublicKey(path accounts.DerivationPath) ([]byte, error) {
+	pubkey, _, err := w.ledgerDerivePublicKey(path)
+	return pubkey, err
+}
+
 // SignTx implements usbwallet.driver, sending the transaction to the Ledger and
 // waiting for the user to confirm or deny the transaction.
 //
@@ -256,6 +264,14 @@ func (w *ledgerDriver) ledgerVersion() ([3]byte, error) {
 //	Ethereum address        | 40 bytes hex ascii
 //	Chain code if requested | 32 bytes
 func (w *ledgerDriver) ledgerDerive(derivationPath []uint32) (common.Address, error) {
+	_, address, err := w.ledgerDerivePublicKey(derivationPath)
+	return address, err
+}
+
+// ledgerDerivePublicKey retrieves both the public key and the Ethereum address
+// from a Ledger wallet at the specified derivation path, following the protocol
+// of ledgerDerive.
+func (w *ledgerDriver) ledgerDerivePublicKey(derivationPath []uint32) ([]byte, common.Address, error) {
 	// Flatten the derivation path into the Ledger request
 	path := make([]byte, 1+4*len(derivationPath))
 	path[0] = byte(len(derivationPath))
@@ -265,26 +281,27 @@ func (w *ledgerDriver) ledgerDerive(derivationPath []uint32) (common.Address, er
 	// Send the request and wait for the response
 	reply, err := w.ledgerExchange(ledgerOpRetrieveAddress, ledgerP1DirectlyFetchAddress, ledgerP2DiscardAddressChainCode, path)
 	if err != nil {
-		return common.Address{}, err
+		return nil, common.Address{}, err
 	}
-	// Discard the public key, we don't need that for now
+	// Extract the uncompressed public key
 	if len(reply) < 1 || len(reply) < 1+int(reply[0]) {
-		return common.Address{}, errors.New("reply lacks public key entry")
+		return nil, common.Address{}, errors.New("reply lacks public key entry")
 	}
+	pubkey := common.CopyBytes(reply[1 : 1+int(reply[0])])
 	reply = reply[1+int(reply[0]):]
 
 	// Extract the Ethereum hex address string
 	if len(reply) < 1 || len(reply) < 1+int(reply[0]) {
-		return common.Address{}, errors.New("reply lacks address entry")
+		return nil, common.Address{}, errors.New("reply lacks address entry")
 	}
 	hexstr := reply[1 : 1+int(reply[0])]
 
 	// Decode the hex string into an Ethereum address and return
 	var address common.Address
 	if _, err = hex.Decode(address[:], hexstr); err != nil {
-		return common.Address{}, err
+		return nil, common.Address{}, err
 	}
-	return address, nil
+	return pubkey, address, nil
 }
 
 // ledgerSign sends the transaction to the Ledger wallet, and waits for the user
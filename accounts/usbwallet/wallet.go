@@ -71,6 +71,14 @@ type driver interface {
 	SignTypedMessage(path accounts.DerivationPath, messageHash []byte, domainHash []byte) ([]byte, error)
 }
 
+// publicKeyDriver is implemented by the drivers of devices revealing the public
+// keys of their accounts, not just the addresses.
+type publicKeyDriver interface {
+	// DerivePublicKey sends a derivation request to the USB device and returns
+	// the uncompressed public key located on that path.
+	DerivePublicKey(path accounts.DerivationPath) ([]byte, error)
+}
+
 // wallet represents the common functionality shared by all USB hardware
 // wallets to prevent reimplementing the same complex maintenance mechanisms
 // for different vendors.
@@ -495,6 +503,26 @@ func (w *wallet) Derive(path accounts.DerivationPath, pin bool) (accounts.Accoun
 	return account, nil
 }
 
+// DerivePublicKey returns the uncompressed public key of the account located on
+// the derivation path, without pinning the account. Devices only revealing the
+// addresses of their accounts fail with accounts.ErrNotSupported.
+func (w *wallet) DerivePublicKey(path accounts.DerivationPath) ([]byte, error) {
+	driver, ok := w.driver.(publicKeyDriver)
+	if !ok {
+		return nil, accounts.ErrNotSupported
+	}
+	w.stateLock.RLock() // Avoid device disappearing during derivation
+	defer w.stateLock.RUnlock()
+
+	if w.device == nil {
+		return nil, accounts.ErrWalletClosed
+	}
+	<-w.commsLock // Avoid concurrent hardware access
+	defer func() { w.commsLock <- struct{}{} }()
+
+	return driver.DerivePublicKey(path)
+}
+
 // SelfDerive sets a base account derivation path from which the wallet attempts
 // to discover non zero accounts and automatically add them to list of tracked
 // accounts.
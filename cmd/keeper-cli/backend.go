@@ -0,0 +1,122 @@
+// Copyright 2026 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/keeper"
+	vault "github.com/hashicorp/vault/api"
+	"github.com/urfave/cli/v2"
+)
+
+var (
+	backendFlag = &cli.StringFlag{
+		Name:  "backend",
+		Usage: "key backend: memory, keystore, vault or kms",
+		Value: "memory",
+	}
+	keystoreFlag = &cli.StringFlag{
+		Name:  "keystore",
+		Usage: "directory of the keystore backend",
+		Value: "keystore",
+	}
+	passphraseFlag = &cli.StringFlag{
+		Name:  "passwordfile",
+		Usage: "the file that contains the password of the keystore keys",
+	}
+	lightKDFFlag = &cli.BoolFlag{
+		Name:  "lightkdf",
+		Usage: "use less secure scrypt parameters for new keystore keys",
+	}
+	vaultMountFlag = &cli.StringFlag{
+		Name:  "vault.mount",
+		Usage: "mount path of the Vault transit engine, the address and token are taken from VAULT_ADDR and VAULT_TOKEN",
+		Value: "transit",
+	}
+	vaultKeyTypeFlag = &cli.StringFlag{
+		Name:  "vault.keytype",
+		Usage: "transit key type of new Vault keys, it has to sign on secp256k1",
+		Value: keeper.DefaultVaultKeyType,
+	}
+	kmsKeySpecFlag = &cli.StringFlag{
+		Name:  "kms.keyspec",
+		Usage: "key spec of new AWS KMS keys, the credentials are taken from the AWS environment",
+	}
+	jsonFlag = &cli.BoolFlag{
+		Name:  "json",
+		Usage: "output JSON instead of human-readable format",
+	}
+)
+
+// newSigner returns the signer of the backend selected by the flags. The prvIDs
+// of the memory backend are the raw private keys, it doesn't store anything.
+func newSigner(ctx *cli.Context) (keeper.SecureSigner, error) {
+	switch backend := ctx.String(backendFlag.Name); backend {
+	case "memory":
+		sec := keeper.DefaultSecureSign()
+		return &sec, nil
+
+	case "keystore":
+		passphrase, err := readPassphrase(ctx)
+		if err != nil {
+			return nil, err
+		}
+		scryptN, scryptP := keystore.StandardScryptN, keystore.StandardScryptP
+		if ctx.Bool(lightKDFFlag.Name) {
+			scryptN, scryptP = keystore.LightScryptN, keystore.LightScryptP
+		}
+		k := keeper.NewKeystoreKeeper(ctx.String(keystoreFlag.Name), scryptN, scryptP, keeper.MemoryPassphraseProvider(passphrase))
+		return keeper.NewSecureSigner(k), nil
+
+	case "vault":
+		client, err := vault.NewClient(vault.DefaultConfig())
+		if err != nil {
+			return nil, fmt.Errorf("failed to create Vault client: %v", err)
+		}
+		k := keeper.NewVaultKeeper(client, ctx.String(vaultMountFlag.Name), keeper.WithVaultKeyType(ctx.String(vaultKeyTypeFlag.Name)))
+		return keeper.NewSecureSigner(k), nil
+
+	case "kms":
+		cfg, err := config.LoadDefaultConfig(ctx.Context)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load AWS config: %v", err)
+		}
+		return keeper.NewSecureSigner(keeper.NewAWSKMSKeeper(cfg, ctx.String(kmsKeySpecFlag.Name))), nil
+
+	default:
+		return nil, fmt.Errorf("unknown backend %q, want memory, keystore, vault or kms", backend)
+	}
+}
+
+// readPassphrase reads the passphrase of the keystore keys from the file given
+// by --passwordfile.
+func readPassphrase(ctx *cli.Context) (string, error) {
+	file := ctx.String(passphraseFlag.Name)
+	if file == "" {
+		return "", fmt.Errorf("the keystore backend needs --%s", passphraseFlag.Name)
+	}
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return "", fmt.Errorf("failed to read password file '%s': %v", file, err)
+	}
+	return strings.TrimRight(string(content), "\r\n"), nil
+}
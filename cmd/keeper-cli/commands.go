@@ -0,0 +1,167 @@
+// Copyright 2026 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/urfave/cli/v2"
+)
+
+// output is the result of a command. Only the fields of the command are set.
+type output struct {
+	PrvID     string `json:"prvID,omitempty"`
+	PublicKey string `json:"publicKey,omitempty"`
+	Address   string `json:"address,omitempty"`
+	Signature string `json:"signature,omitempty"`
+}
+
+var commandGenerate = &cli.Command{
+	Name:  "generate",
+	Usage: "generate a new key",
+	Description: `
+Generate a new key in the backend and print its prvID and address.
+
+The prvID of the memory backend is the raw private key!`,
+	Action: func(ctx *cli.Context) error {
+		s, err := newSigner(ctx)
+		if err != nil {
+			return err
+		}
+		prvID, err := s.GenerateKey()
+		if err != nil {
+			return err
+		}
+		addr, err := s.GetAddress(prvID)
+		if err != nil {
+			return err
+		}
+		return printOutput(ctx, output{PrvID: hexutil.Encode(prvID), Address: addr.Hex()})
+	},
+}
+
+var commandPubkey = &cli.Command{
+	Name:      "pubkey",
+	Usage:     "print the public key of a key",
+	ArgsUsage: "<prvID-hex>",
+	Action: func(ctx *cli.Context) error {
+		prvID, err := hexArg(ctx, 0, "prvID")
+		if err != nil {
+			return err
+		}
+		s, err := newSigner(ctx)
+		if err != nil {
+			return err
+		}
+		pub, err := s.GetPublicKey(prvID)
+		if err != nil {
+			return err
+		}
+		return printOutput(ctx, output{PublicKey: hexutil.Encode(pub)})
+	},
+}
+
+var commandSign = &cli.Command{
+	Name:      "sign",
+	Usage:     "sign a 32 byte hash with a key",
+	ArgsUsage: "<data-hex> <prvID-hex>",
+	Description: `
+Sign the 32 byte hash data with the key and print the 65 byte [R || S || V]
+signature, V being 0 or 1.`,
+	Action: func(ctx *cli.Context) error {
+		data, err := hexArg(ctx, 0, "data")
+		if err != nil {
+			return err
+		}
+		if len(data) != common.HashLength {
+			return fmt.Errorf("data is %d bytes, want a %d byte hash", len(data), common.HashLength)
+		}
+		prvID, err := hexArg(ctx, 1, "prvID")
+		if err != nil {
+			return err
+		}
+		s, err := newSigner(ctx)
+		if err != nil {
+			return err
+		}
+		sig, err := s.SignHash(common.BytesToHash(data), prvID)
+		if err != nil {
+			return err
+		}
+		return printOutput(ctx, output{Signature: hexutil.Encode(sig)})
+	},
+}
+
+var commandAddress = &cli.Command{
+	Name:      "address",
+	Usage:     "print the address of a key",
+	ArgsUsage: "<prvID-hex>",
+	Action: func(ctx *cli.Context) error {
+		prvID, err := hexArg(ctx, 0, "prvID")
+		if err != nil {
+			return err
+		}
+		s, err := newSigner(ctx)
+		if err != nil {
+			return err
+		}
+		addr, err := s.GetAddress(prvID)
+		if err != nil {
+			return err
+		}
+		return printOutput(ctx, output{Address: addr.Hex()})
+	},
+}
+
+// hexArg decodes the i-th argument of the command, the hex encoded name.
+func hexArg(ctx *cli.Context, i int, name string) ([]byte, error) {
+	if ctx.NArg() <= i {
+		return nil, errors.New("missing " + name + " argument, usage: " + ctx.Command.ArgsUsage)
+	}
+	b, err := hexutil.Decode(ctx.Args().Get(i))
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %v", name, err)
+	}
+	return b, nil
+}
+
+// printOutput prints out as JSON with --json, as lines of fields otherwise.
+func printOutput(ctx *cli.Context, out output) error {
+	if ctx.Bool(jsonFlag.Name) {
+		enc, err := json.MarshalIndent(out, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(ctx.App.Writer, string(enc))
+		return nil
+	}
+	for _, field := range []struct{ name, value string }{
+		{"PrvID", out.PrvID},
+		{"Public key", out.PublicKey},
+		{"Address", out.Address},
+		{"Signature", out.Signature},
+	} {
+		if field.value != "" {
+			fmt.Fprintf(ctx.App.Writer, "%s: %s\n", field.name, field.value)
+		}
+	}
+	return nil
+}
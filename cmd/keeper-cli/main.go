@@ -0,0 +1,56 @@
+// Copyright 2026 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+// keeper-cli runs the key operations of the keeper package against one of its
+// backends, for debugging, scripts and smoke tests.
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/ethereum/go-ethereum/internal/flags"
+	"github.com/urfave/cli/v2"
+)
+
+var app *cli.App
+
+func init() {
+	app = flags.NewApp("Private key keeper tool")
+	app.Flags = []cli.Flag{
+		backendFlag,
+		keystoreFlag,
+		passphraseFlag,
+		lightKDFFlag,
+		vaultMountFlag,
+		vaultKeyTypeFlag,
+		kmsKeySpecFlag,
+		jsonFlag,
+	}
+	app.Commands = []*cli.Command{
+		commandGenerate,
+		commandPubkey,
+		commandSign,
+		commandAddress,
+	}
+}
+
+func main() {
+	if err := app.Run(os.Args); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
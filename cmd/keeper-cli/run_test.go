@@ -0,0 +1,127 @@
+// Copyright 2026 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/internal/cmdtest"
+	"github.com/ethereum/go-ethereum/internal/reexec"
+)
+
+type testKeeperCLI struct {
+	*cmdtest.TestCmd
+}
+
+// spawns keeper-cli with the given command line args.
+func runKeeperCLI(t *testing.T, args ...string) *testKeeperCLI {
+	tt := new(testKeeperCLI)
+	tt.TestCmd = cmdtest.NewTestCmd(t, tt)
+	tt.Run("keeper-cli-test", args...)
+	return tt
+}
+
+// runJSON runs keeper-cli with --json and decodes its output.
+func runJSON(t *testing.T, args ...string) output {
+	t.Helper()
+	cli := runKeeperCLI(t, append([]string{"--json"}, args...)...)
+	stdout := cli.Output()
+	cli.WaitExit()
+	if status := cli.ExitStatus(); status != 0 {
+		t.Fatalf("%v: exit status %d: %s", args, status, cli.StderrText())
+	}
+	var out output
+	if err := json.Unmarshal(stdout, &out); err != nil {
+		t.Fatalf("%v: invalid output %q: %v", args, stdout, err)
+	}
+	return out
+}
+
+func TestMain(m *testing.M) {
+	// Run the app if we've been exec'd as "keeper-cli-test" in runKeeperCLI.
+	reexec.Register("keeper-cli-test", func() {
+		if err := app.Run(os.Args); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	})
+	// check if we have been reexec'd
+	if reexec.Init() {
+		return
+	}
+	os.Exit(m.Run())
+}
+
+func TestKeeperCLI(t *testing.T) {
+	t.Parallel()
+	tmpdir := t.TempDir()
+	passfile := filepath.Join(tmpdir, "password")
+	if err := os.WriteFile(passfile, []byte("foobar\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	backends := map[string][]string{
+		"memory":   {"--backend", "memory"},
+		"keystore": {"--backend", "keystore", "--keystore", filepath.Join(tmpdir, "keystore"), "--passwordfile", passfile, "--lightkdf"},
+	}
+	for name, flags := range backends {
+		gen := runJSON(t, append(flags, "generate")...)
+		if gen.PrvID == "" || !common.IsHexAddress(gen.Address) {
+			t.Fatalf("%s: invalid generate output %+v", name, gen)
+		}
+		if addr := runJSON(t, append(flags, "address", gen.PrvID)...); addr.Address != gen.Address {
+			t.Fatalf("%s: address mismatch: have %s, want %s", name, addr.Address, gen.Address)
+		}
+		pub := runJSON(t, append(flags, "pubkey", gen.PrvID)...)
+
+		hash := crypto.Keccak256([]byte("keeper-cli"))
+		sign := runJSON(t, append(flags, "sign", hexutil.Encode(hash), gen.PrvID)...)
+		recovered, err := crypto.Ecrecover(hash, hexutil.MustDecode(sign.Signature))
+		if err != nil {
+			t.Fatalf("%s: failed to recover signer: %v", name, err)
+		}
+		if have := hexutil.Encode(recovered); have != pub.PublicKey {
+			t.Fatalf("%s: recovered key mismatch: have %s, want %s", name, have, pub.PublicKey)
+		}
+	}
+}
+
+func TestKeeperCLIErrors(t *testing.T) {
+	t.Parallel()
+	tests := [][]string{
+		{"--backend", "floppy", "generate"},
+		{"--backend", "keystore", "generate"},
+		{"sign", "0x01", "0x01"},
+		{"address"},
+		{"pubkey", "not-hex"},
+	}
+	for _, args := range tests {
+		cli := runKeeperCLI(t, args...)
+		cli.Output()
+		cli.WaitExit()
+		if cli.ExitStatus() == 0 {
+			t.Errorf("%v: exit status 0, want failure", args)
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package keeper
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sync/atomic"
+	"time"
+)
+
+// AdaptiveTimeoutOptions configures an adaptive timeout keeper. Zero fields
+// take the defaults given.
+type AdaptiveTimeoutOptions struct {
+	Multiplier float64       // times the average latency a signature may take, 4 by default
+	Headroom   time.Duration // time added to the multiplied latency
+	Alpha      float64       // weight of the latest latency in the average, 0.2 by default
+	MinTimeout time.Duration // shortest timeout, 10ms by default
+	MaxTimeout time.Duration // longest timeout and the one of the first signature, 10s by default
+}
+
+// AdaptiveTimeoutKeeper is a PrivateKeyKeeper whose timeout follows the latency
+// of its backend, as returned by NewAdaptiveTimeoutKeeper.
+type AdaptiveTimeoutKeeper interface {
+	PrivateKeyKeeper
+
+	// CurrentTimeout returns the time the next signature may take
+	CurrentTimeout() time.Duration
+}
+
+// adaptiveTimeoutKeeper is a PrivateKeyKeeper bounding its signatures by a
+// timeout derived from the latency observed so far, which is neither so tight
+// that a backend slowing down fails nor so loose that a hanging one holds its
+// callers for long.
+type adaptiveTimeoutKeeper struct {
+	inner PrivateKeyKeeperContext
+	opts  AdaptiveTimeoutOptions
+	ewma  atomic.Uint64 // float64 bits of the average latency in ns, 0 before the first signature
+}
+
+// NewAdaptiveTimeoutKeeper returns an AdaptiveTimeoutKeeper failing signatures
+// with ErrOperationTimeout once they took longer than
+// ewma*opts.Multiplier+opts.Headroom, within opts.MinTimeout and
+// opts.MaxTimeout, where ewma is the exponentially weighted moving average of
+// the latency of the past signatures. With the defaults, signatures taking 5ms
+// get a 20ms timeout, signatures slowing down to 50ms a 200ms one.
+//
+// A timed out signature counts as taking the full timeout, so the timeout
+// widens as the backend slows down instead of failing all signatures. Other
+// calls are forwarded to inner as is; wrap it with NewTimeoutKeeper to bound
+// them as well. As with NewTimeoutKeeper, a timed out inner call is left
+// running in the background until it returns.
+func NewAdaptiveTimeoutKeeper(inner PrivateKeyKeeper, opts AdaptiveTimeoutOptions) PrivateKeyKeeper {
+	if opts.Multiplier <= 0 {
+		opts.Multiplier = 4
+	}
+	if opts.Alpha <= 0 || opts.Alpha > 1 {
+		opts.Alpha = 0.2
+	}
+	if opts.MinTimeout <= 0 {
+		opts.MinTimeout = 10 * time.Millisecond
+	}
+	if opts.MaxTimeout <= 0 {
+		opts.MaxTimeout = 10 * time.Second
+	}
+	return &adaptiveTimeoutKeeper{inner: ContextKeeper(inner), opts: opts}
+}
+
+func (k *adaptiveTimeoutKeeper) CurrentTimeout() time.Duration {
+	ewma := math.Float64frombits(k.ewma.Load())
+	if ewma == 0 {
+		return k.opts.MaxTimeout
+	}
+	timeout := time.Duration(ewma*k.opts.Multiplier) + k.opts.Headroom
+	return min(max(timeout, k.opts.MinTimeout), k.opts.MaxTimeout)
+}
+
+// observe adds the latency of a signature to the moving average.
+func (k *adaptiveTimeoutKeeper) observe(latency time.Duration) {
+	for {
+		old := k.ewma.Load()
+		ewma := float64(latency)
+		if prev := math.Float64frombits(old); prev != 0 {
+			ewma = k.opts.Alpha*ewma + (1-k.opts.Alpha)*prev
+		}
+		if k.ewma.CompareAndSwap(old, math.Float64bits(ewma)) {
+			return
+		}
+	}
+}
+
+func (k *adaptiveTimeoutKeeper) GeneratePrivateKey() ([]byte, error) {
+	return k.GeneratePrivateKeyContext(context.Background())
+}
+
+func (k *adaptiveTimeoutKeeper) GeneratePrivateKeyContext(ctx context.Context) ([]byte, error) {
+	return k.inner.GeneratePrivateKeyContext(ctx)
+}
+
+func (k *adaptiveTimeoutKeeper) GetPublicKey(prvID []byte) ([]byte, error) {
+	return k.GetPublicKeyContext(context.Background(), prvID)
+}
+
+func (k *adaptiveTimeoutKeeper) GetPublicKeyContext(ctx context.Context, prvID []byte) ([]byte, error) {
+	return k.inner.GetPublicKeyContext(ctx, prvID)
+}
+
+func (k *adaptiveTimeoutKeeper) Sign(data []byte, prvID []byte) ([]byte, error) {
+	return k.SignContext(context.Background(), data, prvID)
+}
+
+func (k *adaptiveTimeoutKeeper) SignContext(ctx context.Context, data []byte, prvID []byte) (_ []byte, err error) {
+	defer wrapError(&err, "sign", prvID)
+
+	timeout := k.CurrentTimeout()
+	start := time.Now()
+	sig, err := withTimeout(ctx, timeout, func(ctx context.Context) ([]byte, error) {
+		return k.inner.SignContext(ctx, data, prvID)
+	})
+	switch {
+	case err == nil:
+		k.observe(time.Since(start))
+	case errors.Is(err, ErrOperationTimeout):
+		k.observe(timeout)
+	}
+	return sig, err
+}
+
+func (k *adaptiveTimeoutKeeper) DeletePrivateKey(prvID []byte) error {
+	return k.DeletePrivateKeyContext(context.Background(), prvID)
+}
+
+func (k *adaptiveTimeoutKeeper) DeletePrivateKeyContext(ctx context.Context, prvID []byte) error {
+	return k.inner.DeletePrivateKeyContext(ctx, prvID)
+}
+
+func (k *adaptiveTimeoutKeeper) ListPrivateKeys() ([][]byte, error) {
+	return k.ListPrivateKeysContext(context.Background())
+}
+
+func (k *adaptiveTimeoutKeeper) ListPrivateKeysContext(ctx context.Context) ([][]byte, error) {
+	return k.inner.ListPrivateKeysContext(ctx)
+}
+
+func (k *adaptiveTimeoutKeeper) ImportPrivateKey(rawKey []byte) ([]byte, error) {
+	return k.ImportPrivateKeyContext(context.Background(), rawKey)
+}
+
+func (k *adaptiveTimeoutKeeper) ImportPrivateKeyContext(ctx context.Context, rawKey []byte) ([]byte, error) {
+	return k.inner.ImportPrivateKeyContext(ctx, rawKey)
+}
+
+func (k *adaptiveTimeoutKeeper) HealthCheck(ctx context.Context) error {
+	return checkHealth(ctx, k.inner)
+}
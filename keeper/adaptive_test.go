@@ -0,0 +1,70 @@
+package keeper
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestAdaptiveTimeout(t *testing.T) {
+	k := NewAdaptiveTimeoutKeeper(new(defaultPrivateKeyKeeper), AdaptiveTimeoutOptions{}).(*adaptiveTimeoutKeeper)
+	if timeout := k.CurrentTimeout(); timeout != 10*time.Second {
+		t.Fatalf("initial timeout: have %v, want %v", timeout, 10*time.Second)
+	}
+	for i := 0; i < 20; i++ {
+		k.observe(5 * time.Millisecond)
+	}
+	if timeout := k.CurrentTimeout(); timeout != 20*time.Millisecond {
+		t.Fatalf("timeout at 5ms: have %v, want %v", timeout, 20*time.Millisecond)
+	}
+	for i := 0; i < 100; i++ {
+		k.observe(50 * time.Millisecond)
+	}
+	if timeout := k.CurrentTimeout(); timeout < 199*time.Millisecond || timeout > 200*time.Millisecond {
+		t.Fatalf("timeout at 50ms: have %v, want %v", timeout, 200*time.Millisecond)
+	}
+
+	// The timeout stays within its bounds.
+	k = NewAdaptiveTimeoutKeeper(new(defaultPrivateKeyKeeper), AdaptiveTimeoutOptions{
+		Headroom:   time.Millisecond,
+		MaxTimeout: time.Second,
+	}).(*adaptiveTimeoutKeeper)
+	k.observe(time.Microsecond)
+	if timeout := k.CurrentTimeout(); timeout != 10*time.Millisecond {
+		t.Fatalf("timeout at 1µs: have %v, want %v", timeout, 10*time.Millisecond)
+	}
+	k.observe(time.Hour)
+	if timeout := k.CurrentTimeout(); timeout != time.Second {
+		t.Fatalf("timeout at 1h: have %v, want %v", timeout, time.Second)
+	}
+}
+
+func TestAdaptiveTimeoutKeeper(t *testing.T) {
+	inner := &slowKeeper{PrivateKeyKeeper: new(defaultPrivateKeyKeeper), release: make(chan struct{})}
+	k := NewAdaptiveTimeoutKeeper(inner, AdaptiveTimeoutOptions{}).(*adaptiveTimeoutKeeper)
+	prvID, err := k.GeneratePrivateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	hash := make([]byte, 32)
+
+	k.observe(5 * time.Millisecond)
+	timeout := k.CurrentTimeout()
+	start := time.Now()
+	_, err = k.Sign(hash, prvID)
+	if elapsed := time.Since(start); elapsed < timeout || elapsed > timeout+time.Second {
+		t.Fatalf("timeout fired after %v, want %v", elapsed, timeout)
+	}
+	if !errors.Is(err, ErrOperationTimeout) {
+		t.Fatalf("hanging signature: have %v, want %v", err, ErrOperationTimeout)
+	}
+	// The timed out signature widens the timeout.
+	if widened := k.CurrentTimeout(); widened <= timeout {
+		t.Fatalf("timeout after timing out: have %v, want more than %v", widened, timeout)
+	}
+
+	close(inner.release)
+	if _, err := k.Sign(hash, prvID); err != nil {
+		t.Fatalf("failed to sign in time: %v", err)
+	}
+}
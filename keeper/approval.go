@@ -0,0 +1,100 @@
+package keeper
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// Approver approves signing requests, e.g. by asking an operator or a quorum
+// service. Notification based approvers, such as a Telegram or Slack bot, post
+// the transaction and block in Approve until it was answered or ctx is done.
+type Approver interface {
+	// Approve returns nil if tx may be signed, ErrApprovalDenied if it was
+	// turned down, or any other error if no answer could be obtained.
+	Approve(ctx context.Context, tx *types.Transaction) error
+}
+
+// NewApprovalRequiredSigner returns a SecureSigner signing transactions with
+// inner only after approver approved them. If approver fails, its error is
+// returned and the transaction is not signed. Hashes, messages, typed data,
+// permits and user operations can't be shown to the approver, they fail with
+// ErrPermissionDenied unless AllowRawSigning is given.
+func NewApprovalRequiredSigner(inner SecureSigner, approver Approver, opts ...GuardOption) SecureSigner {
+	return newGuardSigner(inner, func(ctx context.Context, tx *types.Transaction, signer types.Signer) error {
+		return approver.Approve(ctx, tx)
+	}, opts)
+}
+
+type autoApprover struct{}
+
+// AutoApprover returns an Approver approving every transaction. It is mostly
+// useful for testing.
+func AutoApprover() Approver {
+	return autoApprover{}
+}
+
+func (autoApprover) Approve(context.Context, *types.Transaction) error {
+	return nil
+}
+
+// promptApprover is an Approver asking an operator on a terminal.
+type promptApprover struct {
+	out io.Writer
+
+	lock  sync.Mutex // serializes the prompts
+	lines chan string
+}
+
+// PromptApprover returns an Approver writing the details of every transaction
+// to out and approving it if the next line read from in is "yes", e.g. with
+// os.Stdin and os.Stdout. Concurrent requests are prompted one at a time.
+func PromptApprover(in io.Reader, out io.Writer) Approver {
+	a := &promptApprover{out: out, lines: make(chan string)}
+	go a.readLoop(in)
+	return a
+}
+
+// readLoop hands out the lines read from in until it fails. Reads can't be
+// interrupted, so they are made here instead of in Approve.
+func (a *promptApprover) readLoop(in io.Reader) {
+	defer close(a.lines)
+
+	scanner := bufio.NewScanner(in)
+	for scanner.Scan() {
+		a.lines <- scanner.Text()
+	}
+}
+
+func (a *promptApprover) Approve(ctx context.Context, tx *types.Transaction) error {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	to := "contract creation"
+	if tx.To() != nil {
+		to = tx.To().Hex()
+	}
+	fmt.Fprintf(a.out, "Transaction %v\n", tx.Hash())
+	fmt.Fprintf(a.out, "  chain id: %v\n  nonce:    %d\n  to:       %s\n  value:    %v wei\n", tx.ChainId(), tx.Nonce(), to, tx.Value())
+	fmt.Fprintf(a.out, "  gas:      %d at %v wei (tip %v wei)\n  data:     %d bytes\n", tx.Gas(), tx.GasFeeCap(), tx.GasTipCap(), len(tx.Data()))
+	fmt.Fprint(a.out, "Sign transaction? [yes/no]: ")
+
+	select {
+	case line, ok := <-a.lines:
+		if !ok {
+			return fmt.Errorf("%w: no answer", ErrApprovalDenied)
+		}
+		if strings.TrimSpace(line) != "yes" {
+			return ErrApprovalDenied
+		}
+		return nil
+	case <-ctx.Done():
+		fmt.Fprintln(a.out)
+		return ctx.Err()
+	}
+}
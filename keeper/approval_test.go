@@ -0,0 +1,82 @@
+package keeper
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// recordingApprover is an Approver returning err for every transaction, after
+// recording it.
+type recordingApprover struct {
+	err error
+	txs []*types.Transaction
+}
+
+func (a *recordingApprover) Approve(ctx context.Context, tx *types.Transaction) error {
+	a.txs = append(a.txs, tx)
+	return a.err
+}
+
+func TestApprovalRequiredSigner(t *testing.T) {
+	signer := types.LatestSignerForChainID(big.NewInt(1))
+	prvID, _ := defaultKeeper.GeneratePrivateKey()
+
+	if _, err := NewApprovalRequiredSigner(NewSecureSigner(defaultKeeper), AutoApprover()).Sign(newTestTx(), signer, prvID); err != nil {
+		t.Fatalf("failed to sign approved transaction: %v", err)
+	}
+	for _, want := range []error{ErrApprovalDenied, ErrBackendUnavailable} {
+		approver := &recordingApprover{err: want}
+		s := NewApprovalRequiredSigner(NewSecureSigner(defaultKeeper), approver)
+		tx := newTestTx()
+		if signed, err := s.Sign(tx, signer, prvID); !errors.Is(err, want) || signed != nil {
+			t.Fatalf("approval failing with %v: have (%v, %v)", want, signed, err)
+		}
+		if len(approver.txs) != 1 || approver.txs[0] != tx {
+			t.Fatalf("approval failing with %v: approver asked for %d transactions, want 1", want, len(approver.txs))
+		}
+	}
+}
+
+func TestApprovalRequiredSignerRawSigning(t *testing.T) {
+	checkRawSigningGuarded(t, func(inner SecureSigner, opts ...GuardOption) SecureSigner {
+		return NewApprovalRequiredSigner(inner, AutoApprover(), opts...)
+	})
+}
+
+func TestPromptApprover(t *testing.T) {
+	var out bytes.Buffer
+	a := PromptApprover(strings.NewReader("yes\nno\n"), &out)
+	tx := newTestTx()
+
+	if err := a.Approve(context.Background(), tx); err != nil {
+		t.Fatalf("answered yes: have %v, want approval", err)
+	}
+	if !strings.Contains(out.String(), tx.Hash().Hex()) || !strings.Contains(out.String(), tx.To().Hex()) {
+		t.Fatalf("prompt lacks transaction details:\n%s", out.String())
+	}
+	for _, answer := range []string{"no", "end of input"} {
+		if err := a.Approve(context.Background(), tx); !errors.Is(err, ErrApprovalDenied) {
+			t.Fatalf("answered %s: have %v, want %v", answer, err, ErrApprovalDenied)
+		}
+	}
+}
+
+func TestPromptApproverCancel(t *testing.T) {
+	in, w := io.Pipe()
+	defer w.Close()
+	a := PromptApprover(in, io.Discard)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := a.Approve(ctx, newTestTx()); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("unanswered prompt: have %v, want %v", err, context.DeadlineExceeded)
+	}
+}
@@ -0,0 +1,217 @@
+package keeper
+
+import (
+	"container/heap"
+	"context"
+	"runtime"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// DefaultAsyncQueueSize is the number of requests an AsyncSigner queues unless
+// set by WithQueueSize.
+const DefaultAsyncQueueSize = 256
+
+// AsyncSignResult is the outcome of an AsyncSign request.
+type AsyncSignResult struct {
+	Tx  *types.Transaction
+	Err error
+}
+
+// Priority orders the queued requests of an AsyncSigner, requests of a higher
+// priority are signed first.
+type Priority int
+
+const (
+	PriorityLow Priority = iota - 1
+	PriorityNormal
+	PriorityHigh
+)
+
+// AsyncOption configures an AsyncSigner.
+type AsyncOption func(*AsyncSigner)
+
+// WithWorkers sets the number of workers signing the requests of an
+// AsyncSigner, and so the number of requests its SecureSigner handles at a
+// time. It defaults to the number of CPUs.
+func WithWorkers(n int) AsyncOption {
+	return func(s *AsyncSigner) {
+		s.workers = max(n, 1)
+	}
+}
+
+// WithQueueSize sets the number of requests an AsyncSigner queues before new
+// requests block. It defaults to DefaultAsyncQueueSize.
+func WithQueueSize(n int) AsyncOption {
+	return func(s *AsyncSigner) {
+		s.queueSize = max(n, 1)
+	}
+}
+
+// AsyncSigner is a SecureSigner signing transactions in the background on a
+// fixed pool of workers, so that callers of keepers blocking on slow hardware
+// wait on a channel instead of a call. Queued requests are signed by priority,
+// in the order they were made within a priority. Its Close has to be called to
+// stop the workers.
+type AsyncSigner struct {
+	SecureSigner
+	workers   int
+	queueSize int
+	slots     chan struct{} // one per queued request
+	wg        sync.WaitGroup
+
+	lock   sync.Mutex
+	cond   *sync.Cond // signalled on new requests and on close
+	queue  asyncQueue
+	seq    uint64
+	closed bool
+}
+
+// asyncRequest is a queued AsyncSign call. Its result is delivered once, either
+// by the worker or, if the context is done first, by the context.
+type asyncRequest struct {
+	ctx      context.Context
+	tx       *types.Transaction
+	signer   types.Signer
+	prvID    []byte
+	priority Priority
+	seq      uint64 // order of the request within its priority
+
+	out  chan AsyncSignResult
+	once sync.Once
+	stop func() bool
+}
+
+func (r *asyncRequest) deliver(res AsyncSignResult) {
+	r.once.Do(func() {
+		r.out <- res
+		close(r.out)
+	})
+}
+
+// asyncQueue is a heap of requests, highest priority and earliest first.
+type asyncQueue []*asyncRequest
+
+func (q asyncQueue) Len() int      { return len(q) }
+func (q asyncQueue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+
+func (q asyncQueue) Less(i, j int) bool {
+	if q[i].priority != q[j].priority {
+		return q[i].priority > q[j].priority
+	}
+	return q[i].seq < q[j].seq
+}
+
+func (q *asyncQueue) Push(x any) { *q = append(*q, x.(*asyncRequest)) }
+
+func (q *asyncQueue) Pop() any {
+	old := *q
+	r := old[len(old)-1]
+	old[len(old)-1] = nil
+	*q = old[:len(old)-1]
+	return r
+}
+
+// NewAsyncSigner returns an AsyncSigner signing with inner.
+func NewAsyncSigner(inner SecureSigner, opts ...AsyncOption) *AsyncSigner {
+	s := &AsyncSigner{SecureSigner: inner, workers: runtime.NumCPU(), queueSize: DefaultAsyncQueueSize}
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.cond = sync.NewCond(&s.lock)
+	s.slots = make(chan struct{}, s.queueSize)
+	s.wg.Add(s.workers)
+	for i := 0; i < s.workers; i++ {
+		go s.work()
+	}
+	return s
+}
+
+// AsyncSign queues tx to be signed with signer by the key prvID at normal
+// priority, see SignWithPriority.
+func (s *AsyncSigner) AsyncSign(ctx context.Context, tx *types.Transaction, signer types.Signer, prvID []byte) <-chan AsyncSignResult {
+	return s.SignWithPriority(ctx, tx, signer, prvID, PriorityNormal)
+}
+
+// SignWithPriority queues tx to be signed with signer by the key prvID ahead of
+// the queued requests of a lower priority, and returns the channel receiving
+// the result, closed after it. It blocks while the queue is full. If ctx is
+// done before the transaction is signed, the result carries the error of ctx
+// right away and the signed transaction is discarded.
+func (s *AsyncSigner) SignWithPriority(ctx context.Context, tx *types.Transaction, signer types.Signer, prvID []byte, p Priority) <-chan AsyncSignResult {
+	r := &asyncRequest{ctx: ctx, tx: tx, signer: signer, prvID: prvID, priority: p, out: make(chan AsyncSignResult, 1)}
+	r.stop = context.AfterFunc(ctx, func() {
+		r.deliver(AsyncSignResult{Err: &KeeperError{Op: "sign transaction", KeyID: prvID, Err: ctx.Err()}})
+	})
+	closed := func() <-chan AsyncSignResult {
+		r.stop()
+		r.deliver(AsyncSignResult{Err: &KeeperError{Op: "sign transaction", KeyID: prvID, Err: ErrSignerClosed}})
+		return r.out
+	}
+
+	s.lock.Lock()
+	isClosed := s.closed
+	s.lock.Unlock()
+	if isClosed {
+		return closed()
+	}
+	select {
+	case s.slots <- struct{}{}:
+	case <-ctx.Done():
+		// The result is delivered by the context.
+		return r.out
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.closed {
+		<-s.slots
+		return closed()
+	}
+	r.seq = s.seq
+	s.seq++
+	heap.Push(&s.queue, r)
+	s.cond.Signal()
+	return r.out
+}
+
+// Close stops the workers once the queued requests are signed. Requests made
+// after Close fail with ErrSignerClosed.
+func (s *AsyncSigner) Close() {
+	s.lock.Lock()
+	s.closed = true
+	s.cond.Broadcast()
+	s.lock.Unlock()
+	s.wg.Wait()
+}
+
+// next returns the queued request to sign next, or nil once the signer is
+// closed and the queue is empty.
+func (s *AsyncSigner) next() *asyncRequest {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for len(s.queue) == 0 && !s.closed {
+		s.cond.Wait()
+	}
+	if len(s.queue) == 0 {
+		return nil
+	}
+	r := heap.Pop(&s.queue).(*asyncRequest)
+	<-s.slots
+	return r
+}
+
+func (s *AsyncSigner) work() {
+	defer s.wg.Done()
+	for r := s.next(); r != nil; r = s.next() {
+		// Requests abandoned while queued are not signed at all.
+		if r.ctx.Err() != nil {
+			continue
+		}
+		signed, err := signerContext(s.SecureSigner).SignContext(r.ctx, r.tx, r.signer, r.prvID)
+		if r.stop() {
+			r.deliver(AsyncSignResult{Tx: signed, Err: err})
+		}
+	}
+}
@@ -0,0 +1,227 @@
+package keeper
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// blockingSigner is a SecureSigner whose signing blocks until release is
+// closed, like a busy HSM.
+type blockingSigner struct {
+	*SecureSign
+	release chan struct{}
+	active  atomic.Int32
+	peak    atomic.Int32
+
+	lock   sync.Mutex
+	nonces []uint64 // nonces of the signed transactions, in signing order
+}
+
+func (s *blockingSigner) SignContext(ctx context.Context, tx *types.Transaction, signer types.Signer, prvID []byte) (*types.Transaction, error) {
+	n := s.active.Add(1)
+	defer s.active.Add(-1)
+	for {
+		peak := s.peak.Load()
+		if n <= peak || s.peak.CompareAndSwap(peak, n) {
+			break
+		}
+	}
+	<-s.release
+	s.lock.Lock()
+	s.nonces = append(s.nonces, tx.Nonce())
+	s.lock.Unlock()
+	return s.SecureSign.SignContext(ctx, tx, signer, prvID)
+}
+
+func TestAsyncSign(t *testing.T) {
+	inner := &blockingSigner{SecureSign: NewSecureSigner(new(defaultPrivateKeyKeeper)).(*SecureSign), release: make(chan struct{})}
+	s := NewAsyncSigner(inner, WithWorkers(3))
+	defer s.Close()
+	prvID, err := s.GenerateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	addr, _ := s.GetAddress(prvID)
+	signer := types.NewEIP155Signer(big.NewInt(1))
+
+	// Three requests keep the workers busy and three wait in the queue.
+	results := make([]<-chan AsyncSignResult, 10)
+	for i := 0; i < 6; i++ {
+		results[i] = s.AsyncSign(context.Background(), newTestTx(), signer, prvID)
+	}
+	for inner.active.Load() < 3 {
+		time.Sleep(time.Millisecond)
+	}
+	close(inner.release)
+	for i := 6; i < len(results); i++ {
+		results[i] = s.AsyncSign(context.Background(), newTestTx(), signer, prvID)
+	}
+	for i, ch := range results {
+		res, ok := <-ch
+		if !ok || res.Err != nil {
+			t.Fatalf("request %d: have (%v, %v), want signed tx", i, ok, res.Err)
+		}
+		if from, err := types.Sender(signer, res.Tx); err != nil || from != addr {
+			t.Fatalf("request %d: sender mismatch: have (%x, %v), want %x", i, from, err, addr)
+		}
+		if _, ok := <-ch; ok {
+			t.Fatalf("request %d: second result delivered", i)
+		}
+	}
+	if peak := inner.peak.Load(); peak > 3 {
+		t.Fatalf("concurrent signatures: have %d, want at most 3", peak)
+	}
+}
+
+func TestAsyncSignCancel(t *testing.T) {
+	inner := &blockingSigner{SecureSign: NewSecureSigner(new(defaultPrivateKeyKeeper)).(*SecureSign), release: make(chan struct{})}
+	s := NewAsyncSigner(inner, WithWorkers(1))
+	prvID, _ := s.GenerateKey()
+	signer := types.NewEIP155Signer(big.NewInt(1))
+
+	// The first request is taken by the worker, which blocks on it. The second
+	// one waits in the queue.
+	ctx, cancel := context.WithCancel(context.Background())
+	busy := s.AsyncSign(ctx, newTestTx(), signer, prvID)
+	for inner.active.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	queuedCtx, cancelQueued := context.WithCancel(context.Background())
+	queued := s.AsyncSign(queuedCtx, newTestTx(), signer, prvID)
+
+	cancel()
+	cancelQueued()
+	for name, ch := range map[string]<-chan AsyncSignResult{"busy": busy, "queued": queued} {
+		select {
+		case res := <-ch:
+			if !errors.Is(res.Err, context.Canceled) || res.Tx != nil {
+				t.Fatalf("%s request: have (%v, %v), want %v", name, res.Tx, res.Err, context.Canceled)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("%s request: no result after cancellation", name)
+		}
+	}
+	// The worker discards the signature of the cancelled request, and skips the
+	// queued one.
+	close(inner.release)
+	s.Close()
+	if _, ok := <-busy; ok {
+		t.Fatal("result delivered after cancellation")
+	}
+	if res := <-s.AsyncSign(context.Background(), newTestTx(), signer, prvID); !errors.Is(res.Err, ErrSignerClosed) {
+		t.Fatalf("request after close: have %v, want %v", res.Err, ErrSignerClosed)
+	}
+}
+
+func TestSignWithPriority(t *testing.T) {
+	inner := &blockingSigner{SecureSign: NewSecureSigner(new(defaultPrivateKeyKeeper)).(*SecureSign), release: make(chan struct{})}
+	s := NewAsyncSigner(inner, WithWorkers(1))
+	defer s.Close()
+	prvID, _ := s.GenerateKey()
+	signer := types.NewEIP155Signer(big.NewInt(1))
+	txs := newTestBatch(100)
+
+	// The worker is held up by the first request while the others queue up:
+	// normal ones first, then a low and a high priority one.
+	results := []<-chan AsyncSignResult{s.AsyncSign(context.Background(), txs[0], signer, prvID)}
+	for inner.active.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	for i := 1; i <= 20; i++ {
+		results = append(results, s.SignWithPriority(context.Background(), txs[i], signer, prvID, PriorityNormal))
+	}
+	results = append(results, s.SignWithPriority(context.Background(), txs[98], signer, prvID, PriorityLow))
+	results = append(results, s.SignWithPriority(context.Background(), txs[99], signer, prvID, PriorityHigh))
+	close(inner.release)
+	for i, ch := range results {
+		if res := <-ch; res.Err != nil {
+			t.Fatalf("request %d: %v", i, res.Err)
+		}
+	}
+	want := []uint64{0, 99}
+	for i := uint64(1); i <= 20; i++ {
+		want = append(want, i)
+	}
+	want = append(want, 98)
+	if !slices.Equal(inner.nonces, want) {
+		t.Fatalf("signing order: have %v, want %v", inner.nonces, want)
+	}
+}
+
+func TestAsyncSignQueueFull(t *testing.T) {
+	inner := &blockingSigner{SecureSign: NewSecureSigner(new(defaultPrivateKeyKeeper)).(*SecureSign), release: make(chan struct{})}
+	s := NewAsyncSigner(inner, WithWorkers(1), WithQueueSize(1))
+	defer s.Close()
+	prvID, _ := s.GenerateKey()
+	signer := types.NewEIP155Signer(big.NewInt(1))
+
+	busy := s.AsyncSign(context.Background(), newTestTx(), signer, prvID)
+	for inner.active.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	queued := s.AsyncSign(context.Background(), newTestTx(), signer, prvID)
+
+	// With the queue full, requests block until their context is done.
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if res := <-s.SignWithPriority(ctx, newTestTx(), signer, prvID, PriorityHigh); !errors.Is(res.Err, context.DeadlineExceeded) {
+		t.Fatalf("request to full queue: have %v, want %v", res.Err, context.DeadlineExceeded)
+	}
+	close(inner.release)
+	for _, ch := range []<-chan AsyncSignResult{busy, queued} {
+		if res := <-ch; res.Err != nil {
+			t.Fatalf("failed to sign: %v", res.Err)
+		}
+	}
+}
+
+func BenchmarkAsyncSign(b *testing.B) {
+	signer := types.NewEIP155Signer(big.NewInt(1))
+	sec := NewSecureSigner(new(defaultPrivateKeyKeeper))
+	prvID, _ := sec.GenerateKey()
+
+	b.Run("sync", func(b *testing.B) {
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				if _, err := sec.Sign(newTestTx(), signer, prvID); err != nil {
+					b.Error(err)
+				}
+			}
+		})
+	})
+	b.Run("async", func(b *testing.B) {
+		s := NewAsyncSigner(sec)
+		defer s.Close()
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				if res := <-s.AsyncSign(context.Background(), newTestTx(), signer, prvID); res.Err != nil {
+					b.Error(res.Err)
+				}
+			}
+		})
+	})
+	b.Run("async/pipelined", func(b *testing.B) {
+		s := NewAsyncSigner(sec)
+		defer s.Close()
+		var wg sync.WaitGroup
+		for i := 0; i < b.N; i++ {
+			ch := s.AsyncSign(context.Background(), newTestTx(), signer, prvID)
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if res := <-ch; res.Err != nil {
+					b.Error(res.Err)
+				}
+			}()
+		}
+		wg.Wait()
+	})
+}
@@ -0,0 +1,339 @@
+package keeper
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+)
+
+// auditDataPrefix is the number of leading bytes of the signed data recorded
+// in the audit log of a keeper.
+const auditDataPrefix = 8
+
+// auditRecord is a line of the signing audit log.
+type auditRecord struct {
+	Timestamp string          `json:"timestamp"` // start of the call, RFC 3339 with nanoseconds
+	Operation string          `json:"operation"`
+	KeyID     string          `json:"key_id"`         // hex Keccak-256 hash of the prvID
+	Data      hexutil.Bytes   `json:"data,omitempty"` // leading bytes of the signed data
+	TxHash    *common.Hash    `json:"tx_hash,omitempty"`
+	ChainID   *hexutil.Big    `json:"chain_id,omitempty"`
+	Nonce     *uint64         `json:"nonce,omitempty"`
+	To        *common.Address `json:"to,omitempty"`
+	Value     *hexutil.Big    `json:"value,omitempty"`
+	Error     string          `json:"error,omitempty"`
+	Duration  int64           `json:"duration_ns"`
+}
+
+// auditLog writes audit records as JSON lines.
+type auditLog struct {
+	lock sync.Mutex
+	w    io.Writer
+}
+
+// write writes rec as a single line, finishing it with the duration of the
+// call started at start.
+func (l *auditLog) write(rec *auditRecord, start time.Time, err error) error {
+	rec.Timestamp = start.UTC().Format(time.RFC3339Nano)
+	rec.Duration = time.Since(start).Nanoseconds()
+	if err != nil {
+		rec.Error = err.Error()
+	}
+	line, jsonErr := json.Marshal(rec)
+	if jsonErr != nil {
+		return jsonErr
+	}
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if _, err := l.w.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write audit log: %w", err)
+	}
+	return nil
+}
+
+// auditKeyID identifies prvID in the audit log. The prvID itself is not logged,
+// as for some keepers, like the default one, it is the private key.
+func auditKeyID(prvID []byte) string {
+	return hex.EncodeToString(crypto.Keccak256(prvID))
+}
+
+// auditedKeeper is a PrivateKeyKeeper logging every signature of its inner
+// keeper. Signatures that could not be logged are not handed out.
+type auditedKeeper struct {
+	inner PrivateKeyKeeperContext
+	log   *auditLog
+}
+
+// NewAuditedKeeper returns a PrivateKeyKeeper writing a JSON line to w for every
+// Sign call to inner, failed ones included. The lines record the time, key,
+// leading data bytes, error and duration of the call, w should be append-only
+// storage. If a line can't be written, the signature is withheld and Sign
+// fails.
+func NewAuditedKeeper(inner PrivateKeyKeeper, w io.Writer) PrivateKeyKeeper {
+	return &auditedKeeper{inner: ContextKeeper(inner), log: &auditLog{w: w}}
+}
+
+func (k *auditedKeeper) GeneratePrivateKey() ([]byte, error) {
+	return k.GeneratePrivateKeyContext(context.Background())
+}
+
+func (k *auditedKeeper) GeneratePrivateKeyContext(ctx context.Context) ([]byte, error) {
+	return k.inner.GeneratePrivateKeyContext(ctx)
+}
+
+func (k *auditedKeeper) GetPublicKey(prvID []byte) ([]byte, error) {
+	return k.GetPublicKeyContext(context.Background(), prvID)
+}
+
+func (k *auditedKeeper) GetPublicKeyContext(ctx context.Context, prvID []byte) ([]byte, error) {
+	return k.inner.GetPublicKeyContext(ctx, prvID)
+}
+
+func (k *auditedKeeper) Sign(data []byte, prvID []byte) ([]byte, error) {
+	return k.SignContext(context.Background(), data, prvID)
+}
+
+func (k *auditedKeeper) SignContext(ctx context.Context, data []byte, prvID []byte) (_ []byte, err error) {
+	defer wrapError(&err, "sign", prvID)
+
+	start := time.Now()
+	sig, err := k.inner.SignContext(ctx, data, prvID)
+
+	rec := &auditRecord{Operation: "sign", KeyID: auditKeyID(prvID), Data: data[:min(len(data), auditDataPrefix)]}
+	if logErr := k.log.write(rec, start, err); logErr != nil {
+		return nil, logErr
+	}
+	return sig, err
+}
+
+func (k *auditedKeeper) DeletePrivateKey(prvID []byte) error {
+	return k.DeletePrivateKeyContext(context.Background(), prvID)
+}
+
+func (k *auditedKeeper) DeletePrivateKeyContext(ctx context.Context, prvID []byte) error {
+	return k.inner.DeletePrivateKeyContext(ctx, prvID)
+}
+
+func (k *auditedKeeper) ListPrivateKeys() ([][]byte, error) {
+	return k.ListPrivateKeysContext(context.Background())
+}
+
+func (k *auditedKeeper) ListPrivateKeysContext(ctx context.Context) ([][]byte, error) {
+	return k.inner.ListPrivateKeysContext(ctx)
+}
+
+func (k *auditedKeeper) ImportPrivateKey(rawKey []byte) ([]byte, error) {
+	return k.ImportPrivateKeyContext(context.Background(), rawKey)
+}
+
+func (k *auditedKeeper) ImportPrivateKeyContext(ctx context.Context, rawKey []byte) ([]byte, error) {
+	return k.inner.ImportPrivateKeyContext(ctx, rawKey)
+}
+
+func (k *auditedKeeper) HealthCheck(ctx context.Context) error {
+	return checkHealth(ctx, k.inner)
+}
+
+// auditedSigner is a SecureSigner logging every signature made through it.
+// The transaction helpers build their transactions and sign them through the
+// Sign of the audited signer, so every transaction is logged the same way.
+type auditedSigner struct {
+	SecureSigner
+	log *auditLog
+}
+
+// NewAuditedSecureSigner returns a SecureSigner writing a JSON line to w for
+// every transaction, typed data and personal message signed by inner, failed
+// ones included. Transaction lines record the transaction hash, chain ID,
+// nonce, recipient and value. If a line can't be written, the signature is
+// withheld and the call fails.
+func NewAuditedSecureSigner(inner SecureSigner, w io.Writer) SecureSigner {
+	return &auditedSigner{SecureSigner: inner, log: &auditLog{w: w}}
+}
+
+// txRecord returns the audit record of signing tx for the chain of signer.
+func txRecord(tx *types.Transaction, signer types.Signer, prvID []byte) *auditRecord {
+	nonce := tx.Nonce()
+	return &auditRecord{
+		Operation: "sign_transaction",
+		KeyID:     auditKeyID(prvID),
+		ChainID:   (*hexutil.Big)(signer.ChainID()),
+		Nonce:     &nonce,
+		To:        tx.To(),
+		Value:     (*hexutil.Big)(tx.Value()),
+	}
+}
+
+func (s *auditedSigner) GenerateKeyContext(ctx context.Context) ([]byte, error) {
+	return signerContext(s.SecureSigner).GenerateKeyContext(ctx)
+}
+
+func (s *auditedSigner) GetPublicKeyContext(ctx context.Context, prvID []byte) ([]byte, error) {
+	return signerContext(s.SecureSigner).GetPublicKeyContext(ctx, prvID)
+}
+
+func (s *auditedSigner) Sign(tx *types.Transaction, signer types.Signer, prvID []byte) (*types.Transaction, error) {
+	return s.SignContext(context.Background(), tx, signer, prvID)
+}
+
+func (s *auditedSigner) SignContext(ctx context.Context, tx *types.Transaction, signer types.Signer, prvID []byte) (_ *types.Transaction, err error) {
+	defer wrapError(&err, "sign transaction", prvID)
+
+	start := time.Now()
+	signed, err := signerContext(s.SecureSigner).SignContext(ctx, tx, signer, prvID)
+
+	rec := txRecord(tx, signer, prvID)
+	if err == nil {
+		hash := signed.Hash()
+		rec.TxHash = &hash
+	}
+	if logErr := s.log.write(rec, start, err); logErr != nil {
+		return nil, logErr
+	}
+	return signed, err
+}
+
+func (s *auditedSigner) SignForChain(chainID *big.Int, tx *types.Transaction, prvID []byte) (*types.Transaction, error) {
+	return signForChain(s, chainID, tx, prvID)
+}
+
+func (s *auditedSigner) SignTransactionJSON(tx *types.Transaction, signer types.Signer, prvID []byte) (json.RawMessage, error) {
+	return signTransactionJSON(s, tx, signer, prvID)
+}
+
+func (s *auditedSigner) SignAndEncode(tx *types.Transaction, signer types.Signer, prvID []byte) ([]byte, error) {
+	return signAndEncode(s, tx, signer, prvID)
+}
+
+func (s *auditedSigner) SignAndEncodeHex(tx *types.Transaction, signer types.Signer, prvID []byte) (string, error) {
+	return signAndEncodeHex(s, tx, signer, prvID)
+}
+
+func (s *auditedSigner) SignDynamicFeeTx(chainID *big.Int, nonce uint64, to common.Address, value *big.Int, gasLimit uint64, maxFeePerGas, maxPriorityFeePerGas *big.Int, data []byte, prvID []byte) (*types.Transaction, error) {
+	tx := newDynamicFeeTx(chainID, nonce, to, value, gasLimit, maxFeePerGas, maxPriorityFeePerGas, data)
+	return s.Sign(tx, types.LatestSignerForChainID(chainID), prvID)
+}
+
+func (s *auditedSigner) SignAccessListTx(chainID *big.Int, nonce uint64, to common.Address, value *big.Int, gasLimit uint64, gasPrice *big.Int, accessList types.AccessList, data []byte, prvID []byte) (*types.Transaction, error) {
+	tx := newAccessListTx(chainID, nonce, to, value, gasLimit, gasPrice, accessList, data)
+	return s.Sign(tx, types.NewEIP2930Signer(chainID), prvID)
+}
+
+func (s *auditedSigner) SignContractCall(chainID *big.Int, contract common.Address, contractABI abi.ABI, method string, args []interface{}, nonce uint64, gasLimit uint64, gasPrice *big.Int, prvID []byte) (_ *types.Transaction, err error) {
+	defer wrapError(&err, "sign transaction", prvID)
+
+	tx, err := newContractCallTx(chainID, contract, contractABI, method, args, nonce, gasLimit, gasPrice)
+	if err != nil {
+		return nil, err
+	}
+	return s.Sign(tx, types.LatestSignerForChainID(chainID), prvID)
+}
+
+func (s *auditedSigner) SimulateAndSign(ctx context.Context, tx *types.Transaction, signer types.Signer, client ethereum.ContractCaller, prvID []byte) (*types.Transaction, error) {
+	return simulateAndSign(ctx, s, tx, signer, client, prvID)
+}
+
+func (s *auditedSigner) SignBlobTx(chainID *big.Int, blobTx *types.BlobTx, prvID []byte) (_ *types.Transaction, err error) {
+	defer wrapError(&err, "sign transaction", prvID)
+
+	start := time.Now()
+	tx, err := newBlobTx(chainID, blobTx)
+	if err != nil {
+		// The transaction never reached the signer, log what it would have been.
+		rec := txRecord(types.NewTx(blobTx), types.LatestSignerForChainID(chainID), prvID)
+		if logErr := s.log.write(rec, start, err); logErr != nil {
+			return nil, logErr
+		}
+		return nil, err
+	}
+	return s.Sign(tx, types.LatestSignerForChainID(chainID), prvID)
+}
+
+func (s *auditedSigner) SignBatch(txs []*types.Transaction, signer types.Signer, prvID []byte) ([]*types.Transaction, error) {
+	return signBatch(txs, func(tx *types.Transaction) (*types.Transaction, error) {
+		return s.Sign(tx, signer, prvID)
+	})
+}
+
+func (s *auditedSigner) SignBatchParallel(txs []*types.Transaction, signer types.Signer, prvID []byte) ([]*types.Transaction, error) {
+	return signBatchParallel(txs, func(tx *types.Transaction) (*types.Transaction, error) {
+		return s.Sign(tx, signer, prvID)
+	})
+}
+
+func (s *auditedSigner) SignTypedData(typedData apitypes.TypedData, prvID []byte) (_ []byte, err error) {
+	defer wrapError(&err, "sign typed data", prvID)
+
+	start := time.Now()
+	sig, err := s.SecureSigner.SignTypedData(typedData, prvID)
+
+	rec := &auditRecord{Operation: "sign_typed_data", KeyID: auditKeyID(prvID)}
+	if logErr := s.log.write(rec, start, err); logErr != nil {
+		return nil, logErr
+	}
+	return sig, err
+}
+
+// SignPermit signs the permit through the SignTypedData of the audited signer,
+// it is logged as typed data.
+func (s *auditedSigner) SignPermit(chainID *big.Int, tokenAddr common.Address, tokenName, tokenVersion string, ownerAddr, spenderAddr common.Address, value, nonce *big.Int, deadline int64, prvID []byte) (uint8, [32]byte, [32]byte, error) {
+	return signPermit(s, chainID, tokenAddr, tokenName, tokenVersion, ownerAddr, spenderAddr, value, nonce, deadline, prvID)
+}
+
+func (s *auditedSigner) SignUserOperation(chainID *big.Int, entryPoint common.Address, op UserOperation, prvID []byte) (_ []byte, err error) {
+	defer wrapError(&err, "sign user operation", prvID)
+
+	start := time.Now()
+	sig, err := s.SecureSigner.SignUserOperation(chainID, entryPoint, op, prvID)
+
+	hash := UserOperationHash(chainID, entryPoint, op)
+	rec := &auditRecord{Operation: "sign_user_operation", KeyID: auditKeyID(prvID), Data: hash[:auditDataPrefix], ChainID: (*hexutil.Big)(chainID), To: &entryPoint}
+	if logErr := s.log.write(rec, start, err); logErr != nil {
+		return nil, logErr
+	}
+	return sig, err
+}
+
+func (s *auditedSigner) SignHash(hash common.Hash, prvID []byte) (_ []byte, err error) {
+	defer wrapError(&err, "sign hash", prvID)
+
+	start := time.Now()
+	sig, err := s.SecureSigner.SignHash(hash, prvID)
+
+	rec := &auditRecord{Operation: "sign_hash", KeyID: auditKeyID(prvID), Data: hash[:auditDataPrefix]}
+	if logErr := s.log.write(rec, start, err); logErr != nil {
+		return nil, logErr
+	}
+	return sig, err
+}
+
+func (s *auditedSigner) SignHashBytes(data []byte, prvID []byte) ([]byte, error) {
+	return s.SignHash(crypto.Keccak256Hash(data), prvID)
+}
+
+func (s *auditedSigner) SignPersonalMessage(message []byte, prvID []byte) (_ []byte, err error) {
+	defer wrapError(&err, "sign message", prvID)
+
+	start := time.Now()
+	sig, err := s.SecureSigner.SignPersonalMessage(message, prvID)
+
+	rec := &auditRecord{Operation: "sign_personal_message", KeyID: auditKeyID(prvID)}
+	if logErr := s.log.write(rec, start, err); logErr != nil {
+		return nil, logErr
+	}
+	return sig, err
+}
@@ -0,0 +1,163 @@
+package keeper
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// auditLines parses the JSON lines written to buf.
+func auditLines(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+
+	var lines []map[string]any
+	scanner := bufio.NewScanner(buf)
+	for scanner.Scan() {
+		var line map[string]any
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			t.Fatalf("invalid audit line %q: %v", scanner.Text(), err)
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// checkAuditLine checks the fields every audit line carries.
+func checkAuditLine(t *testing.T, line map[string]any, op string, prvID []byte, failed bool) {
+	t.Helper()
+
+	stamp, ok := line["timestamp"].(string)
+	if !ok {
+		t.Fatalf("timestamp: have %T, want string", line["timestamp"])
+	}
+	if _, err := time.Parse(time.RFC3339Nano, stamp); err != nil {
+		t.Errorf("timestamp %q not RFC 3339: %v", stamp, err)
+	}
+	if have := line["operation"]; have != op {
+		t.Errorf("operation: have %v, want %v", have, op)
+	}
+	if have := line["key_id"]; have != auditKeyID(prvID) {
+		t.Errorf("key id: have %v, want %v", have, auditKeyID(prvID))
+	}
+	if duration, ok := line["duration_ns"].(float64); !ok || duration < 0 {
+		t.Errorf("duration: have %v (%T), want non-negative number", line["duration_ns"], line["duration_ns"])
+	}
+	if msg, ok := line["error"].(string); failed != (ok && msg != "") {
+		t.Errorf("error: have %v, want failed %v", line["error"], failed)
+	}
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) { return 0, errors.New("disk full") }
+
+func TestAuditedKeeper(t *testing.T) {
+	var buf bytes.Buffer
+	k := NewAuditedKeeper(defaultKeeper, &buf)
+
+	prvID, err := k.GeneratePrivateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	hash := crypto.Keccak256([]byte("audit"))
+	if _, err := k.Sign(hash, prvID); err != nil {
+		t.Fatalf("failed to sign: %v", err)
+	}
+	if _, err := k.Sign(hash, []byte{0x01}); err == nil {
+		t.Fatal("signed with invalid key")
+	}
+	lines := auditLines(t, &buf)
+	if len(lines) != 2 {
+		t.Fatalf("audit lines: have %d, want 2", len(lines))
+	}
+	checkAuditLine(t, lines[0], "sign", prvID, false)
+	checkAuditLine(t, lines[1], "sign", []byte{0x01}, true)
+	for i, line := range lines {
+		if have, want := line["data"], "0x"+hex.EncodeToString(hash[:8]); have != want {
+			t.Errorf("line %d data: have %v, want %v", i, have, want)
+		}
+	}
+	// The raw key must never end up in the log.
+	if bytes.Contains(buf.Bytes(), []byte(hex.EncodeToString(prvID))) {
+		t.Error("private key written to audit log")
+	}
+
+	// Signatures that can't be audited are withheld.
+	k = NewAuditedKeeper(defaultKeeper, failingWriter{})
+	if sig, err := k.Sign(hash, prvID); err == nil || sig != nil {
+		t.Fatalf("signed without audit log: %x, %v", sig, err)
+	}
+}
+
+func TestAuditedSecureSigner(t *testing.T) {
+	var buf bytes.Buffer
+	sec := NewAuditedSecureSigner(NewSecureSigner(defaultKeeper), &buf)
+
+	prvID, err := sec.GenerateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	to := common.HexToAddress("0x000000000000000000000000000000000000dead")
+	signed, err := sec.SignDynamicFeeTx(big.NewInt(5), 7, to, big.NewInt(1000), 21000, big.NewInt(2), big.NewInt(1), nil, prvID)
+	if err != nil {
+		t.Fatalf("failed to sign: %v", err)
+	}
+	if _, err := sec.Sign(newTestTx(), types.LatestSignerForChainID(big.NewInt(5)), []byte{0x01}); err == nil {
+		t.Fatal("signed with invalid key")
+	}
+	if _, err := sec.SignBlobTx(big.NewInt(5), &types.BlobTx{}, prvID); err == nil {
+		t.Fatal("signed blob transaction without blob hashes")
+	}
+	if _, err := sec.SignPersonalMessage([]byte("hello"), prvID); err != nil {
+		t.Fatalf("failed to sign message: %v", err)
+	}
+
+	lines := auditLines(t, &buf)
+	if len(lines) != 4 {
+		t.Fatalf("audit lines: have %d, want 4", len(lines))
+	}
+	checkAuditLine(t, lines[0], "sign_transaction", prvID, false)
+	checkAuditLine(t, lines[1], "sign_transaction", []byte{0x01}, true)
+	checkAuditLine(t, lines[2], "sign_transaction", prvID, true)
+	checkAuditLine(t, lines[3], "sign_personal_message", prvID, false)
+
+	want := map[string]any{
+		"tx_hash":  signed.Hash().Hex(),
+		"chain_id": "0x5",
+		"nonce":    float64(7),
+		"to":       strings.ToLower(to.Hex()),
+		"value":    "0x3e8",
+	}
+	for field, value := range want {
+		if have := lines[0][field]; have != value {
+			t.Errorf("%s: have %v (%T), want %v (%T)", field, have, have, value, value)
+		}
+	}
+	// Failed signatures are logged without a hash.
+	for _, line := range lines[1:3] {
+		if _, ok := line["tx_hash"]; ok {
+			t.Errorf("failed signature logged with hash %v", line["tx_hash"])
+		}
+		if _, ok := line["chain_id"].(string); !ok {
+			t.Errorf("chain id: have %T, want string", line["chain_id"])
+		}
+	}
+	// Batches are logged transaction by transaction.
+	buf.Reset()
+	if _, err := sec.SignBatch([]*types.Transaction{newTestTx(), newTestTx()}, types.LatestSignerForChainID(big.NewInt(5)), prvID); err != nil {
+		t.Fatalf("failed to sign batch: %v", err)
+	}
+	if have := len(auditLines(t, &buf)); have != 2 {
+		t.Errorf("batch audit lines: have %d, want 2", have)
+	}
+}
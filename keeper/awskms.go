@@ -0,0 +1,419 @@
+package keeper
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	kmstypes "github.com/aws/aws-sdk-go-v2/service/kms/types"
+	"github.com/aws/smithy-go"
+)
+
+const (
+	// kmsMaxAttempts is the number of times a throttled KMS request is tried
+	// before the error is handed to the caller.
+	kmsMaxAttempts = 5
+
+	// kmsBaseBackoff is the delay before the first retry of a throttled KMS
+	// request, doubled on every subsequent attempt.
+	kmsBaseBackoff = 100 * time.Millisecond
+
+	// kmsDeletionWindow is the number of days KMS waits before destroying a
+	// deleted key, the shortest window KMS allows.
+	kmsDeletionWindow = 7
+)
+
+// KMSError is returned by the AWS KMS keeper for any failed KMS request. It
+// wraps the error of the AWS SDK.
+type KMSError struct {
+	Op  string // KMS operation that failed, e.g. "Sign"
+	Err error  // error returned by the AWS SDK
+}
+
+func (e *KMSError) Error() string {
+	return fmt.Sprintf("kms %s: %v", e.Op, e.Err)
+}
+
+func (e *KMSError) Unwrap() error {
+	return e.Err
+}
+
+// Is maps KMS exceptions to the errors of the keeper package: NotFoundException
+// is reported as ErrKeyNotFound, AccessDeniedException as ErrPermissionDenied,
+// throttling as ErrRateLimitExceeded and KMS outages as well as network
+// failures as ErrBackendUnavailable.
+func (e *KMSError) Is(target error) bool {
+	var apiErr smithy.APIError
+	if !errors.As(e.Err, &apiErr) {
+		var netErr net.Error
+		return target == ErrBackendUnavailable && errors.As(e.Err, &netErr)
+	}
+	switch apiErr.ErrorCode() {
+	case "NotFoundException":
+		return target == ErrKeyNotFound
+	case "AccessDeniedException":
+		return target == ErrPermissionDenied
+	case "ThrottlingException", "LimitExceededException":
+		return target == ErrRateLimitExceeded
+	case "DependencyTimeoutException", "KMSInternalException":
+		return target == ErrBackendUnavailable
+	}
+	return false
+}
+
+// Temporary reports whether the request failed due to a transient condition
+// and may succeed if retried later.
+func (e *KMSError) Temporary() bool {
+	var apiErr smithy.APIError
+	if !errors.As(e.Err, &apiErr) {
+		return false
+	}
+	switch apiErr.ErrorCode() {
+	case "ThrottlingException", "DependencyTimeoutException", "KMSInternalException":
+		return true
+	}
+	return false
+}
+
+// isThrottled reports whether err is KMS rejecting a request for exceeding
+// the request quota.
+func isThrottled(err error) bool {
+	var apiErr smithy.APIError
+	return errors.As(err, &apiErr) && apiErr.ErrorCode() == "ThrottlingException"
+}
+
+// kmsAPI is the subset of the AWS KMS client used by the keeper.
+type kmsAPI interface {
+	CreateKey(ctx context.Context, params *kms.CreateKeyInput, optFns ...func(*kms.Options)) (*kms.CreateKeyOutput, error)
+	GetParametersForImport(ctx context.Context, params *kms.GetParametersForImportInput, optFns ...func(*kms.Options)) (*kms.GetParametersForImportOutput, error)
+	ImportKeyMaterial(ctx context.Context, params *kms.ImportKeyMaterialInput, optFns ...func(*kms.Options)) (*kms.ImportKeyMaterialOutput, error)
+	GetPublicKey(ctx context.Context, params *kms.GetPublicKeyInput, optFns ...func(*kms.Options)) (*kms.GetPublicKeyOutput, error)
+	Sign(ctx context.Context, params *kms.SignInput, optFns ...func(*kms.Options)) (*kms.SignOutput, error)
+	ScheduleKeyDeletion(ctx context.Context, params *kms.ScheduleKeyDeletionInput, optFns ...func(*kms.Options)) (*kms.ScheduleKeyDeletionOutput, error)
+	ListKeys(ctx context.Context, params *kms.ListKeysInput, optFns ...func(*kms.Options)) (*kms.ListKeysOutput, error)
+}
+
+// awsKMSKeeper is a PrivateKeyKeeper storing keys in AWS KMS. The private key
+// material never leaves KMS, prvID is the ARN of the KMS key.
+type awsKMSKeeper struct {
+	client  kmsAPI
+	keySpec kmstypes.KeySpec
+	backoff time.Duration
+
+	pubkeys sync.Map // KMS key ARN -> uncompressed public key
+}
+
+// NewAWSKMSKeeper returns a PrivateKeyKeeper that creates and uses keys inside
+// AWS KMS. The keySpec is the KMS key spec of generated keys, if empty it
+// defaults to ECC_SECG_P256K1, the only spec producing Ethereum compatible keys.
+func NewAWSKMSKeeper(cfg aws.Config, keySpec string) PrivateKeyKeeper {
+	return newAWSKMSKeeper(kms.NewFromConfig(cfg), keySpec)
+}
+
+func newAWSKMSKeeper(client kmsAPI, keySpec string) *awsKMSKeeper {
+	if keySpec == "" {
+		keySpec = string(kmstypes.KeySpecEccSecgP256k1)
+	}
+	return &awsKMSKeeper{
+		client:  client,
+		keySpec: kmstypes.KeySpec(keySpec),
+		backoff: kmsBaseBackoff,
+	}
+}
+
+// call runs a KMS request, retrying it with exponential back-off as long as
+// KMS throttles it.
+func (k *awsKMSKeeper) call(ctx context.Context, op string, fn func() error) error {
+	delay := k.backoff
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil {
+			return nil
+		}
+		if !isThrottled(err) || attempt == kmsMaxAttempts {
+			return &KMSError{Op: op, Err: err}
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+		delay *= 2
+	}
+}
+
+func (k *awsKMSKeeper) GeneratePrivateKey() ([]byte, error) {
+	return k.GeneratePrivateKeyContext(context.Background())
+}
+
+func (k *awsKMSKeeper) GeneratePrivateKeyContext(ctx context.Context) (_ []byte, err error) {
+	defer wrapError(&err, "generate key", nil)
+
+	var out *kms.CreateKeyOutput
+	err = k.call(ctx, "CreateKey", func() (err error) {
+		out, err = k.client.CreateKey(ctx, &kms.CreateKeyInput{
+			KeySpec:  k.keySpec,
+			KeyUsage: kmstypes.KeyUsageTypeSignVerify,
+		})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if out.KeyMetadata == nil || out.KeyMetadata.Arn == nil {
+		return nil, &KMSError{Op: "CreateKey", Err: errors.New("missing key ARN in response")}
+	}
+	return []byte(*out.KeyMetadata.Arn), nil
+}
+
+// ExportEncryptedKey returns ErrExportNotSupported, KMS never hands out the key
+// material of its keys, imported ones included.
+func (k *awsKMSKeeper) ExportEncryptedKey(prvID []byte, passphrase string) (_ []byte, err error) {
+	defer wrapError(&err, "export key", prvID)
+	return nil, ErrExportNotSupported
+}
+
+func (k *awsKMSKeeper) ImportEncryptedKey(data []byte, passphrase string) ([]byte, error) {
+	return importEncryptedKey(k, data, passphrase)
+}
+
+func (k *awsKMSKeeper) ImportPrivateKey(rawKey []byte) ([]byte, error) {
+	return k.ImportPrivateKeyContext(context.Background(), rawKey)
+}
+
+// ImportPrivateKeyContext creates a KMS key without key material and imports
+// rawKey into it, encrypted for the one-off wrapping key KMS hands out for the
+// import. The key material never expires. If the import fails, the empty key
+// is scheduled for deletion again.
+func (k *awsKMSKeeper) ImportPrivateKeyContext(ctx context.Context, rawKey []byte) (_ []byte, err error) {
+	defer wrapError(&err, "import key", nil)
+
+	key, err := parseRawKey(rawKey)
+	if err != nil {
+		return nil, err
+	}
+	defer ZeroKey(key)
+
+	var created *kms.CreateKeyOutput
+	err = k.call(ctx, "CreateKey", func() (err error) {
+		created, err = k.client.CreateKey(ctx, &kms.CreateKeyInput{
+			KeySpec:  k.keySpec,
+			KeyUsage: kmstypes.KeyUsageTypeSignVerify,
+			Origin:   kmstypes.OriginTypeExternal,
+		})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if created.KeyMetadata == nil || created.KeyMetadata.Arn == nil {
+		return nil, &KMSError{Op: "CreateKey", Err: errors.New("missing key ARN in response")}
+	}
+	prvID := []byte(*created.KeyMetadata.Arn)
+	if err := k.importKeyMaterial(ctx, prvID, key); err != nil {
+		if delErr := k.DeletePrivateKeyContext(context.WithoutCancel(ctx), prvID); delErr != nil {
+			// Report the failed import, not the failed clean-up.
+			return nil, &KeeperError{Op: "import key", Err: errors.Join(err, delErr)}
+		}
+		return nil, err
+	}
+	return prvID, nil
+}
+
+// importKeyMaterial imports key into the KMS key prvID awaiting its material.
+func (k *awsKMSKeeper) importKeyMaterial(ctx context.Context, prvID []byte, key *ecdsa.PrivateKey) error {
+	var params *kms.GetParametersForImportOutput
+	err := k.call(ctx, "GetParametersForImport", func() (err error) {
+		params, err = k.client.GetParametersForImport(ctx, &kms.GetParametersForImportInput{
+			KeyId:             aws.String(string(prvID)),
+			WrappingAlgorithm: kmstypes.AlgorithmSpecRsaAesKeyWrapSha256,
+			WrappingKeySpec:   kmstypes.WrappingKeySpecRsa4096,
+		})
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	pub, err := x509.ParsePKIXPublicKey(params.PublicKey)
+	if err != nil {
+		return &KMSError{Op: "GetParametersForImport", Err: err}
+	}
+	wrappingKey, ok := pub.(*rsa.PublicKey)
+	if !ok {
+		return &KMSError{Op: "GetParametersForImport", Err: fmt.Errorf("invalid wrapping key type %T", pub)}
+	}
+	material, err := marshalPKCS8PrivateKey(key)
+	if err != nil {
+		return err
+	}
+	defer zeroBytes(material)
+
+	wrapped, err := wrapKeyMaterial(wrappingKey, material)
+	if err != nil {
+		return err
+	}
+	return k.call(ctx, "ImportKeyMaterial", func() error {
+		_, err := k.client.ImportKeyMaterial(ctx, &kms.ImportKeyMaterialInput{
+			KeyId:                aws.String(string(prvID)),
+			ImportToken:          params.ImportToken,
+			EncryptedKeyMaterial: wrapped,
+			ExpirationModel:      kmstypes.ExpirationModelTypeKeyMaterialDoesNotExpire,
+		})
+		return err
+	})
+}
+
+func (k *awsKMSKeeper) GetPublicKey(prvID []byte) ([]byte, error) {
+	return k.GetPublicKeyContext(context.Background(), prvID)
+}
+
+func (k *awsKMSKeeper) GetPublicKeyContext(ctx context.Context, prvID []byte) (_ []byte, err error) {
+	defer wrapError(&err, "get public key", prvID)
+
+	keyID := string(prvID)
+	if pub, ok := k.pubkeys.Load(keyID); ok {
+		return pub.([]byte), nil
+	}
+	var out *kms.GetPublicKeyOutput
+	err = k.call(ctx, "GetPublicKey", func() (err error) {
+		out, err = k.client.GetPublicKey(ctx, &kms.GetPublicKeyInput{KeyId: aws.String(keyID)})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	pub, err := parseSubjectPublicKeyInfo(out.PublicKey)
+	if err != nil {
+		return nil, &KMSError{Op: "GetPublicKey", Err: err}
+	}
+	k.pubkeys.Store(keyID, pub)
+	return pub, nil
+}
+
+func (k *awsKMSKeeper) Sign(data []byte, prvID []byte) ([]byte, error) {
+	return k.SignContext(context.Background(), data, prvID)
+}
+
+// SignContext signs the 32 byte digest data. KMS returns DER encoded signatures
+// without a recovery id, which is reconstructed from the public key of prvID.
+func (k *awsKMSKeeper) SignContext(ctx context.Context, data []byte, prvID []byte) (_ []byte, err error) {
+	defer wrapError(&err, "sign", prvID)
+
+	if len(data) != 32 {
+		return nil, fmt.Errorf("hash is required to be exactly 32 bytes (%d)", len(data))
+	}
+	pub, err := k.GetPublicKeyContext(ctx, prvID)
+	if err != nil {
+		return nil, err
+	}
+	var out *kms.SignOutput
+	err = k.call(ctx, "Sign", func() (err error) {
+		out, err = k.client.Sign(ctx, &kms.SignInput{
+			KeyId:            aws.String(string(prvID)),
+			Message:          data,
+			MessageType:      kmstypes.MessageTypeDigest,
+			SigningAlgorithm: kmstypes.SigningAlgorithmSpecEcdsaSha256,
+		})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	r, s, err := parseDERSignature(out.Signature)
+	if err != nil {
+		return nil, &KMSError{Op: "Sign", Err: err}
+	}
+	return recoverableSignature(data, r, s, pub)
+}
+
+func (k *awsKMSKeeper) DeletePrivateKey(prvID []byte) error {
+	return k.DeletePrivateKeyContext(context.Background(), prvID)
+}
+
+// DeletePrivateKeyContext schedules the KMS key for deletion. KMS doesn't allow
+// destroying keys immediately, the key is disabled right away and destroyed
+// after the shortest possible waiting period of 7 days.
+func (k *awsKMSKeeper) DeletePrivateKeyContext(ctx context.Context, prvID []byte) (err error) {
+	defer wrapError(&err, "delete key", prvID)
+
+	err = k.call(ctx, "ScheduleKeyDeletion", func() error {
+		_, err := k.client.ScheduleKeyDeletion(ctx, &kms.ScheduleKeyDeletionInput{
+			KeyId:               aws.String(string(prvID)),
+			PendingWindowInDays: aws.Int32(kmsDeletionWindow),
+		})
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	k.pubkeys.Delete(string(prvID))
+	return nil
+}
+
+func (k *awsKMSKeeper) ListPrivateKeys() ([][]byte, error) {
+	return k.ListPrivateKeysContext(context.Background())
+}
+
+// ListPrivateKeysContext returns the ARNs of all KMS keys of the account in the
+// region of the client. KMS doesn't filter the keys, so the list includes keys
+// of other specs and keys pending deletion.
+func (k *awsKMSKeeper) ListPrivateKeysContext(ctx context.Context) (_ [][]byte, err error) {
+	defer wrapError(&err, "list keys", nil)
+
+	var (
+		prvIDs [][]byte
+		marker *string
+	)
+	for {
+		var out *kms.ListKeysOutput
+		err := k.call(ctx, "ListKeys", func() (err error) {
+			out, err = k.client.ListKeys(ctx, &kms.ListKeysInput{Marker: marker})
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, key := range out.Keys {
+			if key.KeyArn != nil {
+				prvIDs = append(prvIDs, []byte(*key.KeyArn))
+			}
+		}
+		if !out.Truncated || out.NextMarker == nil {
+			return prvIDs, nil
+		}
+		marker = out.NextMarker
+	}
+}
+
+// Snapshot lists the IDs of the keys, the key material never leaves KMS.
+func (k *awsKMSKeeper) Snapshot() (_ []byte, err error) {
+	defer wrapError(&err, "snapshot", nil)
+	return snapshotKeyIDs(context.Background(), k)
+}
+
+// Restore checks that the keys of the snapshot still exist in KMS.
+func (k *awsKMSKeeper) Restore(snapshot []byte) (err error) {
+	defer wrapError(&err, "restore snapshot", nil)
+	return restoreKeyIDs(context.Background(), k, snapshot)
+}
+
+// HealthCheck lists a single key of the account. KMS has no key independent
+// request that is cheaper, DescribeKey needs a key to describe.
+func (k *awsKMSKeeper) HealthCheck(ctx context.Context) (err error) {
+	defer wrapError(&err, "health check", nil)
+
+	if _, err := k.client.ListKeys(ctx, &kms.ListKeysInput{Limit: aws.Int32(1)}); err != nil {
+		return &KMSError{Op: "ListKeys", Err: err}
+	}
+	return nil
+}
@@ -0,0 +1,352 @@
+package keeper
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	kmstypes "github.com/aws/aws-sdk-go-v2/service/kms/types"
+	"github.com/aws/smithy-go"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// fakeKMS is an in-memory kmsAPI implementation. Keys created for import are
+// stored without key material until it is imported.
+type fakeKMS struct {
+	keys     map[string]*ecdsa.PrivateKey
+	tokens   map[string]string // key ARN -> pending import token
+	throttle int               // number of requests to throttle before serving
+	fail     error             // error returned for every request, if set
+	calls    int
+}
+
+func newFakeKMS() *fakeKMS {
+	return &fakeKMS{keys: make(map[string]*ecdsa.PrivateKey), tokens: make(map[string]string)}
+}
+
+func (f *fakeKMS) check() error {
+	f.calls++
+	if f.fail != nil {
+		return f.fail
+	}
+	if f.throttle > 0 {
+		f.throttle--
+		return &smithy.GenericAPIError{Code: "ThrottlingException", Message: "Rate exceeded"}
+	}
+	return nil
+}
+
+func (f *fakeKMS) key(id *string) (*ecdsa.PrivateKey, error) {
+	key, ok := f.keys[aws.ToString(id)]
+	if !ok {
+		return nil, &kmstypes.NotFoundException{Message: aws.String("key not found")}
+	}
+	if key == nil {
+		return nil, &kmstypes.KMSInvalidStateException{Message: aws.String("key is pending import")}
+	}
+	return key, nil
+}
+
+func (f *fakeKMS) CreateKey(ctx context.Context, params *kms.CreateKeyInput, optFns ...func(*kms.Options)) (*kms.CreateKeyOutput, error) {
+	if err := f.check(); err != nil {
+		return nil, err
+	}
+	if params.KeySpec != kmstypes.KeySpecEccSecgP256k1 || params.KeyUsage != kmstypes.KeyUsageTypeSignVerify {
+		return nil, fmt.Errorf("unexpected key spec %s, usage %s", params.KeySpec, params.KeyUsage)
+	}
+	var key *ecdsa.PrivateKey
+	switch params.Origin {
+	case "", kmstypes.OriginTypeAwsKms:
+		key, _ = crypto.GenerateKey()
+	case kmstypes.OriginTypeExternal:
+	default:
+		return nil, fmt.Errorf("unexpected origin %s", params.Origin)
+	}
+	arn := fmt.Sprintf("arn:aws:kms:us-east-1:000000000000:key/%d", len(f.keys))
+	f.keys[arn] = key
+	return &kms.CreateKeyOutput{KeyMetadata: &kmstypes.KeyMetadata{Arn: aws.String(arn)}}, nil
+}
+
+func (f *fakeKMS) GetParametersForImport(ctx context.Context, params *kms.GetParametersForImportInput, optFns ...func(*kms.Options)) (*kms.GetParametersForImportOutput, error) {
+	if err := f.check(); err != nil {
+		return nil, err
+	}
+	arn := aws.ToString(params.KeyId)
+	if key, ok := f.keys[arn]; !ok || key != nil {
+		return nil, &kmstypes.UnsupportedOperationException{Message: aws.String("key is not awaiting import")}
+	}
+	if params.WrappingAlgorithm != kmstypes.AlgorithmSpecRsaAesKeyWrapSha256 {
+		return nil, fmt.Errorf("unexpected wrapping algorithm %s", params.WrappingAlgorithm)
+	}
+	pub, err := x509.MarshalPKIXPublicKey(&testWrappingKey().PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	token := fmt.Sprintf("token-%d", f.calls)
+	f.tokens[arn] = token
+	return &kms.GetParametersForImportOutput{KeyId: params.KeyId, PublicKey: pub, ImportToken: []byte(token)}, nil
+}
+
+func (f *fakeKMS) ImportKeyMaterial(ctx context.Context, params *kms.ImportKeyMaterialInput, optFns ...func(*kms.Options)) (*kms.ImportKeyMaterialOutput, error) {
+	if err := f.check(); err != nil {
+		return nil, err
+	}
+	arn := aws.ToString(params.KeyId)
+	if token, ok := f.tokens[arn]; !ok || token != string(params.ImportToken) {
+		return nil, &kmstypes.InvalidImportTokenException{Message: aws.String("invalid import token")}
+	}
+	key, err := unwrapTestKeyMaterial(params.EncryptedKeyMaterial)
+	if err != nil {
+		return nil, &kmstypes.IncorrectKeyMaterialException{Message: aws.String(err.Error())}
+	}
+	delete(f.tokens, arn)
+	f.keys[arn] = key
+	return &kms.ImportKeyMaterialOutput{}, nil
+}
+
+func (f *fakeKMS) GetPublicKey(ctx context.Context, params *kms.GetPublicKeyInput, optFns ...func(*kms.Options)) (*kms.GetPublicKeyOutput, error) {
+	if err := f.check(); err != nil {
+		return nil, err
+	}
+	key, err := f.key(params.KeyId)
+	if err != nil {
+		return nil, err
+	}
+	return &kms.GetPublicKeyOutput{PublicKey: marshalTestSPKI(&key.PublicKey)}, nil
+}
+
+func (f *fakeKMS) Sign(ctx context.Context, params *kms.SignInput, optFns ...func(*kms.Options)) (*kms.SignOutput, error) {
+	if err := f.check(); err != nil {
+		return nil, err
+	}
+	if params.MessageType != kmstypes.MessageTypeDigest || params.SigningAlgorithm != kmstypes.SigningAlgorithmSpecEcdsaSha256 {
+		return nil, fmt.Errorf("unexpected message type %s, algorithm %s", params.MessageType, params.SigningAlgorithm)
+	}
+	key, err := f.key(params.KeyId)
+	if err != nil {
+		return nil, err
+	}
+	// KMS doesn't normalise s, return high values half of the time.
+	return &kms.SignOutput{Signature: derSign(params.Message, key, f.calls%2 == 0)}, nil
+}
+
+func (f *fakeKMS) ScheduleKeyDeletion(ctx context.Context, params *kms.ScheduleKeyDeletionInput, optFns ...func(*kms.Options)) (*kms.ScheduleKeyDeletionOutput, error) {
+	if err := f.check(); err != nil {
+		return nil, err
+	}
+	if _, ok := f.keys[aws.ToString(params.KeyId)]; !ok {
+		return nil, &kmstypes.NotFoundException{Message: aws.String("key not found")}
+	}
+	if aws.ToInt32(params.PendingWindowInDays) != kmsDeletionWindow {
+		return nil, fmt.Errorf("unexpected pending window %d", aws.ToInt32(params.PendingWindowInDays))
+	}
+	delete(f.keys, aws.ToString(params.KeyId))
+	return &kms.ScheduleKeyDeletionOutput{KeyId: params.KeyId}, nil
+}
+
+func (f *fakeKMS) ListKeys(ctx context.Context, params *kms.ListKeysInput, optFns ...func(*kms.Options)) (*kms.ListKeysOutput, error) {
+	if err := f.check(); err != nil {
+		return nil, err
+	}
+	arns := make([]string, 0, len(f.keys))
+	for arn := range f.keys {
+		arns = append(arns, arn)
+	}
+	sort.Strings(arns)
+
+	// Serve two keys per page to exercise pagination, the marker is the
+	// index of the first key of the page.
+	start := 0
+	if params.Marker != nil {
+		start, _ = strconv.Atoi(*params.Marker)
+	}
+	end := min(start+2, len(arns))
+	out := new(kms.ListKeysOutput)
+	for _, arn := range arns[start:end] {
+		out.Keys = append(out.Keys, kmstypes.KeyListEntry{KeyArn: aws.String(arn), KeyId: aws.String(arn)})
+	}
+	if end < len(arns) {
+		out.Truncated = true
+		out.NextMarker = aws.String(strconv.Itoa(end))
+	}
+	return out, nil
+}
+
+func TestAWSKMSKeeper(t *testing.T) {
+	fake := newFakeKMS()
+	k := newAWSKMSKeeper(fake, "")
+
+	prvID, err := k.GeneratePrivateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	pub, err := k.GetPublicKey(prvID)
+	if err != nil {
+		t.Fatalf("failed to get public key: %v", err)
+	}
+	for i := 0; i < 4; i++ {
+		hash := crypto.Keccak256([]byte{byte(i)})
+		sig, err := k.Sign(hash, prvID)
+		if err != nil {
+			t.Fatalf("failed to sign: %v", err)
+		}
+		recovered, err := crypto.Ecrecover(hash, sig)
+		if err != nil {
+			t.Fatalf("failed to recover: %v", err)
+		}
+		if !bytes.Equal(recovered, pub) {
+			t.Fatalf("recovered key mismatch: have %x, want %x", recovered, pub)
+		}
+		if !crypto.ValidateSignatureValues(sig[64], new(big.Int).SetBytes(sig[:32]), new(big.Int).SetBytes(sig[32:64]), true) {
+			t.Fatalf("signature %x is not in canonical form", sig)
+		}
+	}
+	if _, err := k.Sign([]byte("not a hash"), prvID); err == nil {
+		t.Fatal("signed data that is not a digest")
+	}
+	if err := k.DeletePrivateKey(prvID); err != nil {
+		t.Fatalf("failed to delete key: %v", err)
+	}
+	if _, err := k.GetPublicKey(prvID); !errors.Is(err, ErrKeyNotFound) {
+		t.Fatalf("deleted key: have %v, want %v", err, ErrKeyNotFound)
+	}
+	if err := k.DeletePrivateKey(prvID); !errors.Is(err, ErrKeyNotFound) {
+		t.Fatalf("deleting missing key: have %v, want %v", err, ErrKeyNotFound)
+	}
+}
+
+func TestAWSKMSKeeperHealthCheck(t *testing.T) {
+	fake := newFakeKMS()
+	k := newAWSKMSKeeper(fake, "")
+
+	if err := k.HealthCheck(context.Background()); err != nil {
+		t.Fatalf("reachable kms: have %v, want healthy", err)
+	}
+	fake.fail = &smithy.GenericAPIError{Code: "KMSInternalException", Message: "internal error"}
+	if err := k.HealthCheck(context.Background()); !errors.Is(err, ErrBackendUnavailable) {
+		t.Fatalf("failing kms: have %v, want %v", err, ErrBackendUnavailable)
+	}
+	if fake.calls != 2 {
+		t.Fatalf("kms calls: have %d, want 2", fake.calls)
+	}
+}
+
+func TestAWSKMSKeeperList(t *testing.T) {
+	fake := newFakeKMS()
+	k := newAWSKMSKeeper(fake, "")
+
+	if ids, err := k.ListPrivateKeys(); err != nil || len(ids) != 0 {
+		t.Fatalf("empty keeper: have (%q, %v), want no keys", ids, err)
+	}
+	want := make(map[string]bool)
+	for i := 0; i < 5; i++ {
+		prvID, err := k.GeneratePrivateKey()
+		if err != nil {
+			t.Fatalf("failed to generate key: %v", err)
+		}
+		want[string(prvID)] = true
+	}
+	fake.throttle = 1
+	ids, err := k.ListPrivateKeys()
+	if err != nil {
+		t.Fatalf("failed to list keys: %v", err)
+	}
+	if len(ids) != len(want) {
+		t.Fatalf("key count mismatch: have %d, want %d", len(ids), len(want))
+	}
+	for _, id := range ids {
+		if !want[string(id)] {
+			t.Errorf("unexpected key %q", id)
+		}
+	}
+}
+
+func TestAWSKMSKeeperThrottling(t *testing.T) {
+	fake := newFakeKMS()
+	k := newAWSKMSKeeper(fake, "")
+	k.backoff = time.Millisecond
+
+	fake.throttle = kmsMaxAttempts - 1
+	if _, err := k.GeneratePrivateKey(); err != nil {
+		t.Fatalf("throttled request not retried: %v", err)
+	}
+	if fake.calls != kmsMaxAttempts {
+		t.Fatalf("call count mismatch: have %d, want %d", fake.calls, kmsMaxAttempts)
+	}
+
+	fake.throttle = kmsMaxAttempts
+	_, err := k.GeneratePrivateKey()
+	var kmsErr *KMSError
+	if !errors.As(err, &kmsErr) {
+		t.Fatalf("error type mismatch: have %T, want %T", err, kmsErr)
+	}
+	if kmsErr.Op != "CreateKey" || !kmsErr.Temporary() {
+		t.Fatalf("unexpected error: op %q, temporary %v", kmsErr.Op, kmsErr.Temporary())
+	}
+
+	// Canceling the context aborts the back-off.
+	k.backoff = time.Hour
+	fake.throttle = 1
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := k.GeneratePrivateKeyContext(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("error mismatch: have %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestAWSKMSKeeperPermanentError(t *testing.T) {
+	fake := newFakeKMS()
+	k := newAWSKMSKeeper(fake, "")
+
+	_, err := k.GetPublicKey([]byte("arn:aws:kms:us-east-1:000000000000:key/missing"))
+	var kmsErr *KMSError
+	if !errors.As(err, &kmsErr) {
+		t.Fatalf("error type mismatch: have %T, want %T", err, kmsErr)
+	}
+	if kmsErr.Temporary() {
+		t.Fatal("missing key reported as temporary failure")
+	}
+	var notFound *kmstypes.NotFoundException
+	if !errors.As(err, &notFound) {
+		t.Fatalf("SDK error not unwrappable: %v", err)
+	}
+	if fake.calls != 1 {
+		t.Fatalf("permanent failure retried: %d calls", fake.calls)
+	}
+}
+
+func TestAWSKMSKeeperImport(t *testing.T) {
+	fake := newFakeKMS()
+	k := newAWSKMSKeeper(fake, "")
+	checkImport(t, k)
+
+	// Keys whose material failed to import are deleted again.
+	key, _ := crypto.GenerateKey()
+	broken := &failingImportKMS{fakeKMS: fake}
+	k = newAWSKMSKeeper(broken, "")
+	if _, err := k.ImportPrivateKey(crypto.FromECDSA(key)); err == nil {
+		t.Fatal("imported key with failing import")
+	}
+	if len(fake.keys) != 1 {
+		t.Fatalf("keys after failed import: have %d, want 1", len(fake.keys))
+	}
+}
+
+// failingImportKMS is a fakeKMS refusing all key material.
+type failingImportKMS struct {
+	*fakeKMS
+}
+
+func (f *failingImportKMS) ImportKeyMaterial(ctx context.Context, params *kms.ImportKeyMaterialInput, optFns ...func(*kms.Options)) (*kms.ImportKeyMaterialOutput, error) {
+	return nil, &kmstypes.ExpiredImportTokenException{Message: aws.String("import token expired")}
+}
@@ -0,0 +1,404 @@
+package keeper
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/runtime"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/google/uuid"
+)
+
+const (
+	// azureKeyVaultAPIVersion is the version of the Key Vault REST API used.
+	azureKeyVaultAPIVersion = "7.4"
+
+	// azureCurve and azureAlgorithm are the JWK curve of secp256k1 and the
+	// JWS algorithm of its ECDSA signatures.
+	azureCurve     = "P-256K"
+	azureAlgorithm = "ES256K"
+)
+
+// azureKey is the JSON web key of a Key Vault key.
+type azureKey struct {
+	KID    string   `json:"kid,omitempty"`
+	KTY    string   `json:"kty"`
+	Crv    string   `json:"crv,omitempty"`
+	KeyOps []string `json:"key_ops,omitempty"`
+	X      string   `json:"x,omitempty"`
+	Y      string   `json:"y,omitempty"`
+	D      string   `json:"d,omitempty"`
+}
+
+// azureKeyBundle is a Key Vault key as returned by the REST API.
+type azureKeyBundle struct {
+	Key azureKey `json:"key"`
+}
+
+// azureKeyItem is a key as listed by the REST API, its kid lacks the version.
+type azureKeyItem struct {
+	KID string `json:"kid"`
+}
+
+// azureKeyList is a page of listed keys.
+type azureKeyList struct {
+	Value    []azureKeyItem `json:"value"`
+	NextLink string         `json:"nextLink"`
+}
+
+// azureSignResult is the result of a sign request, the JWS signature R || S.
+type azureSignResult struct {
+	Value string `json:"value"`
+}
+
+// azureKeyVaultKeeper is a PrivateKeyKeeper storing keys in Azure Key Vault or
+// a Managed HSM. The private key material never leaves Key Vault, the prvID is
+// "name/version" of the Key Vault key.
+type azureKeyVaultKeeper struct {
+	vaultURL string
+	pipeline runtime.Pipeline
+
+	pubkeys sync.Map // prvID -> uncompressed public key
+}
+
+// NewAzureKeyVaultKeeper returns a PrivateKeyKeeper creating and using
+// secp256k1 keys inside the Key Vault at vaultURL, e.g.
+// https://myvault.vault.azure.net. The keeper talks to the Key Vault REST API
+// through the Azure SDK pipeline, authenticating with tokens of credential for
+// the resource of the vault, https://vault.azure.net for the public cloud.
+func NewAzureKeyVaultKeeper(vaultURL string, credential azcore.TokenCredential) (PrivateKeyKeeper, error) {
+	return newAzureKeyVaultKeeper(vaultURL, credential, nil)
+}
+
+func newAzureKeyVaultKeeper(vaultURL string, credential azcore.TokenCredential, opts *policy.ClientOptions) (*azureKeyVaultKeeper, error) {
+	u, err := url.Parse(vaultURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid vault URL %q: %v", vaultURL, err)
+	}
+	_, resource, ok := strings.Cut(u.Hostname(), ".")
+	if u.Scheme != "https" || !ok {
+		return nil, fmt.Errorf("invalid vault URL %q", vaultURL)
+	}
+	auth := runtime.NewBearerTokenPolicy(credential, []string{"https://" + resource + "/.default"}, nil)
+	return &azureKeyVaultKeeper{
+		vaultURL: strings.TrimRight(vaultURL, "/"),
+		pipeline: runtime.NewPipeline("keeper", "v1.0.0", runtime.PipelineOptions{PerRetry: []policy.Policy{auth}}, opts),
+	}, nil
+}
+
+// azureError marks failed Key Vault requests with the matching error of the
+// keeper package, keeping the response error of the SDK accessible.
+func azureError(err error) error {
+	var respErr *azcore.ResponseError
+	if errors.As(err, &respErr) {
+		switch {
+		case respErr.StatusCode == http.StatusNotFound:
+			return fmt.Errorf("%w: %w", ErrKeyNotFound, err)
+		case respErr.StatusCode == http.StatusUnauthorized || respErr.StatusCode == http.StatusForbidden:
+			return fmt.Errorf("%w: %w", ErrPermissionDenied, err)
+		case respErr.StatusCode == http.StatusTooManyRequests:
+			return fmt.Errorf("%w: %w", ErrRateLimitExceeded, err)
+		case respErr.StatusCode >= http.StatusInternalServerError:
+			return fmt.Errorf("%w: %w", ErrBackendUnavailable, err)
+		}
+		return err
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return fmt.Errorf("%w: %w", ErrBackendUnavailable, err)
+	}
+	return err
+}
+
+// keyURL returns the URL of the key endpoint of prvID, followed by op if not
+// empty.
+func (k *azureKeyVaultKeeper) keyURL(prvID []byte, op string) (string, error) {
+	name, version, ok := strings.Cut(string(prvID), "/")
+	if !ok || !validAzureName(name) || !validAzureName(version) {
+		return "", fmt.Errorf("invalid key vault key id %q", prvID)
+	}
+	return runtime.JoinPaths(k.vaultURL, "keys", name, version, op), nil
+}
+
+// validAzureName reports whether s is a valid name or version of a key.
+func validAzureName(s string) bool {
+	return s != "" && !strings.ContainsFunc(s, func(r rune) bool {
+		return !(r >= '0' && r <= '9' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r == '-')
+	})
+}
+
+// do sends a request to endpoint with body as JSON, decoding the JSON response
+// into out if not nil.
+func (k *azureKeyVaultKeeper) do(ctx context.Context, method, endpoint string, body, out any) error {
+	req, err := runtime.NewRequest(ctx, method, endpoint)
+	if err != nil {
+		return err
+	}
+	query := req.Raw().URL.Query()
+	query.Set("api-version", azureKeyVaultAPIVersion)
+	req.Raw().URL.RawQuery = query.Encode()
+	req.Raw().Header.Set("Accept", "application/json")
+	if body != nil {
+		if err := runtime.MarshalAsJSON(req, body); err != nil {
+			return err
+		}
+	}
+	resp, err := k.pipeline.Do(req)
+	if err != nil {
+		return azureError(err)
+	}
+	if !runtime.HasStatusCode(resp, http.StatusOK) {
+		return azureError(runtime.NewResponseError(resp))
+	}
+	if out == nil {
+		return nil
+	}
+	return runtime.UnmarshalAsJSON(resp, out)
+}
+
+// bundleID returns the prvID of the key bundle, caching its public key.
+func (k *azureKeyVaultKeeper) bundleID(bundle *azureKeyBundle) ([]byte, error) {
+	u, err := url.Parse(bundle.Key.KID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid key id %q in response: %v", bundle.Key.KID, err)
+	}
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if len(parts) != 3 || parts[0] != "keys" {
+		return nil, fmt.Errorf("invalid key id %q in response", bundle.Key.KID)
+	}
+	prvID := []byte(parts[1] + "/" + parts[2])
+
+	pub, err := decodeAzurePublicKey(&bundle.Key)
+	if err != nil {
+		return nil, err
+	}
+	k.pubkeys.Store(string(prvID), pub)
+	return prvID, nil
+}
+
+// decodeAzurePublicKey returns the uncompressed public key of the JSON web key.
+func decodeAzurePublicKey(key *azureKey) ([]byte, error) {
+	if key.KTY != "EC" && key.KTY != "EC-HSM" || key.Crv != azureCurve {
+		return nil, fmt.Errorf("unsupported key type %s %s", key.KTY, key.Crv)
+	}
+	x, err := base64.RawURLEncoding.DecodeString(key.X)
+	if err != nil || len(x) != 32 {
+		return nil, fmt.Errorf("invalid public key x coordinate %q", key.X)
+	}
+	y, err := base64.RawURLEncoding.DecodeString(key.Y)
+	if err != nil || len(y) != 32 {
+		return nil, fmt.Errorf("invalid public key y coordinate %q", key.Y)
+	}
+	pub := append(append([]byte{0x04}, x...), y...)
+	if _, err := crypto.UnmarshalPubkey(pub); err != nil {
+		return nil, err
+	}
+	return pub, nil
+}
+
+func (k *azureKeyVaultKeeper) GeneratePrivateKey() ([]byte, error) {
+	return k.GeneratePrivateKeyContext(context.Background())
+}
+
+func (k *azureKeyVaultKeeper) GeneratePrivateKeyContext(ctx context.Context) (_ []byte, err error) {
+	defer wrapError(&err, "generate key", nil)
+
+	var bundle azureKeyBundle
+	endpoint := runtime.JoinPaths(k.vaultURL, "keys", uuid.New().String(), "create")
+	if err := k.do(ctx, http.MethodPost, endpoint, &azureKey{
+		KTY:    "EC",
+		Crv:    azureCurve,
+		KeyOps: []string{"sign", "verify"},
+	}, &bundle); err != nil {
+		return nil, err
+	}
+	return k.bundleID(&bundle)
+}
+
+// ExportEncryptedKey returns ErrExportNotSupported, Key Vault never hands out
+// the key material of its keys.
+func (k *azureKeyVaultKeeper) ExportEncryptedKey(prvID []byte, passphrase string) (_ []byte, err error) {
+	defer wrapError(&err, "export key", prvID)
+	return nil, ErrExportNotSupported
+}
+
+func (k *azureKeyVaultKeeper) ImportEncryptedKey(data []byte, passphrase string) ([]byte, error) {
+	return importEncryptedKey(k, data, passphrase)
+}
+
+func (k *azureKeyVaultKeeper) ImportPrivateKey(rawKey []byte) ([]byte, error) {
+	return k.ImportPrivateKeyContext(context.Background(), rawKey)
+}
+
+// ImportPrivateKeyContext imports rawKey as a new Key Vault key. The key is
+// sent as a JSON web key, protected by TLS only.
+func (k *azureKeyVaultKeeper) ImportPrivateKeyContext(ctx context.Context, rawKey []byte) (_ []byte, err error) {
+	defer wrapError(&err, "import key", nil)
+
+	key, err := parseRawKey(rawKey)
+	if err != nil {
+		return nil, err
+	}
+	defer ZeroKey(key)
+	pub := crypto.FromECDSAPub(&key.PublicKey)
+
+	var bundle azureKeyBundle
+	endpoint := runtime.JoinPaths(k.vaultURL, "keys", uuid.New().String())
+	if err := k.do(ctx, http.MethodPut, endpoint, &struct {
+		Key *azureKey `json:"key"`
+	}{&azureKey{
+		KTY:    "EC",
+		Crv:    azureCurve,
+		KeyOps: []string{"sign", "verify"},
+		X:      base64.RawURLEncoding.EncodeToString(pub[1:33]),
+		Y:      base64.RawURLEncoding.EncodeToString(pub[33:]),
+		D:      base64.RawURLEncoding.EncodeToString(rawKey),
+	}}, &bundle); err != nil {
+		return nil, err
+	}
+	return k.bundleID(&bundle)
+}
+
+func (k *azureKeyVaultKeeper) GetPublicKey(prvID []byte) ([]byte, error) {
+	return k.GetPublicKeyContext(context.Background(), prvID)
+}
+
+func (k *azureKeyVaultKeeper) GetPublicKeyContext(ctx context.Context, prvID []byte) (_ []byte, err error) {
+	defer wrapError(&err, "get public key", prvID)
+
+	if pub, ok := k.pubkeys.Load(string(prvID)); ok {
+		return pub.([]byte), nil
+	}
+	endpoint, err := k.keyURL(prvID, "")
+	if err != nil {
+		return nil, err
+	}
+	var bundle azureKeyBundle
+	if err := k.do(ctx, http.MethodGet, endpoint, nil, &bundle); err != nil {
+		return nil, err
+	}
+	pub, err := decodeAzurePublicKey(&bundle.Key)
+	if err != nil {
+		return nil, err
+	}
+	k.pubkeys.Store(string(prvID), pub)
+	return pub, nil
+}
+
+func (k *azureKeyVaultKeeper) Sign(data []byte, prvID []byte) ([]byte, error) {
+	return k.SignContext(context.Background(), data, prvID)
+}
+
+// SignContext signs the 32 byte digest data with ES256K. Key Vault returns the
+// JWS signature R || S without a recovery id, which is reconstructed from the
+// public key of prvID.
+func (k *azureKeyVaultKeeper) SignContext(ctx context.Context, data []byte, prvID []byte) (_ []byte, err error) {
+	defer wrapError(&err, "sign", prvID)
+
+	if len(data) != 32 {
+		return nil, fmt.Errorf("hash is required to be exactly 32 bytes (%d)", len(data))
+	}
+	pub, err := k.GetPublicKeyContext(ctx, prvID)
+	if err != nil {
+		return nil, err
+	}
+	endpoint, err := k.keyURL(prvID, "sign")
+	if err != nil {
+		return nil, err
+	}
+	var res azureSignResult
+	if err := k.do(ctx, http.MethodPost, endpoint, map[string]string{
+		"alg":   azureAlgorithm,
+		"value": base64.RawURLEncoding.EncodeToString(data),
+	}, &res); err != nil {
+		return nil, err
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(res.Value)
+	if err != nil || len(sig) != 64 {
+		return nil, fmt.Errorf("%w: malformed key vault signature", ErrInvalidSignature)
+	}
+	return recoverableSignature(data, new(big.Int).SetBytes(sig[:32]), new(big.Int).SetBytes(sig[32:]), pub)
+}
+
+func (k *azureKeyVaultKeeper) DeletePrivateKey(prvID []byte) error {
+	return k.DeletePrivateKeyContext(context.Background(), prvID)
+}
+
+// DeletePrivateKeyContext deletes the Key Vault key with all its versions. On
+// vaults with soft delete enabled, the key is recoverable until purged.
+func (k *azureKeyVaultKeeper) DeletePrivateKeyContext(ctx context.Context, prvID []byte) (err error) {
+	defer wrapError(&err, "delete key", prvID)
+
+	if _, err := k.keyURL(prvID, ""); err != nil {
+		return err
+	}
+	name, _, _ := strings.Cut(string(prvID), "/")
+	if err := k.do(ctx, http.MethodDelete, runtime.JoinPaths(k.vaultURL, "keys", name), nil, nil); err != nil {
+		return err
+	}
+	k.pubkeys.Delete(string(prvID))
+	return nil
+}
+
+func (k *azureKeyVaultKeeper) ListPrivateKeys() ([][]byte, error) {
+	return k.ListPrivateKeysContext(context.Background())
+}
+
+// ListPrivateKeysContext returns the IDs of the current versions of the
+// secp256k1 keys of the vault. Key Vault lists keys without their version and
+// type, so every key is looked up.
+func (k *azureKeyVaultKeeper) ListPrivateKeysContext(ctx context.Context) (_ [][]byte, err error) {
+	defer wrapError(&err, "list keys", nil)
+
+	var prvIDs [][]byte
+	for endpoint := runtime.JoinPaths(k.vaultURL, "keys"); endpoint != ""; {
+		var page azureKeyList
+		if err := k.do(ctx, http.MethodGet, endpoint, nil, &page); err != nil {
+			return nil, err
+		}
+		for _, item := range page.Value {
+			var bundle azureKeyBundle
+			if err := k.do(ctx, http.MethodGet, item.KID, nil, &bundle); err != nil {
+				return nil, err
+			}
+			if bundle.Key.Crv != azureCurve {
+				continue
+			}
+			prvID, err := k.bundleID(&bundle)
+			if err != nil {
+				return nil, err
+			}
+			prvIDs = append(prvIDs, prvID)
+		}
+		endpoint = page.NextLink
+	}
+	return prvIDs, nil
+}
+
+// Snapshot lists the IDs of the keys, the key material never leaves Key Vault.
+func (k *azureKeyVaultKeeper) Snapshot() (_ []byte, err error) {
+	defer wrapError(&err, "snapshot", nil)
+	return snapshotKeyIDs(context.Background(), k)
+}
+
+// Restore checks that the keys of the snapshot still exist in Key Vault.
+func (k *azureKeyVaultKeeper) Restore(snapshot []byte) (err error) {
+	defer wrapError(&err, "restore snapshot", nil)
+	return restoreKeyIDs(context.Background(), k, snapshot)
+}
+
+// HealthCheck lists a single key of the vault.
+func (k *azureKeyVaultKeeper) HealthCheck(ctx context.Context) (err error) {
+	defer wrapError(&err, "health check", nil)
+	return k.do(ctx, http.MethodGet, runtime.JoinPaths(k.vaultURL, "keys")+"?maxresults=1", nil, nil)
+}
@@ -0,0 +1,327 @@
+package keeper
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+const testVaultURL = "https://test.vault.azure.net"
+
+// fakeKeyVault emulates the parts of the Key Vault REST API used by the
+// keeper. It is plugged into the SDK pipeline as its transport.
+type fakeKeyVault struct {
+	lock     sync.Mutex
+	keys     map[string]map[string]*ecdsa.PrivateKey // name -> version -> key
+	versions int
+	scope    string // scope of the token the requests carry
+}
+
+func newFakeKeyVault() *fakeKeyVault {
+	return &fakeKeyVault{keys: make(map[string]map[string]*ecdsa.PrivateKey)}
+}
+
+func (f *fakeKeyVault) Do(req *http.Request) (*http.Response, error) {
+	rec := httptest.NewRecorder()
+	f.ServeHTTP(rec, req)
+	resp := rec.Result()
+	resp.Request = req
+	return resp, nil
+}
+
+// GetToken makes fakeKeyVault an azcore.TokenCredential as well.
+func (f *fakeKeyVault) GetToken(ctx context.Context, opts policy.TokenRequestOptions) (azcore.AccessToken, error) {
+	return azcore.AccessToken{Token: strings.Join(opts.Scopes, " "), ExpiresOn: time.Now().Add(time.Hour)}, nil
+}
+
+func azureFail(w http.ResponseWriter, status int, code string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	fmt.Fprintf(w, `{"error":{"code":%q,"message":"fake key vault"}}`, code)
+}
+
+func (f *fakeKeyVault) bundle(name, version string, key *ecdsa.PrivateKey) map[string]any {
+	pub := crypto.FromECDSAPub(&key.PublicKey)
+	return map[string]any{"key": map[string]any{
+		"kid": testVaultURL + "/keys/" + name + "/" + version,
+		"kty": "EC",
+		"crv": "P-256K",
+		"x":   base64.RawURLEncoding.EncodeToString(pub[1:33]),
+		"y":   base64.RawURLEncoding.EncodeToString(pub[33:]),
+	}}
+}
+
+func (f *fakeKeyVault) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if r.Header.Get("Authorization") != "Bearer "+f.scope {
+		azureFail(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	if r.URL.Query().Get("api-version") != azureKeyVaultAPIVersion {
+		azureFail(w, http.StatusBadRequest, "BadParameter")
+		return
+	}
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/keys"), "/")[1:]
+	var (
+		name    string
+		version string
+	)
+	if len(parts) > 0 {
+		name = parts[0]
+	}
+	if len(parts) > 1 {
+		version = parts[1]
+	}
+	newVersion := func(key *ecdsa.PrivateKey) string {
+		f.versions++
+		version := fmt.Sprintf("v%d", f.versions)
+		if f.keys[name] == nil {
+			f.keys[name] = make(map[string]*ecdsa.PrivateKey)
+		}
+		f.keys[name][version] = key
+		return version
+	}
+	switch {
+	case len(parts) == 0 && r.Method == http.MethodGet:
+		// List the keys one per page, to exercise paging.
+		names := make([]string, 0, len(f.keys))
+		for name := range f.keys {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		skip := 0
+		fmt.Sscan(r.URL.Query().Get("skip"), &skip)
+		page := map[string]any{"value": []any{}}
+		if skip < len(names) {
+			page["value"] = []any{map[string]any{"kid": testVaultURL + "/keys/" + names[skip]}}
+			if skip+1 < len(names) {
+				page["nextLink"] = fmt.Sprintf("%s/keys?api-version=%s&skip=%d", testVaultURL, azureKeyVaultAPIVersion, skip+1)
+			}
+		}
+		json.NewEncoder(w).Encode(page)
+
+	case len(parts) == 2 && version == "create" && r.Method == http.MethodPost:
+		var req azureKey
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.KTY != "EC" || req.Crv != "P-256K" {
+			azureFail(w, http.StatusBadRequest, "BadParameter")
+			return
+		}
+		key, _ := crypto.GenerateKey()
+		json.NewEncoder(w).Encode(f.bundle(name, newVersion(key), key))
+
+	case len(parts) == 1 && r.Method == http.MethodPut:
+		var req struct {
+			Key azureKey `json:"key"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Key.Crv != "P-256K" {
+			azureFail(w, http.StatusBadRequest, "BadParameter")
+			return
+		}
+		d, _ := base64.RawURLEncoding.DecodeString(req.Key.D)
+		key, err := crypto.ToECDSA(d)
+		if err != nil {
+			azureFail(w, http.StatusBadRequest, "BadParameter")
+			return
+		}
+		json.NewEncoder(w).Encode(f.bundle(name, newVersion(key), key))
+
+	case len(parts) == 1 && r.Method == http.MethodDelete:
+		if f.keys[name] == nil {
+			azureFail(w, http.StatusNotFound, "KeyNotFound")
+			return
+		}
+		delete(f.keys, name)
+		w.Write([]byte(`{}`))
+
+	case (len(parts) == 1 || len(parts) == 2) && r.Method == http.MethodGet:
+		versions := f.keys[name]
+		if version == "" {
+			// The current version is the latest one.
+			for v := range versions {
+				if v > version {
+					version = v
+				}
+			}
+		}
+		key, ok := versions[version]
+		if !ok {
+			azureFail(w, http.StatusNotFound, "KeyNotFound")
+			return
+		}
+		json.NewEncoder(w).Encode(f.bundle(name, version, key))
+
+	case len(parts) == 3 && parts[2] == "sign" && r.Method == http.MethodPost:
+		key, ok := f.keys[name][version]
+		if !ok {
+			azureFail(w, http.StatusNotFound, "KeyNotFound")
+			return
+		}
+		var req struct {
+			Alg   string `json:"alg"`
+			Value string `json:"value"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		digest, err := base64.RawURLEncoding.DecodeString(req.Value)
+		if err != nil || req.Alg != "ES256K" || len(digest) != 32 {
+			azureFail(w, http.StatusBadRequest, "BadParameter")
+			return
+		}
+		sig, _ := crypto.Sign(digest, key)
+		json.NewEncoder(w).Encode(map[string]string{
+			"kid":   testVaultURL + "/keys/" + name + "/" + version,
+			"value": base64.RawURLEncoding.EncodeToString(sig[:64]),
+		})
+
+	default:
+		azureFail(w, http.StatusNotFound, "NotFound")
+	}
+}
+
+func newTestAzureKeyVaultKeeper(t *testing.T) (*azureKeyVaultKeeper, *fakeKeyVault) {
+	t.Helper()
+
+	kv := newFakeKeyVault()
+	kv.scope = "https://vault.azure.net/.default"
+	k, err := newAzureKeyVaultKeeper(testVaultURL, kv, &policy.ClientOptions{
+		Transport: kv,
+		Retry:     policy.RetryOptions{MaxRetries: -1},
+	})
+	if err != nil {
+		t.Fatalf("failed to create keeper: %v", err)
+	}
+	return k, kv
+}
+
+func TestAzureKeyVaultKeeper(t *testing.T) {
+	k, kv := newTestAzureKeyVaultKeeper(t)
+
+	prvID, err := k.GeneratePrivateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	name, version, _ := strings.Cut(string(prvID), "/")
+	key, ok := kv.keys[name][version]
+	if !ok {
+		t.Fatalf("key %q not created in key vault", prvID)
+	}
+	pub, err := k.GetPublicKey(prvID)
+	if err != nil {
+		t.Fatalf("failed to get public key: %v", err)
+	}
+	if want := crypto.FromECDSAPub(&key.PublicKey); !bytes.Equal(pub, want) {
+		t.Fatalf("public key mismatch: have %x, want %x", pub, want)
+	}
+	// A fresh keeper looks the public key up.
+	fresh, _ := newAzureKeyVaultKeeper(testVaultURL, kv, &policy.ClientOptions{Transport: kv})
+	if pub2, err := fresh.GetPublicKey(prvID); err != nil || !bytes.Equal(pub2, pub) {
+		t.Fatalf("fresh keeper: have (%x, %v), want %x", pub2, err, pub)
+	}
+	hash := crypto.Keccak256([]byte("azure"))
+	sig, err := k.Sign(hash, prvID)
+	if err != nil {
+		t.Fatalf("failed to sign: %v", err)
+	}
+	if recovered, err := crypto.Ecrecover(hash, sig); err != nil || !bytes.Equal(recovered, pub) {
+		t.Fatalf("recovered key mismatch: have (%x, %v), want %x", recovered, err, pub)
+	}
+	if err := k.HealthCheck(context.Background()); err != nil {
+		t.Fatalf("health check failed: %v", err)
+	}
+	if err := k.DeletePrivateKey(prvID); err != nil {
+		t.Fatalf("failed to delete key: %v", err)
+	}
+	if _, ok := kv.keys[name]; ok {
+		t.Fatalf("key %q not deleted in key vault", prvID)
+	}
+	if _, err := fresh.Sign(hash, prvID); !errors.Is(err, ErrKeyNotFound) {
+		t.Fatalf("sign with deleted key: have %v, want %v", err, ErrKeyNotFound)
+	}
+	for _, id := range []string{"", "name", "name/", "a/b/c", "na?me/v1"} {
+		if _, err := k.GetPublicKey([]byte(id)); err == nil {
+			t.Fatalf("got public key of invalid id %q", id)
+		}
+	}
+}
+
+func TestAzureKeyVaultKeeperAuth(t *testing.T) {
+	kv := newFakeKeyVault()
+	kv.scope = "https://managedhsm.azure.net/.default"
+	k, err := newAzureKeyVaultKeeper(testVaultURL, kv, &policy.ClientOptions{Transport: kv})
+	if err != nil {
+		t.Fatalf("failed to create keeper: %v", err)
+	}
+	// The token of the public cloud vault resource isn't accepted.
+	if _, err := k.GeneratePrivateKey(); !errors.Is(err, ErrPermissionDenied) {
+		t.Fatalf("generate with wrong token: have %v, want %v", err, ErrPermissionDenied)
+	}
+	for _, url := range []string{"http://test.vault.azure.net", "https://localhost", "::"} {
+		if _, err := NewAzureKeyVaultKeeper(url, kv); err == nil {
+			t.Fatalf("created keeper of invalid vault URL %q", url)
+		}
+	}
+}
+
+func TestAzureKeyVaultKeeperImport(t *testing.T) {
+	k, kv := newTestAzureKeyVaultKeeper(t)
+
+	prvID := checkImport(t, k)
+	name, _, _ := strings.Cut(string(prvID), "/")
+	if _, ok := kv.keys[name]; !ok || len(kv.keys) != 1 {
+		t.Fatalf("key vault keys after import: have %d, want only %s", len(kv.keys), prvID)
+	}
+	if _, err := k.ExportEncryptedKey(prvID, "pass"); !errors.Is(err, ErrExportNotSupported) {
+		t.Fatalf("export: have %v, want %v", err, ErrExportNotSupported)
+	}
+}
+
+func TestAzureKeyVaultKeeperList(t *testing.T) {
+	k, _ := newTestAzureKeyVaultKeeper(t)
+
+	if ids, err := k.ListPrivateKeys(); err != nil || len(ids) != 0 {
+		t.Fatalf("empty vault: have (%q, %v), want no keys", ids, err)
+	}
+	want := make(map[string]bool)
+	for i := 0; i < 3; i++ {
+		prvID, err := k.GeneratePrivateKey()
+		if err != nil {
+			t.Fatalf("failed to generate key: %v", err)
+		}
+		want[string(prvID)] = true
+	}
+	ids, err := k.ListPrivateKeys()
+	if err != nil {
+		t.Fatalf("failed to list keys: %v", err)
+	}
+	if len(ids) != len(want) {
+		t.Fatalf("key count mismatch: have %d, want %d", len(ids), len(want))
+	}
+	for _, id := range ids {
+		if !want[string(id)] {
+			t.Errorf("unexpected key %q", id)
+		}
+	}
+	snapshot, err := k.Snapshot()
+	if err != nil {
+		t.Fatalf("failed to snapshot: %v", err)
+	}
+	if err := k.Restore(snapshot); err != nil {
+		t.Fatalf("failed to restore: %v", err)
+	}
+}
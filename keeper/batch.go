@@ -0,0 +1,78 @@
+package keeper
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// SignBatch signs the transactions one after the other. If signing fails, the
+// transactions signed so far are returned along with the error, so the index of
+// the failed transaction is the length of the returned slice.
+func (sec *SecureSign) SignBatch(txs []*types.Transaction, s types.Signer, prvID []byte) ([]*types.Transaction, error) {
+	return signBatch(txs, func(tx *types.Transaction) (*types.Transaction, error) {
+		return sec.Sign(tx, s, prvID)
+	})
+}
+
+// signBatch implements SignBatch on top of a single transaction signing
+// function, so that signers wrapping a SecureSigner can sign batches through
+// their own Sign.
+func signBatch(txs []*types.Transaction, sign func(*types.Transaction) (*types.Transaction, error)) ([]*types.Transaction, error) {
+	signed := make([]*types.Transaction, 0, len(txs))
+	for i, tx := range txs {
+		stx, err := sign(tx)
+		if err != nil {
+			return signed, fmt.Errorf("transaction %d: %w", i, err)
+		}
+		signed = append(signed, stx)
+	}
+	return signed, nil
+}
+
+// SignBatchParallel signs the transactions concurrently on a pool of one worker
+// per CPU. The signed transactions are returned in the original order. If any
+// transaction fails to sign, the transactions preceding the first failed one
+// are returned along with its error, same as SignBatch would.
+func (sec *SecureSign) SignBatchParallel(txs []*types.Transaction, s types.Signer, prvID []byte) ([]*types.Transaction, error) {
+	return signBatchParallel(txs, func(tx *types.Transaction) (*types.Transaction, error) {
+		return sec.Sign(tx, s, prvID)
+	})
+}
+
+// signBatchParallel is the concurrent counterpart of signBatch.
+func signBatchParallel(txs []*types.Transaction, sign func(*types.Transaction) (*types.Transaction, error)) ([]*types.Transaction, error) {
+	var (
+		signed = make([]*types.Transaction, len(txs))
+		errs   = make([]error, len(txs))
+		tasks  = make(chan int)
+		wg     sync.WaitGroup
+	)
+	workers := runtime.NumCPU()
+	if workers > len(txs) {
+		workers = len(txs)
+	}
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for idx := range tasks {
+				signed[idx], errs[idx] = sign(txs[idx])
+			}
+		}()
+	}
+	for i := range txs {
+		tasks <- i
+	}
+	close(tasks)
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			return signed[:i], fmt.Errorf("transaction %d: %w", i, err)
+		}
+	}
+	return signed, nil
+}
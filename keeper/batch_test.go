@@ -0,0 +1,122 @@
+package keeper
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// failingKeeper fails to sign failData and signs anything else.
+type failingKeeper struct {
+	defaultPrivateKeyKeeper
+	failData []byte
+}
+
+var errTestSign = errors.New("sign failed")
+
+func (f *failingKeeper) Sign(data []byte, prvID []byte) ([]byte, error) {
+	return f.SignContext(context.Background(), data, prvID)
+}
+
+func (f *failingKeeper) SignContext(ctx context.Context, data []byte, prvID []byte) ([]byte, error) {
+	if bytes.Equal(data, f.failData) {
+		return nil, errTestSign
+	}
+	return f.defaultPrivateKeyKeeper.SignContext(ctx, data, prvID)
+}
+
+func newTestBatch(n int) []*types.Transaction {
+	txs := make([]*types.Transaction, n)
+	for i := range txs {
+		txs[i] = types.NewTx(&types.LegacyTx{
+			Nonce:    uint64(i),
+			To:       &common.Address{0x01},
+			Value:    big.NewInt(1),
+			Gas:      21000,
+			GasPrice: big.NewInt(1),
+		})
+	}
+	return txs
+}
+
+func TestSignBatch(t *testing.T) {
+	signer := types.NewEIP155Signer(big.NewInt(1))
+	for _, parallel := range []bool{false, true} {
+		sec, prvID, addr := newTestSigner(t)
+		sign := sec.SignBatch
+		if parallel {
+			sign = sec.SignBatchParallel
+		}
+		txs := newTestBatch(64)
+		signed, err := sign(txs, signer, prvID)
+		if err != nil {
+			t.Fatalf("parallel=%v: failed to sign batch: %v", parallel, err)
+		}
+		if len(signed) != len(txs) {
+			t.Fatalf("parallel=%v: batch length mismatch: have %d, want %d", parallel, len(signed), len(txs))
+		}
+		for i, tx := range signed {
+			if tx.Nonce() != uint64(i) {
+				t.Fatalf("parallel=%v: transaction %d out of order: nonce %d", parallel, i, tx.Nonce())
+			}
+			if from, err := types.Sender(signer, tx); err != nil || from != addr {
+				t.Fatalf("parallel=%v: transaction %d sender mismatch: have (%v, %v), want %v", parallel, i, from, err, addr)
+			}
+		}
+	}
+}
+
+func TestSignBatchFailure(t *testing.T) {
+	signer := types.NewEIP155Signer(big.NewInt(1))
+	txs := newTestBatch(16)
+
+	for _, parallel := range []bool{false, true} {
+		h := signer.Hash(txs[10])
+		sec := NewSecureSign(&failingKeeper{failData: h[:]})
+		prvID, _ := sec.GenerateKey()
+
+		sign := sec.SignBatch
+		if parallel {
+			sign = sec.SignBatchParallel
+		}
+		signed, err := sign(txs, signer, prvID)
+		if !errors.Is(err, errTestSign) {
+			t.Fatalf("parallel=%v: error mismatch: have %v, want %v", parallel, err, errTestSign)
+		}
+		if len(signed) != 10 {
+			t.Fatalf("parallel=%v: signed prefix length mismatch: have %d, want 10", parallel, len(signed))
+		}
+		for i, tx := range signed {
+			if tx == nil || tx.Nonce() != uint64(i) {
+				t.Fatalf("parallel=%v: transaction %d missing or out of order", parallel, i)
+			}
+		}
+	}
+}
+
+func BenchmarkSignBatch(b *testing.B) {
+	signer := types.NewEIP155Signer(big.NewInt(1))
+	sec := DefaultSecureSign()
+	prvID, _ := sec.GenerateKey()
+	txs := newTestBatch(256)
+
+	b.Run("sequential", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, err := sec.SignBatch(txs, signer, prvID); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("parallel", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, err := sec.SignBatchParallel(txs, signer, prvID); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
@@ -0,0 +1,255 @@
+package keeper
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	blsu "github.com/protolambda/bls12-381-util"
+)
+
+// blsKeyPrefix marks the prvIDs of BLS keys, telling them apart from the raw
+// secp256k1 keys of the default keeper.
+const blsKeyPrefix = "bls:"
+
+// blsGroupOrder is the order r of the BLS12-381 groups, the exclusive upper
+// bound of secret keys.
+var blsGroupOrder, _ = new(big.Int).SetString("73eda753299d7d483339d80809a1d80553bda402fffe5bfeffffffff00000001", 16)
+
+// BLSKeeper is a PrivateKeyKeeper that also holds BLS12-381 keys, as used by
+// beacon chain validators. Signatures follow the proof-of-possession scheme of
+// the consensus specs: public keys are 48 byte compressed G1 points and
+// signatures are 96 byte compressed G2 points.
+type BLSKeeper interface {
+	PrivateKeyKeeper
+	// BLSGenerateKey return identifier of new generated BLS private key
+	BLSGenerateKey() (prvID []byte, err error)
+	// BLSGetPublicKey return the compressed BLS public key by private key ID
+	BLSGetPublicKey(prvID []byte) ([]byte, error)
+	// BLSSign of the message data by BLS private key ID
+	BLSSign(data []byte, prvID []byte) ([]byte, error)
+}
+
+// blsKeeper is a BLSKeeper without hiding the private key, like the default
+// keeper. The prvIDs of BLS keys are the raw secret key after blsKeyPrefix,
+// all other prvIDs are handled by the default keeper.
+type blsKeeper struct {
+	inner defaultPrivateKeyKeeper
+}
+
+// NewBLSKeeper returns a BLSKeeper whose prvIDs are the private keys
+// themselves, as those of the default keeper.
+func NewBLSKeeper() BLSKeeper {
+	return &blsKeeper{}
+}
+
+// IsBLSKeyID reports whether prvID identifies a BLS key of the keeper returned
+// by NewBLSKeeper.
+func IsBLSKeyID(prvID []byte) bool {
+	return bytes.HasPrefix(prvID, []byte(blsKeyPrefix))
+}
+
+// blsSecretKey parses the BLS prvID.
+func blsSecretKey(prvID []byte) (*blsu.SecretKey, error) {
+	if !IsBLSKeyID(prvID) {
+		return nil, fmt.Errorf("%w: not a BLS key", ErrInvalidKey)
+	}
+	raw := prvID[len(blsKeyPrefix):]
+	if len(raw) != 32 || new(big.Int).SetBytes(raw).Cmp(blsGroupOrder) >= 0 {
+		return nil, fmt.Errorf("%w: BLS secret key out of range", ErrInvalidKey)
+	}
+	var sk blsu.SecretKey
+	if err := sk.Deserialize((*[32]byte)(raw)); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidKey, err)
+	}
+	return &sk, nil
+}
+
+func (k *blsKeeper) BLSGenerateKey() (_ []byte, err error) {
+	defer wrapError(&err, "generate key", nil)
+	// Reducing 48 random bytes modulo r, as the hash_to_field of the key
+	// generation of the BLS signature draft, keeps the bias negligible.
+	seed := make([]byte, 48)
+	for {
+		if _, err := rand.Read(seed); err != nil {
+			return nil, err
+		}
+		sk := new(big.Int).Mod(new(big.Int).SetBytes(seed), blsGroupOrder)
+		if sk.Sign() != 0 {
+			return append([]byte(blsKeyPrefix), sk.FillBytes(make([]byte, 32))...), nil
+		}
+	}
+}
+
+func (k *blsKeeper) BLSGetPublicKey(prvID []byte) (_ []byte, err error) {
+	defer wrapError(&err, "get public key", prvID)
+	sk, err := blsSecretKey(prvID)
+	if err != nil {
+		return nil, err
+	}
+	pub, err := blsu.SkToPk(sk)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidKey, err)
+	}
+	out := pub.Serialize()
+	return out[:], nil
+}
+
+func (k *blsKeeper) BLSSign(data []byte, prvID []byte) (_ []byte, err error) {
+	defer wrapError(&err, "sign", prvID)
+	sk, err := blsSecretKey(prvID)
+	if err != nil {
+		return nil, err
+	}
+	sig := blsu.Sign(sk, data)
+	out := sig.Serialize()
+	return out[:], nil
+}
+
+func (k *blsKeeper) GeneratePrivateKey() ([]byte, error) {
+	return k.inner.GeneratePrivateKey()
+}
+
+// GetPublicKey refuses BLS keys, their public keys are not secp256k1 keys.
+func (k *blsKeeper) GetPublicKey(prvID []byte) (_ []byte, err error) {
+	if IsBLSKeyID(prvID) {
+		defer wrapError(&err, "get public key", prvID)
+		return nil, fmt.Errorf("%w: secp256k1 public key of BLS key", ErrNotSupported)
+	}
+	return k.inner.GetPublicKey(prvID)
+}
+
+// Sign refuses BLS keys, use BLSSign for them.
+func (k *blsKeeper) Sign(data []byte, prvID []byte) (_ []byte, err error) {
+	if IsBLSKeyID(prvID) {
+		defer wrapError(&err, "sign", prvID)
+		return nil, fmt.Errorf("%w: secp256k1 signature with BLS key", ErrNotSupported)
+	}
+	return k.inner.Sign(data, prvID)
+}
+
+// DeletePrivateKey is a no-op, the keeper doesn't store any keys.
+func (k *blsKeeper) DeletePrivateKey(prvID []byte) error {
+	return k.inner.DeletePrivateKey(prvID)
+}
+
+// ListPrivateKeys returns ErrNotSupported, the keeper doesn't store any keys.
+func (k *blsKeeper) ListPrivateKeys() ([][]byte, error) {
+	return k.inner.ListPrivateKeys()
+}
+
+// ImportPrivateKey imports a raw secp256k1 key.
+func (k *blsKeeper) ImportPrivateKey(rawKey []byte) ([]byte, error) {
+	return k.inner.ImportPrivateKey(rawKey)
+}
+
+// The beacon chain domain types of the signed messages.
+var (
+	domainBeaconProposer = [4]byte{0x00, 0x00, 0x00, 0x00}
+	domainBeaconAttester = [4]byte{0x01, 0x00, 0x00, 0x00}
+	domainDeposit        = [4]byte{0x03, 0x00, 0x00, 0x00}
+)
+
+// BeaconFork identifies the beacon chain and fork a message is signed for.
+type BeaconFork struct {
+	Version               [4]byte     // fork version at the epoch of the message
+	GenesisValidatorsRoot common.Hash // genesis validators root of the chain
+}
+
+// beaconDomain returns the signature domain of domainType in fork, as
+// compute_domain of the consensus specs.
+func beaconDomain(domainType [4]byte, fork BeaconFork) (domain [32]byte) {
+	var version [32]byte
+	copy(version[:], fork.Version[:])
+	// The hash tree root of ForkData, two 32 byte leaves.
+	forkDataRoot := sha256.Sum256(append(version[:], fork.GenesisValidatorsRoot[:]...))
+
+	copy(domain[:], domainType[:])
+	copy(domain[4:], forkDataRoot[:28])
+	return domain
+}
+
+// beaconSigningRoot returns the signing root of objectRoot in domain, as
+// compute_signing_root of the consensus specs.
+func beaconSigningRoot(objectRoot common.Hash, domain [32]byte) common.Hash {
+	// The hash tree root of SigningData, two 32 byte leaves.
+	return sha256.Sum256(append(objectRoot[:], domain[:]...))
+}
+
+// SignBeaconBlock signs the beacon block with hash tree root blockRoot for the
+// proposer domain of fork, returning the 96 byte BLS signature.
+func SignBeaconBlock(k BLSKeeper, fork BeaconFork, blockRoot common.Hash, prvID []byte) ([]byte, error) {
+	root := beaconSigningRoot(blockRoot, beaconDomain(domainBeaconProposer, fork))
+	return k.BLSSign(root[:], prvID)
+}
+
+// SignAttestation signs the attestation data with hash tree root dataRoot for
+// the attester domain of fork, returning the 96 byte BLS signature.
+func SignAttestation(k BLSKeeper, fork BeaconFork, dataRoot common.Hash, prvID []byte) ([]byte, error) {
+	root := beaconSigningRoot(dataRoot, beaconDomain(domainBeaconAttester, fork))
+	return k.BLSSign(root[:], prvID)
+}
+
+// GenerateValidatorKey generates the BLS signing key of a validator and the BLS
+// key its withdrawal credentials commit to. The withdrawal key should be kept
+// apart from the signing key afterwards, it is only needed to withdraw.
+func GenerateValidatorKey(k BLSKeeper) (validatorPrvID, withdrawalPrvID []byte, err error) {
+	if validatorPrvID, err = k.BLSGenerateKey(); err != nil {
+		return nil, nil, err
+	}
+	if withdrawalPrvID, err = k.BLSGenerateKey(); err != nil {
+		k.DeletePrivateKey(validatorPrvID)
+		return nil, nil, err
+	}
+	return validatorPrvID, withdrawalPrvID, nil
+}
+
+// SignDepositData signs the deposit of amount gwei to the validator key prvID,
+// withdrawable by the 48 byte BLS public key withdrawalPubKey, returning the 96
+// byte BLS signature of the deposit data. Deposits are signed in the deposit
+// domain of the genesis fork version of the chain, with a zero genesis
+// validators root, so that they are valid before genesis and across forks.
+func SignDepositData(k BLSKeeper, withdrawalPubKey []byte, amount uint64, genesisForkVersion [4]byte, prvID []byte) (_ []byte, err error) {
+	defer wrapError(&err, "sign", prvID)
+
+	if len(withdrawalPubKey) != 48 {
+		return nil, fmt.Errorf("withdrawal public key must be 48 bytes long (%d)", len(withdrawalPubKey))
+	}
+	pub, err := k.BLSGetPublicKey(prvID)
+	if err != nil {
+		return nil, err
+	}
+	root := depositMessageRoot(pub, blsWithdrawalCredentials(withdrawalPubKey), amount)
+	signingRoot := beaconSigningRoot(root, beaconDomain(domainDeposit, BeaconFork{Version: genesisForkVersion}))
+	return k.BLSSign(signingRoot[:], prvID)
+}
+
+// blsWithdrawalCredentials returns the BLS withdrawal credentials of
+// withdrawalPubKey, the 0x00 prefix followed by the SHA-256 of the key without
+// its first byte.
+func blsWithdrawalCredentials(withdrawalPubKey []byte) (credentials common.Hash) {
+	credentials = sha256.Sum256(withdrawalPubKey)
+	credentials[0] = 0x00
+	return credentials
+}
+
+// depositMessageRoot returns the hash tree root of the DepositMessage of the
+// consensus specs, the merkle root of four 32 byte leaves: the root of the
+// public key, the withdrawal credentials, the little endian amount and a zero
+// leaf padding the three fields.
+func depositMessageRoot(pub []byte, credentials common.Hash, amount uint64) common.Hash {
+	var pubChunks [64]byte // the 48 byte key, zero padded to two chunks
+	copy(pubChunks[:], pub)
+	pubRoot := sha256.Sum256(pubChunks[:])
+
+	var amountChunks [64]byte // the amount chunk and the zero padding leaf
+	binary.LittleEndian.PutUint64(amountChunks[:8], amount)
+
+	left := sha256.Sum256(append(pubRoot[:], credentials[:]...))
+	right := sha256.Sum256(amountChunks[:])
+	return sha256.Sum256(append(left[:], right[:]...))
+}
@@ -0,0 +1,186 @@
+package keeper
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/beacon/params"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	blsu "github.com/protolambda/bls12-381-util"
+	zrnt "github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/ztyp/tree"
+)
+
+// verifyBLS checks sig over msg against the compressed public key pub.
+func verifyBLS(t *testing.T, pub, msg, sig []byte) bool {
+	t.Helper()
+	var (
+		pk blsu.Pubkey
+		s  blsu.Signature
+	)
+	if err := pk.Deserialize((*[48]byte)(pub)); err != nil {
+		t.Fatalf("invalid public key: %v", err)
+	}
+	if err := s.Deserialize((*[96]byte)(sig)); err != nil {
+		t.Fatalf("invalid signature: %v", err)
+	}
+	return blsu.Verify(&pk, msg, &s)
+}
+
+func TestBLSKeeper(t *testing.T) {
+	k := NewBLSKeeper()
+	prvID, err := k.BLSGenerateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	if !IsBLSKeyID(prvID) {
+		t.Fatalf("generated prvID without BLS prefix: %x", prvID)
+	}
+	pub, err := k.BLSGetPublicKey(prvID)
+	if err != nil {
+		t.Fatalf("failed to get public key: %v", err)
+	}
+	msg := []byte("attestation")
+	sig, err := k.BLSSign(msg, prvID)
+	if err != nil {
+		t.Fatalf("failed to sign: %v", err)
+	}
+	if !verifyBLS(t, pub, msg, sig) {
+		t.Fatal("signature doesn't verify")
+	}
+	if verifyBLS(t, pub, []byte("other"), sig) {
+		t.Fatal("signature verifies for another message")
+	}
+	// BLS and secp256k1 keys must not be mixed up.
+	if _, err := k.Sign(make([]byte, 32), prvID); !errors.Is(err, ErrNotSupported) {
+		t.Fatalf("secp256k1 signature with BLS key: have %v, want %v", err, ErrNotSupported)
+	}
+	if _, err := k.GetPublicKey(prvID); !errors.Is(err, ErrNotSupported) {
+		t.Fatalf("secp256k1 public key of BLS key: have %v, want %v", err, ErrNotSupported)
+	}
+	ecdsaID, err := k.GeneratePrivateKey()
+	if err != nil {
+		t.Fatalf("failed to generate secp256k1 key: %v", err)
+	}
+	if IsBLSKeyID(ecdsaID) {
+		t.Fatalf("secp256k1 prvID with BLS prefix: %x", ecdsaID)
+	}
+	if _, err := k.BLSSign(msg, ecdsaID); !errors.Is(err, ErrInvalidKey) {
+		t.Fatalf("BLS signature with secp256k1 key: have %v, want %v", err, ErrInvalidKey)
+	}
+	if _, err := k.Sign(make([]byte, 32), ecdsaID); err != nil {
+		t.Fatalf("failed to sign with secp256k1 key: %v", err)
+	}
+	for _, raw := range [][]byte{make([]byte, 32), blsGroupOrder.Bytes(), make([]byte, 31)} {
+		if _, err := k.BLSSign(msg, append([]byte(blsKeyPrefix), raw...)); !errors.Is(err, ErrInvalidKey) {
+			t.Fatalf("key %x: have %v, want %v", raw, err, ErrInvalidKey)
+		}
+	}
+	checkImport(t, k)
+}
+
+func TestBLSSignVector(t *testing.T) {
+	// Test case sign_case_84d45c9c7cca6b92 of the consensus spec BLS tests.
+	var (
+		sk   = hexutil.MustDecode("0x263dbd792f5b1be47ed85f8938c0f29586af0d3ac7b977f21c278fe1462040e3")
+		msg  = bytes.Repeat([]byte{0x56}, 32)
+		want = hexutil.MustDecode("0x882730e5d03f6b42c3abc26d3372625034e1d871b65a8a6b900a56dae22da98abbe1b68f85e49fe7652a55ec3d0591c20767677e33e5cbb1207315c41a9ac03be39c2e7668edc043d6cb1d9fd93033caa8a1c5b0e84bedaeb6c64972503a43eb")
+	)
+	sig, err := NewBLSKeeper().BLSSign(msg, append([]byte(blsKeyPrefix), sk...))
+	if err != nil {
+		t.Fatalf("failed to sign: %v", err)
+	}
+	if !bytes.Equal(sig, want) {
+		t.Fatalf("signature mismatch: have %x, want %x", sig, want)
+	}
+}
+
+func TestBeaconSigningRoot(t *testing.T) {
+	// The light client computes the sync committee signing roots the same
+	// way, only the domain type differs.
+	var (
+		config = params.MainnetLightConfig
+		root   = common.HexToHash("0x0102")
+		epoch  = uint64(300000)
+		fork   = config.ForkAtEpoch(epoch)
+	)
+	want, err := config.Forks.SigningRoot(epoch, root)
+	if err != nil {
+		t.Fatalf("failed to compute reference root: %v", err)
+	}
+	domain := beaconDomain([4]byte{0x07}, BeaconFork{Version: [4]byte(fork.Version), GenesisValidatorsRoot: config.GenesisValidatorsRoot})
+	if have := beaconSigningRoot(root, domain); have != want {
+		t.Fatalf("signing root mismatch: have %x, want %x", have, want)
+	}
+
+	// Blocks and attestations are signed in distinct domains.
+	k := NewBLSKeeper()
+	prvID, _ := k.BLSGenerateKey()
+	pub, _ := k.BLSGetPublicKey(prvID)
+	bf := BeaconFork{Version: [4]byte(fork.Version), GenesisValidatorsRoot: config.GenesisValidatorsRoot}
+	blockSig, err := SignBeaconBlock(k, bf, root, prvID)
+	if err != nil {
+		t.Fatalf("failed to sign block: %v", err)
+	}
+	blockRoot := beaconSigningRoot(root, beaconDomain(domainBeaconProposer, bf))
+	if !verifyBLS(t, pub, blockRoot[:], blockSig) {
+		t.Fatal("block signature doesn't verify")
+	}
+	attSig, err := SignAttestation(k, bf, root, prvID)
+	if err != nil {
+		t.Fatalf("failed to sign attestation: %v", err)
+	}
+	if bytes.Equal(attSig, blockSig) {
+		t.Fatal("attestation and block signatures of the same root are equal")
+	}
+	attRoot := beaconSigningRoot(root, beaconDomain(domainBeaconAttester, bf))
+	if !verifyBLS(t, pub, attRoot[:], attSig) {
+		t.Fatal("attestation signature doesn't verify")
+	}
+}
+
+func TestSignDepositData(t *testing.T) {
+	k := NewBLSKeeper()
+	validator, withdrawal, err := GenerateValidatorKey(k)
+	if err != nil {
+		t.Fatalf("failed to generate validator key: %v", err)
+	}
+	if !IsBLSKeyID(validator) || !IsBLSKeyID(withdrawal) || bytes.Equal(validator, withdrawal) {
+		t.Fatalf("validator keys mismatch: %x and %x", validator, withdrawal)
+	}
+	pub, _ := k.BLSGetPublicKey(validator)
+	withdrawalPub, _ := k.BLSGetPublicKey(withdrawal)
+
+	var (
+		amount  = uint64(32_000_000_000)
+		version = [4]byte{0x01, 0x01, 0x70, 0x00} // genesis fork version of Holesky
+	)
+	sig, err := SignDepositData(k, withdrawalPub, amount, version, validator)
+	if err != nil {
+		t.Fatalf("failed to sign deposit: %v", err)
+	}
+	// Check the deposit data as the beacon chain does, with the types of zrnt.
+	data := zrnt.DepositData{Amount: zrnt.Gwei(amount)}
+	copy(data.Pubkey[:], pub)
+	data.WithdrawalCredentials = zrnt.Root(blsWithdrawalCredentials(withdrawalPub))
+	copy(data.Signature[:], sig)
+
+	if h := sha256.Sum256(withdrawalPub); data.WithdrawalCredentials[0] != 0x00 || !bytes.Equal(data.WithdrawalCredentials[1:], h[1:]) {
+		t.Fatalf("withdrawal credentials mismatch: %x", data.WithdrawalCredentials)
+	}
+	if have, want := depositMessageRoot(pub, blsWithdrawalCredentials(withdrawalPub), amount), data.MessageRoot(); have != common.Hash(want) {
+		t.Fatalf("deposit message root mismatch: have %x, want %x", have, want)
+	}
+	domain := zrnt.ComputeDomain(zrnt.DOMAIN_DEPOSIT, zrnt.Version(version), zrnt.Root{})
+	root := zrnt.ComputeSigningRoot(data.ToMessage().HashTreeRoot(tree.GetHashFn()), domain)
+	if !verifyBLS(t, pub, root[:], sig) {
+		t.Fatal("deposit signature doesn't verify")
+	}
+
+	if _, err := SignDepositData(k, withdrawalPub[:47], amount, version, validator); err == nil {
+		t.Fatal("signed deposit to short withdrawal key")
+	}
+}
@@ -0,0 +1,149 @@
+package keeper
+
+import (
+	"context"
+	"encoding/hex"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// cachedPublicKey is a public key held by the cache until expires.
+type cachedPublicKey struct {
+	pub     []byte
+	expires time.Time
+}
+
+// cachedKeeper is a PrivateKeyKeeper remembering the public keys of its inner
+// keeper for a while. It saves the round trip to remote backends such as a KMS,
+// Vault or a gRPC keeper, where GetPublicKey is called on most operations.
+// Signatures are never cached.
+//
+// The expiration loop only references keyCache, so the cachedKeeper can be
+// collected when unused, which ends the loop.
+type cachedKeeper struct {
+	*keyCache
+}
+
+type keyCache struct {
+	inner   PrivateKeyKeeperContext
+	ttl     time.Duration
+	entries sync.Map // hex prvID -> *cachedPublicKey
+	quit    chan struct{}
+}
+
+// NewCachedKeeper returns a PrivateKeyKeeper caching the public keys of inner
+// for ttl, 5 minutes if ttl isn't positive. Expired keys are evicted in the
+// background.
+func NewCachedKeeper(inner PrivateKeyKeeper, ttl time.Duration) PrivateKeyKeeper {
+	if ttl <= 0 {
+		ttl = 5 * time.Minute
+	}
+	c := &keyCache{
+		inner: ContextKeeper(inner),
+		ttl:   ttl,
+		quit:  make(chan struct{}),
+	}
+	go c.expireLoop()
+
+	k := &cachedKeeper{c}
+	runtime.SetFinalizer(k, func(k *cachedKeeper) { close(k.quit) })
+	return k
+}
+
+// expireLoop evicts the expired public keys every ttl.
+func (c *keyCache) expireLoop() {
+	ticker := time.NewTicker(c.ttl)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case now := <-ticker.C:
+			c.entries.Range(func(key, value any) bool {
+				if now.After(value.(*cachedPublicKey).expires) {
+					c.entries.Delete(key)
+				}
+				return true
+			})
+		case <-c.quit:
+			return
+		}
+	}
+}
+
+func (c *keyCache) GeneratePrivateKey() ([]byte, error) {
+	return c.GeneratePrivateKeyContext(context.Background())
+}
+
+func (c *keyCache) GeneratePrivateKeyContext(ctx context.Context) ([]byte, error) {
+	prvID, err := c.inner.GeneratePrivateKeyContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// Backends may hand out the identifier of a deleted key again.
+	c.entries.Delete(hex.EncodeToString(prvID))
+	return prvID, nil
+}
+
+func (c *keyCache) GetPublicKey(prvID []byte) ([]byte, error) {
+	return c.GetPublicKeyContext(context.Background(), prvID)
+}
+
+func (c *keyCache) GetPublicKeyContext(ctx context.Context, prvID []byte) ([]byte, error) {
+	key := hex.EncodeToString(prvID)
+	if value, ok := c.entries.Load(key); ok {
+		// The loop evicts only every ttl, don't serve keys it hasn't got to yet.
+		if entry := value.(*cachedPublicKey); time.Now().Before(entry.expires) {
+			return entry.pub, nil
+		}
+	}
+	pub, err := c.inner.GetPublicKeyContext(ctx, prvID)
+	if err != nil {
+		return nil, err
+	}
+	c.entries.Store(key, &cachedPublicKey{pub: pub, expires: time.Now().Add(c.ttl)})
+	return pub, nil
+}
+
+func (c *keyCache) Sign(data []byte, prvID []byte) ([]byte, error) {
+	return c.SignContext(context.Background(), data, prvID)
+}
+
+func (c *keyCache) SignContext(ctx context.Context, data []byte, prvID []byte) ([]byte, error) {
+	return c.inner.SignContext(ctx, data, prvID)
+}
+
+func (c *keyCache) DeletePrivateKey(prvID []byte) error {
+	return c.DeletePrivateKeyContext(context.Background(), prvID)
+}
+
+func (c *keyCache) DeletePrivateKeyContext(ctx context.Context, prvID []byte) error {
+	c.entries.Delete(hex.EncodeToString(prvID))
+	return c.inner.DeletePrivateKeyContext(ctx, prvID)
+}
+
+func (c *keyCache) ListPrivateKeys() ([][]byte, error) {
+	return c.ListPrivateKeysContext(context.Background())
+}
+
+func (c *keyCache) ListPrivateKeysContext(ctx context.Context) ([][]byte, error) {
+	return c.inner.ListPrivateKeysContext(ctx)
+}
+
+func (c *keyCache) ImportPrivateKey(rawKey []byte) ([]byte, error) {
+	return c.ImportPrivateKeyContext(context.Background(), rawKey)
+}
+
+func (c *keyCache) ImportPrivateKeyContext(ctx context.Context, rawKey []byte) ([]byte, error) {
+	prvID, err := c.inner.ImportPrivateKeyContext(ctx, rawKey)
+	if err != nil {
+		return nil, err
+	}
+	// Backends may hand out the identifier of a deleted key again.
+	c.entries.Delete(hex.EncodeToString(prvID))
+	return prvID, nil
+}
+
+func (c *keyCache) HealthCheck(ctx context.Context) error {
+	return checkHealth(ctx, c.inner)
+}
@@ -0,0 +1,94 @@
+package keeper
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestCachedKeeper(t *testing.T) {
+	inner := new(countingKeeper)
+	k := NewCachedKeeper(inner, time.Hour)
+
+	prvID, err := k.GeneratePrivateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	pub, err := k.GetPublicKey(prvID)
+	if err != nil {
+		t.Fatalf("failed to get public key: %v", err)
+	}
+	inner.calls = 0
+	for i := 0; i < 10; i++ {
+		if have, err := k.GetPublicKey(prvID); err != nil || !bytes.Equal(have, pub) {
+			t.Fatalf("cached public key: have (%x, %v), want %x", have, err, pub)
+		}
+	}
+	if inner.calls != 0 {
+		t.Fatalf("cached lookups reached inner keeper: have %d calls, want 0", inner.calls)
+	}
+	// Signatures must always come from the inner keeper.
+	hash := crypto.Keccak256([]byte("cache"))
+	for i := 0; i < 2; i++ {
+		if _, err := k.Sign(hash, prvID); err != nil {
+			t.Fatalf("failed to sign: %v", err)
+		}
+	}
+	if inner.calls != 2 {
+		t.Fatalf("signatures: have %d inner calls, want 2", inner.calls)
+	}
+	// Deleting the key must drop it from the cache.
+	if err := k.DeletePrivateKey(prvID); err != nil {
+		t.Fatalf("failed to delete: %v", err)
+	}
+	inner.calls = 0
+	if _, err := k.GetPublicKey(prvID); err != nil {
+		t.Fatalf("failed to get public key: %v", err)
+	}
+	if inner.calls != 1 {
+		t.Fatalf("lookup after delete: have %d inner calls, want 1", inner.calls)
+	}
+}
+
+func TestCachedKeeperExpiry(t *testing.T) {
+	inner := new(countingKeeper)
+	k := NewCachedKeeper(inner, 20*time.Millisecond)
+
+	prvID, err := inner.GeneratePrivateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	inner.calls = 0
+	if _, err := k.GetPublicKey(prvID); err != nil {
+		t.Fatalf("failed to get public key: %v", err)
+	}
+	// The background loop has to evict the key once it expired.
+	cache := k.(*cachedKeeper).keyCache
+	for deadline := time.Now().Add(time.Second); ; {
+		if _, ok := cache.entries.Load(hex.EncodeToString(prvID)); !ok {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("expired key not evicted")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if _, err := k.GetPublicKey(prvID); err != nil {
+		t.Fatalf("failed to get public key: %v", err)
+	}
+	if inner.calls != 2 {
+		t.Fatalf("lookup after expiry: have %d inner calls, want 2", inner.calls)
+	}
+}
+
+func TestCachedKeeperDefaultTTL(t *testing.T) {
+	for _, ttl := range []time.Duration{0, -time.Second} {
+		k := NewCachedKeeper(new(countingKeeper), ttl)
+		if have := k.(*cachedKeeper).ttl; have != 5*time.Minute {
+			t.Errorf("ttl %v: have %v, want %v", ttl, have, 5*time.Minute)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package keeper
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"slices"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// ErrChainIDMismatch is returned by chain bound signers for transactions signed
+// for a chain they are not allowed to sign for.
+type ErrChainIDMismatch struct {
+	Got     *big.Int   // chain ID of the signer, nil for unprotected signers
+	Allowed []*big.Int // chain IDs the signer is bound to
+}
+
+func (e *ErrChainIDMismatch) Error() string {
+	allowed := make([]string, len(e.Allowed))
+	for i, id := range e.Allowed {
+		allowed[i] = id.String()
+	}
+	return fmt.Sprintf("chain id %v not allowed, want one of [%s]", e.Got, strings.Join(allowed, ", "))
+}
+
+// NewChainBoundSigner returns a SecureSigner signing transactions with inner
+// only if the chain ID of their types.Signer is one of allowedChainIDs, failing
+// with an *ErrChainIDMismatch otherwise. This keeps e.g. a testnet service from
+// signing mainnet transactions. The types.Signer also has to support the type
+// of the transaction, and typed transactions have to carry the same chain ID
+// as the signer. Permits and user operations are only signed for the allowed
+// chains as well. Hashes, messages and typed data fail with
+// ErrPermissionDenied unless AllowRawSigning is given.
+func NewChainBoundSigner(inner SecureSigner, allowedChainIDs []*big.Int, opts ...GuardOption) SecureSigner {
+	allowed := make([]*big.Int, len(allowedChainIDs))
+	for i, id := range allowedChainIDs {
+		allowed[i] = new(big.Int).Set(id)
+	}
+	s := newGuardSigner(inner, func(ctx context.Context, tx *types.Transaction, signer types.Signer) error {
+		return checkChain(tx, signer, allowed)
+	}, opts)
+	s.chain = func(chainID *big.Int) error {
+		return checkChainID(chainID, allowed)
+	}
+	return s
+}
+
+// checkChain verifies that tx may be signed with signer for one of the allowed
+// chains.
+func checkChain(tx *types.Transaction, signer types.Signer, allowed []*big.Int) error {
+	if err := checkChainID(signer.ChainID(), allowed); err != nil {
+		return err
+	}
+	// The signer validates the transaction type and chain ID when applying a
+	// signature, run that on a dummy one.
+	if _, _, _, err := signer.SignatureValues(tx, make([]byte, 65)); err != nil {
+		return fmt.Errorf("transaction of type %d not signable by %T: %w", tx.Type(), signer, err)
+	}
+	return nil
+}
+
+// checkChainID verifies that id is one of the allowed chain IDs.
+func checkChainID(id *big.Int, allowed []*big.Int) error {
+	if !slices.ContainsFunc(allowed, func(want *big.Int) bool { return id != nil && id.Cmp(want) == 0 }) {
+		return &ErrChainIDMismatch{Got: id, Allowed: allowed}
+	}
+	return nil
+}
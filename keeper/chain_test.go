@@ -0,0 +1,117 @@
+package keeper
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+func TestChainBoundSigner(t *testing.T) {
+	sec := NewSecureSigner(new(defaultPrivateKeyKeeper))
+	s := NewChainBoundSigner(sec, []*big.Int{big.NewInt(5), big.NewInt(11155111)})
+	prvID, err := s.GenerateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	addr, _ := s.GetAddress(prvID)
+
+	sepolia := types.LatestSignerForChainID(big.NewInt(11155111))
+	signed, err := s.Sign(newTestTx(), sepolia, prvID)
+	if err != nil {
+		t.Fatalf("failed to sign for allowed chain: %v", err)
+	}
+	if from, err := types.Sender(sepolia, signed); err != nil || from != addr {
+		t.Fatalf("sender mismatch: have (%x, %v), want %x", from, err, addr)
+	}
+	if _, err := s.SignDynamicFeeTx(big.NewInt(5), 0, common.Address{1}, big.NewInt(1), 21000, big.NewInt(2), big.NewInt(1), nil, prvID); err != nil {
+		t.Fatalf("failed to sign dynamic fee tx for allowed chain: %v", err)
+	}
+
+	// Transactions for other chains, and unprotected ones, are refused.
+	for _, signer := range []types.Signer{types.LatestSignerForChainID(big.NewInt(1)), types.HomesteadSigner{}} {
+		_, err := s.Sign(newTestTx(), signer, prvID)
+		var mismatch *ErrChainIDMismatch
+		if !errors.As(err, &mismatch) {
+			t.Fatalf("signer %T for chain %v: have %v, want %T", signer, signer.ChainID(), err, mismatch)
+		}
+		if len(mismatch.Allowed) != 2 {
+			t.Fatalf("allowed chains: have %v, want 2", mismatch.Allowed)
+		}
+	}
+	var mismatch *ErrChainIDMismatch
+	if _, err := s.SignDynamicFeeTx(big.NewInt(1), 0, common.Address{1}, big.NewInt(1), 21000, big.NewInt(2), big.NewInt(1), nil, prvID); !errors.As(err, &mismatch) || mismatch.Got.Int64() != 1 {
+		t.Fatalf("dynamic fee tx for mainnet: have %v, want %T", err, mismatch)
+	}
+	txs := []*types.Transaction{newTestTx(), newTestTx()}
+	if signed, err := s.SignBatch(txs, types.LatestSignerForChainID(big.NewInt(1)), prvID); !errors.As(err, &mismatch) || len(signed) != 0 {
+		t.Fatalf("batch for mainnet: have (%d signed, %v), want %T", len(signed), err, mismatch)
+	}
+}
+
+func TestChainBoundSignerTxType(t *testing.T) {
+	s := NewChainBoundSigner(NewSecureSigner(new(defaultPrivateKeyKeeper)), []*big.Int{big.NewInt(5)})
+	prvID, err := s.GenerateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	chainID := big.NewInt(5)
+	dynamicFee := newDynamicFeeTx(chainID, 0, common.Address{1}, big.NewInt(1), 21000, big.NewInt(2), big.NewInt(1), nil)
+
+	// EIP-1559 transactions need a London or later signer.
+	for _, signer := range []types.Signer{types.NewEIP155Signer(chainID), types.NewEIP2930Signer(chainID)} {
+		if _, err := s.Sign(dynamicFee, signer, prvID); !errors.Is(err, types.ErrTxTypeNotSupported) {
+			t.Fatalf("dynamic fee tx with %T: have %v, want %v", signer, err, types.ErrTxTypeNotSupported)
+		}
+	}
+	if _, err := s.Sign(dynamicFee, types.NewLondonSigner(chainID), prvID); err != nil {
+		t.Fatalf("dynamic fee tx with london signer: %v", err)
+	}
+	// The chain ID of typed transactions has to match the signer.
+	other := newDynamicFeeTx(big.NewInt(1), 0, common.Address{1}, big.NewInt(1), 21000, big.NewInt(2), big.NewInt(1), nil)
+	if _, err := s.Sign(other, types.NewLondonSigner(chainID), prvID); !errors.Is(err, types.ErrInvalidChainId) {
+		t.Fatalf("tx for other chain: have %v, want %v", err, types.ErrInvalidChainId)
+	}
+}
+
+func TestChainBoundSignerRawSigning(t *testing.T) {
+	checkRawSigningGuarded(t, func(inner SecureSigner, opts ...GuardOption) SecureSigner {
+		return NewChainBoundSigner(inner, []*big.Int{big.NewInt(5)}, opts...)
+	}, "permit", "user operation")
+}
+
+func TestChainBoundSignerPermit(t *testing.T) {
+	s := NewChainBoundSigner(NewSecureSigner(new(defaultPrivateKeyKeeper)), []*big.Int{big.NewInt(5)})
+	prvID, err := s.GenerateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	owner, _ := s.GetAddress(prvID)
+	if _, _, _, err := s.SignPermit(big.NewInt(5), common.Address{1}, "Token", "1", owner, common.Address{2}, big.NewInt(1), big.NewInt(0), 0, prvID); err != nil {
+		t.Fatalf("failed to sign permit for allowed chain: %v", err)
+	}
+	_, _, _, err = s.SignPermit(big.NewInt(1), common.Address{1}, "Token", "1", owner, common.Address{2}, big.NewInt(1), big.NewInt(0), 0, prvID)
+	var mismatch *ErrChainIDMismatch
+	if !errors.As(err, &mismatch) || mismatch.Got.Int64() != 1 {
+		t.Fatalf("permit for other chain: have %v, want chain id mismatch", err)
+	}
+}
+
+func TestChainBoundSignerUserOperation(t *testing.T) {
+	s := NewChainBoundSigner(NewSecureSigner(new(defaultPrivateKeyKeeper)), []*big.Int{big.NewInt(5)})
+	prvID, err := s.GenerateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	op := UserOperation{Sender: common.Address{1}}
+	if _, err := s.SignUserOperation(big.NewInt(5), common.Address{2}, op, prvID); err != nil {
+		t.Fatalf("failed to sign user operation for allowed chain: %v", err)
+	}
+	_, err = s.SignUserOperation(big.NewInt(1), common.Address{2}, op, prvID)
+	var mismatch *ErrChainIDMismatch
+	if !errors.As(err, &mismatch) || mismatch.Got.Int64() != 1 {
+		t.Fatalf("user operation for other chain: have %v, want chain id mismatch", err)
+	}
+}
@@ -0,0 +1,232 @@
+package keeper
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// CircuitState is the state of a circuit breaking keeper.
+type CircuitState int
+
+const (
+	// CircuitClosed forwards all calls, counting the consecutive failures.
+	CircuitClosed CircuitState = iota
+	// CircuitOpen refuses all calls with ErrCircuitOpen.
+	CircuitOpen
+	// CircuitHalfOpen forwards the calls, closing the circuit after enough
+	// successes and opening it again on the first failure.
+	CircuitHalfOpen
+)
+
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	default:
+		return "CircuitState(" + strconv.Itoa(int(s)) + ")"
+	}
+}
+
+// CircuitBreakerOptions configures a circuit breaking keeper. Zero fields take
+// the defaults given.
+type CircuitBreakerOptions struct {
+	FailureThreshold int           // consecutive failures opening the circuit, 5 by default
+	SuccessThreshold int           // consecutive successes closing a half-open circuit, 1 by default
+	Timeout          time.Duration // time an open circuit waits before turning half-open, 30s by default
+}
+
+// CircuitBreaker is a PrivateKeyKeeper refusing calls to a backend failing
+// consistently, as returned by NewCircuitBreakerKeeper.
+type CircuitBreaker interface {
+	PrivateKeyKeeper
+	HealthChecker
+
+	// State returns the current state of the circuit
+	State() CircuitState
+}
+
+// circuitBreakerKeeper is a PrivateKeyKeeper failing fast while its backend is
+// down, instead of having every caller wait for it to time out.
+type circuitBreakerKeeper struct {
+	inner PrivateKeyKeeperContext
+	check func(ctx context.Context) error
+	opts  CircuitBreakerOptions
+
+	lock     sync.Mutex
+	state    CircuitState
+	count    int       // consecutive failures if closed, successes if half-open
+	openedAt time.Time // time the circuit last opened
+}
+
+// NewCircuitBreakerKeeper returns a CircuitBreaker forwarding the calls to inner
+// until opts.FailureThreshold consecutive calls failed with
+// ErrBackendUnavailable or ErrOperationTimeout, such as from an inner
+// NewTimeoutKeeper. Calls whose context is done don't count as failures, and
+// other errors count as successes, the backend did answer. The open circuit refuses all calls with ErrCircuitOpen for
+// opts.Timeout, then turns half-open and lets calls through again, until
+// opts.SuccessThreshold of them succeeded or one failed.
+//
+// HealthCheck checks the health of inner, as NewHealthCheckingKeeper does, even
+// while the circuit is open. A passing check turns an open circuit half-open
+// right away, a failing one opens a half-open circuit again. Wrapped with
+// NewHealthCheckingKeeper, the breaker thus recovers as soon as the backend
+// does.
+func NewCircuitBreakerKeeper(inner PrivateKeyKeeper, opts CircuitBreakerOptions) PrivateKeyKeeper {
+	if opts.FailureThreshold <= 0 {
+		opts.FailureThreshold = 5
+	}
+	if opts.SuccessThreshold <= 0 {
+		opts.SuccessThreshold = 1
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 30 * time.Second
+	}
+	return &circuitBreakerKeeper{inner: ContextKeeper(inner), check: healthCheck(inner), opts: opts}
+}
+
+func (k *circuitBreakerKeeper) State() CircuitState {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+
+	if k.state == CircuitOpen && time.Since(k.openedAt) >= k.opts.Timeout {
+		k.setState(CircuitHalfOpen)
+	}
+	return k.state
+}
+
+// setState moves the circuit into state, k.lock must be held.
+func (k *circuitBreakerKeeper) setState(state CircuitState) {
+	k.state, k.count = state, 0
+	if state == CircuitOpen {
+		k.openedAt = time.Now()
+	}
+}
+
+// call runs fn, the operation op on the key prvID under ctx, unless the circuit
+// is open, and records its result.
+func (k *circuitBreakerKeeper) call(ctx context.Context, op string, prvID []byte, fn func() error) error {
+	if k.State() == CircuitOpen {
+		return &KeeperError{Op: op, KeyID: prvID, Err: ErrCircuitOpen}
+	}
+	err := fn()
+	// A backend leaving the calls to time out is down too, unless the caller
+	// gave up on the call itself.
+	timedOut := errors.Is(err, ErrOperationTimeout) && ctx.Err() == nil
+	k.record(errors.Is(err, ErrBackendUnavailable) || timedOut)
+	return err
+}
+
+// record updates the circuit with the result of a call.
+func (k *circuitBreakerKeeper) record(failed bool) {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+
+	switch {
+	case k.state == CircuitClosed && failed:
+		if k.count++; k.count >= k.opts.FailureThreshold {
+			k.setState(CircuitOpen)
+		}
+	case k.state == CircuitClosed:
+		k.count = 0
+	case k.state == CircuitHalfOpen && failed:
+		k.setState(CircuitOpen)
+	case k.state == CircuitHalfOpen:
+		if k.count++; k.count >= k.opts.SuccessThreshold {
+			k.setState(CircuitClosed)
+		}
+	}
+	// Results of calls let through before the circuit opened are ignored.
+}
+
+func (k *circuitBreakerKeeper) HealthCheck(ctx context.Context) (err error) {
+	defer wrapError(&err, "health check", nil)
+
+	err = k.check(ctx)
+
+	k.lock.Lock()
+	defer k.lock.Unlock()
+	switch {
+	case err == nil && k.state == CircuitOpen:
+		k.setState(CircuitHalfOpen)
+	case err != nil && k.state == CircuitHalfOpen:
+		k.setState(CircuitOpen)
+	}
+	return err
+}
+
+func (k *circuitBreakerKeeper) GeneratePrivateKey() ([]byte, error) {
+	return k.GeneratePrivateKeyContext(context.Background())
+}
+
+func (k *circuitBreakerKeeper) GeneratePrivateKeyContext(ctx context.Context) (prvID []byte, err error) {
+	err = k.call(ctx, "generate key", nil, func() (err error) {
+		prvID, err = k.inner.GeneratePrivateKeyContext(ctx)
+		return err
+	})
+	return prvID, err
+}
+
+func (k *circuitBreakerKeeper) GetPublicKey(prvID []byte) ([]byte, error) {
+	return k.GetPublicKeyContext(context.Background(), prvID)
+}
+
+func (k *circuitBreakerKeeper) GetPublicKeyContext(ctx context.Context, prvID []byte) (pub []byte, err error) {
+	err = k.call(ctx, "get public key", prvID, func() (err error) {
+		pub, err = k.inner.GetPublicKeyContext(ctx, prvID)
+		return err
+	})
+	return pub, err
+}
+
+func (k *circuitBreakerKeeper) Sign(data []byte, prvID []byte) ([]byte, error) {
+	return k.SignContext(context.Background(), data, prvID)
+}
+
+func (k *circuitBreakerKeeper) SignContext(ctx context.Context, data []byte, prvID []byte) (sig []byte, err error) {
+	err = k.call(ctx, "sign", prvID, func() (err error) {
+		sig, err = k.inner.SignContext(ctx, data, prvID)
+		return err
+	})
+	return sig, err
+}
+
+func (k *circuitBreakerKeeper) DeletePrivateKey(prvID []byte) error {
+	return k.DeletePrivateKeyContext(context.Background(), prvID)
+}
+
+func (k *circuitBreakerKeeper) DeletePrivateKeyContext(ctx context.Context, prvID []byte) error {
+	return k.call(ctx, "delete key", prvID, func() error {
+		return k.inner.DeletePrivateKeyContext(ctx, prvID)
+	})
+}
+
+func (k *circuitBreakerKeeper) ListPrivateKeys() ([][]byte, error) {
+	return k.ListPrivateKeysContext(context.Background())
+}
+
+func (k *circuitBreakerKeeper) ListPrivateKeysContext(ctx context.Context) (prvIDs [][]byte, err error) {
+	err = k.call(ctx, "list keys", nil, func() (err error) {
+		prvIDs, err = k.inner.ListPrivateKeysContext(ctx)
+		return err
+	})
+	return prvIDs, err
+}
+
+func (k *circuitBreakerKeeper) ImportPrivateKey(rawKey []byte) ([]byte, error) {
+	return k.ImportPrivateKeyContext(context.Background(), rawKey)
+}
+
+func (k *circuitBreakerKeeper) ImportPrivateKeyContext(ctx context.Context, rawKey []byte) (prvID []byte, err error) {
+	err = k.call(ctx, "import key", nil, func() (err error) {
+		prvID, err = k.inner.ImportPrivateKeyContext(ctx, rawKey)
+		return err
+	})
+	return prvID, err
+}
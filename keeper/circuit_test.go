@@ -0,0 +1,134 @@
+package keeper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreakerKeeper(t *testing.T) {
+	var (
+		flaky = &flakyKeeper{PrivateKeyKeeper: new(defaultPrivateKeyKeeper), failures: 3, err: fmt.Errorf("%w: timeout", ErrBackendUnavailable)}
+		k     = NewCircuitBreakerKeeper(flaky, CircuitBreakerOptions{FailureThreshold: 3, SuccessThreshold: 2, Timeout: 20 * time.Millisecond}).(CircuitBreaker)
+		hash  = make([]byte, 32)
+	)
+	prvID, err := k.GeneratePrivateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	checkState := func(want CircuitState) {
+		t.Helper()
+		if have := k.State(); have != want {
+			t.Fatalf("circuit state mismatch: have %v, want %v", have, want)
+		}
+	}
+	for i := 0; i < 3; i++ {
+		checkState(CircuitClosed)
+		if _, err := k.Sign(hash, prvID); !errors.Is(err, ErrBackendUnavailable) || errors.Is(err, ErrCircuitOpen) {
+			t.Fatalf("signature %d: have %v, want backend failure", i, err)
+		}
+	}
+	checkState(CircuitOpen)
+	if _, err := k.Sign(hash, prvID); !errors.Is(err, ErrCircuitOpen) || !errors.Is(err, ErrBackendUnavailable) {
+		t.Fatalf("sign with open circuit: have %v, want %v", err, ErrCircuitOpen)
+	}
+	if flaky.calls != 3 {
+		t.Fatalf("backend calls mismatch: have %d, want 3", flaky.calls)
+	}
+
+	// After the timeout the calls are let through until enough succeeded.
+	time.Sleep(30 * time.Millisecond)
+	checkState(CircuitHalfOpen)
+	if _, err := k.Sign(hash, prvID); err != nil {
+		t.Fatalf("failed to sign with half-open circuit: %v", err)
+	}
+	checkState(CircuitHalfOpen)
+	if _, err := k.Sign(hash, prvID); err != nil {
+		t.Fatalf("failed to sign with half-open circuit: %v", err)
+	}
+	checkState(CircuitClosed)
+
+	// Errors other than outages don't count as failures.
+	for i := 0; i < 5; i++ {
+		if _, err := k.Sign(hash, nil); err == nil || errors.Is(err, ErrCircuitOpen) {
+			t.Fatalf("sign with invalid key: have %v, want key error", err)
+		}
+	}
+	checkState(CircuitClosed)
+}
+
+func TestCircuitBreakerHealthCheck(t *testing.T) {
+	var (
+		flaky = &flakyKeeper{PrivateKeyKeeper: new(defaultPrivateKeyKeeper), failures: 1, err: ErrBackendUnavailable}
+		inner = &outageKeeper{PrivateKeyKeeper: flaky, down: true}
+		k     = NewCircuitBreakerKeeper(inner, CircuitBreakerOptions{FailureThreshold: 1, Timeout: time.Hour}).(CircuitBreaker)
+		ctx   = context.Background()
+	)
+	prvID, _ := k.GeneratePrivateKey()
+	if _, err := k.Sign(make([]byte, 32), prvID); !errors.Is(err, ErrBackendUnavailable) {
+		t.Fatalf("sign during outage: have %v, want %v", err, ErrBackendUnavailable)
+	}
+	if k.State() != CircuitOpen {
+		t.Fatalf("circuit state mismatch: have %v, want %v", k.State(), CircuitOpen)
+	}
+	// Failing checks keep the circuit open, the first passing one half-opens
+	// it long before the timeout.
+	if err := k.HealthCheck(ctx); !errors.Is(err, ErrBackendUnavailable) {
+		t.Fatalf("health check during outage: have %v, want %v", err, ErrBackendUnavailable)
+	}
+	if k.State() != CircuitOpen {
+		t.Fatalf("circuit state mismatch: have %v, want %v", k.State(), CircuitOpen)
+	}
+	inner.setDown(false)
+	if err := k.HealthCheck(ctx); err != nil {
+		t.Fatalf("health check failed: %v", err)
+	}
+	if k.State() != CircuitHalfOpen {
+		t.Fatalf("circuit state mismatch: have %v, want %v", k.State(), CircuitHalfOpen)
+	}
+	if _, err := k.Sign(make([]byte, 32), prvID); err != nil {
+		t.Fatalf("failed to sign after recovery: %v", err)
+	}
+	if k.State() != CircuitClosed {
+		t.Fatalf("circuit state mismatch: have %v, want %v", k.State(), CircuitClosed)
+	}
+}
+
+func TestCircuitBreakerTimeout(t *testing.T) {
+	var (
+		inner = &slowKeeper{PrivateKeyKeeper: new(defaultPrivateKeyKeeper), release: make(chan struct{})}
+		k     = NewCircuitBreakerKeeper(NewTimeoutKeeper(inner, 10*time.Millisecond, 0, 0), CircuitBreakerOptions{FailureThreshold: 2, Timeout: time.Hour}).(CircuitBreaker)
+		hash  = make([]byte, 32)
+	)
+	defer close(inner.release)
+
+	prvID, err := k.GeneratePrivateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	// Callers giving up on the hanging backend don't count as failures.
+	for i := 0; i < 3; i++ {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+		if _, err := ContextKeeper(k).SignContext(ctx, hash, prvID); !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("signature %d: have %v, want %v", i, err, context.DeadlineExceeded)
+		}
+		cancel()
+	}
+	if k.State() != CircuitClosed {
+		t.Fatalf("circuit state mismatch: have %v, want %v", k.State(), CircuitClosed)
+	}
+	// Timeouts of the keeper do.
+	for i := 0; i < 2; i++ {
+		if _, err := k.Sign(hash, prvID); !errors.Is(err, ErrOperationTimeout) {
+			t.Fatalf("signature %d: have %v, want %v", i, err, ErrOperationTimeout)
+		}
+	}
+	if k.State() != CircuitOpen {
+		t.Fatalf("circuit state mismatch: have %v, want %v", k.State(), CircuitOpen)
+	}
+	if _, err := k.Sign(hash, prvID); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("sign with open circuit: have %v, want %v", err, ErrCircuitOpen)
+	}
+}
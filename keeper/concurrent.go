@@ -0,0 +1,208 @@
+package keeper
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"runtime"
+	"sync/atomic"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+)
+
+// ConcurrentOption configures a ConcurrentSecureSigner.
+type ConcurrentOption func(*ConcurrentSecureSigner)
+
+// WithConcurrency sets the number of signatures a ConcurrentSecureSigner makes
+// at a time. It defaults to the number of CPUs.
+func WithConcurrency(n int) ConcurrentOption {
+	return func(s *ConcurrentSecureSigner) {
+		s.slots = make(chan struct{}, max(n, 1))
+	}
+}
+
+// ConcurrentSecureSigner is a SecureSigner bounding the signatures its inner
+// SecureSigner makes at a time, so that concurrent callers keep a backend such
+// as a remote HSM busy up to the number of requests it handles in parallel,
+// without overloading it. Calls beyond the bound wait for a slot. Unlike the
+// AsyncSigner it signs in the goroutine of the caller, there are no workers to
+// stop.
+type ConcurrentSecureSigner struct {
+	SecureSigner
+	slots   chan struct{} // one per signature in progress
+	pending atomic.Int64  // calls waiting for a slot
+}
+
+// NewConcurrentSecureSigner returns a ConcurrentSecureSigner signing with inner.
+func NewConcurrentSecureSigner(inner SecureSigner, opts ...ConcurrentOption) *ConcurrentSecureSigner {
+	s := &ConcurrentSecureSigner{SecureSigner: inner, slots: make(chan struct{}, runtime.NumCPU())}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Pending returns the number of calls waiting for a slot.
+func (s *ConcurrentSecureSigner) Pending() int {
+	return int(s.pending.Load())
+}
+
+// acquire waits for a slot, failing if ctx is done first. The slot has to be
+// released after the signature is made.
+func (s *ConcurrentSecureSigner) acquire(ctx context.Context) error {
+	select {
+	case s.slots <- struct{}{}:
+		return nil
+	default:
+	}
+	s.pending.Add(1)
+	defer s.pending.Add(-1)
+
+	select {
+	case s.slots <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s *ConcurrentSecureSigner) release() {
+	<-s.slots
+}
+
+func (s *ConcurrentSecureSigner) GenerateKeyContext(ctx context.Context) ([]byte, error) {
+	return signerContext(s.SecureSigner).GenerateKeyContext(ctx)
+}
+
+func (s *ConcurrentSecureSigner) GetPublicKeyContext(ctx context.Context, prvID []byte) ([]byte, error) {
+	return signerContext(s.SecureSigner).GetPublicKeyContext(ctx, prvID)
+}
+
+func (s *ConcurrentSecureSigner) Sign(tx *types.Transaction, signer types.Signer, prvID []byte) (*types.Transaction, error) {
+	return s.SignContext(context.Background(), tx, signer, prvID)
+}
+
+func (s *ConcurrentSecureSigner) SignContext(ctx context.Context, tx *types.Transaction, signer types.Signer, prvID []byte) (_ *types.Transaction, err error) {
+	defer wrapError(&err, "sign transaction", prvID)
+
+	if err := s.acquire(ctx); err != nil {
+		return nil, err
+	}
+	defer s.release()
+	return signerContext(s.SecureSigner).SignContext(ctx, tx, signer, prvID)
+}
+
+func (s *ConcurrentSecureSigner) SignForChain(chainID *big.Int, tx *types.Transaction, prvID []byte) (*types.Transaction, error) {
+	return signForChain(s, chainID, tx, prvID)
+}
+
+func (s *ConcurrentSecureSigner) SignTransactionJSON(tx *types.Transaction, signer types.Signer, prvID []byte) (json.RawMessage, error) {
+	return signTransactionJSON(s, tx, signer, prvID)
+}
+
+func (s *ConcurrentSecureSigner) SignAndEncode(tx *types.Transaction, signer types.Signer, prvID []byte) ([]byte, error) {
+	return signAndEncode(s, tx, signer, prvID)
+}
+
+func (s *ConcurrentSecureSigner) SignAndEncodeHex(tx *types.Transaction, signer types.Signer, prvID []byte) (string, error) {
+	return signAndEncodeHex(s, tx, signer, prvID)
+}
+
+func (s *ConcurrentSecureSigner) SignDynamicFeeTx(chainID *big.Int, nonce uint64, to common.Address, value *big.Int, gasLimit uint64, maxFeePerGas, maxPriorityFeePerGas *big.Int, data []byte, prvID []byte) (*types.Transaction, error) {
+	tx := newDynamicFeeTx(chainID, nonce, to, value, gasLimit, maxFeePerGas, maxPriorityFeePerGas, data)
+	return s.Sign(tx, types.LatestSignerForChainID(chainID), prvID)
+}
+
+func (s *ConcurrentSecureSigner) SignAccessListTx(chainID *big.Int, nonce uint64, to common.Address, value *big.Int, gasLimit uint64, gasPrice *big.Int, accessList types.AccessList, data []byte, prvID []byte) (*types.Transaction, error) {
+	tx := newAccessListTx(chainID, nonce, to, value, gasLimit, gasPrice, accessList, data)
+	return s.Sign(tx, types.NewEIP2930Signer(chainID), prvID)
+}
+
+func (s *ConcurrentSecureSigner) SignContractCall(chainID *big.Int, contract common.Address, contractABI abi.ABI, method string, args []interface{}, nonce uint64, gasLimit uint64, gasPrice *big.Int, prvID []byte) (_ *types.Transaction, err error) {
+	defer wrapError(&err, "sign transaction", prvID)
+
+	tx, err := newContractCallTx(chainID, contract, contractABI, method, args, nonce, gasLimit, gasPrice)
+	if err != nil {
+		return nil, err
+	}
+	return s.Sign(tx, types.LatestSignerForChainID(chainID), prvID)
+}
+
+func (s *ConcurrentSecureSigner) SimulateAndSign(ctx context.Context, tx *types.Transaction, signer types.Signer, client ethereum.ContractCaller, prvID []byte) (*types.Transaction, error) {
+	return simulateAndSign(ctx, s, tx, signer, client, prvID)
+}
+
+func (s *ConcurrentSecureSigner) SignBlobTx(chainID *big.Int, blobTx *types.BlobTx, prvID []byte) (_ *types.Transaction, err error) {
+	defer wrapError(&err, "sign transaction", prvID)
+
+	tx, err := newBlobTx(chainID, blobTx)
+	if err != nil {
+		return nil, err
+	}
+	return s.Sign(tx, types.LatestSignerForChainID(chainID), prvID)
+}
+
+func (s *ConcurrentSecureSigner) SignBatch(txs []*types.Transaction, signer types.Signer, prvID []byte) ([]*types.Transaction, error) {
+	return signBatch(txs, func(tx *types.Transaction) (*types.Transaction, error) {
+		return s.Sign(tx, signer, prvID)
+	})
+}
+
+func (s *ConcurrentSecureSigner) SignBatchParallel(txs []*types.Transaction, signer types.Signer, prvID []byte) ([]*types.Transaction, error) {
+	return signBatchParallel(txs, func(tx *types.Transaction) (*types.Transaction, error) {
+		return s.Sign(tx, signer, prvID)
+	})
+}
+
+func (s *ConcurrentSecureSigner) SignTypedData(typedData apitypes.TypedData, prvID []byte) (_ []byte, err error) {
+	defer wrapError(&err, "sign typed data", prvID)
+
+	if err := s.acquire(context.Background()); err != nil {
+		return nil, err
+	}
+	defer s.release()
+	return s.SecureSigner.SignTypedData(typedData, prvID)
+}
+
+func (s *ConcurrentSecureSigner) SignPermit(chainID *big.Int, tokenAddr common.Address, tokenName, tokenVersion string, ownerAddr, spenderAddr common.Address, value, nonce *big.Int, deadline int64, prvID []byte) (v uint8, r, sv [32]byte, err error) {
+	return signPermit(s, chainID, tokenAddr, tokenName, tokenVersion, ownerAddr, spenderAddr, value, nonce, deadline, prvID)
+}
+
+func (s *ConcurrentSecureSigner) SignUserOperation(chainID *big.Int, entryPoint common.Address, op UserOperation, prvID []byte) (_ []byte, err error) {
+	defer wrapError(&err, "sign user operation", prvID)
+
+	if err := s.acquire(context.Background()); err != nil {
+		return nil, err
+	}
+	defer s.release()
+	return s.SecureSigner.SignUserOperation(chainID, entryPoint, op, prvID)
+}
+
+func (s *ConcurrentSecureSigner) SignHash(hash common.Hash, prvID []byte) (_ []byte, err error) {
+	defer wrapError(&err, "sign hash", prvID)
+
+	if err := s.acquire(context.Background()); err != nil {
+		return nil, err
+	}
+	defer s.release()
+	return s.SecureSigner.SignHash(hash, prvID)
+}
+
+func (s *ConcurrentSecureSigner) SignHashBytes(data []byte, prvID []byte) ([]byte, error) {
+	return s.SignHash(crypto.Keccak256Hash(data), prvID)
+}
+
+func (s *ConcurrentSecureSigner) SignPersonalMessage(message []byte, prvID []byte) (_ []byte, err error) {
+	defer wrapError(&err, "sign personal message", prvID)
+
+	if err := s.acquire(context.Background()); err != nil {
+		return nil, err
+	}
+	defer s.release()
+	return s.SecureSigner.SignPersonalMessage(message, prvID)
+}
@@ -0,0 +1,121 @@
+package keeper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// slowKeeper is a PrivateKeyKeeper taking a while to sign, like a remote HSM.
+// With a release channel, signatures block until it's closed, ignoring any
+// deadline, like a hanging one.
+type slowKeeper struct {
+	PrivateKeyKeeper
+	latency time.Duration
+	release chan struct{}
+}
+
+func (k *slowKeeper) Sign(data []byte, prvID []byte) ([]byte, error) {
+	if k.release != nil {
+		<-k.release
+	}
+	time.Sleep(k.latency)
+	return k.PrivateKeyKeeper.Sign(data, prvID)
+}
+
+func TestConcurrentSign(t *testing.T) {
+	inner := &blockingSigner{SecureSign: NewSecureSigner(new(defaultPrivateKeyKeeper)).(*SecureSign), release: make(chan struct{})}
+	s := NewConcurrentSecureSigner(inner, WithConcurrency(2))
+	prvID, err := s.GenerateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	addr, _ := s.GetAddress(prvID)
+	signer := types.NewEIP155Signer(big.NewInt(1))
+
+	// Two calls sign, the other three wait for a slot.
+	var wg sync.WaitGroup
+	errs := make([]error, 5)
+	for i := range errs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			tx, err := s.Sign(newTestTx(), signer, prvID)
+			if err == nil {
+				if from, _ := types.Sender(signer, tx); from != addr {
+					err = fmt.Errorf("sender mismatch: have %x, want %x", from, addr)
+				}
+			}
+			errs[i] = err
+		}()
+	}
+	for inner.active.Load() < 2 || s.Pending() < 3 {
+		time.Sleep(time.Millisecond)
+	}
+	close(inner.release)
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			t.Fatalf("call %d: failed to sign: %v", i, err)
+		}
+	}
+	if peak := inner.peak.Load(); peak > 2 {
+		t.Fatalf("concurrent signatures: have %d, want at most 2", peak)
+	}
+	if pending := s.Pending(); pending != 0 {
+		t.Fatalf("pending calls: have %d, want 0", pending)
+	}
+}
+
+func TestConcurrentSignCancel(t *testing.T) {
+	inner := &blockingSigner{SecureSign: NewSecureSigner(new(defaultPrivateKeyKeeper)).(*SecureSign), release: make(chan struct{})}
+	defer close(inner.release)
+	s := NewConcurrentSecureSigner(inner, WithConcurrency(1))
+	prvID, _ := s.GenerateKey()
+	signer := types.NewEIP155Signer(big.NewInt(1))
+
+	go s.Sign(newTestTx(), signer, prvID)
+	for inner.active.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := s.SignContext(ctx, newTestTx(), signer, prvID); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("waiting call: have %v, want %v", err, context.DeadlineExceeded)
+	}
+	if pending := s.Pending(); pending != 0 {
+		t.Fatalf("pending calls: have %d, want 0", pending)
+	}
+}
+
+// BenchmarkConcurrentSign shows the throughput gained by signing concurrently
+// with a backend taking 5ms per signature.
+func BenchmarkConcurrentSign(b *testing.B) {
+	for _, n := range []int{1, 8} {
+		b.Run(fmt.Sprintf("concurrency=%d", n), func(b *testing.B) {
+			s := NewConcurrentSecureSigner(NewSecureSigner(&slowKeeper{PrivateKeyKeeper: new(defaultPrivateKeyKeeper), latency: 5 * time.Millisecond}), WithConcurrency(n))
+			prvID, err := s.GenerateKey()
+			if err != nil {
+				b.Fatalf("failed to generate key: %v", err)
+			}
+			signer := types.NewEIP155Signer(big.NewInt(1))
+
+			b.SetParallelism(8)
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					if _, err := s.Sign(newTestTx(), signer, prvID); err != nil {
+						b.Error(err)
+					}
+				}
+			})
+		})
+	}
+}
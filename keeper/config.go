@@ -0,0 +1,355 @@
+package keeper
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"time"
+
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	vault "github.com/hashicorp/vault/api"
+)
+
+// KeeperConfigSchema is the JSON Schema of the KeeperConfig accepted by
+// ParseKeeperConfig.
+//
+//go:embed config.schema.json
+var KeeperConfigSchema []byte
+
+// KeeperConfig describes a PrivateKeyKeeper to build with BuildKeeperFromConfig,
+// usually read from a configuration file with ParseKeeperConfig. Only the fields
+// of the configured backend are used. Secrets such as passphrases and tokens are
+// not part of the configuration, it names the environment variables holding
+// them instead.
+type KeeperConfig struct {
+	// Backend is the keeper holding the keys, one of "memory", "encrypted",
+	// "keystore", "env", "keyring", "vault", "awskms", "gcpkms" and "tpm".
+	Backend string `json:"backend"`
+
+	PassphraseEnv string `json:"passphraseEnv,omitempty"` // variable holding the passphrase of the encrypted and keystore backends
+
+	KeystorePath    string `json:"keystorePath,omitempty"`
+	KeystoreScryptN int    `json:"keystoreScryptN,omitempty"` // keystore.StandardScryptN by default
+	KeystoreScryptP int    `json:"keystoreScryptP,omitempty"` // keystore.StandardScryptP by default
+
+	EnvPrefix      string `json:"envPrefix,omitempty"`
+	KeyringService string `json:"keyringService,omitempty"`
+
+	VaultAddress  string `json:"vaultAddress,omitempty"`  // VAULT_ADDR by default
+	VaultTokenEnv string `json:"vaultTokenEnv,omitempty"` // VAULT_TOKEN by default
+	VaultMount    string `json:"vaultMount,omitempty"`    // "transit" by default
+	VaultKeyType  string `json:"vaultKeyType,omitempty"`  // DefaultVaultKeyType by default
+
+	AWSRegion  string `json:"awsRegion,omitempty"` // region of the shared AWS config by default
+	KMSKeySpec string `json:"kmsKeySpec,omitempty"`
+
+	GCPProject  string `json:"gcpProject,omitempty"`
+	GCPLocation string `json:"gcpLocation,omitempty"`
+	GCPKeyRing  string `json:"gcpKeyRing,omitempty"`
+
+	TPMPath string `json:"tpmPath,omitempty"`
+
+	// Middleware wraps the backend in decorators, the first one being the
+	// outermost layer as with BuildKeeper.
+	Middleware []MiddlewareConfig `json:"middleware,omitempty"`
+}
+
+// MiddlewareConfig describes a KeeperMiddleware of a KeeperConfig. In JSON it is
+// either an object or just the name of a middleware using its defaults, e.g.
+// "retry". Durations are strings as accepted by time.ParseDuration.
+type MiddlewareConfig struct {
+	// Name is the middleware, one of "audit", "cache", "circuitbreaker",
+	// "health", "logging", "ratelimit", "retry" and "timeout".
+	Name string `json:"name"`
+
+	AuditLog string `json:"auditLog,omitempty"` // audit: file appended to, stderr by default
+
+	TTL      string `json:"ttl,omitempty"`      // cache: 5m by default
+	Interval string `json:"interval,omitempty"` // health: 10s by default
+
+	FailureThreshold int `json:"failureThreshold,omitempty"` // circuitbreaker
+	SuccessThreshold int `json:"successThreshold,omitempty"` // circuitbreaker
+
+	RPS   float64 `json:"rps,omitempty"`   // ratelimit: 10 by default
+	Burst int     `json:"burst,omitempty"` // ratelimit: 1 by default
+
+	MaxAttempts int    `json:"maxAttempts,omitempty"` // retry: 3 by default
+	Backoff     string `json:"backoff,omitempty"`     // retry: first delay, growing exponentially, 100ms by default
+	MaxBackoff  string `json:"maxBackoff,omitempty"`  // retry: 5s by default
+
+	Timeout string `json:"timeout,omitempty"` // timeout: 10s by default; circuitbreaker: time until half-open
+}
+
+func (c *MiddlewareConfig) UnmarshalJSON(data []byte) error {
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte(`"`)) {
+		*c = MiddlewareConfig{}
+		return json.Unmarshal(data, &c.Name)
+	}
+	type plain MiddlewareConfig
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	return dec.Decode((*plain)(c))
+}
+
+// ParseKeeperConfig decodes the JSON encoded KeeperConfig in data. Unknown
+// fields are refused, so misspelled ones don't go unnoticed.
+func ParseKeeperConfig(data []byte) (KeeperConfig, error) {
+	var cfg KeeperConfig
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&cfg); err != nil {
+		return KeeperConfig{}, fmt.Errorf("invalid keeper config: %v", err)
+	}
+	return cfg, nil
+}
+
+// configBackends builds the backends of a KeeperConfig by their name.
+var configBackends = map[string]func(cfg KeeperConfig) (PrivateKeyKeeper, error){
+	"memory": func(KeeperConfig) (PrivateKeyKeeper, error) {
+		return new(defaultPrivateKeyKeeper), nil
+	},
+	"encrypted": func(cfg KeeperConfig) (PrivateKeyKeeper, error) {
+		passphrase, err := configSecret(cfg.PassphraseEnv, "passphraseEnv")
+		if err != nil {
+			return nil, err
+		}
+		return NewEncryptedMemoryKeeper(passphrase)
+	},
+	"keystore": func(cfg KeeperConfig) (PrivateKeyKeeper, error) {
+		if cfg.KeystorePath == "" {
+			return nil, errors.New("keystorePath not set")
+		}
+		passphrase, err := configSecret(cfg.PassphraseEnv, "passphraseEnv")
+		if err != nil {
+			return nil, err
+		}
+		scryptN, scryptP := keystore.StandardScryptN, keystore.StandardScryptP
+		if cfg.KeystoreScryptN != 0 {
+			scryptN = cfg.KeystoreScryptN
+		}
+		if cfg.KeystoreScryptP != 0 {
+			scryptP = cfg.KeystoreScryptP
+		}
+		return NewKeystoreKeeper(cfg.KeystorePath, scryptN, scryptP, MemoryPassphraseProvider(passphrase)), nil
+	},
+	"env": func(cfg KeeperConfig) (PrivateKeyKeeper, error) {
+		if cfg.EnvPrefix == "" {
+			return nil, errors.New("envPrefix not set")
+		}
+		return NewEnvKeeper(cfg.EnvPrefix), nil
+	},
+	"keyring": func(cfg KeeperConfig) (PrivateKeyKeeper, error) {
+		if cfg.KeyringService == "" {
+			return nil, errors.New("keyringService not set")
+		}
+		return NewKeyringKeeper(cfg.KeyringService), nil
+	},
+	"vault": func(cfg KeeperConfig) (PrivateKeyKeeper, error) {
+		vcfg := vault.DefaultConfig()
+		if cfg.VaultAddress != "" {
+			vcfg.Address = cfg.VaultAddress
+		}
+		client, err := vault.NewClient(vcfg)
+		if err != nil {
+			return nil, err
+		}
+		if cfg.VaultTokenEnv != "" {
+			token, err := configSecret(cfg.VaultTokenEnv, "vaultTokenEnv")
+			if err != nil {
+				return nil, err
+			}
+			client.SetToken(token)
+		}
+		mount := cfg.VaultMount
+		if mount == "" {
+			mount = "transit"
+		}
+		var opts []VaultOption
+		if cfg.VaultKeyType != "" {
+			opts = append(opts, WithVaultKeyType(cfg.VaultKeyType))
+		}
+		return NewVaultKeeper(client, mount, opts...), nil
+	},
+	"awskms": func(cfg KeeperConfig) (PrivateKeyKeeper, error) {
+		var opts []func(*awsconfig.LoadOptions) error
+		if cfg.AWSRegion != "" {
+			opts = append(opts, awsconfig.WithRegion(cfg.AWSRegion))
+		}
+		acfg, err := awsconfig.LoadDefaultConfig(context.Background(), opts...)
+		if err != nil {
+			return nil, err
+		}
+		return NewAWSKMSKeeper(acfg, cfg.KMSKeySpec), nil
+	},
+	"gcpkms": func(cfg KeeperConfig) (PrivateKeyKeeper, error) {
+		if cfg.GCPProject == "" || cfg.GCPLocation == "" || cfg.GCPKeyRing == "" {
+			return nil, errors.New("gcpProject, gcpLocation and gcpKeyRing must be set")
+		}
+		return NewGCPKMSKeeper(context.Background(), cfg.GCPProject, cfg.GCPLocation, cfg.GCPKeyRing)
+	},
+	"tpm": func(cfg KeeperConfig) (PrivateKeyKeeper, error) {
+		if cfg.TPMPath == "" {
+			return nil, errors.New("tpmPath not set")
+		}
+		return NewTPMKeeper(cfg.TPMPath)
+	},
+}
+
+// configMiddlewares builds the middlewares of a KeeperConfig by their name.
+// Middlewares opening files add them to files, which are closed if the keeper
+// can't be built.
+var configMiddlewares = map[string]func(cfg MiddlewareConfig, files *[]io.Closer) (KeeperMiddleware, error){
+	"audit": func(cfg MiddlewareConfig, files *[]io.Closer) (KeeperMiddleware, error) {
+		var w io.Writer = os.Stderr
+		if cfg.AuditLog != "" {
+			// The log is written for as long as the keeper lives, it's
+			// never closed.
+			f, err := os.OpenFile(cfg.AuditLog, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+			if err != nil {
+				return nil, err
+			}
+			*files = append(*files, f)
+			w = f
+		}
+		return WithAuditLog(w), nil
+	},
+	"cache": func(cfg MiddlewareConfig, _ *[]io.Closer) (KeeperMiddleware, error) {
+		ttl, err := configPositiveDuration(cfg.TTL, "ttl", 5*time.Minute)
+		if err != nil {
+			return nil, err
+		}
+		return func(inner PrivateKeyKeeper) PrivateKeyKeeper { return NewCachedKeeper(inner, ttl) }, nil
+	},
+	"circuitbreaker": func(cfg MiddlewareConfig, _ *[]io.Closer) (KeeperMiddleware, error) {
+		timeout, err := configDuration(cfg.Timeout, "timeout", 0)
+		if err != nil {
+			return nil, err
+		}
+		return WithCircuitBreaker(CircuitBreakerOptions{
+			FailureThreshold: cfg.FailureThreshold,
+			SuccessThreshold: cfg.SuccessThreshold,
+			Timeout:          timeout,
+		}), nil
+	},
+	"health": func(cfg MiddlewareConfig, _ *[]io.Closer) (KeeperMiddleware, error) {
+		interval, err := configPositiveDuration(cfg.Interval, "interval", 10*time.Second)
+		if err != nil {
+			return nil, err
+		}
+		return func(inner PrivateKeyKeeper) PrivateKeyKeeper { return NewHealthCheckingKeeper(inner, interval) }, nil
+	},
+	"logging": func(MiddlewareConfig, *[]io.Closer) (KeeperMiddleware, error) {
+		return WithLogging(slog.Default()), nil
+	},
+	"ratelimit": func(cfg MiddlewareConfig, _ *[]io.Closer) (KeeperMiddleware, error) {
+		rps, burst := cfg.RPS, cfg.Burst
+		if rps == 0 {
+			rps = 10
+		}
+		if burst == 0 {
+			burst = 1
+		}
+		return WithRateLimit(rps, burst), nil
+	},
+	"retry": func(cfg MiddlewareConfig, _ *[]io.Closer) (KeeperMiddleware, error) {
+		base, err := configDuration(cfg.Backoff, "backoff", 100*time.Millisecond)
+		if err != nil {
+			return nil, err
+		}
+		limit, err := configDuration(cfg.MaxBackoff, "maxBackoff", 5*time.Second)
+		if err != nil {
+			return nil, err
+		}
+		attempts := cfg.MaxAttempts
+		if attempts == 0 {
+			attempts = 3
+		}
+		return WithRetry(attempts, ExponentialBackoff(base, limit)), nil
+	},
+	"timeout": func(cfg MiddlewareConfig, _ *[]io.Closer) (KeeperMiddleware, error) {
+		timeout, err := configDuration(cfg.Timeout, "timeout", 10*time.Second)
+		if err != nil {
+			return nil, err
+		}
+		return WithTimeout(timeout, timeout, timeout), nil
+	},
+}
+
+// BuildKeeperFromConfig returns the keeper described by cfg, its backend wrapped
+// in the configured middleware. Backends connecting to a service, such as Vault
+// or a KMS, are set up with the credentials found in the environment as usual
+// for their SDKs.
+func BuildKeeperFromConfig(cfg KeeperConfig) (_ PrivateKeyKeeper, err error) {
+	newBackend, ok := configBackends[cfg.Backend]
+	if !ok {
+		return nil, fmt.Errorf("invalid keeper config: unknown backend %q", cfg.Backend)
+	}
+	var files []io.Closer
+	defer func() {
+		if err != nil {
+			for _, f := range files {
+				f.Close()
+			}
+		}
+	}()
+	middlewares := make([]KeeperMiddleware, len(cfg.Middleware))
+	for i, mcfg := range cfg.Middleware {
+		newMiddleware, ok := configMiddlewares[mcfg.Name]
+		if !ok {
+			return nil, fmt.Errorf("invalid keeper config: unknown middleware %q", mcfg.Name)
+		}
+		middleware, err := newMiddleware(mcfg, &files)
+		if err != nil {
+			return nil, fmt.Errorf("invalid keeper config: middleware %q: %v", mcfg.Name, err)
+		}
+		middlewares[i] = middleware
+	}
+	base, err := newBackend(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s keeper: %w", cfg.Backend, err)
+	}
+	return BuildKeeper(base, middlewares...), nil
+}
+
+// configSecret returns the non-empty value of the environment variable named by
+// the config field field.
+func configSecret(name, field string) (string, error) {
+	if name == "" {
+		return "", fmt.Errorf("%s not set", field)
+	}
+	secret := os.Getenv(name)
+	if secret == "" {
+		return "", fmt.Errorf("environment variable %s of %s is empty", name, field)
+	}
+	return secret, nil
+}
+
+// configDuration parses the duration of the config field field, returning def
+// if it is not set.
+func configDuration(s, field string, def time.Duration) (time.Duration, error) {
+	if s == "" {
+		return def, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %v", field, err)
+	}
+	return d, nil
+}
+
+// configPositiveDuration parses the duration s of field as configDuration does,
+// failing if it isn't positive.
+func configPositiveDuration(s, field string, def time.Duration) (time.Duration, error) {
+	d, err := configDuration(s, field, def)
+	if err == nil && d <= 0 {
+		err = fmt.Errorf("invalid %s: %s not positive", field, s)
+	}
+	return d, err
+}
@@ -0,0 +1,160 @@
+package keeper
+
+import (
+	"encoding/json"
+	"maps"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestBuildKeeperFromConfig(t *testing.T) {
+	t.Setenv("KEEPERTEST_PASSPHRASE", "foo")
+	dir := t.TempDir()
+	auditLog := filepath.Join(dir, "audit.log")
+	data, _ := json.Marshal(map[string]any{
+		"backend":         "keystore",
+		"keystorePath":    filepath.Join(dir, "keys"),
+		"keystoreScryptN": 2,
+		"keystoreScryptP": 1,
+		"passphraseEnv":   "KEEPERTEST_PASSPHRASE",
+		"middleware": []any{
+			"retry",
+			map[string]any{"name": "ratelimit", "rps": 100, "burst": 5},
+			map[string]any{"name": "audit", "auditLog": auditLog},
+		},
+	})
+	cfg, err := ParseKeeperConfig(data)
+	if err != nil {
+		t.Fatalf("failed to parse config: %v", err)
+	}
+	if names := []string{cfg.Middleware[0].Name, cfg.Middleware[1].Name, cfg.Middleware[2].Name}; !slices.Equal(names, []string{"retry", "ratelimit", "audit"}) || cfg.Middleware[1].RPS != 100 {
+		t.Fatalf("middleware mismatch: have %+v", cfg.Middleware)
+	}
+	k, err := BuildKeeperFromConfig(cfg)
+	if err != nil {
+		t.Fatalf("failed to build keeper: %v", err)
+	}
+	if _, ok := k.(*retryingKeeper); !ok {
+		t.Fatalf("outermost layer: have %T, want retrying keeper", k)
+	}
+	prvID, err := k.GeneratePrivateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	if _, err := k.Sign(crypto.Keccak256([]byte("config")), prvID); err != nil {
+		t.Fatalf("failed to sign: %v", err)
+	}
+	if log, err := os.ReadFile(auditLog); err != nil || len(log) == 0 {
+		t.Fatalf("audit log not written: have (%q, %v)", log, err)
+	}
+}
+
+func TestBuildKeeperFromConfigErrors(t *testing.T) {
+	tests := []struct {
+		config string
+		want   string
+	}{
+		{`{"backend": "hsm"}`, `unknown backend "hsm"`},
+		{`{"backend": "memory", "middleware": ["retry", "cors"]}`, `unknown middleware "cors"`},
+		{`{"backend": "memory", "middleware": [{"name": "retry", "backoff": "soon"}]}`, "invalid backoff"},
+		{`{"backend": "memory", "middleware": [{"name": "cache", "ttl": "0s"}]}`, "invalid ttl"},
+		{`{"backend": "memory", "middleware": [{"name": "health", "interval": "-1s"}]}`, "invalid interval"},
+		{`{"backend": "keystore", "keystorePath": "keys"}`, "passphraseEnv not set"},
+		{`{"backend": "encrypted", "passphraseEnv": "KEEPERTEST_UNSET"}`, "KEEPERTEST_UNSET of passphraseEnv is empty"},
+	}
+	for _, tt := range tests {
+		cfg, err := ParseKeeperConfig([]byte(tt.config))
+		if err != nil {
+			t.Fatalf("%s: failed to parse config: %v", tt.config, err)
+		}
+		if _, err := BuildKeeperFromConfig(cfg); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: have %v, want %q", tt.config, err, tt.want)
+		}
+	}
+	for _, config := range []string{`{"backend": "memory", "vaultAdress": "x"}`, `{"backend": "memory", "middleware": [{"name": "retry", "attempts": 3}]}`} {
+		if _, err := ParseKeeperConfig([]byte(config)); err == nil {
+			t.Errorf("%s: parsed config with unknown field", config)
+		}
+	}
+}
+
+func TestBuildKeeperFromConfigClosesAuditLog(t *testing.T) {
+	fds, err := os.ReadDir("/proc/self/fd")
+	if err != nil {
+		t.Skip("open files not listed in /proc")
+	}
+	auditLog := filepath.Join(t.TempDir(), "audit.log")
+	for _, config := range []string{
+		`{"backend": "encrypted", "passphraseEnv": "KEEPERTEST_UNSET", "middleware": [{"name": "audit", "auditLog": "` + auditLog + `"}]}`,
+		`{"backend": "memory", "middleware": [{"name": "audit", "auditLog": "` + auditLog + `"}, {"name": "cache", "ttl": "0s"}]}`,
+	} {
+		cfg, err := ParseKeeperConfig([]byte(config))
+		if err != nil {
+			t.Fatalf("%s: failed to parse config: %v", config, err)
+		}
+		if _, err := BuildKeeperFromConfig(cfg); err == nil {
+			t.Fatalf("%s: built keeper of invalid config", config)
+		}
+	}
+	fds, _ = os.ReadDir("/proc/self/fd")
+	for _, fd := range fds {
+		if target, _ := os.Readlink(filepath.Join("/proc/self/fd", fd.Name())); target == auditLog {
+			t.Fatalf("audit log left open as fd %s", fd.Name())
+		}
+	}
+}
+
+// TestKeeperConfigSchema checks that the schema describes the config the
+// builder accepts.
+func TestKeeperConfigSchema(t *testing.T) {
+	var schema struct {
+		Properties map[string]struct {
+			Enum []string `json:"enum"`
+		} `json:"properties"`
+		Defs struct {
+			MiddlewareName struct {
+				Enum []string `json:"enum"`
+			} `json:"middlewareName"`
+			Middleware struct {
+				Properties map[string]json.RawMessage `json:"properties"`
+			} `json:"middleware"`
+		} `json:"$defs"`
+	}
+	if err := json.Unmarshal(KeeperConfigSchema, &schema); err != nil {
+		t.Fatalf("invalid schema: %v", err)
+	}
+	sorted := func(names []string) []string {
+		slices.Sort(names)
+		return names
+	}
+	if have, want := sorted(schema.Properties["backend"].Enum), sorted(slices.Collect(maps.Keys(configBackends))); !slices.Equal(have, want) {
+		t.Errorf("backends mismatch: have %v, want %v", have, want)
+	}
+	if have, want := sorted(schema.Defs.MiddlewareName.Enum), sorted(slices.Collect(maps.Keys(configMiddlewares))); !slices.Equal(have, want) {
+		t.Errorf("middlewares mismatch: have %v, want %v", have, want)
+	}
+	if have, want := sorted(slices.Collect(maps.Keys(schema.Properties))), jsonFields(KeeperConfig{}); !slices.Equal(have, want) {
+		t.Errorf("config properties mismatch: have %v, want %v", have, want)
+	}
+	if have, want := sorted(slices.Collect(maps.Keys(schema.Defs.Middleware.Properties))), jsonFields(MiddlewareConfig{}); !slices.Equal(have, want) {
+		t.Errorf("middleware properties mismatch: have %v, want %v", have, want)
+	}
+}
+
+// jsonFields returns the sorted JSON names of the fields of the struct v.
+func jsonFields(v any) []string {
+	var names []string
+	typ := reflect.TypeOf(v)
+	for i := 0; i < typ.NumField(); i++ {
+		name, _, _ := strings.Cut(typ.Field(i).Tag.Get("json"), ",")
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
@@ -0,0 +1,71 @@
+package keeper
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// create2FactoryABI is the ABI of the deploy method of common CREATE2 factories.
+const create2FactoryABI = `[{"type":"function","name":"deploy","stateMutability":"nonpayable","inputs":[
+	{"name":"salt","type":"bytes32"},
+	{"name":"bytecode","type":"bytes"}
+],"outputs":[{"name":"","type":"address"}]}]`
+
+// defaultCreate2ABI is the parsed create2FactoryABI.
+var defaultCreate2ABI = func() abi.ABI {
+	parsed, err := abi.JSON(strings.NewReader(create2FactoryABI))
+	if err != nil {
+		panic(err)
+	}
+	return parsed
+}()
+
+// create2Config is the factory interface SignCreate2Deployment calls.
+type create2Config struct {
+	abi    abi.ABI
+	method string
+}
+
+// Create2Option configures SignCreate2Deployment.
+type Create2Option func(*create2Config)
+
+// WithCreate2ABI makes SignCreate2Deployment call method of factoryABI instead
+// of deploy(bytes32 salt, bytes bytecode). The method has to take the salt and
+// the bytecode, in this order.
+func WithCreate2ABI(factoryABI abi.ABI, method string) Create2Option {
+	return func(c *create2Config) {
+		c.abi, c.method = factoryABI, method
+	}
+}
+
+// SignCreate2Deployment signs with s the EIP-1559 transaction calling the
+// deploy(bytes32 salt, bytes bytecode) method of the CREATE2 factory at
+// factoryAddr, and returns it along with the address the contract is deployed
+// at, as computed by crypto.CreateAddress2 from the factory, the salt and the
+// hash of bytecode. The transaction transfers no value.
+//
+// The address only holds for factories passing bytecode to CREATE2 unchanged,
+// which the ones wrapping the init code e.g. with constructor arguments don't.
+func SignCreate2Deployment(s SecureSigner, chainID *big.Int, factoryAddr common.Address, salt [32]byte, bytecode []byte, nonce uint64, gasLimit uint64, maxFeePerGas, maxPriorityFeePerGas *big.Int, prvID []byte, opts ...Create2Option) (_ *types.Transaction, _ common.Address, err error) {
+	defer wrapError(&err, "sign transaction", prvID)
+
+	cfg := &create2Config{abi: defaultCreate2ABI, method: "deploy"}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	data, err := cfg.abi.Pack(cfg.method, salt, bytecode)
+	if err != nil {
+		return nil, common.Address{}, fmt.Errorf("failed to pack call of %q: %w", cfg.method, err)
+	}
+	signed, err := s.SignDynamicFeeTx(chainID, nonce, factoryAddr, new(big.Int), gasLimit, maxFeePerGas, maxPriorityFeePerGas, data, prvID)
+	if err != nil {
+		return nil, common.Address{}, err
+	}
+	return signed, crypto.CreateAddress2(factoryAddr, salt, crypto.Keccak256(bytecode)), nil
+}
@@ -0,0 +1,84 @@
+package keeper
+
+import (
+	"bytes"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestSignCreate2Deployment(t *testing.T) {
+	sec, prvID, addr := newTestSigner(t)
+
+	var (
+		chainID  = big.NewInt(1337)
+		factory  = common.HexToAddress("0x4e59b44847b379578588920ca78fbf26c0b4956c")
+		salt     = [32]byte{0x01, 0x02, 0x03}
+		bytecode = common.FromHex("0x6080604052348015600f57600080fd5b50603f80601d6000396000f3fe")
+	)
+	tx, deployed, err := SignCreate2Deployment(sec, chainID, factory, salt, bytecode, 7, 500000, big.NewInt(20), big.NewInt(2), prvID)
+	if err != nil {
+		t.Fatalf("failed to sign: %v", err)
+	}
+	if want := crypto.CreateAddress2(factory, salt, crypto.Keccak256(bytecode)); deployed != want {
+		t.Fatalf("deployed address mismatch: have %v, want %v", deployed, want)
+	}
+	want, err := defaultCreate2ABI.Pack("deploy", salt, bytecode)
+	if err != nil {
+		t.Fatalf("failed to pack: %v", err)
+	}
+	if !bytes.Equal(tx.Data(), want) {
+		t.Fatalf("call data mismatch: have %x, want %x", tx.Data(), want)
+	}
+	if tx.Type() != types.DynamicFeeTxType || tx.ChainId().Cmp(chainID) != 0 || tx.Nonce() != 7 || *tx.To() != factory ||
+		tx.Value().Sign() != 0 || tx.Gas() != 500000 || tx.GasFeeCap().Int64() != 20 || tx.GasTipCap().Int64() != 2 {
+		t.Fatalf("transaction fields mismatch: %+v", tx)
+	}
+	if from, err := types.Sender(types.LatestSignerForChainID(chainID), tx); err != nil || from != addr {
+		t.Fatalf("sender mismatch: have (%v, %v), want %v", from, err, addr)
+	}
+
+	// Another salt deploys to another address.
+	_, other, err := SignCreate2Deployment(sec, chainID, factory, [32]byte{0x04}, bytecode, 8, 500000, big.NewInt(20), big.NewInt(2), prvID)
+	if err != nil {
+		t.Fatalf("failed to sign: %v", err)
+	}
+	if other == deployed {
+		t.Fatalf("salts %x and %x deploy to the same address %v", salt, [32]byte{0x04}, other)
+	}
+}
+
+func TestSignCreate2DeploymentABI(t *testing.T) {
+	sec, prvID, _ := newTestSigner(t)
+
+	parsed, err := abi.JSON(strings.NewReader(`[{"type":"function","name":"safeCreate2","stateMutability":"payable","inputs":[
+		{"name":"salt","type":"bytes32"},
+		{"name":"initializationCode","type":"bytes"}
+	],"outputs":[{"name":"","type":"address"}]}]`))
+	if err != nil {
+		t.Fatalf("failed to parse ABI: %v", err)
+	}
+	var (
+		factory  = common.HexToAddress("0x0000000000ffe8b47b3e2130213b802212439497")
+		salt     = [32]byte{0xff}
+		bytecode = []byte{0x60, 0x00, 0x60, 0x00, 0xf3}
+	)
+	tx, deployed, err := SignCreate2Deployment(sec, big.NewInt(1), factory, salt, bytecode, 0, 300000, big.NewInt(20), big.NewInt(2), prvID, WithCreate2ABI(parsed, "safeCreate2"))
+	if err != nil {
+		t.Fatalf("failed to sign: %v", err)
+	}
+	if want, _ := parsed.Pack("safeCreate2", salt, bytecode); !bytes.Equal(tx.Data(), want) {
+		t.Fatalf("call data mismatch: have %x, want %x", tx.Data(), want)
+	}
+	if want := crypto.CreateAddress2(factory, salt, crypto.Keccak256(bytecode)); deployed != want {
+		t.Fatalf("deployed address mismatch: have %v, want %v", deployed, want)
+	}
+	if _, _, err := SignCreate2Deployment(sec, big.NewInt(1), factory, salt, bytecode, 0, 300000, big.NewInt(20), big.NewInt(2), prvID, WithCreate2ABI(parsed, "deploy")); err == nil {
+		t.Fatal("signed call of unknown method")
+	}
+}
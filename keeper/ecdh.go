@@ -0,0 +1,104 @@
+package keeper
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// ECDHKeeper is implemented by the keepers able to agree on shared secrets
+// with their secp256k1 keys, as for ECIES encryption or the messaging of
+// Whisper and Waku. Like SchnorrSigner, these are the keepers with access to
+// the key material.
+type ECDHKeeper interface {
+	PrivateKeyKeeper
+	// DeriveSharedSecret return the 32 byte X coordinate of the ECDH shared point
+	// of private key ID and the 33 byte compressed or 65 byte uncompressed public
+	// key of the other party
+	DeriveSharedSecret(prvID []byte, theirPubKey []byte) ([]byte, error)
+}
+
+// deriveSharedSecret returns the X coordinate of the product of the public key
+// theirPubKey and the scalar of key, the shared secret of ECIES as computed by
+// ecies.PrivateKey.GenerateShared.
+func deriveSharedSecret(key *ecdsa.PrivateKey, theirPubKey []byte) ([]byte, error) {
+	pub, err := ParsePublicKey(theirPubKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid public key: %v", err)
+	}
+	scalar := crypto.FromECDSA(key)
+	defer zeroBytes(scalar)
+
+	x, _ := crypto.S256().ScalarMult(pub.X, pub.Y, scalar)
+	if x.Sign() == 0 {
+		return nil, errors.New("shared secret is the point at infinity")
+	}
+	return x.FillBytes(make([]byte, 32)), nil
+}
+
+// DeriveSharedSecret agrees on the secret with the key prvID, which is the raw
+// key itself.
+func (a *defaultPrivateKeyKeeper) DeriveSharedSecret(prvID []byte, theirPubKey []byte) (_ []byte, err error) {
+	defer wrapError(&err, "derive shared secret", prvID)
+
+	key, err := crypto.ToECDSA(prvID)
+	if err != nil {
+		return nil, err
+	}
+	defer ZeroKey(key)
+	return deriveSharedSecret(key, theirPubKey)
+}
+
+func (k *fixedKeeper) DeriveSharedSecret(prvID []byte, theirPubKey []byte) (_ []byte, err error) {
+	defer wrapError(&err, "derive shared secret", prvID)
+
+	key, err := k.key(prvID)
+	if err != nil {
+		return nil, err
+	}
+	return k.inner.DeriveSharedSecret(key, theirPubKey)
+}
+
+// DeriveSharedSecret decrypts the key file of prvID for the duration of the
+// agreement.
+func (k *keystoreKeeper) DeriveSharedSecret(prvID []byte, theirPubKey []byte) (_ []byte, err error) {
+	defer wrapError(&err, "derive shared secret", prvID)
+
+	key, err := k.decrypt(prvID)
+	if err != nil {
+		return nil, err
+	}
+	defer ZeroKey(key.PrivateKey)
+	return deriveSharedSecret(key.PrivateKey, theirPubKey)
+}
+
+func (k *keyringKeeper) DeriveSharedSecret(prvID []byte, theirPubKey []byte) (_ []byte, err error) {
+	defer wrapError(&err, "derive shared secret", prvID)
+
+	key, err := k.load(prvID)
+	if err != nil {
+		return nil, err
+	}
+	defer ZeroKey(key)
+	return deriveSharedSecret(key, theirPubKey)
+}
+
+// DeriveSharedSecret unseals the key prvID for the duration of the agreement.
+func (k *tpmKeeper) DeriveSharedSecret(prvID []byte, theirPubKey []byte) (_ []byte, err error) {
+	defer wrapError(&err, "derive shared secret", prvID)
+
+	secret, err := k.unseal(prvID)
+	if err != nil {
+		return nil, err
+	}
+	defer zeroBytes(secret)
+
+	key, err := crypto.ToECDSA(secret)
+	if err != nil {
+		return nil, err
+	}
+	defer ZeroKey(key)
+	return deriveSharedSecret(key, theirPubKey)
+}
@@ -0,0 +1,56 @@
+package keeper
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/crypto/ecies"
+	"github.com/zalando/go-keyring"
+)
+
+func TestECDHKeeper(t *testing.T) {
+	keyring.MockInit()
+	key, _ := GenerateDeterministicKey([]byte("ecdh"))
+	keepers := map[string]PrivateKeyKeeper{
+		"default":  new(defaultPrivateKeyKeeper),
+		"hd":       NewHDKeeper(),
+		"fixed":    FixedKeeper(map[string][]byte{"alice": key}),
+		"keystore": NewKeystoreKeeper(t.TempDir(), keystore.LightScryptN, keystore.LightScryptP, MemoryPassphraseProvider("foo")),
+		"keyring":  NewKeyringKeeper("keeper-test"),
+	}
+	bob, _ := crypto.GenerateKey()
+	bobPub := crypto.FromECDSAPub(&bob.PublicKey)
+
+	for name, k := range keepers {
+		e, ok := k.(ECDHKeeper)
+		if !ok {
+			t.Fatalf("%s: not an ECDHKeeper", name)
+		}
+		prvID, err := k.GeneratePrivateKey()
+		if err != nil {
+			t.Fatalf("%s: failed to generate key: %v", name, err)
+		}
+		secret, err := e.DeriveSharedSecret(prvID, bobPub)
+		if err != nil {
+			t.Fatalf("%s: failed to derive secret: %v", name, err)
+		}
+		if compressed, err := e.DeriveSharedSecret(prvID, crypto.CompressPubkey(&bob.PublicKey)); err != nil || !bytes.Equal(compressed, secret) {
+			t.Fatalf("%s: secret with compressed key: have (%x, %v), want %x", name, compressed, err, secret)
+		}
+		// The other party derives the same secret, as ECIES does.
+		pub, _ := k.GetPublicKey(prvID)
+		alicePub, _ := ParsePublicKey(pub)
+		want, err := ecies.ImportECDSA(bob).GenerateShared(ecies.ImportECDSAPublic(alicePub), 16, 16)
+		if err != nil {
+			t.Fatalf("%s: failed to generate ecies secret: %v", name, err)
+		}
+		if len(secret) != 32 || !bytes.Equal(secret, want) {
+			t.Fatalf("%s: secret mismatch: have %x, want %x", name, secret, want)
+		}
+		if _, err := e.DeriveSharedSecret(prvID, bobPub[:64]); err == nil {
+			t.Fatalf("%s: derived secret with truncated public key", name)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package keeper
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/crypto/ecies"
+)
+
+const (
+	eciesPubKeyLength = 65 // uncompressed ephemeral public key
+	eciesNonceLength  = 12
+	eciesTagLength    = 16
+	eciesOverhead     = eciesPubKeyLength + eciesNonceLength + eciesTagLength
+)
+
+// Encrypt encrypts data to the holder of the 33 byte compressed or 65 byte
+// uncompressed secp256k1 public key recipientPubKey, who decrypts it with
+// Decrypt, e.g. for encrypted memos or values stored for a key.
+//
+// The scheme is ECIES with an ephemeral key generated by the ecies package, the
+// SHA-256 concatenation KDF of NIST SP 800-56 over the ECDH shared secret and
+// AES-256-GCM. The result is the uncompressed ephemeral public key, the nonce,
+// the GCM tag and the ciphertext, in this order. It is not compatible with
+// ecies.Encrypt, which uses AES-CTR with HMAC.
+func Encrypt(data []byte, recipientPubKey []byte) (_ []byte, err error) {
+	defer wrapError(&err, "encrypt", nil)
+
+	pub, err := ParsePublicKey(recipientPubKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid public key: %v", err)
+	}
+	ephemeral, err := ecies.GenerateKey(rand.Reader, crypto.S256(), nil)
+	if err != nil {
+		return nil, err
+	}
+	defer ZeroKey(ephemeral.ExportECDSA())
+	shared, err := ephemeral.GenerateShared(ecies.ImportECDSAPublic(pub), 32, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer zeroBytes(shared)
+
+	aead, err := eciesCipher(shared)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]byte, eciesPubKeyLength+eciesNonceLength, eciesOverhead+len(data))
+	copy(out, crypto.FromECDSAPub(ephemeral.PublicKey.ExportECDSA()))
+	nonce := out[eciesPubKeyLength:]
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	// Seal appends the tag to the ciphertext, the envelope has it in front.
+	sealed := aead.Seal(nil, nonce, data, nil)
+	ciphertext, tag := sealed[:len(data)], sealed[len(data):]
+	return append(append(out, tag...), ciphertext...), nil
+}
+
+// Decrypt decrypts the data encrypted with Encrypt to the public key of prvID,
+// deriving the shared secret in k. Data not encrypted to the key, or tampered
+// with, fails with ErrDecryptionFailed.
+func Decrypt(k ECDHKeeper, data []byte, prvID []byte) (_ []byte, err error) {
+	defer wrapError(&err, "decrypt", prvID)
+
+	if len(data) < eciesOverhead {
+		return nil, fmt.Errorf("%w: message too short", ErrDecryptionFailed)
+	}
+	ephemeral := data[:eciesPubKeyLength]
+	nonce := data[eciesPubKeyLength : eciesPubKeyLength+eciesNonceLength]
+	tag := data[eciesPubKeyLength+eciesNonceLength : eciesOverhead]
+	ciphertext := data[eciesOverhead:]
+
+	if _, err := crypto.UnmarshalPubkey(ephemeral); err != nil {
+		return nil, fmt.Errorf("%w: invalid ephemeral public key", ErrDecryptionFailed)
+	}
+	shared, err := k.DeriveSharedSecret(prvID, ephemeral)
+	if err != nil {
+		return nil, err
+	}
+	defer zeroBytes(shared)
+
+	aead, err := eciesCipher(shared)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := aead.Open(nil, nonce, append(append([]byte{}, ciphertext...), tag...), nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDecryptionFailed, err)
+	}
+	return plaintext, nil
+}
+
+// eciesCipher returns the AES-256-GCM cipher keyed with the single block
+// SHA-256 concatenation KDF of the shared secret, SHA-256(1 || shared).
+func eciesCipher(shared []byte) (cipher.AEAD, error) {
+	h := sha256.New()
+	h.Write([]byte{0, 0, 0, 1})
+	h.Write(shared)
+	key := h.Sum(nil)
+	defer zeroBytes(key)
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
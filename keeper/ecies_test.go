@@ -0,0 +1,58 @@
+package keeper
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestECIES(t *testing.T) {
+	k := new(defaultPrivateKeyKeeper)
+	prvID, _ := GenerateDeterministicKey([]byte("recipient"))
+	pub, _ := k.GetPublicKey(prvID)
+	key, _ := ParsePublicKey(pub)
+
+	for _, form := range [][]byte{pub, crypto.CompressPubkey(key)} {
+		for _, msg := range [][]byte{nil, []byte("memo: invoice 42")} {
+			enc, err := Encrypt(msg, form)
+			if err != nil {
+				t.Fatalf("failed to encrypt: %v", err)
+			}
+			if len(enc) != 65+12+16+len(msg) {
+				t.Fatalf("envelope of %d bytes for %d byte message", len(enc), len(msg))
+			}
+			dec, err := Decrypt(k, enc, prvID)
+			if err != nil {
+				t.Fatalf("failed to decrypt: %v", err)
+			}
+			if !bytes.Equal(dec, msg) {
+				t.Fatalf("message mismatch: have %q, want %q", dec, msg)
+			}
+		}
+	}
+	msg := []byte("memo: invoice 42")
+	enc, _ := Encrypt(msg, pub)
+	if again, _ := Encrypt(msg, pub); bytes.Equal(again, enc) {
+		t.Fatal("encryption is deterministic")
+	}
+	// Flipping a bit of the nonce, the tag or the ciphertext fails.
+	for _, i := range []int{65, 65 + 12, 65 + 12 + 16} {
+		tampered := bytes.Clone(enc)
+		tampered[i] ^= 1
+		if _, err := Decrypt(k, tampered, prvID); !errors.Is(err, ErrDecryptionFailed) {
+			t.Fatalf("byte %d tampered: have %v, want %v", i, err, ErrDecryptionFailed)
+		}
+	}
+	other, _ := GenerateDeterministicKey([]byte("other"))
+	if _, err := Decrypt(k, enc, other); !errors.Is(err, ErrDecryptionFailed) {
+		t.Fatalf("decrypt with other key: have %v, want %v", err, ErrDecryptionFailed)
+	}
+	if _, err := Decrypt(k, enc[:92], prvID); !errors.Is(err, ErrDecryptionFailed) {
+		t.Fatalf("decrypt truncated message: have %v, want %v", err, ErrDecryptionFailed)
+	}
+	if _, err := Encrypt(msg, pub[:64]); err == nil {
+		t.Fatal("encrypted to truncated public key")
+	}
+}
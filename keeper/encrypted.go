@@ -0,0 +1,200 @@
+package keeper
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"errors"
+	"io"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/google/uuid"
+	"golang.org/x/crypto/scrypt"
+)
+
+// scrypt parameters deriving the key encrypting the keys of the encrypted
+// memory keeper, the standard ones of the keystore.
+const (
+	encryptedScryptN = 1 << 18
+	encryptedScryptP = 1
+	encryptedScryptR = 8
+)
+
+// encryptedMemoryKeeper is a PrivateKeyKeeper holding keys in memory encrypted
+// with AES-256-GCM, under a key derived from a passphrase with scrypt. Keys are
+// only decrypted for the operation using them. The prvID is the UUID of the
+// key, used as additional data of its encryption so ciphertexts can't be
+// swapped between keys.
+type encryptedMemoryKeeper struct {
+	aead cipher.AEAD
+	keys sync.Map  // prvID -> sealed key, nonce || ciphertext
+	rand io.Reader // source of keys, nonces and UUIDs, replaced in tests
+
+	// The passphrase is kept sealed like the keys, to derive the keys of the
+	// snapshots, see Snapshot.
+	passphrase    []byte // nonce || ciphertext
+	scryptN       int
+	snapshotLock  sync.Mutex
+	snapshotSalt  []byte                 // salt of the snapshots taken, drawn on first use
+	snapshotAEADs map[string]cipher.AEAD // snapshot ciphers by salt
+}
+
+// NewEncryptedMemoryKeeper returns a PrivateKeyKeeper holding its keys in
+// process memory, encrypted with a key derived from passphrase. It is a step up
+// from the default keeper where no Vault or KMS is available, similar to an
+// unlocked keystore without files: the keys are lost when the process exits,
+// unless saved with Snapshot, but don't lie around in the clear in memory.
+// Deriving the keys takes a while, it is done once per key; the passphrase is
+// only kept encrypted like the keys.
+func NewEncryptedMemoryKeeper(passphrase string) (PrivateKeyKeeper, error) {
+	return newEncryptedMemoryKeeper(passphrase, encryptedScryptN, rand.Reader)
+}
+
+func newEncryptedMemoryKeeper(passphrase string, scryptN int, random io.Reader) (*encryptedMemoryKeeper, error) {
+	if passphrase == "" {
+		return nil, errors.New("empty passphrase")
+	}
+	// The derived key only lives as long as the process, a fresh salt each
+	// time is fine.
+	salt := make([]byte, 32)
+	if _, err := io.ReadFull(random, salt); err != nil {
+		return nil, err
+	}
+	pass := []byte(passphrase)
+	defer zeroBytes(pass)
+	kek, err := scrypt.Key(pass, salt, scryptN, encryptedScryptR, encryptedScryptP, 32)
+	if err != nil {
+		return nil, err
+	}
+	defer zeroBytes(kek)
+
+	block, err := aes.NewCipher(kek)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	k := &encryptedMemoryKeeper{aead: aead, rand: random, scryptN: scryptN, snapshotAEADs: make(map[string]cipher.AEAD)}
+	k.passphrase = make([]byte, aead.NonceSize(), aead.NonceSize()+len(pass)+aead.Overhead())
+	if _, err := io.ReadFull(random, k.passphrase); err != nil {
+		return nil, err
+	}
+	k.passphrase = aead.Seal(k.passphrase, k.passphrase, pass, passphraseAD)
+	return k, nil
+}
+
+func (k *encryptedMemoryKeeper) GeneratePrivateKey() (_ []byte, err error) {
+	defer wrapError(&err, "generate key", nil)
+
+	secret := make([]byte, 32)
+	defer zeroBytes(secret)
+	for {
+		if _, err := io.ReadFull(k.rand, secret); err != nil {
+			return nil, err
+		}
+		// Values of zero or beyond the curve order are no keys, draw again.
+		if key, err := crypto.ToECDSA(secret); err == nil {
+			ZeroKey(key)
+			return k.store(secret)
+		}
+	}
+}
+
+// ImportPrivateKey encrypts rawKey and stores it under a new UUID.
+func (k *encryptedMemoryKeeper) ImportPrivateKey(rawKey []byte) (_ []byte, err error) {
+	defer wrapError(&err, "import key", nil)
+
+	key, err := parseRawKey(rawKey)
+	if err != nil {
+		return nil, err
+	}
+	ZeroKey(key)
+	return k.store(rawKey)
+}
+
+// store encrypts secret under a new UUID and returns the UUID.
+func (k *encryptedMemoryKeeper) store(secret []byte) ([]byte, error) {
+	id, err := uuid.NewRandomFromReader(k.rand)
+	if err != nil {
+		return nil, err
+	}
+	prvID := []byte(id.String())
+	if err := k.storeAs(prvID, secret); err != nil {
+		return nil, err
+	}
+	return prvID, nil
+}
+
+// storeAs encrypts secret under the given prvID.
+func (k *encryptedMemoryKeeper) storeAs(prvID, secret []byte) error {
+	nonce := make([]byte, k.aead.NonceSize(), k.aead.NonceSize()+len(secret)+k.aead.Overhead())
+	if _, err := io.ReadFull(k.rand, nonce); err != nil {
+		return err
+	}
+	k.keys.Store(string(prvID), k.aead.Seal(nonce, nonce, secret, prvID))
+	return nil
+}
+
+// open decrypts the key prvID. The caller has to clear the returned key after
+// use.
+func (k *encryptedMemoryKeeper) open(prvID []byte) (*ecdsa.PrivateKey, error) {
+	sealed, ok := k.keys.Load(string(prvID))
+	if !ok {
+		return nil, ErrKeyNotFound
+	}
+	nonce, ciphertext := sealed.([]byte)[:k.aead.NonceSize()], sealed.([]byte)[k.aead.NonceSize():]
+	secret, err := k.aead.Open(nil, nonce, ciphertext, prvID)
+	if err != nil {
+		return nil, err
+	}
+	defer zeroBytes(secret)
+	return crypto.ToECDSA(secret)
+}
+
+func (k *encryptedMemoryKeeper) GetPublicKey(prvID []byte) (_ []byte, err error) {
+	defer wrapError(&err, "get public key", prvID)
+
+	key, err := k.open(prvID)
+	if err != nil {
+		return nil, err
+	}
+	defer ZeroKey(key)
+	return crypto.FromECDSAPub(&key.PublicKey), nil
+}
+
+func (k *encryptedMemoryKeeper) Sign(data []byte, prvID []byte) (_ []byte, err error) {
+	defer wrapError(&err, "sign", prvID)
+
+	key, err := k.open(prvID)
+	if err != nil {
+		return nil, err
+	}
+	defer ZeroKey(key)
+	sig, err := crypto.Sign(data, key)
+	if err != nil {
+		return nil, err
+	}
+	return NormaliseSignature(sig)
+}
+
+func (k *encryptedMemoryKeeper) DeletePrivateKey(prvID []byte) (err error) {
+	defer wrapError(&err, "delete key", prvID)
+
+	if _, ok := k.keys.LoadAndDelete(string(prvID)); !ok {
+		return ErrKeyNotFound
+	}
+	return nil
+}
+
+func (k *encryptedMemoryKeeper) ListPrivateKeys() ([][]byte, error) {
+	var prvIDs [][]byte
+	k.keys.Range(func(key, _ any) bool {
+		prvIDs = append(prvIDs, []byte(key.(string)))
+		return true
+	})
+	return prvIDs, nil
+}
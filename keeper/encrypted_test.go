@@ -0,0 +1,94 @@
+package keeper
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math/rand"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// recordingReader is a deterministic random source remembering what it read.
+type recordingReader struct {
+	src   io.Reader
+	reads [][]byte
+}
+
+func (r *recordingReader) Read(p []byte) (int, error) {
+	n, err := r.src.Read(p)
+	r.reads = append(r.reads, bytes.Clone(p[:n]))
+	return n, err
+}
+
+func TestEncryptedMemoryKeeper(t *testing.T) {
+	random := &recordingReader{src: rand.New(rand.NewSource(1))}
+	k, err := newEncryptedMemoryKeeper("passphrase", 1<<10, random)
+	if err != nil {
+		t.Fatalf("failed to create keeper: %v", err)
+	}
+	random.reads = nil
+	prvID, err := k.GeneratePrivateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	// The key is the first thing drawn in the generation.
+	secret := random.reads[0]
+	want, _ := crypto.ToECDSA(secret)
+	if pub, err := k.GetPublicKey(prvID); err != nil || !bytes.Equal(pub, crypto.FromECDSAPub(&want.PublicKey)) {
+		t.Fatalf("public key: have (%x, %v), want key of %x", pub, err, secret)
+	}
+	if bytes.Contains(prvID, secret) {
+		t.Fatal("prvID contains the key")
+	}
+	imported, _ := crypto.GenerateKey()
+	importedID, err := k.ImportPrivateKey(crypto.FromECDSA(imported))
+	if err != nil {
+		t.Fatalf("failed to import key: %v", err)
+	}
+	// The map holds neither of the keys in the clear.
+	for _, plain := range [][]byte{secret, crypto.FromECDSA(imported)} {
+		k.keys.Range(func(_, sealed any) bool {
+			if bytes.Contains(sealed.([]byte), plain) {
+				t.Errorf("stored key holds plaintext %x", plain)
+			}
+			return true
+		})
+	}
+
+	hash := crypto.Keccak256([]byte("encrypted"))
+	sig, err := k.Sign(hash, importedID)
+	if err != nil {
+		t.Fatalf("failed to sign: %v", err)
+	}
+	if pub, err := crypto.Ecrecover(hash, sig); err != nil || !bytes.Equal(pub, crypto.FromECDSAPub(&imported.PublicKey)) {
+		t.Fatalf("recovered key mismatch: %v", err)
+	}
+	if ids, err := k.ListPrivateKeys(); err != nil || len(ids) != 2 {
+		t.Fatalf("list: have (%d keys, %v), want 2", len(ids), err)
+	}
+
+	// Swapped ciphertexts don't decrypt.
+	a, _ := k.keys.Load(string(prvID))
+	b, _ := k.keys.Load(string(importedID))
+	k.keys.Store(string(prvID), b)
+	if _, err := k.Sign(hash, prvID); err == nil {
+		t.Fatal("signed with swapped ciphertext")
+	}
+	k.keys.Store(string(prvID), a)
+
+	if err := k.DeletePrivateKey(prvID); err != nil {
+		t.Fatalf("failed to delete key: %v", err)
+	}
+	if _, err := k.Sign(hash, prvID); !errors.Is(err, ErrKeyNotFound) {
+		t.Fatalf("sign with deleted key: have %v, want %v", err, ErrKeyNotFound)
+	}
+	checkImport(t, k)
+}
+
+func TestEncryptedMemoryKeeperPassphrase(t *testing.T) {
+	if _, err := NewEncryptedMemoryKeeper(""); err == nil {
+		t.Fatal("created keeper with empty passphrase")
+	}
+}
@@ -0,0 +1,121 @@
+package keeper
+
+import (
+	"encoding/hex"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// envKeeper is a PrivateKeyKeeper serving the private keys set in environment
+// variables, as injected into twelve-factor apps, CI jobs and containers.
+type envKeeper struct {
+	inner   defaultPrivateKeyKeeper
+	prefix  string // prefix of the variable names, <PREFIX>_KEY_
+	environ func() []string
+}
+
+// EnvOption configures an environment keeper.
+type EnvOption func(*envKeeper)
+
+// WithEnviron makes the keeper read the variables from environ, in the
+// "key=value" form of os.Environ, instead of the environment of the process.
+func WithEnviron(environ func() []string) EnvOption {
+	return func(k *envKeeper) {
+		k.environ = environ
+	}
+}
+
+// NewEnvKeeper returns a PrivateKeyKeeper holding the hex encoded private keys
+// of the environment variables named <prefix>_KEY_<label>, with or without
+// 0x. The prvID of a key is its label, e.g. "DEPLOYER" for
+// APP_KEY_DEPLOYER with the prefix "APP". The variables are read on every
+// call, malformed keys are reported when used.
+//
+// Keys come from outside only, generating, importing and deleting keys fails
+// with ErrNotSupported. Note that environment variables are visible to child
+// processes and, on Linux, to anyone allowed to read /proc/<pid>/environ.
+func NewEnvKeeper(prefix string, opts ...EnvOption) PrivateKeyKeeper {
+	k := &envKeeper{prefix: prefix + "_KEY_", environ: os.Environ}
+	for _, opt := range opts {
+		opt(k)
+	}
+	return k
+}
+
+// key returns the raw private key labelled prvID.
+func (k *envKeeper) key(prvID []byte) ([]byte, error) {
+	if len(prvID) == 0 {
+		return nil, ErrKeyNotFound
+	}
+	name := k.prefix + string(prvID)
+	for _, kv := range k.environ() {
+		if value, ok := strings.CutPrefix(kv, name+"="); ok {
+			// The value is the key, keep it out of the error.
+			key, err := hex.DecodeString(strings.TrimPrefix(value, "0x"))
+			if err != nil {
+				return nil, fmt.Errorf("%w: variable %s is not hex", ErrInvalidKey, name)
+			}
+			if _, err := parseRawKey(key); err != nil {
+				return nil, fmt.Errorf("%w: variable %s", ErrInvalidKey, name)
+			}
+			return key, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: variable %s not set", ErrKeyNotFound, name)
+}
+
+func (k *envKeeper) GeneratePrivateKey() (_ []byte, err error) {
+	defer wrapError(&err, "generate key", nil)
+	return nil, ErrNotSupported
+}
+
+func (k *envKeeper) GetPublicKey(prvID []byte) (_ []byte, err error) {
+	defer wrapError(&err, "get public key", prvID)
+
+	key, err := k.key(prvID)
+	if err != nil {
+		return nil, err
+	}
+	return k.inner.GetPublicKey(key)
+}
+
+func (k *envKeeper) Sign(data []byte, prvID []byte) (_ []byte, err error) {
+	defer wrapError(&err, "sign", prvID)
+
+	key, err := k.key(prvID)
+	if err != nil {
+		return nil, err
+	}
+	return k.inner.Sign(data, key)
+}
+
+func (k *envKeeper) DeletePrivateKey(prvID []byte) (err error) {
+	defer wrapError(&err, "delete key", prvID)
+	return ErrNotSupported
+}
+
+// ListPrivateKeys returns the sorted labels of the variables with the prefix,
+// whether their keys are valid or not.
+func (k *envKeeper) ListPrivateKeys() ([][]byte, error) {
+	var labels []string
+	for _, kv := range k.environ() {
+		name, _, _ := strings.Cut(kv, "=")
+		if label, ok := strings.CutPrefix(name, k.prefix); ok && label != "" {
+			labels = append(labels, label)
+		}
+	}
+	sort.Strings(labels)
+
+	prvIDs := make([][]byte, len(labels))
+	for i, label := range labels {
+		prvIDs[i] = []byte(label)
+	}
+	return prvIDs, nil
+}
+
+func (k *envKeeper) ImportPrivateKey(rawKey []byte) (_ []byte, err error) {
+	defer wrapError(&err, "import key", nil)
+	return nil, ErrNotSupported
+}
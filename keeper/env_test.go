@@ -0,0 +1,84 @@
+package keeper
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestEnvKeeper(t *testing.T) {
+	deployer, _ := GenerateDeterministicKey([]byte("deployer"))
+	relayer, _ := GenerateDeterministicKey([]byte("relayer"))
+	environ := []string{
+		"PATH=/usr/bin",
+		"APP_KEY_DEPLOYER=" + hex.EncodeToString(deployer),
+		"APP_KEY_RELAYER=0x" + hex.EncodeToString(relayer),
+		"APP_KEY_BROKEN=0x" + hex.EncodeToString(deployer[:31]),
+		"OTHER_KEY_DEPLOYER=" + hex.EncodeToString(relayer),
+		"APP_KEYS=unrelated",
+	}
+	k := NewEnvKeeper("APP", WithEnviron(func() []string { return environ }))
+
+	prvIDs, err := k.ListPrivateKeys()
+	if err != nil {
+		t.Fatalf("failed to list keys: %v", err)
+	}
+	if want := [][]byte{[]byte("BROKEN"), []byte("DEPLOYER"), []byte("RELAYER")}; !reflect.DeepEqual(prvIDs, want) {
+		t.Fatalf("listed keys mismatch: have %q, want %q", prvIDs, want)
+	}
+	for label, key := range map[string][]byte{"DEPLOYER": deployer, "RELAYER": relayer} {
+		pub, err := k.GetPublicKey([]byte(label))
+		if err != nil {
+			t.Fatalf("failed to get public key of %s: %v", label, err)
+		}
+		if want, _ := defaultKeeper.GetPublicKey(key); !bytes.Equal(pub, want) {
+			t.Fatalf("public key of %s mismatch: have %x, want %x", label, pub, want)
+		}
+		hash := crypto.Keccak256([]byte(label))
+		sig, err := k.Sign(hash, []byte(label))
+		if err != nil {
+			t.Fatalf("failed to sign with %s: %v", label, err)
+		}
+		if recovered, err := crypto.Ecrecover(hash, sig); err != nil || !bytes.Equal(recovered, pub) {
+			t.Fatalf("signature of %s recovers to %x, want %x", label, recovered, pub)
+		}
+	}
+
+	_, err = k.Sign(make([]byte, 32), []byte("BROKEN"))
+	if !errors.Is(err, ErrInvalidKey) {
+		t.Fatalf("sign with malformed key: have %v, want %v", err, ErrInvalidKey)
+	}
+	if strings.Contains(err.Error(), hex.EncodeToString(deployer[:31])) {
+		t.Fatalf("error %q leaks the key", err)
+	}
+	if _, err := k.GetPublicKey([]byte("MISSING")); !errors.Is(err, ErrKeyNotFound) {
+		t.Fatalf("public key of unset variable: have %v, want %v", err, ErrKeyNotFound)
+	}
+	if _, err := k.GeneratePrivateKey(); !errors.Is(err, ErrNotSupported) {
+		t.Fatalf("generate key: have %v, want %v", err, ErrNotSupported)
+	}
+	if _, err := k.ImportPrivateKey(deployer); !errors.Is(err, ErrNotSupported) {
+		t.Fatalf("import key: have %v, want %v", err, ErrNotSupported)
+	}
+	if err := k.DeletePrivateKey([]byte("DEPLOYER")); !errors.Is(err, ErrNotSupported) {
+		t.Fatalf("delete key: have %v, want %v", err, ErrNotSupported)
+	}
+}
+
+func TestEnvKeeperProcessEnv(t *testing.T) {
+	key, _ := GenerateDeterministicKey([]byte("process"))
+	t.Setenv("KEEPERTEST_KEY_CI", hex.EncodeToString(key))
+
+	addr, err := AddressFromKeeper(NewEnvKeeper("KEEPERTEST"), []byte("CI"))
+	if err != nil {
+		t.Fatalf("failed to get address: %v", err)
+	}
+	if want, _ := AddressFromKeeper(defaultKeeper, key); addr != want {
+		t.Fatalf("address mismatch: have %v, want %v", addr, want)
+	}
+}
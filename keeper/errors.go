@@ -0,0 +1,138 @@
+package keeper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrKeyNotFound is returned if the key with the given prvID doesn't exist
+	// in the keeper.
+	ErrKeyNotFound = errors.New("key not found")
+
+	// ErrNotSupported is returned if the keeper doesn't support the requested
+	// operation.
+	ErrNotSupported = errors.New("operation not supported")
+
+	// ErrExportNotSupported is returned if the keeper can't export a key, as
+	// its backend never releases the key material. It matches ErrNotSupported.
+	ErrExportNotSupported = fmt.Errorf("key export %w", ErrNotSupported)
+
+	// ErrInvalidKey is returned if a raw private key passed to the keeper is
+	// not a valid secp256k1 private key.
+	ErrInvalidKey = errors.New("invalid private key")
+
+	// ErrInvalidSignature is returned if a signature is malformed, either as
+	// passed to a verification or as produced by a signing backend.
+	ErrInvalidSignature = errors.New("invalid signature")
+
+	// ErrBackendUnavailable is returned if the service or device holding the
+	// keys can't be reached or failed temporarily. Operations failing with it
+	// may succeed if retried later.
+	ErrBackendUnavailable = errors.New("keeper backend unavailable")
+
+	// ErrCircuitOpen is returned by circuit breaking keepers for the calls they
+	// refuse without asking their backend, as it failed too often. It matches
+	// ErrBackendUnavailable.
+	ErrCircuitOpen = fmt.Errorf("circuit breaker open: %w", ErrBackendUnavailable)
+
+	// ErrOperationTimeout is returned by timeout enforcing keepers for calls to
+	// their backend that didn't complete in time. It matches
+	// context.DeadlineExceeded.
+	ErrOperationTimeout = fmt.Errorf("keeper operation timed out: %w", context.DeadlineExceeded)
+
+	// ErrRateLimitExceeded is returned if the call was refused because the
+	// keeper, or the service behind it, is used faster than allowed.
+	ErrRateLimitExceeded = errors.New("keeper rate limit exceeded")
+
+	// ErrPermissionDenied is returned if the keeper lacks the credentials or
+	// permissions for the operation, e.g. a wrong PIN or passphrase.
+	ErrPermissionDenied = errors.New("permission denied")
+
+	// ErrRotationInProgress is returned if a key is rotated again before its
+	// previous rotation was completed.
+	ErrRotationInProgress = errors.New("key rotation in progress")
+
+	// ErrNoRotation is returned if a rotation is completed for a key that is
+	// not being rotated.
+	ErrNoRotation = errors.New("no key rotation in progress")
+
+	// ErrKeyCollision is returned by GenerateUniqueKey if every attempt produced
+	// the prvID of an existing key.
+	ErrKeyCollision = errors.New("generated key collides with existing key")
+
+	// ErrSignerClosed is returned for requests made to an AsyncSigner after it
+	// was closed.
+	ErrSignerClosed = errors.New("signer closed")
+
+	// ErrDuplicateSigning is returned by nonce tracking signers for
+	// transactions that were signed before.
+	ErrDuplicateSigning = errors.New("transaction signed before")
+
+	// ErrKeyExpired is returned by expiry enforcing keepers for keys used after
+	// the expiry in their metadata.
+	ErrKeyExpired = errors.New("key expired")
+
+	// ErrKeyExhausted is returned by usage limiting keepers for keys that made
+	// all the signatures allowed by their metadata.
+	ErrKeyExhausted = errors.New("key usage exhausted")
+
+	// ErrProposalNotFound is returned by threshold signers for proposals that
+	// were never made or are already finalized.
+	ErrProposalNotFound = errors.New("signing proposal not found")
+
+	// ErrThresholdNotMet is returned by threshold signers finalizing proposals
+	// that lack approvals.
+	ErrThresholdNotMet = errors.New("signing threshold not met")
+
+	// ErrInvalidTransaction is returned by validating signers for signed
+	// transactions failing a validation.
+	ErrInvalidTransaction = errors.New("invalid transaction")
+
+	// ErrUnsignedTx is returned for transactions without a signature where a
+	// signed one is needed.
+	ErrUnsignedTx = errors.New("transaction not signed")
+
+	// ErrDecryptionFailed is returned by Decrypt for messages that weren't
+	// encrypted to the key, or were tampered with.
+	ErrDecryptionFailed = errors.New("decryption failed")
+
+	// ErrApprovalDenied is returned by Approvers for signing requests that
+	// were turned down.
+	ErrApprovalDenied = errors.New("signing approval denied")
+)
+
+// KeeperError is the error returned by the keepers and signers of the package.
+// It records the failed operation and the key it was applied to, the cause is
+// available through errors.Is and errors.As.
+type KeeperError struct {
+	Op    string // operation that failed, e.g. "sign"
+	KeyID []byte // prvID of the key, nil if the operation has none
+	Err   error  // underlying error
+}
+
+// Error returns the operation and the underlying error. The KeyID is left out
+// on purpose, the prvID of some keepers, such as the default one, is the
+// private key itself.
+func (e *KeeperError) Error() string {
+	return "keeper " + e.Op + ": " + e.Err.Error()
+}
+
+func (e *KeeperError) Unwrap() error {
+	return e.Err
+}
+
+// wrapError wraps the error in *err into a KeeperError, to be deferred by the
+// exported methods of the package. Errors that already are KeeperErrors are
+// kept as is, so they report the innermost operation.
+func wrapError(err *error, op string, prvID []byte) {
+	if *err == nil {
+		return
+	}
+	var kerr *KeeperError
+	if errors.As(*err, &kerr) {
+		return
+	}
+	*err = &KeeperError{Op: op, KeyID: prvID, Err: *err}
+}
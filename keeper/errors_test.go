@@ -0,0 +1,74 @@
+package keeper
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	kmstypes "github.com/aws/aws-sdk-go-v2/service/kms/types"
+	"github.com/aws/smithy-go"
+	vault "github.com/hashicorp/vault/api"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestKeeperError(t *testing.T) {
+	prvID, err := defaultKeeper.GeneratePrivateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	_, err = defaultKeeper.Sign([]byte("short"), prvID)
+	var kerr *KeeperError
+	if !errors.As(err, &kerr) {
+		t.Fatalf("sign error is not a KeeperError: %v", err)
+	}
+	if kerr.Op != "sign" || !bytes.Equal(kerr.KeyID, prvID) {
+		t.Fatalf("error mismatch: have op %q, key %x", kerr.Op, kerr.KeyID)
+	}
+	// The prvID of the default keeper is the private key, which must not
+	// end up in logs.
+	if strings.Contains(err.Error(), string(prvID)) || strings.Contains(err.Error(), hex.EncodeToString(prvID)) {
+		t.Fatalf("error message leaks the prvID: %v", err)
+	}
+	// Errors passing through several layers keep the innermost operation.
+	sec := NewSecureSigner(defaultKeeper)
+	_, err = sec.SignPersonalMessage([]byte("hello"), []byte{0x01})
+	if !errors.As(err, &kerr) || kerr.Op != "sign" {
+		t.Fatalf("wrapped error mismatch: have %v", err)
+	}
+	if !errors.Is(sec.VerifyPersonalMessage([]byte("hello"), make([]byte, 10), [20]byte{}), ErrInvalidSignature) {
+		t.Fatal("short signature not reported as ErrInvalidSignature")
+	}
+}
+
+func TestBackendErrorMapping(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want error
+	}{
+		{"kms not found", &KMSError{Op: "Sign", Err: &kmstypes.NotFoundException{}}, ErrKeyNotFound},
+		{"kms access denied", &KMSError{Op: "Sign", Err: &smithy.GenericAPIError{Code: "AccessDeniedException"}}, ErrPermissionDenied},
+		{"kms throttled", &KMSError{Op: "Sign", Err: &smithy.GenericAPIError{Code: "ThrottlingException"}}, ErrRateLimitExceeded},
+		{"kms internal", &KMSError{Op: "Sign", Err: &smithy.GenericAPIError{Code: "KMSInternalException"}}, ErrBackendUnavailable},
+		{"gcp denied", gcpError(status.Error(codes.PermissionDenied, "")), ErrPermissionDenied},
+		{"gcp quota", gcpError(status.Error(codes.ResourceExhausted, "")), ErrRateLimitExceeded},
+		{"gcp unavailable", gcpError(status.Error(codes.Unavailable, "")), ErrBackendUnavailable},
+		{"vault forbidden", vaultError(&vault.ResponseError{StatusCode: http.StatusForbidden}), ErrPermissionDenied},
+		{"vault too many requests", vaultError(&vault.ResponseError{StatusCode: http.StatusTooManyRequests}), ErrRateLimitExceeded},
+		{"vault sealed", vaultError(&vault.ResponseError{StatusCode: http.StatusServiceUnavailable}), ErrBackendUnavailable},
+		{"grpc unauthenticated", grpcError(status.Error(codes.Unauthenticated, "")), ErrPermissionDenied},
+		{"grpc round trip", grpcError(statusError(ErrBackendUnavailable)), ErrBackendUnavailable},
+	}
+	for _, tt := range tests {
+		if !errors.Is(tt.err, tt.want) {
+			t.Errorf("%s: have %v, want %v", tt.name, tt.err, tt.want)
+		}
+	}
+	if err := vaultError(&vault.ResponseError{StatusCode: http.StatusBadRequest}); errors.Is(err, ErrBackendUnavailable) {
+		t.Errorf("vault bad request reported as %v", ErrBackendUnavailable)
+	}
+}
@@ -0,0 +1,34 @@
+package keeper_test
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/keeper"
+)
+
+// This example signs a transaction with a key derived from a BIP-39 mnemonic.
+// The prvID returned by the import is used with a SecureSigner like any other.
+func ExampleHDKeeper() {
+	hd := keeper.NewHDKeeper()
+	prvID, err := hd.ImportFromMnemonic("abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about", "", "m/44'/60'/0'/0/0")
+	if err != nil {
+		panic(err)
+	}
+	signer := types.NewEIP155Signer(big.NewInt(1))
+	tx := types.NewTx(&types.LegacyTx{
+		To:       &common.Address{},
+		Value:    big.NewInt(1),
+		Gas:      21000,
+		GasPrice: big.NewInt(1),
+	})
+	signed, err := keeper.NewSecureSigner(hd).Sign(tx, signer, prvID)
+	if err != nil {
+		panic(err)
+	}
+	from, _ := types.Sender(signer, signed)
+	fmt.Println(from)
+	// Output: 0x9858EfFD232B4033E47d90003D41EC34EcaEda94
+}
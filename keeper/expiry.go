@@ -0,0 +1,94 @@
+package keeper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ExpiryOption configures the expiry enforcing keeper.
+type ExpiryOption func(*expiryKeeper)
+
+// WithExpiry sets the lifetime of generated keys, they expire when it has
+// passed. Without it generated keys don't expire.
+func WithExpiry(lifetime time.Duration) ExpiryOption {
+	return func(k *expiryKeeper) {
+		k.lifetime = lifetime
+	}
+}
+
+// expiryKeeper is a MetadataAwareKeeper refusing to use keys after the expiry
+// recorded in their metadata.
+type expiryKeeper struct {
+	MetadataAwareKeeper
+	lifetime time.Duration    // lifetime of generated keys, 0 for no expiry
+	now      func() time.Time // replaced in tests
+}
+
+// NewExpiryEnforcingKeeper returns a PrivateKeyKeeper failing Sign and
+// GetPublicKey with ErrKeyExpired once the ExpiresAt in the metadata of the key
+// has passed, e.g. for session keys of smart contract wallets. Keys without an
+// expiry, or without metadata as they predate the metadata store, are used as
+// usual. The returned keeper is a MetadataAwareKeeper as well, the expiry of a
+// key may be changed with SetMetadata.
+func NewExpiryEnforcingKeeper(inner MetadataAwareKeeper, opts ...ExpiryOption) PrivateKeyKeeper {
+	k := &expiryKeeper{MetadataAwareKeeper: inner, now: time.Now}
+	for _, opt := range opts {
+		opt(k)
+	}
+	return k
+}
+
+func (k *expiryKeeper) GeneratePrivateKey() (_ []byte, err error) {
+	defer wrapError(&err, "generate key", nil)
+
+	prvID, err := k.MetadataAwareKeeper.GeneratePrivateKey()
+	if err != nil || k.lifetime == 0 {
+		return prvID, err
+	}
+	err = updateNewMetadata(k.MetadataAwareKeeper, prvID, func(meta *KeyMetadata) {
+		meta.ExpiresAt = k.now().Add(k.lifetime).UTC()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return prvID, nil
+}
+
+func (k *expiryKeeper) GetPublicKey(prvID []byte) (_ []byte, err error) {
+	defer wrapError(&err, "get public key", prvID)
+
+	if err := k.checkExpiry(prvID); err != nil {
+		return nil, err
+	}
+	return k.MetadataAwareKeeper.GetPublicKey(prvID)
+}
+
+func (k *expiryKeeper) Sign(data []byte, prvID []byte) (_ []byte, err error) {
+	defer wrapError(&err, "sign", prvID)
+
+	if err := k.checkExpiry(prvID); err != nil {
+		return nil, err
+	}
+	return k.MetadataAwareKeeper.Sign(data, prvID)
+}
+
+func (k *expiryKeeper) HealthCheck(ctx context.Context) error {
+	return checkHealth(ctx, ContextKeeper(k.MetadataAwareKeeper))
+}
+
+// checkExpiry fails with ErrKeyExpired if the key expired.
+func (k *expiryKeeper) checkExpiry(prvID []byte) error {
+	meta, err := k.MetadataAwareKeeper.GetMetadata(prvID)
+	if errors.Is(err, ErrKeyNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if meta.Expired(k.now()) {
+		return fmt.Errorf("%w at %v", ErrKeyExpired, meta.ExpiresAt)
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package keeper
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestExpiryEnforcingKeeper(t *testing.T) {
+	inner := NewMetadataAwareKeeper(new(defaultPrivateKeyKeeper), MemoryMetadataStore()).(MetadataAwareKeeper)
+	k := NewExpiryEnforcingKeeper(inner, WithExpiry(time.Hour)).(*expiryKeeper)
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	k.now = func() time.Time { return now }
+
+	prvID, err := k.GeneratePrivateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	meta, err := k.GetMetadata(prvID)
+	if err != nil || !meta.ExpiresAt.Equal(now.Add(time.Hour)) {
+		t.Fatalf("expiry: have (%v, %v), want %v", meta.ExpiresAt, err, now.Add(time.Hour))
+	}
+	hash := crypto.Keccak256([]byte("expiry"))
+	tests := []struct {
+		now     time.Time
+		expired bool
+	}{
+		{now, false},
+		{now.Add(time.Hour - time.Nanosecond), false},
+		// The key expires only after ExpiresAt.
+		{now.Add(time.Hour), false},
+		{now.Add(time.Hour + time.Nanosecond), true},
+		{now.Add(24 * time.Hour), true},
+	}
+	for i, tt := range tests {
+		k.now = func() time.Time { return tt.now }
+		_, signErr := k.Sign(hash, prvID)
+		_, pubErr := k.GetPublicKey(prvID)
+		for _, err := range []error{signErr, pubErr} {
+			if tt.expired && !errors.Is(err, ErrKeyExpired) {
+				t.Errorf("test %d: have %v, want %v", i, err, ErrKeyExpired)
+			}
+			if !tt.expired && err != nil {
+				t.Errorf("test %d: have %v, want no error", i, err)
+			}
+		}
+	}
+
+	// Extending the expiry makes the key usable again.
+	meta.ExpiresAt = meta.ExpiresAt.Add(48 * time.Hour)
+	if err := k.SetMetadata(prvID, meta); err != nil {
+		t.Fatalf("failed to extend expiry: %v", err)
+	}
+	if _, err := k.Sign(hash, prvID); err != nil {
+		t.Fatalf("failed to sign after extending expiry: %v", err)
+	}
+}
+
+func TestExpiryEnforcingKeeperNoExpiry(t *testing.T) {
+	inner := NewMetadataAwareKeeper(new(defaultPrivateKeyKeeper), MemoryMetadataStore()).(MetadataAwareKeeper)
+	k := NewExpiryEnforcingKeeper(inner).(*expiryKeeper)
+	prvID, err := k.GeneratePrivateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	k.now = func() time.Time { return time.Now().Add(100 * 365 * 24 * time.Hour) }
+	if _, err := k.Sign(crypto.Keccak256(nil), prvID); err != nil {
+		t.Fatalf("key without expiry refused: %v", err)
+	}
+	// Keys without metadata don't expire either.
+	legacy, _ := new(defaultPrivateKeyKeeper).GeneratePrivateKey()
+	if _, err := k.Sign(crypto.Keccak256(nil), legacy); err != nil {
+		t.Fatalf("key without metadata refused: %v", err)
+	}
+}
@@ -0,0 +1,170 @@
+package keeper
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"golang.org/x/crypto/argon2"
+)
+
+// ExportableKeeper is a PrivateKeyKeeper supporting encrypted backups of its
+// keys, e.g. for disaster recovery. Backups are portable, a key exported from
+// one keeper can be imported into any other:
+//
+//	backup, err := k.ExportEncryptedKey(prvID, passphrase)
+//	// ... store backup offline ...
+//	prvID, err = other.ImportEncryptedKey(backup, passphrase)
+//
+// Keepers whose keys never leave their backend, like the KMS keepers, still
+// import backups but fail exports with ErrExportNotSupported.
+type ExportableKeeper interface {
+	PrivateKeyKeeper
+
+	// ExportEncryptedKey returns the key prvID encrypted with passphrase.
+	ExportEncryptedKey(prvID []byte, passphrase string) ([]byte, error)
+	// ImportEncryptedKey decrypts a key exported with ExportEncryptedKey and
+	// stores it in the keeper, failing with ErrPermissionDenied for a wrong
+	// passphrase.
+	ImportEncryptedKey(data []byte, passphrase string) (prvID []byte, err error)
+}
+
+// Argon2id parameters of exported keys, the second recommendation of RFC 9106.
+const (
+	exportKDFTime    = 3
+	exportKDFMemory  = 64 * 1024 // KiB
+	exportKDFThreads = 4
+	exportKeyLen     = 32
+
+	// exportKDFMaxMemory bounds the memory an imported envelope can make the
+	// key derivation allocate.
+	exportKDFMaxMemory = 1024 * 1024 // KiB
+)
+
+// exportEnvelope is the JSON encoded export of a key.
+type exportEnvelope struct {
+	Version int    `json:"version"`
+	Address string `json:"address"` // hex address of the key, without 0x prefix
+	Crypto  struct {
+		Cipher     string        `json:"cipher"`
+		Nonce      hexutil.Bytes `json:"nonce"`
+		Ciphertext hexutil.Bytes `json:"ciphertext"`
+		KDF        string        `json:"kdf"`
+		KDFParams  exportKDF     `json:"kdfparams"`
+	} `json:"crypto"`
+}
+
+// exportKDF are the Argon2id parameters of an exportEnvelope.
+type exportKDF struct {
+	Time    uint32        `json:"time"`
+	Memory  uint32        `json:"memory"` // KiB
+	Threads uint8         `json:"threads"`
+	KeyLen  uint32        `json:"keylen"`
+	Salt    hexutil.Bytes `json:"salt"`
+}
+
+// encryptKey encrypts rawKey with passphrase into an export envelope.
+func encryptKey(rawKey []byte, passphrase string) ([]byte, error) {
+	if passphrase == "" {
+		return nil, errors.New("empty export passphrase")
+	}
+	key, err := parseRawKey(rawKey)
+	if err != nil {
+		return nil, err
+	}
+	defer ZeroKey(key)
+
+	env := exportEnvelope{Version: 1, Address: strings.ToLower(crypto.PubkeyToAddress(key.PublicKey).Hex()[2:])}
+	env.Crypto.Cipher = "aes-256-gcm"
+	env.Crypto.KDF = "argon2id"
+	env.Crypto.KDFParams = exportKDF{
+		Time:    exportKDFTime,
+		Memory:  exportKDFMemory,
+		Threads: exportKDFThreads,
+		KeyLen:  exportKeyLen,
+		Salt:    make([]byte, 32),
+	}
+	if _, err := rand.Read(env.Crypto.KDFParams.Salt); err != nil {
+		return nil, err
+	}
+	aead, err := env.Crypto.KDFParams.aead(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	env.Crypto.Nonce = make([]byte, aead.NonceSize())
+	if _, err := rand.Read(env.Crypto.Nonce); err != nil {
+		return nil, err
+	}
+	env.Crypto.Ciphertext = aead.Seal(nil, env.Crypto.Nonce, rawKey, []byte(env.Address))
+	return json.Marshal(env)
+}
+
+// decryptKey decrypts the raw key of an export envelope. The caller has to
+// clear the returned key after use.
+func decryptKey(data []byte, passphrase string) ([]byte, error) {
+	var env exportEnvelope
+	if err := json.Unmarshal(data, &env); err != nil {
+		return nil, fmt.Errorf("invalid key export: %v", err)
+	}
+	if env.Version != 1 || env.Crypto.Cipher != "aes-256-gcm" || env.Crypto.KDF != "argon2id" {
+		return nil, fmt.Errorf("unsupported key export: version %d, cipher %q, kdf %q", env.Version, env.Crypto.Cipher, env.Crypto.KDF)
+	}
+	params := env.Crypto.KDFParams
+	if params.Time == 0 || params.Threads == 0 || params.Memory > exportKDFMaxMemory || params.KeyLen != exportKeyLen {
+		return nil, fmt.Errorf("invalid key export kdf parameters: time %d, memory %d, threads %d, keylen %d", params.Time, params.Memory, params.Threads, params.KeyLen)
+	}
+	aead, err := params.aead(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	if len(env.Crypto.Nonce) != aead.NonceSize() {
+		return nil, fmt.Errorf("invalid key export nonce length %d", len(env.Crypto.Nonce))
+	}
+	// The address is authenticated, a mismatch fails like a wrong passphrase.
+	rawKey, err := aead.Open(nil, env.Crypto.Nonce, env.Crypto.Ciphertext, []byte(env.Address))
+	if err != nil {
+		return nil, fmt.Errorf("%w: could not decrypt key with given passphrase", ErrPermissionDenied)
+	}
+	key, err := parseRawKey(rawKey)
+	if err != nil {
+		zeroBytes(rawKey)
+		return nil, err
+	}
+	defer ZeroKey(key)
+	if crypto.PubkeyToAddress(key.PublicKey) != common.HexToAddress(env.Address) {
+		zeroBytes(rawKey)
+		return nil, errors.New("key export doesn't match its address")
+	}
+	return rawKey, nil
+}
+
+// aead derives the AES-256-GCM cipher of an export from passphrase.
+func (p exportKDF) aead(passphrase string) (cipher.AEAD, error) {
+	derived := argon2.IDKey([]byte(passphrase), p.Salt, p.Time, p.Memory, p.Threads, p.KeyLen)
+	defer zeroBytes(derived)
+
+	block, err := aes.NewCipher(derived)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// importEncryptedKey decrypts data and imports the key into k.
+func importEncryptedKey(k PrivateKeyKeeper, data []byte, passphrase string) (_ []byte, err error) {
+	defer wrapError(&err, "import key", nil)
+
+	rawKey, err := decryptKey(data, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	defer zeroBytes(rawKey)
+	return k.ImportPrivateKey(rawKey)
+}
@@ -0,0 +1,172 @@
+package keeper
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// checkExport exports prvID from k and checks that the backup restores the
+// same key into the default keeper.
+func checkExport(t *testing.T, k ExportableKeeper, prvID []byte) []byte {
+	t.Helper()
+
+	backup, err := k.ExportEncryptedKey(prvID, "backup")
+	if err != nil {
+		t.Fatalf("failed to export key: %v", err)
+	}
+	want, err := k.GetPublicKey(prvID)
+	if err != nil {
+		t.Fatalf("failed to get public key: %v", err)
+	}
+	restored, err := new(defaultPrivateKeyKeeper).ImportEncryptedKey(backup, "backup")
+	if err != nil {
+		t.Fatalf("failed to import backup: %v", err)
+	}
+	if pub, _ := defaultKeeper.GetPublicKey(restored); !bytes.Equal(pub, want) {
+		t.Fatalf("restored public key mismatch: have %x, want %x", pub, want)
+	}
+	return backup
+}
+
+func TestExportEncryptedKey(t *testing.T) {
+	k := new(defaultPrivateKeyKeeper)
+	prvID, _ := k.GeneratePrivateKey()
+	backup := checkExport(t, k, prvID)
+
+	var env exportEnvelope
+	if err := json.Unmarshal(backup, &env); err != nil {
+		t.Fatalf("invalid envelope: %v", err)
+	}
+	key, _ := crypto.ToECDSA(prvID)
+	if want := crypto.PubkeyToAddress(key.PublicKey).Hex()[2:]; !bytes.EqualFold([]byte(env.Address), []byte(want)) {
+		t.Fatalf("envelope address: have %s, want %s", env.Address, want)
+	}
+	if bytes.Contains(env.Crypto.Ciphertext, prvID) {
+		t.Fatal("envelope holds the plain key")
+	}
+	params := env.Crypto.KDFParams
+	if env.Crypto.Cipher != "aes-256-gcm" || env.Crypto.KDF != "argon2id" || len(params.Salt) != 32 ||
+		params.Time != exportKDFTime || params.Memory != exportKDFMemory || params.Threads != exportKDFThreads {
+		t.Fatalf("unexpected envelope parameters: %s", backup)
+	}
+	// Salt and nonce are random, exporting twice gives different backups.
+	if again, _ := k.ExportEncryptedKey(prvID, "backup"); bytes.Equal(again, backup) {
+		t.Fatal("repeated export produced the same backup")
+	}
+	if _, err := k.ImportEncryptedKey(backup, "wrong"); !errors.Is(err, ErrPermissionDenied) {
+		t.Fatalf("wrong passphrase: have %v, want %v", err, ErrPermissionDenied)
+	}
+	if _, err := k.ExportEncryptedKey(prvID, ""); err == nil {
+		t.Fatal("exported key without passphrase")
+	}
+	if _, err := k.ExportEncryptedKey(prvID[:31], "backup"); !errors.Is(err, ErrInvalidKey) {
+		t.Fatalf("export of malformed key: have %v, want %v", err, ErrInvalidKey)
+	}
+}
+
+func TestImportEncryptedKeyTampered(t *testing.T) {
+	k := new(defaultPrivateKeyKeeper)
+	prvID, _ := k.GeneratePrivateKey()
+	backup, err := k.ExportEncryptedKey(prvID, "backup")
+	if err != nil {
+		t.Fatalf("failed to export key: %v", err)
+	}
+	other, _ := crypto.GenerateKey()
+	for name, tamper := range map[string]func(*exportEnvelope){
+		"address":    func(env *exportEnvelope) { env.Address = crypto.PubkeyToAddress(other.PublicKey).Hex()[2:] },
+		"ciphertext": func(env *exportEnvelope) { env.Crypto.Ciphertext[0] ^= 0xff },
+		"salt":       func(env *exportEnvelope) { env.Crypto.KDFParams.Salt[0] ^= 0xff },
+	} {
+		var env exportEnvelope
+		json.Unmarshal(backup, &env)
+		tamper(&env)
+		data, _ := json.Marshal(env)
+		if _, err := k.ImportEncryptedKey(data, "backup"); !errors.Is(err, ErrPermissionDenied) {
+			t.Errorf("tampered %s: have %v, want %v", name, err, ErrPermissionDenied)
+		}
+	}
+	// Parameters making the import allocate excessive memory are refused.
+	var env exportEnvelope
+	json.Unmarshal(backup, &env)
+	env.Crypto.KDFParams.Memory = exportKDFMaxMemory + 1
+	data, _ := json.Marshal(env)
+	if _, err := k.ImportEncryptedKey(data, "backup"); err == nil || errors.Is(err, ErrPermissionDenied) {
+		t.Fatalf("excessive kdf memory: have %v, want parameter error", err)
+	}
+}
+
+func TestKeystoreKeeperExport(t *testing.T) {
+	k := NewKeystoreKeeper(t.TempDir(), keystore.LightScryptN, keystore.LightScryptP, MemoryPassphraseProvider("foo")).(ExportableKeeper)
+	prvID, err := k.GeneratePrivateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	backup := checkExport(t, k, prvID)
+
+	// The backup restores the key into a different keystore.
+	other := NewKeystoreKeeper(t.TempDir(), keystore.LightScryptN, keystore.LightScryptP, MemoryPassphraseProvider("bar")).(ExportableKeeper)
+	restored, err := other.ImportEncryptedKey(backup, "backup")
+	if err != nil {
+		t.Fatalf("failed to import backup: %v", err)
+	}
+	want, _ := k.GetPublicKey(prvID)
+	if pub, err := other.GetPublicKey(restored); err != nil || !bytes.Equal(pub, want) {
+		t.Fatalf("restored public key mismatch: have (%x, %v), want %x", pub, err, want)
+	}
+}
+
+func TestShamirKeeperExport(t *testing.T) {
+	k, err := NewShamirKeeper(2, 3, nil)
+	if err != nil {
+		t.Fatalf("failed to create keeper: %v", err)
+	}
+	prvID, err := k.GeneratePrivateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	backup := checkExport(t, k.(ExportableKeeper), prvID)
+	restored, err := k.(ExportableKeeper).ImportEncryptedKey(backup, "backup")
+	if err != nil {
+		t.Fatalf("failed to import backup: %v", err)
+	}
+	if !bytes.Contains(restored, []byte(`"shares"`)) {
+		t.Fatalf("restored key is not split: %s", restored)
+	}
+	// Backends without export support are reported as such.
+	k, _ = NewShamirKeeper(2, 3, new(countingKeeper))
+	if prvID, err = k.GeneratePrivateKey(); err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	if _, err := k.(ExportableKeeper).ExportEncryptedKey(prvID, "backup"); !errors.Is(err, ErrExportNotSupported) {
+		t.Fatalf("export from plain backend: have %v, want %v", err, ErrExportNotSupported)
+	}
+}
+
+func TestAWSKMSKeeperExport(t *testing.T) {
+	k := newAWSKMSKeeper(newFakeKMS(), "")
+	prvID, err := k.GeneratePrivateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	_, err = k.ExportEncryptedKey(prvID, "backup")
+	if !errors.Is(err, ErrExportNotSupported) || !errors.Is(err, ErrNotSupported) {
+		t.Fatalf("export: have %v, want %v", err, ErrExportNotSupported)
+	}
+	// Backups of other keepers can still be restored into KMS.
+	src := new(defaultPrivateKeyKeeper)
+	srcID, _ := src.GeneratePrivateKey()
+	backup, _ := src.ExportEncryptedKey(srcID, "backup")
+	restored, err := k.ImportEncryptedKey(backup, "backup")
+	if err != nil {
+		t.Fatalf("failed to import backup: %v", err)
+	}
+	want, _ := src.GetPublicKey(srcID)
+	if pub, err := k.GetPublicKey(restored); err != nil || !bytes.Equal(pub, want) {
+		t.Fatalf("restored public key mismatch: have (%x, %v), want %x", pub, err, want)
+	}
+}
@@ -0,0 +1,467 @@
+package keeper
+
+import (
+	"bytes"
+	"context"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/uuid"
+)
+
+const (
+	// DefaultFireblocksURL is the base URL of the Fireblocks API.
+	DefaultFireblocksURL = "https://api.fireblocks.io"
+
+	// DefaultFireblocksAsset is the asset whose wallets hold the keys of the
+	// Fireblocks keeper.
+	DefaultFireblocksAsset = "ETH"
+
+	// fireblocksPollTimeout is the default time a signing request may take,
+	// including the approvals the policy of the workspace asks for.
+	fireblocksPollTimeout = 5 * time.Minute
+
+	// fireblocksPollInterval is the interval between state checks of signing
+	// requests.
+	fireblocksPollInterval = time.Second
+
+	// fireblocksTokenLifetime is the lifetime of the JWT of a request, Fireblocks
+	// refuses tokens valid for 30 seconds or more.
+	fireblocksTokenLifetime = 29 * time.Second
+)
+
+// FireblocksOption configures the Fireblocks keeper.
+type FireblocksOption func(*fireblocksKeeper)
+
+// WithFireblocksAsset sets the asset whose wallets hold the keys, it has to be
+// an asset of an EVM chain.
+func WithFireblocksAsset(assetID string) FireblocksOption {
+	return func(k *fireblocksKeeper) {
+		k.asset = assetID
+	}
+}
+
+// WithFireblocksPollTimeout sets the time a signing request may take before it
+// is cancelled.
+func WithFireblocksPollTimeout(d time.Duration) FireblocksOption {
+	return func(k *fireblocksKeeper) {
+		k.pollTimeout = d
+	}
+}
+
+// fireblocksKeyID is the prvID of the Fireblocks keeper, the wallet of an asset
+// in a vault account.
+type fireblocksKeyID struct {
+	VaultAccountID string `json:"vaultAccountId"`
+	AssetID        string `json:"assetId"`
+}
+
+// fireblocksKeeper is a PrivateKeyKeeper using the keys of Fireblocks vault
+// accounts. The keys are MPC keys held by Fireblocks, they are only ever used
+// through signing requests, which the transaction authorization policy of the
+// workspace may have to approve.
+type fireblocksKeeper struct {
+	client       *http.Client
+	baseURL      string
+	apiKey       string
+	signKey      *rsa.PrivateKey // key the requests are authenticated with
+	asset        string
+	pollTimeout  time.Duration
+	pollInterval time.Duration
+
+	pubkeys sync.Map // prvID -> uncompressed public key
+}
+
+// NewFireblocksKeeper returns a PrivateKeyKeeper backed by the Fireblocks
+// workspace of the API user apiKey, whose RSA private key privKeyPEM signs the
+// JWT authenticating every request. An empty baseURL defaults to
+// DefaultFireblocksURL.
+//
+// GeneratePrivateKey creates a vault account with a wallet of the asset, the
+// prvID is the JSON encoded vault account and asset ID. Sign submits a RAW
+// signing request and polls for its completion. Fireblocks neither imports
+// raw keys nor deletes vault accounts, ImportPrivateKey and DeletePrivateKey
+// fail with ErrNotSupported.
+func NewFireblocksKeeper(apiKey string, privKeyPEM []byte, baseURL string, opts ...FireblocksOption) (PrivateKeyKeeper, error) {
+	signKey, err := jwt.ParseRSAPrivateKeyFromPEM(privKeyPEM)
+	if err != nil {
+		return nil, fmt.Errorf("invalid fireblocks api key: %w", err)
+	}
+	if baseURL == "" {
+		baseURL = DefaultFireblocksURL
+	}
+	k := &fireblocksKeeper{
+		client:       http.DefaultClient,
+		baseURL:      strings.TrimSuffix(baseURL, "/"),
+		apiKey:       apiKey,
+		signKey:      signKey,
+		asset:        DefaultFireblocksAsset,
+		pollTimeout:  fireblocksPollTimeout,
+		pollInterval: fireblocksPollInterval,
+	}
+	for _, opt := range opts {
+		opt(k)
+	}
+	return k, nil
+}
+
+// fireblocksError marks failed Fireblocks requests with the matching error of
+// the keeper package.
+func fireblocksError(status int, body []byte) error {
+	var resp struct {
+		Message string `json:"message"`
+	}
+	msg := http.StatusText(status)
+	if json.Unmarshal(body, &resp) == nil && resp.Message != "" {
+		msg = resp.Message
+	}
+	err := fmt.Errorf("fireblocks: %s (%d)", msg, status)
+	switch {
+	case status == http.StatusUnauthorized || status == http.StatusForbidden:
+		return fmt.Errorf("%w: %w", ErrPermissionDenied, err)
+	case status == http.StatusNotFound:
+		return fmt.Errorf("%w: %w", ErrKeyNotFound, err)
+	case status == http.StatusTooManyRequests:
+		return fmt.Errorf("%w: %w", ErrRateLimitExceeded, err)
+	case status >= http.StatusInternalServerError:
+		return fmt.Errorf("%w: %w", ErrBackendUnavailable, err)
+	}
+	return err
+}
+
+// request sends an authenticated request for path, which includes the query,
+// with the JSON encoded body, if not nil, and decodes the response into out.
+func (k *fireblocksKeeper) request(ctx context.Context, method, path string, body, out interface{}) error {
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return err
+		}
+	}
+	// The token is bound to the request by its path and the hash of its body.
+	now := time.Now()
+	bodyHash := sha256.Sum256(payload)
+	token, err := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"uri":      path,
+		"nonce":    uuid.New().String(),
+		"iat":      now.Unix(),
+		"exp":      now.Add(fireblocksTokenLifetime).Unix(),
+		"sub":      k.apiKey,
+		"bodyHash": hex.EncodeToString(bodyHash[:]),
+	}).SignedString(k.signKey)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, method, k.baseURL+path, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-API-Key", k.apiKey)
+	req.Header.Set("Authorization", "Bearer "+token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := k.client.Do(req)
+	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		return fmt.Errorf("%w: %w", ErrBackendUnavailable, err)
+	}
+	defer resp.Body.Close()
+
+	content, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrBackendUnavailable, err)
+	}
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		return fireblocksError(resp.StatusCode, content)
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.Unmarshal(content, out); err != nil {
+		return fmt.Errorf("invalid fireblocks response: %w", err)
+	}
+	return nil
+}
+
+// keyID decodes the prvID of a key.
+func (k *fireblocksKeeper) keyID(prvID []byte) (fireblocksKeyID, error) {
+	var id fireblocksKeyID
+	if err := json.Unmarshal(prvID, &id); err != nil || id.VaultAccountID == "" || id.AssetID == "" {
+		return fireblocksKeyID{}, fmt.Errorf("%w: invalid fireblocks key id", ErrKeyNotFound)
+	}
+	return id, nil
+}
+
+// walletPath returns the API path of the wallet of id, followed by suffix.
+func walletPath(id fireblocksKeyID, suffix string) string {
+	return "/v1/vault/accounts/" + url.PathEscape(id.VaultAccountID) + "/" + url.PathEscape(id.AssetID) + suffix
+}
+
+func (k *fireblocksKeeper) GeneratePrivateKey() ([]byte, error) {
+	return k.GeneratePrivateKeyContext(context.Background())
+}
+
+// GeneratePrivateKeyContext creates a vault account and the wallet of the asset
+// in it. If the wallet can't be created the vault account is left behind
+// empty, Fireblocks doesn't delete vault accounts.
+func (k *fireblocksKeeper) GeneratePrivateKeyContext(ctx context.Context) (_ []byte, err error) {
+	defer wrapError(&err, "generate key", nil)
+
+	var account struct {
+		ID string `json:"id"`
+	}
+	if err := k.request(ctx, http.MethodPost, "/v1/vault/accounts", map[string]interface{}{
+		"name": "keeper-" + uuid.New().String(),
+	}, &account); err != nil {
+		return nil, err
+	}
+	if account.ID == "" {
+		return nil, errors.New("missing vault account id in fireblocks response")
+	}
+	id := fireblocksKeyID{VaultAccountID: account.ID, AssetID: k.asset}
+	if err := k.request(ctx, http.MethodPost, walletPath(id, ""), struct{}{}, nil); err != nil {
+		return nil, err
+	}
+	return json.Marshal(id)
+}
+
+func (k *fireblocksKeeper) GetPublicKey(prvID []byte) ([]byte, error) {
+	return k.GetPublicKeyContext(context.Background(), prvID)
+}
+
+// GetPublicKeyContext returns the public key of the deposit address of the
+// wallet, the first address of its external chain.
+func (k *fireblocksKeeper) GetPublicKeyContext(ctx context.Context, prvID []byte) (_ []byte, err error) {
+	defer wrapError(&err, "get public key", prvID)
+
+	if pub, ok := k.pubkeys.Load(string(prvID)); ok {
+		return pub.([]byte), nil
+	}
+	id, err := k.keyID(prvID)
+	if err != nil {
+		return nil, err
+	}
+	var info struct {
+		PublicKey string `json:"publicKey"`
+	}
+	if err := k.request(ctx, http.MethodGet, walletPath(id, "/0/0/public_key_info?compressed=false"), nil, &info); err != nil {
+		return nil, err
+	}
+	raw, err := hex.DecodeString(strings.TrimPrefix(info.PublicKey, "0x"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid fireblocks public key: %w", err)
+	}
+	key, err := ParsePublicKey(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid fireblocks public key: %w", err)
+	}
+	pub := crypto.FromECDSAPub(key)
+	k.pubkeys.Store(string(prvID), pub)
+	return pub, nil
+}
+
+func (k *fireblocksKeeper) Sign(data []byte, prvID []byte) ([]byte, error) {
+	return k.SignContext(context.Background(), data, prvID)
+}
+
+// fireblocksTx is the state of a signing request.
+type fireblocksTx struct {
+	ID             string `json:"id"`
+	Status         string `json:"status"`
+	SubStatus      string `json:"subStatus"`
+	SignedMessages []struct {
+		Signature struct {
+			R string `json:"r"`
+			S string `json:"s"`
+		} `json:"signature"`
+	} `json:"signedMessages"`
+}
+
+// SignContext submits a RAW signing request for the hash data and polls it
+// until it completes, fails or the poll timeout passes. Requests still pending
+// then, e.g. waiting for approvals, are cancelled.
+func (k *fireblocksKeeper) SignContext(ctx context.Context, data []byte, prvID []byte) (_ []byte, err error) {
+	defer wrapError(&err, "sign", prvID)
+
+	if len(data) != 32 {
+		return nil, fmt.Errorf("fireblocks signs 32 byte hashes, have %d bytes", len(data))
+	}
+	id, err := k.keyID(prvID)
+	if err != nil {
+		return nil, err
+	}
+	pub, err := k.GetPublicKeyContext(ctx, prvID)
+	if err != nil {
+		return nil, err
+	}
+	var tx fireblocksTx
+	if err := k.request(ctx, http.MethodPost, "/v1/transactions", map[string]interface{}{
+		"operation": "RAW",
+		"assetId":   id.AssetID,
+		"source":    map[string]string{"type": "VAULT_ACCOUNT", "id": id.VaultAccountID},
+		"note":      "keeper signature",
+		"extraParameters": map[string]interface{}{
+			"rawMessageData": map[string]interface{}{
+				"messages": []map[string]string{{"content": hex.EncodeToString(data)}},
+			},
+		},
+	}, &tx); err != nil {
+		return nil, err
+	}
+	if tx.ID == "" {
+		return nil, errors.New("missing transaction id in fireblocks response")
+	}
+	if tx, err = k.await(ctx, tx.ID); err != nil {
+		return nil, err
+	}
+	if len(tx.SignedMessages) != 1 {
+		return nil, fmt.Errorf("%w: fireblocks returned %d signatures", ErrInvalidSignature, len(tx.SignedMessages))
+	}
+	sig := tx.SignedMessages[0].Signature
+	r, okR := new(big.Int).SetString(strings.TrimPrefix(sig.R, "0x"), 16)
+	s, okS := new(big.Int).SetString(strings.TrimPrefix(sig.S, "0x"), 16)
+	if !okR || !okS {
+		return nil, fmt.Errorf("%w: malformed fireblocks signature", ErrInvalidSignature)
+	}
+	return recoverableSignature(data, r, s, pub)
+}
+
+// await polls the signing request txID until it completes. Rejected and blocked
+// requests fail with ErrPermissionDenied.
+func (k *fireblocksKeeper) await(ctx context.Context, txID string) (fireblocksTx, error) {
+	pollCtx, cancel := context.WithTimeout(ctx, k.pollTimeout)
+	defer cancel()
+
+	path := "/v1/transactions/" + url.PathEscape(txID)
+	for {
+		var tx fireblocksTx
+		if err := k.request(pollCtx, http.MethodGet, path, nil, &tx); err != nil {
+			if pollCtx.Err() != nil {
+				k.cancel(path)
+			}
+			return fireblocksTx{}, err
+		}
+		switch tx.Status {
+		case "COMPLETED":
+			return tx, nil
+		case "REJECTED", "BLOCKED":
+			return fireblocksTx{}, fmt.Errorf("%w: fireblocks signing request %s %s (%s)", ErrPermissionDenied, txID, strings.ToLower(tx.Status), tx.SubStatus)
+		case "CANCELLING", "CANCELLED", "FAILED":
+			return fireblocksTx{}, fmt.Errorf("fireblocks signing request %s %s (%s)", txID, strings.ToLower(tx.Status), tx.SubStatus)
+		}
+		timer := time.NewTimer(k.pollInterval)
+		select {
+		case <-pollCtx.Done():
+			timer.Stop()
+			k.cancel(path)
+			return fireblocksTx{}, pollCtx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// cancel cancels the pending signing request at path, so it isn't signed after
+// the caller gave up on it. Failures are ignored, the request may have just
+// completed.
+func (k *fireblocksKeeper) cancel(path string) {
+	ctx, cancel := context.WithTimeout(context.Background(), fireblocksTokenLifetime)
+	defer cancel()
+	k.request(ctx, http.MethodPost, path+"/cancel", struct{}{}, nil)
+}
+
+func (k *fireblocksKeeper) DeletePrivateKey(prvID []byte) error {
+	return k.DeletePrivateKeyContext(context.Background(), prvID)
+}
+
+// DeletePrivateKeyContext returns ErrNotSupported, Fireblocks doesn't delete
+// vault accounts.
+func (k *fireblocksKeeper) DeletePrivateKeyContext(ctx context.Context, prvID []byte) (err error) {
+	defer wrapError(&err, "delete key", prvID)
+	return ErrNotSupported
+}
+
+func (k *fireblocksKeeper) ListPrivateKeys() ([][]byte, error) {
+	return k.ListPrivateKeysContext(context.Background())
+}
+
+// ListPrivateKeysContext returns the wallets of the asset in all vault accounts
+// of the workspace, including the ones not created by the keeper.
+func (k *fireblocksKeeper) ListPrivateKeysContext(ctx context.Context) (_ [][]byte, err error) {
+	defer wrapError(&err, "list keys", nil)
+
+	var (
+		prvIDs [][]byte
+		after  string
+	)
+	for {
+		query := url.Values{"limit": {"200"}}
+		if after != "" {
+			query.Set("after", after)
+		}
+		var page struct {
+			Accounts []struct {
+				ID     string `json:"id"`
+				Assets []struct {
+					ID string `json:"id"`
+				} `json:"assets"`
+			} `json:"accounts"`
+			Paging struct {
+				After string `json:"after"`
+			} `json:"paging"`
+		}
+		if err := k.request(ctx, http.MethodGet, "/v1/vault/accounts_paged?"+query.Encode(), nil, &page); err != nil {
+			return nil, err
+		}
+		for _, account := range page.Accounts {
+			for _, asset := range account.Assets {
+				if asset.ID != k.asset {
+					continue
+				}
+				prvID, err := json.Marshal(fireblocksKeyID{VaultAccountID: account.ID, AssetID: asset.ID})
+				if err != nil {
+					return nil, err
+				}
+				prvIDs = append(prvIDs, prvID)
+			}
+		}
+		if page.Paging.After == "" {
+			return prvIDs, nil
+		}
+		after = page.Paging.After
+	}
+}
+
+func (k *fireblocksKeeper) ImportPrivateKey(rawKey []byte) ([]byte, error) {
+	return k.ImportPrivateKeyContext(context.Background(), rawKey)
+}
+
+// ImportPrivateKeyContext returns ErrNotSupported, Fireblocks doesn't import raw
+// keys through its API.
+func (k *fireblocksKeeper) ImportPrivateKeyContext(ctx context.Context, rawKey []byte) (_ []byte, err error) {
+	defer wrapError(&err, "import key", nil)
+	return nil, ErrNotSupported
+}
+
+// HealthCheck lists a single vault account, which checks both reachability and
+// the credentials of the keeper.
+func (k *fireblocksKeeper) HealthCheck(ctx context.Context) (err error) {
+	defer wrapError(&err, "health check", nil)
+	return k.request(ctx, http.MethodGet, "/v1/vault/accounts_paged?limit=1", nil, nil)
+}
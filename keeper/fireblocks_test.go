@@ -0,0 +1,301 @@
+package keeper
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// fakeFireblocks emulates the parts of the Fireblocks API used by the keeper,
+// checking the authentication of every request. Signing requests are pending
+// for the first polls before they complete.
+type fakeFireblocks struct {
+	apiKey string
+	pub    *rsa.PublicKey
+
+	lock      sync.Mutex
+	wallets   map[string]*ecdsa.PrivateKey // vault account ID -> key of its ETH wallet, nil before creation
+	txs       map[string]*fakeFireblocksTx
+	pending   int    // polls a signing request is pending for
+	status    string // final status of signing requests, COMPLETED if empty
+	cancelled []string
+}
+
+type fakeFireblocksTx struct {
+	account string
+	content []byte
+	polls   int
+}
+
+func (f *fakeFireblocks) reply(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// authenticate checks the API key and the JWT of r against its body.
+func (f *fakeFireblocks) authenticate(r *http.Request, body []byte) error {
+	if r.Header.Get("X-API-Key") != f.apiKey {
+		return errors.New("unknown api key")
+	}
+	token, err := jwt.Parse(strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "), func(t *jwt.Token) (interface{}, error) {
+		if t.Method != jwt.SigningMethodRS256 {
+			return nil, fmt.Errorf("unexpected signing method %v", t.Method.Alg())
+		}
+		return f.pub, nil
+	})
+	if err != nil {
+		return err
+	}
+	claims := token.Claims.(jwt.MapClaims)
+	hash := sha256.Sum256(body)
+	switch {
+	case claims["sub"] != f.apiKey:
+		return errors.New("subject mismatch")
+	case claims["uri"] != r.URL.RequestURI():
+		return fmt.Errorf("uri mismatch: %v", claims["uri"])
+	case claims["bodyHash"] != hex.EncodeToString(hash[:]):
+		return errors.New("body hash mismatch")
+	case claims["nonce"] == "" || claims["exp"] == nil:
+		return errors.New("missing claims")
+	}
+	return nil
+}
+
+func (f *fakeFireblocks) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	if err := f.authenticate(r, body); err != nil {
+		f.reply(w, http.StatusUnauthorized, map[string]interface{}{"message": err.Error(), "code": -7})
+		return
+	}
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/v1/"), "/")
+	switch {
+	case r.Method == http.MethodPost && r.URL.Path == "/v1/vault/accounts":
+		id := fmt.Sprint(len(f.wallets))
+		f.wallets[id] = nil
+		f.reply(w, http.StatusOK, map[string]string{"id": id, "name": "keeper"})
+
+	case r.Method == http.MethodPost && len(parts) == 4 && parts[0] == "vault" && parts[1] == "accounts" && parts[3] == "ETH":
+		if _, ok := f.wallets[parts[2]]; !ok {
+			f.reply(w, http.StatusNotFound, map[string]string{"message": "vault account not found"})
+			return
+		}
+		f.wallets[parts[2]], _ = crypto.GenerateKey()
+		f.reply(w, http.StatusOK, map[string]string{"id": "ETH"})
+
+	case r.Method == http.MethodGet && len(parts) == 7 && parts[6] == "public_key_info":
+		key := f.wallets[parts[2]]
+		if key == nil || parts[3] != "ETH" {
+			f.reply(w, http.StatusNotFound, map[string]string{"message": "wallet not found"})
+			return
+		}
+		f.reply(w, http.StatusOK, map[string]string{"publicKey": hex.EncodeToString(crypto.FromECDSAPub(&key.PublicKey))})
+
+	case r.Method == http.MethodGet && r.URL.Path == "/v1/vault/accounts_paged":
+		var accounts []map[string]interface{}
+		for id, key := range f.wallets {
+			assets := []map[string]string{{"id": "BTC"}}
+			if key != nil {
+				assets = append(assets, map[string]string{"id": "ETH"})
+			}
+			accounts = append(accounts, map[string]interface{}{"id": id, "assets": assets})
+		}
+		f.reply(w, http.StatusOK, map[string]interface{}{"accounts": accounts, "paging": map[string]string{}})
+
+	case r.Method == http.MethodPost && r.URL.Path == "/v1/transactions":
+		var req struct {
+			Operation string
+			Source    struct{ ID string }
+			Extra     struct {
+				RawMessageData struct {
+					Messages []struct{ Content string }
+				}
+			} `json:"extraParameters"`
+		}
+		json.Unmarshal(body, &req)
+		if req.Operation != "RAW" || len(req.Extra.RawMessageData.Messages) != 1 {
+			f.reply(w, http.StatusBadRequest, map[string]string{"message": "invalid signing request"})
+			return
+		}
+		content, _ := hex.DecodeString(req.Extra.RawMessageData.Messages[0].Content)
+		id := fmt.Sprintf("tx-%d", len(f.txs))
+		f.txs[id] = &fakeFireblocksTx{account: req.Source.ID, content: content}
+		f.reply(w, http.StatusOK, map[string]string{"id": id, "status": "SUBMITTED"})
+
+	case r.Method == http.MethodGet && len(parts) == 2 && parts[0] == "transactions":
+		tx := f.txs[parts[1]]
+		if tx.polls++; tx.polls <= f.pending {
+			f.reply(w, http.StatusOK, map[string]string{"id": parts[1], "status": "PENDING_SIGNATURE"})
+			return
+		}
+		if f.status != "" {
+			f.reply(w, http.StatusOK, map[string]string{"id": parts[1], "status": f.status, "subStatus": "BLOCKED_BY_POLICY"})
+			return
+		}
+		sig, _ := crypto.Sign(tx.content, f.wallets[tx.account])
+		f.reply(w, http.StatusOK, map[string]interface{}{
+			"id":     parts[1],
+			"status": "COMPLETED",
+			"signedMessages": []map[string]interface{}{{
+				"content": hex.EncodeToString(tx.content),
+				"signature": map[string]interface{}{
+					"r": hex.EncodeToString(sig[:32]), "s": hex.EncodeToString(sig[32:64]), "v": sig[64],
+				},
+			}},
+		})
+
+	case r.Method == http.MethodPost && len(parts) == 3 && parts[0] == "transactions" && parts[2] == "cancel":
+		f.cancelled = append(f.cancelled, parts[1])
+		f.reply(w, http.StatusOK, map[string]bool{"success": true})
+
+	default:
+		f.reply(w, http.StatusNotFound, map[string]string{"message": "not found"})
+	}
+}
+
+func newTestFireblocksKeeper(t *testing.T) (*fireblocksKeeper, *fakeFireblocks) {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate api key: %v", err)
+	}
+	fake := &fakeFireblocks{
+		apiKey:  "api-key",
+		pub:     &key.PublicKey,
+		wallets: make(map[string]*ecdsa.PrivateKey),
+		txs:     make(map[string]*fakeFireblocksTx),
+		pending: 2,
+	}
+	srv := httptest.NewServer(fake)
+	t.Cleanup(srv.Close)
+
+	der, _ := x509.MarshalPKCS8PrivateKey(key)
+	k, err := NewFireblocksKeeper(fake.apiKey, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), srv.URL)
+	if err != nil {
+		t.Fatalf("failed to create keeper: %v", err)
+	}
+	fk := k.(*fireblocksKeeper)
+	fk.pollInterval = time.Millisecond
+	return fk, fake
+}
+
+func TestFireblocksKeeper(t *testing.T) {
+	k, fake := newTestFireblocksKeeper(t)
+
+	prvID, err := k.GeneratePrivateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	var id fireblocksKeyID
+	if err := json.Unmarshal(prvID, &id); err != nil || id.AssetID != "ETH" || fake.wallets[id.VaultAccountID] == nil {
+		t.Fatalf("unexpected key id %s", prvID)
+	}
+	pub, err := k.GetPublicKey(prvID)
+	if err != nil {
+		t.Fatalf("failed to get public key: %v", err)
+	}
+	if want := crypto.FromECDSAPub(&fake.wallets[id.VaultAccountID].PublicKey); !bytes.Equal(pub, want) {
+		t.Fatalf("public key mismatch: have %x, want %x", pub, want)
+	}
+	hash := crypto.Keccak256([]byte("fireblocks"))
+	sig, err := k.Sign(hash, prvID)
+	if err != nil {
+		t.Fatalf("failed to sign: %v", err)
+	}
+	recovered, err := crypto.Ecrecover(hash, sig)
+	if err != nil {
+		t.Fatalf("failed to recover: %v", err)
+	}
+	if !bytes.Equal(recovered, pub) {
+		t.Fatalf("recovered key mismatch: have %x, want %x", recovered, pub)
+	}
+	if polls := fake.txs["tx-0"].polls; polls != 3 {
+		t.Fatalf("signing request polls: have %d, want 3", polls)
+	}
+	ids, err := k.ListPrivateKeys()
+	if err != nil || len(ids) != 1 || !bytes.Equal(ids[0], prvID) {
+		t.Fatalf("listed keys: have (%q, %v), want [%q]", ids, err, prvID)
+	}
+	if err := k.HealthCheck(context.Background()); err != nil {
+		t.Fatalf("health check: have %v, want healthy", err)
+	}
+	if err := k.DeletePrivateKey(prvID); !errors.Is(err, ErrNotSupported) {
+		t.Fatalf("delete: have %v, want %v", err, ErrNotSupported)
+	}
+	if _, err := k.ImportPrivateKey(crypto.FromECDSA(fake.wallets[id.VaultAccountID])); !errors.Is(err, ErrNotSupported) {
+		t.Fatalf("import: have %v, want %v", err, ErrNotSupported)
+	}
+}
+
+func TestFireblocksKeeperSigningFailures(t *testing.T) {
+	k, fake := newTestFireblocksKeeper(t)
+	prvID, _ := k.GeneratePrivateKey()
+	hash := make([]byte, 32)
+
+	// Requests rejected by the policy are refused.
+	fake.status = "REJECTED"
+	if _, err := k.Sign(hash, prvID); !errors.Is(err, ErrPermissionDenied) {
+		t.Fatalf("rejected request: have %v, want %v", err, ErrPermissionDenied)
+	}
+	fake.status = "FAILED"
+	if _, err := k.Sign(hash, prvID); err == nil {
+		t.Fatal("failed request signed")
+	}
+	// Requests pending past the timeout are cancelled.
+	fake.status, fake.pending = "", 1<<30
+	k.pollTimeout = 20 * time.Millisecond
+	if _, err := k.Sign(hash, prvID); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("pending request: have %v, want %v", err, context.DeadlineExceeded)
+	}
+	fake.lock.Lock()
+	defer fake.lock.Unlock()
+	if len(fake.cancelled) != 1 || fake.cancelled[0] != "tx-2" {
+		t.Fatalf("cancelled requests: have %v, want [tx-2]", fake.cancelled)
+	}
+}
+
+func TestFireblocksKeeperErrors(t *testing.T) {
+	k, _ := newTestFireblocksKeeper(t)
+
+	if _, err := k.GetPublicKey([]byte(`{"vaultAccountId":"7","assetId":"ETH"}`)); !errors.Is(err, ErrKeyNotFound) {
+		t.Fatalf("missing wallet: have %v, want %v", err, ErrKeyNotFound)
+	}
+	if _, err := k.GetPublicKey([]byte("7")); !errors.Is(err, ErrKeyNotFound) {
+		t.Fatalf("malformed key id: have %v, want %v", err, ErrKeyNotFound)
+	}
+	prvID, _ := k.GeneratePrivateKey()
+	if _, err := k.Sign([]byte("not a hash"), prvID); err == nil {
+		t.Fatal("signed data that is not a digest")
+	}
+	// Requests of another API user fail authentication.
+	k.apiKey = "other"
+	if _, err := k.ListPrivateKeys(); !errors.Is(err, ErrPermissionDenied) {
+		t.Fatalf("unknown api key: have %v, want %v", err, ErrPermissionDenied)
+	}
+	if _, err := NewFireblocksKeeper("api-key", []byte("not a key"), ""); err == nil {
+		t.Fatal("accepted malformed api key")
+	}
+}
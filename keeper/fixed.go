@@ -0,0 +1,157 @@
+package keeper
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"golang.org/x/crypto/hkdf"
+)
+
+// deterministicKeySalt is the HKDF salt of GenerateDeterministicKey.
+const deterministicKeySalt = "go-ethereum-keeper-test"
+
+// GenerateDeterministicKey derives a private key from seed with HKDF-SHA256, so
+// tests get the same key on every run. The key is returned as the prvID of the
+// default keeper.
+//
+// WARNING: this is ONLY for testing. Anyone knowing the seed knows the key, never
+// use it for keys holding real funds.
+func GenerateDeterministicKey(seed []byte) ([]byte, error) {
+	key := make([]byte, 32)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, seed, []byte(deterministicKeySalt), nil), key); err != nil {
+		return nil, err
+	}
+	if _, err := crypto.ToECDSA(key); err != nil {
+		return nil, fmt.Errorf("invalid deterministic key: %v", err)
+	}
+	return key, nil
+}
+
+// fixedKeeper is a PrivateKeyKeeper serving a fixed set of raw private keys by
+// name, for test suites.
+type fixedKeeper struct {
+	inner defaultPrivateKeyKeeper
+
+	lock      sync.Mutex
+	keys      map[string][]byte
+	generated map[string]bool // names handed out by GeneratePrivateKey
+}
+
+// FixedKeeper returns a PrivateKeyKeeper holding the raw private keys of keys,
+// identified by their names. Instead of generating keys, GeneratePrivateKey hands
+// out the names in sorted order, and fails once all were handed out. Imported
+// keys are named by their address.
+//
+// WARNING: this is ONLY for testing, the keys are kept in plain memory.
+func FixedKeeper(keys map[string][]byte) PrivateKeyKeeper {
+	k := &fixedKeeper{keys: make(map[string][]byte, len(keys)), generated: make(map[string]bool)}
+	for name, key := range keys {
+		k.keys[name] = key
+	}
+	return k
+}
+
+// key returns the raw private key named prvID.
+func (k *fixedKeeper) key(prvID []byte) ([]byte, error) {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+
+	key, ok := k.keys[string(prvID)]
+	if !ok {
+		return nil, fmt.Errorf("%w: fixed key %q", ErrKeyNotFound, prvID)
+	}
+	return key, nil
+}
+
+func (k *fixedKeeper) GeneratePrivateKey() (_ []byte, err error) {
+	defer wrapError(&err, "generate key", nil)
+
+	k.lock.Lock()
+	defer k.lock.Unlock()
+
+	for _, name := range k.names() {
+		if !k.generated[name] {
+			k.generated[name] = true
+			return []byte(name), nil
+		}
+	}
+	return nil, fmt.Errorf("all %d fixed keys handed out", len(k.keys))
+}
+
+func (k *fixedKeeper) GetPublicKey(prvID []byte) (_ []byte, err error) {
+	defer wrapError(&err, "get public key", prvID)
+
+	key, err := k.key(prvID)
+	if err != nil {
+		return nil, err
+	}
+	return k.inner.GetPublicKey(key)
+}
+
+func (k *fixedKeeper) Sign(data []byte, prvID []byte) (_ []byte, err error) {
+	defer wrapError(&err, "sign", prvID)
+
+	key, err := k.key(prvID)
+	if err != nil {
+		return nil, err
+	}
+	return k.inner.Sign(data, key)
+}
+
+func (k *fixedKeeper) DeletePrivateKey(prvID []byte) (err error) {
+	defer wrapError(&err, "delete key", prvID)
+
+	k.lock.Lock()
+	defer k.lock.Unlock()
+
+	if _, ok := k.keys[string(prvID)]; !ok {
+		return fmt.Errorf("%w: fixed key %q", ErrKeyNotFound, prvID)
+	}
+	delete(k.keys, string(prvID))
+	return nil
+}
+
+func (k *fixedKeeper) ListPrivateKeys() ([][]byte, error) {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+
+	names := k.names()
+	prvIDs := make([][]byte, len(names))
+	for i, name := range names {
+		prvIDs[i] = []byte(name)
+	}
+	return prvIDs, nil
+}
+
+// ImportPrivateKey adds rawKey to the keys, named by the hex address of the
+// key.
+func (k *fixedKeeper) ImportPrivateKey(rawKey []byte) (_ []byte, err error) {
+	defer wrapError(&err, "import key", nil)
+
+	key, err := parseRawKey(rawKey)
+	if err != nil {
+		return nil, err
+	}
+	name := crypto.PubkeyToAddress(key.PublicKey).Hex()
+
+	k.lock.Lock()
+	defer k.lock.Unlock()
+
+	k.keys[name] = common.CopyBytes(rawKey)
+	return []byte(name), nil
+}
+
+// names returns the sorted names of the keys, the lock must be held.
+func (k *fixedKeeper) names() []string {
+	names := make([]string, 0, len(k.keys))
+	for name := range k.keys {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
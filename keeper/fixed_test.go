@@ -0,0 +1,80 @@
+package keeper
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestGenerateDeterministicKey(t *testing.T) {
+	key, err := GenerateDeterministicKey([]byte("seed"))
+	if err != nil {
+		t.Fatalf("failed to derive key: %v", err)
+	}
+	// HKDF-SHA256 with the salt "go-ethereum-keeper-test" and empty info.
+	want := hexutil.MustDecode("0x9c185332b23ecc52dfb2c4f9abe6bee4c1f255a58d291f80b81034a7410ca4e6")
+	if !bytes.Equal(key, want) {
+		t.Fatalf("key mismatch: have %x, want %x", key, want)
+	}
+	addr, err := AddressFromKeeper(defaultKeeper, key)
+	if err != nil {
+		t.Fatalf("failed to get address: %v", err)
+	}
+	if want := common.HexToAddress("0x35704689424187EeE4e1F3Aba2FD6456431d3541"); addr != want {
+		t.Fatalf("address mismatch: have %v, want %v", addr, want)
+	}
+	other, err := GenerateDeterministicKey([]byte("other seed"))
+	if err != nil {
+		t.Fatalf("failed to derive key: %v", err)
+	}
+	if bytes.Equal(key, other) {
+		t.Fatal("different seeds derived the same key")
+	}
+}
+
+func TestFixedKeeper(t *testing.T) {
+	alice, _ := GenerateDeterministicKey([]byte("alice"))
+	bob, _ := GenerateDeterministicKey([]byte("bob"))
+	k := FixedKeeper(map[string][]byte{"bob": bob, "alice": alice})
+
+	for _, want := range []string{"alice", "bob"} {
+		prvID, err := k.GeneratePrivateKey()
+		if err != nil || string(prvID) != want {
+			t.Fatalf("generated key: have (%q, %v), want %q", prvID, err, want)
+		}
+	}
+	if _, err := k.GeneratePrivateKey(); err == nil {
+		t.Fatal("generated key beyond the fixed ones")
+	}
+	pub, err := k.GetPublicKey([]byte("alice"))
+	if err != nil {
+		t.Fatalf("failed to get public key: %v", err)
+	}
+	if want, _ := defaultKeeper.GetPublicKey(alice); !bytes.Equal(pub, want) {
+		t.Fatalf("public key mismatch: have %x, want %x", pub, want)
+	}
+	hash := crypto.Keccak256([]byte("fixed"))
+	sig, err := k.Sign(hash, []byte("alice"))
+	if err != nil {
+		t.Fatalf("failed to sign: %v", err)
+	}
+	if recovered, err := crypto.Ecrecover(hash, sig); err != nil || !bytes.Equal(recovered, pub) {
+		t.Fatalf("recovered key mismatch: have (%x, %v), want %x", recovered, err, pub)
+	}
+	// The names are not keys themselves, not even through the context view.
+	if _, err := ContextKeeper(k).SignContext(context.Background(), hash, []byte("carol")); !errors.Is(err, ErrKeyNotFound) {
+		t.Fatalf("unknown key: have %v, want %v", err, ErrKeyNotFound)
+	}
+	if err := k.DeletePrivateKey([]byte("bob")); err != nil {
+		t.Fatalf("failed to delete key: %v", err)
+	}
+	ids, err := k.ListPrivateKeys()
+	if err != nil || len(ids) != 1 || string(ids[0]) != "alice" {
+		t.Fatalf("keys after deletion: have (%q, %v), want [alice]", ids, err)
+	}
+}
@@ -0,0 +1,63 @@
+package keeper
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+)
+
+// gasPriceCacheTTL is how long a suggested gas price is used, about a block.
+const gasPriceCacheTTL = 12 * time.Second
+
+// GasPriceOracle suggests the gas price of the network, as e.g. the
+// ethclient.Client does.
+type GasPriceOracle interface {
+	SuggestGasPrice(ctx context.Context) (*big.Int, error)
+}
+
+// GasPriceOraclePolicy returns a SigningPolicy refusing transactions whose gas
+// price, or fee cap for EIP-1559 ones, exceeds maxMultiplier times the price
+// suggested by oracle, the price itself if maxMultiplier isn't positive. This
+// keeps runaway bots from outbidding each other in congestion spikes. The
+// suggested price is fetched before signing and reused for 12 seconds; if it
+// can't be fetched, transactions are not signed.
+func GasPriceOraclePolicy(oracle GasPriceOracle, maxMultiplier float64) SigningPolicy {
+	return SigningPolicy{GasPriceOracle: oracle, MaxGasPriceMultiplier: maxMultiplier}
+}
+
+// cachedGasPrice is the price suggested by a GasPriceOracle, fetched at most
+// once per gasPriceCacheTTL.
+type cachedGasPrice struct {
+	oracle GasPriceOracle
+	now    func() time.Time // replaced in tests
+
+	lock    sync.Mutex
+	price   *big.Int // nil if not fetched yet
+	fetched time.Time
+}
+
+func newCachedGasPrice(oracle GasPriceOracle) *cachedGasPrice {
+	return &cachedGasPrice{oracle: oracle, now: time.Now}
+}
+
+// get returns the suggested gas price, fetching it from the oracle if the
+// cached one is outdated. Failures are not cached.
+func (c *cachedGasPrice) get(ctx context.Context) (*big.Int, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.price != nil && c.now().Sub(c.fetched) < gasPriceCacheTTL {
+		return c.price, nil
+	}
+	price, err := c.oracle.SuggestGasPrice(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get suggested gas price: %w", err)
+	}
+	if price == nil || price.Sign() < 0 {
+		return nil, fmt.Errorf("invalid suggested gas price %v", price)
+	}
+	c.price, c.fetched = new(big.Int).Set(price), c.now()
+	return c.price, nil
+}
@@ -0,0 +1,133 @@
+package keeper
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// mockGasPriceOracle is a GasPriceOracle suggesting price, or failing with err.
+type mockGasPriceOracle struct {
+	price atomic.Int64
+	err   error
+	calls atomic.Int32
+}
+
+func (o *mockGasPriceOracle) SuggestGasPrice(ctx context.Context) (*big.Int, error) {
+	o.calls.Add(1)
+	if o.err != nil {
+		return nil, o.err
+	}
+	return big.NewInt(o.price.Load()), nil
+}
+
+func TestGasPriceOraclePolicy(t *testing.T) {
+	oracle := new(mockGasPriceOracle)
+	oracle.price.Store(100)
+
+	s := NewPoliciedSecureSigner(NewSecureSigner(new(defaultPrivateKeyKeeper)), GasPriceOraclePolicy(oracle, 1.5))
+	prvID, err := s.GenerateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	chainID := big.NewInt(1)
+	legacy := func(gasPrice int64) *types.Transaction {
+		return types.NewTx(&types.LegacyTx{To: &common.Address{1}, GasPrice: big.NewInt(gasPrice), Value: big.NewInt(1), Gas: 21000})
+	}
+	if _, err := s.Sign(legacy(150), types.LatestSignerForChainID(chainID), prvID); err != nil {
+		t.Fatalf("failed to sign transaction at the limit: %v", err)
+	}
+	var violation *PolicyViolation
+	if _, err := s.Sign(legacy(151), types.LatestSignerForChainID(chainID), prvID); !errors.As(err, &violation) {
+		t.Fatalf("gas price beyond limit: have %v, want %T", err, violation)
+	}
+	if violation.Field != "gasPrice" || violation.Actual.Int64() != 151 || violation.Allowed.Int64() != 150 {
+		t.Errorf("have %s %v > %v, want gasPrice 151 > 150", violation.Field, violation.Actual, violation.Allowed)
+	}
+	// The fee cap of EIP-1559 transactions is checked, not the tip.
+	if _, err := s.SignDynamicFeeTx(chainID, 0, common.Address{1}, big.NewInt(1), 21000, big.NewInt(200), big.NewInt(1), nil, prvID); !errors.As(err, &violation) || violation.Field != "maxFeePerGas" {
+		t.Fatalf("fee cap beyond limit: have %v, want maxFeePerGas violation", err)
+	}
+	// The suggested price is fetched once for all of them.
+	if calls := oracle.calls.Load(); calls != 1 {
+		t.Fatalf("oracle called %d times, want 1", calls)
+	}
+}
+
+func TestGasPriceOraclePolicyDefaultMultiplier(t *testing.T) {
+	oracle := new(mockGasPriceOracle)
+	oracle.price.Store(100)
+
+	s := NewPoliciedSecureSigner(NewSecureSigner(new(defaultPrivateKeyKeeper)), GasPriceOraclePolicy(oracle, 0))
+	prvID, err := s.GenerateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	signer := types.LatestSignerForChainID(big.NewInt(1))
+	if _, err := s.Sign(types.NewTx(&types.LegacyTx{To: &common.Address{1}, GasPrice: big.NewInt(100), Gas: 21000}), signer, prvID); err != nil {
+		t.Fatalf("failed to sign transaction at the suggested price: %v", err)
+	}
+	var violation *PolicyViolation
+	if _, err := s.Sign(types.NewTx(&types.LegacyTx{To: &common.Address{1}, GasPrice: big.NewInt(101), Gas: 21000}), signer, prvID); !errors.As(err, &violation) {
+		t.Fatalf("gas price beyond suggested price: have %v, want %T", err, violation)
+	}
+}
+
+func TestGasPriceOraclePolicyFailure(t *testing.T) {
+	oracle := &mockGasPriceOracle{err: errors.New("node unreachable")}
+	s := NewPoliciedSecureSigner(NewSecureSigner(new(defaultPrivateKeyKeeper)), GasPriceOraclePolicy(oracle, 2))
+	prvID, err := s.GenerateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	if _, err := s.Sign(newTestTx(), types.LatestSignerForChainID(big.NewInt(1)), prvID); !errors.Is(err, oracle.err) {
+		t.Fatalf("sign without suggested price: have %v, want %v", err, oracle.err)
+	}
+}
+
+func TestCachedGasPrice(t *testing.T) {
+	var (
+		oracle = new(mockGasPriceOracle)
+		now    = time.Unix(1700000000, 0)
+		cache  = newCachedGasPrice(oracle)
+	)
+	cache.now = func() time.Time { return now }
+
+	for _, tt := range []struct {
+		elapsed time.Duration
+		price   int64 // price suggested by the oracle
+		want    int64 // price returned by the cache
+		calls   int32
+	}{
+		{0, 10, 10, 1},
+		{11 * time.Second, 20, 10, 1},
+		{time.Second, 30, 30, 2},
+		{5 * time.Second, 40, 30, 2},
+	} {
+		now = now.Add(tt.elapsed)
+		oracle.price.Store(tt.price)
+		price, err := cache.get(context.Background())
+		if err != nil {
+			t.Fatalf("failed to get price: %v", err)
+		}
+		if price.Int64() != tt.want || oracle.calls.Load() != tt.calls {
+			t.Fatalf("after %v: have price %v after %d calls, want %d after %d", tt.elapsed, price, oracle.calls.Load(), tt.want, tt.calls)
+		}
+	}
+	// Failures are not cached.
+	now = now.Add(gasPriceCacheTTL)
+	oracle.err = errors.New("node unreachable")
+	if _, err := cache.get(context.Background()); !errors.Is(err, oracle.err) {
+		t.Fatalf("have %v, want %v", err, oracle.err)
+	}
+	oracle.err = nil
+	if price, err := cache.get(context.Background()); err != nil || price.Int64() != 40 {
+		t.Fatalf("have (%v, %v), want 40", price, err)
+	}
+}
@@ -0,0 +1,422 @@
+package keeper
+
+import (
+	"context"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"sync"
+	"time"
+
+	kms "cloud.google.com/go/kms/apiv1"
+	"cloud.google.com/go/kms/apiv1/kmspb"
+	"github.com/google/uuid"
+	"github.com/googleapis/gax-go/v2"
+	"google.golang.org/api/iterator"
+	"google.golang.org/api/option"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// gcpKMSAPI is the subset of the Cloud KMS client used by the keeper.
+type gcpKMSAPI interface {
+	CreateCryptoKey(ctx context.Context, req *kmspb.CreateCryptoKeyRequest, opts ...gax.CallOption) (*kmspb.CryptoKey, error)
+	GetPublicKey(ctx context.Context, req *kmspb.GetPublicKeyRequest, opts ...gax.CallOption) (*kmspb.PublicKey, error)
+	AsymmetricSign(ctx context.Context, req *kmspb.AsymmetricSignRequest, opts ...gax.CallOption) (*kmspb.AsymmetricSignResponse, error)
+	DestroyCryptoKeyVersion(ctx context.Context, req *kmspb.DestroyCryptoKeyVersionRequest, opts ...gax.CallOption) (*kmspb.CryptoKeyVersion, error)
+	CreateImportJob(ctx context.Context, req *kmspb.CreateImportJobRequest, opts ...gax.CallOption) (*kmspb.ImportJob, error)
+	GetImportJob(ctx context.Context, req *kmspb.GetImportJobRequest, opts ...gax.CallOption) (*kmspb.ImportJob, error)
+	ImportCryptoKeyVersion(ctx context.Context, req *kmspb.ImportCryptoKeyVersionRequest, opts ...gax.CallOption) (*kmspb.CryptoKeyVersion, error)
+	GetCryptoKeyVersion(ctx context.Context, req *kmspb.GetCryptoKeyVersionRequest, opts ...gax.CallOption) (*kmspb.CryptoKeyVersion, error)
+	GetKeyRing(ctx context.Context, req *kmspb.GetKeyRingRequest, opts ...gax.CallOption) (*kmspb.KeyRing, error)
+	// listCryptoKeys returns all crypto keys matching req, across all pages.
+	listCryptoKeys(ctx context.Context, req *kmspb.ListCryptoKeysRequest) ([]*kmspb.CryptoKey, error)
+}
+
+// gcpKMSClient adapts the Cloud KMS client to gcpKMSAPI. The client returns
+// listings as iterators, which can't be constructed outside of the client
+// package, so they are drained here.
+type gcpKMSClient struct {
+	*kms.KeyManagementClient
+}
+
+func (c gcpKMSClient) listCryptoKeys(ctx context.Context, req *kmspb.ListCryptoKeysRequest) ([]*kmspb.CryptoKey, error) {
+	var (
+		keys []*kmspb.CryptoKey
+		it   = c.ListCryptoKeys(ctx, req)
+	)
+	for {
+		key, err := it.Next()
+		if err == iterator.Done {
+			return keys, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+}
+
+// gcpKMSKeeper is a PrivateKeyKeeper storing keys in Google Cloud KMS. The
+// prvID is the full resource name of the crypto key version.
+type gcpKMSKeeper struct {
+	client       gcpKMSAPI
+	keyRing      string        // resource name of the key ring new keys are created in
+	pollInterval time.Duration // interval between state checks of import jobs and imports
+
+	pubkeys sync.Map // crypto key version name -> uncompressed public key
+}
+
+// gcpPollInterval is the default interval between state checks while waiting
+// for Cloud KMS to finish an import.
+const gcpPollInterval = time.Second
+
+// crc32c computes the checksum Cloud KMS uses to verify request and response
+// integrity.
+var crc32c = crc32.MakeTable(crc32.Castagnoli)
+
+// gcpError marks Cloud KMS errors with the matching error of the keeper
+// package, keeping the gRPC status accessible.
+func gcpError(err error) error {
+	switch status.Code(err) {
+	case codes.NotFound:
+		return fmt.Errorf("%w: %w", ErrKeyNotFound, err)
+	case codes.PermissionDenied, codes.Unauthenticated:
+		return fmt.Errorf("%w: %w", ErrPermissionDenied, err)
+	case codes.ResourceExhausted:
+		return fmt.Errorf("%w: %w", ErrRateLimitExceeded, err)
+	case codes.Unavailable, codes.Internal:
+		return fmt.Errorf("%w: %w", ErrBackendUnavailable, err)
+	}
+	return err
+}
+
+// NewGCPKMSKeeper returns a PrivateKeyKeeper that creates and uses keys inside
+// the given Cloud KMS key ring. The client options are passed to the Cloud KMS
+// client, e.g. to set credentials.
+func NewGCPKMSKeeper(ctx context.Context, projectID, locationID, keyRingID string, opts ...option.ClientOption) (PrivateKeyKeeper, error) {
+	client, err := kms.NewKeyManagementClient(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return newGCPKMSKeeper(gcpKMSClient{client}, projectID, locationID, keyRingID), nil
+}
+
+func newGCPKMSKeeper(client gcpKMSAPI, projectID, locationID, keyRingID string) *gcpKMSKeeper {
+	return &gcpKMSKeeper{
+		client:       client,
+		keyRing:      fmt.Sprintf("projects/%s/locations/%s/keyRings/%s", projectID, locationID, keyRingID),
+		pollInterval: gcpPollInterval,
+	}
+}
+
+func (k *gcpKMSKeeper) GeneratePrivateKey() ([]byte, error) {
+	return k.GeneratePrivateKeyContext(context.Background())
+}
+
+func (k *gcpKMSKeeper) GeneratePrivateKeyContext(ctx context.Context) (_ []byte, err error) {
+	defer wrapError(&err, "generate key", nil)
+
+	key, err := k.client.CreateCryptoKey(ctx, &kmspb.CreateCryptoKeyRequest{
+		Parent:      k.keyRing,
+		CryptoKeyId: "eth-" + uuid.New().String(),
+		CryptoKey: &kmspb.CryptoKey{
+			Purpose: kmspb.CryptoKey_ASYMMETRIC_SIGN,
+			VersionTemplate: &kmspb.CryptoKeyVersionTemplate{
+				Algorithm: kmspb.CryptoKeyVersion_EC_SIGN_SECP256K1_SHA256,
+			},
+		},
+	})
+	if err != nil {
+		return nil, gcpError(err)
+	}
+	// Creating a key also creates its first version, which is the one used
+	// for signing.
+	return []byte(key.Name + "/cryptoKeyVersions/1"), nil
+}
+
+// ExportEncryptedKey returns ErrExportNotSupported, Cloud KMS keys can't be
+// exported.
+func (k *gcpKMSKeeper) ExportEncryptedKey(prvID []byte, passphrase string) (_ []byte, err error) {
+	defer wrapError(&err, "export key", prvID)
+	return nil, ErrExportNotSupported
+}
+
+func (k *gcpKMSKeeper) ImportEncryptedKey(data []byte, passphrase string) ([]byte, error) {
+	return importEncryptedKey(k, data, passphrase)
+}
+
+func (k *gcpKMSKeeper) ImportPrivateKey(rawKey []byte) ([]byte, error) {
+	return k.ImportPrivateKeyContext(context.Background(), rawKey)
+}
+
+// ImportPrivateKeyContext imports rawKey as the first version of a new
+// import-only crypto key. The key is encrypted for a fresh import job, which
+// expires on its own after three days. Cloud KMS imports asynchronously, the
+// call waits until the version is enabled.
+func (k *gcpKMSKeeper) ImportPrivateKeyContext(ctx context.Context, rawKey []byte) (_ []byte, err error) {
+	defer wrapError(&err, "import key", nil)
+
+	key, err := parseRawKey(rawKey)
+	if err != nil {
+		return nil, err
+	}
+	defer ZeroKey(key)
+
+	job, err := k.client.CreateImportJob(ctx, &kmspb.CreateImportJobRequest{
+		Parent:      k.keyRing,
+		ImportJobId: "eth-import-" + uuid.New().String(),
+		ImportJob: &kmspb.ImportJob{
+			ImportMethod:    kmspb.ImportJob_RSA_OAEP_3072_SHA256_AES_256,
+			ProtectionLevel: kmspb.ProtectionLevel_SOFTWARE,
+		},
+	})
+	if err != nil {
+		return nil, gcpError(err)
+	}
+	err = k.poll(ctx, func() (bool, error) {
+		switch job.State {
+		case kmspb.ImportJob_ACTIVE:
+			return true, nil
+		case kmspb.ImportJob_PENDING_GENERATION:
+			if job, err = k.client.GetImportJob(ctx, &kmspb.GetImportJobRequest{Name: job.Name}); err != nil {
+				return false, gcpError(err)
+			}
+			return false, nil
+		}
+		return false, fmt.Errorf("cloud kms import job in state %v", job.State)
+	})
+	if err != nil {
+		return nil, err
+	}
+	wrappingKey, err := parseRSAPublicKeyPEM(job.GetPublicKey().GetPem())
+	if err != nil {
+		return nil, err
+	}
+	material, err := marshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	defer zeroBytes(material)
+
+	wrapped, err := wrapKeyMaterial(wrappingKey, material)
+	if err != nil {
+		return nil, err
+	}
+	cryptoKey, err := k.client.CreateCryptoKey(ctx, &kmspb.CreateCryptoKeyRequest{
+		Parent:      k.keyRing,
+		CryptoKeyId: "eth-" + uuid.New().String(),
+		CryptoKey: &kmspb.CryptoKey{
+			Purpose: kmspb.CryptoKey_ASYMMETRIC_SIGN,
+			VersionTemplate: &kmspb.CryptoKeyVersionTemplate{
+				Algorithm: kmspb.CryptoKeyVersion_EC_SIGN_SECP256K1_SHA256,
+			},
+			ImportOnly: true,
+		},
+		SkipInitialVersionCreation: true,
+	})
+	if err != nil {
+		return nil, gcpError(err)
+	}
+	version, err := k.client.ImportCryptoKeyVersion(ctx, &kmspb.ImportCryptoKeyVersionRequest{
+		Parent:     cryptoKey.Name,
+		Algorithm:  kmspb.CryptoKeyVersion_EC_SIGN_SECP256K1_SHA256,
+		ImportJob:  job.Name,
+		WrappedKey: wrapped,
+	})
+	if err != nil {
+		return nil, gcpError(err)
+	}
+	err = k.poll(ctx, func() (bool, error) {
+		switch version.State {
+		case kmspb.CryptoKeyVersion_ENABLED:
+			return true, nil
+		case kmspb.CryptoKeyVersion_PENDING_IMPORT:
+			if version, err = k.client.GetCryptoKeyVersion(ctx, &kmspb.GetCryptoKeyVersionRequest{Name: version.Name}); err != nil {
+				return false, gcpError(err)
+			}
+			return false, nil
+		case kmspb.CryptoKeyVersion_IMPORT_FAILED:
+			return false, fmt.Errorf("cloud kms key import failed: %s", version.ImportFailureReason)
+		}
+		return false, fmt.Errorf("cloud kms key version in state %v", version.State)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return []byte(version.Name), nil
+}
+
+// poll calls done until it reports completion or fails, waiting the poll
+// interval of the keeper between calls. done refreshes the polled state when
+// reporting it incomplete.
+func (k *gcpKMSKeeper) poll(ctx context.Context, done func() (bool, error)) error {
+	for {
+		ok, err := done()
+		if ok || err != nil {
+			return err
+		}
+		timer := time.NewTimer(k.pollInterval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// parseRSAPublicKeyPEM parses the PEM encoded wrapping key of an import job.
+func parseRSAPublicKeyPEM(data string) (*rsa.PublicKey, error) {
+	block, _ := pem.Decode([]byte(data))
+	if block == nil {
+		return nil, errors.New("cloud kms wrapping key is not PEM encoded")
+	}
+	pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	rsaPub, ok := pub.(*rsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("invalid wrapping key type %T", pub)
+	}
+	return rsaPub, nil
+}
+
+func (k *gcpKMSKeeper) GetPublicKey(prvID []byte) ([]byte, error) {
+	return k.GetPublicKeyContext(context.Background(), prvID)
+}
+
+func (k *gcpKMSKeeper) GetPublicKeyContext(ctx context.Context, prvID []byte) (_ []byte, err error) {
+	defer wrapError(&err, "get public key", prvID)
+
+	name := string(prvID)
+	if pub, ok := k.pubkeys.Load(name); ok {
+		return pub.([]byte), nil
+	}
+	resp, err := k.client.GetPublicKey(ctx, &kmspb.GetPublicKeyRequest{Name: name})
+	if err != nil {
+		return nil, gcpError(err)
+	}
+	if resp.PemCrc32C != nil && int64(crc32.Checksum([]byte(resp.Pem), crc32c)) != resp.PemCrc32C.Value {
+		return nil, fmt.Errorf("%w: cloud kms public key corrupted in transit", ErrBackendUnavailable)
+	}
+	block, _ := pem.Decode([]byte(resp.Pem))
+	if block == nil {
+		return nil, errors.New("cloud kms public key is not PEM encoded")
+	}
+	pub, err := parseSubjectPublicKeyInfo(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	k.pubkeys.Store(name, pub)
+	return pub, nil
+}
+
+func (k *gcpKMSKeeper) Sign(data []byte, prvID []byte) ([]byte, error) {
+	return k.SignContext(context.Background(), data, prvID)
+}
+
+// SignContext signs the 32 byte digest data. Cloud KMS returns DER encoded
+// signatures that are not necessarily in low-S form and carry no recovery id,
+// both of which are fixed up before returning.
+func (k *gcpKMSKeeper) SignContext(ctx context.Context, data []byte, prvID []byte) (_ []byte, err error) {
+	defer wrapError(&err, "sign", prvID)
+
+	if len(data) != 32 {
+		return nil, fmt.Errorf("hash is required to be exactly 32 bytes (%d)", len(data))
+	}
+	pub, err := k.GetPublicKeyContext(ctx, prvID)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := k.client.AsymmetricSign(ctx, &kmspb.AsymmetricSignRequest{
+		Name:         string(prvID),
+		Digest:       &kmspb.Digest{Digest: &kmspb.Digest_Sha256{Sha256: data}},
+		DigestCrc32C: wrapperspb.Int64(int64(crc32.Checksum(data, crc32c))),
+	})
+	if err != nil {
+		return nil, gcpError(err)
+	}
+	if !resp.VerifiedDigestCrc32C {
+		return nil, fmt.Errorf("%w: cloud kms digest corrupted in transit", ErrBackendUnavailable)
+	}
+	if resp.SignatureCrc32C != nil && int64(crc32.Checksum(resp.Signature, crc32c)) != resp.SignatureCrc32C.Value {
+		return nil, fmt.Errorf("%w: cloud kms signature corrupted in transit", ErrBackendUnavailable)
+	}
+	r, s, err := parseDERSignature(resp.Signature)
+	if err != nil {
+		return nil, err
+	}
+	return recoverableSignature(data, r, s, pub)
+}
+
+func (k *gcpKMSKeeper) DeletePrivateKey(prvID []byte) error {
+	return k.DeletePrivateKeyContext(context.Background(), prvID)
+}
+
+// DeletePrivateKeyContext schedules the crypto key version for destruction.
+// Cloud KMS disables it right away and destroys the key material after the
+// destroy scheduled duration of the key, 30 days unless configured otherwise.
+func (k *gcpKMSKeeper) DeletePrivateKeyContext(ctx context.Context, prvID []byte) (err error) {
+	defer wrapError(&err, "delete key", prvID)
+
+	name := string(prvID)
+	if _, err := k.client.DestroyCryptoKeyVersion(ctx, &kmspb.DestroyCryptoKeyVersionRequest{Name: name}); err != nil {
+		return gcpError(err)
+	}
+	k.pubkeys.Delete(name)
+	return nil
+}
+
+func (k *gcpKMSKeeper) ListPrivateKeys() ([][]byte, error) {
+	return k.ListPrivateKeysContext(context.Background())
+}
+
+// ListPrivateKeysContext returns the signing key versions of the secp256k1
+// keys in the key ring. Cloud KMS never deletes crypto keys, so keys destroyed
+// by DeletePrivateKey are still listed.
+func (k *gcpKMSKeeper) ListPrivateKeysContext(ctx context.Context) (_ [][]byte, err error) {
+	defer wrapError(&err, "list keys", nil)
+
+	keys, err := k.client.listCryptoKeys(ctx, &kmspb.ListCryptoKeysRequest{Parent: k.keyRing})
+	if err != nil {
+		return nil, gcpError(err)
+	}
+	var prvIDs [][]byte
+	for _, key := range keys {
+		if key.Purpose != kmspb.CryptoKey_ASYMMETRIC_SIGN ||
+			key.GetVersionTemplate().GetAlgorithm() != kmspb.CryptoKeyVersion_EC_SIGN_SECP256K1_SHA256 {
+			continue
+		}
+		prvIDs = append(prvIDs, []byte(key.Name+"/cryptoKeyVersions/1"))
+	}
+	return prvIDs, nil
+}
+
+// Snapshot lists the IDs of the keys, the key material never leaves Cloud KMS.
+func (k *gcpKMSKeeper) Snapshot() (_ []byte, err error) {
+	defer wrapError(&err, "snapshot", nil)
+	return snapshotKeyIDs(context.Background(), k)
+}
+
+// Restore checks that the keys of the snapshot still exist in Cloud KMS.
+func (k *gcpKMSKeeper) Restore(snapshot []byte) (err error) {
+	defer wrapError(&err, "restore snapshot", nil)
+	return restoreKeyIDs(context.Background(), k, snapshot)
+}
+
+// HealthCheck reads the key ring of the keeper, failing with ErrKeyNotFound if
+// it was deleted.
+func (k *gcpKMSKeeper) HealthCheck(ctx context.Context) (err error) {
+	defer wrapError(&err, "health check", nil)
+
+	if _, err := k.client.GetKeyRing(ctx, &kmspb.GetKeyRingRequest{Name: k.keyRing}); err != nil {
+		return gcpError(err)
+	}
+	return nil
+}
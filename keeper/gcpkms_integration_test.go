@@ -0,0 +1,47 @@
+//go:build integration
+
+package keeper
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// TestGCPKMSKeeperIntegration runs against a real Cloud KMS key ring, selected
+// by the GCP_PROJECT, GCP_LOCATION and GCP_KEYRING environment variables and
+// using the application default credentials.
+func TestGCPKMSKeeperIntegration(t *testing.T) {
+	project, location, ring := os.Getenv("GCP_PROJECT"), os.Getenv("GCP_LOCATION"), os.Getenv("GCP_KEYRING")
+	if project == "" || location == "" || ring == "" {
+		t.Skip("GCP_PROJECT, GCP_LOCATION and GCP_KEYRING not set")
+	}
+	ctx := context.Background()
+	k, err := NewGCPKMSKeeper(ctx, project, location, ring)
+	if err != nil {
+		t.Fatalf("failed to create keeper: %v", err)
+	}
+	prvID, err := k.GeneratePrivateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	pub, err := k.GetPublicKey(prvID)
+	if err != nil {
+		t.Fatalf("failed to get public key: %v", err)
+	}
+	hash := crypto.Keccak256([]byte("gcp integration"))
+	sig, err := k.Sign(hash, prvID)
+	if err != nil {
+		t.Fatalf("failed to sign: %v", err)
+	}
+	recovered, err := crypto.Ecrecover(hash, sig)
+	if err != nil {
+		t.Fatalf("failed to recover: %v", err)
+	}
+	if !bytes.Equal(recovered, pub) {
+		t.Fatalf("recovered key mismatch: have %x, want %x", recovered, pub)
+	}
+}
@@ -0,0 +1,283 @@
+package keeper
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"hash/crc32"
+	"strings"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/kms/apiv1/kmspb"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/googleapis/gax-go/v2"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// fakeCloudKMS is an in-memory gcpKMSAPI implementation. Import jobs and
+// imported versions report their pending state once before completing.
+type fakeCloudKMS struct {
+	keys       map[string]*ecdsa.PrivateKey // crypto key version name -> key
+	cryptoKeys []*kmspb.CryptoKey           // created crypto keys, in order
+	importJobs map[string]*kmspb.ImportJob  // import job name -> job
+	corrupt    bool                         // whether to corrupt returned signatures
+}
+
+func (f *fakeCloudKMS) CreateCryptoKey(ctx context.Context, req *kmspb.CreateCryptoKeyRequest, opts ...gax.CallOption) (*kmspb.CryptoKey, error) {
+	if req.CryptoKey.Purpose != kmspb.CryptoKey_ASYMMETRIC_SIGN ||
+		req.CryptoKey.VersionTemplate.Algorithm != kmspb.CryptoKeyVersion_EC_SIGN_SECP256K1_SHA256 {
+		return nil, status.Error(codes.InvalidArgument, "unexpected key parameters")
+	}
+	name := req.Parent + "/cryptoKeys/" + req.CryptoKeyId
+	if req.SkipInitialVersionCreation != req.CryptoKey.ImportOnly {
+		return nil, status.Error(codes.InvalidArgument, "unexpected initial version creation")
+	}
+	if !req.SkipInitialVersionCreation {
+		key, _ := crypto.GenerateKey()
+		f.keys[name+"/cryptoKeyVersions/1"] = key
+	}
+
+	cryptoKey := proto.Clone(req.CryptoKey).(*kmspb.CryptoKey)
+	cryptoKey.Name = name
+	f.cryptoKeys = append(f.cryptoKeys, cryptoKey)
+	return cryptoKey, nil
+}
+
+func (f *fakeCloudKMS) listCryptoKeys(ctx context.Context, req *kmspb.ListCryptoKeysRequest) ([]*kmspb.CryptoKey, error) {
+	var keys []*kmspb.CryptoKey
+	for _, key := range f.cryptoKeys {
+		if strings.HasPrefix(key.Name, req.Parent+"/cryptoKeys/") {
+			keys = append(keys, key)
+		}
+	}
+	return keys, nil
+}
+
+func (f *fakeCloudKMS) GetPublicKey(ctx context.Context, req *kmspb.GetPublicKeyRequest, opts ...gax.CallOption) (*kmspb.PublicKey, error) {
+	key, ok := f.keys[req.Name]
+	if !ok {
+		return nil, status.Error(codes.NotFound, "key not found")
+	}
+	pubPEM := string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: marshalTestSPKI(&key.PublicKey)}))
+	return &kmspb.PublicKey{
+		Pem:       pubPEM,
+		PemCrc32C: wrapperspb.Int64(int64(crc32.Checksum([]byte(pubPEM), crc32c))),
+		Algorithm: kmspb.CryptoKeyVersion_EC_SIGN_SECP256K1_SHA256,
+		Name:      req.Name,
+	}, nil
+}
+
+func (f *fakeCloudKMS) AsymmetricSign(ctx context.Context, req *kmspb.AsymmetricSignRequest, opts ...gax.CallOption) (*kmspb.AsymmetricSignResponse, error) {
+	key, ok := f.keys[req.Name]
+	if !ok {
+		return nil, status.Error(codes.NotFound, "key not found")
+	}
+	digest := req.Digest.GetSha256()
+	if int64(crc32.Checksum(digest, crc32c)) != req.DigestCrc32C.GetValue() {
+		return nil, status.Error(codes.InvalidArgument, "digest checksum mismatch")
+	}
+	sig := derSign(digest, key, true)
+	crc := int64(crc32.Checksum(sig, crc32c))
+	if f.corrupt {
+		crc++
+	}
+	return &kmspb.AsymmetricSignResponse{
+		Signature:            sig,
+		SignatureCrc32C:      wrapperspb.Int64(crc),
+		VerifiedDigestCrc32C: true,
+		Name:                 req.Name,
+	}, nil
+}
+
+func (f *fakeCloudKMS) DestroyCryptoKeyVersion(ctx context.Context, req *kmspb.DestroyCryptoKeyVersionRequest, opts ...gax.CallOption) (*kmspb.CryptoKeyVersion, error) {
+	if _, ok := f.keys[req.Name]; !ok {
+		return nil, status.Error(codes.NotFound, "key not found")
+	}
+	delete(f.keys, req.Name)
+	return &kmspb.CryptoKeyVersion{Name: req.Name, State: kmspb.CryptoKeyVersion_DESTROY_SCHEDULED}, nil
+}
+
+func (f *fakeCloudKMS) CreateImportJob(ctx context.Context, req *kmspb.CreateImportJobRequest, opts ...gax.CallOption) (*kmspb.ImportJob, error) {
+	if req.ImportJob.ImportMethod != kmspb.ImportJob_RSA_OAEP_3072_SHA256_AES_256 {
+		return nil, status.Error(codes.InvalidArgument, "unexpected import method")
+	}
+	job := proto.Clone(req.ImportJob).(*kmspb.ImportJob)
+	job.Name = req.Parent + "/importJobs/" + req.ImportJobId
+	job.State = kmspb.ImportJob_PENDING_GENERATION
+	if f.importJobs == nil {
+		f.importJobs = make(map[string]*kmspb.ImportJob)
+	}
+	f.importJobs[job.Name] = job
+	return proto.Clone(job).(*kmspb.ImportJob), nil
+}
+
+func (f *fakeCloudKMS) GetImportJob(ctx context.Context, req *kmspb.GetImportJobRequest, opts ...gax.CallOption) (*kmspb.ImportJob, error) {
+	job, ok := f.importJobs[req.Name]
+	if !ok {
+		return nil, status.Error(codes.NotFound, "import job not found")
+	}
+	if job.State == kmspb.ImportJob_PENDING_GENERATION {
+		pub, err := x509.MarshalPKIXPublicKey(&testWrappingKey().PublicKey)
+		if err != nil {
+			return nil, err
+		}
+		job.State = kmspb.ImportJob_ACTIVE
+		job.PublicKey = &kmspb.ImportJob_WrappingPublicKey{
+			Pem: string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pub})),
+		}
+	}
+	return proto.Clone(job).(*kmspb.ImportJob), nil
+}
+
+func (f *fakeCloudKMS) ImportCryptoKeyVersion(ctx context.Context, req *kmspb.ImportCryptoKeyVersionRequest, opts ...gax.CallOption) (*kmspb.CryptoKeyVersion, error) {
+	job, ok := f.importJobs[req.ImportJob]
+	if !ok || job.State != kmspb.ImportJob_ACTIVE {
+		return nil, status.Error(codes.FailedPrecondition, "import job not active")
+	}
+	if req.Algorithm != kmspb.CryptoKeyVersion_EC_SIGN_SECP256K1_SHA256 {
+		return nil, status.Error(codes.InvalidArgument, "unexpected algorithm")
+	}
+	name := req.Parent + "/cryptoKeyVersions/1"
+	version := &kmspb.CryptoKeyVersion{Name: name, State: kmspb.CryptoKeyVersion_PENDING_IMPORT, ImportJob: req.ImportJob}
+	key, err := unwrapTestKeyMaterial(req.GetWrappedKey())
+	if err != nil {
+		// Cloud KMS accepts the request and fails the import later on.
+		version.ImportFailureReason = err.Error()
+	} else {
+		f.keys[name] = key
+	}
+	return version, nil
+}
+
+func (f *fakeCloudKMS) GetCryptoKeyVersion(ctx context.Context, req *kmspb.GetCryptoKeyVersionRequest, opts ...gax.CallOption) (*kmspb.CryptoKeyVersion, error) {
+	if _, ok := f.keys[req.Name]; !ok {
+		return &kmspb.CryptoKeyVersion{Name: req.Name, State: kmspb.CryptoKeyVersion_IMPORT_FAILED, ImportFailureReason: "invalid key material"}, nil
+	}
+	return &kmspb.CryptoKeyVersion{Name: req.Name, State: kmspb.CryptoKeyVersion_ENABLED}, nil
+}
+
+func (f *fakeCloudKMS) GetKeyRing(ctx context.Context, req *kmspb.GetKeyRingRequest, opts ...gax.CallOption) (*kmspb.KeyRing, error) {
+	if req.Name != "projects/project/locations/global/keyRings/ring" {
+		return nil, status.Error(codes.NotFound, "key ring not found")
+	}
+	return &kmspb.KeyRing{Name: req.Name}, nil
+}
+
+func TestGCPKMSKeeperHealthCheck(t *testing.T) {
+	fake := &fakeCloudKMS{keys: make(map[string]*ecdsa.PrivateKey)}
+	if err := newGCPKMSKeeper(fake, "project", "global", "ring").HealthCheck(context.Background()); err != nil {
+		t.Fatalf("existing key ring: have %v, want healthy", err)
+	}
+	if err := newGCPKMSKeeper(fake, "project", "global", "other").HealthCheck(context.Background()); !errors.Is(err, ErrKeyNotFound) {
+		t.Fatalf("missing key ring: have %v, want %v", err, ErrKeyNotFound)
+	}
+}
+
+func TestGCPKMSKeeperImport(t *testing.T) {
+	fake := &fakeCloudKMS{keys: make(map[string]*ecdsa.PrivateKey)}
+	k := newGCPKMSKeeper(fake, "project", "global", "ring")
+	k.pollInterval = time.Millisecond
+
+	prvID := checkImport(t, k)
+	if ids, err := k.ListPrivateKeys(); err != nil || len(ids) != 1 || !bytes.Equal(ids[0], prvID) {
+		t.Fatalf("listed keys: have (%q, %v), want [%q]", ids, err, prvID)
+	}
+	// Canceling the context aborts waiting for the import.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	key, _ := crypto.GenerateKey()
+	if _, err := k.ImportPrivateKeyContext(ctx, crypto.FromECDSA(key)); !errors.Is(err, context.Canceled) {
+		t.Fatalf("canceled import: have %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestGCPKMSKeeperList(t *testing.T) {
+	fake := &fakeCloudKMS{keys: make(map[string]*ecdsa.PrivateKey)}
+	k := newGCPKMSKeeper(fake, "project", "global", "ring")
+
+	var want []string
+	for i := 0; i < 3; i++ {
+		prvID, err := k.GeneratePrivateKey()
+		if err != nil {
+			t.Fatalf("failed to generate key: %v", err)
+		}
+		want = append(want, string(prvID))
+	}
+	// Keys of other purposes in the key ring must be skipped.
+	fake.cryptoKeys = append(fake.cryptoKeys, &kmspb.CryptoKey{
+		Name:    "projects/project/locations/global/keyRings/ring/cryptoKeys/symmetric",
+		Purpose: kmspb.CryptoKey_ENCRYPT_DECRYPT,
+	})
+	ids, err := k.ListPrivateKeys()
+	if err != nil {
+		t.Fatalf("failed to list keys: %v", err)
+	}
+	if len(ids) != len(want) {
+		t.Fatalf("key count mismatch: have %d, want %d", len(ids), len(want))
+	}
+	for i, id := range ids {
+		if string(id) != want[i] {
+			t.Errorf("key %d mismatch: have %q, want %q", i, id, want[i])
+		}
+	}
+}
+
+func TestGCPKMSKeeper(t *testing.T) {
+	fake := &fakeCloudKMS{keys: make(map[string]*ecdsa.PrivateKey)}
+	k := newGCPKMSKeeper(fake, "project", "global", "ring")
+
+	prvID, err := k.GeneratePrivateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	if !strings.HasPrefix(string(prvID), "projects/project/locations/global/keyRings/ring/cryptoKeys/") {
+		t.Fatalf("unexpected resource name %q", prvID)
+	}
+	pub, err := k.GetPublicKey(prvID)
+	if err != nil {
+		t.Fatalf("failed to get public key: %v", err)
+	}
+	if want := crypto.FromECDSAPub(&fake.keys[string(prvID)].PublicKey); !bytes.Equal(pub, want) {
+		t.Fatalf("public key mismatch: have %x, want %x", pub, want)
+	}
+	hash := crypto.Keccak256([]byte("gcp"))
+	sig, err := k.Sign(hash, prvID)
+	if err != nil {
+		t.Fatalf("failed to sign: %v", err)
+	}
+	recovered, err := crypto.Ecrecover(hash, sig)
+	if err != nil {
+		t.Fatalf("failed to recover: %v", err)
+	}
+	if !bytes.Equal(recovered, pub) {
+		t.Fatalf("recovered key mismatch: have %x, want %x", recovered, pub)
+	}
+	if sig[32] >= 0x80 {
+		t.Fatalf("signature %x is not in low-S form", sig)
+	}
+
+	fake.corrupt = true
+	if _, err := k.Sign(hash, prvID); err == nil {
+		t.Fatal("accepted corrupted signature")
+	}
+	if _, err := k.GetPublicKey([]byte("missing")); status.Code(err) != codes.NotFound {
+		t.Fatalf("error mismatch: have %v, want %v", err, codes.NotFound)
+	}
+
+	if err := k.DeletePrivateKey(prvID); err != nil {
+		t.Fatalf("failed to delete key: %v", err)
+	}
+	if _, err := k.GetPublicKey(prvID); !errors.Is(err, ErrKeyNotFound) {
+		t.Fatalf("deleted key: have %v, want %v", err, ErrKeyNotFound)
+	}
+	if err := k.DeletePrivateKey(prvID); !errors.Is(err, ErrKeyNotFound) {
+		t.Fatalf("deleting missing key: have %v, want %v", err, ErrKeyNotFound)
+	}
+}
@@ -0,0 +1,255 @@
+package keeper
+
+//go:generate protoc -I . --go_out=paths=source_relative:. --go-grpc_out=paths=source_relative:. keeperpb/keeper.proto
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/keeper/keeperpb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// grpcKeeper is a PrivateKeyKeeper forwarding every call to a remote keeper
+// served by RegisterKeeperServer.
+type grpcKeeper struct {
+	client keeperpb.KeeperClient
+}
+
+// NewGRPCKeeper returns a PrivateKeyKeeper using the remote keeper reachable
+// through conn. The server refuses plain text connections over the network,
+// conn has to use TLS unless it connects to a UNIX socket.
+func NewGRPCKeeper(conn *grpc.ClientConn) PrivateKeyKeeper {
+	return &grpcKeeper{client: keeperpb.NewKeeperClient(conn)}
+}
+
+// grpcError restores the keeper errors the server translated into status codes.
+// Failures to reach the server are reported as ErrBackendUnavailable.
+func grpcError(err error) error {
+	switch status.Code(err) {
+	case codes.NotFound:
+		return fmt.Errorf("%w: %w", ErrKeyNotFound, err)
+	case codes.Unimplemented:
+		return fmt.Errorf("%w: %w", ErrNotSupported, err)
+	case codes.InvalidArgument:
+		return fmt.Errorf("%w: %w", ErrInvalidKey, err)
+	case codes.PermissionDenied, codes.Unauthenticated:
+		return fmt.Errorf("%w: %w", ErrPermissionDenied, err)
+	case codes.ResourceExhausted:
+		return fmt.Errorf("%w: %w", ErrRateLimitExceeded, err)
+	case codes.Unavailable:
+		return fmt.Errorf("%w: %w", ErrBackendUnavailable, err)
+	}
+	return err
+}
+
+func (k *grpcKeeper) GeneratePrivateKey() ([]byte, error) {
+	return k.GeneratePrivateKeyContext(context.Background())
+}
+
+func (k *grpcKeeper) GeneratePrivateKeyContext(ctx context.Context) (_ []byte, err error) {
+	defer wrapError(&err, "generate key", nil)
+
+	resp, err := k.client.GeneratePrivateKey(ctx, &keeperpb.GeneratePrivateKeyRequest{})
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return resp.PrvId, nil
+}
+
+func (k *grpcKeeper) GetPublicKey(prvID []byte) ([]byte, error) {
+	return k.GetPublicKeyContext(context.Background(), prvID)
+}
+
+func (k *grpcKeeper) GetPublicKeyContext(ctx context.Context, prvID []byte) (_ []byte, err error) {
+	defer wrapError(&err, "get public key", prvID)
+
+	resp, err := k.client.GetPublicKey(ctx, &keeperpb.GetPublicKeyRequest{PrvId: prvID})
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return resp.PublicKey, nil
+}
+
+func (k *grpcKeeper) Sign(data []byte, prvID []byte) ([]byte, error) {
+	return k.SignContext(context.Background(), data, prvID)
+}
+
+func (k *grpcKeeper) SignContext(ctx context.Context, data []byte, prvID []byte) (_ []byte, err error) {
+	defer wrapError(&err, "sign", prvID)
+
+	resp, err := k.client.Sign(ctx, &keeperpb.SignRequest{Data: data, PrvId: prvID})
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	// The signature comes from an arbitrary implementation on the server.
+	return NormaliseSignature(resp.Signature)
+}
+
+func (k *grpcKeeper) DeletePrivateKey(prvID []byte) error {
+	return k.DeletePrivateKeyContext(context.Background(), prvID)
+}
+
+func (k *grpcKeeper) DeletePrivateKeyContext(ctx context.Context, prvID []byte) (err error) {
+	defer wrapError(&err, "delete key", prvID)
+
+	if _, err := k.client.DeletePrivateKey(ctx, &keeperpb.DeletePrivateKeyRequest{PrvId: prvID}); err != nil {
+		return grpcError(err)
+	}
+	return nil
+}
+
+func (k *grpcKeeper) ListPrivateKeys() ([][]byte, error) {
+	return k.ListPrivateKeysContext(context.Background())
+}
+
+func (k *grpcKeeper) ListPrivateKeysContext(ctx context.Context) (_ [][]byte, err error) {
+	defer wrapError(&err, "list keys", nil)
+
+	resp, err := k.client.ListPrivateKeys(ctx, &keeperpb.ListPrivateKeysRequest{})
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return resp.PrvIds, nil
+}
+
+func (k *grpcKeeper) ImportPrivateKey(rawKey []byte) ([]byte, error) {
+	return k.ImportPrivateKeyContext(context.Background(), rawKey)
+}
+
+func (k *grpcKeeper) ImportPrivateKeyContext(ctx context.Context, rawKey []byte) (_ []byte, err error) {
+	defer wrapError(&err, "import key", nil)
+
+	resp, err := k.client.ImportPrivateKey(ctx, &keeperpb.ImportPrivateKeyRequest{RawKey: rawKey})
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return resp.PrvId, nil
+}
+
+// keeperServer serves a PrivateKeyKeeper over gRPC.
+type keeperServer struct {
+	keeperpb.UnimplementedKeeperServer
+	keeper PrivateKeyKeeperContext
+}
+
+// RegisterKeeperServer registers k as the Keeper service of s.
+//
+// Requests are only served over TLS or over UNIX sockets, plain text requests
+// from the network are refused: the prvIDs of some keepers are the private keys
+// themselves. Client authentication, e.g. by mutual TLS, has to be configured
+// on s.
+func RegisterKeeperServer(s *grpc.Server, k PrivateKeyKeeper) {
+	keeperpb.RegisterKeeperServer(s, &keeperServer{keeper: ContextKeeper(k)})
+}
+
+// secureTransport reports whether the request in ctx arrived over TLS or a
+// UNIX socket.
+func secureTransport(ctx context.Context) error {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return status.Error(codes.Unauthenticated, "unknown peer")
+	}
+	if _, ok := p.AuthInfo.(credentials.TLSInfo); ok {
+		return nil
+	}
+	if p.Addr != nil && p.Addr.Network() == "unix" {
+		return nil
+	}
+	return status.Error(codes.Unauthenticated, "keeper requires a TLS connection")
+}
+
+// statusError translates keeper errors into gRPC status errors.
+func statusError(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, ErrKeyNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, ErrNotSupported):
+		return status.Error(codes.Unimplemented, err.Error())
+	case errors.Is(err, ErrInvalidKey):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, ErrPermissionDenied):
+		return status.Error(codes.PermissionDenied, err.Error())
+	case errors.Is(err, ErrRateLimitExceeded):
+		return status.Error(codes.ResourceExhausted, err.Error())
+	case errors.Is(err, ErrBackendUnavailable):
+		return status.Error(codes.Unavailable, err.Error())
+	case errors.Is(err, context.Canceled):
+		return status.Error(codes.Canceled, err.Error())
+	case errors.Is(err, context.DeadlineExceeded):
+		return status.Error(codes.DeadlineExceeded, err.Error())
+	}
+	return status.Error(codes.Unknown, err.Error())
+}
+
+func (s *keeperServer) GeneratePrivateKey(ctx context.Context, req *keeperpb.GeneratePrivateKeyRequest) (*keeperpb.GeneratePrivateKeyResponse, error) {
+	if err := secureTransport(ctx); err != nil {
+		return nil, err
+	}
+	prvID, err := s.keeper.GeneratePrivateKeyContext(ctx)
+	if err != nil {
+		return nil, statusError(err)
+	}
+	return &keeperpb.GeneratePrivateKeyResponse{PrvId: prvID}, nil
+}
+
+func (s *keeperServer) GetPublicKey(ctx context.Context, req *keeperpb.GetPublicKeyRequest) (*keeperpb.GetPublicKeyResponse, error) {
+	if err := secureTransport(ctx); err != nil {
+		return nil, err
+	}
+	pub, err := s.keeper.GetPublicKeyContext(ctx, req.PrvId)
+	if err != nil {
+		return nil, statusError(err)
+	}
+	return &keeperpb.GetPublicKeyResponse{PublicKey: pub}, nil
+}
+
+func (s *keeperServer) Sign(ctx context.Context, req *keeperpb.SignRequest) (*keeperpb.SignResponse, error) {
+	if err := secureTransport(ctx); err != nil {
+		return nil, err
+	}
+	sig, err := s.keeper.SignContext(ctx, req.Data, req.PrvId)
+	if err != nil {
+		return nil, statusError(err)
+	}
+	return &keeperpb.SignResponse{Signature: sig}, nil
+}
+
+func (s *keeperServer) DeletePrivateKey(ctx context.Context, req *keeperpb.DeletePrivateKeyRequest) (*keeperpb.DeletePrivateKeyResponse, error) {
+	if err := secureTransport(ctx); err != nil {
+		return nil, err
+	}
+	if err := s.keeper.DeletePrivateKeyContext(ctx, req.PrvId); err != nil {
+		return nil, statusError(err)
+	}
+	return &keeperpb.DeletePrivateKeyResponse{}, nil
+}
+
+func (s *keeperServer) ListPrivateKeys(ctx context.Context, req *keeperpb.ListPrivateKeysRequest) (*keeperpb.ListPrivateKeysResponse, error) {
+	if err := secureTransport(ctx); err != nil {
+		return nil, err
+	}
+	prvIDs, err := s.keeper.ListPrivateKeysContext(ctx)
+	if err != nil {
+		return nil, statusError(err)
+	}
+	return &keeperpb.ListPrivateKeysResponse{PrvIds: prvIDs}, nil
+}
+
+func (s *keeperServer) ImportPrivateKey(ctx context.Context, req *keeperpb.ImportPrivateKeyRequest) (*keeperpb.ImportPrivateKeyResponse, error) {
+	if err := secureTransport(ctx); err != nil {
+		return nil, err
+	}
+	prvID, err := s.keeper.ImportPrivateKeyContext(ctx, req.RawKey)
+	if err != nil {
+		return nil, statusError(err)
+	}
+	return &keeperpb.ImportPrivateKeyResponse{PrvId: prvID}, nil
+}
@@ -0,0 +1,198 @@
+package keeper
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"math/big"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/crypto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+)
+
+// testCertificate issues a certificate for 127.0.0.1 signed by parent, or a
+// self signed CA certificate if parent is nil.
+func testCertificate(parent *tls.Certificate, name string) tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		panic(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	signer, signerKey := tmpl, any(key)
+	if parent == nil {
+		tmpl.IsCA, tmpl.BasicConstraintsValid = true, true
+	} else {
+		signer, signerKey = parent.Leaf, parent.PrivateKey
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		panic(err)
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		panic(err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}
+}
+
+// serveKeeper serves the default keeper on l until the test ends.
+func serveKeeper(t *testing.T, l net.Listener, opts ...grpc.ServerOption) {
+	srv := grpc.NewServer(opts...)
+	RegisterKeeperServer(srv, defaultKeeper)
+	go srv.Serve(l)
+	t.Cleanup(srv.Stop)
+}
+
+// testGRPCKeeper runs the keeper round trip against a remote keeper.
+func testGRPCKeeper(t *testing.T, k PrivateKeyKeeper) {
+	prvID, err := k.GeneratePrivateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	pub, err := k.GetPublicKey(prvID)
+	if err != nil {
+		t.Fatalf("failed to get public key: %v", err)
+	}
+	hash := crypto.Keccak256([]byte("grpc"))
+	sig, err := k.Sign(hash, prvID)
+	if err != nil {
+		t.Fatalf("failed to sign: %v", err)
+	}
+	recovered, err := crypto.Ecrecover(hash, sig)
+	if err != nil {
+		t.Fatalf("failed to recover: %v", err)
+	}
+	if !bytes.Equal(recovered, pub) {
+		t.Fatalf("recovered key mismatch: have %x, want %x", recovered, pub)
+	}
+	if _, err := k.ListPrivateKeys(); !errors.Is(err, ErrNotSupported) {
+		t.Fatalf("list: have %v, want %v", err, ErrNotSupported)
+	}
+	checkImport(t, k)
+}
+
+// This test serves a keeper with mutual TLS, the setup remote keepers should
+// be deployed with.
+func TestGRPCKeeperMutualTLS(t *testing.T) {
+	ca := testCertificate(nil, "keeper ca")
+	serverCert := testCertificate(&ca, "keeper server")
+	clientCert := testCertificate(&ca, "keeper client")
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.Leaf)
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	serveKeeper(t, l, grpc.Creds(credentials.NewTLS(&tls.Config{
+		Certificates: []tls.Certificate{serverCert},
+		ClientCAs:    pool,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		MinVersion:   tls.VersionTLS13,
+	})))
+
+	conn, err := grpc.NewClient(l.Addr().String(), grpc.WithTransportCredentials(credentials.NewTLS(&tls.Config{
+		Certificates: []tls.Certificate{clientCert},
+		RootCAs:      pool,
+		MinVersion:   tls.VersionTLS13,
+	})))
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	defer conn.Close()
+	testGRPCKeeper(t, NewGRPCKeeper(conn))
+
+	// Clients without a certificate must be turned away by the handshake.
+	anon, err := grpc.NewClient(l.Addr().String(), grpc.WithTransportCredentials(credentials.NewTLS(&tls.Config{
+		RootCAs:    pool,
+		MinVersion: tls.VersionTLS13,
+	})))
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	defer anon.Close()
+	if _, err := NewGRPCKeeper(anon).GeneratePrivateKey(); err == nil {
+		t.Fatal("served client without certificate")
+	}
+}
+
+func TestGRPCKeeperRejectsPlainText(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	serveKeeper(t, l)
+
+	conn, err := grpc.NewClient(l.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	defer conn.Close()
+	if _, err := NewGRPCKeeper(conn).GeneratePrivateKey(); status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("plain text request: have %v, want code %v", err, codes.Unauthenticated)
+	}
+}
+
+func TestGRPCKeeperUnixSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keeper.sock")
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	serveKeeper(t, l)
+
+	conn, err := grpc.NewClient("unix://"+path, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	defer conn.Close()
+	testGRPCKeeper(t, NewGRPCKeeper(conn))
+}
+
+func TestGRPCKeeperErrors(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keeper.sock")
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	srv := grpc.NewServer()
+	RegisterKeeperServer(srv, NewKeystoreKeeper(t.TempDir(), keystore.LightScryptN, keystore.LightScryptP, MemoryPassphraseProvider("foo")))
+	go srv.Serve(l)
+	defer srv.Stop()
+
+	conn, err := grpc.NewClient("unix://"+path, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	defer conn.Close()
+	k := NewGRPCKeeper(conn)
+	missing := []byte("00000000-0000-0000-0000-000000000000")
+	if _, err := k.GetPublicKey(missing); !errors.Is(err, ErrKeyNotFound) {
+		t.Fatalf("missing key: have %v, want %v", err, ErrKeyNotFound)
+	}
+	if _, err := k.Sign(make([]byte, 32), missing); !errors.Is(err, ErrKeyNotFound) {
+		t.Fatalf("sign with missing key: have %v, want %v", err, ErrKeyNotFound)
+	}
+}
@@ -0,0 +1,88 @@
+package keeper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// HashingStrategy selects who hashes a transaction before it is signed.
+type HashingStrategy int
+
+const (
+	// HashByCaller hashes the transaction in the SecureSign, the keeper signs
+	// the 32 byte hash. It is the default.
+	HashByCaller HashingStrategy = iota
+
+	// HashByKeeper hands the signing pre-image of the transaction to the keeper,
+	// which hashes it itself, so that the keeper knows what it signs rather than
+	// signing any hash it is given. The keeper has to implement RawSigner.
+	HashByKeeper
+)
+
+// RawSigner is implemented by keepers hashing the data they sign themselves.
+type RawSigner interface {
+	// SignRaw signs the Keccak-256 hash of preimage by private key ID, hashing
+	// preimage itself
+	SignRaw(ctx context.Context, preimage []byte, prvID []byte) ([]byte, error)
+}
+
+// WithHashingStrategy sets who hashes transactions before they are signed. With
+// HashByKeeper, signing fails with ErrNotSupported if the keeper doesn't
+// implement RawSigner. The strategy applies to transactions only, messages and
+// typed data are hashed by the caller either way.
+func WithHashingStrategy(strategy HashingStrategy) HashingOption {
+	return func(sec *SecureSign) {
+		sec.hashing = strategy
+	}
+}
+
+// signingPreimage returns the data whose Keccak-256 hash s signs for tx: the RLP
+// list of the signed fields, prefixed by the type for typed transactions. The
+// pre-image is checked against the hash of s, so signers hashing differently
+// than the forks known here are refused instead of producing bad signatures.
+func signingPreimage(tx *types.Transaction, s types.Signer) ([]byte, error) {
+	chainID := s.ChainID()
+	var (
+		fields []any
+		prefix []byte
+	)
+	switch tx.Type() {
+	case types.LegacyTxType:
+		fields = []any{tx.Nonce(), tx.GasPrice(), tx.Gas(), tx.To(), tx.Value(), tx.Data()}
+		if chainID != nil && chainID.Sign() != 0 {
+			fields = append(fields, chainID, uint(0), uint(0))
+		}
+	case types.AccessListTxType:
+		fields = []any{chainID, tx.Nonce(), tx.GasPrice(), tx.Gas(), tx.To(), tx.Value(), tx.Data(), tx.AccessList()}
+	case types.DynamicFeeTxType:
+		fields = []any{chainID, tx.Nonce(), tx.GasTipCap(), tx.GasFeeCap(), tx.Gas(), tx.To(), tx.Value(), tx.Data(), tx.AccessList()}
+	case types.BlobTxType:
+		fields = []any{chainID, tx.Nonce(), tx.GasTipCap(), tx.GasFeeCap(), tx.Gas(), tx.To(), tx.Value(), tx.Data(), tx.AccessList(), tx.BlobGasFeeCap(), tx.BlobHashes()}
+	case types.SetCodeTxType:
+		fields = []any{chainID, tx.Nonce(), tx.GasTipCap(), tx.GasFeeCap(), tx.Gas(), tx.To(), tx.Value(), tx.Data(), tx.AccessList(), tx.SetCodeAuthorizations()}
+	default:
+		return nil, fmt.Errorf("%w: pre-image of transaction type %d", ErrNotSupported, tx.Type())
+	}
+	if tx.Type() != types.LegacyTxType {
+		prefix = []byte{tx.Type()}
+	}
+	enc, err := rlp.EncodeToBytes(fields)
+	if err != nil {
+		return nil, err
+	}
+	preimage := append(prefix, enc...)
+	if crypto.Keccak256Hash(preimage) != s.Hash(tx) {
+		return nil, errors.New("transaction pre-image doesn't match the hash of the signer")
+	}
+	return preimage, nil
+}
+
+// SignRaw signs the Keccak-256 hash of preimage with the private key prvID.
+func (a *defaultPrivateKeyKeeper) SignRaw(ctx context.Context, preimage []byte, prvID []byte) ([]byte, error) {
+	return a.SignContext(ctx, crypto.Keccak256(preimage), prvID)
+}
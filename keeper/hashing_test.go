@@ -0,0 +1,84 @@
+package keeper
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/holiman/uint256"
+)
+
+// preimageKeeper is a RawSigner recording the pre-images it signs, refusing to
+// sign bare hashes.
+type preimageKeeper struct {
+	defaultPrivateKeyKeeper
+	preimages [][]byte
+}
+
+func (k *preimageKeeper) SignContext(ctx context.Context, data []byte, prvID []byte) ([]byte, error) {
+	return nil, errors.New("asked to sign bare hash")
+}
+
+func (k *preimageKeeper) SignRaw(ctx context.Context, preimage []byte, prvID []byte) ([]byte, error) {
+	k.preimages = append(k.preimages, preimage)
+	return k.defaultPrivateKeyKeeper.SignRaw(ctx, preimage, prvID)
+}
+
+func TestHashByKeeper(t *testing.T) {
+	keeper := new(preimageKeeper)
+	sec := NewSecureSign(keeper, WithHashingStrategy(HashByKeeper))
+	prvID, _ := sec.GenerateKey()
+	addr, _ := sec.GetAddress(prvID)
+
+	var (
+		chainID = big.NewInt(1337)
+		chain   = uint256.MustFromBig(chainID)
+		to      = common.HexToAddress("0x0102030405060708090a0b0c0d0e0f1011121314")
+		list    = types.AccessList{{Address: to, StorageKeys: []common.Hash{{1}}}}
+	)
+	tests := []struct {
+		tx     *types.Transaction
+		signer types.Signer
+	}{
+		{types.NewTx(&types.LegacyTx{Nonce: 1, GasPrice: big.NewInt(1), Gas: 21000, To: &to, Value: big.NewInt(1)}), types.HomesteadSigner{}},
+		{types.NewTx(&types.LegacyTx{Nonce: 1, GasPrice: big.NewInt(1), Gas: 53000, Data: []byte{0x60}}), types.NewEIP155Signer(chainID)},
+		{types.NewTx(&types.AccessListTx{ChainID: chainID, Nonce: 2, GasPrice: big.NewInt(1), Gas: 30000, To: &to, AccessList: list}), NewChainSigner(chainID)},
+		{types.NewTx(&types.DynamicFeeTx{ChainID: chainID, Nonce: 3, GasTipCap: big.NewInt(1), GasFeeCap: big.NewInt(10), Gas: 21000, To: &to}), NewChainSigner(chainID)},
+		{types.NewTx(&types.BlobTx{ChainID: chain, Nonce: 4, GasTipCap: uint256.NewInt(1), GasFeeCap: uint256.NewInt(10), Gas: 21000, To: to, BlobFeeCap: uint256.NewInt(1), BlobHashes: []common.Hash{{0x01}}}), NewChainSigner(chainID)},
+		{types.NewTx(&types.SetCodeTx{ChainID: chain, Nonce: 5, GasTipCap: uint256.NewInt(1), GasFeeCap: uint256.NewInt(10), Gas: 50000, To: to, AuthList: []types.SetCodeAuthorization{{ChainID: *chain, Address: to, Nonce: 6}}}), NewChainSigner(chainID)},
+	}
+	for i, tt := range tests {
+		signed, err := sec.Sign(tt.tx, tt.signer, prvID)
+		if err != nil {
+			t.Fatalf("test %d: failed to sign type %d: %v", i, tt.tx.Type(), err)
+		}
+		if from, err := types.Sender(tt.signer, signed); err != nil || from != addr {
+			t.Fatalf("test %d: sender mismatch: have (%x, %v), want %x", i, from, err, addr)
+		}
+		preimage := keeper.preimages[len(keeper.preimages)-1]
+		if have, want := crypto.Keccak256Hash(preimage), tt.signer.Hash(tt.tx); have != want {
+			t.Fatalf("test %d: pre-image hash mismatch: have %v, want %v", i, have, want)
+		}
+	}
+	if len(keeper.preimages) != len(tests) {
+		t.Fatalf("pre-images signed: have %d, want %d", len(keeper.preimages), len(tests))
+	}
+}
+
+func TestHashByKeeperUnsupported(t *testing.T) {
+	// Keepers that can't hash themselves are refused rather than handed the hash.
+	inner := &unlistableKeeper{new(defaultPrivateKeyKeeper)}
+	sec := NewSecureSigner(inner, WithHashingStrategy(HashByKeeper))
+	prvID, _ := sec.GenerateKey()
+	if _, err := sec.Sign(newTestTx(), types.HomesteadSigner{}, prvID); !errors.Is(err, ErrNotSupported) {
+		t.Fatalf("keeper without raw signing: have %v, want %v", err, ErrNotSupported)
+	}
+	// The default strategy is unaffected.
+	if _, err := NewSecureSigner(inner).Sign(newTestTx(), types.HomesteadSigner{}, prvID); err != nil {
+		t.Fatalf("failed to sign hash: %v", err)
+	}
+}
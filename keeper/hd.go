@@ -0,0 +1,110 @@
+package keeper
+
+import (
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/tyler-smith/go-bip39"
+)
+
+// HDKeeper is a PrivateKeyKeeper that can additionally import keys derived
+// from a BIP-39 mnemonic.
+type HDKeeper interface {
+	PrivateKeyKeeper
+	// ImportFromMnemonic return identifier of private key derived from the
+	// mnemonic along the BIP-32 derivation path, m/44'/60'/0'/0/0 if empty
+	ImportFromMnemonic(mnemonic, passphrase, derivationPath string) (prvID []byte, err error)
+}
+
+// hdKeeper realized interface HDKeeper, storing derived keys the same way as
+// defaultPrivateKeyKeeper does.
+type hdKeeper struct {
+	defaultPrivateKeyKeeper
+}
+
+// NewHDKeeper returns a new HDKeeper.
+func NewHDKeeper() HDKeeper {
+	return &hdKeeper{}
+}
+
+func (h *hdKeeper) ImportFromMnemonic(mnemonic, passphrase, derivationPath string) (_ []byte, err error) {
+	defer wrapError(&err, "import key", nil)
+
+	path := accounts.DefaultBaseDerivationPath
+	if derivationPath != "" {
+		if path, err = accounts.ParseDerivationPath(derivationPath); err != nil {
+			return nil, err
+		}
+	}
+	seed, err := bip39.NewSeedWithErrorChecking(mnemonic, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	key, err := deriveKey(seed, path)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := crypto.ToECDSA(key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// deriveKey walks the BIP-32 derivation path from the master key of seed and
+// returns the raw private key at its end.
+func deriveKey(seed []byte, path accounts.DerivationPath) ([]byte, error) {
+	mac := hmac.New(sha512.New, []byte("Bitcoin seed"))
+	mac.Write(seed)
+	sum := mac.Sum(nil)
+
+	key, chainCode := sum[:32], sum[32:]
+	if k := new(big.Int).SetBytes(key); k.Sign() == 0 || k.Cmp(secp256k1N) >= 0 {
+		return nil, errors.New("invalid master key")
+	}
+	for _, index := range path {
+		var err error
+		if key, chainCode, err = deriveChild(key, chainCode, index); err != nil {
+			return nil, err
+		}
+	}
+	return key, nil
+}
+
+// deriveChild implements the BIP-32 private parent key to private child key
+// derivation.
+func deriveChild(key, chainCode []byte, index uint32) ([]byte, []byte, error) {
+	var data []byte
+	if index >= 0x80000000 {
+		// Hardened child: 0x00 || ser256(k) || ser32(i)
+		data = append([]byte{0x00}, key...)
+	} else {
+		// Normal child: serP(point(k)) || ser32(i)
+		prv, err := crypto.ToECDSA(key)
+		if err != nil {
+			return nil, nil, err
+		}
+		data = crypto.CompressPubkey(&prv.PublicKey)
+	}
+	data = binary.BigEndian.AppendUint32(data, index)
+
+	mac := hmac.New(sha512.New, chainCode)
+	mac.Write(data)
+	sum := mac.Sum(nil)
+
+	il := new(big.Int).SetBytes(sum[:32])
+	if il.Cmp(secp256k1N) >= 0 {
+		return nil, nil, fmt.Errorf("invalid child key at index %d", index)
+	}
+	child := il.Add(il, new(big.Int).SetBytes(key))
+	child.Mod(child, secp256k1N)
+	if child.Sign() == 0 {
+		return nil, nil, fmt.Errorf("invalid child key at index %d", index)
+	}
+	return child.FillBytes(make([]byte, 32)), sum[32:], nil
+}
@@ -0,0 +1,76 @@
+package keeper
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// Test vector 1 of BIP-32.
+func TestDeriveKey(t *testing.T) {
+	seed := common.FromHex("000102030405060708090a0b0c0d0e0f")
+	tests := []struct {
+		path string
+		key  string
+	}{
+		{"m", "e8f32e723decf4051aefac8e2c93c9c5b214313817cdb01a1494b917c8436b35"},
+		{"m/0'", "edb2e14f9ee77d26dd93b4ecede8d16ed408ce149b6cd80b0715a2d911a0afea"},
+		{"m/0'/1", "3c6cb8d0f6a264c91ea8b5030fadaa8e538b020f0a387421a12de9319dc93368"},
+		{"m/0'/1/2'", "cbce0d719ecf7431d88e6a89fa1483e02e35092af60c042b1df2ff59fa424dca"},
+		{"m/0'/1/2'/2", "0f479245fb19a38a1954c5c7c0ebab2f9bdfd96a17563ef28a6a4b1a2a764ef4"},
+		{"m/0'/1/2'/2/1000000000", "471b76e389e528d6de6d816857e012c5455051cad6660850e58372a6c3e6e7c8"},
+	}
+	for _, tt := range tests {
+		var path accounts.DerivationPath
+		if tt.path != "m" {
+			var err error
+			if path, err = accounts.ParseDerivationPath(tt.path); err != nil {
+				t.Fatalf("%s: invalid path: %v", tt.path, err)
+			}
+		}
+		key, err := deriveKey(seed, path)
+		if err != nil {
+			t.Fatalf("%s: failed to derive key: %v", tt.path, err)
+		}
+		if have := hex.EncodeToString(key); have != tt.key {
+			t.Errorf("%s: key mismatch: have %s, want %s", tt.path, have, tt.key)
+		}
+	}
+}
+
+func TestImportFromMnemonic(t *testing.T) {
+	const mnemonic = "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about"
+
+	tests := []struct {
+		path string
+		addr common.Address
+	}{
+		{"", common.HexToAddress("0x9858EfFD232B4033E47d90003D41EC34EcaEda94")},
+		{"m/44'/60'/0'/0/0", common.HexToAddress("0x9858EfFD232B4033E47d90003D41EC34EcaEda94")},
+		{"m/44'/60'/0'/0/1", common.HexToAddress("0x6Fac4D18c912343BF86fa7049364Dd4E424Ab9C0")},
+	}
+	k := NewHDKeeper()
+	for _, tt := range tests {
+		prvID, err := k.ImportFromMnemonic(mnemonic, "", tt.path)
+		if err != nil {
+			t.Fatalf("%q: failed to import: %v", tt.path, err)
+		}
+		pub, err := k.GetPublicKey(prvID)
+		if err != nil {
+			t.Fatalf("%q: failed to get public key: %v", tt.path, err)
+		}
+		key, _ := crypto.UnmarshalPubkey(pub)
+		if addr := crypto.PubkeyToAddress(*key); addr != tt.addr {
+			t.Errorf("%q: address mismatch: have %v, want %v", tt.path, addr, tt.addr)
+		}
+	}
+	if _, err := k.ImportFromMnemonic("abandon abandon abandon", "", ""); err == nil {
+		t.Error("imported invalid mnemonic")
+	}
+	if _, err := k.ImportFromMnemonic(mnemonic, "", "m/not/a/path"); err == nil {
+		t.Error("imported along invalid derivation path")
+	}
+}
@@ -0,0 +1,178 @@
+package keeper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// HealthChecker is implemented by keepers able to check that their backend is
+// reachable, e.g. for the liveness probes of a service. The check is a cheap
+// request to the backend that doesn't touch any key.
+type HealthChecker interface {
+	// HealthCheck returns an error, ErrBackendUnavailable for outages, if the
+	// backend can't serve requests
+	HealthCheck(ctx context.Context) error
+}
+
+// HealthMonitoredKeeper is a PrivateKeyKeeper checking the health of its backend
+// in the background, as returned by NewHealthCheckingKeeper.
+type HealthMonitoredKeeper interface {
+	PrivateKeyKeeper
+	HealthChecker
+
+	// IsHealthy reports whether the last health check passed
+	IsHealthy() bool
+}
+
+// healthCheckingKeeper is a PrivateKeyKeeper refusing to sign while the last
+// health check of its backend failed, so that callers fail fast during an
+// outage instead of waiting for every request to time out.
+//
+// The check loop only references healthMonitor, so the healthCheckingKeeper can
+// be collected when unused, which ends the loop.
+type healthCheckingKeeper struct {
+	*healthMonitor
+}
+
+type healthMonitor struct {
+	PrivateKeyKeeper
+	inner    PrivateKeyKeeperContext
+	check    func(ctx context.Context) error
+	interval time.Duration
+	quit     chan struct{}
+
+	lock sync.Mutex
+	err  error // result of the last health check
+}
+
+// NewHealthCheckingKeeper returns a HealthMonitoredKeeper checking the health of
+// inner every interval, 10 seconds if interval isn't positive, with a timeout of
+// interval for each check. Signing fails with ErrBackendUnavailable while the
+// last check failed, all other calls are forwarded regardless. Keepers that
+// don't implement HealthChecker are checked by listing their keys, or count as
+// healthy if they can't list them. The decorators of this package forward the
+// health checks of the keepers they wrap. The keeper counts as healthy until
+// the first check, made right away, completes.
+func NewHealthCheckingKeeper(inner PrivateKeyKeeper, interval time.Duration) PrivateKeyKeeper {
+	if interval <= 0 {
+		interval = 10 * time.Second
+	}
+	m := &healthMonitor{
+		PrivateKeyKeeper: inner,
+		inner:            ContextKeeper(inner),
+		interval:         interval,
+		quit:             make(chan struct{}),
+	}
+	m.check = healthCheck(inner)
+	go m.checkLoop()
+
+	k := &healthCheckingKeeper{m}
+	runtime.SetFinalizer(k, func(k *healthCheckingKeeper) { close(k.quit) })
+	return k
+}
+
+// healthCheck returns the health check of k, see checkHealth.
+func healthCheck(k PrivateKeyKeeper) func(ctx context.Context) error {
+	ck := ContextKeeper(k)
+	return func(ctx context.Context) error {
+		return checkHealth(ctx, ck)
+	}
+}
+
+// checkHealth checks the health of k with its own health check if it
+// implements HealthChecker, by listing its keys otherwise. Keepers that can't
+// list their keys count as healthy.
+func checkHealth(ctx context.Context, k PrivateKeyKeeperContext) error {
+	var inner any = k
+	if c, ok := k.(*contextKeeper); ok {
+		inner = c.keeper
+	}
+	if hc, ok := inner.(HealthChecker); ok {
+		return hc.HealthCheck(ctx)
+	}
+	if _, err := k.ListPrivateKeysContext(ctx); err != nil && !errors.Is(err, ErrNotSupported) {
+		return err
+	}
+	return nil
+}
+
+// checkLoop checks the health of the backend right away and then every
+// interval.
+func (m *healthMonitor) checkLoop() {
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), m.interval)
+		m.HealthCheck(ctx)
+		cancel()
+
+		select {
+		case <-ticker.C:
+		case <-m.quit:
+			return
+		}
+	}
+}
+
+// HealthCheck checks the health of the backend right away, recording the result
+// for IsHealthy and Sign.
+func (m *healthMonitor) HealthCheck(ctx context.Context) (err error) {
+	defer wrapError(&err, "health check", nil)
+
+	err = m.check(ctx)
+	m.lock.Lock()
+	m.err = err
+	m.lock.Unlock()
+	return err
+}
+
+func (m *healthMonitor) IsHealthy() bool {
+	return m.lastError() == nil
+}
+
+func (m *healthMonitor) lastError() error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return m.err
+}
+
+func (m *healthMonitor) Sign(data []byte, prvID []byte) ([]byte, error) {
+	return m.SignContext(context.Background(), data, prvID)
+}
+
+func (m *healthMonitor) SignContext(ctx context.Context, data []byte, prvID []byte) (_ []byte, err error) {
+	defer wrapError(&err, "sign", prvID)
+
+	if err := m.lastError(); err != nil {
+		if !errors.Is(err, ErrBackendUnavailable) {
+			err = fmt.Errorf("%w: %w", ErrBackendUnavailable, err)
+		}
+		return nil, err
+	}
+	return m.inner.SignContext(ctx, data, prvID)
+}
+
+func (m *healthMonitor) GeneratePrivateKeyContext(ctx context.Context) ([]byte, error) {
+	return m.inner.GeneratePrivateKeyContext(ctx)
+}
+
+func (m *healthMonitor) GetPublicKeyContext(ctx context.Context, prvID []byte) ([]byte, error) {
+	return m.inner.GetPublicKeyContext(ctx, prvID)
+}
+
+func (m *healthMonitor) DeletePrivateKeyContext(ctx context.Context, prvID []byte) error {
+	return m.inner.DeletePrivateKeyContext(ctx, prvID)
+}
+
+func (m *healthMonitor) ListPrivateKeysContext(ctx context.Context) ([][]byte, error) {
+	return m.inner.ListPrivateKeysContext(ctx)
+}
+
+func (m *healthMonitor) ImportPrivateKeyContext(ctx context.Context, rawKey []byte) ([]byte, error) {
+	return m.inner.ImportPrivateKeyContext(ctx, rawKey)
+}
@@ -0,0 +1,176 @@
+package keeper
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+)
+
+// outageKeeper is a PrivateKeyKeeper whose health check fails while down.
+type outageKeeper struct {
+	PrivateKeyKeeper
+
+	lock sync.Mutex
+	down bool
+}
+
+func (k *outageKeeper) setDown(down bool) {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+	k.down = down
+}
+
+func (k *outageKeeper) HealthCheck(ctx context.Context) error {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+
+	if k.down {
+		return ErrBackendUnavailable
+	}
+	return nil
+}
+
+// waitHealth waits for the health checks of k to report the given health.
+func waitHealth(t *testing.T, k HealthMonitoredKeeper, healthy bool) {
+	t.Helper()
+
+	for deadline := time.Now().Add(5 * time.Second); k.IsHealthy() != healthy; {
+		if time.Now().After(deadline) {
+			t.Fatalf("health not reported: have %v, want %v", !healthy, healthy)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestHealthCheckingKeeper(t *testing.T) {
+	inner := &outageKeeper{PrivateKeyKeeper: new(defaultPrivateKeyKeeper)}
+	k := NewHealthCheckingKeeper(inner, 5*time.Millisecond).(HealthMonitoredKeeper)
+	prvID, err := k.GeneratePrivateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	hash := make([]byte, 32)
+	if _, err := k.Sign(hash, prvID); err != nil {
+		t.Fatalf("failed to sign: %v", err)
+	}
+	// Signing is refused once a check failed, other calls are still served.
+	inner.setDown(true)
+	waitHealth(t, k, false)
+	if _, err := k.Sign(hash, prvID); !errors.Is(err, ErrBackendUnavailable) {
+		t.Fatalf("sign during outage: have %v, want %v", err, ErrBackendUnavailable)
+	}
+	if _, err := k.GetPublicKey(prvID); err != nil {
+		t.Fatalf("failed to get public key during outage: %v", err)
+	}
+	inner.setDown(false)
+	waitHealth(t, k, true)
+	if _, err := k.Sign(hash, prvID); err != nil {
+		t.Fatalf("failed to sign after outage: %v", err)
+	}
+}
+
+// unlistableKeeper is a PrivateKeyKeeper failing to list its keys.
+type unlistableKeeper struct {
+	PrivateKeyKeeper
+}
+
+func (k *unlistableKeeper) ListPrivateKeys() ([][]byte, error) {
+	return nil, ErrPermissionDenied
+}
+
+func TestHealthCheckingKeeperFallback(t *testing.T) {
+	// Keepers without health checks are checked by listing their keys. The
+	// first check runs right away, a manual one updates the result.
+	k := NewHealthCheckingKeeper(&unlistableKeeper{new(defaultPrivateKeyKeeper)}, time.Hour).(HealthMonitoredKeeper)
+	if err := k.HealthCheck(context.Background()); !errors.Is(err, ErrPermissionDenied) {
+		t.Fatalf("health check: have %v, want %v", err, ErrPermissionDenied)
+	}
+	if k.IsHealthy() {
+		t.Fatal("failed check reported healthy")
+	}
+	prvID, _ := k.GeneratePrivateKey()
+	if _, err := k.Sign(make([]byte, 32), prvID); !errors.Is(err, ErrBackendUnavailable) {
+		t.Fatalf("sign while unhealthy: have %v, want %v", err, ErrBackendUnavailable)
+	}
+}
+
+// nonListingKeeper is a PrivateKeyKeeper unable to list its keys.
+type nonListingKeeper struct {
+	PrivateKeyKeeper
+}
+
+func (k *nonListingKeeper) ListPrivateKeys() ([][]byte, error) {
+	return nil, ErrNotSupported
+}
+
+func TestHealthCheckingKeeperDefaultInterval(t *testing.T) {
+	k := NewHealthCheckingKeeper(new(defaultPrivateKeyKeeper), 0)
+	if have := k.(*healthCheckingKeeper).interval; have != 10*time.Second {
+		t.Fatalf("interval: have %v, want %v", have, 10*time.Second)
+	}
+}
+
+func TestHealthCheckingKeeperNotSupported(t *testing.T) {
+	// Keepers unable to list their keys count as healthy.
+	k := NewHealthCheckingKeeper(&nonListingKeeper{new(defaultPrivateKeyKeeper)}, time.Hour).(HealthMonitoredKeeper)
+	if err := k.HealthCheck(context.Background()); err != nil {
+		t.Fatalf("health check: %v", err)
+	}
+	prvID, _ := k.GeneratePrivateKey()
+	if _, err := k.Sign(make([]byte, 32), prvID); err != nil {
+		t.Fatalf("failed to sign: %v", err)
+	}
+}
+
+func TestHealthCheckingKeeperContext(t *testing.T) {
+	// The context of the caller reaches the backend through the decorators
+	// wrapping the health checking keeper.
+	inner := &deadlineKeeper{defaultPrivateKeyKeeper: new(defaultPrivateKeyKeeper), deadlines: make(chan time.Time, 1)}
+	k := NewTimeoutKeeper(NewHealthCheckingKeeper(inner, time.Hour), 0, 0, 10*time.Millisecond)
+	if _, err := k.GetPublicKey(make([]byte, 32)); !errors.Is(err, ErrOperationTimeout) {
+		t.Fatalf("public key lookup: have %v, want %v", err, ErrOperationTimeout)
+	}
+	if deadline := <-inner.deadlines; deadline.IsZero() {
+		t.Fatal("deadline not passed to backend")
+	}
+}
+
+func TestHealthCheckForwarding(t *testing.T) {
+	inner := &outageKeeper{PrivateKeyKeeper: new(defaultPrivateKeyKeeper), down: true}
+	middlewares := map[string]KeeperMiddleware{
+		"audit":            WithAuditLog(io.Discard),
+		"rate limit":       WithRateLimit(10, 5),
+		"retry":            WithRetry(1, FixedBackoff(0)),
+		"timeout":          WithTimeout(time.Second, time.Second, time.Second),
+		"adaptive timeout": WithAdaptiveTimeout(AdaptiveTimeoutOptions{}),
+		"single flight":    WithSingleFlight(),
+		"logging":          WithLogging(slog.New(slog.NewTextHandler(io.Discard, nil))),
+		"cache": func(inner PrivateKeyKeeper) PrivateKeyKeeper {
+			return NewCachedKeeper(inner, time.Hour)
+		},
+		"namespace": func(inner PrivateKeyKeeper) PrivateKeyKeeper {
+			k, _ := NewNamespacedKeeper(inner, "test")
+			return k
+		},
+		"metadata": WithMetadataStore(MemoryMetadataStore()),
+		"expiry": func(inner PrivateKeyKeeper) PrivateKeyKeeper {
+			return NewExpiryEnforcingKeeper(NewMetadataAwareKeeper(inner, MemoryMetadataStore()).(MetadataAwareKeeper))
+		},
+		"usage": func(inner PrivateKeyKeeper) PrivateKeyKeeper {
+			return NewUsageLimitingKeeper(NewMetadataAwareKeeper(inner, MemoryMetadataStore()).(MetadataAwareKeeper))
+		},
+	}
+	for name, mw := range middlewares {
+		hc, ok := mw(inner).(HealthChecker)
+		if !ok {
+			t.Fatalf("%s keeper doesn't forward health checks", name)
+		}
+		if err := hc.HealthCheck(context.Background()); !errors.Is(err, ErrBackendUnavailable) {
+			t.Fatalf("%s health check: have %v, want %v", name, err, ErrBackendUnavailable)
+		}
+	}
+}
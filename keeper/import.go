@@ -0,0 +1,115 @@
+package keeper
+
+import (
+	"crypto/aes"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// parseRawKey validates rawKey as a 32 byte secp256k1 private key. The caller
+// has to clear the returned key after use.
+func parseRawKey(rawKey []byte) (*ecdsa.PrivateKey, error) {
+	key, err := crypto.ToECDSA(rawKey)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidKey, err)
+	}
+	return key, nil
+}
+
+// pkcs8PrivateKey is the PKCS#8 PrivateKeyInfo structure of RFC 5208.
+type pkcs8PrivateKey struct {
+	Version    int
+	Algorithm  pkix.AlgorithmIdentifier
+	PrivateKey []byte
+}
+
+// ecPrivateKey is the ECPrivateKey structure of RFC 5915. The curve is left out
+// when encoding, it is given by the enclosing PrivateKeyInfo.
+type ecPrivateKey struct {
+	Version       int
+	PrivateKey    []byte
+	NamedCurveOID asn1.ObjectIdentifier `asn1:"optional,explicit,tag:0"`
+	PublicKey     asn1.BitString        `asn1:"optional,explicit,tag:1"`
+}
+
+// marshalPKCS8PrivateKey encodes key as PKCS#8, the format KMS and HSM backends
+// import keys in. The standard library refuses to encode secp256k1 keys. The
+// caller has to clear the returned encoding after use.
+func marshalPKCS8PrivateKey(key *ecdsa.PrivateKey) ([]byte, error) {
+	params, err := asn1.Marshal(oidNamedCurveS256)
+	if err != nil {
+		return nil, err
+	}
+	secret := crypto.FromECDSA(key)
+	defer zeroBytes(secret)
+
+	pub := crypto.FromECDSAPub(&key.PublicKey)
+	inner, err := asn1.Marshal(ecPrivateKey{
+		Version:    1,
+		PrivateKey: secret,
+		PublicKey:  asn1.BitString{Bytes: pub, BitLength: 8 * len(pub)},
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer zeroBytes(inner)
+
+	return asn1.Marshal(pkcs8PrivateKey{
+		Algorithm:  pkix.AlgorithmIdentifier{Algorithm: oidPublicKeyECDSA, Parameters: asn1.RawValue{FullBytes: params}},
+		PrivateKey: inner,
+	})
+}
+
+// wrapKeyMaterial encrypts key material for import into a KMS or Vault with the
+// RSA-AES key wrapping scheme they share: an ephemeral AES-256 key encrypted with
+// RSA-OAEP using SHA-256, followed by the material wrapped with that AES key as
+// of RFC 5649.
+func wrapKeyMaterial(pub *rsa.PublicKey, material []byte) ([]byte, error) {
+	kek := make([]byte, 32)
+	if _, err := rand.Read(kek); err != nil {
+		return nil, err
+	}
+	defer zeroBytes(kek)
+
+	wrappedKEK, err := rsa.EncryptOAEP(sha256.New(), rand.Reader, pub, kek, nil)
+	if err != nil {
+		return nil, err
+	}
+	wrapped, err := aesKeyWrapPad(kek, material)
+	if err != nil {
+		return nil, err
+	}
+	return append(wrappedKEK, wrapped...), nil
+}
+
+// aesKeyWrapPad implements the AES key wrap with padding algorithm of RFC 5649.
+func aesKeyWrapPad(kek, plaintext []byte) ([]byte, error) {
+	block, err := aes.NewCipher(kek)
+	if err != nil {
+		return nil, err
+	}
+	// The alternative initial value holds the length of the plaintext, which
+	// is padded with zeros to a multiple of 8 bytes.
+	var aiv [8]byte
+	binary.BigEndian.PutUint32(aiv[:4], 0xa65959a6)
+	binary.BigEndian.PutUint32(aiv[4:], uint32(len(plaintext)))
+
+	padded := make([]byte, (len(plaintext)+7)/8*8)
+	copy(padded, plaintext)
+	defer zeroBytes(padded)
+
+	if len(padded) == 8 {
+		out := make([]byte, 16)
+		block.Encrypt(out, append(aiv[:], padded...))
+		return out, nil
+	}
+	return aesWrapBlocks(block, aiv, padded), nil
+}
@@ -0,0 +1,183 @@
+package keeper
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/asn1"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// testWrappingKey is the RSA key the fake KMS backends hand out for imports.
+var testWrappingKey = sync.OnceValue(func() *rsa.PrivateKey {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		panic(err)
+	}
+	return key
+})
+
+// aesKeyUnwrapPad reverses aesKeyWrapPad.
+func aesKeyUnwrapPad(kek, ciphertext []byte) ([]byte, error) {
+	block, err := aes.NewCipher(kek)
+	if err != nil {
+		return nil, err
+	}
+	if len(ciphertext) < 16 || len(ciphertext)%8 != 0 {
+		return nil, errors.New("invalid wrapped key length")
+	}
+	var (
+		a   [8]byte
+		out []byte
+	)
+	if len(ciphertext) == 16 {
+		buf := make([]byte, 16)
+		block.Decrypt(buf, ciphertext)
+		copy(a[:], buf[:8])
+		out = buf[8:]
+	} else {
+		a, out = aesUnwrapBlocks(block, ciphertext)
+	}
+	if binary.BigEndian.Uint32(a[:4]) != 0xa65959a6 {
+		return nil, errors.New("integrity check failed")
+	}
+	size := int(binary.BigEndian.Uint32(a[4:]))
+	if size > len(out) || len(out)-size >= 8 {
+		return nil, errors.New("invalid padded length")
+	}
+	return out[:size], nil
+}
+
+// unwrapTestKeyMaterial decrypts key material wrapped by wrapKeyMaterial for
+// testWrappingKey and parses the PKCS#8 key in it.
+func unwrapTestKeyMaterial(wrapped []byte) (*ecdsa.PrivateKey, error) {
+	priv := testWrappingKey()
+	if len(wrapped) < priv.Size() {
+		return nil, errors.New("wrapped key material too short")
+	}
+	kek, err := rsa.DecryptOAEP(sha256.New(), nil, priv, wrapped[:priv.Size()], nil)
+	if err != nil {
+		return nil, err
+	}
+	material, err := aesKeyUnwrapPad(kek, wrapped[priv.Size():])
+	if err != nil {
+		return nil, err
+	}
+	var info pkcs8PrivateKey
+	if _, err := asn1.Unmarshal(material, &info); err != nil {
+		return nil, err
+	}
+	if !info.Algorithm.Algorithm.Equal(oidPublicKeyECDSA) {
+		return nil, fmt.Errorf("unexpected key algorithm %v", info.Algorithm.Algorithm)
+	}
+	var curve asn1.ObjectIdentifier
+	if _, err := asn1.Unmarshal(info.Algorithm.Parameters.FullBytes, &curve); err != nil || !curve.Equal(oidNamedCurveS256) {
+		return nil, fmt.Errorf("unexpected curve %v", curve)
+	}
+	var ecKey ecPrivateKey
+	if _, err := asn1.Unmarshal(info.PrivateKey, &ecKey); err != nil {
+		return nil, err
+	}
+	key, err := crypto.ToECDSA(ecKey.PrivateKey)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(ecKey.PublicKey.Bytes, crypto.FromECDSAPub(&key.PublicKey)) {
+		return nil, errors.New("public key mismatch")
+	}
+	return key, nil
+}
+
+// checkImport imports a fresh key into k and checks that k signs with it. It
+// returns the prvID of the imported key.
+func checkImport(t *testing.T, k PrivateKeyKeeper) []byte {
+	t.Helper()
+
+	key, _ := crypto.GenerateKey()
+	prvID, err := k.ImportPrivateKey(crypto.FromECDSA(key))
+	if err != nil {
+		t.Fatalf("failed to import key: %v", err)
+	}
+	want := crypto.FromECDSAPub(&key.PublicKey)
+	if pub, err := k.GetPublicKey(prvID); err != nil || !bytes.Equal(pub, want) {
+		t.Fatalf("imported public key mismatch: have (%x, %v), want %x", pub, err, want)
+	}
+	hash := crypto.Keccak256([]byte("import"))
+	sig, err := k.Sign(hash, prvID)
+	if err != nil {
+		t.Fatalf("failed to sign with imported key: %v", err)
+	}
+	if recovered, err := crypto.Ecrecover(hash, sig); err != nil || !bytes.Equal(recovered, want) {
+		t.Fatalf("recovered key mismatch: have (%x, %v), want %x", recovered, err, want)
+	}
+	for _, raw := range [][]byte{nil, make([]byte, 31), make([]byte, 32), bytes.Repeat([]byte{0xff}, 32)} {
+		if _, err := k.ImportPrivateKey(raw); !errors.Is(err, ErrInvalidKey) {
+			t.Fatalf("import of %x: have %v, want %v", raw, err, ErrInvalidKey)
+		}
+	}
+	return prvID
+}
+
+func TestAESKeyWrapPad(t *testing.T) {
+	// Test vectors of RFC 5649, section 6.
+	kek, _ := hex.DecodeString("5840df6e29b02af1ab493b705bf16ea1ae8338f4dcc176a8")
+	tests := []struct {
+		key, wrapped string
+	}{
+		{"c37b7e6492584340bed12207808941155068f738", "138bdeaa9b8fa7fc61f97742e72248ee5ae6ae5360d1ae6a5f54f373fa543b6a"},
+		{"466f7250617369", "afbeb0f07dfbf5419200f2ccb50bb24f"},
+	}
+	for _, tt := range tests {
+		key, _ := hex.DecodeString(tt.key)
+		wrapped, err := aesKeyWrapPad(kek, key)
+		if err != nil {
+			t.Fatalf("failed to wrap %s: %v", tt.key, err)
+		}
+		if have := hex.EncodeToString(wrapped); have != tt.wrapped {
+			t.Fatalf("wrapped %s: have %s, want %s", tt.key, have, tt.wrapped)
+		}
+		unwrapped, err := aesKeyUnwrapPad(kek, wrapped)
+		if err != nil || !bytes.Equal(unwrapped, key) {
+			t.Fatalf("unwrapped %s: have (%x, %v), want %x", tt.key, unwrapped, err, key)
+		}
+	}
+}
+
+func TestWrapKeyMaterial(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	material, err := marshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("failed to encode key: %v", err)
+	}
+	wrapped, err := wrapKeyMaterial(&testWrappingKey().PublicKey, material)
+	if err != nil {
+		t.Fatalf("failed to wrap key: %v", err)
+	}
+	unwrapped, err := unwrapTestKeyMaterial(wrapped)
+	if err != nil {
+		t.Fatalf("failed to unwrap key: %v", err)
+	}
+	if !bytes.Equal(crypto.FromECDSA(unwrapped), crypto.FromECDSA(key)) {
+		t.Fatal("unwrapped key mismatch")
+	}
+}
+
+func TestDefaultKeeperImport(t *testing.T) {
+	var k defaultPrivateKeyKeeper
+	key, _ := crypto.GenerateKey()
+	raw := crypto.FromECDSA(key)
+	if prvID, err := k.ImportPrivateKey(raw); err != nil || !bytes.Equal(prvID, raw) {
+		t.Fatalf("imported prvID: have (%x, %v), want %x", prvID, err, raw)
+	}
+	checkImport(t, &k)
+}
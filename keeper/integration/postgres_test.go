@@ -0,0 +1,119 @@
+// Package integration holds the tests of the keeper package against real
+// services. It is a module of its own, so that the dependencies starting them
+// stay out of the go-ethereum module.
+package integration
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/keeper"
+	"github.com/ethereum/go-ethereum/keeper/keepertest"
+	"github.com/testcontainers/testcontainers-go/modules/postgres"
+)
+
+// TestPostgresMetadataStoreIntegration runs against a Postgres container, it
+// needs Docker and INTEGRATION_TEST=1.
+func TestPostgresMetadataStoreIntegration(t *testing.T) {
+	if os.Getenv("INTEGRATION_TEST") != "1" {
+		t.Skip("INTEGRATION_TEST not set")
+	}
+	ctx := context.Background()
+	container, err := postgres.Run(ctx, "postgres:16-alpine",
+		postgres.WithDatabase("keeper"),
+		postgres.WithUsername("keeper"),
+		postgres.WithPassword("keeper"),
+		postgres.BasicWaitStrategies(),
+	)
+	if err != nil {
+		t.Fatalf("failed to start postgres: %v", err)
+	}
+	defer container.Terminate(ctx)
+
+	conn, err := container.ConnectionString(ctx, "sslmode=disable", "pool_max_conns=4")
+	if err != nil {
+		t.Fatalf("failed to get connection string: %v", err)
+	}
+	store, err := keeper.NewPostgresMetadataStore(conn)
+	if err != nil {
+		t.Fatalf("failed to open store: %v", err)
+	}
+	defer store.(io.Closer).Close()
+
+	// Keys get their metadata on generation and lose it on deletion.
+	k := keeper.NewMetadataAwareKeeper(keepertest.NewMockKeeper(), store).(keeper.MetadataAwareKeeper)
+	prvID, err := k.GeneratePrivateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	meta, err := k.GetMetadata(prvID)
+	if err != nil || meta.Created.IsZero() || meta.Label == "" {
+		t.Fatalf("generated metadata: have (%+v, %v), want created and labelled", meta, err)
+	}
+	meta.Purpose = "payouts"
+	meta.ExpiresAt = time.Now().Add(time.Hour).UTC()
+	if err := k.SetMetadata(prvID, meta); err != nil {
+		t.Fatalf("failed to set metadata: %v", err)
+	}
+	if have, err := k.GetMetadata(prvID); err != nil || have.Purpose != "payouts" || !have.ExpiresAt.Equal(meta.ExpiresAt) {
+		t.Fatalf("updated metadata: have (%+v, %v), want %+v", have, err, meta)
+	}
+	if err := k.DeletePrivateKey(prvID); err != nil {
+		t.Fatalf("failed to delete key: %v", err)
+	}
+	if _, err := k.GetMetadata(prvID); !errors.Is(err, keeper.ErrKeyNotFound) {
+		t.Fatalf("metadata of deleted key: have %v, want %v", err, keeper.ErrKeyNotFound)
+	}
+
+	// Stores opened on a migrated database don't migrate it again.
+	reopened, err := keeper.NewPostgresMetadataStore(conn)
+	if err != nil {
+		t.Fatalf("failed to reopen store: %v", err)
+	}
+	reopened.(io.Closer).Close()
+
+	// Concurrent uses never exceed the limit.
+	counter := store.(keeper.UseCounter)
+	if err := store.Set([]byte("session"), keeper.KeyMetadata{MaxUses: 10}); err != nil {
+		t.Fatalf("failed to set metadata: %v", err)
+	}
+	var (
+		wg        sync.WaitGroup
+		lock      sync.Mutex
+		granted   int
+		exhausted int
+	)
+	for i := 0; i < 32; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := counter.IncrementUses([]byte("session"))
+
+			lock.Lock()
+			defer lock.Unlock()
+			switch {
+			case err == nil:
+				granted++
+			case errors.Is(err, keeper.ErrKeyExhausted):
+				exhausted++
+			default:
+				t.Errorf("failed to count use: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	if granted != 10 || exhausted != 22 {
+		t.Fatalf("uses mismatch: have %d granted and %d exhausted, want 10 and 22", granted, exhausted)
+	}
+	if meta, err := store.Get([]byte("session")); err != nil || meta.Uses != 10 {
+		t.Fatalf("counted uses: have (%+v, %v), want 10", meta, err)
+	}
+	if _, err := counter.IncrementUses([]byte("unknown")); !errors.Is(err, keeper.ErrKeyNotFound) {
+		t.Fatalf("use of unknown key: have %v, want %v", err, keeper.ErrKeyNotFound)
+	}
+}
@@ -0,0 +1,5 @@
+// Package shamir is a copy of the shamir package of Vault v1.21.4,
+// github.com/hashicorp/vault/shamir, which keeper vendors instead of depending
+// on the whole Vault module. The files taken from Vault are under the MPL-2.0
+// license found in LICENSE.
+package shamir
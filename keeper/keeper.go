@@ -1,20 +1,164 @@
 package keeper
 
 import (
+	"context"
 	"crypto/ecdsa"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
 )
 
 // PrivateKeyKeeper is layer for protecting private key from direct using.
+//
+// The keepers of the package are safe for concurrent use by multiple
+// goroutines. Keepers holding local state guard it with a lock, and keepers
+// talking to a device with a single session, such as PKCS#11 tokens, TPMs and
+// Ledgers, serialise the calls to it. The prvIDs handed out by
+// GeneratePrivateKey are unique without coordination between callers: they are
+// random keys, UUIDs or names assigned by the backing service. Concurrent
+// generation never yields the same prvID twice, short of a collision of 256 or
+// 122 bit random values; GenerateUniqueKey checks for such collisions where the
+// keeper can list its keys.
 type PrivateKeyKeeper interface {
 	// GeneratePrivateKey return identifier of new generated private key
 	GeneratePrivateKey() (prvID []byte, err error)
-	// GetPublicKey return public key by private key ID
+	// GetPublicKey return public key by private key ID, in the 65 byte
+	// uncompressed form 0x04 || X || Y
 	GetPublicKey(prvID []byte) ([]byte, error)
 	// Sign of data by private key ID
 	Sign(data []byte, prvID []byte) ([]byte, error)
+	// DeletePrivateKey destroy private key by private key ID
+	DeletePrivateKey(prvID []byte) error
+	// ListPrivateKeys return identifiers of all private keys in the keeper
+	ListPrivateKeys() ([][]byte, error)
+	// ImportPrivateKey stores the raw 32 byte private key and return its
+	// identifier, failing with ErrInvalidKey for malformed keys
+	ImportPrivateKey(rawKey []byte) (prvID []byte, err error)
+}
+
+// PrivateKeyKeeperContext is the context-aware variant of PrivateKeyKeeper. It
+// lets callers cancel or put a deadline on operations against keepers backed by
+// slow or remote services such as an HSM or a KMS.
+type PrivateKeyKeeperContext interface {
+	// GeneratePrivateKeyContext return identifier of new generated private key
+	GeneratePrivateKeyContext(ctx context.Context) (prvID []byte, err error)
+	// GetPublicKeyContext return public key by private key ID, in the 65 byte
+	// uncompressed form 0x04 || X || Y
+	GetPublicKeyContext(ctx context.Context, prvID []byte) ([]byte, error)
+	// SignContext of data by private key ID
+	SignContext(ctx context.Context, data []byte, prvID []byte) ([]byte, error)
+	// DeletePrivateKeyContext destroy private key by private key ID
+	DeletePrivateKeyContext(ctx context.Context, prvID []byte) error
+	// ListPrivateKeysContext return identifiers of all private keys in the keeper
+	ListPrivateKeysContext(ctx context.Context) ([][]byte, error)
+	// ImportPrivateKeyContext stores the raw 32 byte private key and return its
+	// identifier, failing with ErrInvalidKey for malformed keys
+	ImportPrivateKeyContext(ctx context.Context, rawKey []byte) (prvID []byte, err error)
+}
+
+// ContextKeeper returns the context-aware view of k. Keepers that implement
+// PrivateKeyKeeperContext natively are returned as is, other keepers are wrapped
+// so that a done context is reported before and after forwarding the call.
+func ContextKeeper(k PrivateKeyKeeper) PrivateKeyKeeperContext {
+	if kc, ok := k.(PrivateKeyKeeperContext); ok {
+		return kc
+	}
+	return &contextKeeper{k}
+}
+
+// contextKeeper adapts a PrivateKeyKeeper without context support.
+type contextKeeper struct {
+	keeper PrivateKeyKeeper
+}
+
+func (c *contextKeeper) GeneratePrivateKeyContext(ctx context.Context) (_ []byte, err error) {
+	defer wrapError(&err, "generate key", nil)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	prvID, err := c.keeper.GeneratePrivateKey()
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return nil, ctxErr
+	}
+	return prvID, err
+}
+
+func (c *contextKeeper) GetPublicKeyContext(ctx context.Context, prvID []byte) (_ []byte, err error) {
+	defer wrapError(&err, "get public key", prvID)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	pub, err := c.keeper.GetPublicKey(prvID)
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return nil, ctxErr
+	}
+	return pub, err
+}
+
+func (c *contextKeeper) SignContext(ctx context.Context, data []byte, prvID []byte) (_ []byte, err error) {
+	defer wrapError(&err, "sign", prvID)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	sig, err := c.keeper.Sign(data, prvID)
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return nil, ctxErr
+	}
+	return sig, err
+}
+
+func (c *contextKeeper) DeletePrivateKeyContext(ctx context.Context, prvID []byte) (err error) {
+	defer wrapError(&err, "delete key", prvID)
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return c.keeper.DeletePrivateKey(prvID)
+}
+
+func (c *contextKeeper) ListPrivateKeysContext(ctx context.Context) (_ [][]byte, err error) {
+	defer wrapError(&err, "list keys", nil)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	prvIDs, err := c.keeper.ListPrivateKeys()
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return nil, ctxErr
+	}
+	return prvIDs, err
+}
+
+func (c *contextKeeper) ImportPrivateKeyContext(ctx context.Context, rawKey []byte) (_ []byte, err error) {
+	defer wrapError(&err, "import key", nil)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	prvID, err := c.keeper.ImportPrivateKey(rawKey)
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return nil, ctxErr
+	}
+	return prvID, err
+}
+
+// AddressFromKeeper returns the Ethereum address of the key prvID held by k.
+func AddressFromKeeper(k PrivateKeyKeeper, prvID []byte) (_ common.Address, err error) {
+	defer wrapError(&err, "get address", prvID)
+	pub, err := k.GetPublicKey(prvID)
+	if err != nil {
+		return common.Address{}, err
+	}
+	key, err := ParsePublicKey(pub)
+	if err != nil {
+		return common.Address{}, err
+	}
+	return crypto.PubkeyToAddress(*key), nil
 }
 
 // defaultKeeper realized interface PrivateKeyKeeper without hiding the private key
@@ -24,19 +168,42 @@ type defaultPrivateKeyKeeper struct {
 }
 
 func (a *defaultPrivateKeyKeeper) GeneratePrivateKey() ([]byte, error) {
+	return a.GeneratePrivateKeyContext(context.Background())
+}
+
+func (a *defaultPrivateKeyKeeper) GeneratePrivateKeyContext(ctx context.Context) (_ []byte, err error) {
+	defer wrapError(&err, "generate key", nil)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 	privateKey, err := crypto.GenerateKey()
 	if err != nil {
 		return nil, err
 	}
+	defer ZeroKey(privateKey)
+	// Reading the system entropy source may block, don't hand out a key the
+	// caller has already given up on.
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 	privateKeyBytes := crypto.FromECDSA(privateKey)
 	return privateKeyBytes, nil
 }
 
 func (a *defaultPrivateKeyKeeper) GetPublicKey(prvID []byte) ([]byte, error) {
+	return a.GetPublicKeyContext(context.Background(), prvID)
+}
+
+func (a *defaultPrivateKeyKeeper) GetPublicKeyContext(ctx context.Context, prvID []byte) (_ []byte, err error) {
+	defer wrapError(&err, "get public key", prvID)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 	privateKey, err := crypto.ToECDSA(prvID)
 	if err != nil {
 		return nil, err
 	}
+	defer ZeroKey(privateKey)
 	publicKeyECDSA, ok := privateKey.Public().(*ecdsa.PublicKey)
 	if !ok {
 		return nil, errors.New("cannot cast public key to ecsda public key")
@@ -45,31 +212,252 @@ func (a *defaultPrivateKeyKeeper) GetPublicKey(prvID []byte) ([]byte, error) {
 }
 
 func (a *defaultPrivateKeyKeeper) Sign(data []byte, prvID []byte) ([]byte, error) {
+	return a.SignContext(context.Background(), data, prvID)
+}
+
+func (a *defaultPrivateKeyKeeper) SignContext(ctx context.Context, data []byte, prvID []byte) (_ []byte, err error) {
+	defer wrapError(&err, "sign", prvID)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 	prv, err := crypto.ToECDSA(prvID)
 	if err != nil {
 		return nil, err
 	}
+	defer ZeroKey(prv)
 	sig, err := crypto.Sign(data, prv)
 	if err != nil {
 		return nil, err
 	}
-	return sig, nil
+	// crypto.Sign produces canonical signatures already, normalising keeps the
+	// keeper correct should its signing implementation change.
+	return NormaliseSignature(sig)
+}
+
+// DeletePrivateKey is a no-op, the keeper doesn't store any keys.
+func (a *defaultPrivateKeyKeeper) DeletePrivateKey(prvID []byte) error {
+	return a.DeletePrivateKeyContext(context.Background(), prvID)
+}
+
+func (a *defaultPrivateKeyKeeper) DeletePrivateKeyContext(ctx context.Context, prvID []byte) (err error) {
+	defer wrapError(&err, "delete key", prvID)
+	return ctx.Err()
+}
+
+// ListPrivateKeys returns ErrNotSupported, the keeper doesn't store any keys.
+func (a *defaultPrivateKeyKeeper) ListPrivateKeys() ([][]byte, error) {
+	return a.ListPrivateKeysContext(context.Background())
+}
+
+func (a *defaultPrivateKeyKeeper) ListPrivateKeysContext(ctx context.Context) (_ [][]byte, err error) {
+	defer wrapError(&err, "list keys", nil)
+	return nil, ErrNotSupported
+}
+
+func (a *defaultPrivateKeyKeeper) ImportPrivateKey(rawKey []byte) ([]byte, error) {
+	return a.ImportPrivateKeyContext(context.Background(), rawKey)
+}
+
+// ImportPrivateKeyContext returns a copy of rawKey, the raw key is the prvID of
+// the keeper.
+func (a *defaultPrivateKeyKeeper) ImportPrivateKeyContext(ctx context.Context, rawKey []byte) (_ []byte, err error) {
+	defer wrapError(&err, "import key", nil)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	key, err := parseRawKey(rawKey)
+	if err != nil {
+		return nil, err
+	}
+	ZeroKey(key)
+	return common.CopyBytes(rawKey), nil
+}
+
+// ExportEncryptedKey encrypts the key prvID, which is the raw key itself.
+func (a *defaultPrivateKeyKeeper) ExportEncryptedKey(prvID []byte, passphrase string) (_ []byte, err error) {
+	defer wrapError(&err, "export key", prvID)
+	return encryptKey(prvID, passphrase)
+}
+
+func (a *defaultPrivateKeyKeeper) ImportEncryptedKey(data []byte, passphrase string) ([]byte, error) {
+	return importEncryptedKey(a, data, passphrase)
+}
+
+// WrapKey wraps the key prvID, which is the raw key itself, with AES-256 key wrap
+// (RFC 3394). The kekID is the raw 32 byte KEK, like the prvID is the raw key.
+func (a *defaultPrivateKeyKeeper) WrapKey(prvID []byte, kekID []byte) (_ []byte, err error) {
+	defer wrapError(&err, "wrap key", prvID)
+
+	key, err := parseRawKey(prvID)
+	if err != nil {
+		return nil, err
+	}
+	ZeroKey(key)
+	return aesKeyWrap(kekID, prvID)
+}
+
+// UnwrapKey unwraps a key wrapped with WrapKey, returning the raw key as its
+// prvID.
+func (a *defaultPrivateKeyKeeper) UnwrapKey(wrappedKey []byte, kekID []byte) (_ []byte, err error) {
+	defer wrapError(&err, "unwrap key", nil)
+
+	rawKey, err := aesKeyUnwrap(kekID, wrappedKey)
+	if err != nil {
+		return nil, err
+	}
+	key, err := parseRawKey(rawKey)
+	if err != nil {
+		zeroBytes(rawKey)
+		return nil, err
+	}
+	ZeroKey(key)
+	return rawKey, nil
+}
+
+// SecureSigner signs transactions with keys held by a PrivateKeyKeeper, so that
+// callers only ever handle private key identifiers.
+type SecureSigner interface {
+	// GenerateKey return identifier of new generated private key
+	GenerateKey() ([]byte, error)
+	// GetPublicKey return 65 byte uncompressed public key by private key ID
+	GetPublicKey(prvID []byte) ([]byte, error)
+	// GetPublicKeyCompressed return 33 byte compressed public key by private key ID
+	GetPublicKeyCompressed(prvID []byte) ([]byte, error)
+	// Sign return copy of the transaction signed by private key ID
+	Sign(tx *types.Transaction, s types.Signer, prvID []byte) (*types.Transaction, error)
+	// SignTypedData return EIP-712 signature of the typed data by private key ID
+	SignTypedData(typedData apitypes.TypedData, prvID []byte) ([]byte, error)
+	// SignPersonalMessage return EIP-191 signature of the message by private key ID
+	SignPersonalMessage(message []byte, prvID []byte) ([]byte, error)
+	// SignHash return signature of the 32 byte hash by private key ID
+	SignHash(hash common.Hash, prvID []byte) ([]byte, error)
+	// SignHashBytes return signature of the keccak256 hash of the data by private key ID
+	SignHashBytes(data []byte, prvID []byte) ([]byte, error)
+	// SignUserOperation return ERC-4337 user operation signature by private key ID
+	SignUserOperation(chainID *big.Int, entryPoint common.Address, op UserOperation, prvID []byte) ([]byte, error)
+	// SignPermit return EIP-2612 permit signature of the token owner by private key ID
+	SignPermit(chainID *big.Int, tokenAddr common.Address, tokenName, tokenVersion string, ownerAddr, spenderAddr common.Address, value, nonce *big.Int, deadline int64, prvID []byte) (v uint8, r, s [32]byte, err error)
+	// VerifyPersonalMessage check that the EIP-191 signature of the message was
+	// made by the expected address
+	VerifyPersonalMessage(message, sig []byte, expectedAddr common.Address) error
+	// SignForChain return copy of the transaction signed for the chain ID by private
+	// key ID, with the latest signer of the chain
+	SignForChain(chainID *big.Int, tx *types.Transaction, prvID []byte) (*types.Transaction, error)
+	// SignTransactionJSON return eth_signTransaction result of the transaction
+	// signed by private key ID
+	SignTransactionJSON(tx *types.Transaction, s types.Signer, prvID []byte) (json.RawMessage, error)
+	// SignAndEncode return EIP-2718 encoding of the transaction signed by
+	// private key ID, ready for eth_sendRawTransaction
+	SignAndEncode(tx *types.Transaction, s types.Signer, prvID []byte) ([]byte, error)
+	// SignAndEncodeHex return 0x prefixed hex of the SignAndEncode encoding
+	SignAndEncodeHex(tx *types.Transaction, s types.Signer, prvID []byte) (string, error)
+	// SignDynamicFeeTx return new EIP-1559 transaction signed by private key ID
+	SignDynamicFeeTx(chainID *big.Int, nonce uint64, to common.Address, value *big.Int, gasLimit uint64, maxFeePerGas, maxPriorityFeePerGas *big.Int, data []byte, prvID []byte) (*types.Transaction, error)
+	// SignBlobTx return new EIP-4844 transaction signed by private key ID
+	SignBlobTx(chainID *big.Int, blobTx *types.BlobTx, prvID []byte) (*types.Transaction, error)
+	// SignAccessListTx return new EIP-2930 transaction signed by private key ID
+	SignAccessListTx(chainID *big.Int, nonce uint64, to common.Address, value *big.Int, gasLimit uint64, gasPrice *big.Int, accessList types.AccessList, data []byte, prvID []byte) (*types.Transaction, error)
+	// SignContractCall return new legacy transaction calling the method of the
+	// contract with the ABI-encoded args, signed by private key ID
+	SignContractCall(chainID *big.Int, contract common.Address, contractABI abi.ABI, method string, args []interface{}, nonce uint64, gasLimit uint64, gasPrice *big.Int, prvID []byte) (*types.Transaction, error)
+	// SimulateAndSign return copy of the transaction signed by private key ID
+	// if its eth_call through the client doesn't revert, *RevertError otherwise
+	SimulateAndSign(ctx context.Context, tx *types.Transaction, s types.Signer, client ethereum.ContractCaller, prvID []byte) (*types.Transaction, error)
+	// SignBatch return copies of the transactions signed in sequence by private key ID
+	SignBatch(txs []*types.Transaction, s types.Signer, prvID []byte) ([]*types.Transaction, error)
+	// SignBatchParallel return copies of the transactions signed concurrently by private key ID
+	SignBatchParallel(txs []*types.Transaction, s types.Signer, prvID []byte) ([]*types.Transaction, error)
+	// GetAddress return Ethereum address of the key by private key ID
+	GetAddress(prvID []byte) (common.Address, error)
+	// VerifySignature check that the signature of the data was made by private key ID
+	VerifySignature(data, sig, prvID []byte) (bool, error)
+	// RecoverSigner return Ethereum address of the signer of the data
+	RecoverSigner(data, sig []byte) (common.Address, error)
+	// RecoverSenderFromTx return Ethereum address of the sender of the signed transaction
+	RecoverSenderFromTx(tx *types.Transaction, s types.Signer) (common.Address, error)
+}
+
+// SecureSignerContext is the context-aware variant of SecureSigner.
+type SecureSignerContext interface {
+	// GenerateKeyContext return identifier of new generated private key
+	GenerateKeyContext(ctx context.Context) ([]byte, error)
+	// GetPublicKeyContext return public key by private key ID
+	GetPublicKeyContext(ctx context.Context, prvID []byte) ([]byte, error)
+	// SignContext return copy of the transaction signed by private key ID
+	SignContext(ctx context.Context, tx *types.Transaction, s types.Signer, prvID []byte) (*types.Transaction, error)
+}
+
+// signerContext returns the context-aware view of s, the same way ContextKeeper
+// does for keepers.
+func signerContext(s SecureSigner) SecureSignerContext {
+	if sc, ok := s.(SecureSignerContext); ok {
+		return sc
+	}
+	return &contextSigner{s}
+}
+
+// contextSigner adapts a SecureSigner without context support.
+type contextSigner struct {
+	signer SecureSigner
+}
+
+func (c *contextSigner) GenerateKeyContext(ctx context.Context) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return c.signer.GenerateKey()
+}
+
+func (c *contextSigner) GetPublicKeyContext(ctx context.Context, prvID []byte) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return c.signer.GetPublicKey(prvID)
+}
+
+func (c *contextSigner) SignContext(ctx context.Context, tx *types.Transaction, s types.Signer, prvID []byte) (*types.Transaction, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return c.signer.Sign(tx, s, prvID)
 }
 
 type SecureSign struct {
-	keeper PrivateKeyKeeper
+	keeper  PrivateKeyKeeper
+	hashing HashingStrategy
+}
+
+// NewSecureSign returns a SecureSign backed by keeper, configured by opts such
+// as WithHashingStrategy. The options wrapping the SecureSign in decorators
+// are for NewSecureSigner.
+func NewSecureSign(keeper PrivateKeyKeeper, opts ...HashingOption) SecureSign {
+	sec := SecureSign{keeper: keeper}
+	for _, opt := range opts {
+		opt(&sec)
+	}
+	return sec
 }
 
-func NewSecureSign(keeper PrivateKeyKeeper) SecureSign {
-	return SecureSign{keeper: keeper}
+// NewSecureSigner returns a SecureSigner backed by keeper, configured by opts.
+// Without options it is a plain SecureSign.
+func NewSecureSigner(keeper PrivateKeyKeeper, opts ...SecureSignOption) SecureSigner {
+	cfg := newSecureSignConfig(opts)
+	sec := cfg.sec
+	sec.keeper = keeper
+	return cfg.wrap(&sec)
 }
 
 func DefaultSecureSign() SecureSign {
-	return SecureSign{defaultKeeper}
+	return SecureSign{keeper: defaultKeeper}
 }
 
 func (sec *SecureSign) GenerateKey() ([]byte, error) {
-	prvID, err := sec.keeper.GeneratePrivateKey()
+	return sec.GenerateKeyContext(context.Background())
+}
+
+func (sec *SecureSign) GenerateKeyContext(ctx context.Context) (_ []byte, err error) {
+	defer wrapError(&err, "generate key", nil)
+	prvID, err := ContextKeeper(sec.keeper).GeneratePrivateKeyContext(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -77,16 +465,46 @@ func (sec *SecureSign) GenerateKey() ([]byte, error) {
 }
 
 func (sec *SecureSign) GetPublicKey(prvID []byte) ([]byte, error) {
-	pbl, err := sec.keeper.GetPublicKey(prvID)
+	return sec.GetPublicKeyContext(context.Background(), prvID)
+}
+
+func (sec *SecureSign) GetPublicKeyContext(ctx context.Context, prvID []byte) (_ []byte, err error) {
+	defer wrapError(&err, "get public key", prvID)
+	pbl, err := ContextKeeper(sec.keeper).GetPublicKeyContext(ctx, prvID)
 	if err != nil {
 		return nil, err
 	}
 	return pbl, nil
 }
 
+// GetAddress returns the Ethereum address of the key prvID, e.g. to fill in the
+// sender of a transaction before signing it.
+func (sec *SecureSign) GetAddress(prvID []byte) (common.Address, error) {
+	return AddressFromKeeper(sec.keeper, prvID)
+}
+
 func (sec *SecureSign) Sign(tx *types.Transaction, s types.Signer, prvID []byte) (*types.Transaction, error) {
-	h := s.Hash(tx)
-	sig, err := sec.keeper.Sign(h[:], prvID)
+	return sec.SignContext(context.Background(), tx, s, prvID)
+}
+
+func (sec *SecureSign) SignContext(ctx context.Context, tx *types.Transaction, s types.Signer, prvID []byte) (_ *types.Transaction, err error) {
+	defer wrapError(&err, "sign transaction", prvID)
+
+	var sig []byte
+	if sec.hashing == HashByKeeper {
+		rs, ok := sec.keeper.(RawSigner)
+		if !ok {
+			return nil, fmt.Errorf("%w: keeper doesn't hash transactions itself", ErrNotSupported)
+		}
+		preimage, err := signingPreimage(tx, s)
+		if err != nil {
+			return nil, err
+		}
+		sig, err = rs.SignRaw(ctx, preimage, prvID)
+	} else {
+		h := s.Hash(tx)
+		sig, err = ContextKeeper(sec.keeper).SignContext(ctx, h[:], prvID)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,185 @@
+package keeper
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// countingKeeper is a PrivateKeyKeeper without context support that records
+// how many calls reached it.
+type countingKeeper struct {
+	inner defaultPrivateKeyKeeper
+	calls int
+}
+
+func (c *countingKeeper) GeneratePrivateKey() ([]byte, error) {
+	c.calls++
+	return c.inner.GeneratePrivateKey()
+}
+
+func (c *countingKeeper) GetPublicKey(prvID []byte) ([]byte, error) {
+	c.calls++
+	return c.inner.GetPublicKey(prvID)
+}
+
+func (c *countingKeeper) Sign(data []byte, prvID []byte) ([]byte, error) {
+	c.calls++
+	return c.inner.Sign(data, prvID)
+}
+
+func (c *countingKeeper) DeletePrivateKey(prvID []byte) error {
+	c.calls++
+	return c.inner.DeletePrivateKey(prvID)
+}
+
+func (c *countingKeeper) ListPrivateKeys() ([][]byte, error) {
+	c.calls++
+	return c.inner.ListPrivateKeys()
+}
+
+func (c *countingKeeper) ImportPrivateKey(rawKey []byte) ([]byte, error) {
+	c.calls++
+	return c.inner.ImportPrivateKey(rawKey)
+}
+
+func newTestTx() *types.Transaction {
+	return types.NewTx(&types.LegacyTx{
+		Nonce:    1,
+		To:       &common.Address{0x01},
+		Value:    big.NewInt(1),
+		Gas:      21000,
+		GasPrice: big.NewInt(1),
+	})
+}
+
+func TestSecureSign(t *testing.T) {
+	sec := DefaultSecureSign()
+	prvID, err := sec.GenerateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	pub, err := sec.GetPublicKey(prvID)
+	if err != nil {
+		t.Fatalf("failed to get public key: %v", err)
+	}
+	key, err := crypto.UnmarshalPubkey(pub)
+	if err != nil {
+		t.Fatalf("invalid public key: %v", err)
+	}
+	signer := types.NewEIP155Signer(big.NewInt(1))
+	tx, err := sec.Sign(newTestTx(), signer, prvID)
+	if err != nil {
+		t.Fatalf("failed to sign transaction: %v", err)
+	}
+	from, err := types.Sender(signer, tx)
+	if err != nil {
+		t.Fatalf("failed to recover sender: %v", err)
+	}
+	if want := crypto.PubkeyToAddress(*key); from != want {
+		t.Fatalf("sender mismatch: have %x, want %x", from, want)
+	}
+}
+
+func TestDefaultKeeperContextCanceled(t *testing.T) {
+	k := &defaultPrivateKeyKeeper{}
+	prvID, err := k.GeneratePrivateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if id, err := k.GeneratePrivateKeyContext(ctx); !errors.Is(err, context.Canceled) || id != nil {
+		t.Errorf("GeneratePrivateKeyContext: have (%x, %v), want (nil, %v)", id, err, context.Canceled)
+	}
+	if pub, err := k.GetPublicKeyContext(ctx, prvID); !errors.Is(err, context.Canceled) || pub != nil {
+		t.Errorf("GetPublicKeyContext: have (%x, %v), want (nil, %v)", pub, err, context.Canceled)
+	}
+	if sig, err := k.SignContext(ctx, make([]byte, 32), prvID); !errors.Is(err, context.Canceled) || sig != nil {
+		t.Errorf("SignContext: have (%x, %v), want (nil, %v)", sig, err, context.Canceled)
+	}
+}
+
+func TestDefaultKeeperListNotSupported(t *testing.T) {
+	if ids, err := defaultKeeper.ListPrivateKeys(); !errors.Is(err, ErrNotSupported) || ids != nil {
+		t.Fatalf("have (%v, %v), want (nil, %v)", ids, err, ErrNotSupported)
+	}
+}
+
+func TestContextKeeperCanceled(t *testing.T) {
+	inner := new(countingKeeper)
+	prvID, err := inner.GeneratePrivateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	inner.calls = 0
+
+	k := ContextKeeper(inner)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := k.GeneratePrivateKeyContext(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("GeneratePrivateKeyContext: have %v, want %v", err, context.Canceled)
+	}
+	if _, err := k.GetPublicKeyContext(ctx, prvID); !errors.Is(err, context.Canceled) {
+		t.Errorf("GetPublicKeyContext: have %v, want %v", err, context.Canceled)
+	}
+	if _, err := k.SignContext(ctx, make([]byte, 32), prvID); !errors.Is(err, context.Canceled) {
+		t.Errorf("SignContext: have %v, want %v", err, context.Canceled)
+	}
+	if inner.calls != 0 {
+		t.Fatalf("canceled calls reached the keeper: %d", inner.calls)
+	}
+	if _, err := k.SignContext(context.Background(), make([]byte, 32), prvID); err != nil {
+		t.Fatalf("failed to sign: %v", err)
+	}
+	if inner.calls != 1 {
+		t.Fatalf("call count mismatch: have %d, want 1", inner.calls)
+	}
+}
+
+func TestSecureSignContextCanceled(t *testing.T) {
+	sec := NewSecureSigner(&defaultPrivateKeyKeeper{}).(SecureSignerContext)
+	prvID, err := sec.GenerateKeyContext(context.Background())
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := sec.GenerateKeyContext(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("GenerateKeyContext: have %v, want %v", err, context.Canceled)
+	}
+	if _, err := sec.GetPublicKeyContext(ctx, prvID); !errors.Is(err, context.Canceled) {
+		t.Errorf("GetPublicKeyContext: have %v, want %v", err, context.Canceled)
+	}
+	tx, err := sec.SignContext(ctx, newTestTx(), types.HomesteadSigner{}, prvID)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("SignContext: have %v, want %v", err, context.Canceled)
+	}
+	if tx != nil {
+		t.Errorf("SignContext returned a transaction for a canceled context")
+	}
+}
+
+func TestGetAddress(t *testing.T) {
+	sec := DefaultSecureSign()
+	prvID := crypto.Keccak256([]byte("cow"))
+	want := common.HexToAddress("0xCD2a3d9F938E13CD947Ec05AbC7FE734Df8DD826")
+
+	if addr, err := sec.GetAddress(prvID); err != nil || addr != want {
+		t.Fatalf("GetAddress: have (%v, %v), want %v", addr, err, want)
+	}
+	if addr, err := AddressFromKeeper(defaultKeeper, prvID); err != nil || addr != want {
+		t.Fatalf("AddressFromKeeper: have (%v, %v), want %v", addr, err, want)
+	}
+	if _, err := sec.GetAddress([]byte{0x01}); err == nil {
+		t.Fatal("derived address of invalid key")
+	}
+}
@@ -0,0 +1,310 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.4.0
+// - protoc             (unknown)
+// source: keeperpb/keeper.proto
+
+package keeperpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.62.0 or later.
+const _ = grpc.SupportPackageIsVersion8
+
+const (
+	Keeper_GeneratePrivateKey_FullMethodName = "/keeper.Keeper/GeneratePrivateKey"
+	Keeper_GetPublicKey_FullMethodName       = "/keeper.Keeper/GetPublicKey"
+	Keeper_Sign_FullMethodName               = "/keeper.Keeper/Sign"
+	Keeper_DeletePrivateKey_FullMethodName   = "/keeper.Keeper/DeletePrivateKey"
+	Keeper_ListPrivateKeys_FullMethodName    = "/keeper.Keeper/ListPrivateKeys"
+	Keeper_ImportPrivateKey_FullMethodName   = "/keeper.Keeper/ImportPrivateKey"
+)
+
+// KeeperClient is the client API for Keeper service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// Keeper is the remote signing protocol of the keeper package, mirroring the
+// PrivateKeyKeeper interface. Private key identifiers, public keys and
+// signatures are handed over in the same format as returned by the keeper
+// serving them.
+type KeeperClient interface {
+	GeneratePrivateKey(ctx context.Context, in *GeneratePrivateKeyRequest, opts ...grpc.CallOption) (*GeneratePrivateKeyResponse, error)
+	GetPublicKey(ctx context.Context, in *GetPublicKeyRequest, opts ...grpc.CallOption) (*GetPublicKeyResponse, error)
+	Sign(ctx context.Context, in *SignRequest, opts ...grpc.CallOption) (*SignResponse, error)
+	DeletePrivateKey(ctx context.Context, in *DeletePrivateKeyRequest, opts ...grpc.CallOption) (*DeletePrivateKeyResponse, error)
+	ListPrivateKeys(ctx context.Context, in *ListPrivateKeysRequest, opts ...grpc.CallOption) (*ListPrivateKeysResponse, error)
+	ImportPrivateKey(ctx context.Context, in *ImportPrivateKeyRequest, opts ...grpc.CallOption) (*ImportPrivateKeyResponse, error)
+}
+
+type keeperClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewKeeperClient(cc grpc.ClientConnInterface) KeeperClient {
+	return &keeperClient{cc}
+}
+
+func (c *keeperClient) GeneratePrivateKey(ctx context.Context, in *GeneratePrivateKeyRequest, opts ...grpc.CallOption) (*GeneratePrivateKeyResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GeneratePrivateKeyResponse)
+	err := c.cc.Invoke(ctx, Keeper_GeneratePrivateKey_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *keeperClient) GetPublicKey(ctx context.Context, in *GetPublicKeyRequest, opts ...grpc.CallOption) (*GetPublicKeyResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetPublicKeyResponse)
+	err := c.cc.Invoke(ctx, Keeper_GetPublicKey_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *keeperClient) Sign(ctx context.Context, in *SignRequest, opts ...grpc.CallOption) (*SignResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SignResponse)
+	err := c.cc.Invoke(ctx, Keeper_Sign_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *keeperClient) DeletePrivateKey(ctx context.Context, in *DeletePrivateKeyRequest, opts ...grpc.CallOption) (*DeletePrivateKeyResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DeletePrivateKeyResponse)
+	err := c.cc.Invoke(ctx, Keeper_DeletePrivateKey_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *keeperClient) ListPrivateKeys(ctx context.Context, in *ListPrivateKeysRequest, opts ...grpc.CallOption) (*ListPrivateKeysResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListPrivateKeysResponse)
+	err := c.cc.Invoke(ctx, Keeper_ListPrivateKeys_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *keeperClient) ImportPrivateKey(ctx context.Context, in *ImportPrivateKeyRequest, opts ...grpc.CallOption) (*ImportPrivateKeyResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ImportPrivateKeyResponse)
+	err := c.cc.Invoke(ctx, Keeper_ImportPrivateKey_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// KeeperServer is the server API for Keeper service.
+// All implementations must embed UnimplementedKeeperServer
+// for forward compatibility
+//
+// Keeper is the remote signing protocol of the keeper package, mirroring the
+// PrivateKeyKeeper interface. Private key identifiers, public keys and
+// signatures are handed over in the same format as returned by the keeper
+// serving them.
+type KeeperServer interface {
+	GeneratePrivateKey(context.Context, *GeneratePrivateKeyRequest) (*GeneratePrivateKeyResponse, error)
+	GetPublicKey(context.Context, *GetPublicKeyRequest) (*GetPublicKeyResponse, error)
+	Sign(context.Context, *SignRequest) (*SignResponse, error)
+	DeletePrivateKey(context.Context, *DeletePrivateKeyRequest) (*DeletePrivateKeyResponse, error)
+	ListPrivateKeys(context.Context, *ListPrivateKeysRequest) (*ListPrivateKeysResponse, error)
+	ImportPrivateKey(context.Context, *ImportPrivateKeyRequest) (*ImportPrivateKeyResponse, error)
+	mustEmbedUnimplementedKeeperServer()
+}
+
+// UnimplementedKeeperServer must be embedded to have forward compatible implementations.
+type UnimplementedKeeperServer struct {
+}
+
+func (UnimplementedKeeperServer) GeneratePrivateKey(context.Context, *GeneratePrivateKeyRequest) (*GeneratePrivateKeyResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GeneratePrivateKey not implemented")
+}
+func (UnimplementedKeeperServer) GetPublicKey(context.Context, *GetPublicKeyRequest) (*GetPublicKeyResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetPublicKey not implemented")
+}
+func (UnimplementedKeeperServer) Sign(context.Context, *SignRequest) (*SignResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Sign not implemented")
+}
+func (UnimplementedKeeperServer) DeletePrivateKey(context.Context, *DeletePrivateKeyRequest) (*DeletePrivateKeyResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeletePrivateKey not implemented")
+}
+func (UnimplementedKeeperServer) ListPrivateKeys(context.Context, *ListPrivateKeysRequest) (*ListPrivateKeysResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListPrivateKeys not implemented")
+}
+func (UnimplementedKeeperServer) ImportPrivateKey(context.Context, *ImportPrivateKeyRequest) (*ImportPrivateKeyResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ImportPrivateKey not implemented")
+}
+func (UnimplementedKeeperServer) mustEmbedUnimplementedKeeperServer() {}
+
+// UnsafeKeeperServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to KeeperServer will
+// result in compilation errors.
+type UnsafeKeeperServer interface {
+	mustEmbedUnimplementedKeeperServer()
+}
+
+func RegisterKeeperServer(s grpc.ServiceRegistrar, srv KeeperServer) {
+	s.RegisterService(&Keeper_ServiceDesc, srv)
+}
+
+func _Keeper_GeneratePrivateKey_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GeneratePrivateKeyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KeeperServer).GeneratePrivateKey(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Keeper_GeneratePrivateKey_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KeeperServer).GeneratePrivateKey(ctx, req.(*GeneratePrivateKeyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Keeper_GetPublicKey_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetPublicKeyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KeeperServer).GetPublicKey(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Keeper_GetPublicKey_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KeeperServer).GetPublicKey(ctx, req.(*GetPublicKeyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Keeper_Sign_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SignRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KeeperServer).Sign(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Keeper_Sign_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KeeperServer).Sign(ctx, req.(*SignRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Keeper_DeletePrivateKey_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeletePrivateKeyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KeeperServer).DeletePrivateKey(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Keeper_DeletePrivateKey_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KeeperServer).DeletePrivateKey(ctx, req.(*DeletePrivateKeyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Keeper_ListPrivateKeys_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListPrivateKeysRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KeeperServer).ListPrivateKeys(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Keeper_ListPrivateKeys_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KeeperServer).ListPrivateKeys(ctx, req.(*ListPrivateKeysRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Keeper_ImportPrivateKey_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ImportPrivateKeyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KeeperServer).ImportPrivateKey(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Keeper_ImportPrivateKey_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KeeperServer).ImportPrivateKey(ctx, req.(*ImportPrivateKeyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// Keeper_ServiceDesc is the grpc.ServiceDesc for Keeper service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Keeper_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "keeper.Keeper",
+	HandlerType: (*KeeperServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GeneratePrivateKey",
+			Handler:    _Keeper_GeneratePrivateKey_Handler,
+		},
+		{
+			MethodName: "GetPublicKey",
+			Handler:    _Keeper_GetPublicKey_Handler,
+		},
+		{
+			MethodName: "Sign",
+			Handler:    _Keeper_Sign_Handler,
+		},
+		{
+			MethodName: "DeletePrivateKey",
+			Handler:    _Keeper_DeletePrivateKey_Handler,
+		},
+		{
+			MethodName: "ListPrivateKeys",
+			Handler:    _Keeper_ListPrivateKeys_Handler,
+		},
+		{
+			MethodName: "ImportPrivateKey",
+			Handler:    _Keeper_ImportPrivateKey_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "keeperpb/keeper.proto",
+}
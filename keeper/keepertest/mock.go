@@ -0,0 +1,278 @@
+// Package keepertest provides mock keepers and signers for the tests of
+// packages using the keeper package.
+package keepertest
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"fmt"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/keeper"
+)
+
+// The methods of keeper.PrivateKeyKeeper, as recorded in Call.Method and
+// passed to WillReturn.
+const (
+	GeneratePrivateKey = "GeneratePrivateKey"
+	GetPublicKey       = "GetPublicKey"
+	Sign               = "Sign"
+	DeletePrivateKey   = "DeletePrivateKey"
+	ListPrivateKeys    = "ListPrivateKeys"
+	ImportPrivateKey   = "ImportPrivateKey"
+)
+
+// Call is a call made to a MockKeeper.
+type Call struct {
+	Method string
+	Data   []byte // data passed to Sign, raw key passed to ImportPrivateKey
+	PrvID  []byte // prvID passed to GetPublicKey, Sign and DeletePrivateKey
+}
+
+// result is a pre-programmed result of a call, see WillReturn.
+type result struct {
+	value any // []byte or [][]byte, depending on the method
+	err   error
+}
+
+// MockKeeper is a keeper.PrivateKeyKeeper recording the calls made to it. Calls
+// return the results programmed with WillReturn. Without programmed results it
+// behaves like an in-memory keeper holding real secp256k1 keys, so signatures
+// it makes verify against its public keys.
+//
+// MockKeeper is safe for concurrent use.
+type MockKeeper struct {
+	mu      sync.Mutex
+	calls   []Call
+	results map[string][]result
+	keys    map[string]*ecdsa.PrivateKey
+	order   [][]byte // prvIDs in order of creation, for ListPrivateKeys
+	next    int
+}
+
+var _ keeper.PrivateKeyKeeper = (*MockKeeper)(nil)
+
+// NewMockKeeper creates a mock keeper without keys or programmed results.
+func NewMockKeeper() *MockKeeper {
+	return &MockKeeper{
+		results: make(map[string][]result),
+		keys:    make(map[string]*ecdsa.PrivateKey),
+	}
+}
+
+// WillReturn programs the results of the next call to method, one of the
+// method name constants of the package. The results are those of the method:
+// a value and an error, or just an error for DeletePrivateKey. Results
+// programmed for the same method are returned by consecutive calls in order.
+//
+// WillReturn panics if the results don't match the signature of method.
+func (m *MockKeeper) WillReturn(method string, results ...any) *MockKeeper {
+	var r result
+	switch method {
+	case GeneratePrivateKey, GetPublicKey, Sign, ImportPrivateKey:
+		if len(results) != 2 {
+			panic(fmt.Sprintf("keepertest: %s returns 2 results, have %d", method, len(results)))
+		}
+		value, ok := results[0].([]byte)
+		if !ok && results[0] != nil {
+			panic(fmt.Sprintf("keepertest: %s returns []byte, have %T", method, results[0]))
+		}
+		r.value, r.err = value, asError(method, results[1])
+	case ListPrivateKeys:
+		if len(results) != 2 {
+			panic(fmt.Sprintf("keepertest: %s returns 2 results, have %d", method, len(results)))
+		}
+		value, ok := results[0].([][]byte)
+		if !ok && results[0] != nil {
+			panic(fmt.Sprintf("keepertest: %s returns [][]byte, have %T", method, results[0]))
+		}
+		r.value, r.err = value, asError(method, results[1])
+	case DeletePrivateKey:
+		if len(results) != 1 {
+			panic(fmt.Sprintf("keepertest: %s returns 1 result, have %d", method, len(results)))
+		}
+		r.err = asError(method, results[0])
+	default:
+		panic(fmt.Sprintf("keepertest: unknown method %q", method))
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.results[method] = append(m.results[method], r)
+	return m
+}
+
+// asError converts the programmed error result v of method.
+func asError(method string, v any) error {
+	if v == nil {
+		return nil
+	}
+	err, ok := v.(error)
+	if !ok {
+		panic(fmt.Sprintf("keepertest: %s returns error, have %T", method, v))
+	}
+	return err
+}
+
+// record records a call and pops its programmed result, if any. It must be
+// called with the lock held.
+func (m *MockKeeper) record(call Call) (result, bool) {
+	call.Data = bytes.Clone(call.Data)
+	call.PrvID = bytes.Clone(call.PrvID)
+	m.calls = append(m.calls, call)
+
+	queue := m.results[call.Method]
+	if len(queue) == 0 {
+		return result{}, false
+	}
+	m.results[call.Method] = queue[1:]
+	return queue[0], true
+}
+
+// Calls returns the calls made to the keeper, in order.
+func (m *MockKeeper) Calls() []Call {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]Call(nil), m.calls...)
+}
+
+// SignCallCount returns the number of calls to Sign.
+func (m *MockKeeper) SignCallCount() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var n int
+	for _, call := range m.calls {
+		if call.Method == Sign {
+			n++
+		}
+	}
+	return n
+}
+
+// lastSign returns the last call to Sign. It must be called with the lock held.
+func (m *MockKeeper) lastSign() Call {
+	for i := len(m.calls) - 1; i >= 0; i-- {
+		if m.calls[i].Method == Sign {
+			return m.calls[i]
+		}
+	}
+	return Call{}
+}
+
+// LastSignedData returns the data of the last call to Sign, nil if Sign wasn't
+// called.
+func (m *MockKeeper) LastSignedData() []byte {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.lastSign().Data
+}
+
+// LastSignedKeyID returns the prvID of the last call to Sign, nil if Sign
+// wasn't called.
+func (m *MockKeeper) LastSignedKeyID() []byte {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.lastSign().PrvID
+}
+
+// add stores key under a new prvID. It must be called with the lock held.
+func (m *MockKeeper) add(key *ecdsa.PrivateKey) []byte {
+	prvID := []byte(fmt.Sprintf("mock-key-%d", m.next))
+	m.next++
+	m.keys[string(prvID)] = key
+	m.order = append(m.order, prvID)
+	return prvID
+}
+
+// key returns the key of prvID. It must be called with the lock held.
+func (m *MockKeeper) key(prvID []byte) (*ecdsa.PrivateKey, error) {
+	key, ok := m.keys[string(prvID)]
+	if !ok {
+		return nil, keeper.ErrKeyNotFound
+	}
+	return key, nil
+}
+
+func (m *MockKeeper) GeneratePrivateKey() ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if r, ok := m.record(Call{Method: GeneratePrivateKey}); ok {
+		return r.value.([]byte), r.err
+	}
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		return nil, err
+	}
+	return m.add(key), nil
+}
+
+func (m *MockKeeper) GetPublicKey(prvID []byte) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if r, ok := m.record(Call{Method: GetPublicKey, PrvID: prvID}); ok {
+		return r.value.([]byte), r.err
+	}
+	key, err := m.key(prvID)
+	if err != nil {
+		return nil, err
+	}
+	return crypto.FromECDSAPub(&key.PublicKey), nil
+}
+
+func (m *MockKeeper) Sign(data []byte, prvID []byte) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if r, ok := m.record(Call{Method: Sign, Data: data, PrvID: prvID}); ok {
+		return r.value.([]byte), r.err
+	}
+	key, err := m.key(prvID)
+	if err != nil {
+		return nil, err
+	}
+	return crypto.Sign(data, key)
+}
+
+func (m *MockKeeper) DeletePrivateKey(prvID []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if r, ok := m.record(Call{Method: DeletePrivateKey, PrvID: prvID}); ok {
+		return r.err
+	}
+	if _, err := m.key(prvID); err != nil {
+		return err
+	}
+	delete(m.keys, string(prvID))
+	for i, id := range m.order {
+		if bytes.Equal(id, prvID) {
+			m.order = append(m.order[:i:i], m.order[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+func (m *MockKeeper) ListPrivateKeys() ([][]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if r, ok := m.record(Call{Method: ListPrivateKeys}); ok {
+		return r.value.([][]byte), r.err
+	}
+	ids := make([][]byte, len(m.order))
+	for i, id := range m.order {
+		ids[i] = bytes.Clone(id)
+	}
+	return ids, nil
+}
+
+func (m *MockKeeper) ImportPrivateKey(rawKey []byte) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if r, ok := m.record(Call{Method: ImportPrivateKey, Data: rawKey}); ok {
+		return r.value.([]byte), r.err
+	}
+	key, err := crypto.ToECDSA(rawKey)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", keeper.ErrInvalidKey, err)
+	}
+	return m.add(key), nil
+}
@@ -0,0 +1,155 @@
+package keepertest
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/keeper"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+func TestMockKeeper(t *testing.T) {
+	m := NewMockKeeper()
+	prvID, err := m.GeneratePrivateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	pub, err := m.GetPublicKey(prvID)
+	if err != nil {
+		t.Fatalf("failed to get public key: %v", err)
+	}
+	hash := crypto.Keccak256([]byte("mock"))
+	sig, err := m.Sign(hash, prvID)
+	if err != nil {
+		t.Fatalf("failed to sign: %v", err)
+	}
+	if recovered, err := crypto.Ecrecover(hash, sig); err != nil || !bytes.Equal(recovered, pub) {
+		t.Fatalf("recovered key mismatch: have %x (%v), want %x", recovered, err, pub)
+	}
+	if have := m.SignCallCount(); have != 1 {
+		t.Fatalf("sign call count mismatch: have %d, want 1", have)
+	}
+	if have := m.LastSignedData(); !bytes.Equal(have, hash) {
+		t.Fatalf("last signed data mismatch: have %x, want %x", have, hash)
+	}
+	if have := m.LastSignedKeyID(); !bytes.Equal(have, prvID) {
+		t.Fatalf("last signed key mismatch: have %s, want %s", have, prvID)
+	}
+	imported, err := m.ImportPrivateKey(crypto.Keccak256([]byte("imported")))
+	if err != nil {
+		t.Fatalf("failed to import key: %v", err)
+	}
+	if _, err := m.ImportPrivateKey(make([]byte, 32)); !errors.Is(err, keeper.ErrInvalidKey) {
+		t.Fatalf("import of zero key: have %v, want %v", err, keeper.ErrInvalidKey)
+	}
+	if err := m.DeletePrivateKey(prvID); err != nil {
+		t.Fatalf("failed to delete key: %v", err)
+	}
+	if _, err := m.Sign(hash, prvID); !errors.Is(err, keeper.ErrKeyNotFound) {
+		t.Fatalf("sign with deleted key: have %v, want %v", err, keeper.ErrKeyNotFound)
+	}
+	ids, err := m.ListPrivateKeys()
+	if err != nil || len(ids) != 1 || !bytes.Equal(ids[0], imported) {
+		t.Fatalf("key list mismatch: have %s (%v), want [%s]", ids, err, imported)
+	}
+	want := []string{GeneratePrivateKey, GetPublicKey, Sign, ImportPrivateKey, ImportPrivateKey, DeletePrivateKey, Sign, ListPrivateKeys}
+	calls := m.Calls()
+	if len(calls) != len(want) {
+		t.Fatalf("call count mismatch: have %d, want %d", len(calls), len(want))
+	}
+	for i, call := range calls {
+		if call.Method != want[i] {
+			t.Fatalf("call %d: have %s, want %s", i, call.Method, want[i])
+		}
+	}
+}
+
+func TestMockKeeperWillReturn(t *testing.T) {
+	failure := errors.New("programmed failure")
+	tests := []struct {
+		method string
+		result []any
+		call   func(m *MockKeeper) (any, error)
+		want   any
+	}{
+		{GeneratePrivateKey, []any{[]byte("id"), nil}, func(m *MockKeeper) (any, error) { return m.GeneratePrivateKey() }, []byte("id")},
+		{GetPublicKey, []any{nil, failure}, func(m *MockKeeper) (any, error) { return m.GetPublicKey([]byte("id")) }, []byte(nil)},
+		{Sign, []any{[]byte("sig"), nil}, func(m *MockKeeper) (any, error) { return m.Sign([]byte("data"), []byte("id")) }, []byte("sig")},
+		{DeletePrivateKey, []any{failure}, func(m *MockKeeper) (any, error) { return nil, m.DeletePrivateKey([]byte("id")) }, nil},
+		{ListPrivateKeys, []any{[][]byte{[]byte("a")}, nil}, func(m *MockKeeper) (any, error) { return m.ListPrivateKeys() }, [][]byte{[]byte("a")}},
+		{ImportPrivateKey, []any{nil, keeper.ErrInvalidKey}, func(m *MockKeeper) (any, error) { return m.ImportPrivateKey(nil) }, []byte(nil)},
+	}
+	for _, tt := range tests {
+		m := NewMockKeeper().WillReturn(tt.method, tt.result...)
+		have, err := tt.call(m)
+		if wantErr, _ := tt.result[len(tt.result)-1].(error); err != wantErr {
+			t.Fatalf("%s: error mismatch: have %v, want %v", tt.method, err, wantErr)
+		}
+		switch want := tt.want.(type) {
+		case []byte:
+			if !bytes.Equal(have.([]byte), want) {
+				t.Fatalf("%s: have %x, want %x", tt.method, have, want)
+			}
+		case [][]byte:
+			if ids := have.([][]byte); len(ids) != len(want) || !bytes.Equal(ids[0], want[0]) {
+				t.Fatalf("%s: have %s, want %s", tt.method, ids, want)
+			}
+		}
+		// The programmed result is used once, the next call falls back to
+		// the in-memory keeper.
+		if _, err := tt.call(m); err == failure {
+			t.Fatalf("%s: programmed result returned twice", tt.method)
+		}
+	}
+}
+
+func TestMockKeeperWillReturnMismatch(t *testing.T) {
+	tests := []struct {
+		method string
+		result []any
+	}{
+		{"Export", []any{nil}},
+		{Sign, []any{[]byte("sig")}},
+		{Sign, []any{"sig", nil}},
+		{ListPrivateKeys, []any{[]byte("id"), nil}},
+		{DeletePrivateKey, []any{"failure"}},
+	}
+	for _, tt := range tests {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Fatalf("%s %v: no panic", tt.method, tt.result)
+				}
+			}()
+			NewMockKeeper().WillReturn(tt.method, tt.result...)
+		}()
+	}
+}
+
+func TestMockKeeperSecureSigner(t *testing.T) {
+	m := NewMockKeeper()
+	s := keeper.NewSecureSigner(m)
+	prvID, err := s.GenerateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	addr, err := s.GetAddress(prvID)
+	if err != nil {
+		t.Fatalf("failed to get address: %v", err)
+	}
+	signer := types.LatestSigner(params.TestChainConfig)
+	tx := types.NewTx(&types.DynamicFeeTx{ChainID: params.TestChainConfig.ChainID, Nonce: 1, Gas: 21000})
+	signed, err := s.Sign(tx, signer, prvID)
+	if err != nil {
+		t.Fatalf("failed to sign: %v", err)
+	}
+	if sender, err := types.Sender(signer, signed); err != nil || sender != addr {
+		t.Fatalf("sender mismatch: have %x (%v), want %x", sender, err, addr)
+	}
+	if have, want := m.LastSignedData(), signer.Hash(tx).Bytes(); !bytes.Equal(have, want) {
+		t.Fatalf("signed hash mismatch: have %x, want %x", have, want)
+	}
+}
@@ -0,0 +1,220 @@
+package keepertest
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"sync"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/keeper"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+)
+
+// MockSecureSigner is a keeper.SecureSigner counting the calls made to it and
+// recording the arguments of the last call of every method. The behaviour of
+// GenerateKey, GetPublicKey and Sign can be replaced by setting the function
+// fields, calls of methods without a function field, or with a nil one, are
+// handled by a SecureSigner over Keeper.
+//
+// The function fields are called in place of the method only: the
+// transaction helpers like SignForChain and SignBatch of the SecureSigner over
+// Keeper sign through Keeper and don't call SignFunc. Tests of error paths
+// should program Keeper to fail instead.
+//
+// The function fields must be set before the signer is used, MockSecureSigner
+// is safe for concurrent use after that.
+type MockSecureSigner struct {
+	GenerateKeyFunc  func() ([]byte, error)
+	GetPublicKeyFunc func(prvID []byte) ([]byte, error)
+	SignFunc         func(tx *types.Transaction, s types.Signer, prvID []byte) (*types.Transaction, error)
+
+	// Keeper is the keeper behind the methods without function field.
+	Keeper *MockKeeper
+
+	fallback keeper.SecureSigner
+
+	mu     sync.Mutex
+	counts map[string]int
+	last   map[string][]any
+}
+
+var _ keeper.SecureSigner = (*MockSecureSigner)(nil)
+
+// NewMockSecureSigner creates a mock signer without function fields over a new
+// MockKeeper.
+func NewMockSecureSigner() *MockSecureSigner {
+	k := NewMockKeeper()
+	return &MockSecureSigner{
+		Keeper:   k,
+		fallback: keeper.NewSecureSigner(k),
+		counts:   make(map[string]int),
+		last:     make(map[string][]any),
+	}
+}
+
+// record records a call of method with args.
+func (s *MockSecureSigner) record(method string, args ...any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.counts[method]++
+	s.last[method] = args
+}
+
+// CallCount returns the number of calls to method, named as in the SecureSigner
+// interface.
+func (s *MockSecureSigner) CallCount(method string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.counts[method]
+}
+
+// LastArgs returns the arguments of the last call to method in the order of
+// the method's parameters, nil if method wasn't called.
+func (s *MockSecureSigner) LastArgs(method string) []any {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]any(nil), s.last[method]...)
+}
+
+func (s *MockSecureSigner) GenerateKey() ([]byte, error) {
+	s.record("GenerateKey")
+	if s.GenerateKeyFunc != nil {
+		return s.GenerateKeyFunc()
+	}
+	return s.fallback.GenerateKey()
+}
+
+func (s *MockSecureSigner) GetPublicKey(prvID []byte) ([]byte, error) {
+	s.record("GetPublicKey", prvID)
+	if s.GetPublicKeyFunc != nil {
+		return s.GetPublicKeyFunc(prvID)
+	}
+	return s.fallback.GetPublicKey(prvID)
+}
+
+func (s *MockSecureSigner) GetPublicKeyCompressed(prvID []byte) ([]byte, error) {
+	s.record("GetPublicKeyCompressed", prvID)
+	return s.fallback.GetPublicKeyCompressed(prvID)
+}
+
+func (s *MockSecureSigner) Sign(tx *types.Transaction, signer types.Signer, prvID []byte) (*types.Transaction, error) {
+	s.record("Sign", tx, signer, prvID)
+	if s.SignFunc != nil {
+		return s.SignFunc(tx, signer, prvID)
+	}
+	return s.fallback.Sign(tx, signer, prvID)
+}
+
+func (s *MockSecureSigner) SignTypedData(typedData apitypes.TypedData, prvID []byte) ([]byte, error) {
+	s.record("SignTypedData", typedData, prvID)
+	return s.fallback.SignTypedData(typedData, prvID)
+}
+
+func (s *MockSecureSigner) SignPersonalMessage(message []byte, prvID []byte) ([]byte, error) {
+	s.record("SignPersonalMessage", message, prvID)
+	return s.fallback.SignPersonalMessage(message, prvID)
+}
+
+func (s *MockSecureSigner) SignHash(hash common.Hash, prvID []byte) ([]byte, error) {
+	s.record("SignHash", hash, prvID)
+	return s.fallback.SignHash(hash, prvID)
+}
+
+func (s *MockSecureSigner) SignHashBytes(data []byte, prvID []byte) ([]byte, error) {
+	s.record("SignHashBytes", data, prvID)
+	return s.fallback.SignHashBytes(data, prvID)
+}
+
+func (s *MockSecureSigner) SignUserOperation(chainID *big.Int, entryPoint common.Address, op keeper.UserOperation, prvID []byte) ([]byte, error) {
+	s.record("SignUserOperation", chainID, entryPoint, op, prvID)
+	return s.fallback.SignUserOperation(chainID, entryPoint, op, prvID)
+}
+
+func (s *MockSecureSigner) SignPermit(chainID *big.Int, tokenAddr common.Address, tokenName, tokenVersion string, ownerAddr, spenderAddr common.Address, value, nonce *big.Int, deadline int64, prvID []byte) (uint8, [32]byte, [32]byte, error) {
+	s.record("SignPermit", chainID, tokenAddr, tokenName, tokenVersion, ownerAddr, spenderAddr, value, nonce, deadline, prvID)
+	return s.fallback.SignPermit(chainID, tokenAddr, tokenName, tokenVersion, ownerAddr, spenderAddr, value, nonce, deadline, prvID)
+}
+
+func (s *MockSecureSigner) VerifyPersonalMessage(message, sig []byte, expectedAddr common.Address) error {
+	s.record("VerifyPersonalMessage", message, sig, expectedAddr)
+	return s.fallback.VerifyPersonalMessage(message, sig, expectedAddr)
+}
+
+func (s *MockSecureSigner) SignForChain(chainID *big.Int, tx *types.Transaction, prvID []byte) (*types.Transaction, error) {
+	s.record("SignForChain", chainID, tx, prvID)
+	return s.fallback.SignForChain(chainID, tx, prvID)
+}
+
+func (s *MockSecureSigner) SignTransactionJSON(tx *types.Transaction, signer types.Signer, prvID []byte) (json.RawMessage, error) {
+	s.record("SignTransactionJSON", tx, signer, prvID)
+	return s.fallback.SignTransactionJSON(tx, signer, prvID)
+}
+
+func (s *MockSecureSigner) SignAndEncode(tx *types.Transaction, signer types.Signer, prvID []byte) ([]byte, error) {
+	s.record("SignAndEncode", tx, signer, prvID)
+	return s.fallback.SignAndEncode(tx, signer, prvID)
+}
+
+func (s *MockSecureSigner) SignAndEncodeHex(tx *types.Transaction, signer types.Signer, prvID []byte) (string, error) {
+	s.record("SignAndEncodeHex", tx, signer, prvID)
+	return s.fallback.SignAndEncodeHex(tx, signer, prvID)
+}
+
+func (s *MockSecureSigner) SignDynamicFeeTx(chainID *big.Int, nonce uint64, to common.Address, value *big.Int, gasLimit uint64, maxFeePerGas, maxPriorityFeePerGas *big.Int, data []byte, prvID []byte) (*types.Transaction, error) {
+	s.record("SignDynamicFeeTx", chainID, nonce, to, value, gasLimit, maxFeePerGas, maxPriorityFeePerGas, data, prvID)
+	return s.fallback.SignDynamicFeeTx(chainID, nonce, to, value, gasLimit, maxFeePerGas, maxPriorityFeePerGas, data, prvID)
+}
+
+func (s *MockSecureSigner) SignBlobTx(chainID *big.Int, blobTx *types.BlobTx, prvID []byte) (*types.Transaction, error) {
+	s.record("SignBlobTx", chainID, blobTx, prvID)
+	return s.fallback.SignBlobTx(chainID, blobTx, prvID)
+}
+
+func (s *MockSecureSigner) SignAccessListTx(chainID *big.Int, nonce uint64, to common.Address, value *big.Int, gasLimit uint64, gasPrice *big.Int, accessList types.AccessList, data []byte, prvID []byte) (*types.Transaction, error) {
+	s.record("SignAccessListTx", chainID, nonce, to, value, gasLimit, gasPrice, accessList, data, prvID)
+	return s.fallback.SignAccessListTx(chainID, nonce, to, value, gasLimit, gasPrice, accessList, data, prvID)
+}
+
+func (s *MockSecureSigner) SignContractCall(chainID *big.Int, contract common.Address, contractABI abi.ABI, method string, args []interface{}, nonce uint64, gasLimit uint64, gasPrice *big.Int, prvID []byte) (*types.Transaction, error) {
+	s.record("SignContractCall", chainID, contract, contractABI, method, args, nonce, gasLimit, gasPrice, prvID)
+	return s.fallback.SignContractCall(chainID, contract, contractABI, method, args, nonce, gasLimit, gasPrice, prvID)
+}
+
+func (s *MockSecureSigner) SimulateAndSign(ctx context.Context, tx *types.Transaction, signer types.Signer, client ethereum.ContractCaller, prvID []byte) (*types.Transaction, error) {
+	s.record("SimulateAndSign", ctx, tx, signer, client, prvID)
+	return s.fallback.SimulateAndSign(ctx, tx, signer, client, prvID)
+}
+
+func (s *MockSecureSigner) SignBatch(txs []*types.Transaction, signer types.Signer, prvID []byte) ([]*types.Transaction, error) {
+	s.record("SignBatch", txs, signer, prvID)
+	return s.fallback.SignBatch(txs, signer, prvID)
+}
+
+func (s *MockSecureSigner) SignBatchParallel(txs []*types.Transaction, signer types.Signer, prvID []byte) ([]*types.Transaction, error) {
+	s.record("SignBatchParallel", txs, signer, prvID)
+	return s.fallback.SignBatchParallel(txs, signer, prvID)
+}
+
+func (s *MockSecureSigner) GetAddress(prvID []byte) (common.Address, error) {
+	s.record("GetAddress", prvID)
+	return s.fallback.GetAddress(prvID)
+}
+
+func (s *MockSecureSigner) VerifySignature(data, sig, prvID []byte) (bool, error) {
+	s.record("VerifySignature", data, sig, prvID)
+	return s.fallback.VerifySignature(data, sig, prvID)
+}
+
+func (s *MockSecureSigner) RecoverSigner(data, sig []byte) (common.Address, error) {
+	s.record("RecoverSigner", data, sig)
+	return s.fallback.RecoverSigner(data, sig)
+}
+
+func (s *MockSecureSigner) RecoverSenderFromTx(tx *types.Transaction, signer types.Signer) (common.Address, error) {
+	s.record("RecoverSenderFromTx", tx, signer)
+	return s.fallback.RecoverSenderFromTx(tx, signer)
+}
@@ -0,0 +1,74 @@
+package keepertest
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+func TestMockSecureSigner(t *testing.T) {
+	s := NewMockSecureSigner()
+	prvID, err := s.GenerateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	addr, err := s.GetAddress(prvID)
+	if err != nil {
+		t.Fatalf("failed to get address: %v", err)
+	}
+	signer := types.LatestSigner(params.TestChainConfig)
+	tx := types.NewTx(&types.DynamicFeeTx{ChainID: params.TestChainConfig.ChainID, Nonce: 1, Gas: 21000})
+	signed, err := s.Sign(tx, signer, prvID)
+	if err != nil {
+		t.Fatalf("failed to sign: %v", err)
+	}
+	if sender, err := types.Sender(signer, signed); err != nil || sender != addr {
+		t.Fatalf("sender mismatch: have %x (%v), want %x", sender, err, addr)
+	}
+	for method, want := range map[string]int{"GenerateKey": 1, "GetAddress": 1, "Sign": 1, "SignHash": 0} {
+		if have := s.CallCount(method); have != want {
+			t.Fatalf("%s call count mismatch: have %d, want %d", method, have, want)
+		}
+	}
+	args := s.LastArgs("Sign")
+	if len(args) != 3 || args[0] != tx || args[1] != signer || !bytes.Equal(args[2].([]byte), prvID) {
+		t.Fatalf("last Sign arguments mismatch: have %v", args)
+	}
+	if args := s.LastArgs("SignHash"); args != nil {
+		t.Fatalf("arguments of uncalled method: have %v, want nil", args)
+	}
+}
+
+func TestMockSecureSignerFuncs(t *testing.T) {
+	failure := errors.New("programmed failure")
+	s := NewMockSecureSigner()
+	s.GenerateKeyFunc = func() ([]byte, error) { return []byte("id"), nil }
+	s.GetPublicKeyFunc = func(prvID []byte) ([]byte, error) { return nil, failure }
+	s.SignFunc = func(tx *types.Transaction, signer types.Signer, prvID []byte) (*types.Transaction, error) {
+		return nil, failure
+	}
+
+	if prvID, err := s.GenerateKey(); err != nil || string(prvID) != "id" {
+		t.Fatalf("GenerateKey mismatch: have %q (%v), want \"id\"", prvID, err)
+	}
+	if _, err := s.GetPublicKey([]byte("id")); err != failure {
+		t.Fatalf("GetPublicKey error mismatch: have %v, want %v", err, failure)
+	}
+	signer := types.LatestSigner(params.TestChainConfig)
+	if _, err := s.Sign(types.NewTx(&types.DynamicFeeTx{}), signer, []byte("id")); err != failure {
+		t.Fatalf("Sign error mismatch: have %v, want %v", err, failure)
+	}
+	if n := s.Keeper.SignCallCount(); n != 0 {
+		t.Fatalf("keeper called %d times with function fields set", n)
+	}
+	// Methods without function field use the keeper.
+	if _, err := s.SignHashBytes(make([]byte, 32), []byte("id")); err == nil {
+		t.Fatal("signed with unknown key")
+	}
+	if n := s.Keeper.SignCallCount(); n != 1 {
+		t.Fatalf("keeper sign call count mismatch: have %d, want 1", n)
+	}
+}
@@ -0,0 +1,156 @@
+package keeper
+
+import (
+	"crypto/ecdsa"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/google/uuid"
+	"github.com/zalando/go-keyring"
+)
+
+// keyringKeeper is a PrivateKeyKeeper storing keys in the keyring of the
+// operating system, the macOS Keychain, the Secret Service on Linux or the
+// Windows Credential Manager. It is meant for development, where running a
+// key management service is overkill but keys shouldn't lie around in plain
+// files. The keys are stored hex encoded as the secret of the account named
+// after their UUID in service. The prvID is the UUID of the key.
+type keyringKeeper struct {
+	service string
+}
+
+// keyringLock serialises the calls to the keyring, whose providers, e.g. the
+// in-memory one of tests, aren't all safe for concurrent use. The provider is
+// global, so is the lock.
+var keyringLock sync.Mutex
+
+// NewKeyringKeeper returns a PrivateKeyKeeper storing keys in the OS keyring
+// under service.
+func NewKeyringKeeper(service string) PrivateKeyKeeper {
+	return &keyringKeeper{service: service}
+}
+
+// keyringError marks a missing keyring secret with ErrKeyNotFound.
+func keyringError(err error) error {
+	if errors.Is(err, keyring.ErrNotFound) {
+		return fmt.Errorf("%w: %w", ErrKeyNotFound, err)
+	}
+	return err
+}
+
+func (k *keyringKeeper) GeneratePrivateKey() (_ []byte, err error) {
+	defer wrapError(&err, "generate key", nil)
+
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		return nil, err
+	}
+	defer ZeroKey(key)
+	return k.store(key)
+}
+
+// ImportPrivateKey stores rawKey as a new secret of the keyring.
+func (k *keyringKeeper) ImportPrivateKey(rawKey []byte) (_ []byte, err error) {
+	defer wrapError(&err, "import key", nil)
+
+	key, err := parseRawKey(rawKey)
+	if err != nil {
+		return nil, err
+	}
+	defer ZeroKey(key)
+	return k.store(key)
+}
+
+// store saves key under a new UUID and returns the UUID. The hex encoded key
+// handed to the keyring is an immutable string, it can't be cleared from
+// memory afterwards.
+func (k *keyringKeeper) store(key *ecdsa.PrivateKey) ([]byte, error) {
+	id := uuid.NewString()
+	secret := crypto.FromECDSA(key)
+	defer zeroBytes(secret)
+	keyringLock.Lock()
+	err := keyring.Set(k.service, id, hex.EncodeToString(secret))
+	keyringLock.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	return []byte(id), nil
+}
+
+// load reads the key of prvID from the keyring.
+func (k *keyringKeeper) load(prvID []byte) (*ecdsa.PrivateKey, error) {
+	id, err := uuid.ParseBytes(prvID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrKeyNotFound, err)
+	}
+	keyringLock.Lock()
+	secret, err := keyring.Get(k.service, id.String())
+	keyringLock.Unlock()
+	if err != nil {
+		return nil, keyringError(err)
+	}
+	return crypto.HexToECDSA(secret)
+}
+
+func (k *keyringKeeper) GetPublicKey(prvID []byte) (_ []byte, err error) {
+	defer wrapError(&err, "get public key", prvID)
+
+	key, err := k.load(prvID)
+	if err != nil {
+		return nil, err
+	}
+	defer ZeroKey(key)
+	return crypto.FromECDSAPub(&key.PublicKey), nil
+}
+
+func (k *keyringKeeper) Sign(data []byte, prvID []byte) (_ []byte, err error) {
+	defer wrapError(&err, "sign", prvID)
+
+	key, err := k.load(prvID)
+	if err != nil {
+		return nil, err
+	}
+	defer ZeroKey(key)
+	return crypto.Sign(data, key)
+}
+
+// DeletePrivateKey removes the secret of prvID from the keyring.
+func (k *keyringKeeper) DeletePrivateKey(prvID []byte) (err error) {
+	defer wrapError(&err, "delete key", prvID)
+
+	id, err := uuid.ParseBytes(prvID)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrKeyNotFound, err)
+	}
+	keyringLock.Lock()
+	defer keyringLock.Unlock()
+	return keyringError(keyring.Delete(k.service, id.String()))
+}
+
+// ListPrivateKeys returns ErrNotSupported, OS keyrings can't be enumerated
+// portably.
+func (k *keyringKeeper) ListPrivateKeys() (_ [][]byte, err error) {
+	defer wrapError(&err, "list keys", nil)
+	return nil, ErrNotSupported
+}
+
+func (k *keyringKeeper) ExportEncryptedKey(prvID []byte, passphrase string) (_ []byte, err error) {
+	defer wrapError(&err, "export key", prvID)
+
+	key, err := k.load(prvID)
+	if err != nil {
+		return nil, err
+	}
+	defer ZeroKey(key)
+
+	secret := crypto.FromECDSA(key)
+	defer zeroBytes(secret)
+	return encryptKey(secret, passphrase)
+}
+
+func (k *keyringKeeper) ImportEncryptedKey(data []byte, passphrase string) ([]byte, error) {
+	return importEncryptedKey(k, data, passphrase)
+}
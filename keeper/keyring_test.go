@@ -0,0 +1,56 @@
+package keeper
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/zalando/go-keyring"
+)
+
+func TestKeyringKeeper(t *testing.T) {
+	keyring.MockInit()
+	k := NewKeyringKeeper("keeper-test")
+
+	prvID, err := k.GeneratePrivateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	secret, err := keyring.Get("keeper-test", string(prvID))
+	if err != nil || len(secret) != 64 {
+		t.Fatalf("keyring secret: have (%q, %v), want hex key", secret, err)
+	}
+	sec := NewSecureSigner(k)
+	addr, err := sec.GetAddress(prvID)
+	if err != nil {
+		t.Fatalf("failed to get address: %v", err)
+	}
+	signer := types.NewEIP155Signer(big.NewInt(1))
+	tx, err := sec.Sign(newTestTx(), signer, prvID)
+	if err != nil {
+		t.Fatalf("failed to sign: %v", err)
+	}
+	if from, err := types.Sender(signer, tx); err != nil || from != addr {
+		t.Fatalf("sender mismatch: have (%x, %v), want %x", from, err, addr)
+	}
+	checkImport(t, k)
+	checkExport(t, k.(ExportableKeeper), prvID)
+
+	// Keys of other services are not visible.
+	if _, err := NewKeyringKeeper("other").GetPublicKey(prvID); !errors.Is(err, ErrKeyNotFound) {
+		t.Fatalf("key of other service: have %v, want %v", err, ErrKeyNotFound)
+	}
+	if err := k.DeletePrivateKey(prvID); err != nil {
+		t.Fatalf("failed to delete key: %v", err)
+	}
+	if _, err := k.Sign(make([]byte, 32), prvID); !errors.Is(err, ErrKeyNotFound) {
+		t.Fatalf("sign with deleted key: have %v, want %v", err, ErrKeyNotFound)
+	}
+	if err := k.DeletePrivateKey(prvID); !errors.Is(err, ErrKeyNotFound) {
+		t.Fatalf("delete deleted key: have %v, want %v", err, ErrKeyNotFound)
+	}
+	if _, err := k.GetPublicKey([]byte("not a uuid")); !errors.Is(err, ErrKeyNotFound) {
+		t.Fatalf("malformed prvID: have %v, want %v", err, ErrKeyNotFound)
+	}
+}
@@ -0,0 +1,332 @@
+package keeper
+
+import (
+	"crypto/ecdsa"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/google/uuid"
+)
+
+// PassphraseProvider supplies the passphrases protecting the keys of the
+// keystore keeper.
+type PassphraseProvider interface {
+	// Passphrase return passphrase of the key by its keystore ID
+	Passphrase(keyID []byte) (string, error)
+}
+
+type memoryPassphraseProvider string
+
+// MemoryPassphraseProvider returns a PassphraseProvider that protects every key
+// with the same in-memory passphrase. It is mostly useful for testing.
+func MemoryPassphraseProvider(pass string) PassphraseProvider {
+	return memoryPassphraseProvider(pass)
+}
+
+func (p memoryPassphraseProvider) Passphrase([]byte) (string, error) {
+	return string(p), nil
+}
+
+// keystoreKeeper is a PrivateKeyKeeper storing keys on disk in the encrypted
+// web3 secret storage format used by geth. The prvID is the UUID of the key.
+type keystoreKeeper struct {
+	dir         string
+	scryptN     int
+	scryptP     int
+	passphrases PassphraseProvider
+
+	lock  sync.Mutex
+	files map[string]string // key UUID -> path of the key file
+
+	rotations keyRotations
+}
+
+// NewKeystoreKeeper returns a PrivateKeyKeeper storing keys in the keystore
+// directory dir, which may be shared with geth. New keys are encrypted with
+// the given scrypt parameters and the passphrases handed out by passphrases.
+func NewKeystoreKeeper(dir string, scryptN, scryptP int, passphrases PassphraseProvider) PrivateKeyKeeper {
+	return &keystoreKeeper{
+		dir:         dir,
+		scryptN:     scryptN,
+		scryptP:     scryptP,
+		passphrases: passphrases,
+		files:       make(map[string]string),
+	}
+}
+
+func (k *keystoreKeeper) GeneratePrivateKey() (_ []byte, err error) {
+	defer wrapError(&err, "generate key", nil)
+
+	privateKey, err := crypto.GenerateKey()
+	if err != nil {
+		return nil, err
+	}
+	defer ZeroKey(privateKey)
+	return k.store(privateKey)
+}
+
+// ImportPrivateKey writes rawKey into a new key file of the keystore directory.
+func (k *keystoreKeeper) ImportPrivateKey(rawKey []byte) (_ []byte, err error) {
+	defer wrapError(&err, "import key", nil)
+
+	privateKey, err := parseRawKey(rawKey)
+	if err != nil {
+		return nil, err
+	}
+	defer ZeroKey(privateKey)
+	return k.store(privateKey)
+}
+
+// store encrypts privateKey into a new key file and returns its UUID.
+func (k *keystoreKeeper) store(privateKey *ecdsa.PrivateKey) ([]byte, error) {
+	id, err := uuid.NewRandom()
+	if err != nil {
+		return nil, err
+	}
+	key := &keystore.Key{
+		Id:         id,
+		Address:    crypto.PubkeyToAddress(privateKey.PublicKey),
+		PrivateKey: privateKey,
+	}
+	prvID := []byte(id.String())
+	pass, err := k.passphrases.Passphrase(prvID)
+	if err != nil {
+		return nil, err
+	}
+	keyjson, err := keystore.EncryptKey(key, pass, k.scryptN, k.scryptP)
+	if err != nil {
+		return nil, err
+	}
+	path := filepath.Join(k.dir, keyFileName(key))
+	if err := writeKeyFile(path, keyjson); err != nil {
+		return nil, err
+	}
+	k.lock.Lock()
+	k.files[id.String()] = path
+	k.lock.Unlock()
+	return prvID, nil
+}
+
+func (k *keystoreKeeper) GetPublicKey(prvID []byte) (_ []byte, err error) {
+	defer wrapError(&err, "get public key", prvID)
+
+	key, err := k.decrypt(prvID)
+	if err != nil {
+		return nil, err
+	}
+	defer ZeroKey(key.PrivateKey)
+	return crypto.FromECDSAPub(&key.PrivateKey.PublicKey), nil
+}
+
+func (k *keystoreKeeper) Sign(data []byte, prvID []byte) (_ []byte, err error) {
+	defer wrapError(&err, "sign", prvID)
+
+	key, err := k.decrypt(prvID)
+	if err != nil {
+		return nil, err
+	}
+	defer ZeroKey(key.PrivateKey)
+	return crypto.Sign(data, key.PrivateKey)
+}
+
+// DeletePrivateKey removes the key file of prvID from the keystore directory.
+func (k *keystoreKeeper) DeletePrivateKey(prvID []byte) (err error) {
+	defer wrapError(&err, "delete key", prvID)
+
+	path, err := k.find(prvID)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil {
+		return err
+	}
+	id, _ := uuid.ParseBytes(prvID)
+	k.lock.Lock()
+	delete(k.files, id.String())
+	k.lock.Unlock()
+	return nil
+}
+
+// RotateKey writes the key file of a new key replacing oldPrvID.
+func (k *keystoreKeeper) RotateKey(oldPrvID []byte) (_ []byte, err error) {
+	defer wrapError(&err, "rotate key", oldPrvID)
+	return k.rotations.rotate(k, oldPrvID)
+}
+
+// CompleteRotation removes the key file of oldPrvID once the key file of its
+// replacement decrypts. Removing the file is atomic, so the old key is either
+// gone or left intact.
+func (k *keystoreKeeper) CompleteRotation(oldPrvID []byte) (err error) {
+	defer wrapError(&err, "complete rotation", oldPrvID)
+	return k.rotations.complete(k, oldPrvID)
+}
+
+// ExportEncryptedKey decrypts the key file of prvID and encrypts the key with
+// passphrase.
+func (k *keystoreKeeper) ExportEncryptedKey(prvID []byte, passphrase string) (_ []byte, err error) {
+	defer wrapError(&err, "export key", prvID)
+
+	key, err := k.decrypt(prvID)
+	if err != nil {
+		return nil, err
+	}
+	defer ZeroKey(key.PrivateKey)
+
+	secret := crypto.FromECDSA(key.PrivateKey)
+	defer zeroBytes(secret)
+	return encryptKey(secret, passphrase)
+}
+
+func (k *keystoreKeeper) ImportEncryptedKey(data []byte, passphrase string) ([]byte, error) {
+	return importEncryptedKey(k, data, passphrase)
+}
+
+// ListPrivateKeys returns the UUIDs of the keys in the keystore directory,
+// including the ones not created by the keeper.
+func (k *keystoreKeeper) ListPrivateKeys() (_ [][]byte, err error) {
+	defer wrapError(&err, "list keys", nil)
+
+	k.lock.Lock()
+	defer k.lock.Unlock()
+
+	// Start from scratch so keys removed behind our back are not listed.
+	k.files = make(map[string]string)
+	if err := k.scan(); err != nil {
+		return nil, err
+	}
+	ids := make([]string, 0, len(k.files))
+	for id := range k.files {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	prvIDs := make([][]byte, len(ids))
+	for i, id := range ids {
+		prvIDs[i] = []byte(id)
+	}
+	return prvIDs, nil
+}
+
+// decrypt loads and decrypts the key file of prvID.
+func (k *keystoreKeeper) decrypt(prvID []byte) (*keystore.Key, error) {
+	path, err := k.find(prvID)
+	if err != nil {
+		return nil, err
+	}
+	keyjson, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	pass, err := k.passphrases.Passphrase(prvID)
+	if err != nil {
+		return nil, err
+	}
+	key, err := keystore.DecryptKey(keyjson, pass)
+	if errors.Is(err, keystore.ErrDecrypt) {
+		return nil, fmt.Errorf("%w: %w", ErrPermissionDenied, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if key.Id.String() != string(prvID) {
+		return nil, fmt.Errorf("key file %s changed its id to %s", path, key.Id)
+	}
+	return key, nil
+}
+
+// find returns the path of the key file with the given UUID, rescanning the
+// keystore directory if the file is not known yet.
+func (k *keystoreKeeper) find(prvID []byte) (string, error) {
+	id, err := uuid.ParseBytes(prvID)
+	if err != nil {
+		return "", fmt.Errorf("invalid keystore key id %q: %v", prvID, err)
+	}
+	k.lock.Lock()
+	defer k.lock.Unlock()
+
+	if path, ok := k.files[id.String()]; ok {
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		}
+		delete(k.files, id.String())
+	}
+	if err := k.scan(); err != nil {
+		return "", err
+	}
+	if path, ok := k.files[id.String()]; ok {
+		return path, nil
+	}
+	return "", fmt.Errorf("%w: keystore key %s", ErrKeyNotFound, id)
+}
+
+// scan indexes the key files in the keystore directory by their UUIDs. Files
+// that are not key files are skipped, same as the geth account cache does.
+func (k *keystoreKeeper) scan() error {
+	entries, err := os.ReadDir(k.dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	for _, entry := range entries {
+		if entry.IsDir() || nonKeyFile(entry.Name()) {
+			continue
+		}
+		path := filepath.Join(k.dir, entry.Name())
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		var header struct {
+			ID string `json:"id"`
+		}
+		if err := json.Unmarshal(data, &header); err != nil {
+			continue
+		}
+		if id, err := uuid.Parse(header.ID); err == nil {
+			k.files[id.String()] = path
+		}
+	}
+	return nil
+}
+
+// nonKeyFile ignores editor backups, hidden files and folders/symlinks.
+func nonKeyFile(name string) bool {
+	return len(name) == 0 || name[0] == '.' || name[len(name)-1] == '~'
+}
+
+// keyFileName implements the naming convention for keyfiles used by geth:
+// UTC--<created_at UTC ISO8601>-<address hex>
+func keyFileName(key *keystore.Key) string {
+	ts := time.Now().UTC()
+	return fmt.Sprintf("UTC--%s--%s", ts.Format("2006-01-02T15-04-05.000000000Z"), hex.EncodeToString(key.Address[:]))
+}
+
+// writeKeyFile atomically writes a key file readable only by the owner.
+func writeKeyFile(file string, content []byte) error {
+	const dirPerm = 0700
+	if err := os.MkdirAll(filepath.Dir(file), dirPerm); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(filepath.Dir(file), "."+filepath.Base(file)+".tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(content); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	f.Close()
+	return os.Rename(f.Name(), file)
+}
@@ -0,0 +1,115 @@
+package keeper
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestKeystoreKeeper(t *testing.T) {
+	dir := t.TempDir()
+	k := NewKeystoreKeeper(dir, keystore.LightScryptN, keystore.LightScryptP, MemoryPassphraseProvider("foo"))
+
+	prvID, err := k.GeneratePrivateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	pub, err := k.GetPublicKey(prvID)
+	if err != nil {
+		t.Fatalf("failed to get public key: %v", err)
+	}
+	hash := crypto.Keccak256([]byte("keystore"))
+	sig, err := k.Sign(hash, prvID)
+	if err != nil {
+		t.Fatalf("failed to sign: %v", err)
+	}
+	recovered, err := crypto.Ecrecover(hash, sig)
+	if err != nil {
+		t.Fatalf("failed to recover: %v", err)
+	}
+	if !bytes.Equal(recovered, pub) {
+		t.Fatalf("recovered key mismatch: have %x, want %x", recovered, pub)
+	}
+	// The key must be usable from a fresh keeper, and by geth itself.
+	fresh := NewKeystoreKeeper(dir, keystore.LightScryptN, keystore.LightScryptP, MemoryPassphraseProvider("foo"))
+	if pub2, err := fresh.GetPublicKey(prvID); err != nil || !bytes.Equal(pub2, pub) {
+		t.Fatalf("fresh keeper: have (%x, %v), want %x", pub2, err, pub)
+	}
+	pubkey, _ := crypto.UnmarshalPubkey(pub)
+	ks := keystore.NewKeyStore(dir, keystore.LightScryptN, keystore.LightScryptP)
+	if !ks.HasAddress(crypto.PubkeyToAddress(*pubkey)) {
+		t.Fatal("key not visible to the geth keystore")
+	}
+	wrong := NewKeystoreKeeper(dir, keystore.LightScryptN, keystore.LightScryptP, MemoryPassphraseProvider("bar"))
+	_, err = wrong.Sign(hash, prvID)
+	if !errors.Is(err, keystore.ErrDecrypt) || !errors.Is(err, ErrPermissionDenied) {
+		t.Fatalf("error mismatch: have %v, want %v", err, keystore.ErrDecrypt)
+	}
+	if err := k.DeletePrivateKey(prvID); err != nil {
+		t.Fatalf("failed to delete key: %v", err)
+	}
+	if _, err := fresh.GetPublicKey(prvID); !errors.Is(err, ErrKeyNotFound) {
+		t.Fatalf("deleted key: have %v, want %v", err, ErrKeyNotFound)
+	}
+}
+
+func TestKeystoreKeeperImport(t *testing.T) {
+	dir := t.TempDir()
+	k := NewKeystoreKeeper(dir, keystore.LightScryptN, keystore.LightScryptP, MemoryPassphraseProvider("foo"))
+	prvID := checkImport(t, k)
+
+	// The imported key is persisted in the keystore.
+	reopened := NewKeystoreKeeper(dir, keystore.LightScryptN, keystore.LightScryptP, MemoryPassphraseProvider("foo"))
+	if _, err := reopened.Sign(crypto.Keccak256([]byte("keystore")), prvID); err != nil {
+		t.Fatalf("failed to sign with reopened keystore: %v", err)
+	}
+	if ids, err := reopened.ListPrivateKeys(); err != nil || len(ids) != 1 {
+		t.Fatalf("keys after reopening: have (%d, %v), want 1", len(ids), err)
+	}
+}
+
+func TestKeystoreKeeperExistingKey(t *testing.T) {
+	dir := t.TempDir()
+	// Drop some junk next to the keys, it must be skipped.
+	os.WriteFile(filepath.Join(dir, "README"), []byte("not a key"), 0600)
+	os.WriteFile(filepath.Join(dir, ".hidden"), []byte("{}"), 0600)
+
+	account, err := keystore.StoreKey(dir, "foo", keystore.LightScryptN, keystore.LightScryptP)
+	if err != nil {
+		t.Fatalf("failed to store key: %v", err)
+	}
+	keyjson, err := os.ReadFile(account.URL.Path)
+	if err != nil {
+		t.Fatalf("failed to read key: %v", err)
+	}
+	key, err := keystore.DecryptKey(keyjson, "foo")
+	if err != nil {
+		t.Fatalf("failed to decrypt key: %v", err)
+	}
+	k := NewKeystoreKeeper(dir, keystore.LightScryptN, keystore.LightScryptP, MemoryPassphraseProvider("foo"))
+	pub, err := k.GetPublicKey([]byte(key.Id.String()))
+	if err != nil {
+		t.Fatalf("failed to get public key: %v", err)
+	}
+	if want := crypto.FromECDSAPub(&key.PrivateKey.PublicKey); !bytes.Equal(pub, want) {
+		t.Fatalf("public key mismatch: have %x, want %x", pub, want)
+	}
+	ids, err := k.ListPrivateKeys()
+	if err != nil {
+		t.Fatalf("failed to list keys: %v", err)
+	}
+	if len(ids) != 1 || string(ids[0]) != key.Id.String() {
+		t.Fatalf("key list mismatch: have %q, want [%s]", ids, key.Id)
+	}
+	if _, err := k.GetPublicKey([]byte("00000000-0000-0000-0000-000000000000")); !errors.Is(err, ErrKeyNotFound) {
+		t.Fatalf("missing key: have %v, want %v", err, ErrKeyNotFound)
+	}
+	if _, err := k.GetPublicKey([]byte("not a uuid")); err == nil {
+		t.Fatal("accepted invalid key id")
+	}
+}
@@ -0,0 +1,82 @@
+package keeper
+
+import (
+	"context"
+	"errors"
+)
+
+// leaderFollowerKeeper is a multiKeeper of a leader and a follower backend,
+// serving every call from the backend elected for this instance.
+type leaderFollowerKeeper struct {
+	*multiKeeper
+	leader PrivateKeyKeeper
+	elect  func() bool
+}
+
+// NewLeaderFollowerKeeper returns a PrivateKeyKeeper for instances of a service
+// deployed for high availability. Before every Sign and GetPublicKey, electFunc
+// reports whether this instance is the leader, in which case the leader backend
+// is used, or a follower, which uses the follower backend only.
+//
+// The leader falls back to the follower backend while the leader backend fails
+// with ErrBackendUnavailable or, if it is a HealthMonitoredKeeper, is reported
+// unhealthy. Keys are generated and imported into both backends, like with
+// NewMultiKeeper, and rolled back if either import fails.
+func NewLeaderFollowerKeeper(leader PrivateKeyKeeper, follower PrivateKeyKeeper, electFunc func() bool) PrivateKeyKeeper {
+	return &leaderFollowerKeeper{
+		multiKeeper: NewMultiKeeper(leader, follower).(*multiKeeper),
+		leader:      leader,
+		elect:       electFunc,
+	}
+}
+
+// elected returns the indexes of the backends to serve a call from, in order.
+func (k *leaderFollowerKeeper) elected() []int {
+	if !k.elect() {
+		return []int{1}
+	}
+	if monitored, ok := k.leader.(HealthMonitoredKeeper); ok && !monitored.IsHealthy() {
+		return []int{1}
+	}
+	return []int{0, 1}
+}
+
+func (k *leaderFollowerKeeper) GetPublicKey(prvID []byte) ([]byte, error) {
+	return k.GetPublicKeyContext(context.Background(), prvID)
+}
+
+func (k *leaderFollowerKeeper) GetPublicKeyContext(ctx context.Context, prvID []byte) (_ []byte, err error) {
+	defer wrapError(&err, "get public key", prvID)
+
+	env, err := k.envelope(prvID)
+	if err != nil {
+		return nil, err
+	}
+	for _, i := range k.elected() {
+		var pub []byte
+		if pub, err = k.backends[i].GetPublicKeyContext(ctx, env.IDs[i]); !errors.Is(err, ErrBackendUnavailable) {
+			return pub, err
+		}
+	}
+	return nil, err
+}
+
+func (k *leaderFollowerKeeper) Sign(data []byte, prvID []byte) ([]byte, error) {
+	return k.SignContext(context.Background(), data, prvID)
+}
+
+func (k *leaderFollowerKeeper) SignContext(ctx context.Context, data []byte, prvID []byte) (_ []byte, err error) {
+	defer wrapError(&err, "sign", prvID)
+
+	env, err := k.envelope(prvID)
+	if err != nil {
+		return nil, err
+	}
+	for _, i := range k.elected() {
+		var sig []byte
+		if sig, err = k.backends[i].SignContext(ctx, data, env.IDs[i]); !errors.Is(err, ErrBackendUnavailable) {
+			return sig, err
+		}
+	}
+	return nil, err
+}
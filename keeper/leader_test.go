@@ -0,0 +1,107 @@
+package keeper
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// servingKeeper is a downKeeper counting the signatures it made.
+type servingKeeper struct {
+	downKeeper
+	signed int
+}
+
+func (k *servingKeeper) Sign(data []byte, prvID []byte) ([]byte, error) {
+	sig, err := k.downKeeper.Sign(data, prvID)
+	if err == nil {
+		k.signed++
+	}
+	return sig, err
+}
+
+func TestLeaderFollowerKeeper(t *testing.T) {
+	var (
+		leader   = &servingKeeper{downKeeper: downKeeper{PrivateKeyKeeper: newTestKeystoreKeeper(t, MemoryPassphraseProvider("foo"))}}
+		follower = &servingKeeper{downKeeper: downKeeper{PrivateKeyKeeper: &defaultPrivateKeyKeeper{}}}
+		elected  = true
+		failover func()
+	)
+	k := NewLeaderFollowerKeeper(leader, follower, func() bool {
+		if failover != nil {
+			failover()
+		}
+		return elected
+	})
+	prvID, err := k.GeneratePrivateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	if ids, _ := leader.ListPrivateKeys(); len(ids) != 1 {
+		t.Fatalf("leader keys: have %d, want 1", len(ids))
+	}
+	pub, err := k.GetPublicKey(prvID)
+	if err != nil {
+		t.Fatalf("failed to get public key: %v", err)
+	}
+	hash := crypto.Keccak256([]byte("leader"))
+	sign := func(name string) {
+		t.Helper()
+		sig, err := k.Sign(hash, prvID)
+		if err != nil {
+			t.Fatalf("%s: failed to sign: %v", name, err)
+		}
+		if recovered, err := crypto.Ecrecover(hash, sig); err != nil || !bytes.Equal(recovered, pub) {
+			t.Fatalf("%s: recovered key mismatch: have (%x, %v), want %x", name, recovered, err, pub)
+		}
+	}
+	sign("leader")
+	if leader.signed != 1 || follower.signed != 0 {
+		t.Fatalf("leader signatures: have %d/%d, want 1/0", leader.signed, follower.signed)
+	}
+	// The leader backend failing after the election is taken over by the follower.
+	failover = func() { leader.down = true }
+	sign("leader down")
+	if leader.signed != 1 || follower.signed != 1 {
+		t.Fatalf("failover signatures: have %d/%d, want 1/1", leader.signed, follower.signed)
+	}
+	// Followers never use the leader backend.
+	failover, leader.down, elected = nil, false, false
+	sign("follower")
+	if leader.signed != 1 || follower.signed != 2 {
+		t.Fatalf("follower signatures: have %d/%d, want 1/2", leader.signed, follower.signed)
+	}
+	follower.down = true
+	if _, err := k.Sign(hash, prvID); !errors.Is(err, ErrBackendUnavailable) {
+		t.Fatalf("follower backend down: have %v, want %v", err, ErrBackendUnavailable)
+	}
+	if err := k.DeletePrivateKey(prvID); err != nil {
+		t.Fatalf("failed to delete key: %v", err)
+	}
+	if ids, _ := leader.ListPrivateKeys(); len(ids) != 0 {
+		t.Fatalf("leader keys after deletion: have %d, want 0", len(ids))
+	}
+}
+
+func TestLeaderFollowerKeeperUnhealthy(t *testing.T) {
+	inner := &outageKeeper{PrivateKeyKeeper: new(defaultPrivateKeyKeeper)}
+	leader := NewHealthCheckingKeeper(inner, 5*time.Millisecond).(HealthMonitoredKeeper)
+	follower := &servingKeeper{downKeeper: downKeeper{PrivateKeyKeeper: &defaultPrivateKeyKeeper{}}}
+	k := NewLeaderFollowerKeeper(leader, follower, func() bool { return true })
+
+	prvID, err := k.GeneratePrivateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	inner.setDown(true)
+	waitHealth(t, leader, false)
+	if _, err := k.Sign(make([]byte, 32), prvID); err != nil {
+		t.Fatalf("failed to sign with unhealthy leader: %v", err)
+	}
+	if follower.signed != 1 {
+		t.Fatalf("follower signatures: have %d, want 1", follower.signed)
+	}
+}
@@ -0,0 +1,322 @@
+package keeper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/accounts/usbwallet"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// hashSigningWallet is an accounts.Wallet able to sign raw 32 byte hashes.
+type hashSigningWallet interface {
+	accounts.Wallet
+	SignHash(account accounts.Account, hash []byte) ([]byte, error)
+}
+
+// publicKeyWallet is an accounts.Wallet revealing the public keys of its
+// accounts, like the Ledger wallets of the usbwallet package.
+type publicKeyWallet interface {
+	accounts.Wallet
+	DerivePublicKey(path accounts.DerivationPath) ([]byte, error)
+}
+
+// ledgerKeeper is a PrivateKeyKeeper using the keys of a Ledger hardware
+// wallet. The keys are derived on the device from its seed, so they can't be
+// generated, imported or deleted through the keeper. The prvID is the
+// derivation path of the key in its string form, e.g. m/44'/60'/0'/0/0.
+//
+// The Ethereum app of the Ledger only signs transactions and EIP-712 messages it
+// can display to the user, never bare hashes. The keeper is therefore a
+// RawSigner signing the pre-images of those, which SecureSign hands it for
+// transactions with the HashByKeeper strategy. Sign needs a wallet with a
+// SignHash method, which the usbwallet driver doesn't have, so with a Ledger it
+// fails with ErrNotSupported.
+type ledgerKeeper struct {
+	wallet accounts.Wallet
+	path   accounts.DerivationPath
+
+	pubkeys sync.Map // derivation path -> uncompressed public key
+}
+
+// NewLedgerKeeper returns a PrivateKeyKeeper using the first Ledger connected
+// over USB. ListPrivateKeys lists the key at derivationPath.
+func NewLedgerKeeper(derivationPath accounts.DerivationPath) (PrivateKeyKeeper, error) {
+	hub, err := usbwallet.NewLedgerHub()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrBackendUnavailable, err)
+	}
+	wallets := hub.Wallets()
+	if len(wallets) == 0 {
+		return nil, fmt.Errorf("%w: no Ledger found", ErrBackendUnavailable)
+	}
+	if err := wallets[0].Open(""); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrBackendUnavailable, err)
+	}
+	return newLedgerKeeper(wallets[0], derivationPath), nil
+}
+
+func newLedgerKeeper(wallet accounts.Wallet, path accounts.DerivationPath) *ledgerKeeper {
+	return &ledgerKeeper{wallet: wallet, path: path}
+}
+
+// ledgerError marks failures of a wallet that is closed or lost its device as
+// ErrBackendUnavailable.
+func (k *ledgerKeeper) ledgerError(err error) error {
+	if errors.Is(err, accounts.ErrWalletClosed) {
+		return fmt.Errorf("%w: %w", ErrBackendUnavailable, err)
+	}
+	if _, failure := k.wallet.Status(); failure != nil {
+		return fmt.Errorf("%w: %w", ErrBackendUnavailable, err)
+	}
+	return err
+}
+
+// derive returns the account of the key prvID and its derivation path. The
+// account is pinned, as the wallet only signs with the accounts it tracks.
+func (k *ledgerKeeper) derive(prvID []byte) (accounts.Account, accounts.DerivationPath, error) {
+	path, err := accounts.ParseDerivationPath(string(prvID))
+	if err != nil {
+		return accounts.Account{}, nil, fmt.Errorf("%w: %w", ErrKeyNotFound, err)
+	}
+	account, err := k.wallet.Derive(path, true)
+	if err != nil {
+		return accounts.Account{}, nil, k.ledgerError(err)
+	}
+	return account, path, nil
+}
+
+// GeneratePrivateKey returns ErrNotSupported, the keys of the Ledger are
+// derived from its seed.
+func (k *ledgerKeeper) GeneratePrivateKey() (_ []byte, err error) {
+	defer wrapError(&err, "generate key", nil)
+	return nil, ErrNotSupported
+}
+
+// ImportPrivateKey returns ErrNotSupported, the keys of the Ledger are derived
+// from its seed.
+func (k *ledgerKeeper) ImportPrivateKey(rawKey []byte) (_ []byte, err error) {
+	defer wrapError(&err, "import key", nil)
+	return nil, ErrNotSupported
+}
+
+// GetPublicKey returns the public key of the account derived at prvID, as
+// revealed by the device and checked against the address of the account.
+func (k *ledgerKeeper) GetPublicKey(prvID []byte) (_ []byte, err error) {
+	defer wrapError(&err, "get public key", prvID)
+
+	if pub, ok := k.pubkeys.Load(string(prvID)); ok {
+		return common.CopyBytes(pub.([]byte)), nil
+	}
+	account, path, err := k.derive(prvID)
+	if err != nil {
+		return nil, err
+	}
+	w, ok := k.wallet.(publicKeyWallet)
+	if !ok {
+		return nil, fmt.Errorf("%w: wallet does not reveal public keys", ErrNotSupported)
+	}
+	pubBytes, err := w.DerivePublicKey(path)
+	if err != nil {
+		return nil, k.ledgerError(err)
+	}
+	pub, err := crypto.UnmarshalPubkey(pubBytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid public key from wallet: %v", err)
+	}
+	if addr := crypto.PubkeyToAddress(*pub); addr != account.Address {
+		return nil, fmt.Errorf("public key of %v returned for %v", addr, account.Address)
+	}
+	k.pubkeys.Store(string(prvID), pubBytes)
+	return common.CopyBytes(pubBytes), nil
+}
+
+// Sign signs the hash data, which needs a wallet signing hashes.
+func (k *ledgerKeeper) Sign(data []byte, prvID []byte) (_ []byte, err error) {
+	defer wrapError(&err, "sign", prvID)
+
+	w, ok := k.wallet.(hashSigningWallet)
+	if !ok {
+		return nil, fmt.Errorf("%w: wallet does not sign hashes", ErrNotSupported)
+	}
+	account, _, err := k.derive(prvID)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := w.SignHash(account, data)
+	if err != nil {
+		return nil, k.ledgerError(err)
+	}
+	return ledgerSignature(sig)
+}
+
+// SignRaw has the device sign the Keccak-256 hash of preimage, which is either
+// the signing pre-image of a legacy, access list or dynamic fee transaction, or
+// the 0x19 0x01 || domainSeparator || hashStruct(message) pre-image of EIP-712
+// typed data. The user confirms the signature on the device. The signature is
+// checked to recover the account of prvID over the hash of preimage, so the
+// device can't have signed a differently encoded transaction.
+func (k *ledgerKeeper) SignRaw(ctx context.Context, preimage []byte, prvID []byte) (_ []byte, err error) {
+	defer wrapError(&err, "sign", prvID)
+
+	account, _, err := k.derive(prvID)
+	if err != nil {
+		return nil, err
+	}
+	var sig []byte
+	if len(preimage) == 66 && preimage[0] == 0x19 && preimage[1] == 0x01 {
+		if sig, err = k.wallet.SignData(account, accounts.MimetypeTypedData, preimage); err != nil {
+			return nil, k.ledgerError(err)
+		}
+	} else {
+		tx, chainID, err := decodeSigningPreimage(preimage)
+		if err != nil {
+			return nil, err
+		}
+		signed, err := k.wallet.SignTx(account, tx, chainID)
+		if err != nil {
+			return nil, k.ledgerError(err)
+		}
+		sig = transactionSignature(signed, chainID)
+	}
+	if sig, err = ledgerSignature(sig); err != nil {
+		return nil, err
+	}
+	pub, err := crypto.SigToPub(crypto.Keccak256(preimage), sig)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSignature, err)
+	}
+	if addr := crypto.PubkeyToAddress(*pub); addr != account.Address {
+		return nil, fmt.Errorf("%w: signed by %v, want %v", ErrInvalidSignature, addr, account.Address)
+	}
+	return sig, nil
+}
+
+// ledgerSignature checks the length of a signature returned by the wallet and
+// returns it with a V of 0 or 1.
+func ledgerSignature(sig []byte) ([]byte, error) {
+	if len(sig) != crypto.SignatureLength {
+		return nil, fmt.Errorf("%w: wallet returned %d byte signature", ErrInvalidSignature, len(sig))
+	}
+	return NormaliseSignature(sig)
+}
+
+// decodeSigningPreimage decodes the signing pre-image of a transaction of the
+// types the Ledger signs, returning the chain ID it is signed for, nil for
+// legacy transactions without replay protection.
+func decodeSigningPreimage(preimage []byte) (*types.Transaction, *big.Int, error) {
+	if len(preimage) == 0 {
+		return nil, nil, errors.New("empty transaction pre-image")
+	}
+	switch preimage[0] {
+	case types.AccessListTxType:
+		var fields struct {
+			ChainID    *big.Int
+			Nonce      uint64
+			GasPrice   *big.Int
+			Gas        uint64
+			To         *common.Address `rlp:"nil"`
+			Value      *big.Int
+			Data       []byte
+			AccessList types.AccessList
+		}
+		if err := rlp.DecodeBytes(preimage[1:], &fields); err != nil {
+			return nil, nil, fmt.Errorf("invalid access list transaction pre-image: %v", err)
+		}
+		return types.NewTx(&types.AccessListTx{
+			ChainID: fields.ChainID, Nonce: fields.Nonce, GasPrice: fields.GasPrice,
+			Gas: fields.Gas, To: fields.To, Value: fields.Value, Data: fields.Data, AccessList: fields.AccessList,
+		}), fields.ChainID, nil
+
+	case types.DynamicFeeTxType:
+		var fields struct {
+			ChainID    *big.Int
+			Nonce      uint64
+			GasTipCap  *big.Int
+			GasFeeCap  *big.Int
+			Gas        uint64
+			To         *common.Address `rlp:"nil"`
+			Value      *big.Int
+			Data       []byte
+			AccessList types.AccessList
+		}
+		if err := rlp.DecodeBytes(preimage[1:], &fields); err != nil {
+			return nil, nil, fmt.Errorf("invalid dynamic fee transaction pre-image: %v", err)
+		}
+		return types.NewTx(&types.DynamicFeeTx{
+			ChainID: fields.ChainID, Nonce: fields.Nonce, GasTipCap: fields.GasTipCap, GasFeeCap: fields.GasFeeCap,
+			Gas: fields.Gas, To: fields.To, Value: fields.Value, Data: fields.Data, AccessList: fields.AccessList,
+		}), fields.ChainID, nil
+	}
+	if preimage[0] < 0xc0 {
+		return nil, nil, fmt.Errorf("%w: pre-image of transaction type %d", ErrNotSupported, preimage[0])
+	}
+	var fields struct {
+		Nonce    uint64
+		GasPrice *big.Int
+		Gas      uint64
+		To       *common.Address `rlp:"nil"`
+		Value    *big.Int
+		Data     []byte
+		ChainID  *big.Int `rlp:"optional"`
+		R, S     uint     `rlp:"optional"`
+	}
+	if err := rlp.DecodeBytes(preimage, &fields); err != nil {
+		return nil, nil, fmt.Errorf("invalid legacy transaction pre-image: %v", err)
+	}
+	tx := types.NewTx(&types.LegacyTx{
+		Nonce: fields.Nonce, GasPrice: fields.GasPrice, Gas: fields.Gas, To: fields.To, Value: fields.Value, Data: fields.Data,
+	})
+	if fields.ChainID == nil || fields.ChainID.Sign() == 0 {
+		return tx, nil, nil
+	}
+	return tx, fields.ChainID, nil
+}
+
+// transactionSignature returns the [R || S || V] signature of tx signed for
+// chainID, with the recovery id as V.
+func transactionSignature(tx *types.Transaction, chainID *big.Int) []byte {
+	v, r, s := tx.RawSignatureValues()
+	if tx.Type() == types.LegacyTxType {
+		if chainID != nil {
+			v = new(big.Int).Sub(v, new(big.Int).Add(new(big.Int).Lsh(chainID, 1), big.NewInt(35)))
+		} else {
+			v = new(big.Int).Sub(v, big.NewInt(27))
+		}
+	}
+	sig := make([]byte, crypto.SignatureLength)
+	r.FillBytes(sig[:32])
+	s.FillBytes(sig[32:64])
+	sig[crypto.RecoveryIDOffset] = byte(v.Uint64())
+	return sig
+}
+
+// DeletePrivateKey returns ErrNotSupported, the keys of the Ledger are derived
+// from its seed.
+func (k *ledgerKeeper) DeletePrivateKey(prvID []byte) (err error) {
+	defer wrapError(&err, "delete key", prvID)
+	return ErrNotSupported
+}
+
+// ListPrivateKeys returns the key at the derivation path of the keeper.
+func (k *ledgerKeeper) ListPrivateKeys() ([][]byte, error) {
+	return [][]byte{[]byte(k.path.String())}, nil
+}
+
+// HealthCheck reports the failure of the wallet, such as a lost device, as
+// ErrBackendUnavailable.
+func (k *ledgerKeeper) HealthCheck(ctx context.Context) (err error) {
+	defer wrapError(&err, "health check", nil)
+
+	if _, failure := k.wallet.Status(); failure != nil {
+		return fmt.Errorf("%w: %w", ErrBackendUnavailable, failure)
+	}
+	return nil
+}
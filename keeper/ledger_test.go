@@ -0,0 +1,280 @@
+package keeper
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// fakeLedger is an accounts.Wallet deriving a key per path from the hash of
+// the path. Like the Ledger driver of usbwallet it reveals public keys and signs
+// transactions and EIP-712 messages, but no bare hashes.
+type fakeLedger struct {
+	offline bool
+	signs   int
+}
+
+func (w *fakeLedger) key(path accounts.DerivationPath) *ecdsa.PrivateKey {
+	key, _ := crypto.ToECDSA(crypto.Keccak256([]byte(path.String())))
+	return key
+}
+
+// accountKey returns the key of an account derived by the wallet.
+func (w *fakeLedger) accountKey(account accounts.Account) *ecdsa.PrivateKey {
+	path, _ := accounts.ParseDerivationPath(account.URL.Path[len("fake/"):])
+	return w.key(path)
+}
+
+func (w *fakeLedger) URL() accounts.URL { return accounts.URL{Scheme: "ledger", Path: "fake"} }
+
+func (w *fakeLedger) Status() (string, error) {
+	if w.offline {
+		return "Failed", errors.New("ledger: device disconnected")
+	}
+	return "Ethereum app online", nil
+}
+
+func (w *fakeLedger) Open(passphrase string) error                                    { return nil }
+func (w *fakeLedger) Close() error                                                    { return nil }
+func (w *fakeLedger) Accounts() []accounts.Account                                    { return nil }
+func (w *fakeLedger) Contains(accounts.Account) bool                                  { return false }
+func (w *fakeLedger) SelfDerive([]accounts.DerivationPath, ethereum.ChainStateReader) {}
+
+func (w *fakeLedger) Derive(path accounts.DerivationPath, pin bool) (accounts.Account, error) {
+	if w.offline {
+		return accounts.Account{}, accounts.ErrWalletClosed
+	}
+	key := w.key(path)
+	return accounts.Account{Address: crypto.PubkeyToAddress(key.PublicKey), URL: accounts.URL{Scheme: "ledger", Path: "fake/" + path.String()}}, nil
+}
+
+func (w *fakeLedger) DerivePublicKey(path accounts.DerivationPath) ([]byte, error) {
+	if w.offline {
+		return nil, accounts.ErrWalletClosed
+	}
+	return crypto.FromECDSAPub(&w.key(path).PublicKey), nil
+}
+
+func (w *fakeLedger) SignData(account accounts.Account, mimeType string, data []byte) ([]byte, error) {
+	if mimeType != accounts.MimetypeTypedData || len(data) != 66 {
+		return nil, accounts.ErrNotSupported
+	}
+	if w.offline {
+		return nil, errors.New("hidapi: failed to write")
+	}
+	w.signs++
+	sig, err := crypto.Sign(crypto.Keccak256(data), w.accountKey(account))
+	if err != nil {
+		return nil, err
+	}
+	sig[crypto.RecoveryIDOffset] += 27
+	return sig, nil
+}
+
+func (w *fakeLedger) SignDataWithPassphrase(accounts.Account, string, string, []byte) ([]byte, error) {
+	return nil, accounts.ErrNotSupported
+}
+
+func (w *fakeLedger) SignText(accounts.Account, []byte) ([]byte, error) {
+	return nil, accounts.ErrNotSupported
+}
+
+func (w *fakeLedger) SignTextWithPassphrase(accounts.Account, string, []byte) ([]byte, error) {
+	return nil, accounts.ErrNotSupported
+}
+
+func (w *fakeLedger) SignTx(account accounts.Account, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	if w.offline {
+		return nil, errors.New("hidapi: failed to write")
+	}
+	w.signs++
+	var signer types.Signer = types.HomesteadSigner{}
+	if chainID != nil {
+		signer = types.LatestSignerForChainID(chainID)
+	}
+	return types.SignTx(tx, signer, w.accountKey(account))
+}
+
+func (w *fakeLedger) SignTxWithPassphrase(accounts.Account, string, *types.Transaction, *big.Int) (*types.Transaction, error) {
+	return nil, accounts.ErrNotSupported
+}
+
+// hashSigningLedger is a fakeLedger signing bare hashes as well, with a V of 27
+// or 28.
+type hashSigningLedger struct {
+	*fakeLedger
+}
+
+func (w hashSigningLedger) SignHash(account accounts.Account, hash []byte) ([]byte, error) {
+	if w.offline {
+		return nil, errors.New("hidapi: failed to write")
+	}
+	w.signs++
+	sig, err := crypto.Sign(hash, w.accountKey(account))
+	if err != nil {
+		return nil, err
+	}
+	sig[crypto.RecoveryIDOffset] += 27
+	return sig, nil
+}
+
+func TestLedgerKeeper(t *testing.T) {
+	wallet := new(fakeLedger)
+	k := newLedgerKeeper(wallet, accounts.DefaultBaseDerivationPath)
+
+	prvIDs, err := k.ListPrivateKeys()
+	if err != nil || len(prvIDs) != 1 || string(prvIDs[0]) != "m/44'/60'/0'/0/0" {
+		t.Fatalf("listed keys: have (%q, %v), want [m/44'/60'/0'/0/0]", prvIDs, err)
+	}
+	prvID := prvIDs[0]
+	pub, err := k.GetPublicKey(prvID)
+	if err != nil {
+		t.Fatalf("failed to get public key: %v", err)
+	}
+	want := wallet.key(accounts.DefaultBaseDerivationPath)
+	if !bytes.Equal(pub, crypto.FromECDSAPub(&want.PublicKey)) {
+		t.Fatalf("public key mismatch: have %x, want %x", pub, crypto.FromECDSAPub(&want.PublicKey))
+	}
+	if wallet.signs != 0 {
+		t.Fatalf("public key lookup signed %d times", wallet.signs)
+	}
+	// The public key is cached, the device isn't asked again.
+	wallet.offline = true
+	if _, err := k.GetPublicKey(prvID); err != nil {
+		t.Fatalf("public key not cached: %v", err)
+	}
+	wallet.offline = false
+
+	// Keys of other paths are derived on the device as well.
+	other, err := k.GetPublicKey([]byte("m/44'/60'/0'/0/1"))
+	if err != nil || bytes.Equal(other, pub) {
+		t.Fatalf("public key of other path: have (%x, %v)", other, err)
+	}
+	if _, err := k.SignRaw(context.Background(), nil, []byte("not a path")); !errors.Is(err, ErrKeyNotFound) {
+		t.Fatalf("sign with invalid path: have %v, want %v", err, ErrKeyNotFound)
+	}
+	for name, err := range map[string]error{
+		"generate": func() error { _, err := k.GeneratePrivateKey(); return err }(),
+		"import":   func() error { _, err := k.ImportPrivateKey(crypto.FromECDSA(want)); return err }(),
+		"delete":   k.DeletePrivateKey(prvID),
+	} {
+		if !errors.Is(err, ErrNotSupported) {
+			t.Errorf("%s: have %v, want %v", name, err, ErrNotSupported)
+		}
+	}
+}
+
+func TestLedgerKeeperSignRaw(t *testing.T) {
+	var (
+		wallet  = new(fakeLedger)
+		k       = newLedgerKeeper(wallet, accounts.DefaultBaseDerivationPath)
+		sec     = NewSecureSign(k, WithHashingStrategy(HashByKeeper))
+		prvID   = []byte(accounts.DefaultBaseDerivationPath.String())
+		addr    = crypto.PubkeyToAddress(wallet.key(accounts.DefaultBaseDerivationPath).PublicKey)
+		chainID = big.NewInt(1337)
+		to      = common.HexToAddress("0x0102030405060708090a0b0c0d0e0f1011121314")
+		list    = types.AccessList{{Address: to, StorageKeys: []common.Hash{{1}}}}
+	)
+	// Transactions are handed to the device, which signs what it displays.
+	tests := []struct {
+		tx     *types.Transaction
+		signer types.Signer
+	}{
+		{types.NewTx(&types.LegacyTx{Nonce: 1, GasPrice: big.NewInt(1), Gas: 21000, To: &to, Value: big.NewInt(1)}), types.HomesteadSigner{}},
+		{types.NewTx(&types.LegacyTx{Nonce: 1, GasPrice: big.NewInt(1), Gas: 53000, Data: []byte{0x60}}), types.NewEIP155Signer(chainID)},
+		{types.NewTx(&types.AccessListTx{ChainID: chainID, Nonce: 2, GasPrice: big.NewInt(1), Gas: 30000, To: &to, AccessList: list}), NewChainSigner(chainID)},
+		{types.NewTx(&types.DynamicFeeTx{ChainID: chainID, Nonce: 3, GasTipCap: big.NewInt(1), GasFeeCap: big.NewInt(10), Gas: 21000, To: &to, Value: big.NewInt(2)}), NewChainSigner(chainID)},
+	}
+	for i, tt := range tests {
+		signed, err := sec.Sign(tt.tx, tt.signer, prvID)
+		if err != nil {
+			t.Fatalf("test %d: failed to sign type %d: %v", i, tt.tx.Type(), err)
+		}
+		if from, err := types.Sender(tt.signer, signed); err != nil || from != addr {
+			t.Fatalf("test %d: sender mismatch: have (%x, %v), want %x", i, from, err, addr)
+		}
+	}
+	if wallet.signs != len(tests) {
+		t.Fatalf("device signatures: have %d, want %d", wallet.signs, len(tests))
+	}
+
+	// EIP-712 pre-images are signed as typed data.
+	preimage := append([]byte{0x19, 0x01}, make([]byte, 64)...)
+	preimage[2], preimage[34] = 0xaa, 0xbb
+	sig, err := k.SignRaw(context.Background(), preimage, prvID)
+	if err != nil {
+		t.Fatalf("failed to sign typed data: %v", err)
+	}
+	if pub, err := crypto.SigToPub(crypto.Keccak256(preimage), sig); err != nil || crypto.PubkeyToAddress(*pub) != addr {
+		t.Fatalf("typed data signer mismatch: have (%v, %v), want %x", pub, err, addr)
+	}
+
+	// Hashes and the pre-images of types the device can't display are refused.
+	if _, err := k.Sign(make([]byte, 32), prvID); !errors.Is(err, ErrNotSupported) {
+		t.Fatalf("sign hash: have %v, want %v", err, ErrNotSupported)
+	}
+	if _, err := k.SignRaw(context.Background(), []byte{types.BlobTxType, 0xc0}, prvID); !errors.Is(err, ErrNotSupported) {
+		t.Fatalf("sign blob transaction: have %v, want %v", err, ErrNotSupported)
+	}
+	if _, err := k.SignRaw(context.Background(), []byte{types.DynamicFeeTxType, 0xc0}, prvID); err == nil {
+		t.Fatal("signed malformed pre-image")
+	}
+}
+
+func TestLedgerKeeperHashSigning(t *testing.T) {
+	// Wallets signing hashes are used by Sign.
+	wallet := hashSigningLedger{new(fakeLedger)}
+	k := newLedgerKeeper(wallet, accounts.DefaultBaseDerivationPath)
+	prvID := []byte(accounts.DefaultBaseDerivationPath.String())
+
+	sec := NewSecureSigner(k)
+	signer := types.NewEIP155Signer(big.NewInt(1))
+	tx, err := sec.Sign(newTestTx(), signer, prvID)
+	if err != nil {
+		t.Fatalf("failed to sign: %v", err)
+	}
+	want := crypto.PubkeyToAddress(wallet.key(accounts.DefaultBaseDerivationPath).PublicKey)
+	if from, err := types.Sender(signer, tx); err != nil || from != want {
+		t.Fatalf("sender mismatch: have (%x, %v), want %x", from, err, want)
+	}
+	if _, err := k.Sign(make([]byte, 32), []byte("not a path")); !errors.Is(err, ErrKeyNotFound) {
+		t.Fatalf("sign with invalid path: have %v, want %v", err, ErrKeyNotFound)
+	}
+	// Wallets not revealing public keys can't be used to look them up.
+	k = newLedgerKeeper(struct{ accounts.Wallet }{wallet}, accounts.DefaultBaseDerivationPath)
+	if _, err := k.GetPublicKey(prvID); !errors.Is(err, ErrNotSupported) {
+		t.Fatalf("public key without derivation: have %v, want %v", err, ErrNotSupported)
+	}
+}
+
+func TestLedgerKeeperDisconnected(t *testing.T) {
+	wallet := new(fakeLedger)
+	k := newLedgerKeeper(wallet, accounts.DefaultBaseDerivationPath)
+	prvID := []byte(accounts.DefaultBaseDerivationPath.String())
+	if _, err := k.GetPublicKey(prvID); err != nil {
+		t.Fatalf("failed to get public key: %v", err)
+	}
+	if err := k.HealthCheck(context.Background()); err != nil {
+		t.Fatalf("connected wallet: have %v, want healthy", err)
+	}
+	wallet.offline = true
+	if err := k.HealthCheck(context.Background()); !errors.Is(err, ErrBackendUnavailable) {
+		t.Fatalf("health check while disconnected: have %v, want %v", err, ErrBackendUnavailable)
+	}
+	preimage, _ := signingPreimage(newTestTx(), types.HomesteadSigner{})
+	if _, err := k.SignRaw(context.Background(), preimage, prvID); !errors.Is(err, ErrBackendUnavailable) {
+		t.Fatalf("sign while disconnected: have %v, want %v", err, ErrBackendUnavailable)
+	}
+	if _, err := k.GetPublicKey([]byte("m/44'/60'/0'/0/1")); !errors.Is(err, ErrBackendUnavailable) {
+		t.Fatalf("public key while disconnected: have %v, want %v", err, ErrBackendUnavailable)
+	}
+}
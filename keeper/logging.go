@@ -0,0 +1,271 @@
+package keeper
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+)
+
+// loggingSigner is a SecureSigner logging the calls to another signer.
+type loggingSigner struct {
+	inner  SecureSigner
+	logger *slog.Logger
+}
+
+// NewLoggingSecureSigner returns a SecureSigner logging every call to inner to
+// logger, successful calls at debug level and failed ones as errors. The
+// records carry the operation, the key_id_prefix of the metrics, the duration
+// and the error, never keys, data or signatures.
+func NewLoggingSecureSigner(inner SecureSigner, logger *slog.Logger) SecureSigner {
+	return &loggingSigner{inner: inner, logger: logger}
+}
+
+// log records a call of op on the key prvID that started at start, to be
+// deferred by the logged methods.
+func (s *loggingSigner) log(op string, prvID []byte, start time.Time, err *error) {
+	logCall(s.logger, "Secure signer call", op, prvID, start, *err)
+}
+
+// logCall logs a call of op on the key prvID that started at start and failed
+// with err, if not nil. prvIDs are logged as their key_id_prefix only, as the
+// prvIDs of some keepers are the private keys.
+func logCall(logger *slog.Logger, msg, op string, prvID []byte, start time.Time, err error) {
+	attrs := []any{"op", op, "duration", time.Since(start)}
+	if prvID != nil {
+		attrs = append(attrs, "key_id", keyIDPrefix(prvID))
+	}
+	if err != nil {
+		logger.Error(msg+" failed", append(attrs, "err", err)...)
+		return
+	}
+	logger.Debug(msg, attrs...)
+}
+
+// loggingKeeper is a PrivateKeyKeeper logging the calls to another keeper.
+type loggingKeeper struct {
+	inner  PrivateKeyKeeperContext
+	logger *slog.Logger
+}
+
+// NewLoggingKeeper returns a PrivateKeyKeeper logging every call to inner to
+// logger, as NewLoggingSecureSigner does for signers.
+func NewLoggingKeeper(inner PrivateKeyKeeper, logger *slog.Logger) PrivateKeyKeeper {
+	return &loggingKeeper{inner: ContextKeeper(inner), logger: logger}
+}
+
+// log records a call of op on the key prvID that started at start, to be
+// deferred by the logged methods.
+func (k *loggingKeeper) log(op string, prvID []byte, start time.Time, err *error) {
+	logCall(k.logger, "Keeper call", op, prvID, start, *err)
+}
+
+func (k *loggingKeeper) GeneratePrivateKey() ([]byte, error) {
+	return k.GeneratePrivateKeyContext(context.Background())
+}
+
+func (k *loggingKeeper) GeneratePrivateKeyContext(ctx context.Context) (prvID []byte, err error) {
+	defer func(start time.Time) { k.log("generate_key", prvID, start, &err) }(time.Now())
+	return k.inner.GeneratePrivateKeyContext(ctx)
+}
+
+func (k *loggingKeeper) GetPublicKey(prvID []byte) ([]byte, error) {
+	return k.GetPublicKeyContext(context.Background(), prvID)
+}
+
+func (k *loggingKeeper) GetPublicKeyContext(ctx context.Context, prvID []byte) (_ []byte, err error) {
+	defer k.log("get_public_key", prvID, time.Now(), &err)
+	return k.inner.GetPublicKeyContext(ctx, prvID)
+}
+
+func (k *loggingKeeper) Sign(data []byte, prvID []byte) ([]byte, error) {
+	return k.SignContext(context.Background(), data, prvID)
+}
+
+func (k *loggingKeeper) SignContext(ctx context.Context, data []byte, prvID []byte) (_ []byte, err error) {
+	defer k.log("sign", prvID, time.Now(), &err)
+	return k.inner.SignContext(ctx, data, prvID)
+}
+
+func (k *loggingKeeper) DeletePrivateKey(prvID []byte) error {
+	return k.DeletePrivateKeyContext(context.Background(), prvID)
+}
+
+func (k *loggingKeeper) DeletePrivateKeyContext(ctx context.Context, prvID []byte) (err error) {
+	defer k.log("delete_key", prvID, time.Now(), &err)
+	return k.inner.DeletePrivateKeyContext(ctx, prvID)
+}
+
+func (k *loggingKeeper) ListPrivateKeys() ([][]byte, error) {
+	return k.ListPrivateKeysContext(context.Background())
+}
+
+func (k *loggingKeeper) ListPrivateKeysContext(ctx context.Context) (_ [][]byte, err error) {
+	defer k.log("list_keys", nil, time.Now(), &err)
+	return k.inner.ListPrivateKeysContext(ctx)
+}
+
+func (k *loggingKeeper) ImportPrivateKey(rawKey []byte) ([]byte, error) {
+	return k.ImportPrivateKeyContext(context.Background(), rawKey)
+}
+
+func (k *loggingKeeper) ImportPrivateKeyContext(ctx context.Context, rawKey []byte) (prvID []byte, err error) {
+	defer func(start time.Time) { k.log("import_key", prvID, start, &err) }(time.Now())
+	return k.inner.ImportPrivateKeyContext(ctx, rawKey)
+}
+
+func (k *loggingKeeper) HealthCheck(ctx context.Context) error {
+	return checkHealth(ctx, k.inner)
+}
+
+func (s *loggingSigner) GenerateKey() (_ []byte, err error) {
+	defer s.log("generate_key", nil, time.Now(), &err)
+	return s.inner.GenerateKey()
+}
+
+func (s *loggingSigner) GenerateKeyContext(ctx context.Context) (_ []byte, err error) {
+	defer s.log("generate_key", nil, time.Now(), &err)
+	return signerContext(s.inner).GenerateKeyContext(ctx)
+}
+
+func (s *loggingSigner) GetPublicKey(prvID []byte) (_ []byte, err error) {
+	defer s.log("get_public_key", prvID, time.Now(), &err)
+	return s.inner.GetPublicKey(prvID)
+}
+
+func (s *loggingSigner) GetPublicKeyContext(ctx context.Context, prvID []byte) (_ []byte, err error) {
+	defer s.log("get_public_key", prvID, time.Now(), &err)
+	return signerContext(s.inner).GetPublicKeyContext(ctx, prvID)
+}
+
+func (s *loggingSigner) GetPublicKeyCompressed(prvID []byte) (_ []byte, err error) {
+	defer s.log("get_public_key_compressed", prvID, time.Now(), &err)
+	return s.inner.GetPublicKeyCompressed(prvID)
+}
+
+func (s *loggingSigner) Sign(tx *types.Transaction, signer types.Signer, prvID []byte) (_ *types.Transaction, err error) {
+	defer s.log("sign", prvID, time.Now(), &err)
+	return s.inner.Sign(tx, signer, prvID)
+}
+
+func (s *loggingSigner) SignContext(ctx context.Context, tx *types.Transaction, signer types.Signer, prvID []byte) (_ *types.Transaction, err error) {
+	defer s.log("sign", prvID, time.Now(), &err)
+	return signerContext(s.inner).SignContext(ctx, tx, signer, prvID)
+}
+
+func (s *loggingSigner) SignTypedData(typedData apitypes.TypedData, prvID []byte) (_ []byte, err error) {
+	defer s.log("sign_typed_data", prvID, time.Now(), &err)
+	return s.inner.SignTypedData(typedData, prvID)
+}
+
+func (s *loggingSigner) SignPermit(chainID *big.Int, tokenAddr common.Address, tokenName, tokenVersion string, ownerAddr, spenderAddr common.Address, value, nonce *big.Int, deadline int64, prvID []byte) (_ uint8, _, _ [32]byte, err error) {
+	defer s.log("sign_permit", prvID, time.Now(), &err)
+	return s.inner.SignPermit(chainID, tokenAddr, tokenName, tokenVersion, ownerAddr, spenderAddr, value, nonce, deadline, prvID)
+}
+
+func (s *loggingSigner) SignUserOperation(chainID *big.Int, entryPoint common.Address, op UserOperation, prvID []byte) (_ []byte, err error) {
+	defer s.log("sign_user_operation", prvID, time.Now(), &err)
+	return s.inner.SignUserOperation(chainID, entryPoint, op, prvID)
+}
+
+func (s *loggingSigner) SignHash(hash common.Hash, prvID []byte) (_ []byte, err error) {
+	defer s.log("sign_hash", prvID, time.Now(), &err)
+	return s.inner.SignHash(hash, prvID)
+}
+
+func (s *loggingSigner) SignHashBytes(data []byte, prvID []byte) (_ []byte, err error) {
+	defer s.log("sign_hash_bytes", prvID, time.Now(), &err)
+	return s.inner.SignHashBytes(data, prvID)
+}
+
+func (s *loggingSigner) SignPersonalMessage(message []byte, prvID []byte) (_ []byte, err error) {
+	defer s.log("sign_personal_message", prvID, time.Now(), &err)
+	return s.inner.SignPersonalMessage(message, prvID)
+}
+
+func (s *loggingSigner) VerifyPersonalMessage(message, sig []byte, expectedAddr common.Address) (err error) {
+	defer s.log("verify_personal_message", nil, time.Now(), &err)
+	return s.inner.VerifyPersonalMessage(message, sig, expectedAddr)
+}
+
+func (s *loggingSigner) SignForChain(chainID *big.Int, tx *types.Transaction, prvID []byte) (_ *types.Transaction, err error) {
+	defer s.log("sign_for_chain", prvID, time.Now(), &err)
+	return s.inner.SignForChain(chainID, tx, prvID)
+}
+
+func (s *loggingSigner) SignTransactionJSON(tx *types.Transaction, signer types.Signer, prvID []byte) (_ json.RawMessage, err error) {
+	defer s.log("sign_transaction_json", prvID, time.Now(), &err)
+	return s.inner.SignTransactionJSON(tx, signer, prvID)
+}
+
+func (s *loggingSigner) SignAndEncode(tx *types.Transaction, signer types.Signer, prvID []byte) (_ []byte, err error) {
+	defer s.log("sign_and_encode", prvID, time.Now(), &err)
+	return s.inner.SignAndEncode(tx, signer, prvID)
+}
+
+func (s *loggingSigner) SignAndEncodeHex(tx *types.Transaction, signer types.Signer, prvID []byte) (_ string, err error) {
+	defer s.log("sign_and_encode_hex", prvID, time.Now(), &err)
+	return s.inner.SignAndEncodeHex(tx, signer, prvID)
+}
+
+func (s *loggingSigner) SignDynamicFeeTx(chainID *big.Int, nonce uint64, to common.Address, value *big.Int, gasLimit uint64, maxFeePerGas, maxPriorityFeePerGas *big.Int, data []byte, prvID []byte) (_ *types.Transaction, err error) {
+	defer s.log("sign_dynamic_fee_tx", prvID, time.Now(), &err)
+	return s.inner.SignDynamicFeeTx(chainID, nonce, to, value, gasLimit, maxFeePerGas, maxPriorityFeePerGas, data, prvID)
+}
+
+func (s *loggingSigner) SignBlobTx(chainID *big.Int, blobTx *types.BlobTx, prvID []byte) (_ *types.Transaction, err error) {
+	defer s.log("sign_blob_tx", prvID, time.Now(), &err)
+	return s.inner.SignBlobTx(chainID, blobTx, prvID)
+}
+
+func (s *loggingSigner) SignAccessListTx(chainID *big.Int, nonce uint64, to common.Address, value *big.Int, gasLimit uint64, gasPrice *big.Int, accessList types.AccessList, data []byte, prvID []byte) (_ *types.Transaction, err error) {
+	defer s.log("sign_access_list_tx", prvID, time.Now(), &err)
+	return s.inner.SignAccessListTx(chainID, nonce, to, value, gasLimit, gasPrice, accessList, data, prvID)
+}
+
+func (s *loggingSigner) SignContractCall(chainID *big.Int, contract common.Address, contractABI abi.ABI, method string, args []interface{}, nonce uint64, gasLimit uint64, gasPrice *big.Int, prvID []byte) (_ *types.Transaction, err error) {
+	defer s.log("sign_contract_call", prvID, time.Now(), &err)
+	return s.inner.SignContractCall(chainID, contract, contractABI, method, args, nonce, gasLimit, gasPrice, prvID)
+}
+
+func (s *loggingSigner) SimulateAndSign(ctx context.Context, tx *types.Transaction, signer types.Signer, client ethereum.ContractCaller, prvID []byte) (_ *types.Transaction, err error) {
+	defer s.log("simulate_and_sign", prvID, time.Now(), &err)
+	return s.inner.SimulateAndSign(ctx, tx, signer, client, prvID)
+}
+
+func (s *loggingSigner) SignBatch(txs []*types.Transaction, signer types.Signer, prvID []byte) (_ []*types.Transaction, err error) {
+	defer s.log("sign_batch", prvID, time.Now(), &err)
+	return s.inner.SignBatch(txs, signer, prvID)
+}
+
+func (s *loggingSigner) SignBatchParallel(txs []*types.Transaction, signer types.Signer, prvID []byte) (_ []*types.Transaction, err error) {
+	defer s.log("sign_batch_parallel", prvID, time.Now(), &err)
+	return s.inner.SignBatchParallel(txs, signer, prvID)
+}
+
+func (s *loggingSigner) GetAddress(prvID []byte) (_ common.Address, err error) {
+	defer s.log("get_address", prvID, time.Now(), &err)
+	return s.inner.GetAddress(prvID)
+}
+
+func (s *loggingSigner) VerifySignature(data, sig, prvID []byte) (_ bool, err error) {
+	defer s.log("verify_signature", prvID, time.Now(), &err)
+	return s.inner.VerifySignature(data, sig, prvID)
+}
+
+func (s *loggingSigner) RecoverSigner(data, sig []byte) (_ common.Address, err error) {
+	defer s.log("recover_signer", nil, time.Now(), &err)
+	return s.inner.RecoverSigner(data, sig)
+}
+
+func (s *loggingSigner) RecoverSenderFromTx(tx *types.Transaction, signer types.Signer) (_ common.Address, err error) {
+	defer s.log("recover_sender_from_tx", nil, time.Now(), &err)
+	return s.inner.RecoverSenderFromTx(tx, signer)
+}
@@ -0,0 +1,126 @@
+package keeper
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+func TestLoggingSecureSigner(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	s := NewSecureSigner(defaultKeeper, WithLogger(logger))
+
+	prvID, err := s.GenerateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	signer := types.LatestSigner(params.TestChainConfig)
+	if _, err := s.Sign(newTestTx(), signer, prvID); err != nil {
+		t.Fatalf("failed to sign: %v", err)
+	}
+	if _, err := s.Sign(newTestTx(), signer, []byte{0x01}); err == nil {
+		t.Fatal("signed with invalid key")
+	}
+	records := logRecords(t, &buf)
+	want := []struct {
+		op, level string
+		key       bool
+	}{
+		{"generate_key", "DEBUG", false},
+		{"sign", "DEBUG", true},
+		{"sign", "ERROR", true},
+	}
+	if len(records) != len(want) {
+		t.Fatalf("log records: have %d, want %d", len(records), len(want))
+	}
+	for i, w := range want {
+		rec := records[i]
+		if rec["op"] != w.op || rec["level"] != w.level {
+			t.Errorf("record %d: have %v %v, want %v %v", i, rec["level"], rec["op"], w.level, w.op)
+		}
+		if _, ok := rec["key_id"]; ok != w.key {
+			t.Errorf("record %d: key_id logged %v, want %v", i, ok, w.key)
+		}
+		if _, ok := rec["err"]; ok != (w.level == "ERROR") {
+			t.Errorf("record %d: error logged %v", i, ok)
+		}
+	}
+	checkKeyNotLogged(t, &buf, prvID)
+}
+
+// logRecords parses the JSON log records written to buf.
+func logRecords(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+	var records []map[string]any
+	for _, line := range bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n")) {
+		var rec map[string]any
+		if err := json.Unmarshal(line, &rec); err != nil {
+			t.Fatalf("invalid log line %q: %v", line, err)
+		}
+		records = append(records, rec)
+	}
+	return records
+}
+
+// checkKeyNotLogged fails the test if the raw key ended up in the log, in any
+// of the encodings slog and the errors of the package use for bytes.
+func checkKeyNotLogged(t *testing.T, buf *bytes.Buffer, key []byte) {
+	t.Helper()
+	for _, enc := range []string{hex.EncodeToString(key), base64.StdEncoding.EncodeToString(key), string(key)} {
+		if bytes.Contains(buf.Bytes(), []byte(enc)) {
+			t.Fatalf("private key written to log: %s", buf.Bytes())
+		}
+	}
+}
+
+func TestLoggingKeeper(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	k := NewLoggingKeeper(defaultKeeper, logger)
+
+	prvID, err := k.GeneratePrivateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	if _, err := k.GetPublicKey(prvID); err != nil {
+		t.Fatalf("failed to get public key: %v", err)
+	}
+	hash := crypto.Keccak256([]byte("logging"))
+	if _, err := k.Sign(hash, prvID); err != nil {
+		t.Fatalf("failed to sign: %v", err)
+	}
+	if _, err := k.Sign(hash[:31], prvID); err == nil {
+		t.Fatal("signed short hash")
+	}
+	records := logRecords(t, &buf)
+	want := []struct{ op, level string }{
+		{"generate_key", "DEBUG"},
+		{"get_public_key", "DEBUG"},
+		{"sign", "DEBUG"},
+		{"sign", "ERROR"},
+	}
+	if len(records) != len(want) {
+		t.Fatalf("log records: have %d, want %d", len(records), len(want))
+	}
+	for i, w := range want {
+		rec := records[i]
+		if rec["op"] != w.op || rec["level"] != w.level {
+			t.Errorf("record %d: have %v %v, want %v %v", i, rec["level"], rec["op"], w.level, w.op)
+		}
+		if rec["key_id"] != keyIDPrefix(prvID) {
+			t.Errorf("record %d: key_id %v, want %v", i, rec["key_id"], keyIDPrefix(prvID))
+		}
+		if _, ok := rec["duration"]; !ok {
+			t.Errorf("record %d: no duration", i)
+		}
+	}
+	checkKeyNotLogged(t, &buf, prvID)
+}
@@ -0,0 +1,127 @@
+package keeper
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// SignPersonalMessage signs the message the way personal_sign does, i.e. the
+// hash of "\x19Ethereum Signed Message:\n${len(message)}${message}". The returned
+// signature is in the [R || S || V] format where V is 0 or 1.
+func (sec *SecureSign) SignPersonalMessage(message []byte, prvID []byte) (_ []byte, err error) {
+	defer wrapError(&err, "sign message", prvID)
+	return sec.keeper.Sign(accounts.TextHash(message), prvID)
+}
+
+// SignHash signs the 32 byte hash as is, for contracts verifying signatures of
+// hashes they compute themselves. The hash must not be one of a transaction or
+// of a message a key is expected to sign otherwise, use the dedicated methods
+// for those. The returned signature is in the [R || S || V] format where V is
+// 0 or 1.
+func (sec *SecureSign) SignHash(hash common.Hash, prvID []byte) (_ []byte, err error) {
+	defer wrapError(&err, "sign hash", prvID)
+	return sec.keeper.Sign(hash[:], prvID)
+}
+
+// SignHashBytes signs the keccak256 hash of data with SignHash.
+func (sec *SecureSign) SignHashBytes(data []byte, prvID []byte) ([]byte, error) {
+	return sec.SignHash(crypto.Keccak256Hash(data), prvID)
+}
+
+// VerifyPersonalMessage checks that sig is a personal_sign signature of message
+// made by expectedAddr. Both the 0/1 and the legacy 27/28 V values are accepted.
+func (sec *SecureSign) VerifyPersonalMessage(message, sig []byte, expectedAddr common.Address) (err error) {
+	defer wrapError(&err, "verify message", nil)
+	addr, err := sec.RecoverSigner(accounts.TextHash(message), sig)
+	if err != nil {
+		return err
+	}
+	if addr != expectedAddr {
+		return fmt.Errorf("%w: message signed by %v, expected %v", ErrInvalidSignature, addr, expectedAddr)
+	}
+	return nil
+}
+
+// VerifySignature checks that sig is a signature of the hash data made by the
+// key prvID. A well-formed signature of a different key is reported as false
+// without an error.
+func (sec *SecureSign) VerifySignature(data, sig, prvID []byte) (_ bool, err error) {
+	defer wrapError(&err, "verify signature", prvID)
+	recovered, err := recoverPubkey(data, sig)
+	if err != nil {
+		return false, err
+	}
+	pub, err := sec.keeper.GetPublicKey(prvID)
+	if err != nil {
+		return false, err
+	}
+	return bytes.Equal(crypto.FromECDSAPub(recovered), pub), nil
+}
+
+// RecoverSigner returns the address of the key that made the signature sig of
+// the hash data.
+func (sec *SecureSign) RecoverSigner(data, sig []byte) (_ common.Address, err error) {
+	defer wrapError(&err, "recover signer", nil)
+	pub, err := recoverPubkey(data, sig)
+	if err != nil {
+		return common.Address{}, err
+	}
+	return crypto.PubkeyToAddress(*pub), nil
+}
+
+// ECRecover returns the address that signed data with personal_sign, like
+// personal_ecRecover does: the signature is checked against the hash of
+// "\x19Ethereum Signed Message:\n${len(data)}${data}". See ECRecoverFromHash for
+// the accepted signature formats.
+func ECRecover(data, sig []byte) (common.Address, error) {
+	return ECRecoverFromHash(common.BytesToHash(accounts.TextHash(data)), sig)
+}
+
+// ECRecoverFromHash returns the address that signed hash, like the ecrecover
+// precompile does for contracts. The signature is either in the 65 byte
+// [R || S || V] format, with a V of 0, 1, 27 or 28, or in the 64 byte compact
+// format of EIP-2098, which keeps the recovery id in the top bit of S.
+func ECRecoverFromHash(hash common.Hash, sig []byte) (_ common.Address, err error) {
+	defer wrapError(&err, "recover signer", nil)
+
+	if len(sig) == crypto.SignatureLength-1 {
+		sig = expandCompactSignature(sig)
+	}
+	pub, err := recoverPubkey(hash[:], sig)
+	if err != nil {
+		return common.Address{}, err
+	}
+	return crypto.PubkeyToAddress(*pub), nil
+}
+
+// expandCompactSignature converts the 64 byte EIP-2098 signature
+// [R || yParity << 255 | S] into the [R || S || V] format.
+func expandCompactSignature(compact []byte) []byte {
+	sig := make([]byte, crypto.SignatureLength)
+	copy(sig, compact)
+	sig[crypto.RecoveryIDOffset] = sig[32] >> 7
+	sig[32] &= 0x7f
+	return sig
+}
+
+// recoverPubkey returns the public key that made the [R || S || V] signature of
+// hash. Both the 0/1 and the legacy 27/28 V values are accepted.
+func recoverPubkey(hash, sig []byte) (*ecdsa.PublicKey, error) {
+	if len(sig) != crypto.SignatureLength {
+		return nil, fmt.Errorf("%w: signature must be %d bytes long (%d)", ErrInvalidSignature, crypto.SignatureLength, len(sig))
+	}
+	sig = common.CopyBytes(sig)
+	if sig[crypto.RecoveryIDOffset] == 27 || sig[crypto.RecoveryIDOffset] == 28 {
+		sig[crypto.RecoveryIDOffset] -= 27
+	}
+	pub, err := crypto.SigToPub(hash, sig)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSignature, err)
+	}
+	return pub, nil
+}
@@ -0,0 +1,147 @@
+package keeper
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestPersonalMessage(t *testing.T) {
+	sec := DefaultSecureSign()
+	prvID, _ := sec.GenerateKey()
+	pub, _ := sec.GetPublicKey(prvID)
+	key, _ := crypto.UnmarshalPubkey(pub)
+	addr := crypto.PubkeyToAddress(*key)
+
+	tests := []struct {
+		name    string
+		message []byte
+	}{
+		{"empty", []byte{}},
+		{"ascii", []byte("Hello, world!")},
+		{"binary", []byte{0x00, 0xff, 0x19, 0x80, 0x0a}},
+	}
+	for _, tt := range tests {
+		sig, err := sec.SignPersonalMessage(tt.message, prvID)
+		if err != nil {
+			t.Fatalf("%s: failed to sign: %v", tt.name, err)
+		}
+		if err := sec.VerifyPersonalMessage(tt.message, sig, addr); err != nil {
+			t.Errorf("%s: failed to verify: %v", tt.name, err)
+		}
+		legacy := common.CopyBytes(sig)
+		legacy[64] += 27
+		if err := sec.VerifyPersonalMessage(tt.message, legacy, addr); err != nil {
+			t.Errorf("%s: failed to verify legacy V: %v", tt.name, err)
+		}
+		if err := sec.VerifyPersonalMessage(append(tt.message, 'x'), sig, addr); err == nil {
+			t.Errorf("%s: verified signature of a different message", tt.name)
+		}
+		if err := sec.VerifyPersonalMessage(tt.message, sig, common.Address{}); err == nil {
+			t.Errorf("%s: verified signature against wrong address", tt.name)
+		}
+	}
+	if err := sec.VerifyPersonalMessage(nil, make([]byte, 64), addr); err == nil {
+		t.Error("verified short signature")
+	}
+}
+
+func TestVerifySignature(t *testing.T) {
+	sec := DefaultSecureSign()
+	prvID, _ := sec.GenerateKey()
+	other, _ := sec.GenerateKey()
+	addr, _ := sec.GetAddress(prvID)
+
+	hash := crypto.Keccak256([]byte("verify"))
+	sig, err := defaultKeeper.Sign(hash, prvID)
+	if err != nil {
+		t.Fatalf("failed to sign: %v", err)
+	}
+	if ok, err := sec.VerifySignature(hash, sig, prvID); err != nil || !ok {
+		t.Fatalf("signing key: have (%v, %v), want (true, nil)", ok, err)
+	}
+	if ok, err := sec.VerifySignature(hash, sig, other); err != nil || ok {
+		t.Fatalf("other key: have (%v, %v), want (false, nil)", ok, err)
+	}
+	if _, err := sec.VerifySignature(hash, sig[:64], prvID); err == nil {
+		t.Fatal("verified truncated signature")
+	}
+	if signer, err := sec.RecoverSigner(hash, sig); err != nil || signer != addr {
+		t.Fatalf("RecoverSigner: have (%v, %v), want %v", signer, err, addr)
+	}
+	if _, err := sec.RecoverSigner(hash[:31], sig); err == nil {
+		t.Fatal("recovered signer of short hash")
+	}
+}
+
+func TestSignHash(t *testing.T) {
+	sec := DefaultSecureSign()
+	prvID, _ := sec.GenerateKey()
+	addr, _ := sec.GetAddress(prvID)
+
+	data := []byte("sign hash")
+	hash := crypto.Keccak256Hash(data)
+	sig, err := sec.SignHash(hash, prvID)
+	if err != nil {
+		t.Fatalf("failed to sign hash: %v", err)
+	}
+	if signer, err := sec.RecoverSigner(hash[:], sig); err != nil || signer != addr {
+		t.Fatalf("signer mismatch: have (%v, %v), want %v", signer, err, addr)
+	}
+	// Signing is deterministic, hashing the data first gives the same signature.
+	sig2, err := sec.SignHashBytes(data, prvID)
+	if err != nil {
+		t.Fatalf("failed to sign hash bytes: %v", err)
+	}
+	if !bytes.Equal(sig, sig2) {
+		t.Fatalf("signature mismatch: have %x, want %x", sig2, sig)
+	}
+	if _, err := sec.SignHash(hash, []byte("unknown")); err == nil {
+		t.Fatal("signed with unknown key")
+	}
+}
+
+func TestECRecover(t *testing.T) {
+	// The personal_sign examples of EIP-2098, signed by the key 0x1234...1234,
+	// in both the 65 byte and the compact form.
+	signer := common.HexToAddress("0x2e988A386a799F506693793c6A5AF6B54dfAaBfB")
+	tests := []struct {
+		message      string
+		sig, compact string
+	}{
+		{
+			message: "Hello World",
+			sig:     "0x68a020a209d3d56c46f38cc50a33f704f4a9a10a59377f8dd762ac66910e9b907e865ad05c4035ab5792787d4a0297a43617ae897930a6fe4d822b8faea520641b",
+			compact: "0x68a020a209d3d56c46f38cc50a33f704f4a9a10a59377f8dd762ac66910e9b907e865ad05c4035ab5792787d4a0297a43617ae897930a6fe4d822b8faea52064",
+		},
+		{
+			message: "It's a small(er) world",
+			sig:     "0x9328da16089fcba9bececa81663203989f2df5fe1faa6291a45381c81bd17f76139c6d6b623b42da56557e5e734a43dc83345ddfadec52cbe24d0cc64f5507931c",
+			compact: "0x9328da16089fcba9bececa81663203989f2df5fe1faa6291a45381c81bd17f76939c6d6b623b42da56557e5e734a43dc83345ddfadec52cbe24d0cc64f550793",
+		},
+	}
+	for _, tt := range tests {
+		for _, sig := range []string{tt.sig, tt.compact} {
+			addr, err := ECRecover([]byte(tt.message), hexutil.MustDecode(sig))
+			if err != nil || addr != signer {
+				t.Fatalf("%q: have (%v, %v), want %v", tt.message, addr, err, signer)
+			}
+		}
+		// The hash variant recovers from the prefixed hash only.
+		hash := common.BytesToHash(accounts.TextHash([]byte(tt.message)))
+		if addr, err := ECRecoverFromHash(hash, hexutil.MustDecode(tt.sig)); err != nil || addr != signer {
+			t.Fatalf("%q from hash: have (%v, %v), want %v", tt.message, addr, err, signer)
+		}
+		if addr, _ := ECRecoverFromHash(crypto.Keccak256Hash([]byte(tt.message)), hexutil.MustDecode(tt.sig)); addr == signer {
+			t.Fatalf("%q: recovered signer from unprefixed hash", tt.message)
+		}
+	}
+	if _, err := ECRecover([]byte("Hello World"), make([]byte, 63)); !errors.Is(err, ErrInvalidSignature) {
+		t.Fatalf("short signature: have %v, want %v", err, ErrInvalidSignature)
+	}
+}
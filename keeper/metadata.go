@@ -0,0 +1,277 @@
+package keeper
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// KeyMetadata describes a key of a keeper.
+type KeyMetadata struct {
+	Created   time.Time `json:"created"`
+	Label     string    `json:"label,omitempty"`
+	Purpose   string    `json:"purpose,omitempty"`
+	ExpiresAt time.Time `json:"expiresAt"` // zero if the key doesn't expire
+
+	MaxUses int64 `json:"maxUses,omitempty"` // limit of signatures of the key, 0 for no limit
+	Uses    int64 `json:"uses,omitempty"`    // signatures made by the key so far
+}
+
+// Expired reports whether the key expired at the given time, i.e. the time is
+// after ExpiresAt. Keys without an expiry never do.
+func (m KeyMetadata) Expired(now time.Time) bool {
+	return !m.ExpiresAt.IsZero() && now.After(m.ExpiresAt)
+}
+
+// KeyMetadataStore stores the metadata of keys by their prvID.
+type KeyMetadataStore interface {
+	// Set stores the metadata of the key, replacing its previous metadata
+	Set(prvID []byte, meta KeyMetadata) error
+	// Get return the metadata of the key, failing with ErrKeyNotFound if the
+	// store has none
+	Get(prvID []byte) (KeyMetadata, error)
+	// Delete removes the metadata of the key, keys without are ignored
+	Delete(prvID []byte) error
+}
+
+// UseCounter is implemented by KeyMetadataStores able to count the uses of a
+// key atomically in the store, so that processes sharing the store can't
+// exceed the MaxUses of a key together.
+type UseCounter interface {
+	// IncrementUses counts a use of the key and returns its Uses, failing with
+	// ErrKeyExhausted if the key made its MaxUses already and with
+	// ErrKeyNotFound if the store has no metadata for it
+	IncrementUses(prvID []byte) (uses int64, err error)
+}
+
+// MetadataAwareKeeper is a PrivateKeyKeeper keeping metadata of its keys.
+// Expiry is informational, the keeper keeps signing with expired keys unless
+// wrapped with NewExpiryEnforcingKeeper.
+type MetadataAwareKeeper interface {
+	PrivateKeyKeeper
+
+	// GetMetadata return the metadata of the key by private key ID
+	GetMetadata(prvID []byte) (KeyMetadata, error)
+	// SetMetadata replaces the metadata of the key by private key ID
+	SetMetadata(prvID []byte, meta KeyMetadata) error
+}
+
+// metadataKeeper is a MetadataAwareKeeper keeping the metadata of the keys of
+// its inner keeper in a store.
+type metadataKeeper struct {
+	PrivateKeyKeeper
+	store KeyMetadataStore
+}
+
+// NewMetadataAwareKeeper returns a MetadataAwareKeeper recording the metadata of
+// the keys of inner in store. Generated and imported keys get their creation
+// time and a random label, deleted keys lose their metadata.
+func NewMetadataAwareKeeper(inner PrivateKeyKeeper, store KeyMetadataStore) PrivateKeyKeeper {
+	return &metadataKeeper{PrivateKeyKeeper: inner, store: store}
+}
+
+func (k *metadataKeeper) GeneratePrivateKey() (_ []byte, err error) {
+	defer wrapError(&err, "generate key", nil)
+
+	prvID, err := k.PrivateKeyKeeper.GeneratePrivateKey()
+	if err != nil {
+		return nil, err
+	}
+	return prvID, k.initMetadata(prvID)
+}
+
+func (k *metadataKeeper) ImportPrivateKey(rawKey []byte) (_ []byte, err error) {
+	defer wrapError(&err, "import key", nil)
+
+	prvID, err := k.PrivateKeyKeeper.ImportPrivateKey(rawKey)
+	if err != nil {
+		return nil, err
+	}
+	return prvID, k.initMetadata(prvID)
+}
+
+// initMetadata records the metadata of a new key. Keys whose metadata couldn't
+// be recorded are deleted again, so no key is left without.
+func (k *metadataKeeper) initMetadata(prvID []byte) error {
+	label := make([]byte, 4)
+	if _, err := rand.Read(label); err != nil {
+		return err
+	}
+	meta := KeyMetadata{Created: time.Now().UTC(), Label: "key-" + hex.EncodeToString(label)}
+	if err := k.store.Set(prvID, meta); err != nil {
+		k.PrivateKeyKeeper.DeletePrivateKey(prvID)
+		return fmt.Errorf("failed to record key metadata: %w", err)
+	}
+	return nil
+}
+
+func (k *metadataKeeper) DeletePrivateKey(prvID []byte) (err error) {
+	defer wrapError(&err, "delete key", prvID)
+
+	if err := k.PrivateKeyKeeper.DeletePrivateKey(prvID); err != nil {
+		return err
+	}
+	return k.store.Delete(prvID)
+}
+
+func (k *metadataKeeper) GetMetadata(prvID []byte) (_ KeyMetadata, err error) {
+	defer wrapError(&err, "get metadata", prvID)
+	return k.store.Get(prvID)
+}
+
+func (k *metadataKeeper) SetMetadata(prvID []byte, meta KeyMetadata) (err error) {
+	defer wrapError(&err, "set metadata", prvID)
+
+	if _, err := k.PrivateKeyKeeper.GetPublicKey(prvID); err != nil {
+		return err
+	}
+	return k.store.Set(prvID, meta)
+}
+
+func (k *metadataKeeper) HealthCheck(ctx context.Context) error {
+	return checkHealth(ctx, ContextKeeper(k.PrivateKeyKeeper))
+}
+
+// updateNewMetadata applies update to the metadata of the key just generated by
+// k. If that fails the key is deleted again, so no key is handed out without
+// the metadata it was asked for with.
+func updateNewMetadata(k MetadataAwareKeeper, prvID []byte, update func(*KeyMetadata)) error {
+	meta, err := k.GetMetadata(prvID)
+	if err == nil {
+		update(&meta)
+		err = k.SetMetadata(prvID, meta)
+	}
+	if err != nil {
+		k.DeletePrivateKey(prvID)
+		return fmt.Errorf("failed to update key metadata: %w", err)
+	}
+	return nil
+}
+
+// memoryMetadataStore is a KeyMetadataStore holding the metadata in memory.
+type memoryMetadataStore struct {
+	lock  sync.Mutex
+	metas map[string]KeyMetadata
+}
+
+// MemoryMetadataStore returns a KeyMetadataStore holding the metadata in memory.
+// The metadata is lost when the process exits.
+func MemoryMetadataStore() KeyMetadataStore {
+	return &memoryMetadataStore{metas: make(map[string]KeyMetadata)}
+}
+
+func (s *memoryMetadataStore) Set(prvID []byte, meta KeyMetadata) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.metas[string(prvID)] = meta
+	return nil
+}
+
+func (s *memoryMetadataStore) Get(prvID []byte) (KeyMetadata, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	meta, ok := s.metas[string(prvID)]
+	if !ok {
+		return KeyMetadata{}, ErrKeyNotFound
+	}
+	return meta, nil
+}
+
+func (s *memoryMetadataStore) Delete(prvID []byte) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	delete(s.metas, string(prvID))
+	return nil
+}
+
+// fileMetadataStore is a KeyMetadataStore holding the metadata in a JSON file.
+// The metadata is kept in memory as well, the file is rewritten on changes.
+type fileMetadataStore struct {
+	path  string
+	lock  sync.Mutex
+	metas map[string]KeyMetadata // keccak256 hex of prvID -> metadata
+}
+
+// FileMetadataStore returns a KeyMetadataStore holding the metadata in the JSON
+// file at path, loading the metadata already in it. The file is created on the
+// first change if it doesn't exist. Keys are recorded by the keccak256 hash of
+// their prvID, as the prvID of some keepers is the private key itself. The
+// file must not be shared by processes changing metadata.
+func FileMetadataStore(path string) (KeyMetadataStore, error) {
+	s := &fileMetadataStore{path: path, metas: make(map[string]KeyMetadata)}
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(content, &s.metas); err != nil {
+		return nil, fmt.Errorf("invalid metadata file %s: %w", path, err)
+	}
+	return s, nil
+}
+
+func (s *fileMetadataStore) Set(prvID []byte, meta KeyMetadata) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	key := auditKeyID(prvID)
+	prev, ok := s.metas[key]
+	s.metas[key] = meta
+	if err := s.flush(); err != nil {
+		if ok {
+			s.metas[key] = prev
+		} else {
+			delete(s.metas, key)
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *fileMetadataStore) Get(prvID []byte) (KeyMetadata, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	meta, ok := s.metas[auditKeyID(prvID)]
+	if !ok {
+		return KeyMetadata{}, ErrKeyNotFound
+	}
+	return meta, nil
+}
+
+func (s *fileMetadataStore) Delete(prvID []byte) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	key := auditKeyID(prvID)
+	prev, ok := s.metas[key]
+	if !ok {
+		return nil
+	}
+	delete(s.metas, key)
+	if err := s.flush(); err != nil {
+		s.metas[key] = prev
+		return err
+	}
+	return nil
+}
+
+// flush atomically replaces the file with the metadata in memory.
+func (s *fileMetadataStore) flush() error {
+	content, err := json.MarshalIndent(s.metas, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeKeyFile(s.path, content)
+}
@@ -0,0 +1,212 @@
+package keeper
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// postgresMigrations are the schema migrations of the Postgres metadata store,
+// applied in the order of the version number their file names start with.
+//
+//go:embed migrations/postgres/*.sql
+var postgresMigrations embed.FS
+
+// postgresMigrationLock is the key of the advisory lock serializing the
+// migrations of stores opened at the same time.
+const postgresMigrationLock = 0x6b6565706572 // "keeper"
+
+// postgresMetadataStore is a KeyMetadataStore holding the metadata in a
+// PostgreSQL database.
+type postgresMetadataStore struct {
+	pool *pgxpool.Pool
+}
+
+// NewPostgresMetadataStore returns a KeyMetadataStore holding the metadata in
+// the PostgreSQL database of connString, which may configure the connection
+// pool too, e.g. with pool_max_conns. See NewPostgresMetadataStoreFromConfig.
+func NewPostgresMetadataStore(connString string) (KeyMetadataStore, error) {
+	cfg, err := pgxpool.ParseConfig(connString)
+	if err != nil {
+		return nil, err
+	}
+	return NewPostgresMetadataStoreFromConfig(cfg)
+}
+
+// NewPostgresMetadataStoreFromConfig returns a KeyMetadataStore holding the
+// metadata in the PostgreSQL database of cfg, migrating the schema of the
+// database to the current version first. As in FileMetadataStore, keys are
+// recorded by the keccak256 hash of their prvID. Times are stored with the
+// microsecond precision of Postgres.
+//
+// The store implements UseCounter, and io.Closer to close the pool.
+func NewPostgresMetadataStoreFromConfig(cfg *pgxpool.Config) (KeyMetadataStore, error) {
+	ctx := context.Background()
+	pool, err := pgxpool.NewWithConfig(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	if err := migratePostgres(ctx, pool); err != nil {
+		pool.Close()
+		return nil, fmt.Errorf("failed to migrate metadata database: %w", err)
+	}
+	return &postgresMetadataStore{pool: pool}, nil
+}
+
+// postgresMigrationFiles returns the versions of the migrations in ascending
+// order, and their file names by version.
+func postgresMigrationFiles() ([]int, map[int]string, error) {
+	files, err := postgresMigrations.ReadDir("migrations/postgres")
+	if err != nil {
+		return nil, nil, err
+	}
+	names := make(map[int]string)
+	for _, file := range files {
+		prefix, _, _ := strings.Cut(file.Name(), "_")
+		version, err := strconv.Atoi(prefix)
+		if err != nil || version <= 0 {
+			return nil, nil, fmt.Errorf("invalid migration %s", file.Name())
+		}
+		if names[version] != "" {
+			return nil, nil, fmt.Errorf("duplicate migration version %d", version)
+		}
+		names[version] = file.Name()
+	}
+	versions := make([]int, 0, len(names))
+	for version := range names {
+		versions = append(versions, version)
+	}
+	sort.Ints(versions)
+	return versions, names, nil
+}
+
+// migratePostgres applies the migrations not yet recorded in the
+// schema_migrations table of the database, all in one transaction.
+func migratePostgres(ctx context.Context, pool *pgxpool.Pool) error {
+	versions, names, err := postgresMigrationFiles()
+	if err != nil {
+		return err
+	}
+	return pgx.BeginFunc(ctx, pool, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock($1)`, postgresMigrationLock); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+			version    INTEGER PRIMARY KEY,
+			applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+		)`); err != nil {
+			return err
+		}
+		var current int
+		if err := tx.QueryRow(ctx, `SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&current); err != nil {
+			return err
+		}
+		for _, version := range versions {
+			if version <= current {
+				continue
+			}
+			stmts, err := postgresMigrations.ReadFile(path.Join("migrations/postgres", names[version]))
+			if err != nil {
+				return err
+			}
+			if _, err := tx.Exec(ctx, string(stmts)); err != nil {
+				return fmt.Errorf("migration %s: %w", names[version], err)
+			}
+			if _, err := tx.Exec(ctx, `INSERT INTO schema_migrations (version) VALUES ($1)`, version); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Close closes the connection pool of the store.
+func (s *postgresMetadataStore) Close() error {
+	s.pool.Close()
+	return nil
+}
+
+func (s *postgresMetadataStore) Set(prvID []byte, meta KeyMetadata) error {
+	_, err := s.pool.Exec(context.Background(), `INSERT INTO key_metadata
+		(prv_id, label, purpose, created_at, expires_at, max_uses, use_count)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (prv_id) DO UPDATE SET
+			label = excluded.label, purpose = excluded.purpose,
+			created_at = excluded.created_at, expires_at = excluded.expires_at,
+			max_uses = excluded.max_uses, use_count = excluded.use_count`,
+		crypto.Keccak256(prvID), meta.Label, meta.Purpose,
+		nullTime(meta.Created), nullTime(meta.ExpiresAt), meta.MaxUses, meta.Uses,
+	)
+	return err
+}
+
+func (s *postgresMetadataStore) Get(prvID []byte) (KeyMetadata, error) {
+	var (
+		meta             KeyMetadata
+		created, expires *time.Time
+	)
+	err := s.pool.QueryRow(context.Background(), `SELECT label, purpose, created_at, expires_at, max_uses, use_count
+		FROM key_metadata WHERE prv_id = $1`, crypto.Keccak256(prvID),
+	).Scan(&meta.Label, &meta.Purpose, &created, &expires, &meta.MaxUses, &meta.Uses)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return KeyMetadata{}, ErrKeyNotFound
+	}
+	if err != nil {
+		return KeyMetadata{}, err
+	}
+	if created != nil {
+		meta.Created = created.UTC()
+	}
+	if expires != nil {
+		meta.ExpiresAt = expires.UTC()
+	}
+	return meta, nil
+}
+
+func (s *postgresMetadataStore) Delete(prvID []byte) error {
+	_, err := s.pool.Exec(context.Background(), `DELETE FROM key_metadata WHERE prv_id = $1`, crypto.Keccak256(prvID))
+	return err
+}
+
+// IncrementUses counts a use of the key in a single UPDATE, so that concurrent
+// callers can't both take the last use of a key.
+func (s *postgresMetadataStore) IncrementUses(prvID []byte) (int64, error) {
+	ctx, id := context.Background(), crypto.Keccak256(prvID)
+
+	var uses int64
+	err := s.pool.QueryRow(ctx, `UPDATE key_metadata SET use_count = use_count + 1
+		WHERE prv_id = $1 AND (max_uses = 0 OR use_count < max_uses)
+		RETURNING use_count`, id,
+	).Scan(&uses)
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return uses, err
+	}
+	// Nothing updated, the key is either exhausted or unknown.
+	var exists bool
+	if err := s.pool.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM key_metadata WHERE prv_id = $1)`, id).Scan(&exists); err != nil {
+		return 0, err
+	}
+	if exists {
+		return 0, ErrKeyExhausted
+	}
+	return 0, ErrKeyNotFound
+}
+
+// nullTime returns t for storing in a TIMESTAMPTZ column, NULL for the zero
+// time.
+func nullTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
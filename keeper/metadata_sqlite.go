@@ -0,0 +1,133 @@
+package keeper
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	_ "modernc.org/sqlite" // registers the pure Go "sqlite" driver
+)
+
+// sqliteMetadataSchema is the table of a SQLite metadata store. The times are
+// in nanoseconds since the Unix epoch, 0 for the zero time.
+const sqliteMetadataSchema = `CREATE TABLE IF NOT EXISTS key_metadata (
+	prv_id     BLOB PRIMARY KEY,
+	label      TEXT,
+	purpose    TEXT,
+	created_at INTEGER,
+	expires_at INTEGER,
+	max_uses   INTEGER,
+	use_count  INTEGER
+)`
+
+// sqliteMetadataStore is a KeyMetadataStore holding the metadata in a SQLite
+// database.
+type sqliteMetadataStore struct {
+	db *sql.DB
+}
+
+// NewSQLiteMetadataStore returns a KeyMetadataStore holding the metadata in the
+// SQLite database at dbPath, creating the database and its key_metadata table
+// if they don't exist. ":memory:" keeps the metadata in memory instead. As in
+// FileMetadataStore, keys are recorded by the keccak256 hash of their prvID.
+//
+// Unlike FileMetadataStore, the database may be shared by processes, SQLite
+// serializes their changes. The store implements io.Closer to close the
+// database.
+func NewSQLiteMetadataStore(dbPath string) (KeyMetadataStore, error) {
+	db, err := sql.Open("sqlite", dbPath)
+	if err != nil {
+		return nil, err
+	}
+	// A single connection serializes the transactions of the process, instead
+	// of failing them as the database is locked, and keeps an in-memory
+	// database alive, every connection to ":memory:" opening a new one.
+	db.SetMaxOpenConns(1)
+
+	for _, stmt := range []string{"PRAGMA busy_timeout = 5000", sqliteMetadataSchema} {
+		if _, err := db.Exec(stmt); err != nil {
+			db.Close()
+			return nil, fmt.Errorf("failed to open metadata database %s: %w", dbPath, err)
+		}
+	}
+	return &sqliteMetadataStore{db: db}, nil
+}
+
+// Close closes the database of the store.
+func (s *sqliteMetadataStore) Close() error {
+	return s.db.Close()
+}
+
+// inTx runs fn in a transaction, committing it if fn succeeds.
+func (s *sqliteMetadataStore) inTx(fn func(tx *sql.Tx) error) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *sqliteMetadataStore) Set(prvID []byte, meta KeyMetadata) error {
+	return s.inTx(func(tx *sql.Tx) error {
+		_, err := tx.Exec(`INSERT INTO key_metadata
+			(prv_id, label, purpose, created_at, expires_at, max_uses, use_count)
+			VALUES (?, ?, ?, ?, ?, ?, ?)
+			ON CONFLICT (prv_id) DO UPDATE SET
+				label = excluded.label, purpose = excluded.purpose,
+				created_at = excluded.created_at, expires_at = excluded.expires_at,
+				max_uses = excluded.max_uses, use_count = excluded.use_count`,
+			crypto.Keccak256(prvID), meta.Label, meta.Purpose,
+			unixNano(meta.Created), unixNano(meta.ExpiresAt), meta.MaxUses, meta.Uses,
+		)
+		return err
+	})
+}
+
+func (s *sqliteMetadataStore) Get(prvID []byte) (meta KeyMetadata, err error) {
+	err = s.inTx(func(tx *sql.Tx) error {
+		var created, expires int64
+		row := tx.QueryRow(`SELECT label, purpose, created_at, expires_at, max_uses, use_count
+			FROM key_metadata WHERE prv_id = ?`, crypto.Keccak256(prvID))
+		if err := row.Scan(&meta.Label, &meta.Purpose, &created, &expires, &meta.MaxUses, &meta.Uses); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrKeyNotFound
+			}
+			return err
+		}
+		meta.Created, meta.ExpiresAt = fromUnixNano(created), fromUnixNano(expires)
+		return nil
+	})
+	if err != nil {
+		return KeyMetadata{}, err
+	}
+	return meta, nil
+}
+
+func (s *sqliteMetadataStore) Delete(prvID []byte) error {
+	return s.inTx(func(tx *sql.Tx) error {
+		_, err := tx.Exec(`DELETE FROM key_metadata WHERE prv_id = ?`, crypto.Keccak256(prvID))
+		return err
+	})
+}
+
+// unixNano returns t in nanoseconds since the Unix epoch, 0 for the zero time.
+func unixNano(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixNano()
+}
+
+// fromUnixNano is the inverse of unixNano, returning UTC times.
+func fromUnixNano(ns int64) time.Time {
+	if ns == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, ns).UTC()
+}
@@ -0,0 +1,174 @@
+package keeper
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMetadataAwareKeeper(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "metadata.json")
+	fileStore, err := FileMetadataStore(path)
+	if err != nil {
+		t.Fatalf("failed to open file store: %v", err)
+	}
+	sqliteStore, err := NewSQLiteMetadataStore(":memory:")
+	if err != nil {
+		t.Fatalf("failed to open sqlite store: %v", err)
+	}
+	defer sqliteStore.(io.Closer).Close()
+	stores := map[string]KeyMetadataStore{
+		"memory": MemoryMetadataStore(),
+		"file":   fileStore,
+		"sqlite": sqliteStore,
+	}
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			testMetadataAwareKeeper(t, store)
+		})
+	}
+
+	// The file store loads the metadata recorded before.
+	k := NewMetadataAwareKeeper(new(defaultPrivateKeyKeeper), fileStore).(MetadataAwareKeeper)
+	prvID, _ := k.GeneratePrivateKey()
+	want, _ := k.GetMetadata(prvID)
+	reopened, err := FileMetadataStore(path)
+	if err != nil {
+		t.Fatalf("failed to reopen file store: %v", err)
+	}
+	if have, err := reopened.Get(prvID); err != nil || !have.Created.Equal(want.Created) || have.Label != want.Label {
+		t.Fatalf("reopened metadata: have (%+v, %v), want %+v", have, err, want)
+	}
+}
+
+func testMetadataAwareKeeper(t *testing.T, store KeyMetadataStore) {
+	k, ok := NewMetadataAwareKeeper(new(defaultPrivateKeyKeeper), store).(MetadataAwareKeeper)
+	if !ok {
+		t.Fatal("keeper not metadata aware")
+	}
+	before := time.Now()
+	prvID, err := k.GeneratePrivateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	meta, err := k.GetMetadata(prvID)
+	if err != nil {
+		t.Fatalf("failed to get metadata: %v", err)
+	}
+	if meta.Created.Before(before.Add(-time.Second)) || meta.Created.After(time.Now()) {
+		t.Fatalf("creation time %v not in [%v, now]", meta.Created, before)
+	}
+	if !strings.HasPrefix(meta.Label, "key-") || meta.Expired(time.Now().Add(100*365*24*time.Hour)) {
+		t.Fatalf("generated metadata: have %+v, want labelled and not expiring", meta)
+	}
+	other, _ := k.GeneratePrivateKey()
+	if otherMeta, _ := k.GetMetadata(other); otherMeta.Label == meta.Label {
+		t.Fatalf("keys share label %q", meta.Label)
+	}
+
+	// Update the metadata with an expiry.
+	meta.Purpose = "payouts"
+	meta.ExpiresAt = time.Now().Add(time.Hour).UTC()
+	if err := k.SetMetadata(prvID, meta); err != nil {
+		t.Fatalf("failed to set metadata: %v", err)
+	}
+	meta, err = k.GetMetadata(prvID)
+	if err != nil || meta.Purpose != "payouts" {
+		t.Fatalf("updated metadata: have (%+v, %v), want purpose payouts", meta, err)
+	}
+	if meta.Expired(time.Now()) {
+		t.Fatal("key expired before expiry")
+	}
+	if meta.Expired(meta.ExpiresAt) || !meta.Expired(meta.ExpiresAt.Add(time.Nanosecond)) {
+		t.Fatal("key expiry boundary not respected")
+	}
+	if err := k.SetMetadata([]byte("unknown"), meta); err == nil {
+		t.Fatal("set metadata of unknown key")
+	}
+
+	// Deleted keys lose their metadata.
+	if err := k.DeletePrivateKey(prvID); err != nil {
+		t.Fatalf("failed to delete key: %v", err)
+	}
+	if _, err := k.GetMetadata(prvID); !errors.Is(err, ErrKeyNotFound) {
+		t.Fatalf("metadata of deleted key: have %v, want %v", err, ErrKeyNotFound)
+	}
+	if _, err := k.GetMetadata(other); err != nil {
+		t.Fatalf("metadata of other key: %v", err)
+	}
+	checkImport(t, k)
+}
+
+func TestSQLiteMetadataStore(t *testing.T) {
+	store, err := NewSQLiteMetadataStore(":memory:")
+	if err != nil {
+		t.Fatalf("failed to open store: %v", err)
+	}
+	defer store.(io.Closer).Close()
+
+	// All fields survive the round trip, the zero expiry included.
+	want := KeyMetadata{Created: time.Now().UTC(), Label: "hot", Purpose: "payouts", MaxUses: 10, Uses: 3}
+	if err := store.Set([]byte("key"), want); err != nil {
+		t.Fatalf("failed to set metadata: %v", err)
+	}
+	have, err := store.Get([]byte("key"))
+	if err != nil {
+		t.Fatalf("failed to get metadata: %v", err)
+	}
+	if !have.Created.Equal(want.Created) || !have.ExpiresAt.IsZero() {
+		t.Fatalf("times mismatch: have %+v, want %+v", have, want)
+	}
+	have.Created = want.Created
+	if have != want {
+		t.Fatalf("metadata mismatch: have %+v, want %+v", have, want)
+	}
+
+	// Concurrent updates of distinct keys all land.
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			prvID := []byte(fmt.Sprintf("key-%d", i))
+			for uses := int64(1); uses <= 10; uses++ {
+				if err := store.Set(prvID, KeyMetadata{Uses: uses}); err != nil {
+					t.Errorf("failed to set metadata: %v", err)
+					return
+				}
+				if _, err := store.Get(prvID); err != nil {
+					t.Errorf("failed to get metadata: %v", err)
+					return
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+	for i := 0; i < 16; i++ {
+		if meta, err := store.Get([]byte(fmt.Sprintf("key-%d", i))); err != nil || meta.Uses != 10 {
+			t.Fatalf("metadata of key %d: have (%+v, %v), want 10 uses", i, meta, err)
+		}
+	}
+	if err := store.Delete([]byte("key")); err != nil {
+		t.Fatalf("failed to delete metadata: %v", err)
+	}
+	if _, err := store.Get([]byte("key")); !errors.Is(err, ErrKeyNotFound) {
+		t.Fatalf("deleted metadata: have %v, want %v", err, ErrKeyNotFound)
+	}
+}
+
+func TestPostgresMigrationFiles(t *testing.T) {
+	versions, names, err := postgresMigrationFiles()
+	if err != nil {
+		t.Fatalf("invalid migrations: %v", err)
+	}
+	for i, version := range versions {
+		if version != i+1 {
+			t.Fatalf("migration %s: have version %d, want %d", names[version], version, i+1)
+		}
+	}
+}
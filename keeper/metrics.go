@@ -0,0 +1,264 @@
+package keeper
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// SecureSignerMetrics are the Prometheus metrics of instrumented signers. It is
+// a prometheus.Collector, so it can be registered with any registry.
+//
+// Both metrics are labelled by the operation, the SecureSigner method, and by
+// key_id_prefix, which is the first 8 hex characters of the Keccak-256 hash of
+// the prvID. Hashing keeps the label short and distinct for keepers whose
+// prvIDs share a prefix, such as KMS ARNs, and avoids exposing the key bits of
+// keepers whose prvID is the private key.
+type SecureSignerMetrics struct {
+	Latency *prometheus.HistogramVec // call latency by operation and key_id_prefix
+	Calls   *prometheus.CounterVec   // calls by operation, key_id_prefix and result
+}
+
+// NewSecureSignerMetrics creates an unregistered set of signer metrics.
+func NewSecureSignerMetrics() *SecureSignerMetrics {
+	return &SecureSignerMetrics{
+		Latency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "keeper",
+			Subsystem: "signer",
+			Name:      "duration_seconds",
+			Help:      "Latency of secure signer operations.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"operation", "key_id_prefix"}),
+		Calls: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "keeper",
+			Subsystem: "signer",
+			Name:      "calls_total",
+			Help:      "Secure signer operations by result, success or failure.",
+		}, []string{"operation", "key_id_prefix", "result"}),
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (m *SecureSignerMetrics) Describe(ch chan<- *prometheus.Desc) {
+	m.Latency.Describe(ch)
+	m.Calls.Describe(ch)
+}
+
+// Collect implements prometheus.Collector.
+func (m *SecureSignerMetrics) Collect(ch chan<- prometheus.Metric) {
+	m.Latency.Collect(ch)
+	m.Calls.Collect(ch)
+}
+
+// Instrument returns a SecureSigner recording the calls to inner in m.
+func (m *SecureSignerMetrics) Instrument(inner SecureSigner) SecureSigner {
+	return &instrumentedSigner{inner: inner, metrics: m}
+}
+
+// observe records a call of op on the key prvID that started at start, to be
+// deferred by the instrumented methods.
+func (m *SecureSignerMetrics) observe(op string, prvID []byte, start time.Time, err *error) {
+	prefix := keyIDPrefix(prvID)
+	m.Latency.WithLabelValues(op, prefix).Observe(time.Since(start).Seconds())
+
+	result := "success"
+	if *err != nil {
+		result = "failure"
+	}
+	m.Calls.WithLabelValues(op, prefix, result).Inc()
+}
+
+// keyIDPrefix returns the key_id_prefix label of prvID, empty for operations
+// without a key.
+func keyIDPrefix(prvID []byte) string {
+	if prvID == nil {
+		return ""
+	}
+	return hex.EncodeToString(crypto.Keccak256(prvID)[:4])
+}
+
+// instrumentedSigner is a SecureSigner recording latency and results of the
+// calls to another signer.
+type instrumentedSigner struct {
+	inner   SecureSigner
+	metrics *SecureSignerMetrics
+}
+
+// NewInstrumentedSecureSigner returns a SecureSigner recording latency and
+// results of every call to inner in Prometheus metrics registered with reg.
+// If reg already holds the metrics of another instrumented signer, they are
+// shared, so signers built again with the same registry don't fail. It panics
+// if the metrics can't be registered otherwise. Callers that need to register
+// the metrics themselves should use NewSecureSignerMetrics and Instrument
+// instead.
+func NewInstrumentedSecureSigner(inner SecureSigner, reg prometheus.Registerer) SecureSigner {
+	m := NewSecureSignerMetrics()
+	if err := reg.Register(m); err != nil {
+		var are prometheus.AlreadyRegisteredError
+		if !errors.As(err, &are) {
+			panic(err)
+		}
+		existing, ok := are.ExistingCollector.(*SecureSignerMetrics)
+		if !ok {
+			panic(err)
+		}
+		m = existing
+	}
+	return m.Instrument(inner)
+}
+
+func (s *instrumentedSigner) GenerateKey() (_ []byte, err error) {
+	defer s.metrics.observe("generate_key", nil, time.Now(), &err)
+	return s.inner.GenerateKey()
+}
+
+func (s *instrumentedSigner) GenerateKeyContext(ctx context.Context) (_ []byte, err error) {
+	defer s.metrics.observe("generate_key", nil, time.Now(), &err)
+	return signerContext(s.inner).GenerateKeyContext(ctx)
+}
+
+func (s *instrumentedSigner) GetPublicKey(prvID []byte) (_ []byte, err error) {
+	defer s.metrics.observe("get_public_key", prvID, time.Now(), &err)
+	return s.inner.GetPublicKey(prvID)
+}
+
+func (s *instrumentedSigner) GetPublicKeyContext(ctx context.Context, prvID []byte) (_ []byte, err error) {
+	defer s.metrics.observe("get_public_key", prvID, time.Now(), &err)
+	return signerContext(s.inner).GetPublicKeyContext(ctx, prvID)
+}
+
+func (s *instrumentedSigner) GetPublicKeyCompressed(prvID []byte) (_ []byte, err error) {
+	defer s.metrics.observe("get_public_key_compressed", prvID, time.Now(), &err)
+	return s.inner.GetPublicKeyCompressed(prvID)
+}
+
+func (s *instrumentedSigner) Sign(tx *types.Transaction, signer types.Signer, prvID []byte) (_ *types.Transaction, err error) {
+	defer s.metrics.observe("sign", prvID, time.Now(), &err)
+	return s.inner.Sign(tx, signer, prvID)
+}
+
+func (s *instrumentedSigner) SignContext(ctx context.Context, tx *types.Transaction, signer types.Signer, prvID []byte) (_ *types.Transaction, err error) {
+	defer s.metrics.observe("sign", prvID, time.Now(), &err)
+	return signerContext(s.inner).SignContext(ctx, tx, signer, prvID)
+}
+
+func (s *instrumentedSigner) SignTypedData(typedData apitypes.TypedData, prvID []byte) (_ []byte, err error) {
+	defer s.metrics.observe("sign_typed_data", prvID, time.Now(), &err)
+	return s.inner.SignTypedData(typedData, prvID)
+}
+
+func (s *instrumentedSigner) SignPermit(chainID *big.Int, tokenAddr common.Address, tokenName, tokenVersion string, ownerAddr, spenderAddr common.Address, value, nonce *big.Int, deadline int64, prvID []byte) (_ uint8, _, _ [32]byte, err error) {
+	defer s.metrics.observe("sign_permit", prvID, time.Now(), &err)
+	return s.inner.SignPermit(chainID, tokenAddr, tokenName, tokenVersion, ownerAddr, spenderAddr, value, nonce, deadline, prvID)
+}
+
+func (s *instrumentedSigner) SignUserOperation(chainID *big.Int, entryPoint common.Address, op UserOperation, prvID []byte) (_ []byte, err error) {
+	defer s.metrics.observe("sign_user_operation", prvID, time.Now(), &err)
+	return s.inner.SignUserOperation(chainID, entryPoint, op, prvID)
+}
+
+func (s *instrumentedSigner) SignHash(hash common.Hash, prvID []byte) (_ []byte, err error) {
+	defer s.metrics.observe("sign_hash", prvID, time.Now(), &err)
+	return s.inner.SignHash(hash, prvID)
+}
+
+func (s *instrumentedSigner) SignHashBytes(data []byte, prvID []byte) (_ []byte, err error) {
+	defer s.metrics.observe("sign_hash_bytes", prvID, time.Now(), &err)
+	return s.inner.SignHashBytes(data, prvID)
+}
+
+func (s *instrumentedSigner) SignPersonalMessage(message []byte, prvID []byte) (_ []byte, err error) {
+	defer s.metrics.observe("sign_personal_message", prvID, time.Now(), &err)
+	return s.inner.SignPersonalMessage(message, prvID)
+}
+
+func (s *instrumentedSigner) VerifyPersonalMessage(message, sig []byte, expectedAddr common.Address) (err error) {
+	defer s.metrics.observe("verify_personal_message", nil, time.Now(), &err)
+	return s.inner.VerifyPersonalMessage(message, sig, expectedAddr)
+}
+
+func (s *instrumentedSigner) SignForChain(chainID *big.Int, tx *types.Transaction, prvID []byte) (_ *types.Transaction, err error) {
+	defer s.metrics.observe("sign_for_chain", prvID, time.Now(), &err)
+	return s.inner.SignForChain(chainID, tx, prvID)
+}
+
+func (s *instrumentedSigner) SignTransactionJSON(tx *types.Transaction, signer types.Signer, prvID []byte) (_ json.RawMessage, err error) {
+	defer s.metrics.observe("sign_transaction_json", prvID, time.Now(), &err)
+	return s.inner.SignTransactionJSON(tx, signer, prvID)
+}
+
+func (s *instrumentedSigner) SignAndEncode(tx *types.Transaction, signer types.Signer, prvID []byte) (_ []byte, err error) {
+	defer s.metrics.observe("sign_and_encode", prvID, time.Now(), &err)
+	return s.inner.SignAndEncode(tx, signer, prvID)
+}
+
+func (s *instrumentedSigner) SignAndEncodeHex(tx *types.Transaction, signer types.Signer, prvID []byte) (_ string, err error) {
+	defer s.metrics.observe("sign_and_encode_hex", prvID, time.Now(), &err)
+	return s.inner.SignAndEncodeHex(tx, signer, prvID)
+}
+
+func (s *instrumentedSigner) SignDynamicFeeTx(chainID *big.Int, nonce uint64, to common.Address, value *big.Int, gasLimit uint64, maxFeePerGas, maxPriorityFeePerGas *big.Int, data []byte, prvID []byte) (_ *types.Transaction, err error) {
+	defer s.metrics.observe("sign_dynamic_fee_tx", prvID, time.Now(), &err)
+	return s.inner.SignDynamicFeeTx(chainID, nonce, to, value, gasLimit, maxFeePerGas, maxPriorityFeePerGas, data, prvID)
+}
+
+func (s *instrumentedSigner) SignBlobTx(chainID *big.Int, blobTx *types.BlobTx, prvID []byte) (_ *types.Transaction, err error) {
+	defer s.metrics.observe("sign_blob_tx", prvID, time.Now(), &err)
+	return s.inner.SignBlobTx(chainID, blobTx, prvID)
+}
+
+func (s *instrumentedSigner) SignAccessListTx(chainID *big.Int, nonce uint64, to common.Address, value *big.Int, gasLimit uint64, gasPrice *big.Int, accessList types.AccessList, data []byte, prvID []byte) (_ *types.Transaction, err error) {
+	defer s.metrics.observe("sign_access_list_tx", prvID, time.Now(), &err)
+	return s.inner.SignAccessListTx(chainID, nonce, to, value, gasLimit, gasPrice, accessList, data, prvID)
+}
+
+func (s *instrumentedSigner) SignContractCall(chainID *big.Int, contract common.Address, contractABI abi.ABI, method string, args []interface{}, nonce uint64, gasLimit uint64, gasPrice *big.Int, prvID []byte) (_ *types.Transaction, err error) {
+	defer s.metrics.observe("sign_contract_call", prvID, time.Now(), &err)
+	return s.inner.SignContractCall(chainID, contract, contractABI, method, args, nonce, gasLimit, gasPrice, prvID)
+}
+
+func (s *instrumentedSigner) SimulateAndSign(ctx context.Context, tx *types.Transaction, signer types.Signer, client ethereum.ContractCaller, prvID []byte) (_ *types.Transaction, err error) {
+	defer s.metrics.observe("simulate_and_sign", prvID, time.Now(), &err)
+	return s.inner.SimulateAndSign(ctx, tx, signer, client, prvID)
+}
+
+func (s *instrumentedSigner) SignBatch(txs []*types.Transaction, signer types.Signer, prvID []byte) (_ []*types.Transaction, err error) {
+	defer s.metrics.observe("sign_batch", prvID, time.Now(), &err)
+	return s.inner.SignBatch(txs, signer, prvID)
+}
+
+func (s *instrumentedSigner) SignBatchParallel(txs []*types.Transaction, signer types.Signer, prvID []byte) (_ []*types.Transaction, err error) {
+	defer s.metrics.observe("sign_batch_parallel", prvID, time.Now(), &err)
+	return s.inner.SignBatchParallel(txs, signer, prvID)
+}
+
+func (s *instrumentedSigner) GetAddress(prvID []byte) (_ common.Address, err error) {
+	defer s.metrics.observe("get_address", prvID, time.Now(), &err)
+	return s.inner.GetAddress(prvID)
+}
+
+func (s *instrumentedSigner) VerifySignature(data, sig, prvID []byte) (_ bool, err error) {
+	defer s.metrics.observe("verify_signature", prvID, time.Now(), &err)
+	return s.inner.VerifySignature(data, sig, prvID)
+}
+
+func (s *instrumentedSigner) RecoverSigner(data, sig []byte) (_ common.Address, err error) {
+	defer s.metrics.observe("recover_signer", nil, time.Now(), &err)
+	return s.inner.RecoverSigner(data, sig)
+}
+
+func (s *instrumentedSigner) RecoverSenderFromTx(tx *types.Transaction, signer types.Signer) (_ common.Address, err error) {
+	defer s.metrics.observe("recover_sender_from_tx", nil, time.Now(), &err)
+	return s.inner.RecoverSenderFromTx(tx, signer)
+}
@@ -0,0 +1,72 @@
+package keeper
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestInstrumentedSecureSigner(t *testing.T) {
+	reg := prometheus.NewPedanticRegistry()
+	sec := NewInstrumentedSecureSigner(NewSecureSigner(defaultKeeper), reg)
+
+	prvID, err := sec.GenerateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	signer := types.LatestSignerForChainID(big.NewInt(1))
+	for i := 0; i < 3; i++ {
+		if _, err := sec.Sign(newTestTx(), signer, prvID); err != nil {
+			t.Fatalf("failed to sign: %v", err)
+		}
+	}
+	if _, err := sec.Sign(newTestTx(), signer, []byte{0x01}); err == nil {
+		t.Fatal("signed with invalid key")
+	}
+
+	prefix := keyIDPrefix(prvID)
+	if len(prefix) != 8 {
+		t.Fatalf("key id prefix length: have %d, want 8", len(prefix))
+	}
+	m := sec.(*instrumentedSigner).metrics
+	checks := []struct {
+		labels []string
+		want   float64
+	}{
+		{[]string{"generate_key", "", "success"}, 1},
+		{[]string{"sign", prefix, "success"}, 3},
+		{[]string{"sign", prefix, "failure"}, 0},
+		{[]string{"sign", keyIDPrefix([]byte{0x01}), "failure"}, 1},
+	}
+	for _, c := range checks {
+		if have := testutil.ToFloat64(m.Calls.WithLabelValues(c.labels...)); have != c.want {
+			t.Errorf("calls %v: have %v, want %v", c.labels, have, c.want)
+		}
+	}
+	// Every operation and key observed has its own latency histogram.
+	if have := testutil.CollectAndCount(m.Latency); have != 3 {
+		t.Errorf("latency series: have %d, want 3", have)
+	}
+	if _, err := reg.Gather(); err != nil {
+		t.Errorf("registry not consistent: %v", err)
+	}
+}
+
+func TestInstrumentedSecureSignerSharedRegistry(t *testing.T) {
+	reg := prometheus.NewPedanticRegistry()
+	first := NewInstrumentedSecureSigner(NewSecureSigner(defaultKeeper), reg)
+	second := NewInstrumentedSecureSigner(NewSecureSigner(defaultKeeper), reg)
+	if first.(*instrumentedSigner).metrics != second.(*instrumentedSigner).metrics {
+		t.Fatal("signers of the same registry don't share their metrics")
+	}
+	if _, err := second.GenerateKey(); err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	m := first.(*instrumentedSigner).metrics
+	if have := testutil.ToFloat64(m.Calls.WithLabelValues("generate_key", "", "success")); have != 1 {
+		t.Errorf("calls: have %v, want 1", have)
+	}
+}
@@ -0,0 +1,137 @@
+package keeper
+
+import (
+	"io"
+	"log/slog"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// KeeperMiddleware wraps a PrivateKeyKeeper in a decorator, see BuildKeeper.
+type KeeperMiddleware func(PrivateKeyKeeper) PrivateKeyKeeper
+
+// SignerMiddleware wraps a SecureSigner in a decorator, see BuildSecureSigner.
+type SignerMiddleware func(SecureSigner) SecureSigner
+
+// BuildKeeper wraps base in the decorators of middlewares. The first middleware
+// is the outermost layer, it sees the calls first and the results last, base
+// sees the calls last. E.g.
+//
+//	BuildKeeper(kms, WithAuditLog(w), WithRateLimit(10, 5), WithRetry(3, backoff))
+//
+// logs every signature attempt, rate limited ones included, and retries the
+// KMS calls failing as unavailable without the retries counting against the
+// rate limit.
+func BuildKeeper(base PrivateKeyKeeper, middlewares ...KeeperMiddleware) PrivateKeyKeeper {
+	k := base
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		k = middlewares[i](k)
+	}
+	return k
+}
+
+// BuildSecureSigner wraps base in the decorators of middlewares, the first
+// middleware being the outermost layer, as BuildKeeper does.
+func BuildSecureSigner(base SecureSigner, middlewares ...SignerMiddleware) SecureSigner {
+	s := base
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		s = middlewares[i](s)
+	}
+	return s
+}
+
+// WithAuditLog returns a middleware logging the signatures of the keeper to w,
+// see NewAuditedKeeper.
+func WithAuditLog(w io.Writer) KeeperMiddleware {
+	return func(inner PrivateKeyKeeper) PrivateKeyKeeper {
+		return NewAuditedKeeper(inner, w)
+	}
+}
+
+// WithRateLimit returns a middleware limiting the keeper to rps signatures per
+// second with bursts of burst signatures, see NewRateLimitedKeeper.
+func WithRateLimit(rps float64, burst int) KeeperMiddleware {
+	return func(inner PrivateKeyKeeper) PrivateKeyKeeper {
+		return NewRateLimitedKeeper(inner, rps, burst)
+	}
+}
+
+// WithRetry returns a middleware retrying the calls failing with
+// ErrBackendUnavailable, see NewRetryingKeeper.
+func WithRetry(maxAttempts int, backoff RetryBackoff, opts ...RetryOption) KeeperMiddleware {
+	return func(inner PrivateKeyKeeper) PrivateKeyKeeper {
+		return NewRetryingKeeper(inner, maxAttempts, backoff, opts...)
+	}
+}
+
+// WithTimeout returns a middleware bounding the time the calls to the keeper
+// take, see NewTimeoutKeeper.
+func WithTimeout(signTimeout, keyGenTimeout, pubKeyTimeout time.Duration) KeeperMiddleware {
+	return func(inner PrivateKeyKeeper) PrivateKeyKeeper {
+		return NewTimeoutKeeper(inner, signTimeout, keyGenTimeout, pubKeyTimeout)
+	}
+}
+
+// WithAdaptiveTimeout returns a middleware bounding the time signatures take by
+// a timeout following the latency of the keeper, see NewAdaptiveTimeoutKeeper.
+func WithAdaptiveTimeout(opts AdaptiveTimeoutOptions) KeeperMiddleware {
+	return func(inner PrivateKeyKeeper) PrivateKeyKeeper {
+		return NewAdaptiveTimeoutKeeper(inner, opts)
+	}
+}
+
+// WithCircuitBreaker returns a middleware refusing the calls to a keeper whose
+// backend failed too often, see NewCircuitBreakerKeeper.
+func WithCircuitBreaker(opts CircuitBreakerOptions) KeeperMiddleware {
+	return func(inner PrivateKeyKeeper) PrivateKeyKeeper {
+		return NewCircuitBreakerKeeper(inner, opts)
+	}
+}
+
+// WithSingleFlight returns a middleware coalescing concurrent identical
+// signing requests, see NewSingleFlightKeeper.
+func WithSingleFlight() KeeperMiddleware {
+	return NewSingleFlightKeeper
+}
+
+// WithLogging returns a middleware logging the calls to the keeper to logger,
+// see NewLoggingKeeper.
+func WithLogging(logger *slog.Logger) KeeperMiddleware {
+	return func(inner PrivateKeyKeeper) PrivateKeyKeeper {
+		return NewLoggingKeeper(inner, logger)
+	}
+}
+
+// WithMetadataStore returns a middleware keeping the metadata of the keys in
+// store, see NewMetadataAwareKeeper.
+func WithMetadataStore(store KeyMetadataStore) KeeperMiddleware {
+	return func(inner PrivateKeyKeeper) PrivateKeyKeeper {
+		return NewMetadataAwareKeeper(inner, store)
+	}
+}
+
+// WithExpiryEnforcement returns a middleware refusing expired keys, keeping the
+// metadata of the keys in store, see NewMetadataAwareKeeper and
+// NewExpiryEnforcingKeeper.
+func WithExpiryEnforcement(store KeyMetadataStore, opts ...ExpiryOption) KeeperMiddleware {
+	return func(inner PrivateKeyKeeper) PrivateKeyKeeper {
+		return NewExpiryEnforcingKeeper(NewMetadataAwareKeeper(inner, store).(MetadataAwareKeeper), opts...)
+	}
+}
+
+// WithMetrics returns a middleware recording the calls to the signer in
+// Prometheus metrics registered with reg, see NewInstrumentedSecureSigner.
+func WithMetrics(reg prometheus.Registerer) SignerMiddleware {
+	return func(inner SecureSigner) SecureSigner {
+		return NewInstrumentedSecureSigner(inner, reg)
+	}
+}
+
+// WithSignerAuditLog returns a middleware logging the transactions and messages
+// signed by the signer to w, see NewAuditedSecureSigner.
+func WithSignerAuditLog(w io.Writer) SignerMiddleware {
+	return func(inner SecureSigner) SecureSigner {
+		return NewAuditedSecureSigner(inner, w)
+	}
+}
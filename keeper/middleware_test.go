@@ -0,0 +1,153 @@
+package keeper
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// traceKeeper is a PrivateKeyKeeper noting its name in trace for every
+// signature before and after forwarding it.
+type traceKeeper struct {
+	PrivateKeyKeeper
+	name  string
+	trace *[]string
+}
+
+func (k *traceKeeper) Sign(data []byte, prvID []byte) ([]byte, error) {
+	*k.trace = append(*k.trace, k.name)
+	defer func() { *k.trace = append(*k.trace, "/"+k.name) }()
+	return k.PrivateKeyKeeper.Sign(data, prvID)
+}
+
+func traceMiddleware(name string, trace *[]string) KeeperMiddleware {
+	return func(inner PrivateKeyKeeper) PrivateKeyKeeper {
+		return &traceKeeper{PrivateKeyKeeper: inner, name: name, trace: trace}
+	}
+}
+
+// traceSigner is the SecureSigner counterpart of traceKeeper.
+type traceSigner struct {
+	SecureSigner
+	name  string
+	trace *[]string
+}
+
+func (s *traceSigner) Sign(tx *types.Transaction, signer types.Signer, prvID []byte) (*types.Transaction, error) {
+	*s.trace = append(*s.trace, s.name)
+	defer func() { *s.trace = append(*s.trace, "/"+s.name) }()
+	return s.SecureSigner.Sign(tx, signer, prvID)
+}
+
+func TestBuildKeeperOrder(t *testing.T) {
+	var trace []string
+	base := &traceKeeper{PrivateKeyKeeper: new(defaultPrivateKeyKeeper), name: "base", trace: &trace}
+	k := BuildKeeper(base, traceMiddleware("a", &trace), traceMiddleware("b", &trace), traceMiddleware("c", &trace))
+
+	prvID, err := k.GeneratePrivateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	if _, err := k.Sign(crypto.Keccak256([]byte("middleware")), prvID); err != nil {
+		t.Fatalf("failed to sign: %v", err)
+	}
+	want := []string{"a", "b", "c", "base", "/base", "/c", "/b", "/a"}
+	if !reflect.DeepEqual(trace, want) {
+		t.Fatalf("call sequence mismatch: have %v, want %v", trace, want)
+	}
+	if BuildKeeper(base) != base {
+		t.Fatal("keeper without middlewares is not the base keeper")
+	}
+}
+
+func TestBuildKeeperMiddlewares(t *testing.T) {
+	var (
+		buf   bytes.Buffer
+		inner = &flakyKeeper{PrivateKeyKeeper: new(defaultPrivateKeyKeeper), failures: 2, err: fmt.Errorf("%w: timeout", ErrBackendUnavailable)}
+		k     = BuildKeeper(inner,
+			WithAuditLog(&buf),
+			WithRateLimit(0.001, 1),
+			WithRetry(3, FixedBackoff(time.Millisecond)),
+		)
+		hash = crypto.Keccak256([]byte("middleware"))
+	)
+	prvID, err := k.GeneratePrivateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	// The retries happen below the rate limit and the audit log, they are
+	// neither limited nor logged.
+	if _, err := k.Sign(hash, prvID); err != nil {
+		t.Fatalf("failed to sign: %v", err)
+	}
+	if inner.calls != 3 {
+		t.Fatalf("backend calls mismatch: have %d, want 3", inner.calls)
+	}
+	// The rate limit is above the audit log, refused calls are logged.
+	if _, err := k.Sign(hash, prvID); !errors.Is(err, ErrRateLimitExceeded) {
+		t.Fatalf("second signature: have %v, want %v", err, ErrRateLimitExceeded)
+	}
+	lines := auditLines(t, &buf)
+	if len(lines) != 2 {
+		t.Fatalf("audit lines: have %d, want 2", len(lines))
+	}
+	checkAuditLine(t, lines[0], "sign", prvID, false)
+	checkAuditLine(t, lines[1], "sign", prvID, true)
+}
+
+func TestWithExpiryEnforcement(t *testing.T) {
+	store := MemoryMetadataStore()
+	k := BuildKeeper(new(defaultPrivateKeyKeeper), WithExpiryEnforcement(store, WithExpiry(-time.Hour)))
+	prvID, err := k.GeneratePrivateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	if _, err := store.Get(prvID); err != nil {
+		t.Fatalf("metadata not kept in store: %v", err)
+	}
+	if _, err := k.Sign(crypto.Keccak256([]byte("expiry")), prvID); !errors.Is(err, ErrKeyExpired) {
+		t.Fatalf("sign with expired key: have %v, want %v", err, ErrKeyExpired)
+	}
+}
+
+func TestBuildSecureSigner(t *testing.T) {
+	var (
+		trace []string
+		buf   bytes.Buffer
+		reg   = prometheus.NewRegistry()
+	)
+	base := &traceSigner{SecureSigner: NewSecureSigner(defaultKeeper), name: "base", trace: &trace}
+	s := BuildSecureSigner(base,
+		WithMetrics(reg),
+		func(inner SecureSigner) SecureSigner {
+			return &traceSigner{SecureSigner: inner, name: "a", trace: &trace}
+		},
+		WithSignerAuditLog(&buf),
+	)
+	prvID, err := s.GenerateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	signer := types.LatestSigner(params.TestChainConfig)
+	if _, err := s.Sign(newTestTx(), signer, prvID); err != nil {
+		t.Fatalf("failed to sign: %v", err)
+	}
+	if want := []string{"a", "base", "/base", "/a"}; !reflect.DeepEqual(trace, want) {
+		t.Fatalf("call sequence mismatch: have %v, want %v", trace, want)
+	}
+	if lines := auditLines(t, &buf); len(lines) != 1 {
+		t.Fatalf("audit lines: have %d, want 1", len(lines))
+	}
+	if n := testutil.CollectAndCount(reg, "keeper_signer_calls_total"); n != 2 {
+		t.Fatalf("metric series mismatch: have %d, want 2", n)
+	}
+}
@@ -0,0 +1,9 @@
+CREATE TABLE key_metadata (
+	prv_id     BYTEA PRIMARY KEY, -- keccak256 hash of the prvID
+	label      TEXT NOT NULL DEFAULT '',
+	purpose    TEXT NOT NULL DEFAULT '',
+	created_at TIMESTAMPTZ,
+	expires_at TIMESTAMPTZ,       -- NULL if the key doesn't expire
+	max_uses   BIGINT NOT NULL DEFAULT 0,
+	use_count  BIGINT NOT NULL DEFAULT 0
+);
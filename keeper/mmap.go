@@ -0,0 +1,422 @@
+package keeper
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"golang.org/x/crypto/scrypt"
+)
+
+// Layout of the key file of the memory-mapped keeper. The file starts with a
+// header, followed by a table of fixed-size slots each holding one key. All
+// integers are big endian.
+//
+//	header: magic[8] version[4] slots[4] scryptN[4] reserved[4] salt[32]
+//	        checkNonce[12] checkTag[16] reserved[44]
+//	slot:   used[1] reserved[3] nonce[12] sealedKey[48]
+//
+// The check tag seals the empty message with the first 56 bytes of the header
+// as additional data, so a wrong passphrase or a tampered header are noticed
+// on open. Keys are sealed with their slot index as additional data.
+const (
+	mmapMagic      = "KEEPMMAP"
+	mmapVersion    = 1
+	mmapHeaderSize = 128
+	mmapSlotSize   = 64
+	mmapSlots      = 1 << 14 // slots of new files, a MiB of keys
+
+	mmapCheckOffset = 56
+	mmapSealedSize  = 32 + 16 // raw key and GCM tag
+
+	// mmapMaxScryptN bounds the work the header of a file can make the key
+	// derivation do.
+	mmapMaxScryptN = 1 << 20
+)
+
+var errMMapClosed = errors.New("memory-mapped keeper closed")
+
+// mmapKeeper is a PrivateKeyKeeper holding keys in a single memory-mapped
+// file, encrypted with AES-256-GCM under a key derived from a passphrase with
+// scrypt. The prvID is the decimal index of the slot of the key.
+type mmapKeeper struct {
+	aead  cipher.AEAD
+	slots int
+
+	lock sync.RWMutex // guards data and the slots in it
+	file *os.File
+	data []byte // mapped file, nil once closed
+	next int    // slot to start looking for a free one at
+}
+
+// NewMMapKeeper returns a PrivateKeyKeeper storing its keys in the file at
+// filePath, which is created if it doesn't exist, for services handling many
+// keys where a keystore file per key is too slow. The file is memory-mapped
+// and holds a table of 16384 slots, keys are encrypted with a key derived from
+// passphrase.
+//
+// The file is locked while the keeper uses it, other processes can't open it
+// at the same time. It is recovered on open from crashes: a file cut short is
+// extended again and slots whose key doesn't decrypt any more, e.g. as they
+// were being written, are freed. The keeper implements io.Closer, unmapping
+// the file. It is only available on Unix.
+func NewMMapKeeper(filePath string, passphrase string) (PrivateKeyKeeper, error) {
+	return openMMapKeeper(filePath, passphrase, encryptedScryptN, mmapSlots)
+}
+
+func openMMapKeeper(filePath string, passphrase string, scryptN, slots int) (_ *mmapKeeper, err error) {
+	if passphrase == "" {
+		return nil, errors.New("empty passphrase")
+	}
+	f, err := os.OpenFile(filePath, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			f.Close()
+		}
+	}()
+	if err := lockFile(f); err != nil {
+		return nil, fmt.Errorf("key file %s in use: %v", filePath, err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	var aead cipher.AEAD
+	switch size := info.Size(); {
+	case size == 0:
+		header, newAEAD, err := newMMapHeader(passphrase, scryptN, slots)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := f.WriteAt(header, 0); err != nil {
+			return nil, err
+		}
+		if err := f.Sync(); err != nil {
+			return nil, err
+		}
+		aead = newAEAD
+	case size < mmapHeaderSize:
+		return nil, fmt.Errorf("key file %s corrupted: truncated header", filePath)
+	default:
+		header := make([]byte, mmapHeaderSize)
+		if _, err := f.ReadAt(header, 0); err != nil {
+			return nil, err
+		}
+		if aead, slots, err = openMMapHeader(header, passphrase); err != nil {
+			return nil, err
+		}
+	}
+	// Files cut short are extended, the lost slots read as free.
+	size := int64(mmapHeaderSize + slots*mmapSlotSize)
+	if info.Size() < size {
+		if err := f.Truncate(size); err != nil {
+			return nil, err
+		}
+	}
+	data, err := mapFile(f, int(size))
+	if err != nil {
+		return nil, err
+	}
+	k := &mmapKeeper{aead: aead, slots: slots, file: f, data: data}
+	k.recover()
+	return k, nil
+}
+
+// newMMapHeader returns the header of a new key file and its cipher.
+func newMMapHeader(passphrase string, scryptN, slots int) ([]byte, cipher.AEAD, error) {
+	header := make([]byte, mmapHeaderSize)
+	copy(header, mmapMagic)
+	binary.BigEndian.PutUint32(header[8:], mmapVersion)
+	binary.BigEndian.PutUint32(header[12:], uint32(slots))
+	binary.BigEndian.PutUint32(header[16:], uint32(scryptN))
+	if _, err := rand.Read(header[24:mmapCheckOffset]); err != nil {
+		return nil, nil, err
+	}
+	aead, err := mmapAEAD(passphrase, header[24:mmapCheckOffset], scryptN)
+	if err != nil {
+		return nil, nil, err
+	}
+	nonce := header[mmapCheckOffset : mmapCheckOffset+12]
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, nil, err
+	}
+	aead.Seal(nonce[len(nonce):len(nonce)], nonce, nil, header[:mmapCheckOffset])
+	return header, aead, nil
+}
+
+// openMMapHeader checks the header of a key file, returning its cipher and its
+// number of slots. A wrong passphrase fails with ErrPermissionDenied.
+func openMMapHeader(header []byte, passphrase string) (cipher.AEAD, int, error) {
+	if !bytes.Equal(header[:8], []byte(mmapMagic)) {
+		return nil, 0, errors.New("not a key file")
+	}
+	if version := binary.BigEndian.Uint32(header[8:]); version != mmapVersion {
+		return nil, 0, fmt.Errorf("unsupported key file version %d", version)
+	}
+	slots := int(binary.BigEndian.Uint32(header[12:]))
+	scryptN := int(binary.BigEndian.Uint32(header[16:]))
+	if slots == 0 || scryptN > mmapMaxScryptN {
+		return nil, 0, fmt.Errorf("invalid key file header: %d slots, scrypt N %d", slots, scryptN)
+	}
+	aead, err := mmapAEAD(passphrase, header[24:mmapCheckOffset], scryptN)
+	if err != nil {
+		return nil, 0, err
+	}
+	nonce, tag := header[mmapCheckOffset:mmapCheckOffset+12], header[mmapCheckOffset+12:mmapCheckOffset+28]
+	if _, err := aead.Open(nil, nonce, tag, header[:mmapCheckOffset]); err != nil {
+		return nil, 0, fmt.Errorf("%w: wrong passphrase for key file", ErrPermissionDenied)
+	}
+	return aead, slots, nil
+}
+
+// mmapAEAD derives the cipher of a key file from passphrase.
+func mmapAEAD(passphrase string, salt []byte, scryptN int) (cipher.AEAD, error) {
+	kek, err := scrypt.Key([]byte(passphrase), salt, scryptN, encryptedScryptR, encryptedScryptP, 32)
+	if err != nil {
+		return nil, err
+	}
+	defer zeroBytes(kek)
+
+	block, err := aes.NewCipher(kek)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// recover frees the used slots whose key doesn't decrypt, i.e. the ones torn
+// by a crash while being written.
+func (k *mmapKeeper) recover() {
+	for i := 0; i < k.slots; i++ {
+		if slot := k.slot(i); slot[0] != 0 {
+			secret, err := k.unseal(i)
+			if err != nil {
+				k.clear(i)
+				continue
+			}
+			zeroBytes(secret)
+		}
+	}
+	syncFile(k.data)
+}
+
+// slot returns the bytes of slot i in the mapped file.
+func (k *mmapKeeper) slot(i int) []byte {
+	off := mmapHeaderSize + i*mmapSlotSize
+	return k.data[off : off+mmapSlotSize]
+}
+
+// unseal decrypts the key of slot i. The caller has to clear it after use.
+func (k *mmapKeeper) unseal(i int) ([]byte, error) {
+	slot := k.slot(i)
+	if slot[0] == 0 {
+		return nil, ErrKeyNotFound
+	}
+	secret, err := k.aead.Open(nil, slot[4:16], slot[16:16+mmapSealedSize], mmapSlotID(i))
+	if err != nil {
+		return nil, fmt.Errorf("key slot %d corrupted", i)
+	}
+	return secret, nil
+}
+
+// clear wipes slot i, making it free.
+func (k *mmapKeeper) clear(i int) {
+	slot := k.slot(i)
+	slot[0] = 0
+	zeroBytes(slot[1:])
+}
+
+// syncSlot flushes the page of the mapped file holding slot i to disk.
+func (k *mmapKeeper) syncSlot(i int) error {
+	off := mmapHeaderSize + i*mmapSlotSize
+	start := off / os.Getpagesize() * os.Getpagesize()
+	return syncFile(k.data[start : off+mmapSlotSize])
+}
+
+// mmapSlotID returns the additional data sealing the key of slot i.
+func mmapSlotID(i int) []byte {
+	return binary.BigEndian.AppendUint32(nil, uint32(i))
+}
+
+// slotIndex parses prvID into the index of its slot.
+func (k *mmapKeeper) slotIndex(prvID []byte) (int, error) {
+	i, err := strconv.Atoi(string(prvID))
+	if err != nil || i < 0 || strconv.Itoa(i) != string(prvID) {
+		return 0, fmt.Errorf("invalid memory-mapped keeper prvID %q", prvID)
+	}
+	if i >= k.slots {
+		return 0, ErrKeyNotFound
+	}
+	return i, nil
+}
+
+func (k *mmapKeeper) GeneratePrivateKey() (_ []byte, err error) {
+	defer wrapError(&err, "generate key", nil)
+
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		return nil, err
+	}
+	defer ZeroKey(key)
+	rawKey := crypto.FromECDSA(key)
+	defer zeroBytes(rawKey)
+
+	return k.store(rawKey)
+}
+
+// ImportPrivateKey encrypts rawKey into a free slot.
+func (k *mmapKeeper) ImportPrivateKey(rawKey []byte) (_ []byte, err error) {
+	defer wrapError(&err, "import key", nil)
+
+	key, err := parseRawKey(rawKey)
+	if err != nil {
+		return nil, err
+	}
+	ZeroKey(key)
+	return k.store(rawKey)
+}
+
+// store encrypts secret into the next free slot and returns its prvID. The
+// slot is only marked used once the key is written, a crash in between leaves
+// it free.
+func (k *mmapKeeper) store(secret []byte) ([]byte, error) {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+
+	if k.data == nil {
+		return nil, errMMapClosed
+	}
+	for n := 0; n < k.slots; n++ {
+		i := (k.next + n) % k.slots
+		slot := k.slot(i)
+		if slot[0] != 0 {
+			continue
+		}
+		if _, err := io.ReadFull(rand.Reader, slot[4:16]); err != nil {
+			return nil, err
+		}
+		k.aead.Seal(slot[16:16], slot[4:16], secret, mmapSlotID(i))
+		if err := k.syncSlot(i); err != nil {
+			return nil, err
+		}
+		slot[0] = 1
+		if err := k.syncSlot(i); err != nil {
+			return nil, err
+		}
+		k.next = (i + 1) % k.slots
+		return []byte(strconv.Itoa(i)), nil
+	}
+	return nil, fmt.Errorf("memory-mapped keeper full, all %d slots used", k.slots)
+}
+
+// open decrypts the key prvID. The caller has to clear the returned key after
+// use.
+func (k *mmapKeeper) open(prvID []byte) (*ecdsa.PrivateKey, error) {
+	i, err := k.slotIndex(prvID)
+	if err != nil {
+		return nil, err
+	}
+	k.lock.RLock()
+	defer k.lock.RUnlock()
+
+	if k.data == nil {
+		return nil, errMMapClosed
+	}
+	secret, err := k.unseal(i)
+	if err != nil {
+		return nil, err
+	}
+	defer zeroBytes(secret)
+	return crypto.ToECDSA(secret)
+}
+
+func (k *mmapKeeper) GetPublicKey(prvID []byte) (_ []byte, err error) {
+	defer wrapError(&err, "get public key", prvID)
+
+	key, err := k.open(prvID)
+	if err != nil {
+		return nil, err
+	}
+	defer ZeroKey(key)
+	return crypto.FromECDSAPub(&key.PublicKey), nil
+}
+
+func (k *mmapKeeper) Sign(data []byte, prvID []byte) (_ []byte, err error) {
+	defer wrapError(&err, "sign", prvID)
+
+	key, err := k.open(prvID)
+	if err != nil {
+		return nil, err
+	}
+	defer ZeroKey(key)
+	sig, err := crypto.Sign(data, key)
+	if err != nil {
+		return nil, err
+	}
+	return NormaliseSignature(sig)
+}
+
+// DeletePrivateKey wipes the slot of the key, freeing it for new keys.
+func (k *mmapKeeper) DeletePrivateKey(prvID []byte) (err error) {
+	defer wrapError(&err, "delete key", prvID)
+
+	i, err := k.slotIndex(prvID)
+	if err != nil {
+		return err
+	}
+	k.lock.Lock()
+	defer k.lock.Unlock()
+
+	if k.data == nil {
+		return errMMapClosed
+	}
+	if k.slot(i)[0] == 0 {
+		return ErrKeyNotFound
+	}
+	k.clear(i)
+	return k.syncSlot(i)
+}
+
+func (k *mmapKeeper) ListPrivateKeys() (_ [][]byte, err error) {
+	defer wrapError(&err, "list keys", nil)
+
+	k.lock.RLock()
+	defer k.lock.RUnlock()
+
+	if k.data == nil {
+		return nil, errMMapClosed
+	}
+	var prvIDs [][]byte
+	for i := 0; i < k.slots; i++ {
+		if k.slot(i)[0] != 0 {
+			prvIDs = append(prvIDs, []byte(strconv.Itoa(i)))
+		}
+	}
+	return prvIDs, nil
+}
+
+// Close unmaps and unlocks the key file. The keeper can't be used afterwards.
+func (k *mmapKeeper) Close() error {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+
+	if k.data == nil {
+		return nil
+	}
+	err := unmapFile(k.data)
+	k.data = nil
+	return errors.Join(err, k.file.Close())
+}
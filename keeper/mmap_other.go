@@ -0,0 +1,26 @@
+//go:build !unix
+
+package keeper
+
+import (
+	"errors"
+	"os"
+)
+
+var errMMapUnsupported = errors.New("memory-mapped keeper is only supported on Unix")
+
+func lockFile(*os.File) error {
+	return errMMapUnsupported
+}
+
+func mapFile(*os.File, int) ([]byte, error) {
+	return nil, errMMapUnsupported
+}
+
+func syncFile([]byte) error {
+	return errMMapUnsupported
+}
+
+func unmapFile([]byte) error {
+	return errMMapUnsupported
+}
@@ -0,0 +1,179 @@
+//go:build unix
+
+package keeper
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func newTestMMapKeeper(t *testing.T, path string, passphrase string) *mmapKeeper {
+	t.Helper()
+
+	k, err := openMMapKeeper(path, passphrase, 1<<10, 64)
+	if err != nil {
+		t.Fatalf("failed to open keeper: %v", err)
+	}
+	t.Cleanup(func() { k.Close() })
+	return k
+}
+
+func TestMMapKeeper(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys")
+	k := newTestMMapKeeper(t, path, "foo")
+
+	prvID, err := k.GeneratePrivateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	pub, err := k.GetPublicKey(prvID)
+	if err != nil {
+		t.Fatalf("failed to get public key: %v", err)
+	}
+	hash := crypto.Keccak256([]byte("mmap"))
+	sig, err := k.Sign(hash, prvID)
+	if err != nil {
+		t.Fatalf("failed to sign: %v", err)
+	}
+	if recovered, err := crypto.Ecrecover(hash, sig); err != nil || !bytes.Equal(recovered, pub) {
+		t.Fatalf("recovered key mismatch: have (%x, %v), want %x", recovered, err, pub)
+	}
+	checkImport(t, k)
+
+	if _, err := openMMapKeeper(path, "foo", 1<<10, 64); err == nil {
+		t.Fatal("opened key file in use")
+	}
+	if err := k.DeletePrivateKey(prvID); err != nil {
+		t.Fatalf("failed to delete key: %v", err)
+	}
+	if _, err := k.Sign(hash, prvID); !errors.Is(err, ErrKeyNotFound) {
+		t.Fatalf("sign with deleted key: have %v, want %v", err, ErrKeyNotFound)
+	}
+	for _, id := range []string{"64", "-1", "01", "key"} {
+		if _, err := k.Sign(hash, []byte(id)); err == nil {
+			t.Fatalf("signed with prvID %q", id)
+		}
+	}
+
+	// The keys survive reopening the file, with the right passphrase only.
+	kept, _ := k.GeneratePrivateKey()
+	k.Close()
+	if _, err := k.Sign(hash, kept); !errors.Is(err, errMMapClosed) {
+		t.Fatalf("sign with closed keeper: have %v, want %v", err, errMMapClosed)
+	}
+	if _, err := openMMapKeeper(path, "bar", 1<<10, 64); !errors.Is(err, ErrPermissionDenied) {
+		t.Fatalf("open with wrong passphrase: have %v, want %v", err, ErrPermissionDenied)
+	}
+	reopened := newTestMMapKeeper(t, path, "foo")
+	if _, err := reopened.Sign(hash, kept); err != nil {
+		t.Fatalf("failed to sign after reopening: %v", err)
+	}
+}
+
+func TestMMapKeeperConcurrency(t *testing.T) {
+	k := newTestMMapKeeper(t, filepath.Join(t.TempDir(), "keys"), "foo")
+
+	var (
+		wg    sync.WaitGroup
+		lock  sync.Mutex
+		seen  = make(map[string]bool)
+		errch = make(chan error, 8)
+	)
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 6; j++ {
+				prvID, err := k.GeneratePrivateKey()
+				if err != nil {
+					errch <- err
+					return
+				}
+				if _, err := k.Sign(crypto.Keccak256(prvID), prvID); err != nil {
+					errch <- err
+					return
+				}
+				lock.Lock()
+				seen[string(prvID)] = true
+				lock.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	close(errch)
+	for err := range errch {
+		t.Fatalf("concurrent use failed: %v", err)
+	}
+	if len(seen) != 48 {
+		t.Fatalf("distinct keys: have %d, want 48", len(seen))
+	}
+	if ids, _ := k.ListPrivateKeys(); len(ids) != 48 {
+		t.Fatalf("listed keys: have %d, want 48", len(ids))
+	}
+}
+
+func TestMMapKeeperFull(t *testing.T) {
+	k := newTestMMapKeeper(t, filepath.Join(t.TempDir(), "keys"), "foo")
+	for i := 0; i < 64; i++ {
+		if _, err := k.GeneratePrivateKey(); err != nil {
+			t.Fatalf("failed to generate key %d: %v", i, err)
+		}
+	}
+	if _, err := k.GeneratePrivateKey(); err == nil {
+		t.Fatal("generated key in full keeper")
+	}
+}
+
+// TestMMapKeeperRecovery simulates a crash cutting the file short in the middle
+// of the second of three keys.
+func TestMMapKeeperRecovery(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys")
+	k := newTestMMapKeeper(t, path, "foo")
+
+	var prvIDs [][]byte
+	for i := 0; i < 3; i++ {
+		prvID, err := k.GeneratePrivateKey()
+		if err != nil {
+			t.Fatalf("failed to generate key: %v", err)
+		}
+		prvIDs = append(prvIDs, prvID)
+	}
+	pub, _ := k.GetPublicKey(prvIDs[0])
+	k.Close()
+	if err := os.Truncate(path, mmapHeaderSize+mmapSlotSize+mmapSlotSize/2); err != nil {
+		t.Fatalf("failed to truncate file: %v", err)
+	}
+
+	recovered := newTestMMapKeeper(t, path, "foo")
+	if have, err := recovered.GetPublicKey(prvIDs[0]); err != nil || !bytes.Equal(have, pub) {
+		t.Fatalf("intact key: have (%x, %v), want %x", have, err, pub)
+	}
+	for _, prvID := range prvIDs[1:] {
+		if _, err := recovered.GetPublicKey(prvID); !errors.Is(err, ErrKeyNotFound) {
+			t.Fatalf("lost key %s: have %v, want %v", prvID, err, ErrKeyNotFound)
+		}
+	}
+	if ids, _ := recovered.ListPrivateKeys(); len(ids) != 1 {
+		t.Fatalf("keys after recovery: have %d, want 1", len(ids))
+	}
+	if info, _ := os.Stat(path); info.Size() != mmapHeaderSize+64*mmapSlotSize {
+		t.Fatalf("file size after recovery: have %d, want %d", info.Size(), mmapHeaderSize+64*mmapSlotSize)
+	}
+	if _, err := recovered.GeneratePrivateKey(); err != nil {
+		t.Fatalf("failed to generate key after recovery: %v", err)
+	}
+	recovered.Close()
+
+	if err := os.Truncate(path, mmapHeaderSize/2); err != nil {
+		t.Fatalf("failed to truncate file: %v", err)
+	}
+	if _, err := openMMapKeeper(path, "foo", 1<<10, 64); err == nil {
+		t.Fatal("opened file with truncated header")
+	}
+}
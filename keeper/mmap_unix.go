@@ -0,0 +1,28 @@
+//go:build unix
+
+package keeper
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// lockFile takes an exclusive lock of f, failing if another process holds one.
+func lockFile(f *os.File) error {
+	return unix.Flock(int(f.Fd()), unix.LOCK_EX|unix.LOCK_NB)
+}
+
+// mapFile maps the first size bytes of f into memory, shared with the file.
+func mapFile(f *os.File, size int) ([]byte, error) {
+	return unix.Mmap(int(f.Fd()), 0, size, unix.PROT_READ|unix.PROT_WRITE, unix.MAP_SHARED)
+}
+
+// syncFile writes the changes to the mapping data back to its file.
+func syncFile(data []byte) error {
+	return unix.Msync(data, unix.MS_SYNC)
+}
+
+func unmapFile(data []byte) error {
+	return unix.Munmap(data)
+}
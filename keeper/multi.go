@@ -0,0 +1,177 @@
+package keeper
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// multiEnvelope is the JSON encoded prvID of the multi keeper, holding the
+// prvIDs of the key in the primary keeper and the fallbacks, in that order.
+type multiEnvelope struct {
+	IDs []hexutil.Bytes `json:"ids"`
+}
+
+// multiKeeper is a PrivateKeyKeeper holding every key in several backends, so
+// signing survives an outage of the primary one.
+type multiKeeper struct {
+	backends []PrivateKeyKeeperContext // primary first
+}
+
+// NewMultiKeeper returns a PrivateKeyKeeper replicating its keys from primary
+// to all fallbacks. As most backends never hand out their keys, new keys are
+// generated in memory and imported into every backend.
+//
+// Signing falls through to the next backend while they fail with
+// ErrBackendUnavailable, public keys are read from primary only. The prvID is
+// an envelope holding the prvIDs of all backends, which is only as secret as
+// the most revealing of them.
+func NewMultiKeeper(primary PrivateKeyKeeper, fallbacks ...PrivateKeyKeeper) PrivateKeyKeeper {
+	k := new(multiKeeper)
+	for _, backend := range append([]PrivateKeyKeeper{primary}, fallbacks...) {
+		k.backends = append(k.backends, ContextKeeper(backend))
+	}
+	return k
+}
+
+// envelope decodes the prvID of the keeper.
+func (k *multiKeeper) envelope(prvID []byte) (*multiEnvelope, error) {
+	env := new(multiEnvelope)
+	if err := json.Unmarshal(prvID, env); err != nil {
+		return nil, fmt.Errorf("invalid multi keeper envelope: %v", err)
+	}
+	if len(env.IDs) != len(k.backends) {
+		return nil, fmt.Errorf("invalid multi keeper envelope: %d key ids for %d backends", len(env.IDs), len(k.backends))
+	}
+	return env, nil
+}
+
+func (k *multiKeeper) GeneratePrivateKey() ([]byte, error) {
+	return k.GeneratePrivateKeyContext(context.Background())
+}
+
+func (k *multiKeeper) GeneratePrivateKeyContext(ctx context.Context) (_ []byte, err error) {
+	defer wrapError(&err, "generate key", nil)
+
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		return nil, err
+	}
+	defer ZeroKey(key)
+	rawKey := crypto.FromECDSA(key)
+	defer zeroBytes(rawKey)
+
+	return k.replicate(ctx, rawKey)
+}
+
+func (k *multiKeeper) ImportPrivateKey(rawKey []byte) ([]byte, error) {
+	return k.ImportPrivateKeyContext(context.Background(), rawKey)
+}
+
+func (k *multiKeeper) ImportPrivateKeyContext(ctx context.Context, rawKey []byte) (_ []byte, err error) {
+	defer wrapError(&err, "import key", nil)
+
+	key, err := parseRawKey(rawKey)
+	if err != nil {
+		return nil, err
+	}
+	ZeroKey(key)
+	return k.replicate(ctx, rawKey)
+}
+
+// replicate imports rawKey into all backends. If any import fails, the key is
+// deleted again from the backends holding it already.
+func (k *multiKeeper) replicate(ctx context.Context, rawKey []byte) ([]byte, error) {
+	env := new(multiEnvelope)
+	for i, backend := range k.backends {
+		prvID, err := backend.ImportPrivateKeyContext(ctx, rawKey)
+		if err != nil {
+			return nil, k.rollback(env, fmt.Errorf("failed to replicate key to backend %d: %w", i, err))
+		}
+		env.IDs = append(env.IDs, prvID)
+	}
+	return json.Marshal(env)
+}
+
+// rollback deletes the key of a failed generation from the backends it was
+// replicated to. Failed deletions are reported along with err.
+func (k *multiKeeper) rollback(env *multiEnvelope, err error) error {
+	errs := []error{err}
+	for i, prvID := range env.IDs {
+		if delErr := k.backends[i].DeletePrivateKeyContext(context.Background(), prvID); delErr != nil {
+			errs = append(errs, fmt.Errorf("failed to roll back key of backend %d: %w", i, delErr))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (k *multiKeeper) GetPublicKey(prvID []byte) ([]byte, error) {
+	return k.GetPublicKeyContext(context.Background(), prvID)
+}
+
+func (k *multiKeeper) GetPublicKeyContext(ctx context.Context, prvID []byte) (_ []byte, err error) {
+	defer wrapError(&err, "get public key", prvID)
+
+	env, err := k.envelope(prvID)
+	if err != nil {
+		return nil, err
+	}
+	return k.backends[0].GetPublicKeyContext(ctx, env.IDs[0])
+}
+
+func (k *multiKeeper) Sign(data []byte, prvID []byte) ([]byte, error) {
+	return k.SignContext(context.Background(), data, prvID)
+}
+
+func (k *multiKeeper) SignContext(ctx context.Context, data []byte, prvID []byte) (_ []byte, err error) {
+	defer wrapError(&err, "sign", prvID)
+
+	env, err := k.envelope(prvID)
+	if err != nil {
+		return nil, err
+	}
+	for i, backend := range k.backends {
+		var sig []byte
+		if sig, err = backend.SignContext(ctx, data, env.IDs[i]); !errors.Is(err, ErrBackendUnavailable) {
+			return sig, err
+		}
+	}
+	return nil, err
+}
+
+func (k *multiKeeper) DeletePrivateKey(prvID []byte) error {
+	return k.DeletePrivateKeyContext(context.Background(), prvID)
+}
+
+// DeletePrivateKeyContext deletes the key from all backends, also if some of
+// them fail.
+func (k *multiKeeper) DeletePrivateKeyContext(ctx context.Context, prvID []byte) (err error) {
+	defer wrapError(&err, "delete key", prvID)
+
+	env, err := k.envelope(prvID)
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for i, backend := range k.backends {
+		if err := backend.DeletePrivateKeyContext(ctx, env.IDs[i]); err != nil {
+			errs = append(errs, fmt.Errorf("backend %d: %w", i, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// ListPrivateKeys returns ErrNotSupported, the envelopes are not stored in any
+// of the backends.
+func (k *multiKeeper) ListPrivateKeys() ([][]byte, error) {
+	return k.ListPrivateKeysContext(context.Background())
+}
+
+func (k *multiKeeper) ListPrivateKeysContext(ctx context.Context) (_ [][]byte, err error) {
+	defer wrapError(&err, "list keys", nil)
+	return nil, ErrNotSupported
+}
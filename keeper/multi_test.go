@@ -0,0 +1,115 @@
+package keeper
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// downKeeper is a PrivateKeyKeeper whose signing backend can be taken down.
+type downKeeper struct {
+	PrivateKeyKeeper
+	down bool
+}
+
+func (k *downKeeper) Sign(data []byte, prvID []byte) ([]byte, error) {
+	if k.down {
+		return nil, ErrBackendUnavailable
+	}
+	return k.PrivateKeyKeeper.Sign(data, prvID)
+}
+
+type failingPassphrase struct{}
+
+func (failingPassphrase) Passphrase([]byte) (string, error) {
+	return "", errors.New("passphrase store unreachable")
+}
+
+func newTestKeystoreKeeper(t *testing.T, passphrases PassphraseProvider) PrivateKeyKeeper {
+	return NewKeystoreKeeper(t.TempDir(), keystore.LightScryptN, keystore.LightScryptP, passphrases)
+}
+
+func TestMultiKeeperFallback(t *testing.T) {
+	primary := &downKeeper{PrivateKeyKeeper: newTestKeystoreKeeper(t, MemoryPassphraseProvider("foo"))}
+	fallback := &downKeeper{PrivateKeyKeeper: &defaultPrivateKeyKeeper{}}
+	k := NewMultiKeeper(primary, fallback)
+
+	prvID, err := k.GeneratePrivateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	if ids, _ := primary.ListPrivateKeys(); len(ids) != 1 {
+		t.Fatalf("primary keys: have %d, want 1", len(ids))
+	}
+	pub, err := k.GetPublicKey(prvID)
+	if err != nil {
+		t.Fatalf("failed to get public key: %v", err)
+	}
+	hash := crypto.Keccak256([]byte("multi"))
+	for _, down := range []bool{false, true} {
+		primary.down = down
+		sig, err := k.Sign(hash, prvID)
+		if err != nil {
+			t.Fatalf("primary down %v: failed to sign: %v", down, err)
+		}
+		if recovered, err := crypto.Ecrecover(hash, sig); err != nil || !bytes.Equal(recovered, pub) {
+			t.Fatalf("primary down %v: recovered key mismatch: have (%x, %v), want %x", down, recovered, err, pub)
+		}
+	}
+	fallback.down = true
+	if _, err := k.Sign(hash, prvID); !errors.Is(err, ErrBackendUnavailable) {
+		t.Fatalf("all backends down: have %v, want %v", err, ErrBackendUnavailable)
+	}
+	if err := k.DeletePrivateKey(prvID); err != nil {
+		t.Fatalf("failed to delete key: %v", err)
+	}
+	if ids, _ := primary.ListPrivateKeys(); len(ids) != 0 {
+		t.Fatalf("primary keys after deletion: have %d, want 0", len(ids))
+	}
+}
+
+func TestMultiKeeperImport(t *testing.T) {
+	primary := newTestKeystoreKeeper(t, MemoryPassphraseProvider("foo"))
+	fallback := newTestKeystoreKeeper(t, MemoryPassphraseProvider("foo"))
+	checkImport(t, NewMultiKeeper(primary, fallback))
+
+	for i, backend := range []PrivateKeyKeeper{primary, fallback} {
+		if ids, err := backend.ListPrivateKeys(); err != nil || len(ids) != 1 {
+			t.Errorf("backend %d after import: have (%d keys, %v), want 1", i, len(ids), err)
+		}
+	}
+}
+
+func TestMultiKeeperNoFallthrough(t *testing.T) {
+	primary := newTestKeystoreKeeper(t, MemoryPassphraseProvider("foo"))
+	k := NewMultiKeeper(primary, &downKeeper{PrivateKeyKeeper: &defaultPrivateKeyKeeper{}})
+
+	prvID, err := k.GeneratePrivateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	// Errors other than outages are reported, not retried with the fallbacks.
+	primary.(*keystoreKeeper).passphrases = MemoryPassphraseProvider("bar")
+	if _, err := k.Sign(crypto.Keccak256([]byte("multi")), prvID); !errors.Is(err, ErrPermissionDenied) {
+		t.Fatalf("wrong passphrase: have %v, want %v", err, ErrPermissionDenied)
+	}
+}
+
+func TestMultiKeeperReplicationRollback(t *testing.T) {
+	primary := newTestKeystoreKeeper(t, MemoryPassphraseProvider("foo"))
+	second := newTestKeystoreKeeper(t, MemoryPassphraseProvider("foo"))
+	broken := newTestKeystoreKeeper(t, failingPassphrase{})
+	k := NewMultiKeeper(primary, second, broken)
+
+	if _, err := k.GeneratePrivateKey(); err == nil {
+		t.Fatal("generated key with failing replication")
+	}
+	for i, backend := range []PrivateKeyKeeper{primary, second, broken} {
+		if ids, err := backend.ListPrivateKeys(); err != nil || len(ids) != 0 {
+			t.Errorf("backend %d after rollback: have (%d keys, %v), want none", i, len(ids), err)
+		}
+	}
+}
@@ -0,0 +1,187 @@
+package keeper
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// namespacedKeeper is a PrivateKeyKeeper confining its callers to the keys
+// created in one namespace of a keeper shared with others.
+type namespacedKeeper struct {
+	inner  PrivateKeyKeeperContext
+	prefix []byte // namespace + "/"
+	owners KeyMetadataStore
+}
+
+// NamespaceOption configures a namespaced keeper.
+type NamespaceOption func(*namespacedKeeper)
+
+// WithNamespaceStore records the keys of the namespace in store instead of in
+// memory. The namespaces sharing a keeper may share the store as well, their
+// records don't collide.
+func WithNamespaceStore(store KeyMetadataStore) NamespaceOption {
+	return func(k *namespacedKeeper) {
+		k.owners = store
+	}
+}
+
+// NewNamespacedKeeper returns a PrivateKeyKeeper giving access to the keys of
+// inner generated or imported through the namespace only. The prvIDs it hands
+// out are the ones of inner prefixed with namespace + "/", ListPrivateKeys
+// lists the keys of the namespace and prvIDs of other namespaces, or of keys
+// of inner not created through the namespace, fail with ErrKeyNotFound.
+//
+// The backends choose the prvIDs of their keys themselves, so the prefix alone
+// can't tell whose key a prvID is. The keys of the namespace are recorded by
+// their namespaced prvID in a MemoryMetadataStore, which forgets them when the
+// process exits, unless WithNamespaceStore gives a persistent store. The
+// namespace must not be empty nor contain a "/".
+func NewNamespacedKeeper(inner PrivateKeyKeeper, namespace string, opts ...NamespaceOption) (PrivateKeyKeeper, error) {
+	if namespace == "" || strings.Contains(namespace, "/") {
+		return nil, fmt.Errorf("invalid namespace %q", namespace)
+	}
+	k := &namespacedKeeper{inner: ContextKeeper(inner), prefix: []byte(namespace + "/")}
+	for _, opt := range opts {
+		opt(k)
+	}
+	if k.owners == nil {
+		k.owners = MemoryMetadataStore()
+	}
+	return k, nil
+}
+
+// namespaced returns the prvID of the namespace for the key innerID of inner.
+func (k *namespacedKeeper) namespaced(innerID []byte) []byte {
+	return append(bytes.Clone(k.prefix), innerID...)
+}
+
+// resolve returns the prvID of inner for the key prvID of the namespace.
+func (k *namespacedKeeper) resolve(prvID []byte) ([]byte, error) {
+	innerID, ok := bytes.CutPrefix(prvID, k.prefix)
+	if !ok {
+		return nil, ErrKeyNotFound
+	}
+	if _, err := k.owners.Get(prvID); err != nil {
+		return nil, err
+	}
+	return innerID, nil
+}
+
+// claim records the key innerID just created in inner as a key of the
+// namespace. Keys that couldn't be recorded are deleted again, as they would
+// be out of reach.
+func (k *namespacedKeeper) claim(ctx context.Context, innerID []byte) ([]byte, error) {
+	prvID := k.namespaced(innerID)
+	if err := k.owners.Set(prvID, KeyMetadata{Created: time.Now().UTC()}); err != nil {
+		k.inner.DeletePrivateKeyContext(ctx, innerID)
+		return nil, fmt.Errorf("failed to record key of namespace: %w", err)
+	}
+	return prvID, nil
+}
+
+func (k *namespacedKeeper) GeneratePrivateKey() ([]byte, error) {
+	return k.GeneratePrivateKeyContext(context.Background())
+}
+
+func (k *namespacedKeeper) GeneratePrivateKeyContext(ctx context.Context) (_ []byte, err error) {
+	defer wrapError(&err, "generate key", nil)
+
+	innerID, err := k.inner.GeneratePrivateKeyContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return k.claim(ctx, innerID)
+}
+
+func (k *namespacedKeeper) GetPublicKey(prvID []byte) ([]byte, error) {
+	return k.GetPublicKeyContext(context.Background(), prvID)
+}
+
+func (k *namespacedKeeper) GetPublicKeyContext(ctx context.Context, prvID []byte) (_ []byte, err error) {
+	defer wrapError(&err, "get public key", prvID)
+
+	innerID, err := k.resolve(prvID)
+	if err != nil {
+		return nil, err
+	}
+	return k.inner.GetPublicKeyContext(ctx, innerID)
+}
+
+func (k *namespacedKeeper) Sign(data []byte, prvID []byte) ([]byte, error) {
+	return k.SignContext(context.Background(), data, prvID)
+}
+
+func (k *namespacedKeeper) SignContext(ctx context.Context, data []byte, prvID []byte) (_ []byte, err error) {
+	defer wrapError(&err, "sign", prvID)
+
+	innerID, err := k.resolve(prvID)
+	if err != nil {
+		return nil, err
+	}
+	return k.inner.SignContext(ctx, data, innerID)
+}
+
+func (k *namespacedKeeper) DeletePrivateKey(prvID []byte) error {
+	return k.DeletePrivateKeyContext(context.Background(), prvID)
+}
+
+func (k *namespacedKeeper) DeletePrivateKeyContext(ctx context.Context, prvID []byte) (err error) {
+	defer wrapError(&err, "delete key", prvID)
+
+	innerID, err := k.resolve(prvID)
+	if err != nil {
+		return err
+	}
+	if err := k.inner.DeletePrivateKeyContext(ctx, innerID); err != nil {
+		return err
+	}
+	return k.owners.Delete(prvID)
+}
+
+func (k *namespacedKeeper) ListPrivateKeys() ([][]byte, error) {
+	return k.ListPrivateKeysContext(context.Background())
+}
+
+func (k *namespacedKeeper) ListPrivateKeysContext(ctx context.Context) (_ [][]byte, err error) {
+	defer wrapError(&err, "list keys", nil)
+
+	innerIDs, err := k.inner.ListPrivateKeysContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var prvIDs [][]byte
+	for _, innerID := range innerIDs {
+		prvID := k.namespaced(innerID)
+		_, err := k.owners.Get(prvID)
+		if errors.Is(err, ErrKeyNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		prvIDs = append(prvIDs, prvID)
+	}
+	return prvIDs, nil
+}
+
+func (k *namespacedKeeper) ImportPrivateKey(rawKey []byte) ([]byte, error) {
+	return k.ImportPrivateKeyContext(context.Background(), rawKey)
+}
+
+func (k *namespacedKeeper) ImportPrivateKeyContext(ctx context.Context, rawKey []byte) (_ []byte, err error) {
+	defer wrapError(&err, "import key", nil)
+
+	innerID, err := k.inner.ImportPrivateKeyContext(ctx, rawKey)
+	if err != nil {
+		return nil, err
+	}
+	return k.claim(ctx, innerID)
+}
+
+func (k *namespacedKeeper) HealthCheck(ctx context.Context) error {
+	return checkHealth(ctx, k.inner)
+}
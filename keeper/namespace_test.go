@@ -0,0 +1,108 @@
+package keeper
+
+import (
+	"bytes"
+	"errors"
+	"math/rand"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestNamespacedKeeper(t *testing.T) {
+	store, err := FileMetadataStore(filepath.Join(t.TempDir(), "namespaces.json"))
+	if err != nil {
+		t.Fatalf("failed to open store: %v", err)
+	}
+	shared, err := newEncryptedMemoryKeeper("passphrase", 1<<10, rand.New(rand.NewSource(1)))
+	if err != nil {
+		t.Fatalf("failed to create keeper: %v", err)
+	}
+	a, err := NewNamespacedKeeper(shared, "a", WithNamespaceStore(store))
+	if err != nil {
+		t.Fatalf("failed to create namespace: %v", err)
+	}
+	b, err := NewNamespacedKeeper(shared, "b", WithNamespaceStore(store))
+	if err != nil {
+		t.Fatalf("failed to create namespace: %v", err)
+	}
+	hash := crypto.Keccak256([]byte("namespace"))
+	prvA, err := a.GeneratePrivateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	if !bytes.HasPrefix(prvA, []byte("a/")) {
+		t.Fatalf("prvID %q not in namespace", prvA)
+	}
+	prvB, err := b.ImportPrivateKey(crypto.Keccak256([]byte("tenant b")))
+	if err != nil {
+		t.Fatalf("failed to import key: %v", err)
+	}
+	if _, err := a.Sign(hash, prvA); err != nil {
+		t.Fatalf("failed to sign in own namespace: %v", err)
+	}
+
+	// Neither the prvIDs of the other namespace nor the ones of the shared
+	// keeper, with or without the own prefix, reach the keys of others.
+	innerA := bytes.TrimPrefix(prvA, []byte("a/"))
+	for _, prvID := range [][]byte{prvA, innerA, append([]byte("b/"), innerA...)} {
+		if _, err := b.Sign(hash, prvID); !errors.Is(err, ErrKeyNotFound) {
+			t.Fatalf("sign with %q in other namespace: have %v, want %v", prvID, err, ErrKeyNotFound)
+		}
+		if _, err := b.GetPublicKey(prvID); !errors.Is(err, ErrKeyNotFound) {
+			t.Fatalf("public key of %q in other namespace: have %v, want %v", prvID, err, ErrKeyNotFound)
+		}
+		if err := b.DeletePrivateKey(prvID); !errors.Is(err, ErrKeyNotFound) {
+			t.Fatalf("delete %q in other namespace: have %v, want %v", prvID, err, ErrKeyNotFound)
+		}
+	}
+	// Keys of the shared keeper created outside the namespaces are out of
+	// reach too.
+	outside, _ := shared.GeneratePrivateKey()
+	if _, err := a.Sign(hash, append([]byte("a/"), outside...)); !errors.Is(err, ErrKeyNotFound) {
+		t.Fatalf("sign with key outside namespace: have %v, want %v", err, ErrKeyNotFound)
+	}
+
+	checkList := func(k PrivateKeyKeeper, want ...[]byte) {
+		t.Helper()
+		have, err := k.ListPrivateKeys()
+		if err != nil {
+			t.Fatalf("failed to list keys: %v", err)
+		}
+		if len(have) != len(want) {
+			t.Fatalf("listed keys: have %d, want %d", len(have), len(want))
+		}
+		for i := range want {
+			if !bytes.Equal(have[i], want[i]) {
+				t.Fatalf("listed key %d: have %q, want %q", i, have[i], want[i])
+			}
+		}
+	}
+	checkList(a, prvA)
+	checkList(b, prvB)
+
+	// A keeper of the namespace on the same store takes over its keys.
+	reopened, err := NewNamespacedKeeper(shared, "a", WithNamespaceStore(store))
+	if err != nil {
+		t.Fatalf("failed to reopen namespace: %v", err)
+	}
+	if _, err := reopened.Sign(hash, prvA); err != nil {
+		t.Fatalf("failed to sign with reopened namespace: %v", err)
+	}
+	if err := a.DeletePrivateKey(prvA); err != nil {
+		t.Fatalf("failed to delete key: %v", err)
+	}
+	checkList(a)
+	if _, err := a.Sign(hash, prvA); !errors.Is(err, ErrKeyNotFound) {
+		t.Fatalf("sign with deleted key: have %v, want %v", err, ErrKeyNotFound)
+	}
+}
+
+func TestNamespacedKeeperInvalid(t *testing.T) {
+	for _, namespace := range []string{"", "a/b"} {
+		if _, err := NewNamespacedKeeper(new(defaultPrivateKeyKeeper), namespace); err == nil {
+			t.Errorf("namespace %q accepted", namespace)
+		}
+	}
+}
@@ -0,0 +1,304 @@
+package keeper
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"sync"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/holiman/uint256"
+)
+
+// PendingNoncer returns the next nonce of an account including its pending
+// transactions, like the PendingNonceAt of ethclient.Client.
+type PendingNoncer interface {
+	PendingNonceAt(ctx context.Context, account common.Address) (uint64, error)
+}
+
+// NonceManagedSigner is a SecureSigner assigning sequential nonces to the
+// transactions it signs, replacing the nonce they carry:
+//
+//	s := NewNonceManagedSigner(inner, client)
+//	tx1, _ := s.Sign(tx, signer, prvID) // pending nonce of the account
+//	tx2, _ := s.Sign(tx, signer, prvID) // pending nonce + 1
+//
+// The nonces are counted in memory from the pending nonce of the account on
+// first use. When transactions are dropped or the account is used elsewhere,
+// the counter has to be brought back in line with Reset.
+type NonceManagedSigner interface {
+	SecureSigner
+
+	// Reset refetches the pending nonce of the key prvID, the next transaction
+	// signed with it gets that nonce.
+	Reset(prvID []byte) error
+}
+
+// NewNonceManagedSigner returns a NonceManagedSigner signing with inner and
+// taking the pending nonces of the accounts from client. Transactions of one
+// key are signed one at a time, in the order of their nonces. A transaction
+// that fails to sign doesn't use up its nonce. Batches get consecutive nonces
+// in the order of the transactions.
+func NewNonceManagedSigner(inner SecureSigner, client PendingNoncer) NonceManagedSigner {
+	return &nonceSigner{SecureSigner: inner, client: client, nonces: make(map[string]*keyNonce)}
+}
+
+// keyNonce is the nonce counter of a key. The lock is held while signing, so
+// the counter only moves once the transaction using it is signed.
+type keyNonce struct {
+	lock    sync.Mutex
+	next    uint64
+	fetched bool
+}
+
+type nonceSigner struct {
+	SecureSigner
+	client PendingNoncer
+
+	lock   sync.Mutex
+	nonces map[string]*keyNonce // prvID -> nonce counter
+}
+
+// acquire returns the locked nonce counter of prvID, fetching the pending nonce
+// if it isn't known yet.
+func (s *nonceSigner) acquire(ctx context.Context, prvID []byte) (*keyNonce, error) {
+	s.lock.Lock()
+	n, ok := s.nonces[string(prvID)]
+	if !ok {
+		n = new(keyNonce)
+		s.nonces[string(prvID)] = n
+	}
+	s.lock.Unlock()
+
+	n.lock.Lock()
+	if !n.fetched {
+		if err := s.fetch(ctx, prvID, n); err != nil {
+			n.lock.Unlock()
+			return nil, err
+		}
+	}
+	return n, nil
+}
+
+// fetch sets the counter n of prvID to the pending nonce of its account.
+func (s *nonceSigner) fetch(ctx context.Context, prvID []byte, n *keyNonce) error {
+	addr, err := s.GetAddress(prvID)
+	if err != nil {
+		return err
+	}
+	nonce, err := s.client.PendingNonceAt(ctx, addr)
+	if err != nil {
+		return fmt.Errorf("failed to get pending nonce of %v: %w", addr, err)
+	}
+	n.next, n.fetched = nonce, true
+	return nil
+}
+
+func (s *nonceSigner) Reset(prvID []byte) (err error) {
+	defer wrapError(&err, "reset nonce", prvID)
+
+	s.lock.Lock()
+	n, ok := s.nonces[string(prvID)]
+	if !ok {
+		n = new(keyNonce)
+		s.nonces[string(prvID)] = n
+	}
+	s.lock.Unlock()
+
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	// A failed refetch leaves the counter to be fetched on the next use rather
+	// than keeping a count known to be off.
+	n.fetched = false
+	return s.fetch(context.Background(), prvID, n)
+}
+
+func (s *nonceSigner) GenerateKeyContext(ctx context.Context) ([]byte, error) {
+	return signerContext(s.SecureSigner).GenerateKeyContext(ctx)
+}
+
+func (s *nonceSigner) GetPublicKeyContext(ctx context.Context, prvID []byte) ([]byte, error) {
+	return signerContext(s.SecureSigner).GetPublicKeyContext(ctx, prvID)
+}
+
+func (s *nonceSigner) Sign(tx *types.Transaction, signer types.Signer, prvID []byte) (*types.Transaction, error) {
+	return s.SignContext(context.Background(), tx, signer, prvID)
+}
+
+func (s *nonceSigner) SignContext(ctx context.Context, tx *types.Transaction, signer types.Signer, prvID []byte) (_ *types.Transaction, err error) {
+	defer wrapError(&err, "sign transaction", prvID)
+
+	n, err := s.acquire(ctx, prvID)
+	if err != nil {
+		return nil, err
+	}
+	defer n.lock.Unlock()
+
+	tx, err = withNonce(tx, n.next)
+	if err != nil {
+		return nil, err
+	}
+	signed, err := signerContext(s.SecureSigner).SignContext(ctx, tx, signer, prvID)
+	if err != nil {
+		return nil, err
+	}
+	n.next++
+	return signed, nil
+}
+
+func (s *nonceSigner) SignForChain(chainID *big.Int, tx *types.Transaction, prvID []byte) (*types.Transaction, error) {
+	return signForChain(s, chainID, tx, prvID)
+}
+
+func (s *nonceSigner) SignTransactionJSON(tx *types.Transaction, signer types.Signer, prvID []byte) (json.RawMessage, error) {
+	return signTransactionJSON(s, tx, signer, prvID)
+}
+
+func (s *nonceSigner) SignAndEncode(tx *types.Transaction, signer types.Signer, prvID []byte) ([]byte, error) {
+	return signAndEncode(s, tx, signer, prvID)
+}
+
+func (s *nonceSigner) SignAndEncodeHex(tx *types.Transaction, signer types.Signer, prvID []byte) (string, error) {
+	return signAndEncodeHex(s, tx, signer, prvID)
+}
+
+func (s *nonceSigner) SignDynamicFeeTx(chainID *big.Int, nonce uint64, to common.Address, value *big.Int, gasLimit uint64, maxFeePerGas, maxPriorityFeePerGas *big.Int, data []byte, prvID []byte) (*types.Transaction, error) {
+	tx := newDynamicFeeTx(chainID, nonce, to, value, gasLimit, maxFeePerGas, maxPriorityFeePerGas, data)
+	return s.Sign(tx, types.LatestSignerForChainID(chainID), prvID)
+}
+
+func (s *nonceSigner) SignAccessListTx(chainID *big.Int, nonce uint64, to common.Address, value *big.Int, gasLimit uint64, gasPrice *big.Int, accessList types.AccessList, data []byte, prvID []byte) (*types.Transaction, error) {
+	tx := newAccessListTx(chainID, nonce, to, value, gasLimit, gasPrice, accessList, data)
+	return s.Sign(tx, types.NewEIP2930Signer(chainID), prvID)
+}
+
+func (s *nonceSigner) SignContractCall(chainID *big.Int, contract common.Address, contractABI abi.ABI, method string, args []interface{}, nonce uint64, gasLimit uint64, gasPrice *big.Int, prvID []byte) (_ *types.Transaction, err error) {
+	defer wrapError(&err, "sign transaction", prvID)
+
+	tx, err := newContractCallTx(chainID, contract, contractABI, method, args, nonce, gasLimit, gasPrice)
+	if err != nil {
+		return nil, err
+	}
+	return s.Sign(tx, types.LatestSignerForChainID(chainID), prvID)
+}
+
+func (s *nonceSigner) SimulateAndSign(ctx context.Context, tx *types.Transaction, signer types.Signer, client ethereum.ContractCaller, prvID []byte) (*types.Transaction, error) {
+	return simulateAndSign(ctx, s, tx, signer, client, prvID)
+}
+
+func (s *nonceSigner) SignBlobTx(chainID *big.Int, blobTx *types.BlobTx, prvID []byte) (_ *types.Transaction, err error) {
+	defer wrapError(&err, "sign transaction", prvID)
+
+	tx, err := newBlobTx(chainID, blobTx)
+	if err != nil {
+		return nil, err
+	}
+	return s.Sign(tx, types.LatestSignerForChainID(chainID), prvID)
+}
+
+func (s *nonceSigner) SignBatch(txs []*types.Transaction, signer types.Signer, prvID []byte) ([]*types.Transaction, error) {
+	return s.signBatch(txs, prvID, func(txs []*types.Transaction) ([]*types.Transaction, error) {
+		return s.SecureSigner.SignBatch(txs, signer, prvID)
+	})
+}
+
+func (s *nonceSigner) SignBatchParallel(txs []*types.Transaction, signer types.Signer, prvID []byte) ([]*types.Transaction, error) {
+	return s.signBatch(txs, prvID, func(txs []*types.Transaction) ([]*types.Transaction, error) {
+		return s.SecureSigner.SignBatchParallel(txs, signer, prvID)
+	})
+}
+
+// signBatch assigns consecutive nonces to txs and signs them with sign, which
+// returns the transactions signed before the first failure. The counter moves
+// past the signed transactions only.
+func (s *nonceSigner) signBatch(txs []*types.Transaction, prvID []byte, sign func([]*types.Transaction) ([]*types.Transaction, error)) (_ []*types.Transaction, err error) {
+	defer wrapError(&err, "sign transaction", prvID)
+
+	n, err := s.acquire(context.Background(), prvID)
+	if err != nil {
+		return nil, err
+	}
+	defer n.lock.Unlock()
+
+	nonced := make([]*types.Transaction, len(txs))
+	for i, tx := range txs {
+		if nonced[i], err = withNonce(tx, n.next+uint64(i)); err != nil {
+			return nil, fmt.Errorf("transaction %d: %w", i, err)
+		}
+	}
+	signed, err := sign(nonced)
+	n.next += uint64(len(signed))
+	return signed, err
+}
+
+// withNonce returns a copy of the unsigned transaction tx with the given nonce.
+func withNonce(tx *types.Transaction, nonce uint64) (*types.Transaction, error) {
+	switch tx.Type() {
+	case types.LegacyTxType:
+		return types.NewTx(&types.LegacyTx{
+			Nonce:    nonce,
+			GasPrice: tx.GasPrice(),
+			Gas:      tx.Gas(),
+			To:       tx.To(),
+			Value:    tx.Value(),
+			Data:     tx.Data(),
+		}), nil
+	case types.AccessListTxType:
+		return types.NewTx(&types.AccessListTx{
+			ChainID:    tx.ChainId(),
+			Nonce:      nonce,
+			GasPrice:   tx.GasPrice(),
+			Gas:        tx.Gas(),
+			To:         tx.To(),
+			Value:      tx.Value(),
+			Data:       tx.Data(),
+			AccessList: tx.AccessList(),
+		}), nil
+	case types.DynamicFeeTxType:
+		return types.NewTx(&types.DynamicFeeTx{
+			ChainID:    tx.ChainId(),
+			Nonce:      nonce,
+			GasTipCap:  tx.GasTipCap(),
+			GasFeeCap:  tx.GasFeeCap(),
+			Gas:        tx.Gas(),
+			To:         tx.To(),
+			Value:      tx.Value(),
+			Data:       tx.Data(),
+			AccessList: tx.AccessList(),
+		}), nil
+	case types.BlobTxType:
+		return types.NewTx(&types.BlobTx{
+			ChainID:    uint256.MustFromBig(tx.ChainId()),
+			Nonce:      nonce,
+			GasTipCap:  uint256.MustFromBig(tx.GasTipCap()),
+			GasFeeCap:  uint256.MustFromBig(tx.GasFeeCap()),
+			Gas:        tx.Gas(),
+			To:         *tx.To(),
+			Value:      uint256.MustFromBig(tx.Value()),
+			Data:       tx.Data(),
+			AccessList: tx.AccessList(),
+			BlobFeeCap: uint256.MustFromBig(tx.BlobGasFeeCap()),
+			BlobHashes: tx.BlobHashes(),
+			Sidecar:    tx.BlobTxSidecar(),
+		}), nil
+	case types.SetCodeTxType:
+		return types.NewTx(&types.SetCodeTx{
+			ChainID:    uint256.MustFromBig(tx.ChainId()),
+			Nonce:      nonce,
+			GasTipCap:  uint256.MustFromBig(tx.GasTipCap()),
+			GasFeeCap:  uint256.MustFromBig(tx.GasFeeCap()),
+			Gas:        tx.Gas(),
+			To:         *tx.To(),
+			Value:      uint256.MustFromBig(tx.Value()),
+			Data:       tx.Data(),
+			AccessList: tx.AccessList(),
+			AuthList:   tx.SetCodeAuthorizations(),
+		}), nil
+	default:
+		return nil, fmt.Errorf("%w: %d", types.ErrTxTypeNotSupported, tx.Type())
+	}
+}
@@ -0,0 +1,136 @@
+package keeper
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// fakeNoncer returns a fixed pending nonce, counting the calls.
+type fakeNoncer struct {
+	nonce atomic.Uint64
+	calls atomic.Int32
+	err   error
+}
+
+func (f *fakeNoncer) PendingNonceAt(ctx context.Context, account common.Address) (uint64, error) {
+	f.calls.Add(1)
+	return f.nonce.Load(), f.err
+}
+
+func TestNonceManagedSigner(t *testing.T) {
+	client := new(fakeNoncer)
+	client.nonce.Store(40)
+	s := NewNonceManagedSigner(NewSecureSigner(new(defaultPrivateKeyKeeper)), client)
+	prvID, err := s.GenerateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	signer := types.LatestSignerForChainID(big.NewInt(1))
+
+	const n = 64
+	var (
+		wg     sync.WaitGroup
+		signed = make([]*types.Transaction, n)
+		errs   = make([]error, n)
+	)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			signed[i], errs[i] = s.Sign(newTestTx(), signer, prvID)
+		}(i)
+	}
+	wg.Wait()
+	seen := make(map[uint64]bool)
+	for i, tx := range signed {
+		if errs[i] != nil {
+			t.Fatalf("failed to sign transaction %d: %v", i, errs[i])
+		}
+		if tx.Nonce() < 40 || tx.Nonce() >= 40+n || seen[tx.Nonce()] {
+			t.Fatalf("transaction %d: unexpected nonce %d", i, tx.Nonce())
+		}
+		seen[tx.Nonce()] = true
+	}
+	if calls := client.calls.Load(); calls != 1 {
+		t.Fatalf("pending nonce fetches: have %d, want 1", calls)
+	}
+
+	// A transaction failing to sign doesn't use up its nonce.
+	dynamicFee := newDynamicFeeTx(big.NewInt(1), 0, common.Address{1}, big.NewInt(1), 21000, big.NewInt(2), big.NewInt(1), nil)
+	if _, err := s.Sign(dynamicFee, types.HomesteadSigner{}, prvID); err == nil {
+		t.Fatal("signed dynamic fee tx with homestead signer")
+	}
+	tx, err := s.SignDynamicFeeTx(big.NewInt(1), 0, common.Address{1}, big.NewInt(1), 21000, big.NewInt(2), big.NewInt(1), nil, prvID)
+	if err != nil {
+		t.Fatalf("failed to sign dynamic fee tx: %v", err)
+	}
+	if tx.Nonce() != 40+n {
+		t.Fatalf("nonce after failure: have %d, want %d", tx.Nonce(), 40+n)
+	}
+	addr, _ := s.GetAddress(prvID)
+	if from, err := types.Sender(signer, tx); err != nil || from != addr {
+		t.Fatalf("sender mismatch: have (%x, %v), want %x", from, err, addr)
+	}
+
+	// Reset brings the counter back to the chain.
+	client.nonce.Store(7)
+	if err := s.Reset(prvID); err != nil {
+		t.Fatalf("failed to reset nonce: %v", err)
+	}
+	if tx, _ := s.Sign(newTestTx(), signer, prvID); tx.Nonce() != 7 {
+		t.Fatalf("nonce after reset: have %d, want 7", tx.Nonce())
+	}
+}
+
+func TestNonceManagedSignerBatch(t *testing.T) {
+	client := new(fakeNoncer)
+	client.nonce.Store(3)
+	s := NewNonceManagedSigner(NewSecureSigner(new(defaultPrivateKeyKeeper)), client)
+	prvID, _ := s.GenerateKey()
+	signer := types.LatestSignerForChainID(big.NewInt(1))
+
+	txs := []*types.Transaction{newTestTx(), newTestTx(), newTestTx(), newTestTx()}
+	signed, err := s.SignBatchParallel(txs, signer, prvID)
+	if err != nil {
+		t.Fatalf("failed to sign batch: %v", err)
+	}
+	for i, tx := range signed {
+		if tx.Nonce() != uint64(3+i) {
+			t.Fatalf("transaction %d: have nonce %d, want %d", i, tx.Nonce(), 3+i)
+		}
+	}
+	// Only the transactions preceding a failure use up their nonces.
+	txs[2] = newDynamicFeeTx(big.NewInt(1), 0, common.Address{1}, big.NewInt(1), 21000, big.NewInt(2), big.NewInt(1), nil)
+	signed, err = s.SignBatch(txs, types.HomesteadSigner{}, prvID)
+	if err == nil || len(signed) != 2 {
+		t.Fatalf("batch with invalid tx: have (%d signed, %v), want 2 and error", len(signed), err)
+	}
+	if tx, _ := s.Sign(newTestTx(), signer, prvID); tx.Nonce() != 9 {
+		t.Fatalf("nonce after failed batch: have %d, want 9", tx.Nonce())
+	}
+}
+
+func TestNonceManagedSignerFetchError(t *testing.T) {
+	client := &fakeNoncer{err: errors.New("node down")}
+	s := NewNonceManagedSigner(NewSecureSigner(new(defaultPrivateKeyKeeper)), client)
+	prvID, _ := s.GenerateKey()
+	if _, err := s.Sign(newTestTx(), types.HomesteadSigner{}, prvID); !errors.Is(err, client.err) {
+		t.Fatalf("sign without nonce: have %v, want %v", err, client.err)
+	}
+	if err := s.Reset(prvID); !errors.Is(err, client.err) {
+		t.Fatalf("reset: have %v, want %v", err, client.err)
+	}
+	// The nonce is fetched again once the node is back.
+	client.err = nil
+	client.nonce.Store(5)
+	if tx, err := s.Sign(newTestTx(), types.HomesteadSigner{}, prvID); err != nil || tx.Nonce() != 5 {
+		t.Fatalf("sign after recovery: have (%v, %v), want nonce 5", tx, err)
+	}
+}
@@ -0,0 +1,113 @@
+package keeper
+
+import (
+	"log/slog"
+	"math/big"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// SecureSignOption configures the SecureSigner of NewSecureSigner.
+type SecureSignOption interface {
+	apply(*secureSignConfig)
+}
+
+// HashingOption configures the SecureSign itself. It is the only kind of
+// option NewSecureSign takes, NewSecureSigner takes it as well.
+type HashingOption func(*SecureSign)
+
+func (o HashingOption) apply(c *secureSignConfig) {
+	o(&c.sec)
+}
+
+// signerOption is a SecureSignOption wrapping the SecureSign in a decorator.
+type signerOption func(*secureSignConfig)
+
+func (o signerOption) apply(c *secureSignConfig) {
+	o(c)
+}
+
+// secureSignConfig is the configuration collected from SecureSignOptions.
+type secureSignConfig struct {
+	sec SecureSign // configured by the HashingOptions, without keeper
+
+	// Decorators wrapping the SecureSign, nil or zero if not used.
+	chainIDs    []*big.Int
+	policy      *SigningPolicy
+	metrics     prometheus.Registerer
+	logger      *slog.Logger
+	concurrency int
+}
+
+func newSecureSignConfig(opts []SecureSignOption) *secureSignConfig {
+	cfg := new(secureSignConfig)
+	for _, opt := range opts {
+		opt.apply(cfg)
+	}
+	return cfg
+}
+
+// wrap wraps sec in the configured decorators. From the outermost layer in,
+// they are the metrics and the log, which see all calls including the refused
+// ones, the chain binding and the policy, and the concurrency bound, so that
+// refused transactions don't wait for a slot.
+func (c *secureSignConfig) wrap(sec SecureSigner) SecureSigner {
+	s := sec
+	if c.concurrency > 0 {
+		s = NewConcurrentSecureSigner(s, WithConcurrency(c.concurrency))
+	}
+	if c.policy != nil {
+		s = NewPoliciedSecureSigner(s, *c.policy)
+	}
+	if c.chainIDs != nil {
+		s = NewChainBoundSigner(s, c.chainIDs)
+	}
+	if c.logger != nil {
+		s = NewLoggingSecureSigner(s, c.logger)
+	}
+	if c.metrics != nil {
+		s = NewInstrumentedSecureSigner(s, c.metrics)
+	}
+	return s
+}
+
+// WithChainID binds the signer to the chain chainID, see NewChainBoundSigner.
+// Given more than once, the signer signs for each of the chains.
+func WithChainID(chainID *big.Int) SecureSignOption {
+	return signerOption(func(c *secureSignConfig) {
+		c.chainIDs = append(c.chainIDs, new(big.Int).Set(chainID))
+	})
+}
+
+// WithPolicy limits the transactions the signer signs to policy, see
+// NewPoliciedSecureSigner.
+func WithPolicy(policy SigningPolicy) SecureSignOption {
+	return signerOption(func(c *secureSignConfig) {
+		c.policy = &policy
+	})
+}
+
+// WithSignerMetrics records the calls to the signer in Prometheus metrics
+// registered with reg, see NewInstrumentedSecureSigner. NewSecureSigner panics
+// if the metrics can't be registered.
+func WithSignerMetrics(reg prometheus.Registerer) SecureSignOption {
+	return signerOption(func(c *secureSignConfig) {
+		c.metrics = reg
+	})
+}
+
+// WithLogger logs the calls to the signer to logger, see
+// NewLoggingSecureSigner.
+func WithLogger(logger *slog.Logger) SecureSignOption {
+	return signerOption(func(c *secureSignConfig) {
+		c.logger = logger
+	})
+}
+
+// WithMaxConcurrency bounds the signatures the signer makes at a time to n, see
+// NewConcurrentSecureSigner.
+func WithMaxConcurrency(n int) SecureSignOption {
+	return signerOption(func(c *secureSignConfig) {
+		c.concurrency = max(n, 1)
+	})
+}
@@ -0,0 +1,80 @@
+package keeper
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// callCount returns the keeper_signer_calls_total of operation op with result
+// in reg, summed over the keys.
+func callCount(t *testing.T, reg *prometheus.Registry, op, result string) float64 {
+	t.Helper()
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatalf("failed to gather metrics: %v", err)
+	}
+	var n float64
+	for _, family := range families {
+		if family.GetName() != "keeper_signer_calls_total" {
+			continue
+		}
+		for _, m := range family.GetMetric() {
+			labels := make(map[string]string)
+			for _, l := range m.GetLabel() {
+				labels[l.GetName()] = l.GetValue()
+			}
+			if labels["operation"] == op && labels["result"] == result {
+				n += m.GetCounter().GetValue()
+			}
+		}
+	}
+	return n
+}
+
+func TestNewSecureSignerOptions(t *testing.T) {
+	if _, ok := NewSecureSigner(defaultKeeper).(*SecureSign); !ok {
+		t.Fatal("signer without options is not a SecureSign")
+	}
+	if _, ok := NewSecureSigner(defaultKeeper, WithHashingStrategy(HashByKeeper)).(*SecureSign); !ok {
+		t.Fatal("signer with hashing strategy is not a SecureSign")
+	}
+	if s, ok := NewSecureSigner(defaultKeeper, WithMaxConcurrency(3)).(*ConcurrentSecureSigner); !ok || cap(s.slots) != 3 {
+		t.Fatal("signer with max concurrency is not a ConcurrentSecureSigner of 3 slots")
+	}
+
+	reg := prometheus.NewRegistry()
+	s := NewSecureSigner(defaultKeeper,
+		WithChainID(big.NewInt(1)),
+		WithPolicy(SigningPolicy{MaxValue: big.NewInt(1)}),
+		WithSignerMetrics(reg),
+		WithMaxConcurrency(2),
+	)
+	prvID, err := s.GenerateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	tx := newTestTx()
+	if _, err := s.Sign(tx, types.LatestSignerForChainID(big.NewInt(1)), prvID); err != nil {
+		t.Fatalf("failed to sign: %v", err)
+	}
+	var mismatch *ErrChainIDMismatch
+	if _, err := s.Sign(tx, types.LatestSignerForChainID(big.NewInt(5)), prvID); !errors.As(err, &mismatch) {
+		t.Fatalf("sign for other chain: have %v, want chain ID mismatch", err)
+	}
+	expensive := types.NewTx(&types.LegacyTx{Nonce: 2, Value: big.NewInt(2), Gas: 21000, GasPrice: big.NewInt(1)})
+	var violation *PolicyViolation
+	if _, err := s.Sign(expensive, types.LatestSignerForChainID(big.NewInt(1)), prvID); !errors.As(err, &violation) {
+		t.Fatalf("sign beyond policy: have %v, want policy violation", err)
+	}
+	// The metrics are the outermost layer, they count the refused calls too.
+	if have, want := callCount(t, reg, "sign", "failure"), 2.0; have != want {
+		t.Fatalf("failed signatures: have %v, want %v", have, want)
+	}
+	if have, want := callCount(t, reg, "sign", "success"), 1.0; have != want {
+		t.Fatalf("successful signatures: have %v, want %v", have, want)
+	}
+}
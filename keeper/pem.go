@@ -0,0 +1,97 @@
+package keeper
+
+import (
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// PEM block types of the encoded keys.
+const (
+	pemPrivateKey   = "PRIVATE KEY"    // PKCS#8
+	pemECPrivateKey = "EC PRIVATE KEY" // RFC 5915, as written by openssl ecparam
+	pemPublicKey    = "PUBLIC KEY"     // SubjectPublicKeyInfo
+)
+
+// PrivateKeyToPEM returns the key prvID of the default keeper, the raw key, as
+// a PEM encoded PKCS#8 "PRIVATE KEY", e.g. for keeping development keys in text
+// files. The encoding holds the key in the clear, the caller has to clear it
+// after use.
+func PrivateKeyToPEM(prvID []byte) (_ []byte, err error) {
+	defer wrapError(&err, "export key", prvID)
+
+	der, err := ExportPKCS8Key(prvID)
+	if err != nil {
+		return nil, err
+	}
+	defer zeroBytes(der)
+	return pem.EncodeToMemory(&pem.Block{Type: pemPrivateKey, Bytes: der}), nil
+}
+
+// PEMToPrivateKey decodes a PEM encoded secp256k1 private key and returns its
+// prvID in the default keeper, the raw key itself. Both PKCS#8 "PRIVATE KEY"
+// and "EC PRIVATE KEY" blocks are accepted, keys on other curves fail with
+// ErrInvalidKey.
+func PEMToPrivateKey(data []byte) (_ []byte, err error) {
+	defer wrapError(&err, "import key", nil)
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%w: no PEM block", ErrInvalidKey)
+	}
+	defer zeroBytes(block.Bytes)
+
+	switch block.Type {
+	case pemPrivateKey:
+		return ImportPKCS8Key(block.Bytes)
+	case pemECPrivateKey:
+		key, err := parseECPrivateKey(block.Bytes, true)
+		if err != nil {
+			return nil, err
+		}
+		defer ZeroKey(key)
+		return defaultKeeper.ImportPrivateKey(crypto.FromECDSA(key))
+	}
+	return nil, fmt.Errorf("%w: unexpected PEM block %q", ErrInvalidKey, block.Type)
+}
+
+// PublicKeyToPEM returns the secp256k1 public key, in either the compressed or
+// the uncompressed form, as a PEM encoded "PUBLIC KEY" SubjectPublicKeyInfo,
+// the format written by openssl ec -pubout.
+func PublicKeyToPEM(pubBytes []byte) ([]byte, error) {
+	key, err := ParsePublicKey(pubBytes)
+	if err != nil {
+		return nil, err
+	}
+	params, err := asn1.Marshal(oidNamedCurveS256)
+	if err != nil {
+		return nil, err
+	}
+	pub := crypto.FromECDSAPub(key)
+	der, err := asn1.Marshal(subjectPublicKeyInfo{
+		Algorithm: pkix.AlgorithmIdentifier{Algorithm: oidPublicKeyECDSA, Parameters: asn1.RawValue{FullBytes: params}},
+		PublicKey: asn1.BitString{Bytes: pub, BitLength: 8 * len(pub)},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: pemPublicKey, Bytes: der}), nil
+}
+
+// PEMToPublicKey decodes a PEM encoded "PUBLIC KEY" and returns the secp256k1
+// public key in the 65 byte uncompressed form. Keys on other curves are
+// refused.
+func PEMToPublicKey(data []byte) ([]byte, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("invalid public key: no PEM block")
+	}
+	if block.Type != pemPublicKey {
+		return nil, fmt.Errorf("invalid public key: unexpected PEM block %q", block.Type)
+	}
+	return parseSubjectPublicKeyInfo(block.Bytes)
+}
@@ -0,0 +1,88 @@
+package keeper
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+)
+
+// permitTypes are the EIP-712 types of an EIP-2612 permit.
+var permitTypes = apitypes.Types{
+	"EIP712Domain": {
+		{Name: "name", Type: "string"},
+		{Name: "version", Type: "string"},
+		{Name: "chainId", Type: "uint256"},
+		{Name: "verifyingContract", Type: "address"},
+	},
+	"Permit": {
+		{Name: "owner", Type: "address"},
+		{Name: "spender", Type: "address"},
+		{Name: "value", Type: "uint256"},
+		{Name: "nonce", Type: "uint256"},
+		{Name: "deadline", Type: "uint256"},
+	},
+}
+
+// permitTypedData returns the EIP-712 typed data of an EIP-2612 permit for the
+// token with the given EIP-712 domain name and version.
+func permitTypedData(chainID *big.Int, tokenAddr common.Address, tokenName, tokenVersion string, ownerAddr, spenderAddr common.Address, value, nonce *big.Int, deadline int64) apitypes.TypedData {
+	return apitypes.TypedData{
+		Types:       permitTypes,
+		PrimaryType: "Permit",
+		Domain: apitypes.TypedDataDomain{
+			Name:              tokenName,
+			Version:           tokenVersion,
+			ChainId:           (*math.HexOrDecimal256)(chainID),
+			VerifyingContract: tokenAddr.Hex(),
+		},
+		Message: apitypes.TypedDataMessage{
+			"owner":    ownerAddr.Hex(),
+			"spender":  spenderAddr.Hex(),
+			"value":    value,
+			"nonce":    nonce,
+			"deadline": big.NewInt(deadline),
+		},
+	}
+}
+
+// signPermit signs an EIP-2612 permit with the SignTypedData of signer, so
+// permits go through the same path as other typed data. The permit has to be
+// signed by its owner, the token contract would refuse it otherwise.
+func signPermit(signer SecureSigner, chainID *big.Int, tokenAddr common.Address, tokenName, tokenVersion string, ownerAddr, spenderAddr common.Address, value, nonce *big.Int, deadline int64, prvID []byte) (v uint8, r, s [32]byte, err error) {
+	defer wrapError(&err, "sign permit", prvID)
+
+	addr, err := signer.GetAddress(prvID)
+	if err != nil {
+		return 0, r, s, err
+	}
+	if addr != ownerAddr {
+		return 0, r, s, fmt.Errorf("permit owner %v is not the signing key %v", ownerAddr, addr)
+	}
+	if deadline < 0 {
+		return 0, r, s, fmt.Errorf("negative permit deadline %d", deadline)
+	}
+	typedData := permitTypedData(chainID, tokenAddr, tokenName, tokenVersion, ownerAddr, spenderAddr, value, nonce, deadline)
+	sig, err := signer.SignTypedData(typedData, prvID)
+	if err != nil {
+		return 0, r, s, err
+	}
+	copy(r[:], sig[:32])
+	copy(s[:], sig[32:64])
+	// Token contracts recover the permit signer with ecrecover, which takes
+	// the Ethereum V of 27 or 28.
+	return sig[64] + 27, r, s, nil
+}
+
+// SignPermit signs an EIP-2612 permit allowing spenderAddr to spend value of
+// the tokens of ownerAddr until the deadline, a UNIX timestamp. The permit is
+// EIP-712 typed data in the domain of the token, given by its address and the
+// EIP-712 name and version of the contract, as well as chainID. The nonce is
+// the current permit nonce of the owner with the token. The key prvID has to
+// be the key of ownerAddr. The returned v is 27 or 28, as expected by the
+// permit function of the token.
+func (sec *SecureSign) SignPermit(chainID *big.Int, tokenAddr common.Address, tokenName, tokenVersion string, ownerAddr, spenderAddr common.Address, value, nonce *big.Int, deadline int64, prvID []byte) (v uint8, r, s [32]byte, err error) {
+	return signPermit(sec, chainID, tokenAddr, tokenName, tokenVersion, ownerAddr, spenderAddr, value, nonce, deadline, prvID)
+}
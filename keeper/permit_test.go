@@ -0,0 +1,84 @@
+package keeper
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// permitDigest computes the EIP-2612 permit digest the way the reference
+// ERC20Permit contract does, from the ABI encoded type hashes and fields.
+func permitDigest(chainID *big.Int, token common.Address, name, version string, owner, spender common.Address, value, nonce, deadline *big.Int) []byte {
+	word := func(b []byte) []byte { return common.LeftPadBytes(b, 32) }
+	domain := crypto.Keccak256(
+		crypto.Keccak256([]byte("EIP712Domain(string name,string version,uint256 chainId,address verifyingContract)")),
+		crypto.Keccak256([]byte(name)),
+		crypto.Keccak256([]byte(version)),
+		math.U256Bytes(new(big.Int).Set(chainID)),
+		word(token.Bytes()),
+	)
+	permit := crypto.Keccak256(
+		crypto.Keccak256([]byte("Permit(address owner,address spender,uint256 value,uint256 nonce,uint256 deadline)")),
+		word(owner.Bytes()),
+		word(spender.Bytes()),
+		math.U256Bytes(new(big.Int).Set(value)),
+		math.U256Bytes(new(big.Int).Set(nonce)),
+		math.U256Bytes(new(big.Int).Set(deadline)),
+	)
+	return crypto.Keccak256([]byte("\x19\x01"), domain, permit)
+}
+
+func TestSignPermit(t *testing.T) {
+	s := NewSecureSigner(new(defaultPrivateKeyKeeper))
+	prvID, err := s.GenerateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	owner, _ := s.GetAddress(prvID)
+	var (
+		chainID  = big.NewInt(1)
+		token    = common.HexToAddress("0xa0b86991c6218b36c1d19d4a2e9eb0ce3606eb48")
+		spender  = common.Address{0xbb}
+		value    = new(big.Int).Lsh(big.NewInt(1), 255)
+		nonce    = big.NewInt(3)
+		deadline = int64(1_700_000_000)
+	)
+	v, r, sv, err := s.SignPermit(chainID, token, "USD Coin", "2", owner, spender, value, nonce, deadline, prvID)
+	if err != nil {
+		t.Fatalf("failed to sign permit: %v", err)
+	}
+	if v != 27 && v != 28 {
+		t.Fatalf("permit v: have %d, want 27 or 28", v)
+	}
+	digest := permitDigest(chainID, token, "USD Coin", "2", owner, spender, value, nonce, big.NewInt(deadline))
+	key, _ := crypto.ToECDSA(prvID)
+	want, _ := crypto.Sign(digest, key)
+	if !bytes.Equal(r[:], want[:32]) || !bytes.Equal(sv[:], want[32:64]) || v != want[64]+27 {
+		t.Fatalf("permit signature mismatch: have %x%x%02x, want %x", r, sv, v, want)
+	}
+	// The token recovers the owner from the permit with ecrecover.
+	sig := append(append(r[:], sv[:]...), v-27)
+	pub, err := crypto.SigToPub(digest, sig)
+	if err != nil || crypto.PubkeyToAddress(*pub) != owner {
+		t.Fatalf("recovered signer mismatch: have (%v, %v), want %v", pub, err, owner)
+	}
+}
+
+func TestSignPermitInvalid(t *testing.T) {
+	s := NewSecureSigner(new(defaultPrivateKeyKeeper))
+	prvID, err := s.GenerateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	owner, _ := s.GetAddress(prvID)
+	if _, _, _, err := s.SignPermit(big.NewInt(1), common.Address{1}, "Token", "1", common.Address{0xaa}, common.Address{0xbb}, big.NewInt(1), big.NewInt(0), 0, prvID); err == nil {
+		t.Fatal("signed permit for another owner")
+	}
+	if _, _, _, err := s.SignPermit(big.NewInt(1), common.Address{1}, "Token", "1", owner, common.Address{0xbb}, big.NewInt(1), big.NewInt(0), -1, prvID); err == nil {
+		t.Fatal("signed permit with negative deadline")
+	}
+}
@@ -0,0 +1,457 @@
+//go:build cgo
+
+package keeper
+
+import (
+	"context"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/google/uuid"
+	"github.com/miekg/pkcs11"
+)
+
+// oidNamedCurveS256DER is the DER encoded secp256k1 curve OID, used as the
+// CKA_EC_PARAMS of generated keys.
+var oidNamedCurveS256DER, _ = asn1.Marshal(oidNamedCurveS256)
+
+// pkcs11API is the subset of the PKCS#11 library context used by the keeper.
+type pkcs11API interface {
+	Initialize() error
+	GetInfo() (pkcs11.Info, error)
+	GetSlotList(tokenPresent bool) ([]uint, error)
+	GetTokenInfo(slotID uint) (pkcs11.TokenInfo, error)
+	OpenSession(slotID uint, flags uint) (pkcs11.SessionHandle, error)
+	CloseSession(sh pkcs11.SessionHandle) error
+	Login(sh pkcs11.SessionHandle, userType uint, pin string) error
+	GenerateKeyPair(sh pkcs11.SessionHandle, m []*pkcs11.Mechanism, public, private []*pkcs11.Attribute) (pkcs11.ObjectHandle, pkcs11.ObjectHandle, error)
+	CreateObject(sh pkcs11.SessionHandle, temp []*pkcs11.Attribute) (pkcs11.ObjectHandle, error)
+	FindObjectsInit(sh pkcs11.SessionHandle, temp []*pkcs11.Attribute) error
+	FindObjects(sh pkcs11.SessionHandle, max int) ([]pkcs11.ObjectHandle, bool, error)
+	FindObjectsFinal(sh pkcs11.SessionHandle) error
+	GetAttributeValue(sh pkcs11.SessionHandle, o pkcs11.ObjectHandle, a []*pkcs11.Attribute) ([]*pkcs11.Attribute, error)
+	SignInit(sh pkcs11.SessionHandle, m []*pkcs11.Mechanism, o pkcs11.ObjectHandle) error
+	Sign(sh pkcs11.SessionHandle, message []byte) ([]byte, error)
+	DestroyObject(sh pkcs11.SessionHandle, oh pkcs11.ObjectHandle) error
+}
+
+// pkcs11Keeper is a PrivateKeyKeeper storing keys in an HSM accessed through
+// PKCS#11. The prvID is the CKA_ID shared by the private and public key object
+// of a key pair.
+type pkcs11Keeper struct {
+	ctx  pkcs11API
+	slot uint
+	pin  string
+
+	lock    sync.Mutex // PKCS#11 sessions must not be used concurrently
+	session pkcs11.SessionHandle
+
+	pubkeys sync.Map // CKA_ID -> uncompressed public key
+}
+
+// NewPKCS11Keeper loads the PKCS#11 library at libPath and returns a
+// PrivateKeyKeeper using the token labelled tokenLabel, logged in with pin.
+func NewPKCS11Keeper(libPath, tokenLabel, pin string) (PrivateKeyKeeper, error) {
+	ctx := pkcs11.New(libPath)
+	if ctx == nil {
+		return nil, fmt.Errorf("failed to load PKCS#11 library %s", libPath)
+	}
+	return newPKCS11Keeper(ctx, tokenLabel, pin)
+}
+
+func newPKCS11Keeper(ctx pkcs11API, tokenLabel, pin string) (*pkcs11Keeper, error) {
+	if err := ctx.Initialize(); err != nil && !isPKCS11Error(err, pkcs11.CKR_CRYPTOKI_ALREADY_INITIALIZED) {
+		return nil, err
+	}
+	slots, err := ctx.GetSlotList(true)
+	if err != nil {
+		return nil, err
+	}
+	for _, slot := range slots {
+		info, err := ctx.GetTokenInfo(slot)
+		if err != nil {
+			return nil, err
+		}
+		if info.Label != tokenLabel {
+			continue
+		}
+		k := &pkcs11Keeper{ctx: ctx, slot: slot, pin: pin}
+		if err := k.openSession(); err != nil {
+			return nil, pkcs11Error(err)
+		}
+		return k, nil
+	}
+	return nil, fmt.Errorf("PKCS#11 token %q not found", tokenLabel)
+}
+
+// isPKCS11Error reports whether err is the PKCS#11 return value rv.
+func isPKCS11Error(err error, rv uint) bool {
+	var p11err pkcs11.Error
+	return errors.As(err, &p11err) && uint(p11err) == rv
+}
+
+// pkcs11Error marks PKCS#11 failures with the matching error of the keeper
+// package, keeping the PKCS#11 return value accessible.
+func pkcs11Error(err error) error {
+	var p11err pkcs11.Error
+	if !errors.As(err, &p11err) {
+		return err
+	}
+	switch uint(p11err) {
+	case pkcs11.CKR_PIN_INCORRECT, pkcs11.CKR_PIN_LOCKED, pkcs11.CKR_PIN_EXPIRED, pkcs11.CKR_USER_NOT_LOGGED_IN:
+		return fmt.Errorf("%w: %w", ErrPermissionDenied, err)
+	case pkcs11.CKR_DEVICE_ERROR, pkcs11.CKR_DEVICE_MEMORY, pkcs11.CKR_DEVICE_REMOVED, pkcs11.CKR_TOKEN_NOT_PRESENT:
+		return fmt.Errorf("%w: %w", ErrBackendUnavailable, err)
+	}
+	return err
+}
+
+// openSession opens a new read-write session and logs the user in.
+func (k *pkcs11Keeper) openSession() error {
+	session, err := k.ctx.OpenSession(k.slot, pkcs11.CKF_SERIAL_SESSION|pkcs11.CKF_RW_SESSION)
+	if err != nil {
+		return err
+	}
+	// The login state is shared by all sessions of the application, so it
+	// survives the session being replaced.
+	if err := k.ctx.Login(session, pkcs11.CKU_USER, k.pin); err != nil && !isPKCS11Error(err, pkcs11.CKR_USER_ALREADY_LOGGED_IN) {
+		k.ctx.CloseSession(session)
+		return err
+	}
+	k.session = session
+	return nil
+}
+
+// withSession runs fn on the session of the keeper. If the session turns out
+// to be invalid, e.g. because the HSM was restarted, a new one is opened and
+// fn is retried once.
+func (k *pkcs11Keeper) withSession(fn func(pkcs11.SessionHandle) error) error {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+
+	err := fn(k.session)
+	if !isPKCS11Error(err, pkcs11.CKR_SESSION_HANDLE_INVALID) && !isPKCS11Error(err, pkcs11.CKR_SESSION_CLOSED) {
+		return pkcs11Error(err)
+	}
+	if err := k.openSession(); err != nil {
+		return pkcs11Error(err)
+	}
+	return pkcs11Error(fn(k.session))
+}
+
+// findObjects returns the handles of all objects matching the template.
+func (k *pkcs11Keeper) findObjects(session pkcs11.SessionHandle, template []*pkcs11.Attribute) ([]pkcs11.ObjectHandle, error) {
+	if err := k.ctx.FindObjectsInit(session, template); err != nil {
+		return nil, err
+	}
+	var handles []pkcs11.ObjectHandle
+	for {
+		batch, _, err := k.ctx.FindObjects(session, 16)
+		if err != nil {
+			k.ctx.FindObjectsFinal(session)
+			return nil, err
+		}
+		if len(batch) == 0 {
+			break
+		}
+		handles = append(handles, batch...)
+	}
+	return handles, k.ctx.FindObjectsFinal(session)
+}
+
+// findKey returns the handle of the object of class belonging to prvID.
+func (k *pkcs11Keeper) findKey(session pkcs11.SessionHandle, class uint, prvID []byte) (pkcs11.ObjectHandle, error) {
+	handles, err := k.findObjects(session, []*pkcs11.Attribute{
+		pkcs11.NewAttribute(pkcs11.CKA_CLASS, class),
+		pkcs11.NewAttribute(pkcs11.CKA_ID, prvID),
+	})
+	if err != nil {
+		return 0, err
+	}
+	if len(handles) == 0 {
+		return 0, fmt.Errorf("%w: PKCS#11 key %q", ErrKeyNotFound, prvID)
+	}
+	return handles[0], nil
+}
+
+func (k *pkcs11Keeper) GeneratePrivateKey() (_ []byte, err error) {
+	defer wrapError(&err, "generate key", nil)
+
+	prvID := []byte(uuid.New().String())
+	public := []*pkcs11.Attribute{
+		pkcs11.NewAttribute(pkcs11.CKA_CLASS, pkcs11.CKO_PUBLIC_KEY),
+		pkcs11.NewAttribute(pkcs11.CKA_KEY_TYPE, pkcs11.CKK_EC),
+		pkcs11.NewAttribute(pkcs11.CKA_TOKEN, true),
+		pkcs11.NewAttribute(pkcs11.CKA_VERIFY, true),
+		pkcs11.NewAttribute(pkcs11.CKA_EC_PARAMS, oidNamedCurveS256DER),
+		pkcs11.NewAttribute(pkcs11.CKA_ID, prvID),
+		pkcs11.NewAttribute(pkcs11.CKA_LABEL, string(prvID)),
+	}
+	private := []*pkcs11.Attribute{
+		pkcs11.NewAttribute(pkcs11.CKA_CLASS, pkcs11.CKO_PRIVATE_KEY),
+		pkcs11.NewAttribute(pkcs11.CKA_KEY_TYPE, pkcs11.CKK_EC),
+		pkcs11.NewAttribute(pkcs11.CKA_TOKEN, true),
+		pkcs11.NewAttribute(pkcs11.CKA_PRIVATE, true),
+		pkcs11.NewAttribute(pkcs11.CKA_SENSITIVE, true),
+		pkcs11.NewAttribute(pkcs11.CKA_EXTRACTABLE, false),
+		pkcs11.NewAttribute(pkcs11.CKA_SIGN, true),
+		pkcs11.NewAttribute(pkcs11.CKA_ID, prvID),
+		pkcs11.NewAttribute(pkcs11.CKA_LABEL, string(prvID)),
+	}
+	mech := []*pkcs11.Mechanism{pkcs11.NewMechanism(pkcs11.CKM_EC_KEY_PAIR_GEN, nil)}
+	err = k.withSession(func(session pkcs11.SessionHandle) error {
+		_, _, err := k.ctx.GenerateKeyPair(session, mech, public, private)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return prvID, nil
+}
+
+// ExportEncryptedKey returns ErrExportNotSupported, the private key objects of
+// the keeper are not extractable from the token.
+func (k *pkcs11Keeper) ExportEncryptedKey(prvID []byte, passphrase string) (_ []byte, err error) {
+	defer wrapError(&err, "export key", prvID)
+	return nil, ErrExportNotSupported
+}
+
+func (k *pkcs11Keeper) ImportEncryptedKey(data []byte, passphrase string) ([]byte, error) {
+	return importEncryptedKey(k, data, passphrase)
+}
+
+// ImportPrivateKey creates the key pair objects of rawKey on the token. The
+// private key object is created with the same protection as generated keys, it
+// can't be extracted from the token again.
+func (k *pkcs11Keeper) ImportPrivateKey(rawKey []byte) (_ []byte, err error) {
+	defer wrapError(&err, "import key", nil)
+
+	key, err := parseRawKey(rawKey)
+	if err != nil {
+		return nil, err
+	}
+	defer ZeroKey(key)
+	pub := crypto.FromECDSAPub(&key.PublicKey)
+	point, err := asn1.Marshal(pub)
+	if err != nil {
+		return nil, err
+	}
+	secret := crypto.FromECDSA(key)
+	defer zeroBytes(secret)
+
+	prvID := []byte(uuid.New().String())
+	private := []*pkcs11.Attribute{
+		pkcs11.NewAttribute(pkcs11.CKA_CLASS, pkcs11.CKO_PRIVATE_KEY),
+		pkcs11.NewAttribute(pkcs11.CKA_KEY_TYPE, pkcs11.CKK_EC),
+		pkcs11.NewAttribute(pkcs11.CKA_TOKEN, true),
+		pkcs11.NewAttribute(pkcs11.CKA_PRIVATE, true),
+		pkcs11.NewAttribute(pkcs11.CKA_SENSITIVE, true),
+		pkcs11.NewAttribute(pkcs11.CKA_EXTRACTABLE, false),
+		pkcs11.NewAttribute(pkcs11.CKA_SIGN, true),
+		pkcs11.NewAttribute(pkcs11.CKA_EC_PARAMS, oidNamedCurveS256DER),
+		pkcs11.NewAttribute(pkcs11.CKA_VALUE, secret),
+		pkcs11.NewAttribute(pkcs11.CKA_ID, prvID),
+		pkcs11.NewAttribute(pkcs11.CKA_LABEL, string(prvID)),
+	}
+	public := []*pkcs11.Attribute{
+		pkcs11.NewAttribute(pkcs11.CKA_CLASS, pkcs11.CKO_PUBLIC_KEY),
+		pkcs11.NewAttribute(pkcs11.CKA_KEY_TYPE, pkcs11.CKK_EC),
+		pkcs11.NewAttribute(pkcs11.CKA_TOKEN, true),
+		pkcs11.NewAttribute(pkcs11.CKA_VERIFY, true),
+		pkcs11.NewAttribute(pkcs11.CKA_EC_PARAMS, oidNamedCurveS256DER),
+		pkcs11.NewAttribute(pkcs11.CKA_EC_POINT, point),
+		pkcs11.NewAttribute(pkcs11.CKA_ID, prvID),
+		pkcs11.NewAttribute(pkcs11.CKA_LABEL, string(prvID)),
+	}
+	err = k.withSession(func(session pkcs11.SessionHandle) error {
+		handle, err := k.ctx.CreateObject(session, private)
+		if err != nil {
+			return err
+		}
+		// Don't leave a private key behind that GetPublicKey can't find.
+		if _, err := k.ctx.CreateObject(session, public); err != nil {
+			k.ctx.DestroyObject(session, handle)
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	k.pubkeys.Store(string(prvID), pub)
+	return prvID, nil
+}
+
+func (k *pkcs11Keeper) GetPublicKey(prvID []byte) (_ []byte, err error) {
+	defer wrapError(&err, "get public key", prvID)
+
+	if pub, ok := k.pubkeys.Load(string(prvID)); ok {
+		return pub.([]byte), nil
+	}
+	var point []byte
+	err = k.withSession(func(session pkcs11.SessionHandle) error {
+		handle, err := k.findKey(session, pkcs11.CKO_PUBLIC_KEY, prvID)
+		if err != nil {
+			return err
+		}
+		attrs, err := k.ctx.GetAttributeValue(session, handle, []*pkcs11.Attribute{
+			pkcs11.NewAttribute(pkcs11.CKA_EC_POINT, nil),
+		})
+		if err != nil {
+			return err
+		}
+		if len(attrs) != 1 {
+			return errors.New("missing PKCS#11 public key point")
+		}
+		point = attrs[0].Value
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	pub, err := parseECPoint(point)
+	if err != nil {
+		return nil, err
+	}
+	k.pubkeys.Store(string(prvID), pub)
+	return pub, nil
+}
+
+// parseECPoint decodes a CKA_EC_POINT into an uncompressed public key. The
+// standard mandates a DER OCTET STRING, but some tokens return the raw point.
+func parseECPoint(point []byte) ([]byte, error) {
+	if len(point) == 65 && point[0] == 0x04 {
+		return point, nil
+	}
+	var raw []byte
+	if rest, err := asn1.Unmarshal(point, &raw); err != nil || len(rest) > 0 {
+		return nil, errors.New("invalid PKCS#11 public key point")
+	}
+	if len(raw) != 65 || raw[0] != 0x04 {
+		return nil, errors.New("invalid PKCS#11 public key point")
+	}
+	return raw, nil
+}
+
+// Sign signs the 32 byte digest data with CKM_ECDSA. The digest is handed to
+// the HSM as is, hashing it again would produce signatures that don't recover
+// to the key from the transaction hash. The raw r || s signature of the HSM is
+// normalised to low-S and completed with the recovery id.
+func (k *pkcs11Keeper) Sign(data []byte, prvID []byte) (_ []byte, err error) {
+	defer wrapError(&err, "sign", prvID)
+
+	if len(data) != 32 {
+		return nil, fmt.Errorf("hash is required to be exactly 32 bytes (%d)", len(data))
+	}
+	pub, err := k.GetPublicKey(prvID)
+	if err != nil {
+		return nil, err
+	}
+	var sig []byte
+	mech := []*pkcs11.Mechanism{pkcs11.NewMechanism(pkcs11.CKM_ECDSA, nil)}
+	err = k.withSession(func(session pkcs11.SessionHandle) error {
+		handle, err := k.findKey(session, pkcs11.CKO_PRIVATE_KEY, prvID)
+		if err != nil {
+			return err
+		}
+		if err := k.ctx.SignInit(session, mech, handle); err != nil {
+			return err
+		}
+		sig, err = k.ctx.Sign(session, data)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(sig) != 64 {
+		return nil, fmt.Errorf("%w: PKCS#11 signature length %d", ErrInvalidSignature, len(sig))
+	}
+	r, s := new(big.Int).SetBytes(sig[:32]), new(big.Int).SetBytes(sig[32:])
+	return recoverableSignature(data, r, s, pub)
+}
+
+// DeletePrivateKey destroys both objects of the key pair prvID.
+func (k *pkcs11Keeper) DeletePrivateKey(prvID []byte) (err error) {
+	defer wrapError(&err, "delete key", prvID)
+
+	err = k.withSession(func(session pkcs11.SessionHandle) error {
+		handle, err := k.findKey(session, pkcs11.CKO_PRIVATE_KEY, prvID)
+		if err != nil {
+			return err
+		}
+		if err := k.ctx.DestroyObject(session, handle); err != nil {
+			return err
+		}
+		// A missing public key object doesn't matter, the key material is
+		// gone already.
+		handle, err = k.findKey(session, pkcs11.CKO_PUBLIC_KEY, prvID)
+		if errors.Is(err, ErrKeyNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		return k.ctx.DestroyObject(session, handle)
+	})
+	if err != nil {
+		return err
+	}
+	k.pubkeys.Delete(string(prvID))
+	return nil
+}
+
+// ListPrivateKeys returns the CKA_ID of every EC private key on the token,
+// including the ones not created by the keeper.
+func (k *pkcs11Keeper) ListPrivateKeys() (_ [][]byte, err error) {
+	defer wrapError(&err, "list keys", nil)
+
+	var prvIDs [][]byte
+	err = k.withSession(func(session pkcs11.SessionHandle) error {
+		prvIDs = nil
+		handles, err := k.findObjects(session, []*pkcs11.Attribute{
+			pkcs11.NewAttribute(pkcs11.CKA_CLASS, pkcs11.CKO_PRIVATE_KEY),
+			pkcs11.NewAttribute(pkcs11.CKA_KEY_TYPE, pkcs11.CKK_EC),
+		})
+		if err != nil {
+			return err
+		}
+		for _, handle := range handles {
+			attrs, err := k.ctx.GetAttributeValue(session, handle, []*pkcs11.Attribute{
+				pkcs11.NewAttribute(pkcs11.CKA_ID, nil),
+			})
+			if err != nil {
+				return err
+			}
+			if len(attrs) == 1 && len(attrs[0].Value) > 0 {
+				prvIDs = append(prvIDs, attrs[0].Value)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return prvIDs, nil
+}
+
+// HealthCheck queries the library with C_GetInfo and the token of the keeper
+// with C_GetTokenInfo, which fails with CKR_TOKEN_NOT_PRESENT once the token is
+// removed. PKCS#11 calls can't be cancelled, ctx is ignored.
+func (k *pkcs11Keeper) HealthCheck(ctx context.Context) (err error) {
+	defer wrapError(&err, "health check", nil)
+
+	k.lock.Lock()
+	defer k.lock.Unlock()
+
+	if _, err := k.ctx.GetInfo(); err != nil {
+		return pkcs11Error(err)
+	}
+	if _, err := k.ctx.GetTokenInfo(k.slot); err != nil {
+		return pkcs11Error(err)
+	}
+	return nil
+}
@@ -0,0 +1,10 @@
+//go:build !cgo
+
+package keeper
+
+import "errors"
+
+// NewPKCS11Keeper is not available, loading PKCS#11 libraries requires cgo.
+func NewPKCS11Keeper(libPath, tokenLabel, pin string) (PrivateKeyKeeper, error) {
+	return nil, errors.New("PKCS#11 keeper requires cgo")
+}
@@ -0,0 +1,409 @@
+//go:build cgo
+
+package keeper
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"encoding/asn1"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/miekg/pkcs11"
+)
+
+// fakePKCS11 is an in-memory pkcs11API implementation emulating a single
+// token with secp256k1 support.
+type fakePKCS11 struct {
+	label    string
+	pin      string
+	sessions map[pkcs11.SessionHandle]*fakeSession
+	objects  map[pkcs11.ObjectHandle]*fakeObject
+	next     uint
+	loggedIn bool
+	removed  bool // whether the token was removed from its slot
+}
+
+type fakeSession struct {
+	found   []pkcs11.ObjectHandle // pending results of FindObjects
+	signKey *ecdsa.PrivateKey     // key of the initialised sign operation
+}
+
+type fakeObject struct {
+	class uint
+	id    []byte
+	key   *ecdsa.PrivateKey
+}
+
+func newFakePKCS11() *fakePKCS11 {
+	return &fakePKCS11{
+		label:    "eth",
+		pin:      "1234",
+		sessions: make(map[pkcs11.SessionHandle]*fakeSession),
+		objects:  make(map[pkcs11.ObjectHandle]*fakeObject),
+	}
+}
+
+func (f *fakePKCS11) session(sh pkcs11.SessionHandle) (*fakeSession, error) {
+	s, ok := f.sessions[sh]
+	if !ok {
+		return nil, pkcs11.Error(pkcs11.CKR_SESSION_HANDLE_INVALID)
+	}
+	return s, nil
+}
+
+func (f *fakePKCS11) Initialize() error { return nil }
+
+func (f *fakePKCS11) GetSlotList(tokenPresent bool) ([]uint, error) {
+	return []uint{0, 1}, nil
+}
+
+func (f *fakePKCS11) GetInfo() (pkcs11.Info, error) {
+	return pkcs11.Info{ManufacturerID: "fake"}, nil
+}
+
+func (f *fakePKCS11) GetTokenInfo(slotID uint) (pkcs11.TokenInfo, error) {
+	if f.removed && slotID == 1 {
+		return pkcs11.TokenInfo{}, pkcs11.Error(pkcs11.CKR_TOKEN_NOT_PRESENT)
+	}
+	if slotID == 0 {
+		return pkcs11.TokenInfo{Label: "other"}, nil
+	}
+	return pkcs11.TokenInfo{Label: f.label}, nil
+}
+
+func (f *fakePKCS11) OpenSession(slotID uint, flags uint) (pkcs11.SessionHandle, error) {
+	if slotID != 1 || flags&pkcs11.CKF_RW_SESSION == 0 {
+		return 0, pkcs11.Error(pkcs11.CKR_ARGUMENTS_BAD)
+	}
+	f.next++
+	f.sessions[pkcs11.SessionHandle(f.next)] = new(fakeSession)
+	return pkcs11.SessionHandle(f.next), nil
+}
+
+func (f *fakePKCS11) CloseSession(sh pkcs11.SessionHandle) error {
+	delete(f.sessions, sh)
+	return nil
+}
+
+func (f *fakePKCS11) Login(sh pkcs11.SessionHandle, userType uint, pin string) error {
+	if _, err := f.session(sh); err != nil {
+		return err
+	}
+	if pin != f.pin {
+		return pkcs11.Error(pkcs11.CKR_PIN_INCORRECT)
+	}
+	if f.loggedIn {
+		return pkcs11.Error(pkcs11.CKR_USER_ALREADY_LOGGED_IN)
+	}
+	f.loggedIn = true
+	return nil
+}
+
+func (f *fakePKCS11) GenerateKeyPair(sh pkcs11.SessionHandle, m []*pkcs11.Mechanism, public, private []*pkcs11.Attribute) (pkcs11.ObjectHandle, pkcs11.ObjectHandle, error) {
+	if _, err := f.session(sh); err != nil {
+		return 0, 0, err
+	}
+	if len(m) != 1 || m[0].Mechanism != pkcs11.CKM_EC_KEY_PAIR_GEN {
+		return 0, 0, pkcs11.Error(pkcs11.CKR_MECHANISM_INVALID)
+	}
+	if params := templateValue(public, pkcs11.CKA_EC_PARAMS); !bytes.Equal(params, oidNamedCurveS256DER) {
+		return 0, 0, pkcs11.Error(pkcs11.CKR_DOMAIN_PARAMS_INVALID)
+	}
+	key, _ := crypto.GenerateKey()
+	f.next++
+	pubHandle := pkcs11.ObjectHandle(f.next)
+	f.objects[pubHandle] = &fakeObject{class: pkcs11.CKO_PUBLIC_KEY, id: templateValue(public, pkcs11.CKA_ID), key: key}
+	f.next++
+	prvHandle := pkcs11.ObjectHandle(f.next)
+	f.objects[prvHandle] = &fakeObject{class: pkcs11.CKO_PRIVATE_KEY, id: templateValue(private, pkcs11.CKA_ID), key: key}
+	return pubHandle, prvHandle, nil
+}
+
+func (f *fakePKCS11) CreateObject(sh pkcs11.SessionHandle, temp []*pkcs11.Attribute) (pkcs11.ObjectHandle, error) {
+	if _, err := f.session(sh); err != nil {
+		return 0, err
+	}
+	if params := templateValue(temp, pkcs11.CKA_EC_PARAMS); !bytes.Equal(params, oidNamedCurveS256DER) {
+		return 0, pkcs11.Error(pkcs11.CKR_DOMAIN_PARAMS_INVALID)
+	}
+	obj := &fakeObject{id: templateValue(temp, pkcs11.CKA_ID)}
+	switch class := templateValue(temp, pkcs11.CKA_CLASS); {
+	case bytes.Equal(class, pkcs11.NewAttribute(pkcs11.CKA_CLASS, pkcs11.CKO_PRIVATE_KEY).Value):
+		key, err := crypto.ToECDSA(templateValue(temp, pkcs11.CKA_VALUE))
+		if err != nil {
+			return 0, pkcs11.Error(pkcs11.CKR_ATTRIBUTE_VALUE_INVALID)
+		}
+		obj.class, obj.key = pkcs11.CKO_PRIVATE_KEY, key
+	case bytes.Equal(class, pkcs11.NewAttribute(pkcs11.CKA_CLASS, pkcs11.CKO_PUBLIC_KEY).Value):
+		var point []byte
+		if _, err := asn1.Unmarshal(templateValue(temp, pkcs11.CKA_EC_POINT), &point); err != nil {
+			return 0, pkcs11.Error(pkcs11.CKR_ATTRIBUTE_VALUE_INVALID)
+		}
+		pub, err := crypto.UnmarshalPubkey(point)
+		if err != nil {
+			return 0, pkcs11.Error(pkcs11.CKR_ATTRIBUTE_VALUE_INVALID)
+		}
+		obj.class, obj.key = pkcs11.CKO_PUBLIC_KEY, &ecdsa.PrivateKey{PublicKey: *pub}
+	default:
+		return 0, pkcs11.Error(pkcs11.CKR_TEMPLATE_INCONSISTENT)
+	}
+	f.next++
+	f.objects[pkcs11.ObjectHandle(f.next)] = obj
+	return pkcs11.ObjectHandle(f.next), nil
+}
+
+// templateValue returns the value of the attribute typ in the template.
+func templateValue(template []*pkcs11.Attribute, typ uint) []byte {
+	for _, a := range template {
+		if a.Type == typ {
+			return a.Value
+		}
+	}
+	return nil
+}
+
+func (f *fakePKCS11) FindObjectsInit(sh pkcs11.SessionHandle, temp []*pkcs11.Attribute) error {
+	s, err := f.session(sh)
+	if err != nil {
+		return err
+	}
+	class := templateValue(temp, pkcs11.CKA_CLASS)
+	id := templateValue(temp, pkcs11.CKA_ID)
+	for handle, obj := range f.objects {
+		if class != nil && !bytes.Equal(class, pkcs11.NewAttribute(pkcs11.CKA_CLASS, obj.class).Value) {
+			continue
+		}
+		if id != nil && !bytes.Equal(id, obj.id) {
+			continue
+		}
+		s.found = append(s.found, handle)
+	}
+	return nil
+}
+
+func (f *fakePKCS11) FindObjects(sh pkcs11.SessionHandle, max int) ([]pkcs11.ObjectHandle, bool, error) {
+	s, err := f.session(sh)
+	if err != nil {
+		return nil, false, err
+	}
+	n := min(max, len(s.found))
+	found := s.found[:n]
+	s.found = s.found[n:]
+	return found, false, nil
+}
+
+func (f *fakePKCS11) FindObjectsFinal(sh pkcs11.SessionHandle) error {
+	s, err := f.session(sh)
+	if err != nil {
+		return err
+	}
+	s.found = nil
+	return nil
+}
+
+func (f *fakePKCS11) GetAttributeValue(sh pkcs11.SessionHandle, o pkcs11.ObjectHandle, a []*pkcs11.Attribute) ([]*pkcs11.Attribute, error) {
+	if _, err := f.session(sh); err != nil {
+		return nil, err
+	}
+	obj, ok := f.objects[o]
+	if !ok {
+		return nil, pkcs11.Error(pkcs11.CKR_OBJECT_HANDLE_INVALID)
+	}
+	var attrs []*pkcs11.Attribute
+	for _, attr := range a {
+		switch {
+		case attr.Type == pkcs11.CKA_ID:
+			attrs = append(attrs, pkcs11.NewAttribute(pkcs11.CKA_ID, obj.id))
+		case attr.Type == pkcs11.CKA_EC_POINT && obj.class == pkcs11.CKO_PUBLIC_KEY:
+			point, _ := asn1.Marshal(crypto.FromECDSAPub(&obj.key.PublicKey))
+			attrs = append(attrs, pkcs11.NewAttribute(pkcs11.CKA_EC_POINT, point))
+		default:
+			return nil, pkcs11.Error(pkcs11.CKR_ATTRIBUTE_TYPE_INVALID)
+		}
+	}
+	return attrs, nil
+}
+
+func (f *fakePKCS11) SignInit(sh pkcs11.SessionHandle, m []*pkcs11.Mechanism, o pkcs11.ObjectHandle) error {
+	s, err := f.session(sh)
+	if err != nil {
+		return err
+	}
+	if len(m) != 1 || m[0].Mechanism != pkcs11.CKM_ECDSA {
+		return pkcs11.Error(pkcs11.CKR_MECHANISM_INVALID)
+	}
+	obj, ok := f.objects[o]
+	if !ok || obj.class != pkcs11.CKO_PRIVATE_KEY {
+		return pkcs11.Error(pkcs11.CKR_KEY_HANDLE_INVALID)
+	}
+	s.signKey = obj.key
+	return nil
+}
+
+// Sign returns the raw r || s signature, always with a high s value to check
+// that the keeper normalises it.
+func (f *fakePKCS11) Sign(sh pkcs11.SessionHandle, message []byte) ([]byte, error) {
+	s, err := f.session(sh)
+	if err != nil {
+		return nil, err
+	}
+	if s.signKey == nil {
+		return nil, pkcs11.Error(pkcs11.CKR_OPERATION_NOT_INITIALIZED)
+	}
+	sig, err := crypto.Sign(message, s.signKey)
+	s.signKey = nil
+	if err != nil {
+		return nil, pkcs11.Error(pkcs11.CKR_DATA_LEN_RANGE)
+	}
+	r, sv := new(big.Int).SetBytes(sig[:32]), new(big.Int).SetBytes(sig[32:64])
+	sv.Sub(secp256k1N, sv)
+	return append(r.FillBytes(make([]byte, 32)), sv.FillBytes(make([]byte, 32))...), nil
+}
+
+func (f *fakePKCS11) DestroyObject(sh pkcs11.SessionHandle, oh pkcs11.ObjectHandle) error {
+	if _, err := f.session(sh); err != nil {
+		return err
+	}
+	if _, ok := f.objects[oh]; !ok {
+		return pkcs11.Error(pkcs11.CKR_OBJECT_HANDLE_INVALID)
+	}
+	delete(f.objects, oh)
+	return nil
+}
+
+func TestPKCS11Keeper(t *testing.T) {
+	fake := newFakePKCS11()
+	k, err := newPKCS11Keeper(fake, "eth", "1234")
+	if err != nil {
+		t.Fatalf("failed to create keeper: %v", err)
+	}
+	prvID, err := k.GeneratePrivateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	pub, err := k.GetPublicKey(prvID)
+	if err != nil {
+		t.Fatalf("failed to get public key: %v", err)
+	}
+	hash := crypto.Keccak256([]byte("pkcs11"))
+	sig, err := k.Sign(hash, prvID)
+	if err != nil {
+		t.Fatalf("failed to sign: %v", err)
+	}
+	recovered, err := crypto.Ecrecover(hash, sig)
+	if err != nil {
+		t.Fatalf("failed to recover: %v", err)
+	}
+	if !bytes.Equal(recovered, pub) {
+		t.Fatalf("recovered key mismatch: have %x, want %x", recovered, pub)
+	}
+	if !crypto.ValidateSignatureValues(sig[64], new(big.Int).SetBytes(sig[:32]), new(big.Int).SetBytes(sig[32:64]), true) {
+		t.Fatalf("signature %x is not in canonical form", sig)
+	}
+	ids, err := k.ListPrivateKeys()
+	if err != nil {
+		t.Fatalf("failed to list keys: %v", err)
+	}
+	if len(ids) != 1 || !bytes.Equal(ids[0], prvID) {
+		t.Fatalf("key list mismatch: have %q, want [%s]", ids, prvID)
+	}
+	if err := k.DeletePrivateKey(prvID); err != nil {
+		t.Fatalf("failed to delete key: %v", err)
+	}
+	if len(fake.objects) != 0 {
+		t.Fatalf("objects left after deletion: %d", len(fake.objects))
+	}
+	if _, err := k.Sign(hash, prvID); !errors.Is(err, ErrKeyNotFound) {
+		t.Fatalf("deleted key: have %v, want %v", err, ErrKeyNotFound)
+	}
+}
+
+func TestPKCS11KeeperImport(t *testing.T) {
+	fake := newFakePKCS11()
+	k, err := newPKCS11Keeper(fake, "eth", "1234")
+	if err != nil {
+		t.Fatalf("failed to create keeper: %v", err)
+	}
+	prvID := checkImport(t, k)
+
+	// The public key object is found by keepers not caching it.
+	other, err := newPKCS11Keeper(fake, "eth", "1234")
+	if err != nil {
+		t.Fatalf("failed to create keeper: %v", err)
+	}
+	want, _ := k.GetPublicKey(prvID)
+	if pub, err := other.GetPublicKey(prvID); err != nil || !bytes.Equal(pub, want) {
+		t.Fatalf("public key from token: have (%x, %v), want %x", pub, err, want)
+	}
+	if err := k.DeletePrivateKey(prvID); err != nil {
+		t.Fatalf("failed to delete key: %v", err)
+	}
+	if len(fake.objects) != 0 {
+		t.Fatalf("objects left after deletion: %d", len(fake.objects))
+	}
+}
+
+func TestPKCS11KeeperSessionReconnect(t *testing.T) {
+	fake := newFakePKCS11()
+	k, err := newPKCS11Keeper(fake, "eth", "1234")
+	if err != nil {
+		t.Fatalf("failed to create keeper: %v", err)
+	}
+	prvID, err := k.GeneratePrivateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	// Drop all sessions, like a restarted HSM would.
+	for sh := range fake.sessions {
+		delete(fake.sessions, sh)
+	}
+	if _, err := k.Sign(crypto.Keccak256(nil), prvID); err != nil {
+		t.Fatalf("failed to sign after session loss: %v", err)
+	}
+	if len(fake.sessions) != 1 {
+		t.Fatalf("session count mismatch: have %d, want 1", len(fake.sessions))
+	}
+}
+
+func TestPKCS11KeeperHealthCheck(t *testing.T) {
+	fake := newFakePKCS11()
+	k, err := newPKCS11Keeper(fake, "eth", "1234")
+	if err != nil {
+		t.Fatalf("failed to create keeper: %v", err)
+	}
+	if err := k.HealthCheck(context.Background()); err != nil {
+		t.Fatalf("present token: have %v, want healthy", err)
+	}
+	fake.removed = true
+	if err := k.HealthCheck(context.Background()); !errors.Is(err, ErrBackendUnavailable) {
+		t.Fatalf("removed token: have %v, want %v", err, ErrBackendUnavailable)
+	}
+}
+
+func TestPKCS11KeeperErrors(t *testing.T) {
+	if _, err := newPKCS11Keeper(newFakePKCS11(), "missing", "1234"); err == nil {
+		t.Error("opened missing token")
+	}
+	if _, err := newPKCS11Keeper(newFakePKCS11(), "eth", "wrong"); !isPKCS11Error(err, pkcs11.CKR_PIN_INCORRECT) {
+		t.Errorf("wrong pin: have %v, want %v", err, pkcs11.Error(pkcs11.CKR_PIN_INCORRECT))
+	}
+	point := make([]byte, 65)
+	point[0] = 0x04
+	for _, tt := range []struct {
+		point []byte
+		ok    bool
+	}{
+		{point, true},
+		{append([]byte{0x04, 65}, point...), true},
+		{point[1:], false},
+		{[]byte{0x04, 0x01, 0x00}, false},
+	} {
+		if _, err := parseECPoint(tt.point); (err == nil) != tt.ok {
+			t.Errorf("point %x: have error %v, want ok %v", tt.point, err, tt.ok)
+		}
+	}
+}
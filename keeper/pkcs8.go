@@ -0,0 +1,100 @@
+package keeper
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/x509"
+	"encoding/asn1"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// ImportPKCS8Key parses a DER encoded PKCS#8 secp256k1 private key, as used by
+// TLS, code signing and PKI tooling, and returns the prvID of the key in the
+// default keeper, which is the raw key itself. Keys on other curves, such as
+// P-256, and of other algorithms fail with ErrInvalidKey. Use ImportPrivateKey
+// of another keeper with the returned prvID to store the key there.
+//
+// x509.ParsePKCS8PrivateKey doesn't know the secp256k1 curve and fails on its
+// keys, they are decoded here and the key is checked with the secp256k1
+// curve of the crypto package. Other keys are left to x509 to report what they
+// are.
+func ImportPKCS8Key(der []byte) (_ []byte, err error) {
+	defer wrapError(&err, "import key", nil)
+
+	key, err := parsePKCS8PrivateKey(der)
+	if err != nil {
+		return nil, err
+	}
+	defer ZeroKey(key)
+	return defaultKeeper.ImportPrivateKey(crypto.FromECDSA(key))
+}
+
+// ExportPKCS8Key returns the key prvID of the default keeper, the raw key, as
+// DER encoded PKCS#8 with the secp256k1 curve OID 1.3.132.0.10. The caller has
+// to clear the returned encoding after use.
+//
+// x509.MarshalPKCS8PrivateKey refuses keys on curves it doesn't know, the key
+// is encoded here following RFC 5208 and RFC 5915 instead.
+func ExportPKCS8Key(prvID []byte) (_ []byte, err error) {
+	defer wrapError(&err, "export key", prvID)
+
+	key, err := parseRawKey(prvID)
+	if err != nil {
+		return nil, err
+	}
+	defer ZeroKey(key)
+	return marshalPKCS8PrivateKey(key)
+}
+
+// parsePKCS8PrivateKey decodes a PKCS#8 secp256k1 private key. The caller has
+// to clear the returned key after use.
+func parsePKCS8PrivateKey(der []byte) (*ecdsa.PrivateKey, error) {
+	var info pkcs8PrivateKey
+	if rest, err := asn1.Unmarshal(der, &info); err != nil || len(rest) > 0 {
+		return nil, fmt.Errorf("%w: malformed PKCS#8 encoding", ErrInvalidKey)
+	}
+	var curve asn1.ObjectIdentifier
+	if info.Algorithm.Algorithm.Equal(oidPublicKeyECDSA) {
+		asn1.Unmarshal(info.Algorithm.Parameters.FullBytes, &curve)
+	}
+	if !curve.Equal(oidNamedCurveS256) {
+		other, err := x509.ParsePKCS8PrivateKey(der)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidKey, err)
+		}
+		if ecKey, ok := other.(*ecdsa.PrivateKey); ok {
+			return nil, fmt.Errorf("%w: curve %s is not secp256k1", ErrInvalidKey, ecKey.Curve.Params().Name)
+		}
+		return nil, fmt.Errorf("%w: %T is not a secp256k1 key", ErrInvalidKey, other)
+	}
+	return parseECPrivateKey(info.PrivateKey, false)
+}
+
+// parseECPrivateKey decodes an RFC 5915 secp256k1 private key. The curve may be
+// left out if given by an enclosing structure, unless required. The caller has
+// to clear the returned key after use.
+func parseECPrivateKey(der []byte, requireCurve bool) (*ecdsa.PrivateKey, error) {
+	var ecKey ecPrivateKey
+	if rest, err := asn1.Unmarshal(der, &ecKey); err != nil || len(rest) > 0 {
+		return nil, fmt.Errorf("%w: malformed EC private key", ErrInvalidKey)
+	}
+	defer zeroBytes(ecKey.PrivateKey)
+
+	if len(ecKey.NamedCurveOID) == 0 && requireCurve {
+		return nil, fmt.Errorf("%w: missing curve", ErrInvalidKey)
+	}
+	if len(ecKey.NamedCurveOID) > 0 && !ecKey.NamedCurveOID.Equal(oidNamedCurveS256) {
+		return nil, fmt.Errorf("%w: curve %v is not secp256k1", ErrInvalidKey, ecKey.NamedCurveOID)
+	}
+	key, err := parseRawKey(ecKey.PrivateKey)
+	if err != nil {
+		return nil, err
+	}
+	if len(ecKey.PublicKey.Bytes) > 0 && !bytes.Equal(ecKey.PublicKey.Bytes, crypto.FromECDSAPub(&key.PublicKey)) {
+		ZeroKey(key)
+		return nil, fmt.Errorf("%w: public key doesn't match private key", ErrInvalidKey)
+	}
+	return key, nil
+}
@@ -0,0 +1,77 @@
+package keeper
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"errors"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// opensslPKCS8Key is a secp256k1 key generated by openssl genpkey and converted
+// with openssl pkcs8 -topk8 -nocrypt -outform DER.
+var (
+	opensslPKCS8Key = hexutil.MustDecode("0x308184020100301006072a8648ce3d020106052b8104000a046d306b0201010420ea76d73c282deaec6bf0a2db8adecfa01d3cc25c79e219111e070c7c8f281078a14403420004b92f5c2b38938801a20285e93c1b3d0c8d8e002e7d7de0aee35421a7784b20064bb69077f87118028652ddfea2deff1508146eb6e5a91bba078cbcae16eb8486")
+	opensslRawKey   = hexutil.MustDecode("0xea76d73c282deaec6bf0a2db8adecfa01d3cc25c79e219111e070c7c8f281078")
+)
+
+func TestImportPKCS8Key(t *testing.T) {
+	prvID, err := ImportPKCS8Key(opensslPKCS8Key)
+	if err != nil {
+		t.Fatalf("failed to import openssl key: %v", err)
+	}
+	if !bytes.Equal(prvID, opensslRawKey) {
+		t.Fatalf("imported key: have %x, want %x", prvID, opensslRawKey)
+	}
+	der, err := ExportPKCS8Key(prvID)
+	if err != nil {
+		t.Fatalf("failed to export key: %v", err)
+	}
+	if !bytes.Equal(der, opensslPKCS8Key) {
+		t.Fatalf("exported key: have %x, want %x", der, opensslPKCS8Key)
+	}
+
+	// Round trip of a generated key.
+	prvID, _ = defaultKeeper.GeneratePrivateKey()
+	der, err = ExportPKCS8Key(prvID)
+	if err != nil {
+		t.Fatalf("failed to export generated key: %v", err)
+	}
+	if imported, err := ImportPKCS8Key(der); err != nil || !bytes.Equal(imported, prvID) {
+		t.Fatalf("round trip: have (%x, %v), want %x", imported, err, prvID)
+	}
+	if _, err := ExportPKCS8Key(make([]byte, 31)); !errors.Is(err, ErrInvalidKey) {
+		t.Fatalf("export of short key: have %v, want %v", err, ErrInvalidKey)
+	}
+}
+
+func TestImportPKCS8KeyInvalid(t *testing.T) {
+	p256, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	p256DER, _ := x509.MarshalPKCS8PrivateKey(p256)
+	_, ed, _ := ed25519.GenerateKey(rand.Reader)
+	edDER, _ := x509.MarshalPKCS8PrivateKey(ed)
+
+	// A public key not matching the private key.
+	mismatched := bytes.Clone(opensslPKCS8Key)
+	mismatched[len(mismatched)-1] ^= 0x01
+
+	tests := map[string][]byte{
+		"p256":       p256DER,
+		"ed25519":    edDER,
+		"mismatched": mismatched,
+		"truncated":  opensslPKCS8Key[:len(opensslPKCS8Key)-1],
+		"trailing":   append(bytes.Clone(opensslPKCS8Key), 0),
+		"empty":      nil,
+		"raw":        opensslRawKey,
+	}
+	for name, der := range tests {
+		if _, err := ImportPKCS8Key(der); !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("%s: have %v, want %v", name, err, ErrInvalidKey)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package keeper
+
+import (
+	"sync"
+
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// keySealer encrypts keys with a key held by the key storage hardware of the
+// platform, which never hands it out.
+type keySealer interface {
+	seal(secret []byte) ([]byte, error)
+	unseal(sealed []byte) ([]byte, error)
+}
+
+// sealedKeeper is a PrivateKeyKeeper protecting keys with the key storage
+// hardware of the platform, as returned by NewPlatformKeeper on Windows and
+// macOS.
+//
+// Like TPMs, the platform key stores don't implement the secp256k1 curve, so
+// keys are generated in memory and sealed: encrypted with a key of the
+// hardware, which only it is able to decrypt again. The sealed key is unsealed
+// for the duration of a single signing operation only. The prvID is the sealed
+// key, it is useless without the hardware it was sealed by.
+type sealedKeeper struct {
+	sealer keySealer
+
+	pubkeys sync.Map // prvID -> uncompressed public key
+}
+
+func (k *sealedKeeper) GeneratePrivateKey() (_ []byte, err error) {
+	defer wrapError(&err, "generate key", nil)
+
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		return nil, err
+	}
+	defer ZeroKey(key)
+	secret := crypto.FromECDSA(key)
+	defer zeroBytes(secret)
+
+	return k.sealer.seal(secret)
+}
+
+func (k *sealedKeeper) ImportPrivateKey(rawKey []byte) (_ []byte, err error) {
+	defer wrapError(&err, "import key", nil)
+
+	key, err := parseRawKey(rawKey)
+	if err != nil {
+		return nil, err
+	}
+	ZeroKey(key)
+	return k.sealer.seal(rawKey)
+}
+
+func (k *sealedKeeper) GetPublicKey(prvID []byte) (_ []byte, err error) {
+	defer wrapError(&err, "get public key", prvID)
+
+	if pub, ok := k.pubkeys.Load(string(prvID)); ok {
+		return pub.([]byte), nil
+	}
+	secret, err := k.sealer.unseal(prvID)
+	if err != nil {
+		return nil, err
+	}
+	defer zeroBytes(secret)
+
+	key, err := crypto.ToECDSA(secret)
+	if err != nil {
+		return nil, err
+	}
+	defer ZeroKey(key)
+	pub := crypto.FromECDSAPub(&key.PublicKey)
+	k.pubkeys.Store(string(prvID), pub)
+	return pub, nil
+}
+
+func (k *sealedKeeper) Sign(data []byte, prvID []byte) (_ []byte, err error) {
+	defer wrapError(&err, "sign", prvID)
+
+	secret, err := k.sealer.unseal(prvID)
+	if err != nil {
+		return nil, err
+	}
+	defer zeroBytes(secret)
+
+	key, err := crypto.ToECDSA(secret)
+	if err != nil {
+		return nil, err
+	}
+	defer ZeroKey(key)
+	return crypto.Sign(data, key)
+}
+
+// ExportEncryptedKey unseals the key prvID and encrypts it with passphrase.
+func (k *sealedKeeper) ExportEncryptedKey(prvID []byte, passphrase string) (_ []byte, err error) {
+	defer wrapError(&err, "export key", prvID)
+
+	secret, err := k.sealer.unseal(prvID)
+	if err != nil {
+		return nil, err
+	}
+	defer zeroBytes(secret)
+	return encryptKey(secret, passphrase)
+}
+
+func (k *sealedKeeper) ImportEncryptedKey(data []byte, passphrase string) ([]byte, error) {
+	return importEncryptedKey(k, data, passphrase)
+}
+
+// DeletePrivateKey returns ErrNotSupported. The platform doesn't store the
+// sealed keys, destroying the prvID destroys the key.
+func (k *sealedKeeper) DeletePrivateKey(prvID []byte) (err error) {
+	defer wrapError(&err, "delete key", prvID)
+	return ErrNotSupported
+}
+
+// ListPrivateKeys returns ErrNotSupported, the platform doesn't store the
+// sealed keys.
+func (k *sealedKeeper) ListPrivateKeys() (_ [][]byte, err error) {
+	defer wrapError(&err, "list keys", nil)
+	return nil, ErrNotSupported
+}
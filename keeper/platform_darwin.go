@@ -0,0 +1,177 @@
+//go:build darwin && cgo
+
+package keeper
+
+/*
+#cgo LDFLAGS: -framework Security -framework CoreFoundation
+
+#include <stdlib.h>
+#include <string.h>
+#include <Security/Security.h>
+#include <CoreFoundation/CoreFoundation.h>
+
+// se_error_string copies the description of err into a C string and releases
+// err.
+static char *se_error_string(CFErrorRef err) {
+	if (err == NULL) {
+		return strdup("unknown error");
+	}
+	CFStringRef desc = CFErrorCopyDescription(err);
+	CFRelease(err);
+	if (desc == NULL) {
+		return strdup("unknown error");
+	}
+	CFIndex size = CFStringGetMaximumSizeForEncoding(CFStringGetLength(desc), kCFStringEncodingUTF8) + 1;
+	char *out = malloc(size);
+	if (!CFStringGetCString(desc, out, size, kCFStringEncodingUTF8)) {
+		out[0] = 0;
+	}
+	CFRelease(desc);
+	return out;
+}
+
+// se_open_key finds the Secure Enclave key tagged tag, creating it if it
+// doesn't exist yet.
+static SecKeyRef se_open_key(const char *tag, char **errmsg) {
+	CFDataRef tagData = CFDataCreate(NULL, (const UInt8 *)tag, strlen(tag));
+
+	CFMutableDictionaryRef query = CFDictionaryCreateMutable(NULL, 0, &kCFTypeDictionaryKeyCallBacks, &kCFTypeDictionaryValueCallBacks);
+	CFDictionarySetValue(query, kSecClass, kSecClassKey);
+	CFDictionarySetValue(query, kSecAttrApplicationTag, tagData);
+	CFDictionarySetValue(query, kSecAttrKeyType, kSecAttrKeyTypeECSECPrimeRandom);
+	CFDictionarySetValue(query, kSecReturnRef, kCFBooleanTrue);
+
+	SecKeyRef key = NULL;
+	OSStatus status = SecItemCopyMatching(query, (CFTypeRef *)&key);
+	CFRelease(query);
+	if (status == errSecSuccess) {
+		CFRelease(tagData);
+		return key;
+	}
+
+	CFErrorRef err = NULL;
+	SecAccessControlRef access = SecAccessControlCreateWithFlags(NULL, kSecAttrAccessibleWhenUnlockedThisDeviceOnly, kSecAccessControlPrivateKeyUsage, &err);
+	if (access == NULL) {
+		CFRelease(tagData);
+		*errmsg = se_error_string(err);
+		return NULL;
+	}
+	CFMutableDictionaryRef private = CFDictionaryCreateMutable(NULL, 0, &kCFTypeDictionaryKeyCallBacks, &kCFTypeDictionaryValueCallBacks);
+	CFDictionarySetValue(private, kSecAttrIsPermanent, kCFBooleanTrue);
+	CFDictionarySetValue(private, kSecAttrApplicationTag, tagData);
+	CFDictionarySetValue(private, kSecAttrAccessControl, access);
+
+	int bits = 256;
+	CFNumberRef size = CFNumberCreate(NULL, kCFNumberIntType, &bits);
+	CFMutableDictionaryRef attrs = CFDictionaryCreateMutable(NULL, 0, &kCFTypeDictionaryKeyCallBacks, &kCFTypeDictionaryValueCallBacks);
+	CFDictionarySetValue(attrs, kSecAttrKeyType, kSecAttrKeyTypeECSECPrimeRandom);
+	CFDictionarySetValue(attrs, kSecAttrKeySizeInBits, size);
+	CFDictionarySetValue(attrs, kSecAttrTokenID, kSecAttrTokenIDSecureEnclave);
+	CFDictionarySetValue(attrs, kSecPrivateKeyAttrs, private);
+
+	key = SecKeyCreateRandomKey(attrs, &err);
+	CFRelease(attrs);
+	CFRelease(size);
+	CFRelease(private);
+	CFRelease(access);
+	CFRelease(tagData);
+	if (key == NULL) {
+		*errmsg = se_error_string(err);
+	}
+	return key;
+}
+
+// se_crypt encrypts in with the public key of key, or decrypts it with key.
+static CFDataRef se_crypt(SecKeyRef key, int decrypt, const UInt8 *in, size_t inlen, char **errmsg) {
+	SecKeyAlgorithm alg = kSecKeyAlgorithmECIESEncryptionCofactorVariableIVX963SHA256AESGCM;
+	CFDataRef input = CFDataCreate(NULL, in, inlen);
+	CFErrorRef err = NULL;
+	CFDataRef out = NULL;
+	if (decrypt) {
+		out = SecKeyCreateDecryptedData(key, alg, input, &err);
+	} else {
+		SecKeyRef pub = SecKeyCopyPublicKey(key);
+		if (pub != NULL) {
+			out = SecKeyCreateEncryptedData(pub, alg, input, &err);
+			CFRelease(pub);
+		}
+	}
+	CFRelease(input);
+	if (out == NULL) {
+		*errmsg = se_error_string(err);
+	}
+	return out;
+}
+*/
+import "C"
+
+import (
+	"errors"
+	"fmt"
+	"unsafe"
+)
+
+// seKeyTag is the application tag of the Secure Enclave key sealing the keys.
+const seKeyTag = "org.ethereum.go-ethereum.keeper"
+
+// seSealer is a keySealer encrypting keys with ECIES under a P-256 key of the
+// Secure Enclave.
+type seSealer struct {
+	key C.SecKeyRef
+}
+
+// NewPlatformKeeper returns a PrivateKeyKeeper sealing keys with the Secure
+// Enclave: a P-256 key of the keychain tagged "org.ethereum.go-ethereum.keeper",
+// created on first use. Keeping a permanent Secure Enclave key requires the
+// binary to be signed with a keychain access group entitlement.
+func NewPlatformKeeper() (PrivateKeyKeeper, error) {
+	tag := C.CString(seKeyTag)
+	defer C.free(unsafe.Pointer(tag))
+
+	var errmsg *C.char
+	key := C.se_open_key(tag, &errmsg)
+	if key == nil {
+		return nil, fmt.Errorf("%w: failed to open Secure Enclave key: %s", ErrBackendUnavailable, seError(errmsg))
+	}
+	return &sealedKeeper{sealer: &seSealer{key: key}}, nil
+}
+
+// seError turns the message of a failed call into a string and frees it.
+func seError(errmsg *C.char) string {
+	if errmsg == nil {
+		return "unknown error"
+	}
+	defer C.free(unsafe.Pointer(errmsg))
+	return C.GoString(errmsg)
+}
+
+func (s *seSealer) seal(secret []byte) ([]byte, error) {
+	return s.crypt(false, secret)
+}
+
+func (s *seSealer) unseal(sealed []byte) ([]byte, error) {
+	secret, err := s.crypt(true, sealed)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unseal key: %w", err)
+	}
+	return secret, nil
+}
+
+// crypt encrypts in with the public Secure Enclave key, or decrypts it with
+// the private one.
+func (s *seSealer) crypt(decrypt bool, in []byte) ([]byte, error) {
+	if len(in) == 0 {
+		return nil, errors.New("empty input")
+	}
+	var flag C.int
+	if decrypt {
+		flag = 1
+	}
+	var errmsg *C.char
+	out := C.se_crypt(s.key, flag, (*C.UInt8)(unsafe.Pointer(&in[0])), C.size_t(len(in)), &errmsg)
+	if out == nil {
+		return nil, errors.New(seError(errmsg))
+	}
+	defer C.CFRelease(C.CFTypeRef(unsafe.Pointer(out)))
+	return C.GoBytes(unsafe.Pointer(C.CFDataGetBytePtr(out)), C.int(C.CFDataGetLength(out))), nil
+}
@@ -0,0 +1,8 @@
+package keeper
+
+// NewPlatformKeeper returns the PrivateKeyKeeper of the best key storage
+// hardware of the platform: the TPM on Linux, see NewTPMKeeper, CNG on Windows
+// and the Secure Enclave on macOS.
+func NewPlatformKeeper() (PrivateKeyKeeper, error) {
+	return NewTPMKeeper("/dev/tpmrm0")
+}
@@ -0,0 +1,10 @@
+//go:build !linux && !windows && !(darwin && cgo)
+
+package keeper
+
+import "errors"
+
+// NewPlatformKeeper is only available on Linux, Windows and, with cgo, macOS.
+func NewPlatformKeeper() (PrivateKeyKeeper, error) {
+	return nil, errors.New("no platform keeper on this platform")
+}
@@ -0,0 +1,110 @@
+package keeper
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// gcmSealer is a keySealer standing in for the platform hardware.
+type gcmSealer struct {
+	aead cipher.AEAD
+}
+
+func newGCMSealer(t *testing.T) *gcmSealer {
+	block, err := aes.NewCipher(make([]byte, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &gcmSealer{aead: aead}
+}
+
+func (s *gcmSealer) seal(secret []byte) ([]byte, error) {
+	nonce := make([]byte, s.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return s.aead.Seal(nonce, nonce, secret, nil), nil
+}
+
+func (s *gcmSealer) unseal(sealed []byte) ([]byte, error) {
+	if len(sealed) < s.aead.NonceSize() {
+		return nil, errors.New("sealed key too short")
+	}
+	n := s.aead.NonceSize()
+	return s.aead.Open(nil, sealed[:n], sealed[n:], nil)
+}
+
+func TestSealedKeeper(t *testing.T) {
+	sealer := newGCMSealer(t)
+	k := &sealedKeeper{sealer: sealer}
+
+	prvID, err := k.GeneratePrivateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	pub, err := k.GetPublicKey(prvID)
+	if err != nil {
+		t.Fatalf("failed to get public key: %v", err)
+	}
+	hash := crypto.Keccak256([]byte("platform"))
+	sig, err := k.Sign(hash, prvID)
+	if err != nil {
+		t.Fatalf("failed to sign: %v", err)
+	}
+	if recovered, err := crypto.Ecrecover(hash, sig); err != nil || !bytes.Equal(recovered, pub) {
+		t.Fatalf("recovered key mismatch: have (%x, %v), want %x", recovered, err, pub)
+	}
+	// A fresh keeper has to unseal the key to find its public key.
+	fresh := &sealedKeeper{sealer: sealer}
+	if pub2, err := fresh.GetPublicKey(prvID); err != nil || !bytes.Equal(pub2, pub) {
+		t.Fatalf("fresh keeper: have (%x, %v), want %x", pub2, err, pub)
+	}
+
+	tampered := bytes.Clone(prvID)
+	tampered[len(tampered)-1] ^= 0xff
+	if _, err := k.Sign(hash, tampered); err == nil {
+		t.Fatal("signed with tampered key")
+	}
+
+	// Imported keys are sealed just the same.
+	key, _ := crypto.GenerateKey()
+	imported, err := k.ImportPrivateKey(crypto.FromECDSA(key))
+	if err != nil {
+		t.Fatalf("failed to import key: %v", err)
+	}
+	if pub, err := k.GetPublicKey(imported); err != nil || !bytes.Equal(pub, crypto.FromECDSAPub(&key.PublicKey)) {
+		t.Fatalf("imported public key: have (%x, %v), want %x", pub, err, crypto.FromECDSAPub(&key.PublicKey))
+	}
+	if bytes.Contains(imported, crypto.FromECDSA(key)) {
+		t.Fatal("prvID holds the plain key")
+	}
+
+	exported, err := k.ExportEncryptedKey(imported, "pass")
+	if err != nil {
+		t.Fatalf("failed to export key: %v", err)
+	}
+	reimported, err := k.ImportEncryptedKey(exported, "pass")
+	if err != nil {
+		t.Fatalf("failed to import exported key: %v", err)
+	}
+	if pub, err := k.GetPublicKey(reimported); err != nil || !bytes.Equal(pub, crypto.FromECDSAPub(&key.PublicKey)) {
+		t.Fatalf("reimported public key: have (%x, %v), want %x", pub, err, crypto.FromECDSAPub(&key.PublicKey))
+	}
+
+	if err := k.DeletePrivateKey(prvID); !errors.Is(err, ErrNotSupported) {
+		t.Fatalf("delete: have %v, want %v", err, ErrNotSupported)
+	}
+	if _, err := k.ListPrivateKeys(); !errors.Is(err, ErrNotSupported) {
+		t.Fatalf("list: have %v, want %v", err, ErrNotSupported)
+	}
+}
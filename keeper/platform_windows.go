@@ -0,0 +1,145 @@
+package keeper
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// Names and flags of the CNG key storage API, see ncrypt.h.
+const (
+	cngPlatformProvider = "Microsoft Platform Crypto Provider" // TPM backed key storage provider
+	cngSealingKeyName   = "go-ethereum keeper sealing key"
+	cngRSAAlgorithm     = "RSA"
+	cngSHA256Algorithm  = "SHA256"
+
+	cngPadOAEP    = 0x4
+	cngNTEBadKeys = 0x80090016 // NTE_BAD_KEYSET, the key doesn't exist
+)
+
+var (
+	ncrypt = windows.NewLazySystemDLL("ncrypt.dll")
+
+	procNCryptOpenStorageProvider = ncrypt.NewProc("NCryptOpenStorageProvider")
+	procNCryptOpenKey             = ncrypt.NewProc("NCryptOpenKey")
+	procNCryptCreatePersistedKey  = ncrypt.NewProc("NCryptCreatePersistedKey")
+	procNCryptFinalizeKey         = ncrypt.NewProc("NCryptFinalizeKey")
+	procNCryptEncrypt             = ncrypt.NewProc("NCryptEncrypt")
+	procNCryptDecrypt             = ncrypt.NewProc("NCryptDecrypt")
+	procNCryptFreeObject          = ncrypt.NewProc("NCryptFreeObject")
+)
+
+// cngOAEPPaddingInfo is the BCRYPT_OAEP_PADDING_INFO structure.
+type cngOAEPPaddingInfo struct {
+	algID   *uint16
+	label   *byte
+	labelSz uint32
+}
+
+// cngSealer is a keySealer encrypting keys with RSA-OAEP under an RSA key of
+// the Microsoft Platform Crypto Provider, the TPM behind CNG.
+type cngSealer struct {
+	lock sync.Mutex
+	key  uintptr // NCRYPT_KEY_HANDLE of the sealing key
+}
+
+// NewPlatformKeeper returns a PrivateKeyKeeper sealing keys with CNG: an RSA
+// key of the Microsoft Platform Crypto Provider, created on first use under
+// the name "go-ethereum keeper sealing key" of the current user.
+func NewPlatformKeeper() (PrivateKeyKeeper, error) {
+	sealer, err := openCNGSealer()
+	if err != nil {
+		return nil, err
+	}
+	return &sealedKeeper{sealer: sealer}, nil
+}
+
+// cngCall calls the NCrypt function proc, turning a failed SECURITY_STATUS
+// into an error.
+func cngCall(proc *windows.LazyProc, args ...uintptr) error {
+	if err := proc.Find(); err != nil {
+		return err
+	}
+	status, _, _ := proc.Call(args...)
+	if status != 0 {
+		return &cngError{fn: proc.Name, status: uint32(status)}
+	}
+	return nil
+}
+
+// cngError is a failed SECURITY_STATUS of an NCrypt function.
+type cngError struct {
+	fn     string
+	status uint32
+}
+
+func (e *cngError) Error() string {
+	return fmt.Sprintf("%s failed: 0x%08x", e.fn, e.status)
+}
+
+// openCNGSealer opens the sealing key of the platform provider, creating it if
+// it doesn't exist yet.
+func openCNGSealer() (*cngSealer, error) {
+	provName, _ := windows.UTF16PtrFromString(cngPlatformProvider)
+	keyName, _ := windows.UTF16PtrFromString(cngSealingKeyName)
+	algName, _ := windows.UTF16PtrFromString(cngRSAAlgorithm)
+
+	var prov uintptr
+	if err := cngCall(procNCryptOpenStorageProvider, uintptr(unsafe.Pointer(&prov)), uintptr(unsafe.Pointer(provName)), 0); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrBackendUnavailable, err)
+	}
+	defer procNCryptFreeObject.Call(prov)
+
+	var key uintptr
+	err := cngCall(procNCryptOpenKey, prov, uintptr(unsafe.Pointer(&key)), uintptr(unsafe.Pointer(keyName)), 0, 0)
+	var cerr *cngError
+	if errors.As(err, &cerr) && cerr.status == cngNTEBadKeys {
+		if err = cngCall(procNCryptCreatePersistedKey, prov, uintptr(unsafe.Pointer(&key)), uintptr(unsafe.Pointer(algName)), uintptr(unsafe.Pointer(keyName)), 0, 0); err == nil {
+			if err = cngCall(procNCryptFinalizeKey, key, 0); err != nil {
+				procNCryptFreeObject.Call(key)
+			}
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open CNG sealing key: %w", err)
+	}
+	return &cngSealer{key: key}, nil
+}
+
+func (s *cngSealer) seal(secret []byte) ([]byte, error) {
+	return s.crypt(procNCryptEncrypt, secret)
+}
+
+func (s *cngSealer) unseal(sealed []byte) ([]byte, error) {
+	secret, err := s.crypt(procNCryptDecrypt, sealed)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unseal key: %w", err)
+	}
+	return secret, nil
+}
+
+// crypt runs NCryptEncrypt or NCryptDecrypt over in with OAEP padding, asking
+// for the size of the output first.
+func (s *cngSealer) crypt(proc *windows.LazyProc, in []byte) ([]byte, error) {
+	if len(in) == 0 {
+		return nil, errors.New("empty input")
+	}
+	algID, _ := windows.UTF16PtrFromString(cngSHA256Algorithm)
+	padding := cngOAEPPaddingInfo{algID: algID}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	var size uint32
+	if err := cngCall(proc, s.key, uintptr(unsafe.Pointer(&in[0])), uintptr(len(in)), uintptr(unsafe.Pointer(&padding)), 0, 0, uintptr(unsafe.Pointer(&size)), cngPadOAEP); err != nil {
+		return nil, err
+	}
+	out := make([]byte, size)
+	if err := cngCall(proc, s.key, uintptr(unsafe.Pointer(&in[0])), uintptr(len(in)), uintptr(unsafe.Pointer(&padding)), uintptr(unsafe.Pointer(&out[0])), uintptr(size), uintptr(unsafe.Pointer(&size)), cngPadOAEP); err != nil {
+		return nil, err
+	}
+	return out[:size], nil
+}
@@ -0,0 +1,343 @@
+package keeper
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+)
+
+// SigningPolicy limits the transactions a policied signer signs. Unset limits,
+// nil or zero, are not enforced.
+type SigningPolicy struct {
+	MaxGasPrice *big.Int // limit of the gas price, or of the fee cap of EIP-1559 transactions
+	MaxValue    *big.Int // limit of the transferred wei
+	MaxGasLimit uint64   // limit of the gas of the transaction
+
+	// If AllowedTo is set, transactions may only be sent to its addresses.
+	// Contract creations have no recipient, they are only allowed along with
+	// them if AllowContractCreation is set.
+	AllowedTo             []common.Address
+	AllowContractCreation bool
+
+	// If GasPriceOracle is set, the gas price, or the fee cap of EIP-1559
+	// transactions, may not exceed the price suggested by the oracle times
+	// MaxGasPriceMultiplier, 1 if not positive.
+	GasPriceOracle        GasPriceOracle
+	MaxGasPriceMultiplier float64
+}
+
+// AddressAllowlistPolicy returns a SigningPolicy only allowing transactions to
+// the allowed addresses. An empty allowlist refuses all transactions.
+func AddressAllowlistPolicy(allowed []common.Address) SigningPolicy {
+	return SigningPolicy{AllowedTo: append([]common.Address{}, allowed...)}
+}
+
+// PolicyViolation is returned by policied signers for transactions exceeding a
+// limit of their SigningPolicy.
+type PolicyViolation struct {
+	Field   string   // violated transaction field, e.g. "maxFeePerGas"
+	Actual  *big.Int // value of the field in the transaction, nil for "to"
+	Allowed *big.Int // limit set by the policy, nil for "to"
+
+	To *common.Address // recipient of a refused "to", nil for contract creations
+}
+
+func (e *PolicyViolation) Error() string {
+	if e.Field == "to" {
+		if e.To == nil {
+			return "signing policy violated: contract creation not allowed"
+		}
+		return fmt.Sprintf("signing policy violated: to %v not allowed", e.To.Hex())
+	}
+	return fmt.Sprintf("signing policy violated: %s %v exceeds limit %v", e.Field, e.Actual, e.Allowed)
+}
+
+// signingPolicy is a SigningPolicy prepared for checking transactions.
+type signingPolicy struct {
+	SigningPolicy
+	allowedTo map[common.Address]struct{} // nil if any recipient is allowed
+	gasPrice  *cachedGasPrice             // nil if no oracle is set
+}
+
+// NewPoliciedSecureSigner returns a SecureSigner signing transactions with inner
+// only if they are within the limits of policy, failing with a *PolicyViolation
+// otherwise. Transactions with a fee cap, like EIP-1559 ones, are checked by
+// their max fee per gas instead of the gas price.
+//
+// Hashes, messages, typed data, permits and user operations can't be checked
+// against the policy, signing them fails with ErrPermissionDenied unless
+// AllowRawSigning is given.
+func NewPoliciedSecureSigner(inner SecureSigner, policy SigningPolicy, opts ...GuardOption) SecureSigner {
+	p := &signingPolicy{SigningPolicy: policy}
+	if policy.MaxGasPrice != nil {
+		p.MaxGasPrice = new(big.Int).Set(policy.MaxGasPrice)
+	}
+	if policy.MaxValue != nil {
+		p.MaxValue = new(big.Int).Set(policy.MaxValue)
+	}
+	if policy.AllowedTo != nil {
+		p.allowedTo = make(map[common.Address]struct{}, len(policy.AllowedTo))
+		for _, addr := range policy.AllowedTo {
+			p.allowedTo[addr] = struct{}{}
+		}
+		p.AllowedTo = nil
+	}
+	if policy.GasPriceOracle != nil {
+		if policy.MaxGasPriceMultiplier <= 0 {
+			p.MaxGasPriceMultiplier = 1
+		}
+		p.gasPrice = newCachedGasPrice(policy.GasPriceOracle)
+	}
+	return newGuardSigner(inner, func(ctx context.Context, tx *types.Transaction, signer types.Signer) error {
+		return p.check(ctx, tx)
+	}, opts)
+}
+
+// check verifies that tx is within the limits of the policy.
+func (p *signingPolicy) check(ctx context.Context, tx *types.Transaction) error {
+	if p.allowedTo != nil {
+		to := tx.To()
+		if to == nil && !p.AllowContractCreation {
+			return &PolicyViolation{Field: "to"}
+		}
+		if to != nil {
+			if _, ok := p.allowedTo[*to]; !ok {
+				return &PolicyViolation{Field: "to", To: to}
+			}
+		}
+	}
+	// GasFeeCap is the gas price of transactions without a fee cap.
+	field := "maxFeePerGas"
+	if tx.Type() == types.LegacyTxType || tx.Type() == types.AccessListTxType {
+		field = "gasPrice"
+	}
+	if p.MaxGasPrice != nil && tx.GasFeeCap().Cmp(p.MaxGasPrice) > 0 {
+		return &PolicyViolation{Field: field, Actual: tx.GasFeeCap(), Allowed: p.MaxGasPrice}
+	}
+	if p.gasPrice != nil {
+		suggested, err := p.gasPrice.get(ctx)
+		if err != nil {
+			return err
+		}
+		limit, _ := new(big.Float).Mul(new(big.Float).SetInt(suggested), big.NewFloat(p.MaxGasPriceMultiplier)).Int(nil)
+		if tx.GasFeeCap().Cmp(limit) > 0 {
+			return &PolicyViolation{Field: field, Actual: tx.GasFeeCap(), Allowed: limit}
+		}
+	}
+	if p.MaxValue != nil && tx.Value().Cmp(p.MaxValue) > 0 {
+		return &PolicyViolation{Field: "value", Actual: tx.Value(), Allowed: p.MaxValue}
+	}
+	if p.MaxGasLimit != 0 && tx.Gas() > p.MaxGasLimit {
+		return &PolicyViolation{Field: "gas", Actual: new(big.Int).SetUint64(tx.Gas()), Allowed: new(big.Int).SetUint64(p.MaxGasLimit)}
+	}
+	return nil
+}
+
+// GuardOption configures the signers guarding transactions:
+// NewPoliciedSecureSigner, NewChainBoundSigner, NewNonceTrackingSigner and
+// NewApprovalRequiredSigner.
+type GuardOption func(*checkedSigner)
+
+// AllowRawSigning lets the guarding signer sign hashes, messages, typed data,
+// permits and user operations, which it can't check, instead of refusing them with
+// ErrPermissionDenied. A signed transaction hash is as good as the signed
+// transaction, so this reopens the door the guard closes for anyone able to
+// call SignHash.
+func AllowRawSigning() GuardOption {
+	return func(s *checkedSigner) {
+		s.raw = true
+	}
+}
+
+// checkedSigner is a SecureSigner signing only the transactions passing a
+// check, and handing out only the signed ones passing a verification. The
+// transaction helpers build their transactions and sign them through the Sign
+// of the checked signer, so every transaction is checked the same way.
+//
+// Signers with a check refuse the signatures of hashes, messages, typed data,
+// permits and user operations unless raw is set, as the checked transactions
+// could be signed through them as well. Signers bound to chains sign the
+// permits and user operations for those chains instead.
+type checkedSigner struct {
+	SecureSigner
+	check  func(ctx context.Context, tx *types.Transaction, signer types.Signer) error                   // nil if not checked
+	verify func(ctx context.Context, signed *types.Transaction, signer types.Signer, prvID []byte) error // nil if not verified
+	chain  func(chainID *big.Int) error                                                                  // nil if not bound to chains
+	raw    bool                                                                                          // allow raw signing despite the check
+}
+
+// newGuardSigner returns a checkedSigner checking transactions with check and
+// configured by opts.
+func newGuardSigner(inner SecureSigner, check func(ctx context.Context, tx *types.Transaction, signer types.Signer) error, opts []GuardOption) *checkedSigner {
+	s := &checkedSigner{SecureSigner: inner, check: check}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// allowRaw fails with ErrPermissionDenied if the signer checks transactions and
+// raw signing isn't allowed.
+func (s *checkedSigner) allowRaw() error {
+	if s.check != nil && !s.raw {
+		return fmt.Errorf("%w: raw signing not allowed by transaction guard", ErrPermissionDenied)
+	}
+	return nil
+}
+
+func (s *checkedSigner) SignHash(hash common.Hash, prvID []byte) (_ []byte, err error) {
+	defer wrapError(&err, "sign hash", prvID)
+	if err := s.allowRaw(); err != nil {
+		return nil, err
+	}
+	return s.SecureSigner.SignHash(hash, prvID)
+}
+
+func (s *checkedSigner) SignHashBytes(data []byte, prvID []byte) (_ []byte, err error) {
+	defer wrapError(&err, "sign hash", prvID)
+	if err := s.allowRaw(); err != nil {
+		return nil, err
+	}
+	return s.SecureSigner.SignHashBytes(data, prvID)
+}
+
+func (s *checkedSigner) SignTypedData(typedData apitypes.TypedData, prvID []byte) (_ []byte, err error) {
+	defer wrapError(&err, "sign typed data", prvID)
+	if err := s.allowRaw(); err != nil {
+		return nil, err
+	}
+	return s.SecureSigner.SignTypedData(typedData, prvID)
+}
+
+func (s *checkedSigner) SignPersonalMessage(message []byte, prvID []byte) (_ []byte, err error) {
+	defer wrapError(&err, "sign message", prvID)
+	if err := s.allowRaw(); err != nil {
+		return nil, err
+	}
+	return s.SecureSigner.SignPersonalMessage(message, prvID)
+}
+
+func (s *checkedSigner) SignUserOperation(chainID *big.Int, entryPoint common.Address, op UserOperation, prvID []byte) (_ []byte, err error) {
+	defer wrapError(&err, "sign user operation", prvID)
+	if s.chain != nil {
+		err = s.chain(chainID)
+	} else {
+		err = s.allowRaw()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s.SecureSigner.SignUserOperation(chainID, entryPoint, op, prvID)
+}
+
+func (s *checkedSigner) SignPermit(chainID *big.Int, tokenAddr common.Address, tokenName, tokenVersion string, ownerAddr, spenderAddr common.Address, value, nonce *big.Int, deadline int64, prvID []byte) (_ uint8, _, _ [32]byte, err error) {
+	defer wrapError(&err, "sign permit", prvID)
+	if s.chain != nil {
+		err = s.chain(chainID)
+	} else {
+		err = s.allowRaw()
+	}
+	if err != nil {
+		return 0, [32]byte{}, [32]byte{}, err
+	}
+	return s.SecureSigner.SignPermit(chainID, tokenAddr, tokenName, tokenVersion, ownerAddr, spenderAddr, value, nonce, deadline, prvID)
+}
+
+func (s *checkedSigner) GenerateKeyContext(ctx context.Context) ([]byte, error) {
+	return signerContext(s.SecureSigner).GenerateKeyContext(ctx)
+}
+
+func (s *checkedSigner) GetPublicKeyContext(ctx context.Context, prvID []byte) ([]byte, error) {
+	return signerContext(s.SecureSigner).GetPublicKeyContext(ctx, prvID)
+}
+
+func (s *checkedSigner) Sign(tx *types.Transaction, signer types.Signer, prvID []byte) (*types.Transaction, error) {
+	return s.SignContext(context.Background(), tx, signer, prvID)
+}
+
+func (s *checkedSigner) SignContext(ctx context.Context, tx *types.Transaction, signer types.Signer, prvID []byte) (_ *types.Transaction, err error) {
+	defer wrapError(&err, "sign transaction", prvID)
+
+	if s.check != nil {
+		if err := s.check(ctx, tx, signer); err != nil {
+			return nil, err
+		}
+	}
+	signed, err := signerContext(s.SecureSigner).SignContext(ctx, tx, signer, prvID)
+	if err != nil || s.verify == nil {
+		return signed, err
+	}
+	if err := s.verify(ctx, signed, signer, prvID); err != nil {
+		return nil, err
+	}
+	return signed, nil
+}
+
+func (s *checkedSigner) SignForChain(chainID *big.Int, tx *types.Transaction, prvID []byte) (*types.Transaction, error) {
+	return signForChain(s, chainID, tx, prvID)
+}
+
+func (s *checkedSigner) SignTransactionJSON(tx *types.Transaction, signer types.Signer, prvID []byte) (json.RawMessage, error) {
+	return signTransactionJSON(s, tx, signer, prvID)
+}
+
+func (s *checkedSigner) SignAndEncode(tx *types.Transaction, signer types.Signer, prvID []byte) ([]byte, error) {
+	return signAndEncode(s, tx, signer, prvID)
+}
+
+func (s *checkedSigner) SignAndEncodeHex(tx *types.Transaction, signer types.Signer, prvID []byte) (string, error) {
+	return signAndEncodeHex(s, tx, signer, prvID)
+}
+
+func (s *checkedSigner) SignDynamicFeeTx(chainID *big.Int, nonce uint64, to common.Address, value *big.Int, gasLimit uint64, maxFeePerGas, maxPriorityFeePerGas *big.Int, data []byte, prvID []byte) (*types.Transaction, error) {
+	tx := newDynamicFeeTx(chainID, nonce, to, value, gasLimit, maxFeePerGas, maxPriorityFeePerGas, data)
+	return s.Sign(tx, types.LatestSignerForChainID(chainID), prvID)
+}
+
+func (s *checkedSigner) SignAccessListTx(chainID *big.Int, nonce uint64, to common.Address, value *big.Int, gasLimit uint64, gasPrice *big.Int, accessList types.AccessList, data []byte, prvID []byte) (*types.Transaction, error) {
+	tx := newAccessListTx(chainID, nonce, to, value, gasLimit, gasPrice, accessList, data)
+	return s.Sign(tx, types.NewEIP2930Signer(chainID), prvID)
+}
+
+func (s *checkedSigner) SignContractCall(chainID *big.Int, contract common.Address, contractABI abi.ABI, method string, args []interface{}, nonce uint64, gasLimit uint64, gasPrice *big.Int, prvID []byte) (_ *types.Transaction, err error) {
+	defer wrapError(&err, "sign transaction", prvID)
+
+	tx, err := newContractCallTx(chainID, contract, contractABI, method, args, nonce, gasLimit, gasPrice)
+	if err != nil {
+		return nil, err
+	}
+	return s.Sign(tx, types.LatestSignerForChainID(chainID), prvID)
+}
+
+func (s *checkedSigner) SimulateAndSign(ctx context.Context, tx *types.Transaction, signer types.Signer, client ethereum.ContractCaller, prvID []byte) (*types.Transaction, error) {
+	return simulateAndSign(ctx, s, tx, signer, client, prvID)
+}
+
+func (s *checkedSigner) SignBlobTx(chainID *big.Int, blobTx *types.BlobTx, prvID []byte) (_ *types.Transaction, err error) {
+	defer wrapError(&err, "sign transaction", prvID)
+
+	tx, err := newBlobTx(chainID, blobTx)
+	if err != nil {
+		return nil, err
+	}
+	return s.Sign(tx, types.LatestSignerForChainID(chainID), prvID)
+}
+
+func (s *checkedSigner) SignBatch(txs []*types.Transaction, signer types.Signer, prvID []byte) ([]*types.Transaction, error) {
+	return signBatch(txs, func(tx *types.Transaction) (*types.Transaction, error) {
+		return s.Sign(tx, signer, prvID)
+	})
+}
+
+func (s *checkedSigner) SignBatchParallel(txs []*types.Transaction, signer types.Signer, prvID []byte) ([]*types.Transaction, error) {
+	return signBatchParallel(txs, func(tx *types.Transaction) (*types.Transaction, error) {
+		return s.Sign(tx, signer, prvID)
+	})
+}
@@ -0,0 +1,209 @@
+package keeper
+
+import (
+	"encoding/json"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+)
+
+// checkRawSigningGuarded checks that the signer returned by guard refuses to
+// sign what it can't check, unless raw signing is allowed. The signatures
+// named in checked are checked by the guard instead and skipped.
+func checkRawSigningGuarded(t *testing.T, guard func(inner SecureSigner, opts ...GuardOption) SecureSigner, checked ...string) {
+	t.Helper()
+
+	var typedData apitypes.TypedData
+	if err := json.Unmarshal([]byte(mailTypedData), &typedData); err != nil {
+		t.Fatal(err)
+	}
+	// The hash of a transaction the guard would refuse to sign.
+	hash := types.LatestSignerForChainID(big.NewInt(1)).Hash(types.NewTx(&types.LegacyTx{
+		To: &common.Address{1}, GasPrice: big.NewInt(1e12), Value: big.NewInt(1e18), Gas: 1e6,
+	}))
+	s := guard(NewSecureSigner(new(defaultPrivateKeyKeeper)))
+	prvID, err := s.GenerateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	owner, err := s.GetAddress(prvID)
+	if err != nil {
+		t.Fatalf("failed to get address: %v", err)
+	}
+	sign := map[string]func(s SecureSigner) error{
+		"hash": func(s SecureSigner) error {
+			_, err := s.SignHash(hash, prvID)
+			return err
+		},
+		"hash bytes": func(s SecureSigner) error {
+			_, err := s.SignHashBytes([]byte("raw"), prvID)
+			return err
+		},
+		"typed data": func(s SecureSigner) error {
+			_, err := s.SignTypedData(typedData, prvID)
+			return err
+		},
+		"message": func(s SecureSigner) error {
+			_, err := s.SignPersonalMessage([]byte("raw"), prvID)
+			return err
+		},
+		"permit": func(s SecureSigner) error {
+			_, _, _, err := s.SignPermit(big.NewInt(1), common.Address{1}, "Token", "1", owner, common.Address{2}, big.NewInt(1e18), big.NewInt(0), 0, prvID)
+			return err
+		},
+		"user operation": func(s SecureSigner) error {
+			_, err := s.SignUserOperation(big.NewInt(1), common.Address{2}, UserOperation{Sender: owner}, prvID)
+			return err
+		},
+	}
+	for _, name := range checked {
+		delete(sign, name)
+	}
+	for name, fn := range sign {
+		if err := fn(s); !errors.Is(err, ErrPermissionDenied) {
+			t.Fatalf("signing %s: have %v, want %v", name, err, ErrPermissionDenied)
+		}
+	}
+	s = guard(NewSecureSigner(new(defaultPrivateKeyKeeper)), AllowRawSigning())
+	for name, fn := range sign {
+		if err := fn(s); err != nil {
+			t.Fatalf("signing %s with raw signing allowed: %v", name, err)
+		}
+	}
+}
+
+func TestPoliciedSecureSigner(t *testing.T) {
+	policy := SigningPolicy{
+		MaxGasPrice: big.NewInt(100),
+		MaxValue:    big.NewInt(1000),
+		MaxGasLimit: 50000,
+	}
+	s := NewPoliciedSecureSigner(NewSecureSigner(new(defaultPrivateKeyKeeper)), policy)
+	prvID, err := s.GenerateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	chainID := big.NewInt(1)
+	signer := types.LatestSignerForChainID(chainID)
+	legacy := func(gasPrice, value int64, gas uint64) *types.Transaction {
+		return types.NewTx(&types.LegacyTx{To: &common.Address{1}, GasPrice: big.NewInt(gasPrice), Value: big.NewInt(value), Gas: gas})
+	}
+	if _, err := s.Sign(legacy(100, 1000, 50000), signer, prvID); err != nil {
+		t.Fatalf("failed to sign transaction at the limits: %v", err)
+	}
+	tests := []struct {
+		tx      *types.Transaction
+		field   string
+		actual  int64
+		allowed int64
+	}{
+		{legacy(101, 1, 21000), "gasPrice", 101, 100},
+		{legacy(1, 1001, 21000), "value", 1001, 1000},
+		{legacy(1, 1, 50001), "gas", 50001, 50000},
+		{newAccessListTx(chainID, 0, common.Address{1}, big.NewInt(1), 21000, big.NewInt(200), nil, nil), "gasPrice", 200, 100},
+		// The tip is within the limit, the fee cap is not.
+		{newDynamicFeeTx(chainID, 0, common.Address{1}, big.NewInt(1), 21000, big.NewInt(150), big.NewInt(1), nil), "maxFeePerGas", 150, 100},
+	}
+	for i, tt := range tests {
+		_, err := s.Sign(tt.tx, signer, prvID)
+		var violation *PolicyViolation
+		if !errors.As(err, &violation) {
+			t.Fatalf("test %d: have %v, want %T", i, err, violation)
+		}
+		if violation.Field != tt.field || violation.Actual.Int64() != tt.actual || violation.Allowed.Int64() != tt.allowed {
+			t.Errorf("test %d: have %s %v > %v, want %s %d > %d", i, violation.Field, violation.Actual, violation.Allowed, tt.field, tt.actual, tt.allowed)
+		}
+	}
+	// The helpers building transactions are checked as well.
+	if _, err := s.SignDynamicFeeTx(chainID, 0, common.Address{1}, big.NewInt(1), 21000, big.NewInt(100), big.NewInt(200), nil, prvID); err != nil {
+		t.Fatalf("failed to sign dynamic fee tx within limits: %v", err)
+	}
+	var violation *PolicyViolation
+	if _, err := s.SignDynamicFeeTx(chainID, 0, common.Address{1}, big.NewInt(5000), 21000, big.NewInt(100), big.NewInt(1), nil, prvID); !errors.As(err, &violation) || violation.Field != "value" {
+		t.Fatalf("dynamic fee tx over value limit: have %v, want value violation", err)
+	}
+	// Changing the policy after creating the signer doesn't lift the limits.
+	policy.MaxValue.SetInt64(1 << 40)
+	if _, err := s.Sign(legacy(1, 5000, 21000), signer, prvID); !errors.As(err, &violation) {
+		t.Fatalf("limit changed through policy: have %v, want %T", err, violation)
+	}
+}
+
+func TestPoliciedSecureSignerUnlimited(t *testing.T) {
+	s := NewPoliciedSecureSigner(NewSecureSigner(new(defaultPrivateKeyKeeper)), SigningPolicy{})
+	prvID, err := s.GenerateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	tx := types.NewTx(&types.LegacyTx{To: &common.Address{1}, GasPrice: big.NewInt(1e18), Value: big.NewInt(1e18), Gas: 30_000_000})
+	if _, err := s.Sign(tx, types.HomesteadSigner{}, prvID); err != nil {
+		t.Fatalf("empty policy refused transaction: %v", err)
+	}
+}
+
+func TestPoliciedSecureSignerAllowlist(t *testing.T) {
+	multisig, other := common.Address{0xaa}, common.Address{0xbb}
+	chainID := big.NewInt(1)
+	signer := types.LatestSignerForChainID(chainID)
+	tx := func(to *common.Address) *types.Transaction {
+		return types.NewTx(&types.DynamicFeeTx{ChainID: chainID, To: to, Value: big.NewInt(1), Gas: 21000, GasFeeCap: big.NewInt(1)})
+	}
+	tests := []struct {
+		policy SigningPolicy
+		to     *common.Address
+		ok     bool
+	}{
+		{AddressAllowlistPolicy([]common.Address{multisig}), &multisig, true},
+		{AddressAllowlistPolicy([]common.Address{multisig}), &other, false},
+		{AddressAllowlistPolicy([]common.Address{multisig}), nil, false},
+		{SigningPolicy{AllowedTo: []common.Address{multisig}, AllowContractCreation: true}, nil, true},
+		{SigningPolicy{AllowedTo: []common.Address{multisig}, AllowContractCreation: true}, &other, false},
+		// Without an allowlist any recipient is allowed, an empty one allows none.
+		{SigningPolicy{}, &other, true},
+		{SigningPolicy{}, nil, true},
+		{AddressAllowlistPolicy(nil), &multisig, false},
+		{SigningPolicy{AllowedTo: []common.Address{}, AllowContractCreation: true}, nil, true},
+	}
+	for i, tt := range tests {
+		s := NewPoliciedSecureSigner(NewSecureSigner(new(defaultPrivateKeyKeeper)), tt.policy)
+		prvID, err := s.GenerateKey()
+		if err != nil {
+			t.Fatalf("failed to generate key: %v", err)
+		}
+		_, err = s.Sign(tx(tt.to), signer, prvID)
+		if tt.ok {
+			if err != nil {
+				t.Errorf("test %d: failed to sign: %v", i, err)
+			}
+			continue
+		}
+		var violation *PolicyViolation
+		if !errors.As(err, &violation) || violation.Field != "to" {
+			t.Errorf("test %d: have %v, want to violation", i, err)
+			continue
+		}
+		if (violation.To == nil) != (tt.to == nil) || (tt.to != nil && *violation.To != *tt.to) {
+			t.Errorf("test %d: refused recipient %v, want %v", i, violation.To, tt.to)
+		}
+	}
+	// The helpers building transactions are checked as well.
+	s := NewPoliciedSecureSigner(NewSecureSigner(new(defaultPrivateKeyKeeper)), AddressAllowlistPolicy([]common.Address{multisig}))
+	prvID, _ := s.GenerateKey()
+	if _, err := s.SignDynamicFeeTx(chainID, 0, multisig, big.NewInt(1), 21000, big.NewInt(1), big.NewInt(1), nil, prvID); err != nil {
+		t.Fatalf("failed to sign to multisig: %v", err)
+	}
+	var violation *PolicyViolation
+	if _, err := s.SignAccessListTx(chainID, 0, other, big.NewInt(1), 21000, big.NewInt(1), nil, nil, prvID); !errors.As(err, &violation) {
+		t.Fatalf("access list tx to other: have %v, want %T", err, violation)
+	}
+}
+
+func TestPoliciedSecureSignerRawSigning(t *testing.T) {
+	checkRawSigningGuarded(t, func(inner SecureSigner, opts ...GuardOption) SecureSigner {
+		return NewPoliciedSecureSigner(inner, SigningPolicy{MaxValue: big.NewInt(1)}, opts...)
+	})
+}
@@ -0,0 +1,35 @@
+package keeper
+
+import (
+	"crypto/ecdsa"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// ParsePublicKey parses a secp256k1 public key in either the 33 byte compressed
+// or the 65 byte uncompressed form.
+func ParsePublicKey(raw []byte) (*ecdsa.PublicKey, error) {
+	switch len(raw) {
+	case 33:
+		return crypto.DecompressPubkey(raw)
+	case 65:
+		return crypto.UnmarshalPubkey(raw)
+	}
+	return nil, fmt.Errorf("invalid public key length %d", len(raw))
+}
+
+// GetPublicKeyCompressed returns the public key of prvID in the 33 byte
+// compressed form.
+func (sec *SecureSign) GetPublicKeyCompressed(prvID []byte) (_ []byte, err error) {
+	defer wrapError(&err, "get public key", prvID)
+	pub, err := sec.GetPublicKey(prvID)
+	if err != nil {
+		return nil, err
+	}
+	key, err := ParsePublicKey(pub)
+	if err != nil {
+		return nil, err
+	}
+	return crypto.CompressPubkey(key), nil
+}
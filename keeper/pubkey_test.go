@@ -0,0 +1,51 @@
+package keeper
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestParsePublicKey(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	for _, raw := range [][]byte{
+		crypto.FromECDSAPub(&key.PublicKey),
+		crypto.CompressPubkey(&key.PublicKey),
+	} {
+		pub, err := ParsePublicKey(raw)
+		if err != nil {
+			t.Fatalf("failed to parse %x: %v", raw, err)
+		}
+		if !pub.Equal(&key.PublicKey) {
+			t.Fatalf("%d byte key mismatch", len(raw))
+		}
+	}
+	invalid := [][]byte{
+		nil,
+		make([]byte, 33),
+		make([]byte, 64),
+		append([]byte{0x04}, make([]byte, 64)...),
+	}
+	for _, raw := range invalid {
+		if _, err := ParsePublicKey(raw); err == nil {
+			t.Errorf("parsed invalid key %x", raw)
+		}
+	}
+}
+
+func TestGetPublicKeyCompressed(t *testing.T) {
+	sec := DefaultSecureSign()
+	prvID, err := sec.GenerateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	compressed, err := sec.GetPublicKeyCompressed(prvID)
+	if err != nil {
+		t.Fatalf("failed to get compressed key: %v", err)
+	}
+	key, _ := crypto.ToECDSA(prvID)
+	if want := crypto.CompressPubkey(&key.PublicKey); !bytes.Equal(compressed, want) {
+		t.Fatalf("compressed key mismatch: have %x, want %x", compressed, want)
+	}
+}
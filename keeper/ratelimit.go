@@ -0,0 +1,110 @@
+package keeper
+
+import (
+	"context"
+
+	"golang.org/x/time/rate"
+)
+
+// keyRateFactor is how many times more key management calls than signatures a
+// rate limited keeper allows.
+const keyRateFactor = 10
+
+// rateLimitedKeeper is a PrivateKeyKeeper refusing calls beyond a fixed rate,
+// so that a runaway caller can't use up the signing quota of an HSM or a KMS.
+// Calls over the limit fail with ErrRateLimitExceeded instead of waiting, to
+// let callers apply back-pressure.
+type rateLimitedKeeper struct {
+	inner PrivateKeyKeeperContext
+	sign  *rate.Limiter // limits Sign
+	keys  *rate.Limiter // limits all other calls
+}
+
+// NewRateLimitedKeeper returns a PrivateKeyKeeper allowing rps signatures per
+// second on average, with bursts of up to burst signatures. Key generation and
+// lookups are limited separately, at keyRateFactor times the rate and burst.
+func NewRateLimitedKeeper(inner PrivateKeyKeeper, rps float64, burst int) PrivateKeyKeeper {
+	return &rateLimitedKeeper{
+		inner: ContextKeeper(inner),
+		sign:  rate.NewLimiter(rate.Limit(rps), burst),
+		keys:  rate.NewLimiter(rate.Limit(rps*keyRateFactor), burst*keyRateFactor),
+	}
+}
+
+// allow reports ErrRateLimitExceeded if l has no token left for op.
+func allow(l *rate.Limiter, op string, prvID []byte) error {
+	if !l.Allow() {
+		return &KeeperError{Op: op, KeyID: prvID, Err: ErrRateLimitExceeded}
+	}
+	return nil
+}
+
+func (k *rateLimitedKeeper) GeneratePrivateKey() ([]byte, error) {
+	return k.GeneratePrivateKeyContext(context.Background())
+}
+
+func (k *rateLimitedKeeper) GeneratePrivateKeyContext(ctx context.Context) ([]byte, error) {
+	if err := allow(k.keys, "generate key", nil); err != nil {
+		return nil, err
+	}
+	return k.inner.GeneratePrivateKeyContext(ctx)
+}
+
+func (k *rateLimitedKeeper) GetPublicKey(prvID []byte) ([]byte, error) {
+	return k.GetPublicKeyContext(context.Background(), prvID)
+}
+
+func (k *rateLimitedKeeper) GetPublicKeyContext(ctx context.Context, prvID []byte) ([]byte, error) {
+	if err := allow(k.keys, "get public key", prvID); err != nil {
+		return nil, err
+	}
+	return k.inner.GetPublicKeyContext(ctx, prvID)
+}
+
+func (k *rateLimitedKeeper) Sign(data []byte, prvID []byte) ([]byte, error) {
+	return k.SignContext(context.Background(), data, prvID)
+}
+
+func (k *rateLimitedKeeper) SignContext(ctx context.Context, data []byte, prvID []byte) ([]byte, error) {
+	if err := allow(k.sign, "sign", prvID); err != nil {
+		return nil, err
+	}
+	return k.inner.SignContext(ctx, data, prvID)
+}
+
+func (k *rateLimitedKeeper) DeletePrivateKey(prvID []byte) error {
+	return k.DeletePrivateKeyContext(context.Background(), prvID)
+}
+
+func (k *rateLimitedKeeper) DeletePrivateKeyContext(ctx context.Context, prvID []byte) error {
+	if err := allow(k.keys, "delete key", prvID); err != nil {
+		return err
+	}
+	return k.inner.DeletePrivateKeyContext(ctx, prvID)
+}
+
+func (k *rateLimitedKeeper) ListPrivateKeys() ([][]byte, error) {
+	return k.ListPrivateKeysContext(context.Background())
+}
+
+func (k *rateLimitedKeeper) ListPrivateKeysContext(ctx context.Context) ([][]byte, error) {
+	if err := allow(k.keys, "list keys", nil); err != nil {
+		return nil, err
+	}
+	return k.inner.ListPrivateKeysContext(ctx)
+}
+
+func (k *rateLimitedKeeper) ImportPrivateKey(rawKey []byte) ([]byte, error) {
+	return k.ImportPrivateKeyContext(context.Background(), rawKey)
+}
+
+func (k *rateLimitedKeeper) ImportPrivateKeyContext(ctx context.Context, rawKey []byte) ([]byte, error) {
+	if err := allow(k.keys, "import key", nil); err != nil {
+		return nil, err
+	}
+	return k.inner.ImportPrivateKeyContext(ctx, rawKey)
+}
+
+func (k *rateLimitedKeeper) HealthCheck(ctx context.Context) error {
+	return checkHealth(ctx, k.inner)
+}
@@ -0,0 +1,42 @@
+package keeper
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestRateLimitedKeeper(t *testing.T) {
+	inner := new(countingKeeper)
+	// A rate this low won't refill a token while the test runs.
+	k := NewRateLimitedKeeper(inner, 0.001, 3)
+
+	prvID, err := k.GeneratePrivateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	hash := crypto.Keccak256([]byte("rate limit"))
+	for i := 0; i < 3; i++ {
+		if _, err := k.Sign(hash, prvID); err != nil {
+			t.Fatalf("signature %d within burst: %v", i, err)
+		}
+	}
+	inner.calls = 0
+	_, err = k.Sign(hash, prvID)
+	if !errors.Is(err, ErrRateLimitExceeded) {
+		t.Fatalf("signature over limit: have %v, want %v", err, ErrRateLimitExceeded)
+	}
+	if inner.calls != 0 {
+		t.Fatalf("refused signature reached inner keeper: have %d calls", inner.calls)
+	}
+	// Key lookups have their own, larger budget.
+	for i := 0; i < 29; i++ {
+		if _, err := k.GetPublicKey(prvID); err != nil {
+			t.Fatalf("lookup %d within burst: %v", i, err)
+		}
+	}
+	if _, err := k.GetPublicKey(prvID); !errors.Is(err, ErrRateLimitExceeded) {
+		t.Fatalf("lookup over limit: have %v, want %v", err, ErrRateLimitExceeded)
+	}
+}
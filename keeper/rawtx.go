@@ -0,0 +1,62 @@
+package keeper
+
+import (
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// EncodeSignedTx returns the canonical encoding of the signed transaction tx,
+// the RLP list of legacy transactions and the type prefixed RLP list of typed
+// ones, as accepted by eth_sendRawTransaction. Blob transactions are encoded
+// with their sidecar if they carry one. Unsigned transactions fail with
+// ErrUnsignedTx.
+func EncodeSignedTx(tx *types.Transaction) (_ []byte, err error) {
+	defer wrapError(&err, "encode transaction", nil)
+
+	if !isSigned(tx) {
+		return nil, ErrUnsignedTx
+	}
+	return tx.MarshalBinary()
+}
+
+// DecodeSignedTx decodes a signed transaction encoded by EncodeSignedTx.
+// Unsigned transactions fail with ErrUnsignedTx.
+func DecodeSignedTx(data []byte) (_ *types.Transaction, err error) {
+	defer wrapError(&err, "decode transaction", nil)
+
+	tx := new(types.Transaction)
+	if err := tx.UnmarshalBinary(data); err != nil {
+		return nil, err
+	}
+	if !isSigned(tx) {
+		return nil, ErrUnsignedTx
+	}
+	return tx, nil
+}
+
+// EncodeSignedTxHex returns the encoding of EncodeSignedTx as 0x prefixed hex.
+func EncodeSignedTxHex(tx *types.Transaction) (string, error) {
+	data, err := EncodeSignedTx(tx)
+	if err != nil {
+		return "", err
+	}
+	return hexutil.Encode(data), nil
+}
+
+// DecodeSignedTxHex decodes a signed transaction encoded by EncodeSignedTxHex.
+// The 0x prefix is required.
+func DecodeSignedTxHex(s string) (_ *types.Transaction, err error) {
+	defer wrapError(&err, "decode transaction", nil)
+
+	data, err := hexutil.Decode(s)
+	if err != nil {
+		return nil, err
+	}
+	return DecodeSignedTx(data)
+}
+
+// isSigned reports whether tx carries a signature.
+func isSigned(tx *types.Transaction) bool {
+	v, r, s := tx.RawSignatureValues()
+	return !isZero(v) || !isZero(r) || !isZero(s)
+}
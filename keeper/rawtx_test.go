@@ -0,0 +1,94 @@
+package keeper
+
+import (
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto/kzg4844"
+	"github.com/holiman/uint256"
+)
+
+func TestEncodeSignedTx(t *testing.T) {
+	sec, prvID, _ := newTestSigner(t)
+
+	var (
+		blob    kzg4844.Blob
+		chainID = big.NewInt(1337)
+		to      = common.HexToAddress("0x0102030405060708090a0b0c0d0e0f1011121314")
+	)
+	commitment, _ := kzg4844.BlobToCommitment(&blob)
+	proof, _ := kzg4844.ComputeBlobProof(&blob, commitment)
+	sidecar := types.NewBlobTxSidecar(types.BlobSidecarVersion0, []kzg4844.Blob{blob}, []kzg4844.Commitment{commitment}, []kzg4844.Proof{proof})
+
+	legacy, err := sec.SignForChain(chainID, newTestTx(), prvID)
+	if err != nil {
+		t.Fatalf("failed to sign legacy tx: %v", err)
+	}
+	accessList, err := sec.SignAccessListTx(chainID, 2, to, big.NewInt(1), 30000, big.NewInt(5), types.AccessList{{Address: to, StorageKeys: []common.Hash{{1}}}}, nil, prvID)
+	if err != nil {
+		t.Fatalf("failed to sign access list tx: %v", err)
+	}
+	dynamicFee, err := sec.SignDynamicFeeTx(chainID, 3, to, big.NewInt(1), 21000, big.NewInt(10), big.NewInt(1), []byte{0xca, 0xfe}, prvID)
+	if err != nil {
+		t.Fatalf("failed to sign dynamic fee tx: %v", err)
+	}
+	blobTx, err := sec.SignBlobTx(chainID, &types.BlobTx{
+		Nonce:      4,
+		GasTipCap:  uint256.NewInt(1),
+		GasFeeCap:  uint256.NewInt(10),
+		Gas:        21000,
+		To:         to,
+		BlobFeeCap: uint256.NewInt(1),
+		BlobHashes: sidecar.BlobHashes(),
+		Sidecar:    sidecar,
+	}, prvID)
+	if err != nil {
+		t.Fatalf("failed to sign blob tx: %v", err)
+	}
+	for _, tx := range []*types.Transaction{legacy, accessList, dynamicFee, blobTx} {
+		data, err := EncodeSignedTx(tx)
+		if err != nil {
+			t.Fatalf("type %d: failed to encode: %v", tx.Type(), err)
+		}
+		decoded, err := DecodeSignedTx(data)
+		if err != nil {
+			t.Fatalf("type %d: failed to decode: %v", tx.Type(), err)
+		}
+		if decoded.Type() != tx.Type() || decoded.Hash() != tx.Hash() {
+			t.Fatalf("type %d: decoded mismatch: have type %d hash %v, want %v", tx.Type(), decoded.Type(), decoded.Hash(), tx.Hash())
+		}
+		if (decoded.BlobTxSidecar() == nil) != (tx.BlobTxSidecar() == nil) {
+			t.Fatalf("type %d: sidecar lost", tx.Type())
+		}
+		hex, err := EncodeSignedTxHex(tx)
+		if err != nil {
+			t.Fatalf("type %d: failed to encode hex: %v", tx.Type(), err)
+		}
+		if !strings.HasPrefix(hex, "0x") {
+			t.Fatalf("type %d: hex without prefix: %s", tx.Type(), hex[:8])
+		}
+		if decoded, err = DecodeSignedTxHex(hex); err != nil || decoded.Hash() != tx.Hash() {
+			t.Fatalf("type %d: hex round trip: have (%v, %v), want %v", tx.Type(), decoded, err, tx.Hash())
+		}
+	}
+	// Unsigned and malformed transactions are refused.
+	if _, err := EncodeSignedTx(newTestTx()); !errors.Is(err, ErrUnsignedTx) {
+		t.Fatalf("encoding unsigned tx: have %v, want %v", err, ErrUnsignedTx)
+	}
+	unsigned, _ := newTestTx().MarshalBinary()
+	if _, err := DecodeSignedTx(unsigned); !errors.Is(err, ErrUnsignedTx) {
+		t.Fatalf("decoding unsigned tx: have %v, want %v", err, ErrUnsignedTx)
+	}
+	data, _ := EncodeSignedTx(dynamicFee)
+	if _, err := DecodeSignedTx(data[:len(data)-1]); err == nil {
+		t.Fatal("decoded truncated tx")
+	}
+	hex, _ := EncodeSignedTxHex(dynamicFee)
+	if _, err := DecodeSignedTxHex(hex[2:]); err == nil {
+		t.Fatal("decoded hex without prefix")
+	}
+}
@@ -0,0 +1,101 @@
+package keeper
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/redis/go-redis/v9"
+)
+
+// NonceStore records the nonces, opaque byte strings, seen by a nonce tracking
+// signer.
+type NonceStore interface {
+	// Record stores the nonce, failing with ErrDuplicateSigning if it was
+	// recorded before. Recording has to be atomic, of concurrent calls with the
+	// same nonce only one may succeed.
+	Record(nonce []byte) error
+	// Has reports whether the nonce was recorded.
+	Has(nonce []byte) (bool, error)
+}
+
+// NewNonceTrackingSigner returns a SecureSigner signing every transaction with
+// inner at most once, failing with ErrDuplicateSigning for transactions seen
+// before. This protects against replays at the application level, like a
+// request being retried, independent of the chain nonce of the transactions.
+// The signing hash of a transaction, covering the chain ID of the signer, is
+// recorded in store before it is signed, so a transaction is not signed again
+// even if signing it failed. Hashes, messages, typed data, permits and user
+// operations fail with ErrPermissionDenied unless AllowRawSigning is given.
+func NewNonceTrackingSigner(inner SecureSigner, store NonceStore, opts ...GuardOption) SecureSigner {
+	return newGuardSigner(inner, func(ctx context.Context, tx *types.Transaction, signer types.Signer) error {
+		return store.Record(signer.Hash(tx).Bytes())
+	}, opts)
+}
+
+// memoryNonceStore is a NonceStore holding the nonces in memory.
+type memoryNonceStore struct {
+	lock   sync.Mutex
+	nonces map[string]struct{}
+}
+
+// MemoryNonceStore returns a NonceStore holding the nonces in memory, for a
+// single process. The nonces are lost when the process exits.
+func MemoryNonceStore() NonceStore {
+	return &memoryNonceStore{nonces: make(map[string]struct{})}
+}
+
+func (s *memoryNonceStore) Record(nonce []byte) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if _, ok := s.nonces[string(nonce)]; ok {
+		return ErrDuplicateSigning
+	}
+	s.nonces[string(nonce)] = struct{}{}
+	return nil
+}
+
+func (s *memoryNonceStore) Has(nonce []byte) (bool, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	_, ok := s.nonces[string(nonce)]
+	return ok, nil
+}
+
+// redisNoncePrefix is the prefix of the Redis keys of the recorded nonces.
+const redisNoncePrefix = "keeper:nonce:"
+
+// redisNonceStore is a NonceStore holding the nonces in Redis.
+type redisNonceStore struct {
+	client *redis.Client
+}
+
+// RedisNonceStore returns a NonceStore holding the nonces in Redis, shared by
+// the processes using the same server. Nonces are recorded with SETNX, making
+// the check and the record a single atomic step, and are kept without expiry.
+func RedisNonceStore(client *redis.Client) NonceStore {
+	return &redisNonceStore{client: client}
+}
+
+func (s *redisNonceStore) Record(nonce []byte) error {
+	ok, err := s.client.SetNX(context.Background(), redisNoncePrefix+hexutil.Encode(nonce), 1, 0).Result()
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrBackendUnavailable, err)
+	}
+	if !ok {
+		return ErrDuplicateSigning
+	}
+	return nil
+}
+
+func (s *redisNonceStore) Has(nonce []byte) (bool, error) {
+	n, err := s.client.Exists(context.Background(), redisNoncePrefix+hexutil.Encode(nonce)).Result()
+	if err != nil {
+		return false, fmt.Errorf("%w: %w", ErrBackendUnavailable, err)
+	}
+	return n > 0, nil
+}
@@ -0,0 +1,96 @@
+package keeper
+
+import (
+	"errors"
+	"math/big"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestNonceTrackingSigner(t *testing.T) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	defer client.Close()
+
+	stores := map[string]NonceStore{
+		"memory": MemoryNonceStore(),
+		"redis":  RedisNonceStore(client),
+	}
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			testNonceTrackingSigner(t, store)
+		})
+	}
+}
+
+func TestNonceTrackingSignerRawSigning(t *testing.T) {
+	checkRawSigningGuarded(t, func(inner SecureSigner, opts ...GuardOption) SecureSigner {
+		return NewNonceTrackingSigner(inner, MemoryNonceStore(), opts...)
+	})
+}
+
+func testNonceTrackingSigner(t *testing.T, store NonceStore) {
+	s := NewNonceTrackingSigner(NewSecureSigner(new(defaultPrivateKeyKeeper)), store)
+	prvID, err := s.GenerateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	signer := types.LatestSignerForChainID(big.NewInt(1))
+	if _, err := s.Sign(newTestTx(), signer, prvID); err != nil {
+		t.Fatalf("failed to sign: %v", err)
+	}
+	if ok, err := store.Has(signer.Hash(newTestTx()).Bytes()); err != nil || !ok {
+		t.Fatalf("signed transaction not recorded: (%v, %v)", ok, err)
+	}
+	if _, err := s.Sign(newTestTx(), signer, prvID); !errors.Is(err, ErrDuplicateSigning) {
+		t.Fatalf("second signing: have %v, want %v", err, ErrDuplicateSigning)
+	}
+	// The same transaction for another chain has another signing hash.
+	if _, err := s.Sign(newTestTx(), types.LatestSignerForChainID(big.NewInt(5)), prvID); err != nil {
+		t.Fatalf("failed to sign for other chain: %v", err)
+	}
+
+	// Of concurrent duplicates exactly one is signed.
+	tx := newTestBatch(2)[0]
+	var (
+		wg     sync.WaitGroup
+		signed atomic.Int32
+	)
+	for range 16 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := s.Sign(tx, signer, prvID)
+			switch {
+			case err == nil:
+				signed.Add(1)
+			case !errors.Is(err, ErrDuplicateSigning):
+				t.Errorf("concurrent signing: have %v, want %v", err, ErrDuplicateSigning)
+			}
+		}()
+	}
+	wg.Wait()
+	if n := signed.Load(); n != 1 {
+		t.Fatalf("concurrent duplicates signed: have %d, want 1", n)
+	}
+}
+
+func TestRedisNonceStoreUnavailable(t *testing.T) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr(), MaxRetries: -1})
+	defer client.Close()
+	store := RedisNonceStore(client)
+	server.Close()
+
+	if err := store.Record([]byte{1}); !errors.Is(err, ErrBackendUnavailable) {
+		t.Fatalf("record: have %v, want %v", err, ErrBackendUnavailable)
+	}
+	if _, err := store.Has([]byte{1}); !errors.Is(err, ErrBackendUnavailable) {
+		t.Fatalf("has: have %v, want %v", err, ErrBackendUnavailable)
+	}
+}
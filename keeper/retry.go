@@ -0,0 +1,196 @@
+package keeper
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+)
+
+// RetryBackoff is the delay policy of a retrying keeper.
+type RetryBackoff interface {
+	// Wait returns the delay before retrying a call whose attempt, counted from
+	// 1, failed
+	Wait(attempt int) time.Duration
+}
+
+// exponentialBackoff doubles the delay after every attempt, up to max.
+type exponentialBackoff struct {
+	base, max time.Duration
+}
+
+// ExponentialBackoff returns a RetryBackoff waiting base after the first failed
+// attempt and twice as long after every further one, but never more than max.
+func ExponentialBackoff(base, max time.Duration) RetryBackoff {
+	return &exponentialBackoff{base: base, max: max}
+}
+
+func (b *exponentialBackoff) Wait(attempt int) time.Duration {
+	delay := b.base
+	for i := 1; i < attempt && delay < b.max; i++ {
+		delay *= 2
+	}
+	return min(delay, b.max)
+}
+
+// fixedBackoff waits the same delay after every attempt.
+type fixedBackoff time.Duration
+
+// FixedBackoff returns a RetryBackoff waiting d after every failed attempt.
+func FixedBackoff(d time.Duration) RetryBackoff {
+	return fixedBackoff(d)
+}
+
+func (b fixedBackoff) Wait(int) time.Duration {
+	return time.Duration(b)
+}
+
+// retryingKeeper is a PrivateKeyKeeper retrying the calls to another keeper
+// that fail as its backend is unavailable.
+type retryingKeeper struct {
+	inner    PrivateKeyKeeperContext
+	attempts int
+	backoff  RetryBackoff
+	logger   *slog.Logger // nil if retries aren't logged
+}
+
+// RetryOption configures a retrying keeper.
+type RetryOption func(*retryingKeeper)
+
+// WithRetryLogger logs every retry to logger as a warning, with the operation,
+// the key_id_prefix of the metrics, the attempt, the delay and the error of the
+// failed attempt.
+func WithRetryLogger(logger *slog.Logger) RetryOption {
+	return func(k *retryingKeeper) {
+		k.logger = logger
+	}
+}
+
+// NewRetryingKeeper returns a PrivateKeyKeeper making up to maxAttempts calls to
+// inner for every call, waiting as told by backoff between them. Only failures
+// reported as ErrBackendUnavailable are retried, all other errors are returned
+// right away, as is the error of the last attempt. A context done while waiting
+// aborts the call with the error of the context.
+//
+// Generating or importing a key is retried as well. If a failed attempt did
+// create the key in the backend, e.g. as the response was lost, that key is left
+// behind unused.
+func NewRetryingKeeper(inner PrivateKeyKeeper, maxAttempts int, backoff RetryBackoff, opts ...RetryOption) PrivateKeyKeeper {
+	k := &retryingKeeper{inner: ContextKeeper(inner), attempts: max(maxAttempts, 1), backoff: backoff}
+	for _, opt := range opts {
+		opt(k)
+	}
+	return k
+}
+
+// retry runs fn, the operation op on the key prvID, until it doesn't fail with
+// ErrBackendUnavailable, the attempts are used up or ctx is done.
+func (k *retryingKeeper) retry(ctx context.Context, op string, prvID []byte, fn func() error) error {
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || !errors.Is(err, ErrBackendUnavailable) || attempt == k.attempts {
+			return err
+		}
+		wait := k.backoff.Wait(attempt)
+		if k.logger != nil {
+			attrs := []any{"op", op, "attempt", attempt, "wait", wait, "err", err}
+			if prvID != nil {
+				attrs = append(attrs, "key_id", keyIDPrefix(prvID))
+			}
+			k.logger.Warn("Retrying keeper call", attrs...)
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+func (k *retryingKeeper) GeneratePrivateKey() ([]byte, error) {
+	return k.GeneratePrivateKeyContext(context.Background())
+}
+
+func (k *retryingKeeper) GeneratePrivateKeyContext(ctx context.Context) (prvID []byte, err error) {
+	defer wrapError(&err, "generate key", nil)
+
+	err = k.retry(ctx, "generate key", nil, func() (err error) {
+		prvID, err = k.inner.GeneratePrivateKeyContext(ctx)
+		return err
+	})
+	return prvID, err
+}
+
+func (k *retryingKeeper) GetPublicKey(prvID []byte) ([]byte, error) {
+	return k.GetPublicKeyContext(context.Background(), prvID)
+}
+
+func (k *retryingKeeper) GetPublicKeyContext(ctx context.Context, prvID []byte) (pub []byte, err error) {
+	defer wrapError(&err, "get public key", prvID)
+
+	err = k.retry(ctx, "get public key", prvID, func() (err error) {
+		pub, err = k.inner.GetPublicKeyContext(ctx, prvID)
+		return err
+	})
+	return pub, err
+}
+
+func (k *retryingKeeper) Sign(data []byte, prvID []byte) ([]byte, error) {
+	return k.SignContext(context.Background(), data, prvID)
+}
+
+func (k *retryingKeeper) SignContext(ctx context.Context, data []byte, prvID []byte) (sig []byte, err error) {
+	defer wrapError(&err, "sign", prvID)
+
+	err = k.retry(ctx, "sign", prvID, func() (err error) {
+		sig, err = k.inner.SignContext(ctx, data, prvID)
+		return err
+	})
+	return sig, err
+}
+
+func (k *retryingKeeper) DeletePrivateKey(prvID []byte) error {
+	return k.DeletePrivateKeyContext(context.Background(), prvID)
+}
+
+func (k *retryingKeeper) DeletePrivateKeyContext(ctx context.Context, prvID []byte) (err error) {
+	defer wrapError(&err, "delete key", prvID)
+
+	return k.retry(ctx, "delete key", prvID, func() error {
+		return k.inner.DeletePrivateKeyContext(ctx, prvID)
+	})
+}
+
+func (k *retryingKeeper) ListPrivateKeys() ([][]byte, error) {
+	return k.ListPrivateKeysContext(context.Background())
+}
+
+func (k *retryingKeeper) ListPrivateKeysContext(ctx context.Context) (prvIDs [][]byte, err error) {
+	defer wrapError(&err, "list keys", nil)
+
+	err = k.retry(ctx, "list keys", nil, func() (err error) {
+		prvIDs, err = k.inner.ListPrivateKeysContext(ctx)
+		return err
+	})
+	return prvIDs, err
+}
+
+func (k *retryingKeeper) ImportPrivateKey(rawKey []byte) ([]byte, error) {
+	return k.ImportPrivateKeyContext(context.Background(), rawKey)
+}
+
+func (k *retryingKeeper) ImportPrivateKeyContext(ctx context.Context, rawKey []byte) (prvID []byte, err error) {
+	defer wrapError(&err, "import key", nil)
+
+	err = k.retry(ctx, "import key", nil, func() (err error) {
+		prvID, err = k.inner.ImportPrivateKeyContext(ctx, rawKey)
+		return err
+	})
+	return prvID, err
+}
+
+func (k *retryingKeeper) HealthCheck(ctx context.Context) error {
+	return checkHealth(ctx, k.inner)
+}
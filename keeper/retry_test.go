@@ -0,0 +1,129 @@
+package keeper
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+// flakyKeeper is a PrivateKeyKeeper failing its first signatures with err.
+type flakyKeeper struct {
+	PrivateKeyKeeper
+	failures int
+	err      error
+	calls    int
+}
+
+func (k *flakyKeeper) Sign(data []byte, prvID []byte) ([]byte, error) {
+	k.calls++
+	if k.calls <= k.failures {
+		return nil, k.err
+	}
+	return k.PrivateKeyKeeper.Sign(data, prvID)
+}
+
+func TestRetryingKeeper(t *testing.T) {
+	unavailable := fmt.Errorf("%w: connection refused", ErrBackendUnavailable)
+	tests := []struct {
+		failures, attempts int
+		err                error
+		calls              int
+		want               error
+	}{
+		{failures: 2, attempts: 3, err: unavailable, calls: 3},
+		{failures: 3, attempts: 3, err: unavailable, calls: 3, want: ErrBackendUnavailable},
+		{failures: 1, attempts: 0, err: unavailable, calls: 1, want: ErrBackendUnavailable},
+		{failures: 1, attempts: 3, err: ErrKeyNotFound, calls: 1, want: ErrKeyNotFound},
+		{failures: 1, attempts: 3, err: ErrPermissionDenied, calls: 1, want: ErrPermissionDenied},
+	}
+	hash := make([]byte, 32)
+	for i, tt := range tests {
+		inner := &flakyKeeper{PrivateKeyKeeper: new(defaultPrivateKeyKeeper), failures: tt.failures, err: tt.err}
+		k := NewRetryingKeeper(inner, tt.attempts, FixedBackoff(time.Millisecond))
+		prvID, err := k.GeneratePrivateKey()
+		if err != nil {
+			t.Fatalf("test %d: failed to generate key: %v", i, err)
+		}
+		_, err = k.Sign(hash, prvID)
+		if tt.want == nil && err != nil {
+			t.Fatalf("test %d: failed to sign: %v", i, err)
+		}
+		if tt.want != nil && !errors.Is(err, tt.want) {
+			t.Fatalf("test %d: have %v, want %v", i, err, tt.want)
+		}
+		if inner.calls != tt.calls {
+			t.Fatalf("test %d: calls: have %d, want %d", i, inner.calls, tt.calls)
+		}
+	}
+}
+
+func TestRetryingKeeperLog(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+	inner := &flakyKeeper{PrivateKeyKeeper: new(defaultPrivateKeyKeeper), failures: 2, err: fmt.Errorf("%w: timeout", ErrBackendUnavailable)}
+	k := NewRetryingKeeper(inner, 3, FixedBackoff(time.Millisecond), WithRetryLogger(logger))
+	prvID, err := k.GeneratePrivateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	if _, err := k.Sign(make([]byte, 32), prvID); err != nil {
+		t.Fatalf("failed to sign: %v", err)
+	}
+	records := logRecords(t, &buf)
+	if len(records) != 2 {
+		t.Fatalf("log records: have %d, want 2", len(records))
+	}
+	for i, rec := range records {
+		if rec["level"] != "WARN" || rec["op"] != "sign" || rec["attempt"] != float64(i+1) || rec["key_id"] != keyIDPrefix(prvID) {
+			t.Errorf("record %d mismatch: %v", i, rec)
+		}
+	}
+	checkKeyNotLogged(t, &buf, prvID)
+}
+
+func TestRetryingKeeperCancel(t *testing.T) {
+	inner := &flakyKeeper{PrivateKeyKeeper: new(defaultPrivateKeyKeeper), failures: 1, err: ErrBackendUnavailable}
+	k := NewRetryingKeeper(inner, 3, FixedBackoff(time.Hour)).(PrivateKeyKeeperContext)
+	prvID, _ := k.GeneratePrivateKeyContext(context.Background())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+	done := make(chan error, 1)
+	go func() {
+		_, err := k.SignContext(ctx, make([]byte, 32), prvID)
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("have %v, want %v", err, context.Canceled)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("backoff not aborted by cancellation")
+	}
+	if inner.calls != 1 {
+		t.Fatalf("calls: have %d, want 1", inner.calls)
+	}
+}
+
+func TestExponentialBackoff(t *testing.T) {
+	b := ExponentialBackoff(100*time.Millisecond, time.Second)
+	for attempt, want := range map[int]time.Duration{
+		1:  100 * time.Millisecond,
+		2:  200 * time.Millisecond,
+		4:  800 * time.Millisecond,
+		5:  time.Second,
+		64: time.Second,
+	} {
+		if have := b.Wait(attempt); have != want {
+			t.Fatalf("attempt %d: have %v, want %v", attempt, have, want)
+		}
+	}
+	if have := FixedBackoff(time.Second).Wait(7); have != time.Second {
+		t.Fatalf("fixed backoff: have %v, want %v", have, time.Second)
+	}
+}
@@ -0,0 +1,81 @@
+package keeper
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// RotatableKeeper is a PrivateKeyKeeper supporting the rotation of keys with a
+// transition window, during which both the old and the new key of a rotation
+// are valid. This lets signatures of the old key be replaced by ones of the new
+// key before the old one is destroyed:
+//
+//	newPrvID, err := k.RotateKey(oldPrvID)
+//	// ... re-sign with newPrvID, publish its address ...
+//	err = k.CompleteRotation(oldPrvID)
+//
+// The pending rotations are kept in memory. If the keeper is restarted in the
+// transition window, both keys are left in place and the old key has to be
+// deleted with DeletePrivateKey.
+type RotatableKeeper interface {
+	PrivateKeyKeeper
+
+	// RotateKey generates the key replacing oldPrvID and returns its identifier.
+	// Both keys stay valid until the rotation is completed.
+	RotateKey(oldPrvID []byte) (newPrvID []byte, err error)
+	// CompleteRotation ends the rotation of oldPrvID by deleting the old key. If
+	// it fails, the old key and the rotation are kept and the call may be
+	// retried.
+	CompleteRotation(oldPrvID []byte) error
+}
+
+// keyRotations tracks the rotations in the transition window of a keeper. The
+// lock is held for whole rotation steps, so a key can't be rotated twice.
+type keyRotations struct {
+	lock    sync.Mutex
+	pending map[string][]byte // old prvID -> new prvID
+}
+
+// rotate generates the key replacing oldPrvID in k.
+func (r *keyRotations) rotate(k PrivateKeyKeeper, oldPrvID []byte) ([]byte, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if _, ok := r.pending[string(oldPrvID)]; ok {
+		return nil, ErrRotationInProgress
+	}
+	if _, err := k.GetPublicKey(oldPrvID); err != nil {
+		return nil, err
+	}
+	newPrvID, err := k.GeneratePrivateKey()
+	if err != nil {
+		return nil, err
+	}
+	if r.pending == nil {
+		r.pending = make(map[string][]byte)
+	}
+	r.pending[string(oldPrvID)] = newPrvID
+	return newPrvID, nil
+}
+
+// complete deletes oldPrvID from k once the key replacing it is known to be
+// usable. The rotation is only forgotten after the old key is gone.
+func (r *keyRotations) complete(k PrivateKeyKeeper, oldPrvID []byte) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	newPrvID, ok := r.pending[string(oldPrvID)]
+	if !ok {
+		return ErrNoRotation
+	}
+	if _, err := k.GetPublicKey(newPrvID); err != nil {
+		return fmt.Errorf("replacing key unusable: %w", err)
+	}
+	// A key deleted behind our back needs no deletion anymore.
+	if err := k.DeletePrivateKey(oldPrvID); err != nil && !errors.Is(err, ErrKeyNotFound) {
+		return err
+	}
+	delete(r.pending, string(oldPrvID))
+	return nil
+}
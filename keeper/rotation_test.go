@@ -0,0 +1,127 @@
+package keeper
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// checkUsable checks that k signs with prvID.
+func checkUsable(t *testing.T, k PrivateKeyKeeper, prvID []byte) {
+	t.Helper()
+
+	pub, err := k.GetPublicKey(prvID)
+	if err != nil {
+		t.Fatalf("key %q unusable: %v", prvID, err)
+	}
+	hash := crypto.Keccak256(prvID)
+	sig, err := k.Sign(hash, prvID)
+	if err != nil {
+		t.Fatalf("key %q failed to sign: %v", prvID, err)
+	}
+	if recovered, err := crypto.Ecrecover(hash, sig); err != nil || !bytes.Equal(recovered, pub) {
+		t.Fatalf("key %q signature mismatch: have (%x, %v), want %x", prvID, recovered, err, pub)
+	}
+}
+
+func TestKeystoreKeyRotation(t *testing.T) {
+	dir := t.TempDir()
+	k := NewKeystoreKeeper(dir, keystore.LightScryptN, keystore.LightScryptP, MemoryPassphraseProvider("foo")).(RotatableKeeper)
+
+	if _, err := k.RotateKey([]byte("8f2bd0a2-54a4-4bb1-8ae0-d7c9f5a7b0e3")); !errors.Is(err, ErrKeyNotFound) {
+		t.Fatalf("rotating missing key: have %v, want %v", err, ErrKeyNotFound)
+	}
+	oldPrvID, err := k.GeneratePrivateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	if err := k.CompleteRotation(oldPrvID); !errors.Is(err, ErrNoRotation) {
+		t.Fatalf("completing unstarted rotation: have %v, want %v", err, ErrNoRotation)
+	}
+	newPrvID, err := k.RotateKey(oldPrvID)
+	if err != nil {
+		t.Fatalf("failed to rotate key: %v", err)
+	}
+	// In the transition window both keys are valid, and the key can't be
+	// rotated again.
+	checkUsable(t, k, oldPrvID)
+	checkUsable(t, k, newPrvID)
+	if _, err := k.RotateKey(oldPrvID); !errors.Is(err, ErrRotationInProgress) {
+		t.Fatalf("rotating key twice: have %v, want %v", err, ErrRotationInProgress)
+	}
+	if ids, _ := k.ListPrivateKeys(); len(ids) != 2 {
+		t.Fatalf("keys in transition window: have %d, want 2", len(ids))
+	}
+
+	// A failed completion keeps the old key and the rotation.
+	path, err := k.(*keystoreKeeper).find(newPrvID)
+	if err != nil {
+		t.Fatalf("failed to find new key file: %v", err)
+	}
+	keyjson, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read new key file: %v", err)
+	}
+	os.Remove(path)
+	if err := k.CompleteRotation(oldPrvID); err == nil {
+		t.Fatal("completed rotation to missing key")
+	}
+	checkUsable(t, k, oldPrvID)
+	if err := os.WriteFile(path, keyjson, 0600); err != nil {
+		t.Fatalf("failed to restore new key file: %v", err)
+	}
+
+	if err := k.CompleteRotation(oldPrvID); err != nil {
+		t.Fatalf("failed to complete rotation: %v", err)
+	}
+	if _, err := k.GetPublicKey(oldPrvID); !errors.Is(err, ErrKeyNotFound) {
+		t.Fatalf("rotated key: have %v, want %v", err, ErrKeyNotFound)
+	}
+	checkUsable(t, k, newPrvID)
+	if err := k.CompleteRotation(oldPrvID); !errors.Is(err, ErrNoRotation) {
+		t.Fatalf("completing rotation twice: have %v, want %v", err, ErrNoRotation)
+	}
+	// The new key can be rotated in turn.
+	if _, err := k.RotateKey(newPrvID); err != nil {
+		t.Fatalf("failed to rotate new key: %v", err)
+	}
+}
+
+func TestVaultKeyRotation(t *testing.T) {
+	keeper, transit := newTestVaultKeeper(t)
+	k := keeper.(RotatableKeeper)
+
+	oldPrvID, err := k.GeneratePrivateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	newPrvID, err := k.RotateKey(oldPrvID)
+	if err != nil {
+		t.Fatalf("failed to rotate key: %v", err)
+	}
+	checkUsable(t, k, oldPrvID)
+	checkUsable(t, k, newPrvID)
+
+	// A failed deletion leaves the old key in place, protected from deletion.
+	transit.failDelete = true
+	if err := k.CompleteRotation(oldPrvID); !errors.Is(err, ErrBackendUnavailable) {
+		t.Fatalf("completing rotation on sealed vault: have %v, want %v", err, ErrBackendUnavailable)
+	}
+	if transit.deletable[string(oldPrvID)] {
+		t.Fatal("old key left deletable")
+	}
+	checkUsable(t, k, oldPrvID)
+
+	transit.failDelete = false
+	if err := k.CompleteRotation(oldPrvID); err != nil {
+		t.Fatalf("failed to complete rotation: %v", err)
+	}
+	if _, ok := transit.keys[string(oldPrvID)]; ok {
+		t.Fatalf("key %q not deleted in transit", oldPrvID)
+	}
+	checkUsable(t, k, newPrvID)
+}
@@ -0,0 +1,145 @@
+package keeper
+
+import (
+	"crypto/ecdsa"
+	"fmt"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4/schnorr"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// SchnorrSigner is implemented by the keepers able to make Schnorr signatures
+// with their secp256k1 keys, next to the ECDSA ones of Sign. These are the
+// keepers with access to the key material, whose keys are unlocked in the
+// process; KMS and HSM backends only offer ECDSA. The prvIDs are the same as
+// for Sign.
+//
+// The scheme is EC-Schnorr-DCRv0 as implemented by the schnorr package of dcrd,
+// which signs 32 byte hashes with deterministic RFC 6979 nonces into 64 byte
+// [R || S] signatures. It is not BIP-340.
+type SchnorrSigner interface {
+	// SignSchnorr return Schnorr signature of the 32 byte hash data by private key ID
+	SignSchnorr(data []byte, prvID []byte) ([]byte, error)
+	// VerifySchnorr check that sig is a Schnorr signature of the hash data made by
+	// the key of the 33 byte compressed or 65 byte uncompressed public key
+	VerifySchnorr(data, sig []byte, pubKey []byte) (bool, error)
+}
+
+// signSchnorr makes the Schnorr signature of hash with key.
+func signSchnorr(hash []byte, key *ecdsa.PrivateKey) ([]byte, error) {
+	secret := crypto.FromECDSA(key)
+	defer zeroBytes(secret)
+	prv := secp256k1.PrivKeyFromBytes(secret)
+	defer prv.Zero()
+
+	sig, err := schnorr.Sign(prv, hash)
+	if err != nil {
+		return nil, err
+	}
+	return sig.Serialize(), nil
+}
+
+// verifySchnorr implements VerifySchnorr. A well-formed signature of another key
+// or hash is reported as false without an error.
+func verifySchnorr(hash, sig, pubKey []byte) (bool, error) {
+	if len(hash) != 32 {
+		return false, fmt.Errorf("hash must be 32 bytes long (%d)", len(hash))
+	}
+	pub, err := secp256k1.ParsePubKey(pubKey)
+	if err != nil {
+		return false, fmt.Errorf("invalid public key: %v", err)
+	}
+	s, err := schnorr.ParseSignature(sig)
+	if err != nil {
+		return false, fmt.Errorf("%w: %v", ErrInvalidSignature, err)
+	}
+	return s.Verify(hash, pub), nil
+}
+
+// SignSchnorr signs data with the key prvID, which is the raw key itself.
+func (a *defaultPrivateKeyKeeper) SignSchnorr(data []byte, prvID []byte) (_ []byte, err error) {
+	defer wrapError(&err, "sign schnorr", prvID)
+
+	key, err := crypto.ToECDSA(prvID)
+	if err != nil {
+		return nil, err
+	}
+	defer ZeroKey(key)
+	return signSchnorr(data, key)
+}
+
+func (a *defaultPrivateKeyKeeper) VerifySchnorr(data, sig []byte, pubKey []byte) (_ bool, err error) {
+	defer wrapError(&err, "verify schnorr", nil)
+	return verifySchnorr(data, sig, pubKey)
+}
+
+func (k *fixedKeeper) SignSchnorr(data []byte, prvID []byte) (_ []byte, err error) {
+	defer wrapError(&err, "sign schnorr", prvID)
+
+	key, err := k.key(prvID)
+	if err != nil {
+		return nil, err
+	}
+	return k.inner.SignSchnorr(data, key)
+}
+
+func (k *fixedKeeper) VerifySchnorr(data, sig []byte, pubKey []byte) (bool, error) {
+	return k.inner.VerifySchnorr(data, sig, pubKey)
+}
+
+// SignSchnorr decrypts the key file of prvID for the duration of the signature.
+func (k *keystoreKeeper) SignSchnorr(data []byte, prvID []byte) (_ []byte, err error) {
+	defer wrapError(&err, "sign schnorr", prvID)
+
+	key, err := k.decrypt(prvID)
+	if err != nil {
+		return nil, err
+	}
+	defer ZeroKey(key.PrivateKey)
+	return signSchnorr(data, key.PrivateKey)
+}
+
+func (k *keystoreKeeper) VerifySchnorr(data, sig []byte, pubKey []byte) (_ bool, err error) {
+	defer wrapError(&err, "verify schnorr", nil)
+	return verifySchnorr(data, sig, pubKey)
+}
+
+func (k *keyringKeeper) SignSchnorr(data []byte, prvID []byte) (_ []byte, err error) {
+	defer wrapError(&err, "sign schnorr", prvID)
+
+	key, err := k.load(prvID)
+	if err != nil {
+		return nil, err
+	}
+	defer ZeroKey(key)
+	return signSchnorr(data, key)
+}
+
+func (k *keyringKeeper) VerifySchnorr(data, sig []byte, pubKey []byte) (_ bool, err error) {
+	defer wrapError(&err, "verify schnorr", nil)
+	return verifySchnorr(data, sig, pubKey)
+}
+
+// SignSchnorr unseals the key prvID for the duration of the signature.
+func (k *tpmKeeper) SignSchnorr(data []byte, prvID []byte) (_ []byte, err error) {
+	defer wrapError(&err, "sign schnorr", prvID)
+
+	secret, err := k.unseal(prvID)
+	if err != nil {
+		return nil, err
+	}
+	defer zeroBytes(secret)
+
+	key, err := crypto.ToECDSA(secret)
+	if err != nil {
+		return nil, err
+	}
+	defer ZeroKey(key)
+	return signSchnorr(data, key)
+}
+
+func (k *tpmKeeper) VerifySchnorr(data, sig []byte, pubKey []byte) (_ bool, err error) {
+	defer wrapError(&err, "verify schnorr", nil)
+	return verifySchnorr(data, sig, pubKey)
+}
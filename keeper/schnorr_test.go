@@ -0,0 +1,106 @@
+package keeper
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/zalando/go-keyring"
+)
+
+func TestSignSchnorrVectors(t *testing.T) {
+	// EC-Schnorr-DCRv0 test vectors of dcrd, for RFC 6979 nonces.
+	tests := []struct {
+		key, hash, sig string
+	}{
+		{
+			"0000000000000000000000000000000000000000000000000000000000000001",
+			"c301ba9de5d6053caad9f5eb46523f007702add2c62fa39de03146a36b8026b7",
+			"4c68976afe187ff0167919ad181cb30f187e2af1c8233b2cbebbbe0fc97fff61e9ae2d0e306497236d4e328dc1a34244045745e87da69d806859348bc2a74525",
+		},
+		{
+			"0000000000000000000000000000000000000000000000000000000000000002",
+			"c301ba9de5d6053caad9f5eb46523f007702add2c62fa39de03146a36b8026b7",
+			"c6deb3a26c08842612bfd4411a91c90f64cfea2206c758cd1352ff2b93cc3611c9ffe5dd240f52d3ee199e29373030a5d795b674cd4da991fd07f5edefc3817d",
+		},
+		{
+			"0000000000000000000000000000000000000000000000000000000000000001",
+			"dc063eba3c8d52a159e725c1a161506f6cb6b53478ad5ef3f08d534efa871d9f",
+			"461646005002d673c2e903f3c9ff2c2455e60810445ee486b9c36152287bc41a1b54733190ed128e466c5263a404f17344b73426d7faf00325c7a0af04be6cfe",
+		},
+		{
+			"0000000000000000000000000000000000000000000000000000000000000002",
+			"dc063eba3c8d52a159e725c1a161506f6cb6b53478ad5ef3f08d534efa871d9f",
+			"f3632492a72eb8e175b93e1eb31ef382e49f3f3fe385892523beaef9171aa15d441e1a94ab9b1dafa93e0d48d08c26513d53449197e761c74bebb2fae97525c3",
+		},
+		{
+			"a1becef2069444a9dc6331c3247e113c3ee142edda683db8643f9cb0af7cbe33",
+			"4a6c419a1e25c85327115c4ace586decddfe2990ed8f3d4d801871158338501d",
+			"0b89d1fb10635e4a5da463c7339fd0f8d2e7d205a8288d4f973635beb8b59f7fe7c69c94ac665d14c105c2b4ba3b4c59a7819f8ecfe0d9f5f0c93a9f6d7ef447",
+		},
+	}
+	k := new(defaultPrivateKeyKeeper)
+	for i, tt := range tests {
+		prvID, hash, want := common.FromHex(tt.key), common.FromHex(tt.hash), common.FromHex(tt.sig)
+		sig, err := k.SignSchnorr(hash, prvID)
+		if err != nil {
+			t.Fatalf("test %d: failed to sign: %v", i, err)
+		}
+		if !bytes.Equal(sig, want) {
+			t.Fatalf("test %d: signature mismatch: have %x, want %x", i, sig, want)
+		}
+		pub, _ := k.GetPublicKey(prvID)
+		if ok, err := k.VerifySchnorr(hash, want, pub); err != nil || !ok {
+			t.Fatalf("test %d: vector not verified: (%v, %v)", i, ok, err)
+		}
+	}
+}
+
+func TestSchnorrSigner(t *testing.T) {
+	keyring.MockInit()
+	key, _ := GenerateDeterministicKey([]byte("schnorr"))
+	keepers := map[string]PrivateKeyKeeper{
+		"default":  new(defaultPrivateKeyKeeper),
+		"hd":       NewHDKeeper(),
+		"fixed":    FixedKeeper(map[string][]byte{"alice": key}),
+		"keystore": NewKeystoreKeeper(t.TempDir(), keystore.LightScryptN, keystore.LightScryptP, MemoryPassphraseProvider("foo")),
+		"keyring":  NewKeyringKeeper("keeper-test"),
+	}
+	hash := sha256.Sum256([]byte("schnorr"))
+	for name, k := range keepers {
+		s, ok := k.(SchnorrSigner)
+		if !ok {
+			t.Fatalf("%s: not a SchnorrSigner", name)
+		}
+		prvID, err := k.GeneratePrivateKey()
+		if err != nil {
+			t.Fatalf("%s: failed to generate key: %v", name, err)
+		}
+		sig, err := s.SignSchnorr(hash[:], prvID)
+		if err != nil {
+			t.Fatalf("%s: failed to sign: %v", name, err)
+		}
+		pub, _ := k.GetPublicKey(prvID)
+		pubKey, _ := ParsePublicKey(pub)
+		for _, form := range [][]byte{pub, crypto.CompressPubkey(pubKey)} {
+			if ok, err := s.VerifySchnorr(hash[:], sig, form); err != nil || !ok {
+				t.Fatalf("%s: signature not verified with %d byte key: (%v, %v)", name, len(form), ok, err)
+			}
+		}
+		// Same as for ECDSA, a signature of another hash is invalid, not an error.
+		other := sha256.Sum256([]byte("other"))
+		if ok, err := s.VerifySchnorr(other[:], sig, pub); err != nil || ok {
+			t.Fatalf("%s: signature of other hash: have (%v, %v), want false", name, ok, err)
+		}
+		if _, err := s.VerifySchnorr(hash[:], sig[:63], pub); !errors.Is(err, ErrInvalidSignature) {
+			t.Fatalf("%s: truncated signature: have %v, want %v", name, err, ErrInvalidSignature)
+		}
+		if _, err := s.SignSchnorr(hash[:31], prvID); err == nil {
+			t.Fatalf("%s: signed short hash", name)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package keeper
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// ErrSenderMismatch is returned by VerifyTxSender for transactions signed by
+// another account than expected.
+type ErrSenderMismatch struct {
+	Got      common.Address // recovered sender of the transaction
+	Expected common.Address // sender the transaction was expected from
+}
+
+func (e *ErrSenderMismatch) Error() string {
+	return fmt.Sprintf("transaction sender %v, expected %v", e.Got.Hex(), e.Expected.Hex())
+}
+
+// RecoverSenderFromTx returns the address of the key that signed tx, recovered
+// with s. Transactions without a signature fail with ErrUnsignedTx, ones whose
+// signature doesn't recover, e.g. as it was made for another chain or type of
+// signer, with ErrInvalidSignature.
+func RecoverSenderFromTx(tx *types.Transaction, s types.Signer) (_ common.Address, err error) {
+	defer wrapError(&err, "recover sender", nil)
+
+	if !isSigned(tx) {
+		return common.Address{}, ErrUnsignedTx
+	}
+	from, err := types.Sender(s, tx)
+	if err != nil {
+		return common.Address{}, fmt.Errorf("%w: %w", ErrInvalidSignature, err)
+	}
+	return from, nil
+}
+
+// isZero reports whether the signature value n is unset.
+func isZero(n *big.Int) bool {
+	return n == nil || n.Sign() == 0
+}
+
+// RecoverSenderFromTx returns the address of the key that signed tx, see the
+// package level RecoverSenderFromTx.
+func (sec *SecureSign) RecoverSenderFromTx(tx *types.Transaction, s types.Signer) (common.Address, error) {
+	return RecoverSenderFromTx(tx, s)
+}
+
+// VerifyTxSender checks that tx was signed by expected, failing with an
+// *ErrSenderMismatch if it was signed by another key.
+func VerifyTxSender(tx *types.Transaction, s types.Signer, expected common.Address) error {
+	from, err := RecoverSenderFromTx(tx, s)
+	if err != nil {
+		return err
+	}
+	if from != expected {
+		return &ErrSenderMismatch{Got: from, Expected: expected}
+	}
+	return nil
+}
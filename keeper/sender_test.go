@@ -0,0 +1,41 @@
+package keeper
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+func TestRecoverSenderFromTx(t *testing.T) {
+	sec, prvID, addr := newTestSigner(t)
+	signer := types.LatestSignerForChainID(big.NewInt(1))
+	signed, err := sec.Sign(newTestTx(), signer, prvID)
+	if err != nil {
+		t.Fatalf("failed to sign: %v", err)
+	}
+	if from, err := sec.RecoverSenderFromTx(signed, signer); err != nil || from != addr {
+		t.Fatalf("sender: have (%v, %v), want %v", from, err, addr)
+	}
+	if err := VerifyTxSender(signed, signer, addr); err != nil {
+		t.Fatalf("failed to verify sender: %v", err)
+	}
+	var mismatch *ErrSenderMismatch
+	if err := VerifyTxSender(signed, signer, common.Address{1}); !errors.As(err, &mismatch) || mismatch.Got != addr || mismatch.Expected != (common.Address{1}) {
+		t.Fatalf("other sender: have %v, want %T", err, mismatch)
+	}
+
+	if _, err := RecoverSenderFromTx(newTestTx(), signer); !errors.Is(err, ErrUnsignedTx) {
+		t.Fatalf("unsigned tx: have %v, want %v", err, ErrUnsignedTx)
+	}
+	if err := VerifyTxSender(newTestTx(), signer, addr); !errors.Is(err, ErrUnsignedTx) {
+		t.Fatalf("verify unsigned tx: have %v, want %v", err, ErrUnsignedTx)
+	}
+	// A signature for another chain doesn't recover, the cause is kept.
+	_, err = RecoverSenderFromTx(signed, types.LatestSignerForChainID(big.NewInt(5)))
+	if !errors.Is(err, ErrInvalidSignature) || !errors.Is(err, types.ErrInvalidChainId) {
+		t.Fatalf("tx of other chain: have %v, want %v and %v", err, ErrInvalidSignature, types.ErrInvalidChainId)
+	}
+}
@@ -0,0 +1,190 @@
+package keeper
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/keeper/internal/shamir"
+)
+
+// shamirEnvelope is the JSON encoded prvID of the Shamir keeper.
+type shamirEnvelope struct {
+	Threshold int             `json:"threshold"`
+	PublicKey hexutil.Bytes   `json:"publicKey"`
+	Shares    []hexutil.Bytes `json:"shares"`
+}
+
+// shamirKeeper is a PrivateKeyKeeper splitting the key identifiers of its
+// backend with Shamir's Secret Sharing, as implemented by the Vault shamir
+// package, so that no single share reveals the key. This is meant for
+// backends whose prvID is the key itself, such as the default keeper.
+//
+// The prvID is a JSON envelope holding the shares and the public key. It can be
+// taken apart to hand single shares to different custodians, signing requires
+// an envelope holding at least threshold of them.
+type shamirKeeper struct {
+	threshold int
+	total     int
+	backend   PrivateKeyKeeper
+}
+
+// NewShamirKeeper returns a PrivateKeyKeeper splitting the keys generated by
+// backend into total shares, threshold of which are needed to use the key. If
+// backend is nil, the default keeper is used.
+func NewShamirKeeper(threshold, total int, backend PrivateKeyKeeper) (PrivateKeyKeeper, error) {
+	if threshold < 2 || threshold > total || total > 255 {
+		return nil, fmt.Errorf("invalid shamir parameters: threshold %d, total %d", threshold, total)
+	}
+	if backend == nil {
+		backend = defaultKeeper
+	}
+	return &shamirKeeper{threshold: threshold, total: total, backend: backend}, nil
+}
+
+func (k *shamirKeeper) GeneratePrivateKey() (_ []byte, err error) {
+	defer wrapError(&err, "generate key", nil)
+
+	secret, err := k.backend.GeneratePrivateKey()
+	if err != nil {
+		return nil, err
+	}
+	defer zeroBytes(secret)
+	return k.split(secret)
+}
+
+// ImportPrivateKey imports rawKey into the backend and splits the resulting
+// backend key.
+func (k *shamirKeeper) ImportPrivateKey(rawKey []byte) (_ []byte, err error) {
+	defer wrapError(&err, "import key", nil)
+
+	secret, err := k.backend.ImportPrivateKey(rawKey)
+	if err != nil {
+		return nil, err
+	}
+	defer zeroBytes(secret)
+	return k.split(secret)
+}
+
+// split splits the backend key secret into the envelope of the keeper.
+func (k *shamirKeeper) split(secret []byte) ([]byte, error) {
+	pub, err := k.backend.GetPublicKey(secret)
+	if err != nil {
+		return nil, err
+	}
+	shares, err := shamir.Split(secret, k.total, k.threshold)
+	if err != nil {
+		return nil, err
+	}
+	env := shamirEnvelope{Threshold: k.threshold, PublicKey: pub}
+	for _, share := range shares {
+		env.Shares = append(env.Shares, share)
+		defer zeroBytes(share)
+	}
+	return json.Marshal(env)
+}
+
+// envelope decodes the prvID of the keeper.
+func (k *shamirKeeper) envelope(prvID []byte) (*shamirEnvelope, error) {
+	env := new(shamirEnvelope)
+	if err := json.Unmarshal(prvID, env); err != nil {
+		return nil, fmt.Errorf("invalid shamir envelope: %v", err)
+	}
+	if len(env.PublicKey) != 65 {
+		return nil, errors.New("invalid shamir envelope: missing public key")
+	}
+	return env, nil
+}
+
+// reconstruct combines the shares in prvID into the backend key. The caller
+// has to zero the key after use.
+func (k *shamirKeeper) reconstruct(prvID []byte) ([]byte, error) {
+	env, err := k.envelope(prvID)
+	if err != nil {
+		return nil, err
+	}
+	shares := make([][]byte, len(env.Shares))
+	for i, share := range env.Shares {
+		shares[i] = share
+		defer zeroBytes(share)
+	}
+	if len(shares) < env.Threshold {
+		return nil, fmt.Errorf("not enough shamir shares: have %d, need %d", len(shares), env.Threshold)
+	}
+	secret, err := shamir.Combine(shares)
+	if err != nil {
+		return nil, err
+	}
+	// Wrong or too few shares combine into a different key without notice,
+	// check that the right one came out.
+	pub, err := k.backend.GetPublicKey(secret)
+	if err != nil || !bytes.Equal(pub, env.PublicKey) {
+		zeroBytes(secret)
+		return nil, errors.New("shamir shares don't reconstruct the key")
+	}
+	return secret, nil
+}
+
+// GetPublicKey returns the public key recorded in the envelope, without
+// reconstructing the key.
+func (k *shamirKeeper) GetPublicKey(prvID []byte) (_ []byte, err error) {
+	defer wrapError(&err, "get public key", prvID)
+
+	env, err := k.envelope(prvID)
+	if err != nil {
+		return nil, err
+	}
+	return env.PublicKey, nil
+}
+
+// Sign reconstructs the key, signs with the backend and zeros the key again.
+func (k *shamirKeeper) Sign(data []byte, prvID []byte) (_ []byte, err error) {
+	defer wrapError(&err, "sign", prvID)
+
+	secret, err := k.reconstruct(prvID)
+	if err != nil {
+		return nil, err
+	}
+	defer zeroBytes(secret)
+	return k.backend.Sign(data, secret)
+}
+
+func (k *shamirKeeper) DeletePrivateKey(prvID []byte) (err error) {
+	defer wrapError(&err, "delete key", prvID)
+
+	secret, err := k.reconstruct(prvID)
+	if err != nil {
+		return err
+	}
+	defer zeroBytes(secret)
+	return k.backend.DeletePrivateKey(secret)
+}
+
+// ExportEncryptedKey reconstructs the backend key of prvID and exports it from
+// the backend, which has to support exports.
+func (k *shamirKeeper) ExportEncryptedKey(prvID []byte, passphrase string) (_ []byte, err error) {
+	defer wrapError(&err, "export key", prvID)
+
+	backend, ok := k.backend.(ExportableKeeper)
+	if !ok {
+		return nil, ErrExportNotSupported
+	}
+	secret, err := k.reconstruct(prvID)
+	if err != nil {
+		return nil, err
+	}
+	defer zeroBytes(secret)
+	return backend.ExportEncryptedKey(secret, passphrase)
+}
+
+func (k *shamirKeeper) ImportEncryptedKey(data []byte, passphrase string) ([]byte, error) {
+	return importEncryptedKey(k, data, passphrase)
+}
+
+// ListPrivateKeys returns ErrNotSupported, the keeper doesn't store the shares.
+func (k *shamirKeeper) ListPrivateKeys() (_ [][]byte, err error) {
+	defer wrapError(&err, "list keys", nil)
+	return nil, ErrNotSupported
+}
@@ -0,0 +1,62 @@
+package keeper
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestShamirKeeper(t *testing.T) {
+	k, err := NewShamirKeeper(2, 3, nil)
+	if err != nil {
+		t.Fatalf("failed to create keeper: %v", err)
+	}
+	prvID, err := k.GeneratePrivateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	pub, err := k.GetPublicKey(prvID)
+	if err != nil {
+		t.Fatalf("failed to get public key: %v", err)
+	}
+	var env shamirEnvelope
+	if err := json.Unmarshal(prvID, &env); err != nil {
+		t.Fatalf("invalid envelope: %v", err)
+	}
+	if len(env.Shares) != 3 || env.Threshold != 2 {
+		t.Fatalf("envelope mismatch: have %d shares, threshold %d", len(env.Shares), env.Threshold)
+	}
+	// Any threshold shares must be able to sign.
+	hash := crypto.Keccak256([]byte("shamir"))
+	env.Shares = env.Shares[1:]
+	partial, _ := json.Marshal(env)
+	sig, err := k.Sign(hash, partial)
+	if err != nil {
+		t.Fatalf("failed to sign: %v", err)
+	}
+	recovered, err := crypto.Ecrecover(hash, sig)
+	if err != nil {
+		t.Fatalf("failed to recover: %v", err)
+	}
+	if !bytes.Equal(recovered, pub) {
+		t.Fatalf("recovered key mismatch: have %x, want %x", recovered, pub)
+	}
+	// Too few or corrupted shares must be refused.
+	env.Shares = env.Shares[1:]
+	single, _ := json.Marshal(env)
+	if _, err := k.Sign(hash, single); err == nil {
+		t.Fatal("signed with a single share")
+	}
+	json.Unmarshal(prvID, &env)
+	env.Shares[0][0] ^= 0xff
+	corrupt, _ := json.Marshal(env)
+	if _, err := k.Sign(hash, corrupt); err == nil {
+		t.Fatal("signed with corrupted share")
+	}
+	if _, err := NewShamirKeeper(1, 3, nil); err == nil {
+		t.Fatal("accepted threshold of one")
+	}
+	checkImport(t, k)
+}
@@ -0,0 +1,140 @@
+package keeper
+
+import (
+	"bytes"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+var (
+	secp256k1N     = crypto.S256().Params().N
+	secp256k1HalfN = new(big.Int).Rsh(secp256k1N, 1)
+
+	oidPublicKeyECDSA = asn1.ObjectIdentifier{1, 2, 840, 10045, 2, 1}
+	oidNamedCurveS256 = asn1.ObjectIdentifier{1, 3, 132, 0, 10}
+)
+
+// derSignature is the ASN.1 structure of an ECDSA signature as returned by
+// most HSM and KMS signing APIs.
+type derSignature struct {
+	R, S *big.Int
+}
+
+// subjectPublicKeyInfo is the ASN.1 structure of a DER encoded public key.
+type subjectPublicKeyInfo struct {
+	Algorithm pkix.AlgorithmIdentifier
+	PublicKey asn1.BitString
+}
+
+// parseDERSignature decodes an ASN.1 DER encoded ECDSA signature.
+func parseDERSignature(der []byte) (r, s *big.Int, err error) {
+	var sig derSignature
+	rest, err := asn1.Unmarshal(der, &sig)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: invalid DER encoding: %v", ErrInvalidSignature, err)
+	}
+	if len(rest) != 0 {
+		return nil, nil, fmt.Errorf("%w: trailing DER data", ErrInvalidSignature)
+	}
+	if sig.R == nil || sig.S == nil || sig.R.Sign() <= 0 || sig.S.Sign() <= 0 {
+		return nil, nil, fmt.Errorf("%w: non-positive component", ErrInvalidSignature)
+	}
+	return sig.R, sig.S, nil
+}
+
+// parseSubjectPublicKeyInfo decodes a DER encoded secp256k1 public key into the
+// 65 byte uncompressed form. The standard library can't do this, as x509 does
+// not know about the secp256k1 curve.
+func parseSubjectPublicKeyInfo(der []byte) ([]byte, error) {
+	var spki subjectPublicKeyInfo
+	rest, err := asn1.Unmarshal(der, &spki)
+	if err != nil {
+		return nil, fmt.Errorf("invalid public key: %v", err)
+	}
+	if len(rest) != 0 {
+		return nil, errors.New("invalid public key: trailing data")
+	}
+	if !spki.Algorithm.Algorithm.Equal(oidPublicKeyECDSA) {
+		return nil, fmt.Errorf("invalid public key: unsupported algorithm %v", spki.Algorithm.Algorithm)
+	}
+	var curve asn1.ObjectIdentifier
+	if _, err := asn1.Unmarshal(spki.Algorithm.Parameters.FullBytes, &curve); err != nil {
+		return nil, fmt.Errorf("invalid public key: %v", err)
+	}
+	if !curve.Equal(oidNamedCurveS256) {
+		return nil, fmt.Errorf("invalid public key: unsupported curve %v", curve)
+	}
+	pub, err := crypto.UnmarshalPubkey(spki.PublicKey.RightAlign())
+	if err != nil {
+		return nil, fmt.Errorf("invalid public key: %v", err)
+	}
+	return crypto.FromECDSAPub(pub), nil
+}
+
+// NormaliseSignature returns sig in the canonical form of Ethereum signatures:
+// the s value in the lower half of the curve order as required by EIP-2, and a
+// V of 0 or 1. A high s is replaced by N - s, which flips the recovery id, so
+// the signature still recovers the same public key. sig is either the 65 byte
+// [R || S || V] format, with a V of 0, 1, 27 or 28, or the 64 byte [R || S]
+// format without a recovery id, which is returned in kind. DER signatures lack
+// the recovery id and have to be converted with the public key of the signer.
+func NormaliseSignature(sig []byte) ([]byte, error) {
+	if len(sig) != crypto.SignatureLength && len(sig) != crypto.SignatureLength-1 {
+		return nil, fmt.Errorf("%w: signature must be %d or %d bytes long (%d)", ErrInvalidSignature, crypto.SignatureLength, crypto.SignatureLength-1, len(sig))
+	}
+	r, s := new(big.Int).SetBytes(sig[:32]), new(big.Int).SetBytes(sig[32:64])
+	if r.Sign() == 0 || s.Sign() == 0 || r.Cmp(secp256k1N) >= 0 || s.Cmp(secp256k1N) >= 0 {
+		return nil, fmt.Errorf("%w: component out of range", ErrInvalidSignature)
+	}
+	norm := bytes.Clone(sig)
+	high := s.Cmp(secp256k1HalfN) > 0
+	if high {
+		s.Sub(secp256k1N, s).FillBytes(norm[32:64])
+	}
+	if len(norm) == crypto.SignatureLength-1 {
+		return norm, nil
+	}
+	v := norm[crypto.RecoveryIDOffset]
+	if v == 27 || v == 28 {
+		v -= 27
+	}
+	if v > 1 {
+		return nil, fmt.Errorf("%w: invalid recovery id %d", ErrInvalidSignature, norm[crypto.RecoveryIDOffset])
+	}
+	if high {
+		v ^= 1
+	}
+	norm[crypto.RecoveryIDOffset] = v
+	return norm, nil
+}
+
+// recoverableSignature converts the r and s values of an ECDSA signature over
+// hash into the 65 byte [R || S || V] format used by Ethereum. Backends that
+// only return r and s do not tell which of the two candidate public keys the
+// signature belongs to, so the recovery id is found by comparing against the
+// known public key of the signing key. The s value is normalised to the lower
+// half of the curve order as required by EIP-2.
+func recoverableSignature(hash []byte, r, s *big.Int, pub []byte) ([]byte, error) {
+	if r.Cmp(secp256k1N) >= 0 || s.Cmp(secp256k1N) >= 0 {
+		return nil, fmt.Errorf("%w: component out of range", ErrInvalidSignature)
+	}
+	if s.Cmp(secp256k1HalfN) > 0 {
+		s = new(big.Int).Sub(secp256k1N, s)
+	}
+	sig := make([]byte, crypto.SignatureLength)
+	r.FillBytes(sig[:32])
+	s.FillBytes(sig[32:64])
+	for v := byte(0); v < 2; v++ {
+		sig[crypto.RecoveryIDOffset] = v
+		recovered, err := crypto.Ecrecover(hash, sig)
+		if err == nil && bytes.Equal(recovered, pub) {
+			return sig, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: signature does not match public key", ErrInvalidSignature)
+}
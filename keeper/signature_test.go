@@ -0,0 +1,146 @@
+package keeper
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// marshalTestSPKI encodes a secp256k1 public key the way KMS and HSM backends
+// return it.
+func marshalTestSPKI(pub *ecdsa.PublicKey) []byte {
+	params, err := asn1.Marshal(oidNamedCurveS256)
+	if err != nil {
+		panic(err)
+	}
+	raw := crypto.FromECDSAPub(pub)
+	der, err := asn1.Marshal(subjectPublicKeyInfo{
+		Algorithm: pkix.AlgorithmIdentifier{Algorithm: oidPublicKeyECDSA, Parameters: asn1.RawValue{FullBytes: params}},
+		PublicKey: asn1.BitString{Bytes: raw, BitLength: 8 * len(raw)},
+	})
+	if err != nil {
+		panic(err)
+	}
+	return der
+}
+
+// derSign signs hash with key and returns the DER encoded signature the way a
+// KMS or HSM backend would. If highS is set, the s value is flipped to the upper
+// half of the curve order, which such backends are free to return.
+func derSign(hash []byte, key *ecdsa.PrivateKey, highS bool) []byte {
+	sig, err := crypto.Sign(hash, key)
+	if err != nil {
+		panic(err)
+	}
+	r, s := new(big.Int).SetBytes(sig[:32]), new(big.Int).SetBytes(sig[32:64])
+	if highS {
+		s.Sub(secp256k1N, s)
+	}
+	der, err := asn1.Marshal(derSignature{R: r, S: s})
+	if err != nil {
+		panic(err)
+	}
+	return der
+}
+
+func TestParseSubjectPublicKeyInfo(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	pub, err := parseSubjectPublicKeyInfo(marshalTestSPKI(&key.PublicKey))
+	if err != nil {
+		t.Fatalf("failed to parse public key: %v", err)
+	}
+	if want := crypto.FromECDSAPub(&key.PublicKey); !bytes.Equal(pub, want) {
+		t.Fatalf("public key mismatch: have %x, want %x", pub, want)
+	}
+	if _, err := parseSubjectPublicKeyInfo([]byte{0x30, 0x00}); err == nil {
+		t.Fatal("parsed invalid public key")
+	}
+}
+
+func TestRecoverableSignature(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	pub := crypto.FromECDSAPub(&key.PublicKey)
+	hash := crypto.Keccak256([]byte("recoverable"))
+
+	want, _ := crypto.Sign(hash, key)
+	for _, highS := range []bool{false, true} {
+		r, s, err := parseDERSignature(derSign(hash, key, highS))
+		if err != nil {
+			t.Fatalf("highS=%v: failed to parse signature: %v", highS, err)
+		}
+		sig, err := recoverableSignature(hash, r, s, pub)
+		if err != nil {
+			t.Fatalf("highS=%v: failed to convert signature: %v", highS, err)
+		}
+		if !bytes.Equal(sig, want) {
+			t.Fatalf("highS=%v: signature mismatch: have %x, want %x", highS, sig, want)
+		}
+	}
+	other, _ := crypto.GenerateKey()
+	r, s, _ := parseDERSignature(derSign(hash, other, false))
+	if _, err := recoverableSignature(hash, r, s, pub); err == nil {
+		t.Fatal("converted signature of a different key")
+	}
+}
+
+func TestNormaliseSignature(t *testing.T) {
+	// Signature of keccak256("high-s") by the key 4c0883a6...3f362318 of
+	// address 0x2c7536E3605D9C16a7a3D7b1898e529396a65c23, and its high-S twin.
+	var (
+		hash = common.FromHex("1fa14a25864b24514c6c7ea0a9a360d122605d499d074436c96e6222fb5550b4")
+		low  = common.FromHex("77db219b69fcbae7c0d2617e2b395f08b58c5b785867aecd64a752ffe5653b8f3162e05c4654429ef95bb50b861ede282266ad20b99267630f86da4bce0727f001")
+		high = common.FromHex("77db219b69fcbae7c0d2617e2b395f08b58c5b785867aecd64a752ffe5653b8fce9d1fa3b9abbd6106a44af479e121d698482fc5f5b638d8b04b8441022f195100")
+		addr = common.HexToAddress("0x2c7536E3605D9C16a7a3D7b1898e529396a65c23")
+	)
+	legacyV := bytes.Clone(high)
+	legacyV[crypto.RecoveryIDOffset] += 27
+
+	tests := []struct {
+		sig, want []byte
+	}{
+		{low, low},
+		{high, low},
+		{legacyV, low},
+		{low[:64], low[:64]},
+		{high[:64], low[:64]},
+	}
+	for i, tt := range tests {
+		norm, err := NormaliseSignature(tt.sig)
+		if err != nil {
+			t.Fatalf("test %d: failed to normalise: %v", i, err)
+		}
+		if !bytes.Equal(norm, tt.want) {
+			t.Fatalf("test %d: have %x, want %x", i, norm, tt.want)
+		}
+	}
+	// The high-S signature is valid, but refused by the EIP-2 rules; its
+	// normalised form recovers the same signer.
+	if crypto.ValidateSignatureValues(high[64], new(big.Int).SetBytes(high[:32]), new(big.Int).SetBytes(high[32:64]), true) {
+		t.Fatal("high-S vector accepted as homestead signature")
+	}
+	norm, _ := NormaliseSignature(high)
+	if pub, err := crypto.SigToPub(hash, norm); err != nil || crypto.PubkeyToAddress(*pub) != addr {
+		t.Fatalf("recovered signer mismatch: have (%v, %v), want %v", pub, err, addr)
+	}
+	if !bytes.Equal(high[32:64], common.FromHex("ce9d1fa3b9abbd6106a44af479e121d698482fc5f5b638d8b04b8441022f1951")) {
+		t.Fatal("input modified")
+	}
+
+	for name, sig := range map[string][]byte{
+		"short":       low[:63],
+		"zero r":      append(make([]byte, 32), low[32:]...),
+		"s = n":       append(append(bytes.Clone(low[:32]), secp256k1N.Bytes()...), 0),
+		"recovery id": append(bytes.Clone(low[:64]), 2),
+	} {
+		if _, err := NormaliseSignature(sig); !errors.Is(err, ErrInvalidSignature) {
+			t.Errorf("%s: have %v, want %v", name, err, ErrInvalidSignature)
+		}
+	}
+}
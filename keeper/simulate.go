@@ -0,0 +1,112 @@
+package keeper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// RevertError is returned by SimulateAndSign for transactions reverting in the
+// simulation. They are not signed.
+type RevertError struct {
+	Reason string // reason of a revert with Error(string) or Panic(uint256), empty otherwise
+	Data   []byte // return data of the reverted call, e.g. a custom error, nil if not reported
+}
+
+func (e *RevertError) Error() string {
+	switch {
+	case e.Reason != "":
+		return "execution reverted: " + e.Reason
+	case len(e.Data) > 0:
+		return fmt.Sprintf("execution reverted: %#x", e.Data)
+	default:
+		return "execution reverted"
+	}
+}
+
+// SimulateAndSign simulates tx with eth_call through client, at the latest
+// block, and signs it with the Sign of s only if the call didn't revert.
+func (sec *SecureSign) SimulateAndSign(ctx context.Context, tx *types.Transaction, s types.Signer, client ethereum.ContractCaller, prvID []byte) (*types.Transaction, error) {
+	return simulateAndSign(ctx, sec, tx, s, client, prvID)
+}
+
+// simulateAndSign implements SimulateAndSign on top of the SignContext of s, so
+// signers wrapping a SecureSigner sign through their own checks. The call is
+// made from the address of the key prvID. A reverted call fails with a
+// *RevertError, any other failure of the simulation is returned as is.
+func simulateAndSign(ctx context.Context, s SecureSigner, tx *types.Transaction, signer types.Signer, client ethereum.ContractCaller, prvID []byte) (_ *types.Transaction, err error) {
+	defer wrapError(&err, "sign transaction", prvID)
+
+	pub, err := signerContext(s).GetPublicKeyContext(ctx, prvID)
+	if err != nil {
+		return nil, err
+	}
+	key, err := ParsePublicKey(pub)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := client.CallContract(ctx, callMsg(crypto.PubkeyToAddress(*key), tx), nil); err != nil {
+		if revert := revertError(err); revert != nil {
+			return nil, revert
+		}
+		return nil, fmt.Errorf("failed to simulate transaction: %w", err)
+	}
+	return signerContext(s).SignContext(ctx, tx, signer, prvID)
+}
+
+// callMsg returns the call of tx sent from from.
+func callMsg(from common.Address, tx *types.Transaction) ethereum.CallMsg {
+	msg := ethereum.CallMsg{
+		From:              from,
+		To:                tx.To(),
+		Gas:               tx.Gas(),
+		Value:             tx.Value(),
+		Data:              tx.Data(),
+		AccessList:        tx.AccessList(),
+		BlobHashes:        tx.BlobHashes(),
+		AuthorizationList: tx.SetCodeAuthorizations(),
+	}
+	switch tx.Type() {
+	case types.LegacyTxType, types.AccessListTxType:
+		msg.GasPrice = tx.GasPrice()
+	default:
+		msg.GasFeeCap, msg.GasTipCap = tx.GasFeeCap(), tx.GasTipCap()
+	}
+	if tx.Type() == types.BlobTxType {
+		msg.BlobGasFeeCap = tx.BlobGasFeeCap()
+	}
+	return msg
+}
+
+// revertError returns the *RevertError of a call failing with err, nil if the
+// call didn't revert. Nodes report reverts with the "execution reverted"
+// message, along with the return data as error data over JSON-RPC.
+func revertError(err error) *RevertError {
+	var revert *RevertError
+	if errors.As(err, &revert) {
+		return revert
+	}
+	if !strings.Contains(err.Error(), "execution reverted") {
+		return nil
+	}
+	revert = new(RevertError)
+	var dataErr rpc.DataError
+	if errors.As(err, &dataErr) {
+		if data, ok := dataErr.ErrorData().(string); ok {
+			revert.Data, _ = hexutil.Decode(data)
+		}
+	}
+	if reason, err := abi.UnpackRevert(revert.Data); err == nil {
+		revert.Reason = reason
+	}
+	return revert
+}
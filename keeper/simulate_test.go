@@ -0,0 +1,111 @@
+package keeper
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// mockContractCaller is an ethereum.ContractCaller failing calls with err.
+type mockContractCaller struct {
+	err   error
+	calls []ethereum.CallMsg
+}
+
+func (c *mockContractCaller) CallContract(ctx context.Context, call ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	c.calls = append(c.calls, call)
+	return nil, c.err
+}
+
+// revertDataError is a reverted call as reported over JSON-RPC.
+type revertDataError struct {
+	data string
+}
+
+func (e *revertDataError) Error() string          { return "execution reverted" }
+func (e *revertDataError) ErrorData() interface{} { return e.data }
+
+// revertReasonData returns the return data of a revert with reason.
+func revertReasonData(t *testing.T, reason string) []byte {
+	stringType, _ := abi.NewType("string", "", nil)
+	packed, err := abi.Arguments{{Type: stringType}}.Pack(reason)
+	if err != nil {
+		t.Fatalf("failed to pack reason: %v", err)
+	}
+	return append(crypto.Keccak256([]byte("Error(string)"))[:4], packed...)
+}
+
+func TestSimulateAndSign(t *testing.T) {
+	sec, prvID, addr := newTestSigner(t)
+	var (
+		chainID = big.NewInt(1)
+		signer  = types.LatestSignerForChainID(chainID)
+		tx      = newDynamicFeeTx(chainID, 4, common.Address{0x01}, big.NewInt(5), 60000, big.NewInt(20), big.NewInt(2), []byte{0xca, 0xfe})
+		client  = new(mockContractCaller)
+	)
+	signed, err := sec.SimulateAndSign(context.Background(), tx, signer, client, prvID)
+	if err != nil {
+		t.Fatalf("failed to sign: %v", err)
+	}
+	if from, err := types.Sender(signer, signed); err != nil || from != addr {
+		t.Fatalf("sender mismatch: have (%v, %v), want %v", from, err, addr)
+	}
+	if len(client.calls) != 1 {
+		t.Fatalf("simulated %d times, want once", len(client.calls))
+	}
+	call := client.calls[0]
+	if call.From != addr || *call.To != (common.Address{0x01}) || call.Gas != 60000 || call.Value.Int64() != 5 ||
+		call.GasPrice != nil || call.GasFeeCap.Int64() != 20 || call.GasTipCap.Int64() != 2 || !bytes.Equal(call.Data, []byte{0xca, 0xfe}) {
+		t.Fatalf("call mismatch: %+v", call)
+	}
+
+	customError := crypto.Keccak256([]byte("InsufficientBalance()"))[:4]
+	tests := []struct {
+		name   string
+		err    error
+		reason string
+		data   []byte
+	}{
+		{"reason", &revertDataError{hexutil.Encode(revertReasonData(t, "insufficient balance"))}, "insufficient balance", revertReasonData(t, "insufficient balance")},
+		{"custom error", &revertDataError{hexutil.Encode(customError)}, "", customError},
+		{"without data", errors.New("execution reverted"), "", nil},
+	}
+	for _, tt := range tests {
+		client.err = tt.err
+		signed, err := sec.SimulateAndSign(context.Background(), tx, signer, client, prvID)
+		var revert *RevertError
+		if !errors.As(err, &revert) || signed != nil {
+			t.Fatalf("%s: have (%v, %v), want %T", tt.name, signed, err, revert)
+		}
+		if revert.Reason != tt.reason || !bytes.Equal(revert.Data, tt.data) {
+			t.Errorf("%s: have reason %q and data %x, want %q and %x", tt.name, revert.Reason, revert.Data, tt.reason, tt.data)
+		}
+	}
+
+	client.err = errors.New("connection refused")
+	var revert *RevertError
+	if signed, err := sec.SimulateAndSign(context.Background(), tx, signer, client, prvID); !errors.Is(err, client.err) || errors.As(err, &revert) || signed != nil {
+		t.Fatalf("failed simulation: have (%v, %v), want %v", signed, err, client.err)
+	}
+}
+
+func TestSimulateAndSignPolicy(t *testing.T) {
+	sec, prvID, _ := newTestSigner(t)
+	policied := NewPoliciedSecureSigner(sec, SigningPolicy{MaxGasLimit: 21000})
+
+	// Simulated transactions are signed through the checks of the signer.
+	var violation *PolicyViolation
+	tx := types.NewTx(&types.LegacyTx{To: &common.Address{0x01}, Value: big.NewInt(1), Gas: 30000, GasPrice: big.NewInt(1)})
+	if _, err := policied.SimulateAndSign(context.Background(), tx, types.LatestSignerForChainID(big.NewInt(1)), new(mockContractCaller), prvID); !errors.As(err, &violation) {
+		t.Fatalf("transaction beyond policy: have %v, want policy violation", err)
+	}
+}
@@ -0,0 +1,111 @@
+package keeper
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// singleFlightKeeper is a PrivateKeyKeeper coalescing concurrent signatures of
+// the same data with the same key: while one is in flight, identical requests
+// wait for its result instead of reaching the inner keeper. A thundering herd
+// of callers thus costs a single HSM or KMS request.
+//
+// Sharing the signature is safe since signing data twice with the same key
+// yields a valid signature both times; with the RFC 6979 deterministic nonces
+// of the local keepers it even is the very same signature.
+type singleFlightKeeper struct {
+	inner   PrivateKeyKeeperContext
+	flights singleflight.Group
+}
+
+// NewSingleFlightKeeper returns a PrivateKeyKeeper coalescing the concurrent
+// identical signing requests to inner. All other calls go to inner directly.
+func NewSingleFlightKeeper(inner PrivateKeyKeeper) PrivateKeyKeeper {
+	return &singleFlightKeeper{inner: ContextKeeper(inner)}
+}
+
+// flightKey returns the sha256 of data and prvID identifying a signing request,
+// the length of data keeping apart requests which concatenate the same.
+func flightKey(data []byte, prvID []byte) string {
+	h := sha256.New()
+	binary.Write(h, binary.BigEndian, uint64(len(data)))
+	h.Write(data)
+	h.Write(prvID)
+	return string(h.Sum(nil))
+}
+
+func (k *singleFlightKeeper) GeneratePrivateKey() ([]byte, error) {
+	return k.GeneratePrivateKeyContext(context.Background())
+}
+
+func (k *singleFlightKeeper) GeneratePrivateKeyContext(ctx context.Context) ([]byte, error) {
+	return k.inner.GeneratePrivateKeyContext(ctx)
+}
+
+func (k *singleFlightKeeper) GetPublicKey(prvID []byte) ([]byte, error) {
+	return k.GetPublicKeyContext(context.Background(), prvID)
+}
+
+func (k *singleFlightKeeper) GetPublicKeyContext(ctx context.Context, prvID []byte) ([]byte, error) {
+	return k.inner.GetPublicKeyContext(ctx, prvID)
+}
+
+func (k *singleFlightKeeper) Sign(data []byte, prvID []byte) ([]byte, error) {
+	return k.SignContext(context.Background(), data, prvID)
+}
+
+// SignContext signs data with the key prvID, or waits for the identical
+// request in flight. The inner keeper isn't canceled along with the request
+// starting the flight, as others may be waiting for it; a waiter giving up
+// returns when its own ctx is done.
+func (k *singleFlightKeeper) SignContext(ctx context.Context, data []byte, prvID []byte) ([]byte, error) {
+	ch := k.flights.DoChan(flightKey(data, prvID), func() (any, error) {
+		return k.inner.SignContext(context.WithoutCancel(ctx), data, prvID)
+	})
+	select {
+	case res := <-ch:
+		if res.Err != nil {
+			return nil, res.Err
+		}
+		sig := res.Val.([]byte)
+		if res.Shared {
+			// Callers may alter the signature, e.g. to adjust its V.
+			sig = bytes.Clone(sig)
+		}
+		return sig, nil
+	case <-ctx.Done():
+		return nil, &KeeperError{Op: "sign", KeyID: prvID, Err: ctx.Err()}
+	}
+}
+
+func (k *singleFlightKeeper) DeletePrivateKey(prvID []byte) error {
+	return k.DeletePrivateKeyContext(context.Background(), prvID)
+}
+
+func (k *singleFlightKeeper) DeletePrivateKeyContext(ctx context.Context, prvID []byte) error {
+	return k.inner.DeletePrivateKeyContext(ctx, prvID)
+}
+
+func (k *singleFlightKeeper) ListPrivateKeys() ([][]byte, error) {
+	return k.ListPrivateKeysContext(context.Background())
+}
+
+func (k *singleFlightKeeper) ListPrivateKeysContext(ctx context.Context) ([][]byte, error) {
+	return k.inner.ListPrivateKeysContext(ctx)
+}
+
+func (k *singleFlightKeeper) ImportPrivateKey(rawKey []byte) ([]byte, error) {
+	return k.ImportPrivateKeyContext(context.Background(), rawKey)
+}
+
+func (k *singleFlightKeeper) ImportPrivateKeyContext(ctx context.Context, rawKey []byte) ([]byte, error) {
+	return k.inner.ImportPrivateKeyContext(ctx, rawKey)
+}
+
+func (k *singleFlightKeeper) HealthCheck(ctx context.Context) error {
+	return checkHealth(ctx, k.inner)
+}
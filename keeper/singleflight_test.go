@@ -0,0 +1,124 @@
+package keeper
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// blockingKeeper is a PrivateKeyKeeper whose signatures wait for release,
+// counting the ones it was asked for.
+type blockingKeeper struct {
+	defaultPrivateKeyKeeper
+	release chan struct{}
+	signs   atomic.Int32
+}
+
+func (k *blockingKeeper) Sign(data []byte, prvID []byte) ([]byte, error) {
+	return k.SignContext(context.Background(), data, prvID)
+}
+
+func (k *blockingKeeper) SignContext(ctx context.Context, data []byte, prvID []byte) ([]byte, error) {
+	k.signs.Add(1)
+	<-k.release
+	return k.defaultPrivateKeyKeeper.SignContext(ctx, data, prvID)
+}
+
+func TestSingleFlightKeeper(t *testing.T) {
+	inner := &blockingKeeper{release: make(chan struct{})}
+	k := NewSingleFlightKeeper(inner)
+
+	prvID, err := k.GeneratePrivateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	pub, err := k.GetPublicKey(prvID)
+	if err != nil {
+		t.Fatalf("failed to get public key: %v", err)
+	}
+	hash := crypto.Keccak256([]byte("single flight"))
+
+	const callers = 100
+	var (
+		wg      sync.WaitGroup
+		entered sync.WaitGroup
+		sigs    = make([][]byte, callers)
+		errs    = make([]error, callers)
+	)
+	entered.Add(callers)
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			entered.Done()
+			sigs[i], errs[i] = k.Sign(hash, prvID)
+		}()
+	}
+	entered.Wait()
+	// Give the callers the time to join the flight before it lands.
+	time.Sleep(50 * time.Millisecond)
+	close(inner.release)
+	wg.Wait()
+
+	if n := inner.signs.Load(); n != 1 {
+		t.Fatalf("inner signatures: have %d, want 1", n)
+	}
+	for i := range sigs {
+		if errs[i] != nil {
+			t.Fatalf("caller %d: failed to sign: %v", i, errs[i])
+		}
+		if recovered, err := crypto.Ecrecover(hash, sigs[i]); err != nil || !bytes.Equal(recovered, pub) {
+			t.Fatalf("caller %d: recovered key mismatch: have (%x, %v), want %x", i, recovered, err, pub)
+		}
+	}
+	// Every caller owns its signature.
+	sigs[0][0] ^= 0xff
+	if bytes.Equal(sigs[0], sigs[1]) {
+		t.Fatal("callers share the signature slice")
+	}
+
+	// Requests that aren't in flight at the same time, or differ, aren't
+	// coalesced.
+	if _, err := k.Sign(hash, prvID); err != nil {
+		t.Fatalf("failed to sign again: %v", err)
+	}
+	if _, err := k.Sign(crypto.Keccak256([]byte("other")), prvID); err != nil {
+		t.Fatalf("failed to sign other data: %v", err)
+	}
+	if n := inner.signs.Load(); n != 3 {
+		t.Fatalf("inner signatures: have %d, want 3", n)
+	}
+}
+
+func TestSingleFlightKeeperCancel(t *testing.T) {
+	inner := &blockingKeeper{release: make(chan struct{})}
+	k := NewSingleFlightKeeper(inner).(PrivateKeyKeeperContext)
+
+	prvID, err := k.GeneratePrivateKeyContext(context.Background())
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	hash := crypto.Keccak256([]byte("cancel"))
+
+	done := make(chan error)
+	go func() {
+		_, err := k.SignContext(context.Background(), hash, prvID)
+		done <- err
+	}()
+	// A waiter giving up doesn't cancel the flight of the others.
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := k.SignContext(ctx, hash, prvID); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("canceled waiter: have %v, want %v", err, context.DeadlineExceeded)
+	}
+	close(inner.release)
+	if err := <-done; err != nil {
+		t.Fatalf("remaining waiter: failed to sign: %v", err)
+	}
+}
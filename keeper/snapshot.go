@@ -0,0 +1,268 @@
+package keeper
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"golang.org/x/crypto/scrypt"
+)
+
+// SnapshottableKeeper is a PrivateKeyKeeper whose state can be saved and
+// restored, e.g. to keep the keys of an in-memory keeper across restarts of a
+// pod. Keepers holding the key material themselves encrypt their snapshots,
+// keepers of remote backends, like Vault or a KMS, just list the IDs of their
+// keys, the keys stay in the backend.
+type SnapshottableKeeper interface {
+	PrivateKeyKeeper
+
+	// Snapshot returns the state of the keeper.
+	Snapshot() ([]byte, error)
+	// Restore adds the keys of a snapshot to the keeper, failing with
+	// ErrPermissionDenied if an encrypted snapshot can't be decrypted, e.g.
+	// as it was tampered with.
+	Restore(snapshot []byte) error
+}
+
+// snapshotVersion is the version of the snapshot formats.
+const snapshotVersion = 1
+
+// passphraseAD is the additional data sealing the passphrase of the encrypted
+// memory keeper, distinct from the UUIDs sealing its keys.
+var passphraseAD = []byte("snapshot passphrase")
+
+// encryptedSnapshot is the JSON encoded snapshot of the encrypted memory
+// keeper. The ciphertext is the AES-256-GCM sealed JSON of snapshotKeys, under
+// a key derived from the passphrase of the keeper and the salt with scrypt.
+type encryptedSnapshot struct {
+	Version int `json:"version"`
+	KDF     struct {
+		N    int           `json:"n"`
+		R    int           `json:"r"`
+		P    int           `json:"p"`
+		Salt hexutil.Bytes `json:"salt"`
+	} `json:"kdf"`
+	Nonce      hexutil.Bytes `json:"nonce"`
+	Ciphertext hexutil.Bytes `json:"ciphertext"`
+}
+
+// snapshotKeys is the plaintext of an encryptedSnapshot, the raw keys by their
+// prvID.
+type snapshotKeys struct {
+	Keys map[string]hexutil.Bytes `json:"keys"`
+}
+
+// metadataSnapshot is the JSON encoded snapshot of the keepers of remote
+// backends.
+type metadataSnapshot struct {
+	Version int      `json:"version"`
+	KeyIDs  []string `json:"keyIds"` // hex encoded prvIDs
+}
+
+// snapshotAEAD returns the cipher of the snapshots of the keepers of
+// passphrase, deriving its key from salt with the scrypt parameter n.
+func snapshotAEAD(passphrase, salt []byte, n int) (cipher.AEAD, error) {
+	key, err := scrypt.Key(passphrase, salt, n, encryptedScryptR, encryptedScryptP, 32)
+	if err != nil {
+		return nil, err
+	}
+	defer zeroBytes(key)
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// snapshotCipher returns the cipher of the snapshots of salt, deriving its key
+// from the sealed passphrase unless the salt was used before. A nil salt
+// selects the salt of the snapshots taken by the keeper, drawn on first use.
+// Only the ciphers of own salts are remembered, the ones of restored snapshots
+// are remembered by Restore once they decrypted a snapshot.
+func (k *encryptedMemoryKeeper) snapshotCipher(salt []byte) ([]byte, cipher.AEAD, error) {
+	k.snapshotLock.Lock()
+	defer k.snapshotLock.Unlock()
+
+	own := salt == nil
+	if own {
+		if k.snapshotSalt == nil {
+			fresh := make([]byte, 32)
+			if _, err := io.ReadFull(k.rand, fresh); err != nil {
+				return nil, nil, err
+			}
+			k.snapshotSalt = fresh
+		}
+		salt = k.snapshotSalt
+	}
+	if aead, ok := k.snapshotAEADs[string(salt)]; ok {
+		return salt, aead, nil
+	}
+	nonceSize := k.aead.NonceSize()
+	pass, err := k.aead.Open(nil, k.passphrase[:nonceSize], k.passphrase[nonceSize:], passphraseAD)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer zeroBytes(pass)
+
+	aead, err := snapshotAEAD(pass, salt, k.scryptN)
+	if err != nil {
+		return nil, nil, err
+	}
+	if own {
+		k.snapshotAEADs[string(salt)] = aead
+	}
+	return salt, aead, nil
+}
+
+// Snapshot encrypts all keys of the keeper with its snapshot key. Restoring the
+// snapshot needs a keeper with the same passphrase.
+func (k *encryptedMemoryKeeper) Snapshot() (_ []byte, err error) {
+	defer wrapError(&err, "snapshot", nil)
+
+	plain := snapshotKeys{Keys: make(map[string]hexutil.Bytes)}
+	defer func() {
+		for _, key := range plain.Keys {
+			zeroBytes(key)
+		}
+	}()
+	prvIDs, err := k.ListPrivateKeys()
+	if err != nil {
+		return nil, err
+	}
+	for _, prvID := range prvIDs {
+		key, err := k.open(prvID)
+		if errors.Is(err, ErrKeyNotFound) {
+			continue // deleted meanwhile
+		}
+		if err != nil {
+			return nil, err
+		}
+		plain.Keys[string(prvID)] = crypto.FromECDSA(key)
+		ZeroKey(key)
+	}
+	data, err := json.Marshal(plain)
+	if err != nil {
+		return nil, err
+	}
+	defer zeroBytes(data)
+
+	salt, aead, err := k.snapshotCipher(nil)
+	if err != nil {
+		return nil, err
+	}
+	snap := encryptedSnapshot{Version: snapshotVersion}
+	snap.KDF.N, snap.KDF.R, snap.KDF.P, snap.KDF.Salt = k.scryptN, encryptedScryptR, encryptedScryptP, salt
+	snap.Nonce = make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(k.rand, snap.Nonce); err != nil {
+		return nil, err
+	}
+	snap.Ciphertext = aead.Seal(nil, snap.Nonce, data, nil)
+	return json.Marshal(snap)
+}
+
+// Restore decrypts the snapshot and stores its keys under their former prvIDs,
+// replacing keys of the same prvID.
+func (k *encryptedMemoryKeeper) Restore(snapshot []byte) (err error) {
+	defer wrapError(&err, "restore snapshot", nil)
+
+	var snap encryptedSnapshot
+	if err := json.Unmarshal(snapshot, &snap); err != nil {
+		return fmt.Errorf("invalid snapshot: %v", err)
+	}
+	if snap.Version != snapshotVersion {
+		return fmt.Errorf("unsupported snapshot version %d", snap.Version)
+	}
+	if snap.KDF.N != k.scryptN || snap.KDF.R != encryptedScryptR || snap.KDF.P != encryptedScryptP {
+		return fmt.Errorf("unsupported snapshot scrypt parameters N=%d r=%d p=%d", snap.KDF.N, snap.KDF.R, snap.KDF.P)
+	}
+	if len(snap.KDF.Salt) != 32 {
+		return fmt.Errorf("invalid snapshot salt length %d", len(snap.KDF.Salt))
+	}
+	salt, aead, err := k.snapshotCipher(snap.KDF.Salt)
+	if err != nil {
+		return err
+	}
+	if len(snap.Nonce) != aead.NonceSize() {
+		return fmt.Errorf("invalid snapshot nonce length %d", len(snap.Nonce))
+	}
+	data, err := aead.Open(nil, snap.Nonce, snap.Ciphertext, nil)
+	if err != nil {
+		return fmt.Errorf("%w: snapshot not decryptable with the passphrase of the keeper", ErrPermissionDenied)
+	}
+	defer zeroBytes(data)
+
+	// Further snapshots of the salt are decrypted without deriving the key again.
+	k.snapshotLock.Lock()
+	k.snapshotAEADs[string(salt)] = aead
+	k.snapshotLock.Unlock()
+
+	var plain snapshotKeys
+	if err := json.Unmarshal(data, &plain); err != nil {
+		return fmt.Errorf("invalid snapshot keys: %v", err)
+	}
+	defer func() {
+		for _, key := range plain.Keys {
+			zeroBytes(key)
+		}
+	}()
+	// Check all keys before storing any, a snapshot is restored completely or
+	// not at all.
+	for prvID, rawKey := range plain.Keys {
+		key, err := parseRawKey(rawKey)
+		if err != nil {
+			return fmt.Errorf("snapshot key %s: %w", prvID, err)
+		}
+		ZeroKey(key)
+	}
+	for prvID, rawKey := range plain.Keys {
+		if err := k.storeAs([]byte(prvID), rawKey); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// snapshotKeyIDs returns the metadata snapshot of a keeper of a remote backend,
+// listing the IDs of its keys.
+func snapshotKeyIDs(ctx context.Context, k PrivateKeyKeeperContext) ([]byte, error) {
+	prvIDs, err := k.ListPrivateKeysContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	snap := metadataSnapshot{Version: snapshotVersion, KeyIDs: make([]string, len(prvIDs))}
+	for i, prvID := range prvIDs {
+		snap.KeyIDs[i] = hex.EncodeToString(prvID)
+	}
+	return json.Marshal(snap)
+}
+
+// restoreKeyIDs checks that the keys listed in the metadata snapshot of a
+// keeper of a remote backend are still there, failing with ErrKeyNotFound
+// otherwise. There is nothing else to restore, the keys live in the backend.
+func restoreKeyIDs(ctx context.Context, k PrivateKeyKeeperContext, snapshot []byte) error {
+	var snap metadataSnapshot
+	if err := json.Unmarshal(snapshot, &snap); err != nil {
+		return fmt.Errorf("invalid snapshot: %v", err)
+	}
+	if snap.Version != snapshotVersion {
+		return fmt.Errorf("unsupported snapshot version %d", snap.Version)
+	}
+	for _, id := range snap.KeyIDs {
+		prvID, err := hex.DecodeString(id)
+		if err != nil {
+			return fmt.Errorf("invalid snapshot key id %q: %v", id, err)
+		}
+		if _, err := k.GetPublicKeyContext(ctx, prvID); err != nil {
+			return fmt.Errorf("snapshot key %s: %w", prvID, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package keeper
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestEncryptedMemoryKeeperSnapshot(t *testing.T) {
+	k, err := newEncryptedMemoryKeeper("passphrase", 1<<10, rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to create keeper: %v", err)
+	}
+	pubs := make(map[string][]byte)
+	for i := 0; i < 3; i++ {
+		prvID, err := k.GeneratePrivateKey()
+		if err != nil {
+			t.Fatalf("failed to generate key: %v", err)
+		}
+		pubs[string(prvID)], _ = k.GetPublicKey(prvID)
+	}
+	snapshot, err := k.Snapshot()
+	if err != nil {
+		t.Fatalf("failed to take snapshot: %v", err)
+	}
+	for prvID := range pubs {
+		key, _ := k.open([]byte(prvID))
+		if bytes.Contains(snapshot, []byte(hex.EncodeToString(crypto.FromECDSA(key)))) {
+			t.Fatal("snapshot holds a plain key")
+		}
+		ZeroKey(key)
+	}
+
+	// A keeper of the next process has a fresh KEK, but the same passphrase.
+	restored, err := newEncryptedMemoryKeeper("passphrase", 1<<10, rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to create keeper: %v", err)
+	}
+	if err := restored.Restore(snapshot); err != nil {
+		t.Fatalf("failed to restore snapshot: %v", err)
+	}
+	if ids, _ := restored.ListPrivateKeys(); len(ids) != len(pubs) {
+		t.Fatalf("restored keys: have %d, want %d", len(ids), len(pubs))
+	}
+	for prvID, want := range pubs {
+		if pub, err := restored.GetPublicKey([]byte(prvID)); err != nil || !bytes.Equal(pub, want) {
+			t.Fatalf("restored key %s: have (%x, %v), want %x", prvID, pub, err, want)
+		}
+	}
+
+	// Each keeper draws a salt of its own and keeps it for its snapshots.
+	var first, second, third encryptedSnapshot
+	json.Unmarshal(snapshot, &first)
+	again, _ := k.Snapshot()
+	json.Unmarshal(again, &second)
+	fromRestored, _ := restored.Snapshot()
+	json.Unmarshal(fromRestored, &third)
+	if len(first.KDF.Salt) != 32 || !bytes.Equal(first.KDF.Salt, second.KDF.Salt) {
+		t.Fatalf("snapshot salts of a keeper: have %x and %x, want one 32 byte salt", first.KDF.Salt, second.KDF.Salt)
+	}
+	if bytes.Equal(first.KDF.Salt, third.KDF.Salt) {
+		t.Fatal("keepers share a snapshot salt")
+	}
+	if err := k.Restore(fromRestored); err != nil {
+		t.Fatalf("failed to restore snapshot of other keeper: %v", err)
+	}
+
+	other, _ := newEncryptedMemoryKeeper("other", 1<<10, rand.Reader)
+	if err := other.Restore(snapshot); !errors.Is(err, ErrPermissionDenied) {
+		t.Fatalf("restore with other passphrase: have %v, want %v", err, ErrPermissionDenied)
+	}
+	var snap encryptedSnapshot
+	json.Unmarshal(snapshot, &snap)
+	snap.Ciphertext[len(snap.Ciphertext)/2] ^= 1
+	tampered, _ := json.Marshal(snap)
+	empty, _ := newEncryptedMemoryKeeper("passphrase", 1<<10, rand.Reader)
+	if err := empty.Restore(tampered); !errors.Is(err, ErrPermissionDenied) {
+		t.Fatalf("restore tampered snapshot: have %v, want %v", err, ErrPermissionDenied)
+	}
+	if ids, _ := empty.ListPrivateKeys(); len(ids) != 0 {
+		t.Fatalf("keys of tampered snapshot restored: %d", len(ids))
+	}
+}
+
+func TestRemoteKeeperSnapshot(t *testing.T) {
+	fake := newFakeKMS()
+	k := newAWSKMSKeeper(fake, "")
+	prvID, err := k.GeneratePrivateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	snapshot, err := k.Snapshot()
+	if err != nil {
+		t.Fatalf("failed to take snapshot: %v", err)
+	}
+	var snap metadataSnapshot
+	if err := json.Unmarshal(snapshot, &snap); err != nil || len(snap.KeyIDs) != 1 {
+		t.Fatalf("snapshot mismatch: have (%s, %v), want one key id", snapshot, err)
+	}
+	if err := newAWSKMSKeeper(fake, "").Restore(snapshot); err != nil {
+		t.Fatalf("failed to restore snapshot: %v", err)
+	}
+	if err := k.DeletePrivateKey(prvID); err != nil {
+		t.Fatalf("failed to delete key: %v", err)
+	}
+	if err := newAWSKMSKeeper(fake, "").Restore(snapshot); !errors.Is(err, ErrKeyNotFound) {
+		t.Fatalf("restore snapshot of deleted key: have %v, want %v", err, ErrKeyNotFound)
+	}
+}
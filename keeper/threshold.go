@@ -0,0 +1,168 @@
+package keeper
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// ThresholdSigner signs transactions only once enough parties approved them:
+//
+//	proposalID, err := s.ProposeSign(tx, signer, prvID)
+//	// ... every approving party signs proposalID with its own key ...
+//	err = s.CollectSignature(proposalID, approval)
+//	// ... once the threshold is met ...
+//	signed, err := s.FinalizeSign(proposalID)
+type ThresholdSigner interface {
+	// ProposeSign proposes signing tx with the key prvID and returns the
+	// identifier of the proposal, the 32 byte hash parties approve. Proposing
+	// the same signing again returns the same proposal.
+	ProposeSign(tx *types.Transaction, s types.Signer, prvID []byte) (proposalID []byte, err error)
+	// CollectSignature records the approval of a party, its 65 byte signature of
+	// the proposal ID. Signatures not recovering to a party fail with
+	// ErrInvalidSignature.
+	CollectSignature(proposalID, sig []byte) error
+	// FinalizeSign signs the transaction of the proposal once enough parties
+	// approved it, failing with ErrThresholdNotMet before. The proposal is
+	// done once signed, failed signings can be finalized again. Finalizing a
+	// proposal while it is being signed fails with ErrDuplicateSigning.
+	FinalizeSign(proposalID []byte) (*types.Transaction, error)
+}
+
+// signProposal is a transaction waiting for approvals.
+type signProposal struct {
+	tx        *types.Transaction
+	signer    types.Signer
+	prvID     []byte
+	approvals map[common.Address]struct{}
+	signing   bool // FinalizeSign is signing the transaction
+}
+
+// thresholdSigner is a ThresholdSigner requiring m approvals of its parties.
+type thresholdSigner struct {
+	m       int
+	parties map[common.Address]struct{}
+	inner   SecureSigner
+
+	lock      sync.Mutex
+	proposals map[common.Hash]*signProposal
+}
+
+// NewMofNThresholdSigner returns a ThresholdSigner signing transactions with
+// inner once m of the n parties, given by the addresses of their keys, approved
+// them.
+//
+// The approvals gate the signing, they are not combined into the signature:
+// ECDSA signatures can't be aggregated, threshold ECDSA schemes need the
+// parties to run an interactive protocol over shares of the key. The
+// transaction is signed by the single key prvID, protecting it is up to inner,
+// e.g. by splitting it with a Shamir keeper. For signatures the chain checks
+// against m-of-n parties, use a multisig contract.
+func NewMofNThresholdSigner(m, n int, inner SecureSigner, parties []common.Address) (ThresholdSigner, error) {
+	if len(parties) != n {
+		return nil, fmt.Errorf("threshold signer with %d parties, want %d", len(parties), n)
+	}
+	s := &thresholdSigner{m: m, parties: make(map[common.Address]struct{}, n), inner: inner, proposals: make(map[common.Hash]*signProposal)}
+	for _, party := range parties {
+		s.parties[party] = struct{}{}
+	}
+	if m < 1 || m > n || len(s.parties) != n {
+		return nil, fmt.Errorf("invalid threshold parameters: %d of %d distinct parties", m, len(s.parties))
+	}
+	return s, nil
+}
+
+// proposalID identifies the signing of tx with signer and the key prvID. The
+// hash of prvID is used, as the prvID of some keepers is the key itself.
+func proposalID(tx *types.Transaction, signer types.Signer, prvID []byte) common.Hash {
+	return crypto.Keccak256Hash(signer.Hash(tx).Bytes(), crypto.Keccak256(prvID))
+}
+
+func (s *thresholdSigner) ProposeSign(tx *types.Transaction, signer types.Signer, prvID []byte) (_ []byte, err error) {
+	defer wrapError(&err, "propose sign", prvID)
+
+	// Refuse proposals the key can't sign before collecting approvals.
+	if _, err := s.inner.GetPublicKey(prvID); err != nil {
+		return nil, err
+	}
+	id := proposalID(tx, signer, prvID)
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if _, ok := s.proposals[id]; !ok {
+		s.proposals[id] = &signProposal{tx: tx, signer: signer, prvID: slices.Clone(prvID), approvals: make(map[common.Address]struct{})}
+	}
+	return id.Bytes(), nil
+}
+
+func (s *thresholdSigner) CollectSignature(id, sig []byte) (err error) {
+	defer wrapError(&err, "collect signature", nil)
+
+	if len(id) != common.HashLength {
+		return ErrProposalNotFound
+	}
+	pub, err := crypto.SigToPub(id, sig)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidSignature, err)
+	}
+	party := crypto.PubkeyToAddress(*pub)
+	if _, ok := s.parties[party]; !ok {
+		return fmt.Errorf("%w: %v is not a party", ErrInvalidSignature, party)
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	proposal, ok := s.proposals[common.BytesToHash(id)]
+	if !ok {
+		return ErrProposalNotFound
+	}
+	// Approving twice is harmless, it counts once.
+	proposal.approvals[party] = struct{}{}
+	return nil
+}
+
+func (s *thresholdSigner) FinalizeSign(id []byte) (_ *types.Transaction, err error) {
+	defer wrapError(&err, "finalize sign", nil)
+
+	if len(id) != common.HashLength {
+		return nil, ErrProposalNotFound
+	}
+	key := common.BytesToHash(id)
+
+	s.lock.Lock()
+	proposal, ok := s.proposals[key]
+	switch {
+	case !ok:
+		s.lock.Unlock()
+		return nil, ErrProposalNotFound
+	case proposal.signing:
+		s.lock.Unlock()
+		return nil, fmt.Errorf("%w: proposal is being signed", ErrDuplicateSigning)
+	case len(proposal.approvals) < s.m:
+		approvals := len(proposal.approvals)
+		s.lock.Unlock()
+		return nil, fmt.Errorf("%w: %d of %d approvals", ErrThresholdNotMet, approvals, s.m)
+	}
+	// Marking the proposal keeps it from being signed twice, it is only done
+	// once signed, so that failed signings keep their approvals.
+	proposal.signing = true
+	s.lock.Unlock()
+
+	signed, err := s.inner.Sign(proposal.tx, proposal.signer, proposal.prvID)
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if err != nil {
+		proposal.signing = false
+		return nil, err
+	}
+	delete(s.proposals, key)
+	return signed, nil
+}
@@ -0,0 +1,195 @@
+package keeper
+
+import (
+	"errors"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+func TestThresholdSigner(t *testing.T) {
+	sec := NewSecureSigner(new(defaultPrivateKeyKeeper))
+	prvID, _ := sec.GenerateKey()
+	addr, _ := sec.GetAddress(prvID)
+
+	// Each party approves with a key of its own.
+	var (
+		partyKeys [][]byte
+		parties   []common.Address
+	)
+	for range 3 {
+		key, _ := sec.GenerateKey()
+		party, _ := sec.GetAddress(key)
+		partyKeys, parties = append(partyKeys, key), append(parties, party)
+	}
+	s, err := NewMofNThresholdSigner(2, 3, sec, parties)
+	if err != nil {
+		t.Fatalf("failed to create signer: %v", err)
+	}
+	signer := types.LatestSignerForChainID(big.NewInt(1))
+	tx := newTestTx()
+
+	// All parties propose the same signing concurrently and approve it, two
+	// approvals suffice.
+	ids := make([][]byte, 2)
+	var wg sync.WaitGroup
+	for i := range ids {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			id, err := s.ProposeSign(tx, signer, prvID)
+			if err != nil {
+				t.Errorf("party %d: failed to propose: %v", i, err)
+				return
+			}
+			ids[i] = id
+			sig, _ := sec.SignHash(common.BytesToHash(id), partyKeys[i])
+			if err := s.CollectSignature(id, sig); err != nil {
+				t.Errorf("party %d: failed to approve: %v", i, err)
+			}
+		}()
+	}
+	wg.Wait()
+	if common.BytesToHash(ids[0]) != common.BytesToHash(ids[1]) {
+		t.Fatalf("proposals differ: %x != %x", ids[0], ids[1])
+	}
+	signed, err := s.FinalizeSign(ids[0])
+	if err != nil {
+		t.Fatalf("failed to finalize: %v", err)
+	}
+	if from, err := types.Sender(signer, signed); err != nil || from != addr {
+		t.Fatalf("sender: have (%v, %v), want %v", from, err, addr)
+	}
+	if _, err := s.FinalizeSign(ids[0]); !errors.Is(err, ErrProposalNotFound) {
+		t.Fatalf("second finalize: have %v, want %v", err, ErrProposalNotFound)
+	}
+}
+
+func TestThresholdSignerApprovals(t *testing.T) {
+	sec := NewSecureSigner(new(defaultPrivateKeyKeeper))
+	prvID, _ := sec.GenerateKey()
+	party, _ := sec.GenerateKey()
+	partyAddr, _ := sec.GetAddress(party)
+	outsider, _ := sec.GenerateKey()
+	s, err := NewMofNThresholdSigner(2, 2, sec, []common.Address{partyAddr, {1}})
+	if err != nil {
+		t.Fatalf("failed to create signer: %v", err)
+	}
+	id, err := s.ProposeSign(newTestTx(), types.HomesteadSigner{}, prvID)
+	if err != nil {
+		t.Fatalf("failed to propose: %v", err)
+	}
+	sig, _ := sec.SignHash(common.BytesToHash(id), party)
+	if err := s.CollectSignature(id, sig); err != nil {
+		t.Fatalf("failed to approve: %v", err)
+	}
+	// Approving twice doesn't count twice.
+	if err := s.CollectSignature(id, sig); err != nil {
+		t.Fatalf("failed to approve again: %v", err)
+	}
+	if _, err := s.FinalizeSign(id); !errors.Is(err, ErrThresholdNotMet) {
+		t.Fatalf("finalize with one approval: have %v, want %v", err, ErrThresholdNotMet)
+	}
+	outsiderSig, _ := sec.SignHash(common.BytesToHash(id), outsider)
+	if err := s.CollectSignature(id, outsiderSig); !errors.Is(err, ErrInvalidSignature) {
+		t.Fatalf("outsider approval: have %v, want %v", err, ErrInvalidSignature)
+	}
+	if err := s.CollectSignature(id, sig[:64]); !errors.Is(err, ErrInvalidSignature) {
+		t.Fatalf("truncated approval: have %v, want %v", err, ErrInvalidSignature)
+	}
+	other := make([]byte, 32)
+	otherSig, _ := sec.SignHash(common.BytesToHash(other), party)
+	if err := s.CollectSignature(other, otherSig); !errors.Is(err, ErrProposalNotFound) {
+		t.Fatalf("approval of unknown proposal: have %v, want %v", err, ErrProposalNotFound)
+	}
+	if _, err := s.ProposeSign(newTestTx(), types.HomesteadSigner{}, []byte("unknown")); err == nil {
+		t.Fatal("proposed signing with unknown key")
+	}
+
+	for _, tt := range []struct {
+		m, n    int
+		parties []common.Address
+	}{
+		{0, 1, []common.Address{{1}}},
+		{3, 2, []common.Address{{1}, {2}}},
+		{2, 3, []common.Address{{1}, {2}}},
+		{2, 2, []common.Address{{1}, {1}}},
+	} {
+		if _, err := NewMofNThresholdSigner(tt.m, tt.n, sec, tt.parties); err == nil {
+			t.Errorf("%d of %d with %v accepted", tt.m, tt.n, tt.parties)
+		}
+	}
+}
+
+// failingSigner is a SecureSigner whose signatures fail with err while it is
+// set, waiting for release first if it is not nil.
+type failingSigner struct {
+	SecureSigner
+	err     error
+	release chan struct{}
+}
+
+func (s *failingSigner) Sign(tx *types.Transaction, signer types.Signer, prvID []byte) (*types.Transaction, error) {
+	if s.release != nil {
+		<-s.release
+	}
+	if s.err != nil {
+		return nil, s.err
+	}
+	return s.SecureSigner.Sign(tx, signer, prvID)
+}
+
+func TestThresholdSignerFailedSigning(t *testing.T) {
+	sec := NewSecureSigner(new(defaultPrivateKeyKeeper))
+	prvID, _ := sec.GenerateKey()
+	party, _ := sec.GenerateKey()
+	partyAddr, _ := sec.GetAddress(party)
+
+	inner := &failingSigner{SecureSigner: sec, err: ErrBackendUnavailable}
+	s, err := NewMofNThresholdSigner(1, 1, inner, []common.Address{partyAddr})
+	if err != nil {
+		t.Fatalf("failed to create signer: %v", err)
+	}
+	id, _ := s.ProposeSign(newTestTx(), types.HomesteadSigner{}, prvID)
+	sig, _ := sec.SignHash(common.BytesToHash(id), party)
+	if err := s.CollectSignature(id, sig); err != nil {
+		t.Fatalf("failed to approve: %v", err)
+	}
+	if _, err := s.FinalizeSign(id); !errors.Is(err, ErrBackendUnavailable) {
+		t.Fatalf("failing signing: have %v, want %v", err, ErrBackendUnavailable)
+	}
+
+	// The proposal and its approvals survive the failure. While it is signed
+	// again, it can't be finalized a second time.
+	inner.err, inner.release = nil, make(chan struct{})
+	done := make(chan error, 1)
+	go func() {
+		_, err := s.FinalizeSign(id)
+		done <- err
+	}()
+	for !signing(s.(*thresholdSigner), id) {
+		time.Sleep(time.Millisecond)
+	}
+	if _, err := s.FinalizeSign(id); !errors.Is(err, ErrDuplicateSigning) {
+		t.Fatalf("finalize while signing: have %v, want %v", err, ErrDuplicateSigning)
+	}
+	close(inner.release)
+	if err := <-done; err != nil {
+		t.Fatalf("failed to finalize after failure: %v", err)
+	}
+	if _, err := s.FinalizeSign(id); !errors.Is(err, ErrProposalNotFound) {
+		t.Fatalf("finalize after signing: have %v, want %v", err, ErrProposalNotFound)
+	}
+}
+
+// signing reports whether the proposal id is being signed.
+func signing(s *thresholdSigner, id []byte) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	proposal, ok := s.proposals[common.BytesToHash(id)]
+	return ok && proposal.signing
+}
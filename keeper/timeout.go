@@ -0,0 +1,137 @@
+package keeper
+
+import (
+	"context"
+	"time"
+)
+
+// timeoutKeeper is a PrivateKeyKeeper bounding the time its callers wait for
+// the backend, so that a hanging HSM or KMS doesn't hang them as well.
+type timeoutKeeper struct {
+	inner         PrivateKeyKeeperContext
+	signTimeout   time.Duration
+	keyGenTimeout time.Duration
+	pubKeyTimeout time.Duration
+}
+
+// NewTimeoutKeeper returns a PrivateKeyKeeper failing the calls to inner with
+// ErrOperationTimeout once they took longer than their timeout: signTimeout for
+// signatures, keyGenTimeout for generating and importing keys and pubKeyTimeout
+// for the other key lookups. Zero timeouts leave the calls unbounded.
+//
+// The context passed to inner carries the deadline, but the call returns at the
+// deadline even if inner doesn't heed it. Such an inner call is left running in
+// the background until it returns, its result is dropped. For generated keys
+// this means that the key is left behind unused.
+func NewTimeoutKeeper(inner PrivateKeyKeeper, signTimeout, keyGenTimeout, pubKeyTimeout time.Duration) PrivateKeyKeeper {
+	return &timeoutKeeper{
+		inner:         ContextKeeper(inner),
+		signTimeout:   signTimeout,
+		keyGenTimeout: keyGenTimeout,
+		pubKeyTimeout: pubKeyTimeout,
+	}
+}
+
+// withTimeout runs fn with a context expiring after timeout, returning when fn
+// did or when the context is done, whichever happens first. Calls exceeding
+// timeout fail with ErrOperationTimeout, calls aborted by ctx with its error.
+func withTimeout[T any](ctx context.Context, timeout time.Duration, fn func(ctx context.Context) (T, error)) (T, error) {
+	if timeout <= 0 {
+		return fn(ctx)
+	}
+	tctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	type result struct {
+		val T
+		err error
+	}
+	done := make(chan result, 1)
+	go func() {
+		val, err := fn(tctx)
+		done <- result{val, err}
+	}()
+
+	var zero T
+	select {
+	case res := <-done:
+		// Inner calls heeding the deadline fail with the error of the
+		// context, report them as timed out as well.
+		if res.err != nil && tctx.Err() == context.DeadlineExceeded && ctx.Err() == nil {
+			return zero, ErrOperationTimeout
+		}
+		return res.val, res.err
+	case <-tctx.Done():
+		if err := ctx.Err(); err != nil {
+			return zero, err
+		}
+		return zero, ErrOperationTimeout
+	}
+}
+
+func (k *timeoutKeeper) GeneratePrivateKey() ([]byte, error) {
+	return k.GeneratePrivateKeyContext(context.Background())
+}
+
+func (k *timeoutKeeper) GeneratePrivateKeyContext(ctx context.Context) (_ []byte, err error) {
+	defer wrapError(&err, "generate key", nil)
+	return withTimeout(ctx, k.keyGenTimeout, k.inner.GeneratePrivateKeyContext)
+}
+
+func (k *timeoutKeeper) GetPublicKey(prvID []byte) ([]byte, error) {
+	return k.GetPublicKeyContext(context.Background(), prvID)
+}
+
+func (k *timeoutKeeper) GetPublicKeyContext(ctx context.Context, prvID []byte) (_ []byte, err error) {
+	defer wrapError(&err, "get public key", prvID)
+	return withTimeout(ctx, k.pubKeyTimeout, func(ctx context.Context) ([]byte, error) {
+		return k.inner.GetPublicKeyContext(ctx, prvID)
+	})
+}
+
+func (k *timeoutKeeper) Sign(data []byte, prvID []byte) ([]byte, error) {
+	return k.SignContext(context.Background(), data, prvID)
+}
+
+func (k *timeoutKeeper) SignContext(ctx context.Context, data []byte, prvID []byte) (_ []byte, err error) {
+	defer wrapError(&err, "sign", prvID)
+	return withTimeout(ctx, k.signTimeout, func(ctx context.Context) ([]byte, error) {
+		return k.inner.SignContext(ctx, data, prvID)
+	})
+}
+
+func (k *timeoutKeeper) DeletePrivateKey(prvID []byte) error {
+	return k.DeletePrivateKeyContext(context.Background(), prvID)
+}
+
+func (k *timeoutKeeper) DeletePrivateKeyContext(ctx context.Context, prvID []byte) (err error) {
+	defer wrapError(&err, "delete key", prvID)
+	_, err = withTimeout(ctx, k.pubKeyTimeout, func(ctx context.Context) (struct{}, error) {
+		return struct{}{}, k.inner.DeletePrivateKeyContext(ctx, prvID)
+	})
+	return err
+}
+
+func (k *timeoutKeeper) ListPrivateKeys() ([][]byte, error) {
+	return k.ListPrivateKeysContext(context.Background())
+}
+
+func (k *timeoutKeeper) ListPrivateKeysContext(ctx context.Context) (_ [][]byte, err error) {
+	defer wrapError(&err, "list keys", nil)
+	return withTimeout(ctx, k.pubKeyTimeout, k.inner.ListPrivateKeysContext)
+}
+
+func (k *timeoutKeeper) ImportPrivateKey(rawKey []byte) ([]byte, error) {
+	return k.ImportPrivateKeyContext(context.Background(), rawKey)
+}
+
+func (k *timeoutKeeper) ImportPrivateKeyContext(ctx context.Context, rawKey []byte) (_ []byte, err error) {
+	defer wrapError(&err, "import key", nil)
+	return withTimeout(ctx, k.keyGenTimeout, func(ctx context.Context) ([]byte, error) {
+		return k.inner.ImportPrivateKeyContext(ctx, rawKey)
+	})
+}
+
+func (k *timeoutKeeper) HealthCheck(ctx context.Context) error {
+	return checkHealth(ctx, k.inner)
+}
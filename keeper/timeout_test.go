@@ -0,0 +1,75 @@
+package keeper
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestTimeoutKeeper(t *testing.T) {
+	var (
+		inner   = &slowKeeper{PrivateKeyKeeper: new(defaultPrivateKeyKeeper), release: make(chan struct{})}
+		timeout = 50 * time.Millisecond
+		k       = NewTimeoutKeeper(inner, timeout, 0, 0)
+		hash    = make([]byte, 32)
+	)
+	prvID, err := k.GeneratePrivateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+
+	start := time.Now()
+	_, err = k.Sign(hash, prvID)
+	if elapsed := time.Since(start); elapsed < timeout || elapsed > timeout+time.Second {
+		t.Fatalf("timeout fired after %v, want %v", elapsed, timeout)
+	}
+	if !errors.Is(err, ErrOperationTimeout) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("hanging signature: have %v, want %v", err, ErrOperationTimeout)
+	}
+
+	// A caller giving up before the timeout gets the error of its context.
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(5*time.Millisecond, cancel)
+	if _, err := ContextKeeper(k).SignContext(ctx, hash, prvID); !errors.Is(err, context.Canceled) {
+		t.Fatalf("cancelled signature: have %v, want %v", err, context.Canceled)
+	}
+
+	// Signatures released in time succeed, the ones left behind by the timed
+	// out calls don't interfere.
+	time.AfterFunc(10*time.Millisecond, func() { close(inner.release) })
+	if _, err := k.Sign(hash, prvID); err != nil {
+		t.Fatalf("failed to sign in time: %v", err)
+	}
+}
+
+func TestTimeoutKeeperContext(t *testing.T) {
+	// Backends heeding the deadline fail with the error of the context, which
+	// is reported as a timeout too.
+	inner := &deadlineKeeper{defaultPrivateKeyKeeper: new(defaultPrivateKeyKeeper), deadlines: make(chan time.Time, 1)}
+	k := NewTimeoutKeeper(inner, 0, 0, 10*time.Millisecond)
+	if _, err := k.GetPublicKey(make([]byte, 32)); !errors.Is(err, ErrOperationTimeout) {
+		t.Fatalf("public key lookup: have %v, want %v", err, ErrOperationTimeout)
+	}
+	if deadline := <-inner.deadlines; deadline.IsZero() {
+		t.Fatal("deadline not passed to backend")
+	}
+}
+
+// deadlineKeeper is a PrivateKeyKeeper whose public key lookups wait for their
+// context to be done, sending its deadline to deadlines.
+type deadlineKeeper struct {
+	*defaultPrivateKeyKeeper
+	deadlines chan time.Time
+}
+
+func (k *deadlineKeeper) GetPublicKey(prvID []byte) ([]byte, error) {
+	return k.GetPublicKeyContext(context.Background(), prvID)
+}
+
+func (k *deadlineKeeper) GetPublicKeyContext(ctx context.Context, prvID []byte) ([]byte, error) {
+	deadline, _ := ctx.Deadline()
+	k.deadlines <- deadline
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
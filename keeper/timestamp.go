@@ -0,0 +1,60 @@
+package keeper
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// timestampLength is the length of the timestamp appended to the signature by
+// SignWithTimestamp.
+const timestampLength = 8
+
+// timestampedHash returns the hash signed for data at the Unix nano timestamp
+// ts, binding the signature to the time.
+func timestampedHash(data []byte, ts [timestampLength]byte) []byte {
+	return crypto.Keccak256(data, ts[:])
+}
+
+// SignWithTimestamp signs data together with the current time with the key
+// prvID of s, proving when the signature was made, e.g. for non-repudiation
+// logs. The signature covers keccak256(data || ts), ts being the time in Unix
+// nanoseconds as 8 big endian bytes, and is made with SignHash. Besides the
+// signature and the time, the signature with ts appended is returned, as
+// checked by VerifyTimestampedSignature.
+func SignWithTimestamp(s SecureSigner, data []byte, prvID []byte) (sig []byte, ts time.Time, combined []byte, err error) {
+	defer wrapError(&err, "sign with timestamp", prvID)
+
+	ts = time.Now()
+	var stamp [timestampLength]byte
+	binary.BigEndian.PutUint64(stamp[:], uint64(ts.UnixNano()))
+
+	sig, err = s.SignHash([32]byte(timestampedHash(data, stamp)), prvID)
+	if err != nil {
+		return nil, time.Time{}, nil, err
+	}
+	return sig, time.Unix(0, ts.UnixNano()), append(bytes.Clone(sig), stamp[:]...), nil
+}
+
+// VerifyTimestampedSignature checks that combined, as returned by
+// SignWithTimestamp, holds a signature of data made by the uncompressed public
+// key pubKey, and returns the time it was made at.
+func VerifyTimestampedSignature(data, combined []byte, pubKey []byte) (ts time.Time, err error) {
+	defer wrapError(&err, "verify timestamped signature", nil)
+
+	if len(combined) != crypto.SignatureLength+timestampLength {
+		return time.Time{}, fmt.Errorf("%w: timestamped signature must be %d bytes long (%d)", ErrInvalidSignature, crypto.SignatureLength+timestampLength, len(combined))
+	}
+	sig, stamp := combined[:crypto.SignatureLength], [timestampLength]byte(combined[crypto.SignatureLength:])
+	recovered, err := recoverPubkey(timestampedHash(data, stamp), sig)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if !bytes.Equal(crypto.FromECDSAPub(recovered), pubKey) {
+		return time.Time{}, fmt.Errorf("%w: not signed by the key", ErrInvalidSignature)
+	}
+	return time.Unix(0, int64(binary.BigEndian.Uint64(stamp[:]))), nil
+}
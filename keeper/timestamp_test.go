@@ -0,0 +1,55 @@
+package keeper
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSignWithTimestamp(t *testing.T) {
+	s := NewSecureSigner(defaultKeeper)
+	prvID, _ := s.GenerateKey()
+	pub, _ := s.GetPublicKey(prvID)
+	data := []byte("dispute")
+
+	before := time.Now()
+	sig, ts, combined, err := SignWithTimestamp(s, data, prvID)
+	if err != nil {
+		t.Fatalf("failed to sign: %v", err)
+	}
+	if ts.Before(before) || ts.After(time.Now()) {
+		t.Fatalf("timestamp %v not the signing time", ts)
+	}
+	if len(combined) != 73 || !bytes.Equal(combined[:65], sig) {
+		t.Fatalf("combined signature mismatch: have %x, want %x and timestamp", combined, sig)
+	}
+	verified, err := VerifyTimestampedSignature(data, combined, pub)
+	if err != nil {
+		t.Fatalf("failed to verify: %v", err)
+	}
+	if !verified.Equal(ts) {
+		t.Fatalf("verified timestamp mismatch: have %v, want %v", verified, ts)
+	}
+
+	backdated := bytes.Clone(combined)
+	backdated[len(backdated)-5]--
+	otherID, _ := s.GenerateKey()
+	other, _ := s.GetPublicKey(otherID)
+	tests := []struct {
+		name     string
+		data     []byte
+		combined []byte
+		pub      []byte
+	}{
+		{"backdated", data, backdated, pub},
+		{"other data", []byte("other"), combined, pub},
+		{"other key", data, combined, other},
+		{"truncated", data, combined[:72], pub},
+	}
+	for _, tt := range tests {
+		if _, err := VerifyTimestampedSignature(tt.data, tt.combined, tt.pub); !errors.Is(err, ErrInvalidSignature) {
+			t.Errorf("%s: have %v, want %v", tt.name, err, ErrInvalidSignature)
+		}
+	}
+}
@@ -0,0 +1,264 @@
+package keeper
+
+import (
+	"crypto/ecdsa"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/google/go-tpm/tpm2"
+	"github.com/google/go-tpm/tpm2/transport"
+)
+
+// tpmKeeper is a PrivateKeyKeeper protecting keys with a TPM 2.0 chip.
+//
+// TPMs don't implement the secp256k1 curve, so keys can't be generated and
+// used inside of the TPM. Instead every key is sealed to the TPM: it is
+// encrypted under the storage root key of the owner hierarchy and only the TPM
+// is able to unseal it again. The sealed key is unsealed for the duration of a
+// single signing operation only. The prvID is the sealed object, it is useless
+// without the TPM it was created on.
+type tpmKeeper struct {
+	tpm  transport.TPM
+	lock sync.Mutex // serialises TPM commands
+
+	pubkeys sync.Map // prvID -> uncompressed public key
+}
+
+func newTPMKeeper(tpm transport.TPM) *tpmKeeper {
+	return &tpmKeeper{tpm: tpm}
+}
+
+// withSRK runs fn with the storage root key loaded. The SRK is derived from
+// the owner seed, so it is the same key every time it is created.
+func (k *tpmKeeper) withSRK(fn func(srk *tpm2.CreatePrimaryResponse) error) error {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+
+	srk, err := tpm2.CreatePrimary{
+		PrimaryHandle: tpm2.TPMRHOwner,
+		InPublic:      tpm2.New2B(tpm2.ECCSRKTemplate),
+	}.Execute(k.tpm)
+	if err != nil {
+		return fmt.Errorf("failed to create TPM storage root key: %w", err)
+	}
+	defer tpm2.FlushContext{FlushHandle: srk.ObjectHandle}.Execute(k.tpm)
+	return fn(srk)
+}
+
+// srkSession returns an HMAC session salted with the storage root key. The
+// encryption option protects the key material on the bus between the keeper
+// and the TPM.
+func srkSession(srk *tpm2.CreatePrimaryResponse, encryption tpm2.AuthOption) (tpm2.Session, error) {
+	pub, err := srk.OutPublic.Contents()
+	if err != nil {
+		return nil, err
+	}
+	return tpm2.HMAC(tpm2.TPMAlgSHA256, 16, encryption, tpm2.Salted(srk.ObjectHandle, *pub)), nil
+}
+
+func (k *tpmKeeper) GeneratePrivateKey() (_ []byte, err error) {
+	defer wrapError(&err, "generate key", nil)
+
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		return nil, err
+	}
+	defer ZeroKey(key)
+	return k.seal(key)
+}
+
+// ImportPrivateKey seals rawKey to the TPM, the same as generated keys.
+func (k *tpmKeeper) ImportPrivateKey(rawKey []byte) (_ []byte, err error) {
+	defer wrapError(&err, "import key", nil)
+
+	key, err := parseRawKey(rawKey)
+	if err != nil {
+		return nil, err
+	}
+	defer ZeroKey(key)
+	return k.seal(key)
+}
+
+// seal seals key to the TPM and returns the sealed object as prvID.
+func (k *tpmKeeper) seal(key *ecdsa.PrivateKey) ([]byte, error) {
+	secret := crypto.FromECDSA(key)
+	defer zeroBytes(secret)
+
+	var prvID []byte
+	err := k.withSRK(func(srk *tpm2.CreatePrimaryResponse) error {
+		session, err := srkSession(srk, tpm2.AESEncryption(128, tpm2.EncryptIn))
+		if err != nil {
+			return err
+		}
+		sealed, err := tpm2.Create{
+			ParentHandle: tpm2.AuthHandle{Handle: srk.ObjectHandle, Name: srk.Name, Auth: session},
+			InSensitive: tpm2.TPM2BSensitiveCreate{
+				Sensitive: &tpm2.TPMSSensitiveCreate{
+					Data: tpm2.NewTPMUSensitiveCreate(&tpm2.TPM2BSensitiveData{Buffer: secret}),
+				},
+			},
+			InPublic: tpm2.New2B(tpm2.TPMTPublic{
+				Type:    tpm2.TPMAlgKeyedHash,
+				NameAlg: tpm2.TPMAlgSHA256,
+				ObjectAttributes: tpm2.TPMAObject{
+					FixedTPM:     true,
+					FixedParent:  true,
+					UserWithAuth: true,
+					NoDA:         true,
+				},
+			}),
+		}.Execute(k.tpm)
+		if err != nil {
+			return err
+		}
+		prvID = encodeTPMBlob(sealed.OutPublic.Bytes(), sealed.OutPrivate.Buffer)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	k.pubkeys.Store(string(prvID), crypto.FromECDSAPub(&key.PublicKey))
+	return prvID, nil
+}
+
+// unseal loads the sealed object prvID and returns the raw private key in it.
+// The caller has to clear the key after use.
+func (k *tpmKeeper) unseal(prvID []byte) ([]byte, error) {
+	public, private, err := decodeTPMBlob(prvID)
+	if err != nil {
+		return nil, err
+	}
+	var secret []byte
+	err = k.withSRK(func(srk *tpm2.CreatePrimaryResponse) error {
+		loaded, err := tpm2.Load{
+			ParentHandle: tpm2.NamedHandle{Handle: srk.ObjectHandle, Name: srk.Name},
+			InPublic:     tpm2.BytesAs2B[tpm2.TPMTPublic](public),
+			InPrivate:    tpm2.TPM2BPrivate{Buffer: private},
+		}.Execute(k.tpm)
+		if err != nil {
+			return fmt.Errorf("failed to load sealed key: %w", err)
+		}
+		defer tpm2.FlushContext{FlushHandle: loaded.ObjectHandle}.Execute(k.tpm)
+
+		session, err := srkSession(srk, tpm2.AESEncryption(128, tpm2.EncryptOut))
+		if err != nil {
+			return err
+		}
+		unsealed, err := tpm2.Unseal{
+			ItemHandle: tpm2.AuthHandle{Handle: loaded.ObjectHandle, Name: loaded.Name, Auth: session},
+		}.Execute(k.tpm)
+		if err != nil {
+			return fmt.Errorf("failed to unseal key: %w", err)
+		}
+		secret = unsealed.OutData.Buffer
+		return nil
+	})
+	return secret, err
+}
+
+func (k *tpmKeeper) GetPublicKey(prvID []byte) (_ []byte, err error) {
+	defer wrapError(&err, "get public key", prvID)
+
+	if pub, ok := k.pubkeys.Load(string(prvID)); ok {
+		return pub.([]byte), nil
+	}
+	secret, err := k.unseal(prvID)
+	if err != nil {
+		return nil, err
+	}
+	defer zeroBytes(secret)
+
+	key, err := crypto.ToECDSA(secret)
+	if err != nil {
+		return nil, err
+	}
+	defer ZeroKey(key)
+	pub := crypto.FromECDSAPub(&key.PublicKey)
+	k.pubkeys.Store(string(prvID), pub)
+	return pub, nil
+}
+
+func (k *tpmKeeper) Sign(data []byte, prvID []byte) (_ []byte, err error) {
+	defer wrapError(&err, "sign", prvID)
+
+	secret, err := k.unseal(prvID)
+	if err != nil {
+		return nil, err
+	}
+	defer zeroBytes(secret)
+
+	key, err := crypto.ToECDSA(secret)
+	if err != nil {
+		return nil, err
+	}
+	defer ZeroKey(key)
+	return crypto.Sign(data, key)
+}
+
+// ExportEncryptedKey unseals the key prvID and encrypts it with passphrase.
+func (k *tpmKeeper) ExportEncryptedKey(prvID []byte, passphrase string) (_ []byte, err error) {
+	defer wrapError(&err, "export key", prvID)
+
+	secret, err := k.unseal(prvID)
+	if err != nil {
+		return nil, err
+	}
+	defer zeroBytes(secret)
+	return encryptKey(secret, passphrase)
+}
+
+func (k *tpmKeeper) ImportEncryptedKey(data []byte, passphrase string) ([]byte, error) {
+	return importEncryptedKey(k, data, passphrase)
+}
+
+// DeletePrivateKey returns ErrNotSupported. The TPM doesn't store the sealed
+// keys, destroying the prvID destroys the key.
+func (k *tpmKeeper) DeletePrivateKey(prvID []byte) (err error) {
+	defer wrapError(&err, "delete key", prvID)
+	return ErrNotSupported
+}
+
+// ListPrivateKeys returns ErrNotSupported, the TPM doesn't store the sealed
+// keys.
+func (k *tpmKeeper) ListPrivateKeys() (_ [][]byte, err error) {
+	defer wrapError(&err, "list keys", nil)
+	return nil, ErrNotSupported
+}
+
+// encodeTPMBlob concatenates the public and private area of a sealed object,
+// both prefixed with their 16 bit length the way TPM2B structures are.
+func encodeTPMBlob(public, private []byte) []byte {
+	blob := binary.BigEndian.AppendUint16(nil, uint16(len(public)))
+	blob = append(blob, public...)
+	blob = binary.BigEndian.AppendUint16(blob, uint16(len(private)))
+	return append(blob, private...)
+}
+
+// decodeTPMBlob splits a prvID created by encodeTPMBlob.
+func decodeTPMBlob(blob []byte) (public, private []byte, err error) {
+	errInvalid := errors.New("invalid TPM key blob")
+	next := func() ([]byte, bool) {
+		if len(blob) < 2 {
+			return nil, false
+		}
+		size := int(binary.BigEndian.Uint16(blob))
+		if len(blob) < 2+size {
+			return nil, false
+		}
+		part := blob[2 : 2+size]
+		blob = blob[2+size:]
+		return part, true
+	}
+	public, ok := next()
+	if !ok {
+		return nil, nil, errInvalid
+	}
+	private, ok = next()
+	if !ok || len(blob) != 0 {
+		return nil, nil, errInvalid
+	}
+	return public, private, nil
+}
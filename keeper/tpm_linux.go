@@ -0,0 +1,13 @@
+package keeper
+
+import "github.com/google/go-tpm/tpm2/transport/linuxtpm"
+
+// NewTPMKeeper returns a PrivateKeyKeeper sealing keys to the TPM 2.0 device at
+// tpmPath, usually the in-kernel resource manager /dev/tpmrm0.
+func NewTPMKeeper(tpmPath string) (PrivateKeyKeeper, error) {
+	tpm, err := linuxtpm.Open(tpmPath)
+	if err != nil {
+		return nil, err
+	}
+	return newTPMKeeper(tpm), nil
+}
@@ -0,0 +1,10 @@
+//go:build !linux
+
+package keeper
+
+import "errors"
+
+// NewTPMKeeper is only available on Linux.
+func NewTPMKeeper(tpmPath string) (PrivateKeyKeeper, error) {
+	return nil, errors.New("TPM keeper is only supported on Linux")
+}
@@ -0,0 +1,69 @@
+//go:build cgo
+
+package keeper
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/google/go-tpm/tpm2/transport/simulator"
+)
+
+func TestTPMKeeper(t *testing.T) {
+	tpm, err := simulator.OpenSimulator()
+	if err != nil {
+		t.Fatalf("failed to open TPM simulator: %v", err)
+	}
+	defer tpm.Close()
+	k := newTPMKeeper(tpm)
+
+	prvID, err := k.GeneratePrivateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	pub, err := k.GetPublicKey(prvID)
+	if err != nil {
+		t.Fatalf("failed to get public key: %v", err)
+	}
+	hash := crypto.Keccak256([]byte("tpm"))
+	sig, err := k.Sign(hash, prvID)
+	if err != nil {
+		t.Fatalf("failed to sign: %v", err)
+	}
+	recovered, err := crypto.Ecrecover(hash, sig)
+	if err != nil {
+		t.Fatalf("failed to recover: %v", err)
+	}
+	if !bytes.Equal(recovered, pub) {
+		t.Fatalf("recovered key mismatch: have %x, want %x", recovered, pub)
+	}
+	schnorrSig, err := k.SignSchnorr(hash, prvID)
+	if err != nil {
+		t.Fatalf("failed to sign schnorr: %v", err)
+	}
+	if ok, err := k.VerifySchnorr(hash, schnorrSig, pub); err != nil || !ok {
+		t.Fatalf("schnorr signature not verified: (%v, %v)", ok, err)
+	}
+	// A fresh keeper has to unseal the key to find its public key.
+	fresh := newTPMKeeper(tpm)
+	if pub2, err := fresh.GetPublicKey(prvID); err != nil || !bytes.Equal(pub2, pub) {
+		t.Fatalf("fresh keeper: have (%x, %v), want %x", pub2, err, pub)
+	}
+
+	// Tampering with the sealed object must be detected by the TPM.
+	tampered := bytes.Clone(prvID)
+	tampered[len(tampered)-1] ^= 0xff
+	if _, err := k.Sign(hash, tampered); err == nil {
+		t.Fatal("signed with tampered key")
+	}
+	if _, err := k.Sign(hash, prvID[:10]); err == nil {
+		t.Fatal("signed with truncated key")
+	}
+	if err := k.DeletePrivateKey(prvID); !errors.Is(err, ErrNotSupported) {
+		t.Fatalf("delete: have %v, want %v", err, ErrNotSupported)
+	}
+	checkExport(t, k, prvID)
+	checkImport(t, k)
+}
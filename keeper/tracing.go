@@ -0,0 +1,241 @@
+package keeper
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracedSigner is a SecureSigner recording a span for every call to another
+// signer. The keeper.key_id attribute is the same hashed prvID prefix as the
+// key_id_prefix label of SecureSignerMetrics, so traces and metrics of a key
+// can be correlated.
+type tracedSigner struct {
+	inner  SecureSigner
+	tracer trace.Tracer
+}
+
+// NewTracedSecureSigner returns a SecureSigner tracing every call to inner with
+// tracer. The context-aware methods start their spans as children of the span
+// in the context and hand the span on to inner.
+func NewTracedSecureSigner(inner SecureSigner, tracer trace.Tracer) SecureSigner {
+	return &tracedSigner{inner: inner, tracer: tracer}
+}
+
+// start starts the span of the operation op on the key prvID.
+func (s *tracedSigner) start(ctx context.Context, op string, prvID []byte) (context.Context, trace.Span) {
+	attrs := []attribute.KeyValue{attribute.String("keeper.operation", op)}
+	if prvID != nil {
+		attrs = append(attrs, attribute.String("keeper.key_id", keyIDPrefix(prvID)))
+	}
+	return s.tracer.Start(ctx, "keeper."+op, trace.WithAttributes(attrs...))
+}
+
+// endSpan records the outcome of the call in span and ends it, to be deferred by
+// the traced methods.
+func endSpan(span trace.Span, err *error) {
+	span.SetAttributes(attribute.Bool("keeper.error", *err != nil))
+	if *err != nil {
+		span.RecordError(*err)
+		span.SetStatus(codes.Error, (*err).Error())
+	}
+	span.End()
+}
+
+// traceTx records the type of tx and the chain it is signed for in span. The
+// chain is taken from the signer, unsigned legacy transactions don't carry one.
+func traceTx(span trace.Span, tx *types.Transaction, signer types.Signer) {
+	span.SetAttributes(
+		attribute.String("keeper.chain_id", signer.ChainID().String()),
+		attribute.Int("keeper.tx_type", int(tx.Type())),
+	)
+}
+
+func (s *tracedSigner) GenerateKey() ([]byte, error) {
+	return s.GenerateKeyContext(context.Background())
+}
+
+func (s *tracedSigner) GenerateKeyContext(ctx context.Context) (_ []byte, err error) {
+	ctx, span := s.start(ctx, "generate_key", nil)
+	defer endSpan(span, &err)
+	return signerContext(s.inner).GenerateKeyContext(ctx)
+}
+
+func (s *tracedSigner) GetPublicKey(prvID []byte) ([]byte, error) {
+	return s.GetPublicKeyContext(context.Background(), prvID)
+}
+
+func (s *tracedSigner) GetPublicKeyContext(ctx context.Context, prvID []byte) (_ []byte, err error) {
+	ctx, span := s.start(ctx, "get_public_key", prvID)
+	defer endSpan(span, &err)
+	return signerContext(s.inner).GetPublicKeyContext(ctx, prvID)
+}
+
+func (s *tracedSigner) GetPublicKeyCompressed(prvID []byte) (_ []byte, err error) {
+	_, span := s.start(context.Background(), "get_public_key_compressed", prvID)
+	defer endSpan(span, &err)
+	return s.inner.GetPublicKeyCompressed(prvID)
+}
+
+func (s *tracedSigner) Sign(tx *types.Transaction, signer types.Signer, prvID []byte) (*types.Transaction, error) {
+	return s.SignContext(context.Background(), tx, signer, prvID)
+}
+
+func (s *tracedSigner) SignContext(ctx context.Context, tx *types.Transaction, signer types.Signer, prvID []byte) (_ *types.Transaction, err error) {
+	ctx, span := s.start(ctx, "sign", prvID)
+	defer endSpan(span, &err)
+	traceTx(span, tx, signer)
+	return signerContext(s.inner).SignContext(ctx, tx, signer, prvID)
+}
+
+func (s *tracedSigner) SignTypedData(typedData apitypes.TypedData, prvID []byte) (_ []byte, err error) {
+	_, span := s.start(context.Background(), "sign_typed_data", prvID)
+	defer endSpan(span, &err)
+	return s.inner.SignTypedData(typedData, prvID)
+}
+
+func (s *tracedSigner) SignPermit(chainID *big.Int, tokenAddr common.Address, tokenName, tokenVersion string, ownerAddr, spenderAddr common.Address, value, nonce *big.Int, deadline int64, prvID []byte) (_ uint8, _, _ [32]byte, err error) {
+	_, span := s.start(context.Background(), "sign_permit", prvID)
+	defer endSpan(span, &err)
+	return s.inner.SignPermit(chainID, tokenAddr, tokenName, tokenVersion, ownerAddr, spenderAddr, value, nonce, deadline, prvID)
+}
+
+func (s *tracedSigner) SignUserOperation(chainID *big.Int, entryPoint common.Address, op UserOperation, prvID []byte) (_ []byte, err error) {
+	_, span := s.start(context.Background(), "sign_user_operation", prvID)
+	defer endSpan(span, &err)
+	return s.inner.SignUserOperation(chainID, entryPoint, op, prvID)
+}
+
+func (s *tracedSigner) SignHash(hash common.Hash, prvID []byte) (_ []byte, err error) {
+	_, span := s.start(context.Background(), "sign_hash", prvID)
+	defer endSpan(span, &err)
+	return s.inner.SignHash(hash, prvID)
+}
+
+func (s *tracedSigner) SignHashBytes(data []byte, prvID []byte) (_ []byte, err error) {
+	_, span := s.start(context.Background(), "sign_hash_bytes", prvID)
+	defer endSpan(span, &err)
+	return s.inner.SignHashBytes(data, prvID)
+}
+
+func (s *tracedSigner) SignPersonalMessage(message []byte, prvID []byte) (_ []byte, err error) {
+	_, span := s.start(context.Background(), "sign_personal_message", prvID)
+	defer endSpan(span, &err)
+	return s.inner.SignPersonalMessage(message, prvID)
+}
+
+func (s *tracedSigner) VerifyPersonalMessage(message, sig []byte, expectedAddr common.Address) (err error) {
+	_, span := s.start(context.Background(), "verify_personal_message", nil)
+	defer endSpan(span, &err)
+	return s.inner.VerifyPersonalMessage(message, sig, expectedAddr)
+}
+
+func (s *tracedSigner) SignForChain(chainID *big.Int, tx *types.Transaction, prvID []byte) (_ *types.Transaction, err error) {
+	_, span := s.start(context.Background(), "sign_for_chain", prvID)
+	defer endSpan(span, &err)
+	span.SetAttributes(attribute.String("keeper.chain_id", chainID.String()), attribute.Int("keeper.tx_type", int(tx.Type())))
+	return s.inner.SignForChain(chainID, tx, prvID)
+}
+
+func (s *tracedSigner) SignTransactionJSON(tx *types.Transaction, signer types.Signer, prvID []byte) (_ json.RawMessage, err error) {
+	_, span := s.start(context.Background(), "sign_transaction_json", prvID)
+	defer endSpan(span, &err)
+	span.SetAttributes(attribute.Int("keeper.tx_type", int(tx.Type())))
+	return s.inner.SignTransactionJSON(tx, signer, prvID)
+}
+
+func (s *tracedSigner) SignAndEncode(tx *types.Transaction, signer types.Signer, prvID []byte) (_ []byte, err error) {
+	_, span := s.start(context.Background(), "sign_and_encode", prvID)
+	defer endSpan(span, &err)
+	span.SetAttributes(attribute.Int("keeper.tx_type", int(tx.Type())))
+	return s.inner.SignAndEncode(tx, signer, prvID)
+}
+
+func (s *tracedSigner) SignAndEncodeHex(tx *types.Transaction, signer types.Signer, prvID []byte) (_ string, err error) {
+	_, span := s.start(context.Background(), "sign_and_encode_hex", prvID)
+	defer endSpan(span, &err)
+	span.SetAttributes(attribute.Int("keeper.tx_type", int(tx.Type())))
+	return s.inner.SignAndEncodeHex(tx, signer, prvID)
+}
+
+func (s *tracedSigner) SignDynamicFeeTx(chainID *big.Int, nonce uint64, to common.Address, value *big.Int, gasLimit uint64, maxFeePerGas, maxPriorityFeePerGas *big.Int, data []byte, prvID []byte) (_ *types.Transaction, err error) {
+	_, span := s.start(context.Background(), "sign_dynamic_fee_tx", prvID)
+	defer endSpan(span, &err)
+	span.SetAttributes(attribute.String("keeper.chain_id", chainID.String()), attribute.Int("keeper.tx_type", types.DynamicFeeTxType))
+	return s.inner.SignDynamicFeeTx(chainID, nonce, to, value, gasLimit, maxFeePerGas, maxPriorityFeePerGas, data, prvID)
+}
+
+func (s *tracedSigner) SignBlobTx(chainID *big.Int, blobTx *types.BlobTx, prvID []byte) (_ *types.Transaction, err error) {
+	_, span := s.start(context.Background(), "sign_blob_tx", prvID)
+	defer endSpan(span, &err)
+	span.SetAttributes(attribute.String("keeper.chain_id", chainID.String()), attribute.Int("keeper.tx_type", types.BlobTxType))
+	return s.inner.SignBlobTx(chainID, blobTx, prvID)
+}
+
+func (s *tracedSigner) SignAccessListTx(chainID *big.Int, nonce uint64, to common.Address, value *big.Int, gasLimit uint64, gasPrice *big.Int, accessList types.AccessList, data []byte, prvID []byte) (_ *types.Transaction, err error) {
+	_, span := s.start(context.Background(), "sign_access_list_tx", prvID)
+	defer endSpan(span, &err)
+	span.SetAttributes(attribute.String("keeper.chain_id", chainID.String()), attribute.Int("keeper.tx_type", types.AccessListTxType))
+	return s.inner.SignAccessListTx(chainID, nonce, to, value, gasLimit, gasPrice, accessList, data, prvID)
+}
+
+func (s *tracedSigner) SignContractCall(chainID *big.Int, contract common.Address, contractABI abi.ABI, method string, args []interface{}, nonce uint64, gasLimit uint64, gasPrice *big.Int, prvID []byte) (_ *types.Transaction, err error) {
+	_, span := s.start(context.Background(), "sign_contract_call", prvID)
+	defer endSpan(span, &err)
+	span.SetAttributes(attribute.String("keeper.chain_id", chainID.String()), attribute.String("keeper.method", method))
+	return s.inner.SignContractCall(chainID, contract, contractABI, method, args, nonce, gasLimit, gasPrice, prvID)
+}
+
+func (s *tracedSigner) SimulateAndSign(ctx context.Context, tx *types.Transaction, signer types.Signer, client ethereum.ContractCaller, prvID []byte) (_ *types.Transaction, err error) {
+	ctx, span := s.start(ctx, "simulate_and_sign", prvID)
+	defer endSpan(span, &err)
+	span.SetAttributes(attribute.Int("keeper.tx_type", int(tx.Type())))
+	return s.inner.SimulateAndSign(ctx, tx, signer, client, prvID)
+}
+
+func (s *tracedSigner) SignBatch(txs []*types.Transaction, signer types.Signer, prvID []byte) (_ []*types.Transaction, err error) {
+	_, span := s.start(context.Background(), "sign_batch", prvID)
+	defer endSpan(span, &err)
+	span.SetAttributes(attribute.Int("keeper.batch_size", len(txs)))
+	return s.inner.SignBatch(txs, signer, prvID)
+}
+
+func (s *tracedSigner) SignBatchParallel(txs []*types.Transaction, signer types.Signer, prvID []byte) (_ []*types.Transaction, err error) {
+	_, span := s.start(context.Background(), "sign_batch_parallel", prvID)
+	defer endSpan(span, &err)
+	span.SetAttributes(attribute.Int("keeper.batch_size", len(txs)))
+	return s.inner.SignBatchParallel(txs, signer, prvID)
+}
+
+func (s *tracedSigner) GetAddress(prvID []byte) (_ common.Address, err error) {
+	_, span := s.start(context.Background(), "get_address", prvID)
+	defer endSpan(span, &err)
+	return s.inner.GetAddress(prvID)
+}
+
+func (s *tracedSigner) VerifySignature(data, sig, prvID []byte) (_ bool, err error) {
+	_, span := s.start(context.Background(), "verify_signature", prvID)
+	defer endSpan(span, &err)
+	return s.inner.VerifySignature(data, sig, prvID)
+}
+
+func (s *tracedSigner) RecoverSigner(data, sig []byte) (_ common.Address, err error) {
+	_, span := s.start(context.Background(), "recover_signer", nil)
+	defer endSpan(span, &err)
+	return s.inner.RecoverSigner(data, sig)
+}
+
+func (s *tracedSigner) RecoverSenderFromTx(tx *types.Transaction, signer types.Signer) (_ common.Address, err error) {
+	_, span := s.start(context.Background(), "recover_sender_from_tx", nil)
+	defer endSpan(span, &err)
+	return s.inner.RecoverSenderFromTx(tx, signer)
+}
@@ -0,0 +1,76 @@
+package keeper
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// spanAttrs returns the attributes of span by key.
+func spanAttrs(span sdktrace.ReadOnlySpan) map[attribute.Key]attribute.Value {
+	attrs := make(map[attribute.Key]attribute.Value)
+	for _, kv := range span.Attributes() {
+		attrs[kv.Key] = kv.Value
+	}
+	return attrs
+}
+
+func TestTracedSecureSigner(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	tracer := provider.Tracer("keeper")
+	sec := NewTracedSecureSigner(NewSecureSigner(defaultKeeper), tracer)
+
+	prvID, err := sec.GenerateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	ctx, parent := tracer.Start(context.Background(), "parent")
+	signer := types.LatestSignerForChainID(big.NewInt(5))
+	if _, err := sec.(SecureSignerContext).SignContext(ctx, newTestTx(), signer, prvID); err != nil {
+		t.Fatalf("failed to sign: %v", err)
+	}
+	parent.End()
+	if _, err := sec.GetAddress([]byte{0x01}); err == nil {
+		t.Fatal("got address of invalid key")
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 4 {
+		t.Fatalf("span count: have %d, want 4", len(spans))
+	}
+	sign := spans[1]
+	if sign.Name() != "keeper.sign" || sign.Parent().SpanID() != parent.SpanContext().SpanID() {
+		t.Fatalf("sign span: have %q with parent %v", sign.Name(), sign.Parent().SpanID())
+	}
+	attrs := spanAttrs(sign)
+	if have := attrs["keeper.operation"].AsString(); have != "sign" {
+		t.Errorf("operation: have %q, want %q", have, "sign")
+	}
+	if have := attrs["keeper.key_id"].AsString(); have != keyIDPrefix(prvID) {
+		t.Errorf("key id: have %q, want %q", have, keyIDPrefix(prvID))
+	}
+	if have := attrs["keeper.chain_id"].AsString(); have != "5" {
+		t.Errorf("chain id: have %q, want %q", have, "5")
+	}
+	if have := attrs["keeper.tx_type"].AsInt64(); have != types.LegacyTxType {
+		t.Errorf("tx type: have %d, want %d", have, types.LegacyTxType)
+	}
+	if attrs["keeper.error"].AsBool() || sign.Status().Code == codes.Error {
+		t.Error("successful sign marked as failed")
+	}
+
+	failed := spans[3]
+	if !spanAttrs(failed)["keeper.error"].AsBool() || failed.Status().Code != codes.Error {
+		t.Errorf("failed call not marked as failed: %v", failed.Status())
+	}
+	if len(failed.Events()) == 0 {
+		t.Error("error not recorded on span")
+	}
+}
@@ -0,0 +1,243 @@
+package keeper
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/holiman/uint256"
+)
+
+// NewChainSigner returns the types.Signer for transactions of chainID, the one
+// of the latest fork, as returned by types.LatestSignerForChainID. It accepts
+// every transaction type and signs legacy transactions with EIP-155 replay
+// protection, so it is the right signer unless a transaction is deliberately
+// signed the way an older fork did:
+//
+//   - types.HomesteadSigner for legacy transactions without replay protection,
+//     valid on every chain; it is what a nil chainID yields here
+//   - types.NewEIP155Signer for replay protected legacy transactions only
+//   - types.NewEIP2930Signer adding EIP-2930 access list transactions
+//   - types.NewLondonSigner adding EIP-1559 dynamic fee transactions
+//   - types.NewCancunSigner adding EIP-4844 blob transactions
+//   - types.NewPragueSigner adding EIP-7702 set code transactions
+//
+// The older signers refuse the transaction types introduced later.
+func NewChainSigner(chainID *big.Int) types.Signer {
+	return types.LatestSignerForChainID(chainID)
+}
+
+// SignForChain signs tx for chainID with the signer of NewChainSigner, see
+// signForChain.
+func (sec *SecureSign) SignForChain(chainID *big.Int, tx *types.Transaction, prvID []byte) (*types.Transaction, error) {
+	return signForChain(sec, chainID, tx, prvID)
+}
+
+// SignTransactionResult is the result of eth_signTransaction, the signed
+// transaction both RLP encoded and as JSON.
+type SignTransactionResult struct {
+	Raw hexutil.Bytes      `json:"raw"`
+	Tx  *types.Transaction `json:"tx"`
+}
+
+// SignTransactionJSON signs tx with s and returns the SignTransactionResult of
+// eth_signTransaction, for serving the JSON-RPC method with keys of a keeper.
+func (sec *SecureSign) SignTransactionJSON(tx *types.Transaction, s types.Signer, prvID []byte) (json.RawMessage, error) {
+	return signTransactionJSON(sec, tx, s, prvID)
+}
+
+// signTransactionJSON implements SignTransactionJSON on top of the Sign of s,
+// so signers wrapping a SecureSigner sign through their own Sign.
+func signTransactionJSON(s SecureSigner, tx *types.Transaction, signer types.Signer, prvID []byte) (_ json.RawMessage, err error) {
+	defer wrapError(&err, "sign transaction", prvID)
+
+	signed, err := s.Sign(tx, signer, prvID)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := signed.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(SignTransactionResult{Raw: raw, Tx: signed})
+}
+
+// SignAndEncode signs tx with s and returns its encoding of EncodeSignedTx.
+func (sec *SecureSign) SignAndEncode(tx *types.Transaction, s types.Signer, prvID []byte) ([]byte, error) {
+	return signAndEncode(sec, tx, s, prvID)
+}
+
+// SignAndEncodeHex signs tx with s and returns its encoding of
+// EncodeSignedTxHex.
+func (sec *SecureSign) SignAndEncodeHex(tx *types.Transaction, s types.Signer, prvID []byte) (string, error) {
+	return signAndEncodeHex(sec, tx, s, prvID)
+}
+
+// signAndEncode implements SignAndEncode on top of the Sign of s, so signers
+// wrapping a SecureSigner sign through their own Sign.
+func signAndEncode(s SecureSigner, tx *types.Transaction, signer types.Signer, prvID []byte) (_ []byte, err error) {
+	defer wrapError(&err, "sign transaction", prvID)
+
+	signed, err := s.Sign(tx, signer, prvID)
+	if err != nil {
+		return nil, err
+	}
+	return EncodeSignedTx(signed)
+}
+
+// signAndEncodeHex implements SignAndEncodeHex on top of the Sign of s.
+func signAndEncodeHex(s SecureSigner, tx *types.Transaction, signer types.Signer, prvID []byte) (string, error) {
+	raw, err := signAndEncode(s, tx, signer, prvID)
+	if err != nil {
+		return "", err
+	}
+	return hexutil.Encode(raw), nil
+}
+
+// signForChain implements SignForChain on top of the Sign of s, so signers
+// wrapping a SecureSigner sign through their own Sign. Typed transactions have
+// to carry chainID, legacy ones get it from the signer. The sender of the
+// signed transaction is checked to be the key prvID. Unprotected transactions
+// are not signed, chainID must not be nil.
+func signForChain(s SecureSigner, chainID *big.Int, tx *types.Transaction, prvID []byte) (_ *types.Transaction, err error) {
+	defer wrapError(&err, "sign transaction", prvID)
+
+	if chainID == nil || chainID.Sign() <= 0 {
+		return nil, fmt.Errorf("invalid chain id %v", chainID)
+	}
+	if tx.Type() != types.LegacyTxType && tx.ChainId().Cmp(chainID) != 0 {
+		return nil, fmt.Errorf("%w: transaction for chain %v, signing for %v", types.ErrInvalidChainId, tx.ChainId(), chainID)
+	}
+	signer := NewChainSigner(chainID)
+	signed, err := s.Sign(tx, signer, prvID)
+	if err != nil {
+		return nil, err
+	}
+	addr, err := s.GetAddress(prvID)
+	if err != nil {
+		return nil, err
+	}
+	from, err := types.Sender(signer, signed)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSignature, err)
+	}
+	if from != addr {
+		return nil, fmt.Errorf("%w: transaction signed by %v, expected %v", ErrInvalidSignature, from, addr)
+	}
+	return signed, nil
+}
+
+// SignDynamicFeeTx builds an EIP-1559 transaction from the given fields and signs
+// it with the latest signer of chainID.
+func (sec *SecureSign) SignDynamicFeeTx(chainID *big.Int, nonce uint64, to common.Address, value *big.Int, gasLimit uint64, maxFeePerGas, maxPriorityFeePerGas *big.Int, data []byte, prvID []byte) (*types.Transaction, error) {
+	tx := newDynamicFeeTx(chainID, nonce, to, value, gasLimit, maxFeePerGas, maxPriorityFeePerGas, data)
+	return sec.Sign(tx, types.LatestSignerForChainID(chainID), prvID)
+}
+
+// newDynamicFeeTx builds the unsigned transaction of SignDynamicFeeTx.
+func newDynamicFeeTx(chainID *big.Int, nonce uint64, to common.Address, value *big.Int, gasLimit uint64, maxFeePerGas, maxPriorityFeePerGas *big.Int, data []byte) *types.Transaction {
+	return types.NewTx(&types.DynamicFeeTx{
+		ChainID:   chainID,
+		Nonce:     nonce,
+		GasTipCap: maxPriorityFeePerGas,
+		GasFeeCap: maxFeePerGas,
+		Gas:       gasLimit,
+		To:        &to,
+		Value:     value,
+		Data:      data,
+	})
+}
+
+// SignAccessListTx builds an EIP-2930 transaction from the given fields and
+// signs it with the EIP-2930 signer of chainID.
+func (sec *SecureSign) SignAccessListTx(chainID *big.Int, nonce uint64, to common.Address, value *big.Int, gasLimit uint64, gasPrice *big.Int, accessList types.AccessList, data []byte, prvID []byte) (*types.Transaction, error) {
+	tx := newAccessListTx(chainID, nonce, to, value, gasLimit, gasPrice, accessList, data)
+	return sec.Sign(tx, types.NewEIP2930Signer(chainID), prvID)
+}
+
+// newAccessListTx builds the unsigned transaction of SignAccessListTx.
+func newAccessListTx(chainID *big.Int, nonce uint64, to common.Address, value *big.Int, gasLimit uint64, gasPrice *big.Int, accessList types.AccessList, data []byte) *types.Transaction {
+	return types.NewTx(&types.AccessListTx{
+		ChainID:    chainID,
+		Nonce:      nonce,
+		GasPrice:   gasPrice,
+		Gas:        gasLimit,
+		To:         &to,
+		Value:      value,
+		Data:       data,
+		AccessList: accessList,
+	})
+}
+
+// SignContractCall packs the call of method with args by contractABI and signs
+// the legacy transaction sending it to contract, with the latest signer of
+// chainID. The transaction transfers no value.
+func (sec *SecureSign) SignContractCall(chainID *big.Int, contract common.Address, contractABI abi.ABI, method string, args []interface{}, nonce uint64, gasLimit uint64, gasPrice *big.Int, prvID []byte) (_ *types.Transaction, err error) {
+	defer wrapError(&err, "sign transaction", prvID)
+
+	tx, err := newContractCallTx(chainID, contract, contractABI, method, args, nonce, gasLimit, gasPrice)
+	if err != nil {
+		return nil, err
+	}
+	return sec.Sign(tx, types.LatestSignerForChainID(chainID), prvID)
+}
+
+// newContractCallTx builds the unsigned transaction of SignContractCall.
+func newContractCallTx(chainID *big.Int, contract common.Address, contractABI abi.ABI, method string, args []interface{}, nonce uint64, gasLimit uint64, gasPrice *big.Int) (*types.Transaction, error) {
+	data, err := contractABI.Pack(method, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to pack call of %q: %w", method, err)
+	}
+	return types.NewTx(&types.LegacyTx{
+		Nonce:    nonce,
+		GasPrice: gasPrice,
+		Gas:      gasLimit,
+		To:       &contract,
+		Value:    new(big.Int),
+		Data:     data,
+	}), nil
+}
+
+// SignBlobTx signs an EIP-4844 blob transaction with the latest signer of
+// chainID. The chain ID of blobTx is filled in if unset. If blobTx carries a
+// sidecar, its commitments are checked against the blob hashes so a malformed
+// transaction that the network would reject is never signed.
+func (sec *SecureSign) SignBlobTx(chainID *big.Int, blobTx *types.BlobTx, prvID []byte) (_ *types.Transaction, err error) {
+	defer wrapError(&err, "sign transaction", prvID)
+
+	tx, err := newBlobTx(chainID, blobTx)
+	if err != nil {
+		return nil, err
+	}
+	return sec.Sign(tx, types.LatestSignerForChainID(chainID), prvID)
+}
+
+// newBlobTx validates blobTx and builds the unsigned transaction of SignBlobTx.
+func newBlobTx(chainID *big.Int, blobTx *types.BlobTx) (*types.Transaction, error) {
+	if len(blobTx.BlobHashes) == 0 {
+		return nil, errors.New("blob transaction without blob hashes")
+	}
+	id, overflow := uint256.FromBig(chainID)
+	if overflow {
+		return nil, fmt.Errorf("chain id %v overflows 256 bits", chainID)
+	}
+	if blobTx.ChainID != nil && !blobTx.ChainID.Eq(id) {
+		return nil, fmt.Errorf("blob transaction chain id %v does not match %v", blobTx.ChainID, chainID)
+	}
+	if sidecar := blobTx.Sidecar; sidecar != nil {
+		if len(sidecar.Blobs) != len(sidecar.Commitments) {
+			return nil, fmt.Errorf("invalid number of %d blobs compared to %d blob commitments", len(sidecar.Blobs), len(sidecar.Commitments))
+		}
+		if err := sidecar.ValidateBlobCommitmentHashes(blobTx.BlobHashes); err != nil {
+			return nil, err
+		}
+	}
+	inner := *blobTx
+	inner.ChainID = id
+	return types.NewTx(&inner), nil
+}
@@ -0,0 +1,366 @@
+package keeper
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/crypto/kzg4844"
+	"github.com/holiman/uint256"
+)
+
+// newTestSigner returns a signer over the default keeper along with a fresh
+// key and its address.
+func newTestSigner(t *testing.T) (*SecureSign, []byte, common.Address) {
+	t.Helper()
+
+	sec := DefaultSecureSign()
+	prvID, err := sec.GenerateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	pub, err := sec.GetPublicKey(prvID)
+	if err != nil {
+		t.Fatalf("failed to get public key: %v", err)
+	}
+	key, err := crypto.UnmarshalPubkey(pub)
+	if err != nil {
+		t.Fatalf("invalid public key: %v", err)
+	}
+	return &sec, prvID, crypto.PubkeyToAddress(*key)
+}
+
+func TestSignDynamicFeeTx(t *testing.T) {
+	sec, prvID, addr := newTestSigner(t)
+
+	var (
+		chainID = big.NewInt(1337)
+		to      = common.HexToAddress("0x0102030405060708090a0b0c0d0e0f1011121314")
+		data    = []byte{0xde, 0xad, 0xbe, 0xef}
+	)
+	tx, err := sec.SignDynamicFeeTx(chainID, 7, to, big.NewInt(100), 50000, big.NewInt(30), big.NewInt(2), data, prvID)
+	if err != nil {
+		t.Fatalf("failed to sign: %v", err)
+	}
+	if tx.Type() != types.DynamicFeeTxType {
+		t.Fatalf("transaction type mismatch: have %d, want %d", tx.Type(), types.DynamicFeeTxType)
+	}
+	if tx.ChainId().Cmp(chainID) != 0 || tx.Nonce() != 7 || *tx.To() != to || tx.Value().Int64() != 100 ||
+		tx.Gas() != 50000 || tx.GasFeeCap().Int64() != 30 || tx.GasTipCap().Int64() != 2 || !bytes.Equal(tx.Data(), data) {
+		t.Fatalf("transaction fields mismatch: %+v", tx)
+	}
+	from, err := types.Sender(types.LatestSignerForChainID(chainID), tx)
+	if err != nil {
+		t.Fatalf("failed to recover sender: %v", err)
+	}
+	if from != addr {
+		t.Fatalf("sender mismatch: have %v, want %v", from, addr)
+	}
+}
+
+func TestSignBlobTx(t *testing.T) {
+	sec, prvID, addr := newTestSigner(t)
+
+	var (
+		blob       kzg4844.Blob
+		chainID    = big.NewInt(1337)
+		to         = common.HexToAddress("0x0102030405060708090a0b0c0d0e0f1011121314")
+		commitment kzg4844.Commitment
+		proof      kzg4844.Proof
+		err        error
+	)
+	if commitment, err = kzg4844.BlobToCommitment(&blob); err != nil {
+		t.Fatalf("failed to commit to blob: %v", err)
+	}
+	if proof, err = kzg4844.ComputeBlobProof(&blob, commitment); err != nil {
+		t.Fatalf("failed to compute blob proof: %v", err)
+	}
+	sidecar := types.NewBlobTxSidecar(types.BlobSidecarVersion0, []kzg4844.Blob{blob}, []kzg4844.Commitment{commitment}, []kzg4844.Proof{proof})
+	newBlobTx := func() *types.BlobTx {
+		return &types.BlobTx{
+			Nonce:      1,
+			GasTipCap:  uint256.NewInt(1),
+			GasFeeCap:  uint256.NewInt(10),
+			Gas:        21000,
+			To:         to,
+			BlobFeeCap: uint256.NewInt(1),
+			BlobHashes: sidecar.BlobHashes(),
+			Sidecar:    sidecar,
+		}
+	}
+	tx, err := sec.SignBlobTx(chainID, newBlobTx(), prvID)
+	if err != nil {
+		t.Fatalf("failed to sign: %v", err)
+	}
+	if tx.Type() != types.BlobTxType || tx.ChainId().Cmp(chainID) != 0 {
+		t.Fatalf("transaction mismatch: type %d, chain id %v", tx.Type(), tx.ChainId())
+	}
+	from, err := types.Sender(types.LatestSignerForChainID(chainID), tx)
+	if err != nil {
+		t.Fatalf("failed to recover sender: %v", err)
+	}
+	if from != addr {
+		t.Fatalf("sender mismatch: have %v, want %v", from, addr)
+	}
+
+	noHashes := newBlobTx()
+	noHashes.BlobHashes = nil
+	if _, err := sec.SignBlobTx(chainID, noHashes, prvID); err == nil {
+		t.Error("signed blob transaction without blob hashes")
+	}
+	badHash := newBlobTx()
+	badHash.BlobHashes = []common.Hash{{0x01}}
+	if _, err := sec.SignBlobTx(chainID, badHash, prvID); err == nil {
+		t.Error("signed blob transaction with mismatching blob hash")
+	}
+	wrongChain := newBlobTx()
+	wrongChain.ChainID = uint256.NewInt(1)
+	if _, err := sec.SignBlobTx(chainID, wrongChain, prvID); err == nil {
+		t.Error("signed blob transaction for a different chain")
+	}
+}
+
+func TestSignAccessListTx(t *testing.T) {
+	sec, prvID, _ := newTestSigner(t)
+
+	pub, err := sec.GetPublicKey(prvID)
+	if err != nil {
+		t.Fatalf("failed to get public key: %v", err)
+	}
+	key, _ := crypto.UnmarshalPubkey(pub)
+	addr := crypto.PubkeyToAddress(*key)
+
+	var (
+		chainID = big.NewInt(1337)
+		to      = common.HexToAddress("0x0102030405060708090a0b0c0d0e0f1011121314")
+	)
+	tests := []struct {
+		name       string
+		accessList types.AccessList
+	}{
+		{"empty", nil},
+		{"non-empty", types.AccessList{
+			{Address: to, StorageKeys: []common.Hash{{0x01}, {0x02}}},
+			{Address: common.Address{0xff}},
+		}},
+	}
+	for _, tt := range tests {
+		tx, err := sec.SignAccessListTx(chainID, 3, to, big.NewInt(1), 60000, big.NewInt(20), tt.accessList, nil, prvID)
+		if err != nil {
+			t.Fatalf("%s: failed to sign: %v", tt.name, err)
+		}
+		if tx.Type() != types.AccessListTxType {
+			t.Fatalf("%s: transaction type mismatch: have %d, want %d", tt.name, tx.Type(), types.AccessListTxType)
+		}
+		if len(tx.AccessList()) != len(tt.accessList) {
+			t.Fatalf("%s: access list mismatch: have %v, want %v", tt.name, tx.AccessList(), tt.accessList)
+		}
+		from, err := types.Sender(types.NewEIP2930Signer(chainID), tx)
+		if err != nil {
+			t.Fatalf("%s: failed to recover sender: %v", tt.name, err)
+		}
+		if from != addr {
+			t.Fatalf("%s: sender mismatch: have %v, want %v", tt.name, from, addr)
+		}
+	}
+}
+
+// lyingKeeper reports the public key of another key than the one it signs
+// with, like a misconfigured backend.
+type lyingKeeper struct {
+	defaultPrivateKeyKeeper
+	pub []byte
+}
+
+func (k *lyingKeeper) GetPublicKey(prvID []byte) ([]byte, error) {
+	return k.pub, nil
+}
+
+func TestSignForChain(t *testing.T) {
+	sec, prvID, addr := newTestSigner(t)
+	chainID := big.NewInt(11155111)
+
+	// Legacy transactions get the chain ID from the signer, typed ones have to
+	// carry it.
+	txs := []*types.Transaction{
+		newTestTx(),
+		newAccessListTx(chainID, 0, common.Address{1}, big.NewInt(1), 21000, big.NewInt(1), nil, nil),
+		newDynamicFeeTx(chainID, 0, common.Address{1}, big.NewInt(1), 21000, big.NewInt(2), big.NewInt(1), nil),
+	}
+	for i, tx := range txs {
+		signed, err := sec.SignForChain(chainID, tx, prvID)
+		if err != nil {
+			t.Fatalf("tx %d: failed to sign: %v", i, err)
+		}
+		if !signed.Protected() || signed.ChainId().Cmp(chainID) != 0 {
+			t.Fatalf("tx %d: have chain id %v (protected %v), want %v", i, signed.ChainId(), signed.Protected(), chainID)
+		}
+		if from, err := types.Sender(types.LatestSignerForChainID(chainID), signed); err != nil || from != addr {
+			t.Fatalf("tx %d: sender mismatch: have (%x, %v), want %x", i, from, err, addr)
+		}
+	}
+	if _, err := sec.SignForChain(big.NewInt(1), txs[2], prvID); !errors.Is(err, types.ErrInvalidChainId) {
+		t.Fatalf("tx for other chain: have %v, want %v", err, types.ErrInvalidChainId)
+	}
+	if _, err := sec.SignForChain(nil, txs[0], prvID); err == nil {
+		t.Fatal("signed unprotected transaction")
+	}
+
+	// A signature not made by the key prvID is reported.
+	other, _ := crypto.GenerateKey()
+	liar := NewSecureSigner(&lyingKeeper{pub: crypto.FromECDSAPub(&other.PublicKey)})
+	if _, err := liar.SignForChain(chainID, newTestTx(), prvID); !errors.Is(err, ErrInvalidSignature) {
+		t.Fatalf("sign with mismatching key: have %v, want %v", err, ErrInvalidSignature)
+	}
+}
+
+func TestSignTransactionJSON(t *testing.T) {
+	sec, prvID, addr := newTestSigner(t)
+	chainID := big.NewInt(1)
+	signer := types.LatestSignerForChainID(chainID)
+	tx := newDynamicFeeTx(chainID, 3, common.Address{1}, big.NewInt(1), 21000, big.NewInt(2), big.NewInt(1), []byte{0xca, 0xfe})
+
+	result, err := sec.SignTransactionJSON(tx, signer, prvID)
+	if err != nil {
+		t.Fatalf("failed to sign: %v", err)
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(result, &fields); err != nil || len(fields) != 2 || fields["raw"] == nil || fields["tx"] == nil {
+		t.Fatalf("result fields: have %s (%v), want raw and tx", result, err)
+	}
+	var decoded SignTransactionResult
+	if err := json.Unmarshal(result, &decoded); err != nil {
+		t.Fatalf("failed to decode result: %v", err)
+	}
+	// The RLP and the JSON encoding hold the same signed transaction.
+	fromRaw := new(types.Transaction)
+	if err := fromRaw.UnmarshalBinary(decoded.Raw); err != nil {
+		t.Fatalf("failed to decode raw transaction: %v", err)
+	}
+	if fromRaw.Hash() != decoded.Tx.Hash() {
+		t.Fatalf("raw and tx differ: %v != %v", fromRaw.Hash(), decoded.Tx.Hash())
+	}
+	if from, err := types.Sender(signer, fromRaw); err != nil || from != addr {
+		t.Fatalf("sender mismatch: have (%x, %v), want %x", from, err, addr)
+	}
+	if fromRaw.Nonce() != 3 || !bytes.Equal(fromRaw.Data(), []byte{0xca, 0xfe}) {
+		t.Fatalf("signed transaction: have nonce %d data %x, want 3 cafe", fromRaw.Nonce(), fromRaw.Data())
+	}
+	if _, err := sec.SignTransactionJSON(tx, types.HomesteadSigner{}, prvID); err == nil {
+		t.Fatal("signed dynamic fee tx with homestead signer")
+	}
+}
+
+func TestSignAndEncode(t *testing.T) {
+	sec, prvID, addr := newTestSigner(t)
+	chainID := big.NewInt(1)
+	signer := types.LatestSignerForChainID(chainID)
+
+	for _, tx := range []*types.Transaction{newTestTx(), newDynamicFeeTx(chainID, 3, common.Address{1}, big.NewInt(1), 21000, big.NewInt(2), big.NewInt(1), nil)} {
+		raw, err := sec.SignAndEncode(tx, signer, prvID)
+		if err != nil {
+			t.Fatalf("type %d: failed to sign: %v", tx.Type(), err)
+		}
+		decoded, err := DecodeSignedTx(raw)
+		if err != nil {
+			t.Fatalf("type %d: failed to decode: %v", tx.Type(), err)
+		}
+		if decoded.Type() != tx.Type() || decoded.Nonce() != tx.Nonce() {
+			t.Fatalf("type %d: decoded type %d nonce %d, want nonce %d", tx.Type(), decoded.Type(), decoded.Nonce(), tx.Nonce())
+		}
+		if from, err := types.Sender(signer, decoded); err != nil || from != addr {
+			t.Fatalf("type %d: sender mismatch: have (%x, %v), want %x", tx.Type(), from, err, addr)
+		}
+		encoded, err := sec.SignAndEncodeHex(tx, signer, prvID)
+		if err != nil {
+			t.Fatalf("type %d: failed to sign as hex: %v", tx.Type(), err)
+		}
+		if want := hexutil.Encode(raw); encoded != want {
+			t.Fatalf("type %d: hex encoding mismatch: have %s, want %s", tx.Type(), encoded, want)
+		}
+	}
+	// Wrapping signers sign through their own Sign.
+	bound := NewChainBoundSigner(sec, []*big.Int{big.NewInt(5)})
+	if raw, err := bound.SignAndEncode(newTestTx(), signer, prvID); raw != nil || err == nil {
+		t.Fatalf("sign for other chain: have (%x, %v), want error", raw, err)
+	}
+}
+
+func TestNewChainSigner(t *testing.T) {
+	if _, ok := NewChainSigner(nil).(types.HomesteadSigner); !ok {
+		t.Fatalf("signer without chain id: have %T, want types.HomesteadSigner", NewChainSigner(nil))
+	}
+	// The latest signer accepts the newest transaction types.
+	signer := NewChainSigner(big.NewInt(1))
+	setCode := types.NewTx(&types.SetCodeTx{ChainID: uint256.NewInt(1), AuthList: []types.SetCodeAuthorization{{}}})
+	if _, _, _, err := signer.SignatureValues(setCode, make([]byte, 65)); err != nil || signer.ChainID().Int64() != 1 {
+		t.Fatalf("signer of chain 1: have %T for chain %v (%v), want latest", signer, signer.ChainID(), err)
+	}
+}
+
+// testContractABI has a method mixing static and dynamic argument types.
+const testContractABI = `[{"type":"function","name":"settle","stateMutability":"nonpayable","inputs":[
+	{"name":"to","type":"address"},
+	{"name":"amount","type":"uint256"},
+	{"name":"final","type":"bool"},
+	{"name":"memo","type":"string"},
+	{"name":"ref","type":"bytes32"},
+	{"name":"legs","type":"uint64[]"}
+],"outputs":[]}]`
+
+func TestSignContractCall(t *testing.T) {
+	sec, prvID, addr := newTestSigner(t)
+
+	parsed, err := abi.JSON(strings.NewReader(testContractABI))
+	if err != nil {
+		t.Fatalf("failed to parse ABI: %v", err)
+	}
+	var (
+		chainID  = big.NewInt(1337)
+		contract = common.HexToAddress("0x0102030405060708090a0b0c0d0e0f1011121314")
+		args     = []interface{}{
+			common.HexToAddress("0xaaaa"), big.NewInt(1e18), true, "invoice 42",
+			[32]byte{0x01, 0x02}, []uint64{1, 2, 3},
+		}
+	)
+	tx, err := sec.SignContractCall(chainID, contract, parsed, "settle", args, 3, 90000, big.NewInt(10), prvID)
+	if err != nil {
+		t.Fatalf("failed to sign: %v", err)
+	}
+	want, err := parsed.Pack("settle", args...)
+	if err != nil {
+		t.Fatalf("failed to pack: %v", err)
+	}
+	if !bytes.Equal(tx.Data(), want) {
+		t.Fatalf("call data mismatch: have %x, want %x", tx.Data(), want)
+	}
+	if tx.Type() != types.LegacyTxType || tx.ChainId().Cmp(chainID) != 0 || tx.Nonce() != 3 || *tx.To() != contract ||
+		tx.Value().Sign() != 0 || tx.Gas() != 90000 || tx.GasPrice().Int64() != 10 {
+		t.Fatalf("transaction fields mismatch: %+v", tx)
+	}
+	if from, err := types.Sender(types.LatestSignerForChainID(chainID), tx); err != nil || from != addr {
+		t.Fatalf("sender mismatch: have (%v, %v), want %v", from, err, addr)
+	}
+
+	if _, err := sec.SignContractCall(chainID, contract, parsed, "missing", nil, 0, 90000, big.NewInt(10), prvID); err == nil {
+		t.Fatal("signed call of unknown method")
+	}
+	if _, err := sec.SignContractCall(chainID, contract, parsed, "settle", args[:2], 0, 90000, big.NewInt(10), prvID); err == nil {
+		t.Fatal("signed call with missing arguments")
+	}
+
+	// Decorated signers sign the call through their own checks.
+	policied := NewPoliciedSecureSigner(sec, SigningPolicy{MaxGasLimit: 50000})
+	var violation *PolicyViolation
+	if _, err := policied.SignContractCall(chainID, contract, parsed, "settle", args, 3, 90000, big.NewInt(10), prvID); !errors.As(err, &violation) {
+		t.Fatalf("call beyond policy: have %v, want policy violation", err)
+	}
+}
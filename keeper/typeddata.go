@@ -0,0 +1,35 @@
+package keeper
+
+import (
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+)
+
+// typedDataHash returns the EIP-712 signing hash of typedData:
+// keccak256("\x19\x01" || domainSeparator || hashStruct(message))
+func typedDataHash(typedData apitypes.TypedData) ([]byte, error) {
+	domainSeparator, err := typedData.HashStruct("EIP712Domain", typedData.Domain.Map())
+	if err != nil {
+		return nil, err
+	}
+	messageHash, err := typedData.HashStruct(typedData.PrimaryType, typedData.Message)
+	if err != nil {
+		return nil, err
+	}
+	rawData := make([]byte, 0, 2+len(domainSeparator)+len(messageHash))
+	rawData = append(rawData, 0x19, 0x01)
+	rawData = append(rawData, domainSeparator...)
+	rawData = append(rawData, messageHash...)
+	return crypto.Keccak256(rawData), nil
+}
+
+// SignTypedData signs the EIP-712 hash of typedData. The returned signature is
+// in the [R || S || V] format where V is 0 or 1.
+func (sec *SecureSign) SignTypedData(typedData apitypes.TypedData, prvID []byte) (_ []byte, err error) {
+	defer wrapError(&err, "sign typed data", prvID)
+	hash, err := typedDataHash(typedData)
+	if err != nil {
+		return nil, err
+	}
+	return sec.keeper.Sign(hash, prvID)
+}
@@ -0,0 +1,73 @@
+package keeper
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+)
+
+// mailTypedData is the example message of the EIP-712 specification.
+const mailTypedData = `{
+	"types": {
+		"EIP712Domain": [
+			{"name": "name", "type": "string"},
+			{"name": "version", "type": "string"},
+			{"name": "chainId", "type": "uint256"},
+			{"name": "verifyingContract", "type": "address"}
+		],
+		"Person": [
+			{"name": "name", "type": "string"},
+			{"name": "wallet", "type": "address"}
+		],
+		"Mail": [
+			{"name": "from", "type": "Person"},
+			{"name": "to", "type": "Person"},
+			{"name": "contents", "type": "string"}
+		]
+	},
+	"primaryType": "Mail",
+	"domain": {
+		"name": "Ether Mail",
+		"version": "1",
+		"chainId": "1",
+		"verifyingContract": "0xCcCCccccCCCCcCCCCCCcCcCccCcCCCcCcccccccC"
+	},
+	"message": {
+		"from": {"name": "Cow", "wallet": "0xCD2a3d9F938E13CD947Ec05AbC7FE734Df8DD826"},
+		"to": {"name": "Bob", "wallet": "0xbBbBBBBbbBBBbbbBbbBbbbbBBbBbbbbBbBbbBBbB"},
+		"contents": "Hello, Bob!"
+	}
+}`
+
+func TestSignTypedData(t *testing.T) {
+	var typedData apitypes.TypedData
+	if err := json.Unmarshal([]byte(mailTypedData), &typedData); err != nil {
+		t.Fatalf("failed to parse typed data: %v", err)
+	}
+	hash, err := typedDataHash(typedData)
+	if err != nil {
+		t.Fatalf("failed to hash typed data: %v", err)
+	}
+	if want := common.FromHex("0xbe609aee343fb3c4b28e1df9e632fca64fcfaede20f02e86244efddf30957bd2"); !bytes.Equal(hash, want) {
+		t.Fatalf("hash mismatch: have %x, want %x", hash, want)
+	}
+	// The reference signature of the specification, by the key of "cow".
+	sec := DefaultSecureSign()
+	sig, err := sec.SignTypedData(typedData, crypto.Keccak256([]byte("cow")))
+	if err != nil {
+		t.Fatalf("failed to sign typed data: %v", err)
+	}
+	want := common.FromHex("0x4355c47d63924e8a72e509b65029052eb6c299d53a04e167c5775fd466751c9d07299936d304c153f6443dfa05f40ff007d72911b6f72307f996231605b9156201")
+	if !bytes.Equal(sig, want) {
+		t.Fatalf("signature mismatch: have %x, want %x", sig, want)
+	}
+
+	typedData.PrimaryType = "Unknown"
+	if _, err := sec.SignTypedData(typedData, crypto.Keccak256([]byte("cow"))); err == nil {
+		t.Fatal("signed typed data with unknown primary type")
+	}
+}
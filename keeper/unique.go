@@ -0,0 +1,50 @@
+package keeper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// DefaultUniqueKeyAttempts is the number of keys GenerateUniqueKey generates
+// before giving up, unless set by its caller.
+const DefaultUniqueKeyAttempts = 3
+
+// GenerateUniqueKey generates a key with k and checks that its prvID isn't the
+// one of a key k held before, generating another one up to maxAttempts times,
+// or DefaultUniqueKeyAttempts if maxAttempts isn't positive. It fails with
+// ErrKeyCollision if every attempt collided. A colliding prvID is not deleted,
+// as it identifies the existing key.
+//
+// The check relies on ListPrivateKeys, keepers not supporting it, such as the
+// default keeper, get the generated key unchecked. Keepers listing keys they
+// haven't handed out yet, such as FixedKeeper, can't be used with it.
+func GenerateUniqueKey(ctx context.Context, k PrivateKeyKeeper, maxAttempts int) (_ []byte, err error) {
+	defer wrapError(&err, "generate key", nil)
+
+	if maxAttempts <= 0 {
+		maxAttempts = DefaultUniqueKeyAttempts
+	}
+	kc := ContextKeeper(k)
+	listed, err := kc.ListPrivateKeysContext(ctx)
+	if errors.Is(err, ErrNotSupported) {
+		return kc.GeneratePrivateKeyContext(ctx)
+	}
+	if err != nil {
+		return nil, err
+	}
+	existing := make(map[string]struct{}, len(listed))
+	for _, prvID := range listed {
+		existing[string(prvID)] = struct{}{}
+	}
+	for i := 0; i < maxAttempts; i++ {
+		prvID, err := kc.GeneratePrivateKeyContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := existing[string(prvID)]; !ok {
+			return prvID, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %d attempts", ErrKeyCollision, maxAttempts)
+}
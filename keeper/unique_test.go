@@ -0,0 +1,107 @@
+package keeper
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/zalando/go-keyring"
+)
+
+// sequenceKeeper hands out the prvIDs of ids in order, listing the ones handed
+// out so far.
+type sequenceKeeper struct {
+	countingKeeper
+	ids    []string
+	stored [][]byte
+}
+
+func (k *sequenceKeeper) GeneratePrivateKey() ([]byte, error) {
+	prvID := []byte(k.ids[0])
+	k.ids = k.ids[1:]
+	k.stored = append(k.stored, prvID)
+	return prvID, nil
+}
+
+func (k *sequenceKeeper) ListPrivateKeys() ([][]byte, error) {
+	return k.stored, nil
+}
+
+func TestGenerateUniqueKey(t *testing.T) {
+	k := &sequenceKeeper{ids: []string{"a", "a", "a", "b", "b", "b", "b"}}
+	if prvID, err := GenerateUniqueKey(context.Background(), k, 0); err != nil || string(prvID) != "a" {
+		t.Fatalf("first key: have (%q, %v), want a", prvID, err)
+	}
+	// The colliding keys are skipped.
+	if prvID, err := GenerateUniqueKey(context.Background(), k, 3); err != nil || string(prvID) != "b" {
+		t.Fatalf("second key: have (%q, %v), want b", prvID, err)
+	}
+	if _, err := GenerateUniqueKey(context.Background(), k, 3); !errors.Is(err, ErrKeyCollision) {
+		t.Fatalf("only collisions: have %v, want %v", err, ErrKeyCollision)
+	}
+	if len(k.ids) != 0 {
+		t.Fatalf("attempts: have %d unused keys, want none", len(k.ids))
+	}
+	// Keepers that can't list their keys get theirs unchecked.
+	if _, err := GenerateUniqueKey(context.Background(), new(defaultPrivateKeyKeeper), 1); err != nil {
+		t.Fatalf("unlisted keeper: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := GenerateUniqueKey(ctx, NewKeystoreKeeper(t.TempDir(), keystore.LightScryptN, keystore.LightScryptP, MemoryPassphraseProvider("foo")), 1); !errors.Is(err, context.Canceled) {
+		t.Fatalf("cancelled context: have %v, want %v", err, context.Canceled)
+	}
+}
+
+// TestConcurrentGeneratePrivateKey generates keys from many goroutines and
+// checks that no prvID is handed out twice. It is meant to be run with the
+// race detector.
+func TestConcurrentGeneratePrivateKey(t *testing.T) {
+	keyring.MockInit()
+	keepers := map[string]PrivateKeyKeeper{
+		"default":  new(defaultPrivateKeyKeeper),
+		"keystore": NewKeystoreKeeper(t.TempDir(), keystore.LightScryptN, keystore.LightScryptP, MemoryPassphraseProvider("foo")),
+		"keyring":  NewKeyringKeeper("keeper-test"),
+		"cached":   NewCachedKeeper(new(defaultPrivateKeyKeeper), time.Minute),
+	}
+	fixed := make(map[string][]byte)
+	for i := 0; i < 32; i++ {
+		key, _ := GenerateDeterministicKey([]byte{byte(i)})
+		fixed[string(rune('a'+i))] = key
+	}
+	keepers["fixed"] = FixedKeeper(fixed)
+
+	for name, k := range keepers {
+		const n = 32
+		var (
+			wg     sync.WaitGroup
+			prvIDs = make([][]byte, n)
+			errs   = make([]error, n)
+		)
+		for i := 0; i < n; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				if name == "keystore" || name == "keyring" {
+					prvIDs[i], errs[i] = GenerateUniqueKey(context.Background(), k, 0)
+				} else {
+					prvIDs[i], errs[i] = k.GeneratePrivateKey()
+				}
+			}(i)
+		}
+		wg.Wait()
+		seen := make(map[string]bool)
+		for i, prvID := range prvIDs {
+			if errs[i] != nil {
+				t.Fatalf("%s: failed to generate key %d: %v", name, i, errs[i])
+			}
+			if seen[string(prvID)] {
+				t.Fatalf("%s: prvID %x generated twice", name, prvID)
+			}
+			seen[string(prvID)] = true
+		}
+	}
+}
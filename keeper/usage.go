@@ -0,0 +1,182 @@
+package keeper
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+)
+
+// UsageOption configures the usage limiting keeper.
+type UsageOption func(*usageKeeper)
+
+// WithMaxUses sets the number of signatures generated keys may make. Without it
+// generated keys are not limited.
+func WithMaxUses(n int64) UsageOption {
+	return func(k *usageKeeper) {
+		k.maxUses = n
+	}
+}
+
+// keyUsage counts the signatures of a key.
+type keyUsage struct {
+	uses    atomic.Int64
+	maxUses atomic.Int64 // 0 for no limit
+	persist sync.Mutex   // serialises writing uses to the metadata store
+}
+
+// reserve counts a signature, failing with ErrKeyExhausted if the key made all
+// the signatures it may.
+func (u *keyUsage) reserve() error {
+	for {
+		n, limit := u.uses.Load(), u.maxUses.Load()
+		if limit > 0 && n >= limit {
+			return ErrKeyExhausted
+		}
+		if u.uses.CompareAndSwap(n, n+1) {
+			return nil
+		}
+	}
+}
+
+// usageKeeper is a MetadataAwareKeeper limiting the signatures of its keys to
+// the MaxUses in their metadata.
+type usageKeeper struct {
+	MetadataAwareKeeper
+	maxUses int64 // limit of generated keys, 0 for no limit
+
+	lock  sync.Mutex           // guards loading usages into keys
+	usage map[string]*keyUsage // prvID -> usage, loaded on first use
+}
+
+// NewUsageLimitingKeeper returns a PrivateKeyKeeper failing Sign with
+// ErrKeyExhausted once a key made the MaxUses signatures recorded in its
+// metadata, e.g. for single-use session keys. Keys without a limit, or without
+// metadata, are not limited. Every signature is counted in the Uses of the key
+// before it is made, failed ones included, and written to the metadata store
+// so the count survives restarts. Signing fails if the count can't be written.
+// The store must not be shared with other keepers limiting the same keys.
+func NewUsageLimitingKeeper(inner MetadataAwareKeeper, opts ...UsageOption) PrivateKeyKeeper {
+	k := &usageKeeper{MetadataAwareKeeper: inner, usage: make(map[string]*keyUsage)}
+	for _, opt := range opts {
+		opt(k)
+	}
+	return k
+}
+
+func (k *usageKeeper) GeneratePrivateKey() (_ []byte, err error) {
+	defer wrapError(&err, "generate key", nil)
+
+	prvID, err := k.MetadataAwareKeeper.GeneratePrivateKey()
+	if err != nil || k.maxUses == 0 {
+		return prvID, err
+	}
+	err = updateNewMetadata(k.MetadataAwareKeeper, prvID, func(meta *KeyMetadata) {
+		meta.MaxUses = k.maxUses
+	})
+	if err != nil {
+		return nil, err
+	}
+	return prvID, nil
+}
+
+func (k *usageKeeper) Sign(data []byte, prvID []byte) (_ []byte, err error) {
+	defer wrapError(&err, "sign", prvID)
+
+	u, err := k.keyUsage(prvID)
+	if err != nil {
+		return nil, err
+	}
+	if u != nil {
+		if err := u.reserve(); err != nil {
+			return nil, err
+		}
+		if err := k.persist(prvID, u); err != nil {
+			return nil, err
+		}
+	}
+	return k.MetadataAwareKeeper.Sign(data, prvID)
+}
+
+func (k *usageKeeper) SetMetadata(prvID []byte, meta KeyMetadata) (err error) {
+	defer wrapError(&err, "set metadata", prvID)
+
+	u, err := k.keyUsage(prvID)
+	if err != nil {
+		return err
+	}
+	if u == nil {
+		if err := k.MetadataAwareKeeper.SetMetadata(prvID, meta); err != nil {
+			return err
+		}
+		// The key has metadata now, load it on its next use.
+		k.lock.Lock()
+		delete(k.usage, string(prvID))
+		k.lock.Unlock()
+		return nil
+	}
+	u.persist.Lock()
+	defer u.persist.Unlock()
+
+	// Signatures can't be taken back by setting stale metadata.
+	meta.Uses = max(meta.Uses, u.uses.Load())
+	if err := k.MetadataAwareKeeper.SetMetadata(prvID, meta); err != nil {
+		return err
+	}
+	u.maxUses.Store(meta.MaxUses)
+	return nil
+}
+
+func (k *usageKeeper) DeletePrivateKey(prvID []byte) (err error) {
+	defer wrapError(&err, "delete key", prvID)
+
+	if err := k.MetadataAwareKeeper.DeletePrivateKey(prvID); err != nil {
+		return err
+	}
+	k.lock.Lock()
+	delete(k.usage, string(prvID))
+	k.lock.Unlock()
+	return nil
+}
+
+func (k *usageKeeper) HealthCheck(ctx context.Context) error {
+	return checkHealth(ctx, ContextKeeper(k.MetadataAwareKeeper))
+}
+
+// keyUsage returns the usage of the key, loading it from its metadata on first
+// use. Keys without metadata have no usage.
+func (k *usageKeeper) keyUsage(prvID []byte) (*keyUsage, error) {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+
+	if u, ok := k.usage[string(prvID)]; ok {
+		return u, nil
+	}
+	meta, err := k.MetadataAwareKeeper.GetMetadata(prvID)
+	if errors.Is(err, ErrKeyNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	u := new(keyUsage)
+	u.uses.Store(meta.Uses)
+	u.maxUses.Store(meta.MaxUses)
+	k.usage[string(prvID)] = u
+	return u, nil
+}
+
+// persist writes the signatures counted for the key to its metadata. The count
+// only grows, writing its latest value keeps the store from going backwards
+// when concurrent signatures are persisted out of order.
+func (k *usageKeeper) persist(prvID []byte, u *keyUsage) error {
+	u.persist.Lock()
+	defer u.persist.Unlock()
+
+	meta, err := k.MetadataAwareKeeper.GetMetadata(prvID)
+	if err != nil {
+		return err
+	}
+	meta.Uses = max(meta.Uses, u.uses.Load())
+	return k.MetadataAwareKeeper.SetMetadata(prvID, meta)
+}
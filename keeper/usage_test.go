@@ -0,0 +1,110 @@
+package keeper
+
+import (
+	"errors"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestUsageLimitingKeeper(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "metadata.json")
+	store, err := FileMetadataStore(path)
+	if err != nil {
+		t.Fatalf("failed to open store: %v", err)
+	}
+	inner := NewMetadataAwareKeeper(new(defaultPrivateKeyKeeper), store).(MetadataAwareKeeper)
+	k := NewUsageLimitingKeeper(inner, WithMaxUses(10)).(MetadataAwareKeeper)
+	prvID, err := k.GeneratePrivateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	if meta, _ := k.GetMetadata(prvID); meta.MaxUses != 10 || meta.Uses != 0 {
+		t.Fatalf("generated metadata: have %d/%d uses, want 0/10", meta.Uses, meta.MaxUses)
+	}
+	hash := crypto.Keccak256([]byte("usage"))
+	for range 3 {
+		if _, err := k.Sign(hash, prvID); err != nil {
+			t.Fatalf("failed to sign: %v", err)
+		}
+	}
+
+	// Concurrent signers make exactly the remaining signatures.
+	var (
+		wg     sync.WaitGroup
+		signed atomic.Int32
+	)
+	for range 32 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := k.Sign(hash, prvID)
+			switch {
+			case err == nil:
+				signed.Add(1)
+			case !errors.Is(err, ErrKeyExhausted):
+				t.Errorf("concurrent signing: have %v, want %v", err, ErrKeyExhausted)
+			}
+		}()
+	}
+	wg.Wait()
+	if n := signed.Load(); n != 7 {
+		t.Fatalf("concurrent signatures: have %d, want 7", n)
+	}
+
+	// The count survives restarts.
+	reopened, err := FileMetadataStore(path)
+	if err != nil {
+		t.Fatalf("failed to reopen store: %v", err)
+	}
+	if meta, err := reopened.Get(prvID); err != nil || meta.Uses != 10 {
+		t.Fatalf("persisted uses: have (%d, %v), want 10", meta.Uses, err)
+	}
+	restarted := NewUsageLimitingKeeper(NewMetadataAwareKeeper(new(defaultPrivateKeyKeeper), reopened).(MetadataAwareKeeper)).(MetadataAwareKeeper)
+	if _, err := restarted.Sign(hash, prvID); !errors.Is(err, ErrKeyExhausted) {
+		t.Fatalf("restarted keeper: have %v, want %v", err, ErrKeyExhausted)
+	}
+
+	// Raising the limit allows more signatures, stale metadata doesn't reset
+	// the count.
+	if err := restarted.SetMetadata(prvID, KeyMetadata{MaxUses: 11}); err != nil {
+		t.Fatalf("failed to raise limit: %v", err)
+	}
+	if _, err := restarted.Sign(hash, prvID); err != nil {
+		t.Fatalf("failed to sign after raising limit: %v", err)
+	}
+	if _, err := restarted.Sign(hash, prvID); !errors.Is(err, ErrKeyExhausted) {
+		t.Fatalf("sign over raised limit: have %v, want %v", err, ErrKeyExhausted)
+	}
+}
+
+func TestUsageLimitingKeeperUnlimited(t *testing.T) {
+	inner := NewMetadataAwareKeeper(new(defaultPrivateKeyKeeper), MemoryMetadataStore()).(MetadataAwareKeeper)
+	k := NewUsageLimitingKeeper(inner).(MetadataAwareKeeper)
+	prvID, err := k.GeneratePrivateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	hash := crypto.Keccak256([]byte("unlimited"))
+	for range 5 {
+		if _, err := k.Sign(hash, prvID); err != nil {
+			t.Fatalf("failed to sign: %v", err)
+		}
+	}
+	if meta, _ := k.GetMetadata(prvID); meta.Uses != 5 {
+		t.Fatalf("uses: have %d, want 5", meta.Uses)
+	}
+	// Single-use keys sign once.
+	if err := k.SetMetadata(prvID, KeyMetadata{MaxUses: 6}); err != nil {
+		t.Fatalf("failed to set limit: %v", err)
+	}
+	if _, err := k.Sign(hash, prvID); err != nil {
+		t.Fatalf("failed to sign last signature: %v", err)
+	}
+	if _, err := k.Sign(hash, prvID); !errors.Is(err, ErrKeyExhausted) {
+		t.Fatalf("exhausted key: have %v, want %v", err, ErrKeyExhausted)
+	}
+}
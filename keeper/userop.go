@@ -0,0 +1,70 @@
+package keeper
+
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// UserOperation is an ERC-4337 user operation as taken by the EntryPoint
+// contract. Nil integer fields are encoded as zero.
+type UserOperation struct {
+	Sender               common.Address
+	Nonce                *big.Int
+	InitCode             []byte
+	CallData             []byte
+	CallGasLimit         *big.Int
+	VerificationGasLimit *big.Int
+	PreVerificationGas   *big.Int
+	MaxFeePerGas         *big.Int
+	MaxPriorityFeePerGas *big.Int
+	PaymasterAndData     []byte
+	Signature            []byte // not part of the hash
+}
+
+// hash returns the hash of the user operation without its signature, the
+// keccak256 of the ABI encoded fields with the dynamic ones replaced by their
+// keccak256.
+func (op *UserOperation) hash() common.Hash {
+	return crypto.Keccak256Hash(
+		common.LeftPadBytes(op.Sender.Bytes(), 32),
+		abiUint256(op.Nonce),
+		crypto.Keccak256(op.InitCode),
+		crypto.Keccak256(op.CallData),
+		abiUint256(op.CallGasLimit),
+		abiUint256(op.VerificationGasLimit),
+		abiUint256(op.PreVerificationGas),
+		abiUint256(op.MaxFeePerGas),
+		abiUint256(op.MaxPriorityFeePerGas),
+		crypto.Keccak256(op.PaymasterAndData),
+	)
+}
+
+// UserOperationHash returns the ERC-4337 hash of op for entryPoint on chainID,
+// keccak256(abi.encode(op.hash(), entryPoint, chainID)), as returned by
+// getUserOpHash of the EntryPoint.
+func UserOperationHash(chainID *big.Int, entryPoint common.Address, op UserOperation) common.Hash {
+	opHash := op.hash()
+	return crypto.Keccak256Hash(opHash[:], common.LeftPadBytes(entryPoint.Bytes(), 32), abiUint256(chainID))
+}
+
+// abiUint256 returns the 32 byte ABI encoding of x, a nil x is encoded as zero.
+func abiUint256(x *big.Int) []byte {
+	if x == nil {
+		return make([]byte, 32)
+	}
+	return math.U256Bytes(new(big.Int).Set(x))
+}
+
+// SignUserOperation signs the ERC-4337 hash of op for entryPoint on chainID.
+// The returned signature is in the [R || S || V] format where V is 0 or 1.
+// Accounts verifying the signature of the EIP-191 message of the hash instead,
+// like the SimpleAccount of the reference implementation, need to be signed
+// with SignPersonalMessage of UserOperationHash.
+func (sec *SecureSign) SignUserOperation(chainID *big.Int, entryPoint common.Address, op UserOperation, prvID []byte) (_ []byte, err error) {
+	defer wrapError(&err, "sign user operation", prvID)
+	hash := UserOperationHash(chainID, entryPoint, op)
+	return sec.keeper.Sign(hash[:], prvID)
+}
@@ -0,0 +1,104 @@
+package keeper
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// referenceUserOpHash computes the user operation hash like the getUserOpHash
+// of the EntryPoint, with the ABI encoder.
+func referenceUserOpHash(t *testing.T, chainID *big.Int, entryPoint common.Address, op UserOperation) common.Hash {
+	t.Helper()
+
+	newType := func(name string) abi.Type {
+		typ, err := abi.NewType(name, "", nil)
+		if err != nil {
+			t.Fatalf("invalid abi type %s: %v", name, err)
+		}
+		return typ
+	}
+	address, uint256, bytes32 := abi.Argument{Type: newType("address")}, abi.Argument{Type: newType("uint256")}, abi.Argument{Type: newType("bytes32")}
+	packed, err := abi.Arguments{address, uint256, bytes32, bytes32, uint256, uint256, uint256, uint256, uint256, bytes32}.Pack(
+		op.Sender, op.Nonce, crypto.Keccak256Hash(op.InitCode), crypto.Keccak256Hash(op.CallData),
+		op.CallGasLimit, op.VerificationGasLimit, op.PreVerificationGas, op.MaxFeePerGas, op.MaxPriorityFeePerGas,
+		crypto.Keccak256Hash(op.PaymasterAndData),
+	)
+	if err != nil {
+		t.Fatalf("failed to pack user operation: %v", err)
+	}
+	packed, err = abi.Arguments{bytes32, address, uint256}.Pack(crypto.Keccak256Hash(packed), entryPoint, chainID)
+	if err != nil {
+		t.Fatalf("failed to pack user operation hash: %v", err)
+	}
+	return crypto.Keccak256Hash(packed)
+}
+
+func TestSignUserOperation(t *testing.T) {
+	s := NewSecureSigner(new(defaultPrivateKeyKeeper))
+	prvID, err := s.GenerateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	addr, _ := s.GetAddress(prvID)
+	var (
+		chainID    = big.NewInt(11155111)
+		entryPoint = common.HexToAddress("0x5FF137D4b0FDCD49DcA30c7CF57E578a026d2789")
+		op         = UserOperation{
+			Sender:               common.HexToAddress("0x9406Cc6185a346906296840746125a0E44976454"),
+			Nonce:                new(big.Int).Lsh(big.NewInt(7), 64), // key 7, sequence 0
+			InitCode:             common.FromHex("0x9406cc6185a346906296840746125a0e449764545fbfb9cf"),
+			CallData:             common.FromHex("0xb61d27f6"),
+			CallGasLimit:         big.NewInt(100000),
+			VerificationGasLimit: big.NewInt(500000),
+			PreVerificationGas:   big.NewInt(50000),
+			MaxFeePerGas:         big.NewInt(30e9),
+			MaxPriorityFeePerGas: big.NewInt(1e9),
+			PaymasterAndData:     nil,
+			Signature:            []byte{1, 2, 3},
+		}
+	)
+	hash := UserOperationHash(chainID, entryPoint, op)
+	if want := referenceUserOpHash(t, chainID, entryPoint, op); hash != want {
+		t.Fatalf("user operation hash mismatch: have %x, want %x", hash, want)
+	}
+	// The signature of the operation isn't part of its hash, the entry point and
+	// chain are.
+	other := op
+	other.Signature = nil
+	if h := UserOperationHash(chainID, entryPoint, other); h != hash {
+		t.Fatalf("hash depends on signature: have %x, want %x", h, hash)
+	}
+	if h := UserOperationHash(big.NewInt(1), entryPoint, op); h == hash {
+		t.Fatal("hash doesn't depend on chain id")
+	}
+	if h := UserOperationHash(chainID, common.Address{1}, op); h == hash {
+		t.Fatal("hash doesn't depend on entry point")
+	}
+
+	sig, err := s.SignUserOperation(chainID, entryPoint, op, prvID)
+	if err != nil {
+		t.Fatalf("failed to sign user operation: %v", err)
+	}
+	if len(sig) != crypto.SignatureLength {
+		t.Fatalf("signature length: have %d, want %d", len(sig), crypto.SignatureLength)
+	}
+	pub, err := crypto.SigToPub(hash[:], sig)
+	if err != nil || crypto.PubkeyToAddress(*pub) != addr {
+		t.Fatalf("recovered signer mismatch: have (%v, %v), want %v", pub, err, addr)
+	}
+}
+
+func TestUserOperationHashNilFields(t *testing.T) {
+	op := UserOperation{Sender: common.Address{1}}
+	zero := UserOperation{
+		Sender: common.Address{1}, Nonce: new(big.Int), CallGasLimit: new(big.Int), VerificationGasLimit: new(big.Int),
+		PreVerificationGas: new(big.Int), MaxFeePerGas: new(big.Int), MaxPriorityFeePerGas: new(big.Int),
+	}
+	if have, want := UserOperationHash(big.NewInt(1), common.Address{2}, op), referenceUserOpHash(t, big.NewInt(1), common.Address{2}, zero); have != want {
+		t.Fatalf("hash of nil fields: have %x, want %x", have, want)
+	}
+}
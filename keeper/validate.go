@@ -0,0 +1,93 @@
+package keeper
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// TxValidator validates a signed transaction, returning an error if it must not
+// be handed out.
+type TxValidator func(*types.Transaction) error
+
+// NewValidatingSecureSigner returns a SecureSigner running validators on every
+// transaction signed by inner, in order. If one fails, its error is returned
+// instead of the signed transaction. The validators of this package fail with
+// ErrInvalidTransaction.
+//
+// Besides, every transaction has to recover to the address of its key under the
+// types.Signer it was signed with, as TxValidators don't know the key. This
+// catches backends signing with another key than asked for.
+func NewValidatingSecureSigner(inner SecureSigner, validators ...TxValidator) SecureSigner {
+	return &checkedSigner{SecureSigner: inner, verify: func(ctx context.Context, signed *types.Transaction, signer types.Signer, prvID []byte) error {
+		pub, err := signerContext(inner).GetPublicKeyContext(ctx, prvID)
+		if err != nil {
+			return err
+		}
+		key, err := crypto.UnmarshalPubkey(pub)
+		if err != nil {
+			return err
+		}
+		from, err := types.Sender(signer, signed)
+		if err != nil {
+			return fmt.Errorf("%w: sender not recoverable: %v", ErrInvalidTransaction, err)
+		}
+		if want := crypto.PubkeyToAddress(*key); from != want {
+			return fmt.Errorf("%w: signed by %v, want %v", ErrInvalidTransaction, from, want)
+		}
+		for _, validate := range validators {
+			if err := validate(signed); err != nil {
+				return err
+			}
+		}
+		return nil
+	}}
+}
+
+// ValidateNonZeroValue refuses transactions transferring no value, e.g. payouts
+// whose amount was lost on the way.
+func ValidateNonZeroValue(tx *types.Transaction) error {
+	if tx.Value().Sign() <= 0 {
+		return fmt.Errorf("%w: value %v not positive", ErrInvalidTransaction, tx.Value())
+	}
+	return nil
+}
+
+// ValidateGasLimitRange returns a TxValidator refusing transactions with a gas
+// limit below min or above max.
+func ValidateGasLimitRange(min, max uint64) TxValidator {
+	return func(tx *types.Transaction) error {
+		if tx.Gas() < min || tx.Gas() > max {
+			return fmt.Errorf("%w: gas limit %d not in [%d, %d]", ErrInvalidTransaction, tx.Gas(), min, max)
+		}
+		return nil
+	}
+}
+
+// ValidateChainID returns a TxValidator refusing transactions not bound to the
+// chain chainID, unprotected legacy transactions included.
+func ValidateChainID(chainID *big.Int) TxValidator {
+	want := new(big.Int).Set(chainID)
+	return func(tx *types.Transaction) error {
+		if !tx.Protected() || tx.ChainId().Cmp(want) != 0 {
+			return fmt.Errorf("%w: chain id %v, want %v", ErrInvalidTransaction, tx.ChainId(), want)
+		}
+		return nil
+	}
+}
+
+// ValidateSignatureRecovery returns a TxValidator refusing transactions whose
+// sender can't be recovered with signer, e.g. when they were signed for another
+// chain or fork. The recovered sender is checked against the key by the
+// validating signer itself.
+func ValidateSignatureRecovery(signer types.Signer) TxValidator {
+	return func(tx *types.Transaction) error {
+		if _, err := types.Sender(signer, tx); err != nil {
+			return fmt.Errorf("%w: sender not recoverable with %T: %v", ErrInvalidTransaction, signer, err)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,78 @@
+package keeper
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// swappedKeeper is a PrivateKeyKeeper signing with other instead of the key
+// asked for, like a misconfigured backend.
+type swappedKeeper struct {
+	PrivateKeyKeeper
+	other []byte
+}
+
+func (k *swappedKeeper) Sign(data []byte, prvID []byte) ([]byte, error) {
+	return k.PrivateKeyKeeper.Sign(data, k.other)
+}
+
+func TestValidatingSecureSigner(t *testing.T) {
+	var (
+		chainID = big.NewInt(1)
+		signer  = types.LatestSignerForChainID(chainID)
+		s       = NewValidatingSecureSigner(NewSecureSigner(defaultKeeper),
+			ValidateNonZeroValue,
+			ValidateGasLimitRange(21000, 100000),
+			ValidateChainID(chainID),
+			ValidateSignatureRecovery(signer),
+		)
+	)
+	prvID, err := s.GenerateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	if _, err := s.Sign(newTestTx(), signer, prvID); err != nil {
+		t.Fatalf("failed to sign valid transaction: %v", err)
+	}
+	if _, err := s.SignDynamicFeeTx(chainID, 0, common.Address{0x01}, big.NewInt(1), 21000, big.NewInt(2), big.NewInt(1), nil, prvID); err != nil {
+		t.Fatalf("failed to sign valid dynamic fee transaction: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		tx     *types.Transaction
+		signer types.Signer
+	}{
+		{"zero value", types.NewTx(&types.LegacyTx{To: &common.Address{0x01}, Value: new(big.Int), Gas: 21000, GasPrice: big.NewInt(1)}), signer},
+		{"gas too low", types.NewTx(&types.LegacyTx{To: &common.Address{0x01}, Value: big.NewInt(1), Gas: 20999, GasPrice: big.NewInt(1)}), signer},
+		{"gas too high", types.NewTx(&types.LegacyTx{To: &common.Address{0x01}, Value: big.NewInt(1), Gas: 100001, GasPrice: big.NewInt(1)}), signer},
+		{"other chain", newTestTx(), types.LatestSignerForChainID(big.NewInt(5))},
+		{"unprotected", newTestTx(), types.HomesteadSigner{}},
+	}
+	for _, tt := range tests {
+		signed, err := s.Sign(tt.tx, tt.signer, prvID)
+		if !errors.Is(err, ErrInvalidTransaction) || signed != nil {
+			t.Fatalf("%s: have (%v, %v), want %v", tt.name, signed, err, ErrInvalidTransaction)
+		}
+	}
+	// The helpers building their transactions are validated as well.
+	if _, err := s.SignDynamicFeeTx(chainID, 0, common.Address{0x01}, new(big.Int), 21000, big.NewInt(2), big.NewInt(1), nil, prvID); !errors.Is(err, ErrInvalidTransaction) {
+		t.Fatalf("dynamic fee transaction of zero value: have %v, want %v", err, ErrInvalidTransaction)
+	}
+}
+
+func TestValidatingSecureSignerKey(t *testing.T) {
+	other, _ := GenerateDeterministicKey([]byte("other"))
+	s := NewValidatingSecureSigner(NewSecureSigner(&swappedKeeper{PrivateKeyKeeper: defaultKeeper, other: other}))
+	prvID, err := s.GenerateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	if _, err := s.Sign(newTestTx(), types.LatestSignerForChainID(big.NewInt(1)), prvID); !errors.Is(err, ErrInvalidTransaction) {
+		t.Fatalf("transaction signed by other key: have %v, want %v", err, ErrInvalidTransaction)
+	}
+}
@@ -0,0 +1,453 @@
+package keeper
+
+import (
+	"context"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/google/uuid"
+	vault "github.com/hashicorp/vault/api"
+)
+
+// DefaultVaultKeyType is the transit key type of keys generated by the Vault
+// keeper.
+//
+// Note, Ethereum signatures require secp256k1 keys, which are not supported by
+// the stock transit engine. The key type has to be changed by the WithVaultKeyType
+// option to the one a secp256k1 capable transit compatible plugin mounted at the
+// mount path registers; keys of any other curve are refused, and deleted again,
+// by GeneratePrivateKey.
+const DefaultVaultKeyType = "ecdsa-p256"
+
+// VaultOption configures the Vault keeper.
+type VaultOption func(*vaultKeeper)
+
+// WithVaultKeyType sets the transit key type of generated keys.
+func WithVaultKeyType(keyType string) VaultOption {
+	return func(k *vaultKeeper) {
+		k.keyType = keyType
+	}
+}
+
+// vaultKeeper is a PrivateKeyKeeper storing keys in the transit secrets engine
+// of HashiCorp Vault. The prvID is the name of the transit key.
+type vaultKeeper struct {
+	client  *vault.Client
+	mount   string
+	keyType string
+
+	rotations keyRotations
+}
+
+// NewVaultKeeper returns a PrivateKeyKeeper backed by the transit secrets engine
+// mounted at mountPath.
+func NewVaultKeeper(client *vault.Client, mountPath string, opts ...VaultOption) PrivateKeyKeeper {
+	k := &vaultKeeper{
+		client:  client,
+		mount:   strings.Trim(mountPath, "/"),
+		keyType: DefaultVaultKeyType,
+	}
+	for _, opt := range opts {
+		opt(k)
+	}
+	return k
+}
+
+// keyPath returns the path of the transit endpoint op for key name.
+func (k *vaultKeeper) keyPath(op string, name []byte) (string, error) {
+	if len(name) == 0 || strings.ContainsAny(string(name), "/?#") {
+		return "", fmt.Errorf("invalid vault key name %q", name)
+	}
+	return path.Join(k.mount, op, string(name)), nil
+}
+
+// vaultError marks failed Vault requests with the matching error of the keeper
+// package, keeping the Vault response error accessible.
+func vaultError(err error) error {
+	var respErr *vault.ResponseError
+	if errors.As(err, &respErr) {
+		switch {
+		case respErr.StatusCode == http.StatusUnauthorized || respErr.StatusCode == http.StatusForbidden:
+			return fmt.Errorf("%w: %w", ErrPermissionDenied, err)
+		case respErr.StatusCode == http.StatusTooManyRequests:
+			return fmt.Errorf("%w: %w", ErrRateLimitExceeded, err)
+		case respErr.StatusCode >= http.StatusInternalServerError:
+			// Sealed or standby Vault nodes answer with 503.
+			return fmt.Errorf("%w: %w", ErrBackendUnavailable, err)
+		}
+		return err
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return fmt.Errorf("%w: %w", ErrBackendUnavailable, err)
+	}
+	return err
+}
+
+func (k *vaultKeeper) GeneratePrivateKey() ([]byte, error) {
+	return k.GeneratePrivateKeyContext(context.Background())
+}
+
+func (k *vaultKeeper) GeneratePrivateKeyContext(ctx context.Context) (_ []byte, err error) {
+	defer wrapError(&err, "generate key", nil)
+
+	name := []byte(uuid.New().String())
+	p, err := k.keyPath("keys", name)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := k.client.Logical().WriteWithContext(ctx, p, map[string]interface{}{
+		"type":       k.keyType,
+		"exportable": false,
+	}); err != nil {
+		return nil, vaultError(err)
+	}
+	// Transit accepts key types signing on other curves, whose keys can never
+	// make an Ethereum signature.
+	der, err := k.publicKeyDER(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := parseSubjectPublicKeyInfo(der); err != nil {
+		k.DeletePrivateKeyContext(context.WithoutCancel(ctx), name)
+		return nil, fmt.Errorf("vault key type %q is not secp256k1: %v", k.keyType, err)
+	}
+	return name, nil
+}
+
+// ExportEncryptedKey returns ErrExportNotSupported. The keeper creates and
+// imports its transit keys as non-exportable.
+func (k *vaultKeeper) ExportEncryptedKey(prvID []byte, passphrase string) (_ []byte, err error) {
+	defer wrapError(&err, "export key", prvID)
+	return nil, ErrExportNotSupported
+}
+
+func (k *vaultKeeper) ImportEncryptedKey(data []byte, passphrase string) ([]byte, error) {
+	return importEncryptedKey(k, data, passphrase)
+}
+
+func (k *vaultKeeper) ImportPrivateKey(rawKey []byte) ([]byte, error) {
+	return k.ImportPrivateKeyContext(context.Background(), rawKey)
+}
+
+// ImportPrivateKeyContext imports rawKey as a new transit key with the bring
+// your own key flow of transit: the key is encrypted for the wrapping key of
+// the mount, so it is only ever decrypted inside of Vault.
+func (k *vaultKeeper) ImportPrivateKeyContext(ctx context.Context, rawKey []byte) (_ []byte, err error) {
+	defer wrapError(&err, "import key", nil)
+
+	key, err := parseRawKey(rawKey)
+	if err != nil {
+		return nil, err
+	}
+	defer ZeroKey(key)
+
+	name := []byte(uuid.New().String())
+	p, err := k.keyPath("keys", name)
+	if err != nil {
+		return nil, err
+	}
+	wrappingKey, err := k.wrappingKey(ctx)
+	if err != nil {
+		return nil, err
+	}
+	material, err := marshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	defer zeroBytes(material)
+
+	ciphertext, err := wrapKeyMaterial(wrappingKey, material)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := k.client.Logical().WriteWithContext(ctx, p+"/import", map[string]interface{}{
+		"ciphertext":    base64.StdEncoding.EncodeToString(ciphertext),
+		"type":          k.keyType,
+		"hash_function": "SHA256",
+		"exportable":    false,
+	}); err != nil {
+		return nil, vaultError(err)
+	}
+	return name, nil
+}
+
+// wrappingKey returns the RSA key of the mount that imported keys are
+// encrypted for.
+func (k *vaultKeeper) wrappingKey(ctx context.Context) (*rsa.PublicKey, error) {
+	secret, err := k.client.Logical().ReadWithContext(ctx, path.Join(k.mount, "wrapping_key"))
+	if err != nil {
+		return nil, vaultError(err)
+	}
+	if secret == nil || secret.Data == nil {
+		return nil, errors.New("empty vault wrapping key response")
+	}
+	pubPEM, ok := secret.Data["public_key"].(string)
+	if !ok {
+		return nil, errors.New("missing public key in vault wrapping key response")
+	}
+	block, _ := pem.Decode([]byte(pubPEM))
+	if block == nil {
+		return nil, errors.New("vault wrapping key is not PEM encoded")
+	}
+	pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid vault wrapping key: %v", err)
+	}
+	rsaPub, ok := pub.(*rsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("invalid vault wrapping key type %T", pub)
+	}
+	return rsaPub, nil
+}
+
+func (k *vaultKeeper) GetPublicKey(prvID []byte) ([]byte, error) {
+	return k.GetPublicKeyContext(context.Background(), prvID)
+}
+
+func (k *vaultKeeper) GetPublicKeyContext(ctx context.Context, prvID []byte) (_ []byte, err error) {
+	defer wrapError(&err, "get public key", prvID)
+
+	der, err := k.publicKeyDER(ctx, prvID)
+	if err != nil {
+		return nil, err
+	}
+	pub, err := parseSubjectPublicKeyInfo(der)
+	if err != nil {
+		return nil, fmt.Errorf("invalid vault key %q: %v", prvID, err)
+	}
+	return pub, nil
+}
+
+// publicKeyDER returns the DER encoded public key of the latest version of the
+// transit key prvID.
+func (k *vaultKeeper) publicKeyDER(ctx context.Context, prvID []byte) ([]byte, error) {
+	p, err := k.keyPath("keys", prvID)
+	if err != nil {
+		return nil, err
+	}
+	secret, err := k.client.Logical().ReadWithContext(ctx, p)
+	if err != nil {
+		return nil, vaultError(err)
+	}
+	if secret == nil || secret.Data == nil {
+		return nil, fmt.Errorf("%w: vault key %q", ErrKeyNotFound, prvID)
+	}
+	// Public keys are listed per key version, signing uses the latest one.
+	latest, err := jsonNumber(secret.Data["latest_version"])
+	if err != nil {
+		return nil, fmt.Errorf("invalid vault key %q: %v", prvID, err)
+	}
+	versions, ok := secret.Data["keys"].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("invalid vault key %q: missing key versions", prvID)
+	}
+	version, ok := versions[latest.String()].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("invalid vault key %q: missing version %s", prvID, latest)
+	}
+	pubPEM, ok := version["public_key"].(string)
+	if !ok {
+		return nil, fmt.Errorf("invalid vault key %q: missing public key", prvID)
+	}
+	block, _ := pem.Decode([]byte(pubPEM))
+	if block == nil {
+		return nil, fmt.Errorf("invalid vault key %q: public key is not PEM encoded", prvID)
+	}
+	return block.Bytes, nil
+}
+
+func (k *vaultKeeper) Sign(data []byte, prvID []byte) ([]byte, error) {
+	return k.SignContext(context.Background(), data, prvID)
+}
+
+// SignContext signs the 32 byte digest data. Transit returns DER encoded
+// signatures without a recovery id, which is reconstructed from the public key
+// of prvID.
+func (k *vaultKeeper) SignContext(ctx context.Context, data []byte, prvID []byte) (_ []byte, err error) {
+	defer wrapError(&err, "sign", prvID)
+
+	if len(data) != 32 {
+		return nil, fmt.Errorf("hash is required to be exactly 32 bytes (%d)", len(data))
+	}
+	pub, err := k.GetPublicKeyContext(ctx, prvID)
+	if err != nil {
+		return nil, err
+	}
+	p, err := k.keyPath("sign", prvID)
+	if err != nil {
+		return nil, err
+	}
+	secret, err := k.client.Logical().WriteWithContext(ctx, p, map[string]interface{}{
+		"input":                base64.StdEncoding.EncodeToString(data),
+		"prehashed":            true,
+		"hash_algorithm":       "sha2-256",
+		"marshaling_algorithm": "asn1",
+	})
+	if err != nil {
+		return nil, vaultError(err)
+	}
+	if secret == nil || secret.Data == nil {
+		return nil, errors.New("empty vault sign response")
+	}
+	encoded, ok := secret.Data["signature"].(string)
+	if !ok {
+		return nil, errors.New("missing signature in vault sign response")
+	}
+	der, err := decodeVaultSignature(encoded)
+	if err != nil {
+		return nil, err
+	}
+	r, s, err := parseDERSignature(der)
+	if err != nil {
+		return nil, err
+	}
+	return recoverableSignature(data, r, s, pub)
+}
+
+func (k *vaultKeeper) DeletePrivateKey(prvID []byte) error {
+	return k.DeletePrivateKeyContext(context.Background(), prvID)
+}
+
+// DeletePrivateKeyContext deletes the transit key. Transit refuses to delete
+// keys unless explicitly allowed, so deletion is enabled on the key first, and
+// disabled again if the deletion fails.
+func (k *vaultKeeper) DeletePrivateKeyContext(ctx context.Context, prvID []byte) (err error) {
+	defer wrapError(&err, "delete key", prvID)
+
+	p, err := k.keyPath("keys", prvID)
+	if err != nil {
+		return err
+	}
+	// Transit silently succeeds deleting missing keys, and would create the
+	// key config of one, so check that the key exists.
+	secret, err := k.client.Logical().ReadWithContext(ctx, p)
+	if err != nil {
+		return vaultError(err)
+	}
+	if secret == nil || secret.Data == nil {
+		return fmt.Errorf("%w: vault key %q", ErrKeyNotFound, prvID)
+	}
+	if _, err := k.client.Logical().WriteWithContext(ctx, p+"/config", map[string]interface{}{
+		"deletion_allowed": true,
+	}); err != nil {
+		return vaultError(err)
+	}
+	if _, err := k.client.Logical().DeleteWithContext(ctx, p); err != nil {
+		k.client.Logical().WriteWithContext(context.WithoutCancel(ctx), p+"/config", map[string]interface{}{
+			"deletion_allowed": false,
+		})
+		return vaultError(err)
+	}
+	return nil
+}
+
+// RotateKey creates a new transit key replacing oldPrvID. Transit's own key
+// rotation is not used, as it keeps the key name and hence the prvID.
+func (k *vaultKeeper) RotateKey(oldPrvID []byte) (_ []byte, err error) {
+	defer wrapError(&err, "rotate key", oldPrvID)
+	return k.rotations.rotate(k, oldPrvID)
+}
+
+// CompleteRotation deletes the transit key oldPrvID once its replacement can be
+// read. If the deletion fails, the old key is left protected from deletion.
+func (k *vaultKeeper) CompleteRotation(oldPrvID []byte) (err error) {
+	defer wrapError(&err, "complete rotation", oldPrvID)
+	return k.rotations.complete(k, oldPrvID)
+}
+
+func (k *vaultKeeper) ListPrivateKeys() ([][]byte, error) {
+	return k.ListPrivateKeysContext(context.Background())
+}
+
+// ListPrivateKeysContext returns the names of all keys of the transit mount,
+// including the ones not created by the keeper.
+func (k *vaultKeeper) ListPrivateKeysContext(ctx context.Context) (_ [][]byte, err error) {
+	defer wrapError(&err, "list keys", nil)
+
+	secret, err := k.client.Logical().ListWithContext(ctx, path.Join(k.mount, "keys"))
+	if err != nil {
+		return nil, vaultError(err)
+	}
+	// Vault answers listing an empty mount with 404, which the client
+	// reports as no secret.
+	if secret == nil || secret.Data == nil {
+		return nil, nil
+	}
+	names, ok := secret.Data["keys"].([]interface{})
+	if !ok {
+		return nil, errors.New("missing keys in vault list response")
+	}
+	prvIDs := make([][]byte, 0, len(names))
+	for _, name := range names {
+		s, ok := name.(string)
+		if !ok {
+			return nil, fmt.Errorf("invalid vault key name %v", name)
+		}
+		prvIDs = append(prvIDs, []byte(s))
+	}
+	return prvIDs, nil
+}
+
+// Snapshot lists the IDs of the keys, the key material never leaves Vault.
+func (k *vaultKeeper) Snapshot() (_ []byte, err error) {
+	defer wrapError(&err, "snapshot", nil)
+	return snapshotKeyIDs(context.Background(), k)
+}
+
+// Restore checks that the keys of the snapshot still exist in Vault.
+func (k *vaultKeeper) Restore(snapshot []byte) (err error) {
+	defer wrapError(&err, "restore snapshot", nil)
+	return restoreKeyIDs(context.Background(), k, snapshot)
+}
+
+// HealthCheck queries sys/health, failing with ErrBackendUnavailable if Vault
+// is unreachable, sealed or not initialized. Standby nodes count as healthy, they
+// forward requests to the active node.
+func (k *vaultKeeper) HealthCheck(ctx context.Context) (err error) {
+	defer wrapError(&err, "health check", nil)
+
+	health, err := k.client.Sys().HealthWithContext(ctx)
+	if err != nil {
+		return vaultError(err)
+	}
+	if !health.Initialized || health.Sealed {
+		return fmt.Errorf("%w: vault sealed or not initialized", ErrBackendUnavailable)
+	}
+	return nil
+}
+
+// decodeVaultSignature strips the "vault:v<version>:" prefix off a transit
+// signature and decodes the base64 payload.
+func decodeVaultSignature(sig string) ([]byte, error) {
+	parts := strings.SplitN(sig, ":", 3)
+	if len(parts) != 3 || parts[0] != "vault" || !strings.HasPrefix(parts[1], "v") {
+		return nil, fmt.Errorf("%w: malformed vault signature %q", ErrInvalidSignature, sig)
+	}
+	der, err := base64.StdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed vault signature: %v", ErrInvalidSignature, err)
+	}
+	return der, nil
+}
+
+// jsonNumber converts a number decoded by the Vault client, which decodes
+// responses with UseNumber, into a json.Number.
+func jsonNumber(v interface{}) (json.Number, error) {
+	switch n := v.(type) {
+	case json.Number:
+		return n, nil
+	case float64:
+		return json.Number(fmt.Sprint(int64(n))), nil
+	}
+	return "", fmt.Errorf("invalid number %v", v)
+}
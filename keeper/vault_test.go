@@ -0,0 +1,338 @@
+package keeper
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	vault "github.com/hashicorp/vault/api"
+)
+
+// fakeTransit emulates the parts of the Vault transit secrets engine used by
+// the keeper, with support for secp256k1 keys.
+type fakeTransit struct {
+	mount   string
+	keyType string
+
+	lock       sync.Mutex
+	keys       map[string]*ecdsa.PrivateKey
+	deletable  map[string]bool
+	failDelete bool // fail key deletions as a sealed Vault would
+	sealed     bool // report Vault as sealed on sys/health
+}
+
+func (f *fakeTransit) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if r.URL.Path == "/v1/sys/health" {
+		json.NewEncoder(w).Encode(map[string]interface{}{"initialized": true, "sealed": f.sealed})
+		return
+	}
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/v1/"+f.mount+"/"), "/")
+	if len(parts) == 1 && parts[0] == "keys" && r.URL.Query().Get("list") == "true" {
+		if len(f.keys) == 0 {
+			http.Error(w, `{"errors":[]}`, http.StatusNotFound)
+			return
+		}
+		names := make([]string, 0, len(f.keys))
+		for name := range f.keys {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"data": map[string]interface{}{"keys": names},
+		})
+		return
+	}
+	if len(parts) == 1 && parts[0] == "wrapping_key" && r.Method == http.MethodGet {
+		pub, _ := x509.MarshalPKIXPublicKey(&testWrappingKey().PublicKey)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"data": map[string]interface{}{
+				"public_key": string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pub})),
+			},
+		})
+		return
+	}
+	if len(parts) != 2 && (len(parts) != 3 || (parts[2] != "config" && parts[2] != "import")) {
+		http.NotFound(w, r)
+		return
+	}
+	var body map[string]interface{}
+	if r.Body != nil {
+		json.NewDecoder(r.Body).Decode(&body)
+	}
+	op, name := parts[0], parts[1]
+	if len(parts) == 3 {
+		op += "/" + parts[2]
+	}
+	switch {
+	case op == "keys" && r.Method == http.MethodPut:
+		var key *ecdsa.PrivateKey
+		switch body["type"] {
+		case f.keyType:
+			key, _ = crypto.GenerateKey()
+		case "ecdsa-p256":
+			key, _ = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		default:
+			http.Error(w, `{"errors":["unsupported key type"]}`, http.StatusBadRequest)
+			return
+		}
+		f.keys[name] = key
+		w.WriteHeader(http.StatusNoContent)
+
+	case op == "keys/import" && r.Method == http.MethodPut:
+		if _, ok := f.keys[name]; ok {
+			http.Error(w, `{"errors":["the import path cannot be used with an existing key"]}`, http.StatusBadRequest)
+			return
+		}
+		if body["type"] != f.keyType || body["hash_function"] != "SHA256" {
+			http.Error(w, `{"errors":["unexpected import parameters"]}`, http.StatusBadRequest)
+			return
+		}
+		ciphertext, _ := base64.StdEncoding.DecodeString(body["ciphertext"].(string))
+		key, err := unwrapTestKeyMaterial(ciphertext)
+		if err != nil {
+			http.Error(w, `{"errors":["failed to unwrap key"]}`, http.StatusBadRequest)
+			return
+		}
+		f.keys[name] = key
+		w.WriteHeader(http.StatusNoContent)
+
+	case op == "keys" && r.Method == http.MethodGet:
+		key, ok := f.keys[name]
+		if !ok {
+			http.Error(w, `{"errors":[]}`, http.StatusNotFound)
+			return
+		}
+		der := marshalTestSPKI(&key.PublicKey)
+		if key.Curve == elliptic.P256() {
+			der, _ = x509.MarshalPKIXPublicKey(&key.PublicKey)
+		}
+		pubPEM := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"data": map[string]interface{}{
+				"name":           name,
+				"type":           f.keyType,
+				"latest_version": 1,
+				"keys": map[string]interface{}{
+					"1": map[string]interface{}{"public_key": string(pubPEM)},
+				},
+			},
+		})
+
+	case op == "keys/config" && r.Method == http.MethodPut:
+		if _, ok := f.keys[name]; !ok {
+			http.Error(w, `{"errors":["no existing key named `+name+` could be found"]}`, http.StatusBadRequest)
+			return
+		}
+		f.deletable[name] = body["deletion_allowed"] == true
+		w.WriteHeader(http.StatusNoContent)
+
+	case op == "keys" && r.Method == http.MethodDelete:
+		if f.failDelete {
+			http.Error(w, `{"errors":["Vault is sealed"]}`, http.StatusServiceUnavailable)
+			return
+		}
+		if _, ok := f.keys[name]; ok && !f.deletable[name] {
+			http.Error(w, `{"errors":["deletion is not allowed for this key"]}`, http.StatusBadRequest)
+			return
+		}
+		delete(f.keys, name)
+		w.WriteHeader(http.StatusNoContent)
+
+	case op == "sign" && r.Method == http.MethodPut:
+		key, ok := f.keys[name]
+		if !ok {
+			http.Error(w, `{"errors":["signing key not found"]}`, http.StatusBadRequest)
+			return
+		}
+		if body["prehashed"] != true || body["marshaling_algorithm"] != "asn1" {
+			http.Error(w, `{"errors":["unexpected signing parameters"]}`, http.StatusBadRequest)
+			return
+		}
+		input, _ := base64.StdEncoding.DecodeString(body["input"].(string))
+		sig := base64.StdEncoding.EncodeToString(derSign(input, key, true))
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"data": map[string]interface{}{"signature": "vault:v1:" + sig},
+		})
+
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func newTestVaultKeeper(t *testing.T) (PrivateKeyKeeper, *fakeTransit) {
+	t.Helper()
+
+	transit := &fakeTransit{mount: "transit", keyType: "ecdsa-secp256k1", keys: make(map[string]*ecdsa.PrivateKey), deletable: make(map[string]bool)}
+	srv := httptest.NewServer(transit)
+	t.Cleanup(srv.Close)
+
+	cfg := vault.DefaultConfig()
+	cfg.Address = srv.URL
+	cfg.MaxRetries = 0
+	client, err := vault.NewClient(cfg)
+	if err != nil {
+		t.Fatalf("failed to create vault client: %v", err)
+	}
+	client.SetToken("test")
+	return NewVaultKeeper(client, "/transit/", WithVaultKeyType(transit.keyType)), transit
+}
+
+func TestVaultKeeper(t *testing.T) {
+	k, transit := newTestVaultKeeper(t)
+
+	prvID, err := k.GeneratePrivateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	if _, ok := transit.keys[string(prvID)]; !ok {
+		t.Fatalf("key %q not created in transit", prvID)
+	}
+	pub, err := k.GetPublicKey(prvID)
+	if err != nil {
+		t.Fatalf("failed to get public key: %v", err)
+	}
+	if want := crypto.FromECDSAPub(&transit.keys[string(prvID)].PublicKey); !bytes.Equal(pub, want) {
+		t.Fatalf("public key mismatch: have %x, want %x", pub, want)
+	}
+	hash := crypto.Keccak256([]byte("vault"))
+	sig, err := k.Sign(hash, prvID)
+	if err != nil {
+		t.Fatalf("failed to sign: %v", err)
+	}
+	if len(sig) != crypto.SignatureLength {
+		t.Fatalf("signature length mismatch: have %d, want %d", len(sig), crypto.SignatureLength)
+	}
+	recovered, err := crypto.Ecrecover(hash, sig)
+	if err != nil {
+		t.Fatalf("failed to recover: %v", err)
+	}
+	if !bytes.Equal(recovered, pub) {
+		t.Fatalf("recovered key mismatch: have %x, want %x", recovered, pub)
+	}
+	if err := k.DeletePrivateKey(prvID); err != nil {
+		t.Fatalf("failed to delete key: %v", err)
+	}
+	if _, ok := transit.keys[string(prvID)]; ok {
+		t.Fatalf("key %q not deleted in transit", prvID)
+	}
+}
+
+func TestVaultKeeperImport(t *testing.T) {
+	k, transit := newTestVaultKeeper(t)
+
+	prvID := checkImport(t, k)
+	if _, ok := transit.keys[string(prvID)]; !ok || len(transit.keys) != 1 {
+		t.Fatalf("transit keys after import: have %d, want only %s", len(transit.keys), prvID)
+	}
+}
+
+func TestVaultKeeperList(t *testing.T) {
+	k, _ := newTestVaultKeeper(t)
+
+	if ids, err := k.ListPrivateKeys(); err != nil || len(ids) != 0 {
+		t.Fatalf("empty mount: have (%q, %v), want no keys", ids, err)
+	}
+	want := make(map[string]bool)
+	for i := 0; i < 3; i++ {
+		prvID, err := k.GeneratePrivateKey()
+		if err != nil {
+			t.Fatalf("failed to generate key: %v", err)
+		}
+		want[string(prvID)] = true
+	}
+	ids, err := k.ListPrivateKeys()
+	if err != nil {
+		t.Fatalf("failed to list keys: %v", err)
+	}
+	if len(ids) != len(want) {
+		t.Fatalf("key count mismatch: have %d, want %d", len(ids), len(want))
+	}
+	for _, id := range ids {
+		if !want[string(id)] {
+			t.Errorf("unexpected key %q", id)
+		}
+	}
+}
+
+func TestVaultKeeperErrors(t *testing.T) {
+	k, _ := newTestVaultKeeper(t)
+
+	if _, err := k.GetPublicKey([]byte("missing")); !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("missing key: have %v, want %v", err, ErrKeyNotFound)
+	}
+	if err := k.DeletePrivateKey([]byte("missing")); !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("deleting missing key: have %v, want %v", err, ErrKeyNotFound)
+	}
+	if _, err := k.Sign(make([]byte, 32), []byte("missing")); err == nil {
+		t.Error("signed with missing key")
+	}
+	if _, err := k.Sign(make([]byte, 32), []byte("../sys/seal")); err == nil {
+		t.Error("accepted path traversing key name")
+	}
+}
+
+func TestVaultKeeperKeyType(t *testing.T) {
+	k, transit := newTestVaultKeeper(t)
+
+	// Keys of the default type sign on P-256, they are refused and deleted.
+	if _, err := NewVaultKeeper(k.(*vaultKeeper).client, "transit").GeneratePrivateKey(); err == nil {
+		t.Error("generated key of unsupported type")
+	}
+	if len(transit.keys) != 0 {
+		t.Errorf("unsupported keys left in transit: %d", len(transit.keys))
+	}
+	// Types transit doesn't know fail on creation.
+	if _, err := NewVaultKeeper(k.(*vaultKeeper).client, "transit", WithVaultKeyType("unknown")).GeneratePrivateKey(); err == nil {
+		t.Error("generated key of unknown type")
+	}
+}
+
+func TestVaultKeeperHealthCheck(t *testing.T) {
+	k, transit := newTestVaultKeeper(t)
+	hc := k.(HealthChecker)
+
+	if err := hc.HealthCheck(context.Background()); err != nil {
+		t.Fatalf("unsealed vault: have %v, want healthy", err)
+	}
+	transit.lock.Lock()
+	transit.sealed = true
+	transit.lock.Unlock()
+	if err := hc.HealthCheck(context.Background()); !errors.Is(err, ErrBackendUnavailable) {
+		t.Fatalf("sealed vault: have %v, want %v", err, ErrBackendUnavailable)
+	}
+}
+
+func TestDecodeVaultSignature(t *testing.T) {
+	tests := []struct {
+		sig string
+		ok  bool
+	}{
+		{"vault:v1:MEQCIA==", true},
+		{"vault:v12:MEQCIA==", true},
+		{"vault:MEQCIA==", false},
+		{"notvault:v1:MEQCIA==", false},
+		{"vault:v1:not base64", false},
+	}
+	for _, tt := range tests {
+		if _, err := decodeVaultSignature(tt.sig); (err == nil) != tt.ok {
+			t.Errorf("%q: have error %v, want ok %v", tt.sig, err, tt.ok)
+		}
+	}
+}
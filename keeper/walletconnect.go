@@ -0,0 +1,943 @@
+package keeper
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/gorilla/websocket"
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/curve25519"
+	"golang.org/x/crypto/hkdf"
+)
+
+const (
+	// DefaultWalletConnectRelayURL is the URL of the WalletConnect relay.
+	DefaultWalletConnectRelayURL = "wss://relay.walletconnect.com"
+
+	// walletConnectTTL is the time the relay keeps the messages of the keeper
+	// for an offline wallet, and the default time the wallet has to answer.
+	walletConnectTTL = 5 * time.Minute
+
+	// walletConnectTokenLifetime is the lifetime of the JWT authenticating the
+	// relay connection.
+	walletConnectTokenLifetime = 24 * time.Hour
+)
+
+// Tags of the WalletConnect v2 sign protocol messages, telling the relay how to
+// handle them.
+const (
+	wcTagSessionPropose         = 1100
+	wcTagSessionProposeResponse = 1101
+	wcTagSessionSettle          = 1102
+	wcTagSessionSettleResponse  = 1103
+	wcTagSessionDelete          = 1112
+	wcTagSessionRequest         = 1108
+	wcTagSessionRequestResponse = 1109
+)
+
+// WalletConnectMetadata describes the app to the wallet when pairing.
+type WalletConnectMetadata struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	URL         string   `json:"url"`
+	Icons       []string `json:"icons"`
+}
+
+// WalletConnectOption configures the WalletConnect keeper.
+type WalletConnectOption func(*walletConnectKeeper)
+
+// WithPairingHandler sets the function showing the pairing URI of
+// GeneratePrivateKey to the user, e.g. as a QR code to scan with the wallet.
+// It is called before the keeper waits for the wallet and must not block.
+func WithPairingHandler(handler func(uri string)) WalletConnectOption {
+	return func(k *walletConnectKeeper) {
+		k.onPairing = handler
+	}
+}
+
+// WithWalletConnectChainID sets the chain the wallet is asked to sign for, 1
+// by default.
+func WithWalletConnectChainID(chainID *big.Int) WalletConnectOption {
+	return func(k *walletConnectKeeper) {
+		k.chainID = new(big.Int).Set(chainID)
+	}
+}
+
+// WithWalletConnectMetadata sets the metadata the wallet shows about the app.
+func WithWalletConnectMetadata(metadata WalletConnectMetadata) WalletConnectOption {
+	return func(k *walletConnectKeeper) {
+		k.metadata = metadata
+	}
+}
+
+// walletConnectSession is a session with a paired wallet account.
+type walletConnectSession struct {
+	peer    *wcPeer
+	account common.Address
+	pub     []byte // uncompressed public key of the account
+}
+
+// walletConnectKeeper is a PrivateKeyKeeper signing with the accounts of
+// wallets paired over WalletConnect v2. The keys stay in the wallets, every
+// signature has to be approved by the user in the wallet app.
+type walletConnectKeeper struct {
+	projectID string
+	relayURL  string
+	clientKey ed25519.PrivateKey // key the relay connection is authenticated with
+	chainID   *big.Int
+	metadata  WalletConnectMetadata
+	onPairing func(uri string)
+
+	lock     sync.Mutex
+	relay    *wcRelay                         // nil until connected, or after it broke
+	sessions map[string]*walletConnectSession // prvID -> session
+}
+
+// NewWalletConnectKeeper returns a PrivateKeyKeeper signing with mobile wallets
+// paired over the WalletConnect v2 relay at relayURL, authenticated with the
+// WalletConnect Cloud projectID. An empty relayURL defaults to
+// DefaultWalletConnectRelayURL.
+//
+// GeneratePrivateKey pairs with a wallet: it hands the pairing URI to the
+// handler set with WithPairingHandler and waits for the user to connect the
+// wallet, then to sign a pairing message the public key of the account is
+// recovered from. The prvID is the checksummed address of the account. Sign
+// sends the hash with eth_sign and waits for the wallet to sign it. Signatures
+// not recovering to the account over the hash itself, as from wallets applying
+// the EIP-191 prefix to eth_sign, fail with ErrInvalidSignature. Both wait up
+// to five minutes unless their context ends earlier; rejections in the wallet
+// fail with ErrPermissionDenied.
+//
+// Sessions are kept in memory, they have to be paired again after a restart.
+// DeletePrivateKey disconnects a session, importing keys fails with
+// ErrNotSupported.
+func NewWalletConnectKeeper(projectID, relayURL string, opts ...WalletConnectOption) (PrivateKeyKeeper, error) {
+	if projectID == "" {
+		return nil, errors.New("missing walletconnect project id")
+	}
+	if relayURL == "" {
+		relayURL = DefaultWalletConnectRelayURL
+	}
+	if _, err := url.Parse(relayURL); err != nil {
+		return nil, fmt.Errorf("invalid walletconnect relay url: %w", err)
+	}
+	_, clientKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	k := &walletConnectKeeper{
+		projectID: projectID,
+		relayURL:  relayURL,
+		clientKey: clientKey,
+		chainID:   big.NewInt(1),
+		metadata:  WalletConnectMetadata{Name: "keeper", Icons: []string{}},
+		sessions:  make(map[string]*walletConnectSession),
+	}
+	for _, opt := range opts {
+		opt(k)
+	}
+	return k, nil
+}
+
+// connect returns the relay connection, dialing it again if it broke. The
+// topics of the peers are subscribed on the new connection.
+func (k *walletConnectKeeper) connect(ctx context.Context) (*wcRelay, error) {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+
+	if k.relay != nil && k.relay.err() == nil {
+		return k.relay, nil
+	}
+	relay, err := dialWalletConnectRelay(ctx, k.relayURL, k.projectID, k.clientKey)
+	if err != nil {
+		return nil, err
+	}
+	for _, session := range k.sessions {
+		if err := relay.subscribe(ctx, session.peer.topic, session.peer.handle); err != nil {
+			relay.close()
+			return nil, err
+		}
+		session.peer.setRelay(relay)
+	}
+	k.relay = relay
+	return relay, nil
+}
+
+// session returns the session of prvID.
+func (k *walletConnectKeeper) session(prvID []byte) (*walletConnectSession, error) {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+
+	session, ok := k.sessions[string(prvID)]
+	if !ok {
+		return nil, fmt.Errorf("%w: no walletconnect session", ErrKeyNotFound)
+	}
+	return session, nil
+}
+
+// caip2 returns the CAIP-2 chain ID of the chain of the keeper.
+func (k *walletConnectKeeper) caip2() string {
+	return "eip155:" + k.chainID.String()
+}
+
+func (k *walletConnectKeeper) GeneratePrivateKey() ([]byte, error) {
+	return k.GeneratePrivateKeyContext(context.Background())
+}
+
+// GeneratePrivateKeyContext pairs with a wallet, see NewWalletConnectKeeper.
+func (k *walletConnectKeeper) GeneratePrivateKeyContext(ctx context.Context) (_ []byte, err error) {
+	defer wrapError(&err, "generate key", nil)
+
+	if k.onPairing == nil {
+		return nil, fmt.Errorf("%w: no walletconnect pairing handler set", ErrNotSupported)
+	}
+	ctx, cancel := context.WithTimeout(ctx, walletConnectTTL)
+	defer cancel()
+
+	relay, err := k.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	pairing, err := newWCPeer(relay, randomTopic(), randomBytes(32))
+	if err != nil {
+		return nil, err
+	}
+	if err := relay.subscribe(ctx, pairing.topic, pairing.handle); err != nil {
+		return nil, err
+	}
+	defer relay.unsubscribe(pairing.topic)
+
+	proposerKey := randomBytes(curve25519.ScalarSize)
+	proposerPub, err := curve25519.X25519(proposerKey, curve25519.Basepoint)
+	if err != nil {
+		return nil, err
+	}
+	expiry := time.Now().Add(walletConnectTTL).Unix()
+	k.onPairing(fmt.Sprintf("wc:%s@2?relay-protocol=irn&symKey=%x&expiryTimestamp=%d", pairing.topic, pairing.symKey, expiry))
+
+	// Propose the session and derive its key from the answer of the wallet.
+	var proposal struct {
+		ResponderPublicKey string `json:"responderPublicKey"`
+	}
+	if err := pairing.call(ctx, "wc_sessionPropose", map[string]interface{}{
+		"relays": []map[string]string{{"protocol": "irn"}},
+		"requiredNamespaces": map[string]interface{}{
+			"eip155": map[string]interface{}{
+				"chains":  []string{k.caip2()},
+				"methods": []string{"eth_sign", "personal_sign"},
+				"events":  []string{},
+			},
+		},
+		"proposer": map[string]interface{}{
+			"publicKey": hex.EncodeToString(proposerPub),
+			"metadata":  k.metadata,
+		},
+		"expiryTimestamp": expiry,
+	}, wcTagSessionPropose, &proposal); err != nil {
+		return nil, err
+	}
+	responderPub, err := hex.DecodeString(proposal.ResponderPublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid walletconnect responder key: %w", err)
+	}
+	symKey, topic, err := wcSessionKey(proposerKey, responderPub)
+	if err != nil {
+		return nil, err
+	}
+	peer, err := newWCPeer(relay, topic, symKey)
+	if err != nil {
+		return nil, err
+	}
+	if err := relay.subscribe(ctx, peer.topic, peer.handle); err != nil {
+		return nil, err
+	}
+	account, err := k.settle(ctx, peer)
+	if err != nil {
+		relay.unsubscribe(peer.topic)
+		return nil, err
+	}
+
+	// Wallets don't tell the public key of an account, recover it from the
+	// signature of a pairing message.
+	message := fmt.Sprintf("Pair %s with keeper\n\nSession: %s", account.Hex(), peer.topic)
+	var result string
+	if err := peer.call(ctx, "wc_sessionRequest", map[string]interface{}{
+		"request": map[string]interface{}{
+			"method": "personal_sign",
+			"params": []string{hexutil.Encode([]byte(message)), account.Hex()},
+		},
+		"chainId": k.caip2(),
+	}, wcTagSessionRequest, &result); err != nil {
+		relay.unsubscribe(peer.topic)
+		return nil, err
+	}
+	_, pub, err := walletSignature(accounts.TextHash([]byte(message)), result)
+	if err == nil && common.BytesToAddress(crypto.Keccak256(pub[1:])[12:]) != account {
+		err = fmt.Errorf("%w: pairing message not signed by %v", ErrInvalidSignature, account)
+	}
+	if err != nil {
+		relay.unsubscribe(peer.topic)
+		return nil, err
+	}
+
+	prvID := []byte(account.Hex())
+	k.lock.Lock()
+	old := k.sessions[string(prvID)]
+	k.sessions[string(prvID)] = &walletConnectSession{peer: peer, account: account, pub: pub}
+	k.lock.Unlock()
+	if old != nil {
+		k.disconnect(old)
+	}
+	return prvID, nil
+}
+
+// settle waits for the wallet to settle the session on peer, and returns the
+// first account of the session on the chain of the keeper.
+func (k *walletConnectKeeper) settle(ctx context.Context, peer *wcPeer) (common.Address, error) {
+	for {
+		var req wcRequest
+		select {
+		case <-ctx.Done():
+			return common.Address{}, ctx.Err()
+		case req = <-peer.requests:
+		}
+		if req.Method != "wc_sessionSettle" {
+			continue
+		}
+		var settle struct {
+			Namespaces map[string]struct {
+				Accounts []string `json:"accounts"`
+			} `json:"namespaces"`
+		}
+		if err := json.Unmarshal(req.Params, &settle); err != nil {
+			return common.Address{}, fmt.Errorf("invalid walletconnect session: %w", err)
+		}
+		if err := peer.respond(ctx, req.ID, true, wcTagSessionSettleResponse); err != nil {
+			return common.Address{}, err
+		}
+		prefix := k.caip2() + ":"
+		for _, account := range settle.Namespaces["eip155"].Accounts {
+			if addr, ok := strings.CutPrefix(account, prefix); ok && common.IsHexAddress(addr) {
+				return common.HexToAddress(addr), nil
+			}
+		}
+		return common.Address{}, fmt.Errorf("%w: wallet connected no account on %s", ErrPermissionDenied, k.caip2())
+	}
+}
+
+// disconnect deletes session in the wallet. Failures are ignored, the wallet
+// may have disconnected already.
+func (k *walletConnectKeeper) disconnect(session *walletConnectSession) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	session.peer.notify(ctx, "wc_sessionDelete", map[string]interface{}{
+		"code":    6000,
+		"message": "User disconnected.",
+	}, wcTagSessionDelete)
+	session.peer.unsubscribe()
+}
+
+func (k *walletConnectKeeper) GetPublicKey(prvID []byte) ([]byte, error) {
+	return k.GetPublicKeyContext(context.Background(), prvID)
+}
+
+// GetPublicKeyContext returns the public key recovered when pairing.
+func (k *walletConnectKeeper) GetPublicKeyContext(ctx context.Context, prvID []byte) (_ []byte, err error) {
+	defer wrapError(&err, "get public key", prvID)
+
+	session, err := k.session(prvID)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.Clone(session.pub), nil
+}
+
+func (k *walletConnectKeeper) Sign(data []byte, prvID []byte) ([]byte, error) {
+	return k.SignContext(context.Background(), data, prvID)
+}
+
+// SignContext asks the wallet to sign the hash data with eth_sign.
+func (k *walletConnectKeeper) SignContext(ctx context.Context, data []byte, prvID []byte) (_ []byte, err error) {
+	defer wrapError(&err, "sign", prvID)
+
+	if len(data) != 32 {
+		return nil, fmt.Errorf("walletconnect signs 32 byte hashes, have %d bytes", len(data))
+	}
+	session, err := k.session(prvID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := k.connect(ctx); err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, walletConnectTTL)
+	defer cancel()
+
+	var result string
+	if err := session.peer.call(ctx, "wc_sessionRequest", map[string]interface{}{
+		"request": map[string]interface{}{
+			"method": "eth_sign",
+			"params": []string{session.account.Hex(), hexutil.Encode(data)},
+		},
+		"chainId": k.caip2(),
+	}, wcTagSessionRequest, &result); err != nil {
+		return nil, err
+	}
+	sig, pub, err := walletSignature(data, result)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(pub, session.pub) {
+		return nil, fmt.Errorf("%w: wallet didn't sign the hash itself", ErrInvalidSignature)
+	}
+	return sig, nil
+}
+
+func (k *walletConnectKeeper) DeletePrivateKey(prvID []byte) error {
+	return k.DeletePrivateKeyContext(context.Background(), prvID)
+}
+
+// DeletePrivateKeyContext disconnects the session of prvID, the key stays in
+// the wallet.
+func (k *walletConnectKeeper) DeletePrivateKeyContext(ctx context.Context, prvID []byte) (err error) {
+	defer wrapError(&err, "delete key", prvID)
+
+	k.lock.Lock()
+	session, ok := k.sessions[string(prvID)]
+	delete(k.sessions, string(prvID))
+	k.lock.Unlock()
+	if !ok {
+		return fmt.Errorf("%w: no walletconnect session", ErrKeyNotFound)
+	}
+	k.disconnect(session)
+	return nil
+}
+
+func (k *walletConnectKeeper) ListPrivateKeys() ([][]byte, error) {
+	return k.ListPrivateKeysContext(context.Background())
+}
+
+// ListPrivateKeysContext returns the sorted prvIDs of the paired accounts.
+func (k *walletConnectKeeper) ListPrivateKeysContext(ctx context.Context) ([][]byte, error) {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+
+	prvIDs := make([][]byte, 0, len(k.sessions))
+	for prvID := range k.sessions {
+		prvIDs = append(prvIDs, []byte(prvID))
+	}
+	sort.Slice(prvIDs, func(i, j int) bool { return bytes.Compare(prvIDs[i], prvIDs[j]) < 0 })
+	return prvIDs, nil
+}
+
+func (k *walletConnectKeeper) ImportPrivateKey(rawKey []byte) ([]byte, error) {
+	return k.ImportPrivateKeyContext(context.Background(), rawKey)
+}
+
+// ImportPrivateKeyContext returns ErrNotSupported, keys can't be moved into a
+// wallet.
+func (k *walletConnectKeeper) ImportPrivateKeyContext(ctx context.Context, rawKey []byte) (_ []byte, err error) {
+	defer wrapError(&err, "import key", nil)
+	return nil, ErrNotSupported
+}
+
+// walletSignature decodes the hex encoded signature of hash by a wallet, whose
+// recovery ID may be 27 or 28, and returns it in the [R || S || V] format with
+// V 0 or 1, along with the public key it recovers to.
+func walletSignature(hash []byte, signature string) ([]byte, []byte, error) {
+	sig, err := hexutil.Decode(signature)
+	if err != nil || len(sig) != crypto.SignatureLength {
+		return nil, nil, fmt.Errorf("%w: malformed walletconnect signature", ErrInvalidSignature)
+	}
+	if sig[crypto.RecoveryIDOffset] >= 27 {
+		sig[crypto.RecoveryIDOffset] -= 27
+	}
+	pub, err := crypto.Ecrecover(hash, sig)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrInvalidSignature, err)
+	}
+	return sig, pub, nil
+}
+
+// randomBytes returns n random bytes.
+func randomBytes(n int) []byte {
+	b := make([]byte, n)
+	if _, err := io.ReadFull(rand.Reader, b); err != nil {
+		panic(err)
+	}
+	return b
+}
+
+// randomTopic returns a random relay topic.
+func randomTopic() string {
+	return hex.EncodeToString(randomBytes(32))
+}
+
+// wcSessionKey derives the symmetric key of a session from the X25519 private
+// key of one party and the public key of the other, and returns it along with
+// the topic of the session, the SHA-256 of the key.
+func wcSessionKey(priv, peerPub []byte) ([]byte, string, error) {
+	shared, err := curve25519.X25519(priv, peerPub)
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid walletconnect public key: %w", err)
+	}
+	symKey := make([]byte, chacha20poly1305.KeySize)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, shared, nil, nil), symKey); err != nil {
+		return nil, "", err
+	}
+	topic := sha256.Sum256(symKey)
+	return symKey, hex.EncodeToString(topic[:]), nil
+}
+
+// wcEncrypt seals plaintext with symKey into a base64 encoded type 0 envelope,
+// the type byte followed by the nonce and the ChaCha20-Poly1305 ciphertext.
+func wcEncrypt(symKey, plaintext []byte) (string, error) {
+	aead, err := chacha20poly1305.New(symKey)
+	if err != nil {
+		return "", err
+	}
+	nonce := randomBytes(aead.NonceSize())
+	envelope := append([]byte{0}, nonce...)
+	return base64.StdEncoding.EncodeToString(aead.Seal(envelope, nonce, plaintext, nil)), nil
+}
+
+// wcDecrypt opens the type 0 envelope message sealed with symKey.
+func wcDecrypt(symKey []byte, message string) ([]byte, error) {
+	envelope, err := base64.StdEncoding.DecodeString(message)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := chacha20poly1305.New(symKey)
+	if err != nil {
+		return nil, err
+	}
+	if len(envelope) < 1+aead.NonceSize() || envelope[0] != 0 {
+		return nil, errors.New("unsupported walletconnect envelope")
+	}
+	nonce, sealed := envelope[1:1+aead.NonceSize()], envelope[1+aead.NonceSize():]
+	return aead.Open(nil, nonce, sealed, nil)
+}
+
+var (
+	// wcIDBase and wcIDCounter make up the IDs of JSON-RPC requests, starting
+	// at the time in microseconds to keep them unique across restarts.
+	wcIDBase    = uint64(time.Now().UnixMilli()) * 1000
+	wcIDCounter atomic.Uint64
+)
+
+// wcID returns a new JSON-RPC request ID.
+func wcID() uint64 {
+	return wcIDBase + wcIDCounter.Add(1)
+}
+
+// wcRequest is a JSON-RPC request, to the relay or within a session.
+type wcRequest struct {
+	ID      uint64          `json:"id"`
+	JSONRPC string          `json:"jsonrpc"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params"`
+}
+
+// wcResponse is a JSON-RPC response, to the relay or within a session.
+type wcResponse struct {
+	ID      uint64          `json:"id"`
+	JSONRPC string          `json:"jsonrpc"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *wcError        `json:"error,omitempty"`
+}
+
+// wcError is the error of a JSON-RPC response.
+type wcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *wcError) Error() string {
+	return fmt.Sprintf("walletconnect: %s (%d)", e.Message, e.Code)
+}
+
+// err returns the error of the response, marking rejections by the user with
+// ErrPermissionDenied.
+func (r *wcResponse) err() error {
+	switch {
+	case r.Error == nil:
+		return nil
+	// 4001 is the rejection code of EIP-1193, 5000 to 5002 the ones of
+	// WalletConnect.
+	case r.Error.Code == 4001 || (r.Error.Code >= 5000 && r.Error.Code <= 5002):
+		return fmt.Errorf("%w: %w", ErrPermissionDenied, r.Error)
+	}
+	return r.Error
+}
+
+// wcRelay is a connection to a WalletConnect relay, routing the messages of
+// subscribed topics to their handlers.
+type wcRelay struct {
+	conn      *websocket.Conn
+	writeLock sync.Mutex
+
+	lock     sync.Mutex
+	pending  map[uint64]chan wcResponse
+	handlers map[string]func(message string) // topic -> handler
+	failure  error                           // why the connection broke, nil while up
+}
+
+// dialWalletConnectRelay connects to the relay at relayURL, authenticated
+// with a JWT signed by clientKey.
+func dialWalletConnectRelay(ctx context.Context, relayURL, projectID string, clientKey ed25519.PrivateKey) (*wcRelay, error) {
+	now := time.Now()
+	token, err := jwt.NewWithClaims(jwt.SigningMethodEdDSA, jwt.MapClaims{
+		"iss": wcDIDKey(clientKey.Public().(ed25519.PublicKey)),
+		"sub": randomTopic(),
+		"aud": relayURL,
+		"iat": now.Unix(),
+		"exp": now.Add(walletConnectTokenLifetime).Unix(),
+	}).SignedString(clientKey)
+	if err != nil {
+		return nil, err
+	}
+	u, err := url.Parse(relayURL)
+	if err != nil {
+		return nil, err
+	}
+	query := u.Query()
+	query.Set("auth", token)
+	query.Set("projectId", projectID)
+	query.Set("ua", "wc-2/go-ethereum-keeper")
+	u.RawQuery = query.Encode()
+
+	conn, resp, err := websocket.DefaultDialer.DialContext(ctx, u.String(), nil)
+	if err != nil {
+		if resp != nil && (resp.StatusCode == 401 || resp.StatusCode == 403) {
+			return nil, fmt.Errorf("%w: walletconnect relay refused project %s", ErrPermissionDenied, projectID)
+		}
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, ctxErr
+		}
+		return nil, fmt.Errorf("%w: %w", ErrBackendUnavailable, err)
+	}
+	r := &wcRelay{
+		conn:     conn,
+		pending:  make(map[uint64]chan wcResponse),
+		handlers: make(map[string]func(string)),
+	}
+	go r.read()
+	return r, nil
+}
+
+// wcDIDKey returns the did:key identifier of the Ed25519 public key pub.
+func wcDIDKey(pub ed25519.PublicKey) string {
+	// 0xed 0x01 is the multicodec prefix of Ed25519 public keys, z the multibase
+	// prefix of base58.
+	return "did:key:z" + base58Encode(append([]byte{0xed, 0x01}, pub...))
+}
+
+// err returns why the connection broke, nil if it is up.
+func (r *wcRelay) err() error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.failure
+}
+
+// close closes the connection.
+func (r *wcRelay) close() {
+	r.conn.Close()
+}
+
+// read dispatches the messages of the relay until the connection breaks.
+func (r *wcRelay) read() {
+	for {
+		_, data, err := r.conn.ReadMessage()
+		if err != nil {
+			r.lock.Lock()
+			r.failure = fmt.Errorf("%w: walletconnect relay connection lost: %w", ErrBackendUnavailable, err)
+			for id, ch := range r.pending {
+				close(ch)
+				delete(r.pending, id)
+			}
+			r.lock.Unlock()
+			return
+		}
+		var msg struct {
+			wcResponse
+			Method string          `json:"method"`
+			Params json.RawMessage `json:"params"`
+		}
+		if json.Unmarshal(data, &msg) != nil {
+			continue
+		}
+		if msg.Method == "" {
+			r.lock.Lock()
+			ch, ok := r.pending[msg.ID]
+			delete(r.pending, msg.ID)
+			r.lock.Unlock()
+			if ok {
+				ch <- msg.wcResponse
+			}
+			continue
+		}
+		if msg.Method != "irn_subscription" {
+			continue
+		}
+		var sub struct {
+			Data struct {
+				Topic   string `json:"topic"`
+				Message string `json:"message"`
+			} `json:"data"`
+		}
+		if json.Unmarshal(msg.Params, &sub) != nil {
+			continue
+		}
+		// The relay delivers the message again until it is acknowledged.
+		r.write(wcResponse{ID: msg.ID, JSONRPC: "2.0", Result: json.RawMessage("true")})
+
+		r.lock.Lock()
+		handle := r.handlers[sub.Data.Topic]
+		r.lock.Unlock()
+		if handle != nil {
+			handle(sub.Data.Message)
+		}
+	}
+}
+
+// write sends v as a JSON message.
+func (r *wcRelay) write(v interface{}) error {
+	r.writeLock.Lock()
+	defer r.writeLock.Unlock()
+	return r.conn.WriteJSON(v)
+}
+
+// call sends the request method with params to the relay and decodes the
+// result into out, if not nil.
+func (r *wcRelay) call(ctx context.Context, method string, params, out interface{}) error {
+	raw, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	id := wcID()
+	ch := make(chan wcResponse, 1)
+
+	r.lock.Lock()
+	if r.failure != nil {
+		r.lock.Unlock()
+		return r.failure
+	}
+	r.pending[id] = ch
+	r.lock.Unlock()
+
+	if err := r.write(wcRequest{ID: id, JSONRPC: "2.0", Method: method, Params: raw}); err != nil {
+		r.lock.Lock()
+		delete(r.pending, id)
+		r.lock.Unlock()
+		return fmt.Errorf("%w: %w", ErrBackendUnavailable, err)
+	}
+	select {
+	case <-ctx.Done():
+		r.lock.Lock()
+		delete(r.pending, id)
+		r.lock.Unlock()
+		return ctx.Err()
+	case resp, ok := <-ch:
+		if !ok {
+			return r.err()
+		}
+		if resp.Error != nil {
+			return resp.Error
+		}
+		if out == nil {
+			return nil
+		}
+		return json.Unmarshal(resp.Result, out)
+	}
+}
+
+// subscribe subscribes to topic, passing its messages to handle. Handlers are
+// called in turn and must not block.
+func (r *wcRelay) subscribe(ctx context.Context, topic string, handle func(message string)) error {
+	r.lock.Lock()
+	r.handlers[topic] = handle
+	r.lock.Unlock()
+	return r.call(ctx, "irn_subscribe", map[string]string{"topic": topic}, nil)
+}
+
+// unsubscribe drops the messages of topic. The relay isn't told, it stops
+// delivering them when the messages expire.
+func (r *wcRelay) unsubscribe(topic string) {
+	r.lock.Lock()
+	delete(r.handlers, topic)
+	r.lock.Unlock()
+}
+
+// publish publishes the encrypted message on topic.
+func (r *wcRelay) publish(ctx context.Context, topic, message string, tag int, prompt bool) error {
+	return r.call(ctx, "irn_publish", map[string]interface{}{
+		"topic":   topic,
+		"message": message,
+		"ttl":     int(walletConnectTTL / time.Second),
+		"tag":     tag,
+		"prompt":  prompt,
+	}, nil)
+}
+
+// wcPeer is the JSON-RPC channel with the other party on a topic, encrypted
+// with its symmetric key.
+type wcPeer struct {
+	topic  string
+	symKey []byte
+
+	lock     sync.Mutex
+	relay    *wcRelay // replaced when the keeper reconnects
+	pending  map[uint64]chan wcResponse
+	requests chan wcRequest // requests of the other party, dropped if not read
+}
+
+func newWCPeer(relay *wcRelay, topic string, symKey []byte) (*wcPeer, error) {
+	if len(symKey) != chacha20poly1305.KeySize {
+		return nil, errors.New("invalid walletconnect symmetric key")
+	}
+	return &wcPeer{
+		relay:    relay,
+		topic:    topic,
+		symKey:   symKey,
+		pending:  make(map[uint64]chan wcResponse),
+		requests: make(chan wcRequest, 16),
+	}, nil
+}
+
+// handle decrypts a message of the topic and routes it.
+func (p *wcPeer) handle(message string) {
+	plaintext, err := wcDecrypt(p.symKey, message)
+	if err != nil {
+		return
+	}
+	var msg struct {
+		wcResponse
+		Method string          `json:"method"`
+		Params json.RawMessage `json:"params"`
+	}
+	if json.Unmarshal(plaintext, &msg) != nil {
+		return
+	}
+	if msg.Method != "" {
+		select {
+		case p.requests <- wcRequest{ID: msg.ID, JSONRPC: msg.JSONRPC, Method: msg.Method, Params: msg.Params}:
+		default:
+		}
+		return
+	}
+	p.lock.Lock()
+	ch, ok := p.pending[msg.ID]
+	delete(p.pending, msg.ID)
+	p.lock.Unlock()
+	if ok {
+		ch <- msg.wcResponse
+	}
+}
+
+// send publishes v encrypted on the topic.
+func (p *wcPeer) send(ctx context.Context, v interface{}, tag int, prompt bool) error {
+	plaintext, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	message, err := wcEncrypt(p.symKey, plaintext)
+	if err != nil {
+		return err
+	}
+	p.lock.Lock()
+	relay := p.relay
+	p.lock.Unlock()
+	return relay.publish(ctx, p.topic, message, tag, prompt)
+}
+
+// setRelay makes the peer publish through relay.
+func (p *wcPeer) setRelay(relay *wcRelay) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.relay = relay
+}
+
+// unsubscribe drops the messages of the topic.
+func (p *wcPeer) unsubscribe() {
+	p.lock.Lock()
+	relay := p.relay
+	p.lock.Unlock()
+	relay.unsubscribe(p.topic)
+}
+
+// call sends the request method with params to the other party and waits for
+// its response, decoding the result into out.
+func (p *wcPeer) call(ctx context.Context, method string, params interface{}, tag int, out interface{}) error {
+	raw, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	id := wcID()
+	ch := make(chan wcResponse, 1)
+
+	p.lock.Lock()
+	p.pending[id] = ch
+	p.lock.Unlock()
+	defer func() {
+		p.lock.Lock()
+		delete(p.pending, id)
+		p.lock.Unlock()
+	}()
+
+	if err := p.send(ctx, wcRequest{ID: id, JSONRPC: "2.0", Method: method, Params: raw}, tag, true); err != nil {
+		return err
+	}
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case resp := <-ch:
+		if err := resp.err(); err != nil {
+			return err
+		}
+		if err := json.Unmarshal(resp.Result, out); err != nil {
+			return fmt.Errorf("invalid walletconnect response: %w", err)
+		}
+		return nil
+	}
+}
+
+// notify sends the request method with params without waiting for the
+// response.
+func (p *wcPeer) notify(ctx context.Context, method string, params interface{}, tag int) error {
+	raw, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	return p.send(ctx, wcRequest{ID: wcID(), JSONRPC: "2.0", Method: method, Params: raw}, tag, false)
+}
+
+// respond answers the request id of the other party with result.
+func (p *wcPeer) respond(ctx context.Context, id uint64, result interface{}, tag int) error {
+	raw, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	return p.send(ctx, wcResponse{ID: id, JSONRPC: "2.0", Result: raw}, tag, false)
+}
@@ -0,0 +1,383 @@
+package keeper
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/gorilla/websocket"
+	"golang.org/x/crypto/curve25519"
+)
+
+// fakeRelay is a WalletConnect relay routing the messages published on a topic
+// to the other connections subscribed to it, including the ones subscribing
+// later.
+type fakeRelay struct {
+	t        *testing.T
+	upgrader websocket.Upgrader
+
+	lock     sync.Mutex
+	subs     map[string][]*fakeRelayConn // topic -> subscribers
+	messages map[string][]fakeRelayMessage
+}
+
+type fakeRelayConn struct {
+	conn *websocket.Conn
+	lock sync.Mutex
+}
+
+type fakeRelayMessage struct {
+	from    *fakeRelayConn
+	message string
+	tag     int
+}
+
+func newFakeRelay(t *testing.T) *httptest.Server {
+	relay := &fakeRelay{t: t, subs: make(map[string][]*fakeRelayConn), messages: make(map[string][]fakeRelayMessage)}
+	srv := httptest.NewServer(relay)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func (r *fakeRelay) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.URL.Query().Get("projectId") != "test-project" {
+		http.Error(w, "unknown project", http.StatusUnauthorized)
+		return
+	}
+	token, _, err := new(jwt.Parser).ParseUnverified(req.URL.Query().Get("auth"), jwt.MapClaims{})
+	if err != nil || token.Method != jwt.SigningMethodEdDSA || !strings.HasPrefix(token.Claims.(jwt.MapClaims)["iss"].(string), "did:key:z6Mk") {
+		http.Error(w, "invalid auth", http.StatusUnauthorized)
+		return
+	}
+	ws, err := r.upgrader.Upgrade(w, req, nil)
+	if err != nil {
+		return
+	}
+	conn := &fakeRelayConn{conn: ws}
+	defer ws.Close()
+	for {
+		var msg wcRequest
+		if err := ws.ReadJSON(&msg); err != nil {
+			return
+		}
+		switch msg.Method {
+		case "irn_subscribe":
+			var params struct{ Topic string }
+			json.Unmarshal(msg.Params, &params)
+			r.lock.Lock()
+			r.subs[params.Topic] = append(r.subs[params.Topic], conn)
+			stored := append([]fakeRelayMessage{}, r.messages[params.Topic]...)
+			r.lock.Unlock()
+			conn.write(wcResponse{ID: msg.ID, JSONRPC: "2.0", Result: json.RawMessage(`"sub"`)})
+			for _, m := range stored {
+				if m.from != conn {
+					conn.deliver(params.Topic, m)
+				}
+			}
+		case "irn_publish":
+			var params struct {
+				Topic   string
+				Message string
+				Tag     int
+			}
+			json.Unmarshal(msg.Params, &params)
+			m := fakeRelayMessage{from: conn, message: params.Message, tag: params.Tag}
+			r.lock.Lock()
+			r.messages[params.Topic] = append(r.messages[params.Topic], m)
+			subs := append([]*fakeRelayConn{}, r.subs[params.Topic]...)
+			r.lock.Unlock()
+			conn.write(wcResponse{ID: msg.ID, JSONRPC: "2.0", Result: json.RawMessage("true")})
+			for _, sub := range subs {
+				if sub != conn {
+					sub.deliver(params.Topic, m)
+				}
+			}
+		}
+	}
+}
+
+func (c *fakeRelayConn) write(v interface{}) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.conn.WriteJSON(v)
+}
+
+func (c *fakeRelayConn) deliver(topic string, m fakeRelayMessage) {
+	params, _ := json.Marshal(map[string]interface{}{
+		"id":   "sub",
+		"data": map[string]interface{}{"topic": topic, "message": m.message, "publishedAt": time.Now().UnixMilli(), "tag": m.tag},
+	})
+	c.write(wcRequest{ID: wcID(), JSONRPC: "2.0", Method: "irn_subscription", Params: params})
+}
+
+// Behaviours of the fake wallet when asked to sign.
+const (
+	walletApprove = iota
+	walletReject
+	walletPrefix // applies the EIP-191 prefix to eth_sign
+)
+
+// fakeWallet is a wallet app pairing over the relay and signing with key.
+type fakeWallet struct {
+	t        *testing.T
+	relayURL string
+	key      []byte
+	mode     atomic.Int32
+	deleted  chan struct{}
+}
+
+// pair connects the wallet to the app of the pairing uri and then serves the
+// requests of the session.
+func (w *fakeWallet) pair(uri string) {
+	ctx := context.Background()
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "wc" || u.Query().Get("relay-protocol") != "irn" {
+		w.t.Errorf("invalid pairing uri %q", uri)
+		return
+	}
+	topic, version, _ := strings.Cut(u.Opaque, "@")
+	symKey, err := hex.DecodeString(u.Query().Get("symKey"))
+	if err != nil || version != "2" {
+		w.t.Errorf("invalid pairing uri %q", uri)
+		return
+	}
+	_, clientKey, _ := ed25519.GenerateKey(rand.Reader)
+	relay, err := dialWalletConnectRelay(ctx, w.relayURL, "test-project", clientKey)
+	if err != nil {
+		w.t.Errorf("failed to connect wallet: %v", err)
+		return
+	}
+	pairing, _ := newWCPeer(relay, topic, symKey)
+	if err := relay.subscribe(ctx, topic, pairing.handle); err != nil {
+		w.t.Errorf("failed to subscribe pairing: %v", err)
+		return
+	}
+	propose := <-pairing.requests
+	var proposal struct {
+		Proposer struct {
+			PublicKey string `json:"publicKey"`
+		} `json:"proposer"`
+	}
+	json.Unmarshal(propose.Params, &proposal)
+	proposerPub, _ := hex.DecodeString(proposal.Proposer.PublicKey)
+
+	responderKey := randomBytes(curve25519.ScalarSize)
+	responderPub, _ := curve25519.X25519(responderKey, curve25519.Basepoint)
+	sessionKey, sessionTopic, err := wcSessionKey(responderKey, proposerPub)
+	if err != nil {
+		w.t.Errorf("failed to derive session key: %v", err)
+		return
+	}
+	session, _ := newWCPeer(relay, sessionTopic, sessionKey)
+	relay.subscribe(ctx, sessionTopic, session.handle)
+	pairing.respond(ctx, propose.ID, map[string]interface{}{
+		"relay":              map[string]string{"protocol": "irn"},
+		"responderPublicKey": hex.EncodeToString(responderPub),
+	}, wcTagSessionProposeResponse)
+
+	addr, _ := AddressFromKeeper(defaultKeeper, w.key)
+	var settled bool
+	if err := session.call(ctx, "wc_sessionSettle", map[string]interface{}{
+		"relay": map[string]string{"protocol": "irn"},
+		"namespaces": map[string]interface{}{
+			"eip155": map[string]interface{}{
+				"accounts": []string{"eip155:5:0x0000000000000000000000000000000000000001", "eip155:1:" + addr.Hex()},
+				"methods":  []string{"eth_sign", "personal_sign"},
+				"events":   []string{},
+			},
+		},
+	}, wcTagSessionSettle, &settled); err != nil || !settled {
+		w.t.Errorf("session not settled: %v", err)
+		return
+	}
+	for req := range session.requests {
+		switch req.Method {
+		case "wc_sessionDelete":
+			close(w.deleted)
+			return
+		case "wc_sessionRequest":
+			w.sign(ctx, session, req)
+		}
+	}
+}
+
+// sign answers the signing request req.
+func (w *fakeWallet) sign(ctx context.Context, session *wcPeer, req wcRequest) {
+	var params struct {
+		Request struct {
+			Method string   `json:"method"`
+			Params []string `json:"params"`
+		} `json:"request"`
+		ChainID string `json:"chainId"`
+	}
+	json.Unmarshal(req.Params, &params)
+	if w.mode.Load() == walletReject {
+		session.send(ctx, wcResponse{ID: req.ID, JSONRPC: "2.0", Error: &wcError{Code: 5000, Message: "User rejected."}}, wcTagSessionRequestResponse, false)
+		return
+	}
+	var hash []byte
+	switch params.Request.Method {
+	case "personal_sign":
+		message, _ := hexutil.Decode(params.Request.Params[0])
+		hash = accounts.TextHash(message)
+	case "eth_sign":
+		hash, _ = hexutil.Decode(params.Request.Params[1])
+		if w.mode.Load() == walletPrefix {
+			hash = accounts.TextHash(hash)
+		}
+	}
+	sig, err := defaultKeeper.Sign(hash, w.key)
+	if err != nil {
+		w.t.Errorf("wallet failed to sign: %v", err)
+		return
+	}
+	sig[crypto.RecoveryIDOffset] += 27
+	session.respond(ctx, req.ID, hexutil.Encode(sig), wcTagSessionRequestResponse)
+}
+
+func TestWalletConnectKeeper(t *testing.T) {
+	srv := newFakeRelay(t)
+	relayURL := "ws" + strings.TrimPrefix(srv.URL, "http")
+	key, _ := GenerateDeterministicKey([]byte("wallet"))
+	wallet := &fakeWallet{t: t, relayURL: relayURL, key: key, deleted: make(chan struct{})}
+
+	k, err := NewWalletConnectKeeper("test-project", relayURL, WithPairingHandler(func(uri string) { go wallet.pair(uri) }))
+	if err != nil {
+		t.Fatalf("failed to create keeper: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	kc := k.(PrivateKeyKeeperContext)
+	prvID, err := kc.GeneratePrivateKeyContext(ctx)
+	if err != nil {
+		t.Fatalf("failed to pair: %v", err)
+	}
+	addr, _ := AddressFromKeeper(defaultKeeper, key)
+	if string(prvID) != addr.Hex() {
+		t.Fatalf("prvID mismatch: have %s, want %s", prvID, addr.Hex())
+	}
+	pub, err := k.GetPublicKey(prvID)
+	if err != nil {
+		t.Fatalf("failed to get public key: %v", err)
+	}
+	if want, _ := defaultKeeper.GetPublicKey(key); !bytes.Equal(pub, want) {
+		t.Fatalf("public key mismatch: have %x, want %x", pub, want)
+	}
+	// Transactions are signed by the wallet.
+	signer := types.LatestSignerForChainID(big.NewInt(1))
+	tx, err := NewSecureSigner(k).Sign(newTestTx(), signer, prvID)
+	if err != nil {
+		t.Fatalf("failed to sign transaction: %v", err)
+	}
+	if from, err := types.Sender(signer, tx); err != nil || from != addr {
+		t.Fatalf("sender mismatch: have (%v, %v), want %v", from, err, addr)
+	}
+	if prvIDs, err := k.ListPrivateKeys(); err != nil || !reflect.DeepEqual(prvIDs, [][]byte{prvID}) {
+		t.Fatalf("listed keys mismatch: have (%q, %v), want %q", prvIDs, err, prvID)
+	}
+
+	hash := crypto.Keccak256([]byte("payload"))
+	wallet.mode.Store(walletReject)
+	if _, err := kc.SignContext(ctx, hash, prvID); !errors.Is(err, ErrPermissionDenied) {
+		t.Fatalf("sign rejected in wallet: have %v, want %v", err, ErrPermissionDenied)
+	}
+	wallet.mode.Store(walletPrefix)
+	if _, err := kc.SignContext(ctx, hash, prvID); !errors.Is(err, ErrInvalidSignature) {
+		t.Fatalf("sign of prefixed hash: have %v, want %v", err, ErrInvalidSignature)
+	}
+	wallet.mode.Store(walletApprove)
+	if _, err := kc.SignContext(ctx, hash[:31], prvID); err == nil {
+		t.Fatal("signed message of 31 bytes")
+	}
+
+	if err := k.DeletePrivateKey(prvID); err != nil {
+		t.Fatalf("failed to disconnect: %v", err)
+	}
+	select {
+	case <-wallet.deleted:
+	case <-ctx.Done():
+		t.Fatal("wallet not told about the disconnect")
+	}
+	if _, err := k.Sign(hash, prvID); !errors.Is(err, ErrKeyNotFound) {
+		t.Fatalf("sign after disconnect: have %v, want %v", err, ErrKeyNotFound)
+	}
+	if _, err := k.ImportPrivateKey(key); !errors.Is(err, ErrNotSupported) {
+		t.Fatalf("import key: have %v, want %v", err, ErrNotSupported)
+	}
+}
+
+func TestWalletConnectKeeperTimeout(t *testing.T) {
+	srv := newFakeRelay(t)
+	var uris []string
+	k, err := NewWalletConnectKeeper("test-project", "ws"+strings.TrimPrefix(srv.URL, "http"), WithPairingHandler(func(uri string) { uris = append(uris, uri) }))
+	if err != nil {
+		t.Fatalf("failed to create keeper: %v", err)
+	}
+	// Nobody scans the pairing URI.
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	if _, err := k.(PrivateKeyKeeperContext).GeneratePrivateKeyContext(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("unanswered pairing: have %v, want %v", err, context.DeadlineExceeded)
+	}
+	if len(uris) != 1 || !strings.HasPrefix(uris[0], "wc:") {
+		t.Fatalf("pairing uris mismatch: %q", uris)
+	}
+
+	k, _ = NewWalletConnectKeeper("test-project", "ws"+strings.TrimPrefix(srv.URL, "http"))
+	if _, err := k.GeneratePrivateKey(); !errors.Is(err, ErrNotSupported) {
+		t.Fatalf("pairing without handler: have %v, want %v", err, ErrNotSupported)
+	}
+	k, _ = NewWalletConnectKeeper("other-project", "ws"+strings.TrimPrefix(srv.URL, "http"), WithPairingHandler(func(string) {}))
+	if _, err := k.GeneratePrivateKey(); !errors.Is(err, ErrPermissionDenied) {
+		t.Fatalf("pairing of unknown project: have %v, want %v", err, ErrPermissionDenied)
+	}
+}
+
+func TestWalletConnectCrypto(t *testing.T) {
+	a, b := randomBytes(curve25519.ScalarSize), randomBytes(curve25519.ScalarSize)
+	aPub, _ := curve25519.X25519(a, curve25519.Basepoint)
+	bPub, _ := curve25519.X25519(b, curve25519.Basepoint)
+	keyA, topicA, err := wcSessionKey(a, bPub)
+	if err != nil {
+		t.Fatalf("failed to derive key: %v", err)
+	}
+	keyB, topicB, _ := wcSessionKey(b, aPub)
+	if !bytes.Equal(keyA, keyB) || topicA != topicB {
+		t.Fatalf("parties derived different sessions: %x/%s and %x/%s", keyA, topicA, keyB, topicB)
+	}
+	message, err := wcEncrypt(keyA, []byte(`{"id":1}`))
+	if err != nil {
+		t.Fatalf("failed to encrypt: %v", err)
+	}
+	if plaintext, err := wcDecrypt(keyB, message); err != nil || string(plaintext) != `{"id":1}` {
+		t.Fatalf("decrypted (%q, %v)", plaintext, err)
+	}
+	if _, err := wcDecrypt(randomBytes(32), message); err == nil {
+		t.Fatal("decrypted with another key")
+	}
+
+	pub := ed25519.PublicKey(make([]byte, ed25519.PublicKeySize))
+	if did := wcDIDKey(pub); !strings.HasPrefix(did, "did:key:z6Mk") {
+		t.Fatalf("did %s isn't an Ed25519 did:key", did)
+	}
+}
@@ -0,0 +1,122 @@
+package keeper
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// WIF version bytes of Bitcoin mainnet and testnet keys.
+const (
+	wifMainnet = 0x80
+	wifTestnet = 0xef
+)
+
+// base58Alphabet is the Bitcoin base58 alphabet.
+const base58Alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+
+// ImportWIF decodes a private key in the Wallet Import Format and returns its
+// prvID in the default keeper, the raw key itself. Mainnet and testnet keys,
+// with or without the compressed public key flag, are accepted. The flag only
+// tells Bitcoin wallets how to encode the public key, it doesn't change the
+// key. Malformed keys and bad checksums fail with ErrInvalidKey.
+func ImportWIF(wif string) (_ []byte, err error) {
+	defer wrapError(&err, "import key", nil)
+
+	payload, err := base58CheckDecode(wif)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidKey, err)
+	}
+	defer zeroBytes(payload)
+
+	switch {
+	case len(payload) == 34 && payload[33] == 0x01:
+	case len(payload) == 33:
+	default:
+		return nil, fmt.Errorf("%w: invalid WIF length %d", ErrInvalidKey, len(payload))
+	}
+	if payload[0] != wifMainnet && payload[0] != wifTestnet {
+		return nil, fmt.Errorf("%w: unknown WIF version %#x", ErrInvalidKey, payload[0])
+	}
+	return defaultKeeper.ImportPrivateKey(payload[1:33])
+}
+
+// ExportWIF returns the key prvID of the default keeper, the raw key, in the
+// Wallet Import Format of mainnet. The key is flagged for uncompressed public
+// keys, the form Ethereum derives addresses from.
+func ExportWIF(prvID []byte) (_ string, err error) {
+	defer wrapError(&err, "export key", prvID)
+
+	key, err := parseRawKey(prvID)
+	if err != nil {
+		return "", err
+	}
+	ZeroKey(key)
+
+	payload := append([]byte{wifMainnet}, prvID...)
+	defer zeroBytes(payload)
+	return base58CheckEncode(payload), nil
+}
+
+// base58CheckEncode encodes payload followed by its checksum, the first four
+// bytes of its double SHA-256, in base58.
+func base58CheckEncode(payload []byte) string {
+	data := append(bytes.Clone(payload), base58Checksum(payload)...)
+	defer zeroBytes(data)
+	return base58Encode(data)
+}
+
+// base58Encode encodes data in base58.
+func base58Encode(data []byte) string {
+	var out []byte
+	for n, rem := new(big.Int).SetBytes(data), new(big.Int); n.Sign() > 0; {
+		n.DivMod(n, big.NewInt(58), rem)
+		out = append(out, base58Alphabet[rem.Int64()])
+	}
+	// Leading zero bytes are kept as leading ones.
+	for _, b := range data {
+		if b != 0 {
+			break
+		}
+		out = append(out, base58Alphabet[0])
+	}
+	for i, j := 0, len(out)-1; i < j; i, j = i+1, j-1 {
+		out[i], out[j] = out[j], out[i]
+	}
+	return string(out)
+}
+
+// base58CheckDecode decodes a base58 string and verifies the checksum at its
+// end, returning the payload before it.
+func base58CheckDecode(s string) ([]byte, error) {
+	n := new(big.Int)
+	for _, c := range []byte(s) {
+		digit := strings.IndexByte(base58Alphabet, c)
+		if digit < 0 {
+			return nil, fmt.Errorf("invalid base58 character %q", c)
+		}
+		n.Mul(n, big.NewInt(58))
+		n.Add(n, big.NewInt(int64(digit)))
+	}
+	zeros := len(s) - len(strings.TrimLeft(s, base58Alphabet[:1]))
+	data := append(make([]byte, zeros), n.Bytes()...)
+	if len(data) < 5 {
+		return nil, errors.New("base58check string too short")
+	}
+	payload, checksum := data[:len(data)-4], data[len(data)-4:]
+	if !bytes.Equal(checksum, base58Checksum(payload)) {
+		zeroBytes(data)
+		return nil, errors.New("invalid base58check checksum")
+	}
+	return payload, nil
+}
+
+// base58Checksum returns the first four bytes of the double SHA-256 of data.
+func base58Checksum(data []byte) []byte {
+	first := sha256.Sum256(data)
+	second := sha256.Sum256(first[:])
+	return second[:4]
+}
@@ -0,0 +1,78 @@
+package keeper
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// wifKey is the key of the Wallet Import Format example of the Bitcoin wiki.
+var wifKey = hexutil.MustDecode("0x0c28fca386c7a227600b2fe50b7cae11ec86d3bf1fbe471be89827e19d72aa1d")
+
+func TestImportWIF(t *testing.T) {
+	tests := []string{
+		"5HueCGU8rMjxEXxiPuD5BDku4MkFqeZyd4dZ1jvhTVqvbTLvyTJ",  // mainnet, uncompressed
+		"KwdMAjGmerYanjeui5SHS7JkmpZvVipYvB2LJGU1ZxJwYvP98617", // mainnet, compressed
+		"91gGn1HgSap6CbU12F6z3pJri26xzp7Ay1VW6NHCoEayNXwRpu2",  // testnet, uncompressed
+		"cMzLdeGd5vEqxB8B6VFQoRopQ3sLAAvEzDAoQgvX54xwofSWj1fx", // testnet, compressed
+	}
+	key, _ := crypto.ToECDSA(wifKey)
+	want := crypto.FromECDSAPub(&key.PublicKey)
+	for _, wif := range tests {
+		prvID, err := ImportWIF(wif)
+		if err != nil {
+			t.Fatalf("%s: failed to import: %v", wif, err)
+		}
+		if !bytes.Equal(prvID, wifKey) {
+			t.Fatalf("%s: have %x, want %x", wif, prvID, wifKey)
+		}
+		if pub, err := defaultKeeper.GetPublicKey(prvID); err != nil || !bytes.Equal(pub, want) {
+			t.Fatalf("%s: public key mismatch: have (%x, %v), want %x", wif, pub, err, want)
+		}
+	}
+	if wif, err := ExportWIF(wifKey); err != nil || wif != tests[0] {
+		t.Fatalf("export: have (%s, %v), want %s", wif, err, tests[0])
+	}
+	prvID, _ := defaultKeeper.GeneratePrivateKey()
+	wif, err := ExportWIF(prvID)
+	if err != nil {
+		t.Fatalf("failed to export generated key: %v", err)
+	}
+	if imported, err := ImportWIF(wif); err != nil || !bytes.Equal(imported, prvID) {
+		t.Fatalf("round trip: have (%x, %v), want %x", imported, err, prvID)
+	}
+}
+
+func TestImportWIFInvalid(t *testing.T) {
+	tests := map[string]string{
+		"checksum":  "5HueCGU8rMjxEXxiPuD5BDku4MkFqeZyd4dZ1jvhTVqvbTLvyTK",
+		"character": "5HueCGU8rMjxEXxiPuD5BDku4MkFqeZyd4dZ1jvhTVqvbTLvyT0",
+		"empty":     "",
+		// Valid base58check payloads that aren't keys.
+		"version": base58CheckEncode(append([]byte{0x00}, wifKey...)),
+		"flag":    base58CheckEncode(append(append([]byte{wifMainnet}, wifKey...), 0x02)),
+		"short":   base58CheckEncode(append([]byte{wifMainnet}, wifKey[:31]...)),
+		"zero":    base58CheckEncode(append([]byte{wifMainnet}, make([]byte, 32)...)),
+	}
+	for name, wif := range tests {
+		if _, err := ImportWIF(wif); !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("%s: have %v, want %v", name, err, ErrInvalidKey)
+		}
+	}
+	if _, err := ExportWIF(wifKey[:31]); !errors.Is(err, ErrInvalidKey) {
+		t.Fatalf("export of short key: have %v, want %v", err, ErrInvalidKey)
+	}
+}
+
+func TestBase58Check(t *testing.T) {
+	// Leading zero bytes map to leading ones.
+	for _, payload := range [][]byte{{0}, {0, 0, 1}, {1, 2, 3}, bytes.Repeat([]byte{0xff}, 40)} {
+		s := base58CheckEncode(payload)
+		if decoded, err := base58CheckDecode(s); err != nil || !bytes.Equal(decoded, payload) {
+			t.Fatalf("%x: have (%x, %v) from %s", payload, decoded, err, s)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package keeper
+
+import (
+	"crypto/ecdsa"
+	"math/big"
+	"runtime"
+)
+
+// zeroBytes overwrites b with zeros, in the spirit of SecureZeroMemory. The
+// KeepAlive keeps the compiler from dropping the writes to a slice that is
+// never read again.
+func zeroBytes(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+	runtime.KeepAlive(b)
+}
+
+// ZeroKey overwrites the private scalar and the public point of prv with zeros,
+// for callers done with a decoded key. The garbage collector may have copied
+// the key before, and the math library makes copies while computing with it,
+// those are out of reach: zeroing is a best effort that narrows the window a
+// memory dump or swapped page could reveal the key in.
+func ZeroKey(prv *ecdsa.PrivateKey) {
+	if prv == nil {
+		return
+	}
+	for _, n := range []*big.Int{prv.D, prv.PublicKey.X, prv.PublicKey.Y} {
+		if n == nil {
+			continue
+		}
+		words := n.Bits()
+		for i := range words {
+			words[i] = 0
+		}
+		n.SetInt64(0)
+		runtime.KeepAlive(words)
+	}
+}
+
+// SecureBytes holds sensitive bytes, such as a raw private key, and zeroes them
+// when wiped or, failing that, when the SecureBytes is garbage collected. The
+// finaliser is a safety net, it runs at the discretion of the collector, if at
+// all: call Wipe as soon as the bytes are not needed anymore.
+type SecureBytes struct {
+	b []byte
+}
+
+// NewSecureBytes returns a SecureBytes holding b, without copying it. The
+// caller must not keep b beyond the lifetime of the SecureBytes.
+func NewSecureBytes(b []byte) *SecureBytes {
+	s := &SecureBytes{b: b}
+	runtime.SetFinalizer(s, (*SecureBytes).Wipe)
+	return s
+}
+
+// Bytes returns the held bytes, all zero after Wipe.
+func (s *SecureBytes) Bytes() []byte {
+	return s.b
+}
+
+// Wipe zeroes the held bytes.
+func (s *SecureBytes) Wipe() {
+	zeroBytes(s.b)
+}
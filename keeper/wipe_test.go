@@ -0,0 +1,57 @@
+package keeper
+
+import (
+	"bytes"
+	"math/big"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestZeroKey(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	d, x, y := key.D.Bits(), key.PublicKey.X.Bits(), key.PublicKey.Y.Bits()
+	ZeroKey(key)
+
+	// The words backing the numbers are checked, not just their values.
+	for _, words := range [][]big.Word{d, x, y} {
+		for _, w := range words {
+			if w != 0 {
+				t.Fatal("key material left in memory")
+			}
+		}
+	}
+	if key.D.Sign() != 0 || key.PublicKey.X.Sign() != 0 || key.PublicKey.Y.Sign() != 0 {
+		t.Fatal("key not zeroed")
+	}
+	// Zeroing twice, or keys without numbers, is harmless.
+	ZeroKey(key)
+	ZeroKey(nil)
+}
+
+func TestSecureBytes(t *testing.T) {
+	b := []byte{1, 2, 3, 4}
+	s := NewSecureBytes(b)
+	if !bytes.Equal(s.Bytes(), []byte{1, 2, 3, 4}) {
+		t.Fatalf("bytes: have %x, want 01020304", s.Bytes())
+	}
+	s.Wipe()
+	if !bytes.Equal(b, make([]byte, 4)) {
+		t.Fatalf("wiped bytes: have %x, want zeros", b)
+	}
+
+	// The finaliser wipes bytes that were never wiped. The collector decides
+	// when, or whether, finalisers run, so this is a best effort check.
+	b = []byte{1, 2, 3, 4}
+	NewSecureBytes(b)
+	for range 20 {
+		runtime.GC()
+		if bytes.Equal(b, make([]byte, 4)) {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Skip("finaliser didn't run")
+}
@@ -0,0 +1,121 @@
+package keeper
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/subtle"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// WrappableKeeper is a PrivateKeyKeeper exporting its keys wrapped with a key
+// encryption key (KEK), as HSMs do, so the plain key never leaves the keeper:
+//
+//	wrapped, err := k.WrapKey(prvID, kekID)
+//	// ... store wrapped as backup ...
+//	prvID, err = k.UnwrapKey(wrapped, kekID)
+type WrappableKeeper interface {
+	PrivateKeyKeeper
+
+	// WrapKey returns the key prvID wrapped with the KEK kekID.
+	WrapKey(prvID []byte, kekID []byte) (wrappedKey []byte, err error)
+	// UnwrapKey unwraps a key wrapped with WrapKey and stores it in the
+	// keeper, failing with ErrPermissionDenied if it wasn't wrapped with the
+	// KEK kekID.
+	UnwrapKey(wrappedKey []byte, kekID []byte) (prvID []byte, err error)
+}
+
+// aesKeyWrapIV is the default initial value of RFC 3394.
+var aesKeyWrapIV = [8]byte{0xa6, 0xa6, 0xa6, 0xa6, 0xa6, 0xa6, 0xa6, 0xa6}
+
+// kekCipher returns the AES-256 cipher of the KEK kek.
+func kekCipher(kek []byte) (cipher.Block, error) {
+	if len(kek) != 32 {
+		return nil, fmt.Errorf("invalid key encryption key length %d, want 32", len(kek))
+	}
+	return aes.NewCipher(kek)
+}
+
+// aesKeyWrap implements the AES key wrap algorithm of RFC 3394, wrapping
+// plaintext of at least 16 bytes and a multiple of 8 long.
+func aesKeyWrap(kek, plaintext []byte) ([]byte, error) {
+	block, err := kekCipher(kek)
+	if err != nil {
+		return nil, err
+	}
+	if len(plaintext) < 16 || len(plaintext)%8 != 0 {
+		return nil, fmt.Errorf("invalid key length %d for key wrap", len(plaintext))
+	}
+	return aesWrapBlocks(block, aesKeyWrapIV, plaintext), nil
+}
+
+// aesKeyUnwrap reverses aesKeyWrap. It fails with ErrPermissionDenied if the
+// integrity check fails, i.e. ciphertext was wrapped with another KEK or was
+// tampered with.
+func aesKeyUnwrap(kek, ciphertext []byte) ([]byte, error) {
+	block, err := kekCipher(kek)
+	if err != nil {
+		return nil, err
+	}
+	if len(ciphertext) < 24 || len(ciphertext)%8 != 0 {
+		return nil, errors.New("invalid wrapped key length")
+	}
+	a, out := aesUnwrapBlocks(block, ciphertext)
+	if subtle.ConstantTimeCompare(a[:], aesKeyWrapIV[:]) != 1 {
+		zeroBytes(out)
+		return nil, fmt.Errorf("%w: key wrap integrity check failed", ErrPermissionDenied)
+	}
+	return out, nil
+}
+
+// aesWrapBlocks runs the wrapping process of RFC 3394 over the 64-bit blocks
+// of plaintext, starting with the initial value iv.
+func aesWrapBlocks(block cipher.Block, iv [8]byte, plaintext []byte) []byte {
+	n := len(plaintext) / 8
+	out := make([]byte, 8+len(plaintext))
+	copy(out[8:], plaintext)
+
+	var (
+		a   = iv
+		buf [16]byte
+	)
+	for j := 0; j < 6; j++ {
+		for i := 1; i <= n; i++ {
+			copy(buf[:8], a[:])
+			copy(buf[8:], out[8*i:8*i+8])
+			block.Encrypt(buf[:], buf[:])
+
+			t := uint64(n*j + i)
+			binary.BigEndian.PutUint64(a[:], binary.BigEndian.Uint64(buf[:8])^t)
+			copy(out[8*i:], buf[8:])
+		}
+	}
+	copy(out[:8], a[:])
+	return out
+}
+
+// aesUnwrapBlocks runs the unwrapping process of RFC 3394 over ciphertext,
+// returning the recovered initial value and plaintext.
+func aesUnwrapBlocks(block cipher.Block, ciphertext []byte) ([8]byte, []byte) {
+	n := len(ciphertext)/8 - 1
+	out := make([]byte, 8*n)
+
+	var (
+		a   [8]byte
+		buf [16]byte
+	)
+	copy(a[:], ciphertext[:8])
+	copy(out, ciphertext[8:])
+	for j := 5; j >= 0; j-- {
+		for i := n; i >= 1; i-- {
+			t := uint64(n*j + i)
+			binary.BigEndian.PutUint64(buf[:8], binary.BigEndian.Uint64(a[:])^t)
+			copy(buf[8:], out[8*(i-1):8*i])
+			block.Decrypt(buf[:], buf[:])
+			copy(a[:], buf[:8])
+			copy(out[8*(i-1):], buf[8:])
+		}
+	}
+	return a, out
+}
@@ -0,0 +1,64 @@
+package keeper
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"testing"
+)
+
+// TestAESKeyWrap checks the key wrap against the 256 bit KEK and key vector of
+// RFC 3394, section 4.6.
+func TestAESKeyWrap(t *testing.T) {
+	kek, _ := hex.DecodeString("000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f")
+	key, _ := hex.DecodeString("00112233445566778899aabbccddeeff000102030405060708090a0b0c0d0e0f")
+	want := "28c9f404c4b810f4cbccb35cfb87f8263f5786e2d80ed326cbc7f0e71a99f43bfb988b9b7a02dd21"
+
+	wrapped, err := aesKeyWrap(kek, key)
+	if err != nil {
+		t.Fatalf("failed to wrap key: %v", err)
+	}
+	if have := hex.EncodeToString(wrapped); have != want {
+		t.Fatalf("wrapped key mismatch: have %s, want %s", have, want)
+	}
+	unwrapped, err := aesKeyUnwrap(kek, wrapped)
+	if err != nil || !bytes.Equal(unwrapped, key) {
+		t.Fatalf("unwrapped key mismatch: have (%x, %v), want %x", unwrapped, err, key)
+	}
+}
+
+func TestDefaultKeeperWrapKey(t *testing.T) {
+	var k WrappableKeeper = &defaultPrivateKeyKeeper{}
+	prvID, _ := k.GeneratePrivateKey()
+	kek, _ := GenerateDeterministicKey([]byte("kek"))
+
+	wrapped, err := k.WrapKey(prvID, kek)
+	if err != nil {
+		t.Fatalf("failed to wrap key: %v", err)
+	}
+	if bytes.Contains(wrapped, prvID) {
+		t.Fatal("wrapped key holds the plain key")
+	}
+	unwrapped, err := k.UnwrapKey(wrapped, kek)
+	if err != nil {
+		t.Fatalf("failed to unwrap key: %v", err)
+	}
+	if !bytes.Equal(unwrapped, prvID) {
+		t.Fatalf("unwrapped key mismatch: have %x, want %x", unwrapped, prvID)
+	}
+
+	other, _ := GenerateDeterministicKey([]byte("other kek"))
+	if _, err := k.UnwrapKey(wrapped, other); !errors.Is(err, ErrPermissionDenied) {
+		t.Fatalf("unwrap with other KEK: have %v, want %v", err, ErrPermissionDenied)
+	}
+	wrapped[len(wrapped)-1] ^= 1
+	if _, err := k.UnwrapKey(wrapped, kek); !errors.Is(err, ErrPermissionDenied) {
+		t.Fatalf("unwrap tampered key: have %v, want %v", err, ErrPermissionDenied)
+	}
+	if _, err := k.WrapKey(prvID, kek[:16]); err == nil {
+		t.Fatal("wrapped key with AES-128 KEK")
+	}
+	if _, err := k.WrapKey(prvID[:31], kek); !errors.Is(err, ErrInvalidKey) {
+		t.Fatalf("wrap malformed key: have %v, want %v", err, ErrInvalidKey)
+	}
+}